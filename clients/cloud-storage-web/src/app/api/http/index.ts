@@ -2,6 +2,21 @@ import axios, {AxiosInstance, AxiosResponse, InternalAxiosRequestConfig} from 'a
 import {AuthResponse} from "@/app/api/models/response/AuthResponse";
 import {AUTH_API_URL, USAGE_CLOUD_HANDSHAKE_URL} from "./urls";
 
+// refresh-токен одноразовый: параллельные запросы с 401 должны дождаться одного обновления,
+// иначе повторно предъявленный старый токен отзовёт весь вход
+let pendingRefresh: Promise<AxiosResponse<AuthResponse>> | null = null;
+
+export const refreshTokens = (): Promise<AxiosResponse<AuthResponse>> => {
+    if (!pendingRefresh) {
+        pendingRefresh = axios.post<AuthResponse>(`${AUTH_API_URL}/token/update`, {}, {
+            withCredentials: true
+        }).finally(() => {
+            pendingRefresh = null;
+        });
+    }
+    return pendingRefresh;
+};
+
 export const createApiInstance = (
     baseURL: string,
 
@@ -31,9 +46,7 @@ export const createApiInstance = (
             originalRequest._isRetry = true;
 
             try {
-                const response = await axios.post<AuthResponse>(`${AUTH_API_URL}/token/update`, {}, {
-                    withCredentials: true
-                });
+                const response = await refreshTokens();
                 localStorage.setItem('token', response.data.access_token);
                 originalRequest.headers.Authorization = `Bearer ${response.data.access_token}`;
                 return api.request(originalRequest);
@@ -4,4 +4,5 @@ export type FileData = {
     obj_id: string;
     url: string;
     mime_type: string;
-  };
\ No newline at end of file
+    encrypted_meta?: string;
+  };
@@ -10,5 +10,6 @@ export interface OneFileResponse {
     mime_type: string,
     name: string,
     obj_id: string,
-    url: string
+    url: string,
+    encrypted_meta?: string
 }
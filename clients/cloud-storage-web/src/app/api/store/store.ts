@@ -1,8 +1,6 @@
 import {makeAutoObservable} from "mobx";
 import AuthService from "@/app/api/services/AuthServices";
-import axios from 'axios';
-import {AuthResponse} from "../models/response/AuthResponse";
-import {AUTH_API_URL} from "@/app/api/http/urls";
+import {refreshTokens} from "@/app/api/http";
 import {decryptStoredKey, encryptAndStoreKey} from '@/app/api/utils/EncryptDecryptKey';
 import {doHandshake} from "@/app/api/services/HandshakeService/HandshakeService";
 import {getKs, removeKs} from "@/app/api/utils/ksInStorage";
@@ -88,13 +86,7 @@ export default class Store {
     async checkAuth() {
         this.setLoading(true);
         try {
-            const response = await axios.post<AuthResponse>(
-                `${AUTH_API_URL}/token/update`,
-                {},
-                {
-                    withCredentials: true
-                }
-            );
+            const response = await refreshTokens();
             localStorage.setItem('token', response.data.access_token);
             this.setAuth(true);
             return Promise.resolve();
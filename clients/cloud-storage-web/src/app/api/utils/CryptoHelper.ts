@@ -1,3 +1,106 @@
+/**
+ * Метаданные файла, которые шифруются на клиенте и передаются в X-Encrypted-Meta.
+ * Сервер хранит их непрозрачным blob-ом и не видит имя и MIME-тип.
+ */
+export type FileMeta = {
+    name: string;
+    mime_type: string;
+    tags?: string[];
+};
+
+/**
+ * Шифрует данные в формат nonce(16) || iv(16) || AES-CBC(PKCS7) || HMAC-SHA256(iv || ciphertext)
+ */
+export async function buildEncryptedBlob(
+    data: Uint8Array,
+    kEnc: CryptoKey,
+    kMac: CryptoKey
+): Promise<Uint8Array> {
+    const iv = crypto.getRandomValues(new Uint8Array(16));
+    const nonce = crypto.getRandomValues(new Uint8Array(16));
+
+    // WebCrypto сам добавляет PKCS7 padding для AES-CBC
+    const encrypted = new Uint8Array(await crypto.subtle.encrypt(
+        { name: 'AES-CBC', iv },
+        kEnc,
+        data
+    ));
+
+    // Вычисляем HMAC (iv + ciphertext)
+    const dataToMac = new Uint8Array(iv.length + encrypted.length);
+    dataToMac.set(iv, 0);
+    dataToMac.set(encrypted, iv.length);
+    const hmac = new Uint8Array(await crypto.subtle.sign('HMAC', kMac, dataToMac));
+
+    const out = new Uint8Array(nonce.length + dataToMac.length + hmac.length);
+    out.set(nonce, 0);
+    out.set(dataToMac, nonce.length);
+    out.set(hmac, nonce.length + dataToMac.length);
+    return out;
+}
+
+/**
+ * Проверяет HMAC и расшифровывает blob, собранный buildEncryptedBlob
+ */
+export async function openEncryptedBlob(
+    bytes: Uint8Array,
+    kEnc: CryptoKey,
+    kMac: CryptoKey
+): Promise<Uint8Array> {
+    // Validate minimum length (nonce + iv + min 1 block + hmac)
+    if (bytes.length < 16 + 16 + 16 + 32) {
+        throw new Error(`Invalid data length: ${bytes.length} bytes`);
+    }
+
+    const iv = bytes.slice(16, 32);
+    const ciphertext = bytes.slice(32, bytes.length - 32);
+    const receivedHmac = bytes.slice(bytes.length - 32);
+
+    // Compute HMAC (iv + ciphertext)
+    const hmacData = bytes.slice(16, bytes.length - 32);
+    const computedHmac = new Uint8Array(
+        await crypto.subtle.sign('HMAC', kMac, hmacData)
+    );
+
+    // Timing-safe comparison
+    if (!compareHmac(computedHmac, receivedHmac)) {
+        throw new Error('HMAC verification failed');
+    }
+
+    // WebCrypto проверяет и снимает PKCS7 padding сам
+    const decrypted = await crypto.subtle.decrypt(
+        { name: 'AES-CBC', iv },
+        kEnc,
+        ciphertext
+    );
+    return new Uint8Array(decrypted);
+}
+
+/**
+ * Шифрует метаданные файла и возвращает base64 для заголовка X-Encrypted-Meta
+ */
+export async function encryptFileMeta(meta: FileMeta, kEnc: CryptoKey, kMac: CryptoKey): Promise<string> {
+    const plain = new TextEncoder().encode(JSON.stringify(meta));
+    return bytesToBase64(await buildEncryptedBlob(plain, kEnc, kMac));
+}
+
+/**
+ * Расшифровывает encrypted_meta из ответа сервера
+ */
+export async function decryptFileMeta(encryptedMeta: string, kEnc: CryptoKey, kMac: CryptoKey): Promise<FileMeta> {
+    const blob = Uint8Array.from(atob(encryptedMeta), (c) => c.charCodeAt(0));
+    const plain = await openEncryptedBlob(blob, kEnc, kMac);
+    return JSON.parse(new TextDecoder('utf-8').decode(plain)) as FileMeta;
+}
+
+function bytesToBase64(bytes: Uint8Array): string {
+    let binary = '';
+    bytes.forEach((byte) => {
+        binary += String.fromCharCode(byte);
+    });
+    return btoa(binary);
+}
+
 /**
  * Потоково загружает зашифрованный файл на сервер, используя AES-CBC и HMAC-SHA256
  *
@@ -46,29 +149,14 @@ export async function streamUploadEncryptedFile(
         dataToMac
     );
 
-    // Кодируем имя файла
-    function encodeFilenameToBase64(filename: string): string {
-        // 1. Преобразуем строку в байты (UTF-8)
-        const encoder = new TextEncoder();
-        const bytes = encoder.encode(filename);
-
-        // 2. Конвертируем байты в Base64
-        let binary = '';
-        bytes.forEach((byte) => {
-            binary += String.fromCharCode(byte);
-        });
-
-        return btoa(binary);
-    }
-
-    const encodedFilename = encodeFilenameToBase64(file.name);
+    // Метаданные (имя и MIME) шифруются ключами сессии, сервер хранит их непрозрачным blob-ом
+    const encryptedMeta = await encryptFileMeta({ name: file.name, mime_type: file.type }, kEnc, kMac);
 
     const token = localStorage.getItem('token');
     const headers = new Headers({
         'Authorization': `Bearer ${token}`,
         "X-File-Category": category,
-        "X-Orig-Filename": encodedFilename,
-        "X-Orig-Mime": file.type,
+        "X-Encrypted-Meta": encryptedMeta,
         "Content-Type": "application/octet-stream"
     });
 
@@ -0,0 +1,61 @@
+import {FileData} from "@/app/api/models/FileData";
+import {decryptFileMeta} from "@/app/api/utils/CryptoHelper";
+import {getKs} from "@/app/api/utils/ksInStorage";
+
+// Декодирует имя старого файла, загруженного до шифрования метаданных (base64 от UTF-8)
+function decodeLegacyName(name: string): string {
+    try {
+        if (name && /^[A-Za-z0-9+/=]+$/.test(name)) {
+            const binaryString = atob(name);
+            const bytes = new Uint8Array(binaryString.length);
+            for (let i = 0; i < binaryString.length; i++) {
+                bytes[i] = binaryString.charCodeAt(i);
+            }
+            return new TextDecoder('utf-8').decode(bytes);
+        }
+    } catch (error) {
+        console.warn(`Не удалось раскодировать имя файла: ${name}. Оставляем без изменений.`, error);
+    }
+    return name;
+}
+
+/**
+ * Приводит файлы из ответа сервера к FileData.
+ * Имя и MIME новых файлов лежат в encrypted_meta и расшифровываются ключами сессии;
+ * если ключей нет (сессия истекла), показываем obj_id до повторного ввода пароля.
+ */
+export async function toFileData(files: any[]): Promise<FileData[]> {
+    const keys = await getKs();
+
+    return Promise.all(files.map(async (file: any): Promise<FileData> => {
+        let name = String(file.name ?? '');
+        let mimeType = String(file.mime_type ?? '');
+        const encryptedMeta = file.encrypted_meta ? String(file.encrypted_meta) : undefined;
+
+        if (encryptedMeta) {
+            name = String(file.obj_id);
+            mimeType = 'application/octet-stream';
+            if (keys) {
+                const [kMac, kEnc] = keys;
+                try {
+                    const meta = await decryptFileMeta(encryptedMeta, kEnc, kMac);
+                    name = meta.name || name;
+                    mimeType = meta.mime_type || mimeType;
+                } catch (error) {
+                    console.warn(`Не удалось расшифровать метаданные файла ${file.obj_id}`, error);
+                }
+            }
+        } else {
+            name = decodeLegacyName(name);
+        }
+
+        return {
+            obj_id: String(file.obj_id),
+            name,
+            url: String(file.url),
+            created_at: String(file.created_at),
+            mime_type: mimeType,
+            encrypted_meta: encryptedMeta,
+        };
+    }));
+}
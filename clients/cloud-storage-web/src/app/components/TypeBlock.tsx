@@ -2,6 +2,7 @@
 import {useEffect, useState} from "react";
 import {FileData} from "@/app/api/models/FileData";
 import CloudService from "../api/services/CloudServices";
+import {toFileData} from "@/app/api/utils/fileMeta";
 import FileCard from "@/app/ui/FileCard";
 import TypeFileIcon from "../ui/TypeFileIcon";
 import FileUploader from "./FileUploader";
@@ -26,31 +27,7 @@ export default function TypeBlock({ type }) {
         const fileData = response.data.file_data;
 
         if (Array.isArray(fileData)) {
-          const files: FileData[] = fileData.map((file: any) => {
-            let decodedName = file.name; // По умолчанию используем имя как есть
-            try {
-              if (file.name && /^[A-Za-z0-9+/=]+$/.test(file.name)) {
-                // Декодируем Base64 в строку байтов
-                const binaryString = atob(file.name);
-                // Преобразуем строку байтов в массив Uint8Array
-                const bytes = new Uint8Array(binaryString.length);
-                for (let i = 0; i < binaryString.length; i++) {
-                  bytes[i] = binaryString.charCodeAt(i);
-                }
-                // Декодируем байты как UTF-8
-                decodedName = new TextDecoder('utf-8').decode(bytes);
-              }
-            } catch (error) {
-              console.warn(`Не удалось раскодировать имя файла: ${file.name}. Оставляем без изменений.`, error);
-            }
-            return {
-              obj_id: String(file.obj_id),
-              name: decodedName,
-              url: String(file.url),
-              created_at: String(file.created_at),
-              mime_type: String(file.mime_type),
-            };
-          });
+          const files: FileData[] = await toFileData(fileData);
           setFile(files);
           setFilteredFiles(files);
         } else {
@@ -2,6 +2,7 @@
 import React, {useEffect, useState} from 'react';
 import {FileData} from '@/app/api/models/FileData';
 import CloudService from '../api/services/CloudServices';
+import {toFileData} from '@/app/api/utils/fileMeta';
 import dynamic from 'next/dynamic';
 import TypeFileIcon from '../ui/TypeFileIcon';
 import FileCard from '../ui/FileCard';
@@ -33,41 +34,16 @@ export default function TypeGetAll() {
                 );
 
                 const result: Record<string, FileData[]> = {};
-                responses.forEach((response, index) => {
+                await Promise.all(responses.map(async (response, index) => {
                     const type = types[index];
                     const fileData = response.data.file_data;
 
                     if (Array.isArray(fileData)) {
-                        result[type] = fileData.map((file) => {
-                            let decodedName = file.name; // По умолчанию используем имя как есть
-                            try {
-                                // Проверяем, является ли строка валидной Base64
-                                if (file.name && /^[A-Za-z0-9+/=]+$/.test(file.name)) {
-                                    // Декодируем Base64 в строку байтов
-                                    const binaryString = atob(file.name);
-                                    // Преобразуем строку байтов в массив Uint8Array
-                                    const bytes = new Uint8Array(binaryString.length);
-                                    for (let i = 0; i < binaryString.length; i++) {
-                                        bytes[i] = binaryString.charCodeAt(i);
-                                    }
-                                    // Декодируем байты как UTF-8
-                                    decodedName = new TextDecoder('utf-8').decode(bytes);
-                                }
-                            } catch (error) {
-                                console.warn(`Не удалось раскодировать имя файла: ${file.name}. Оставляем без изменений.`, error);
-                            }
-                            return {
-                                obj_id: String(file.obj_id),
-                                name: decodedName,
-                                url: file.url,
-                                created_at: String(file.created_at),
-                                mime_type: String(file.mime_type),
-                            };
-                        });
+                        result[type] = await toFileData(fileData);
                     } else {
                         result[type] = [];
                     }
-                });
+                }));
 
                 setFilesByType(result);
             } catch (error) {
@@ -6,6 +6,7 @@ import { Loader2 } from 'lucide-react';
 import { useEffect, useState } from "react";
 import { FileData } from "@/app/api/models/FileData";
 import CloudService from "../api/services/CloudServices";
+import {toFileData} from "@/app/api/utils/fileMeta";
 
 
 const TypeBlockHome = ({ type }: { type: string }) => {
@@ -25,13 +26,7 @@ useEffect(() => {
           const fileData = response.data.file_data;
 
           if (Array.isArray(fileData)) {
-            result[type] = fileData.map((file: any) => ({
-              obj_id: String(file.obj_id),
-              name: String(file.name),
-              url: String(file.url),
-              created_at: String(file.created_at),
-              mime_type: String(file.mime_type)
-            }));
+            result[type] = await toFileData(fileData);
           } else {
             result[type] = [];
           }
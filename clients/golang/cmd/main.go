@@ -33,6 +33,11 @@ func main() {
 	cloudURL := flag.String("cloud-url", "http://localhost:8080/files/one/encrypted", "URL эндпоинта загрузки")
 	category := flag.String("category", "photo", "Категория файла: photo|video|text|unknown")
 
+	// для скачивания всей категории одним архивом
+	archiveURL := flag.String("archive-url", "http://localhost:8080/files/archive", "URL эндпоинта архива")
+	archiveFormat := flag.String("archive-format", "zip", "Формат архива: zip|tar")
+	archiveOut := flag.String("archive-out", "", "Папка для расшифрованных файлов из архива категории (пусто — не скачивать)")
+
 	flag.Parse()
 
 	// регистрация и получение access_token
@@ -107,6 +112,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	// имя и MIME-тип сервер хранит только в зашифрованном виде
+	if fileResp.EncryptedMeta != "" {
+		meta, err := client.DecryptFileMeta(fileResp.EncryptedMeta, session.KEnc, session.KMac)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "не удалось расшифровать метаданные файла: %v\n", err)
+			os.Exit(1)
+		}
+		fileResp.Name, fileResp.MimeType = meta.Name, meta.MimeType
+	}
+
 	fmt.Printf("Ответ от cloud-API: \n{\n name:  %v\n created_at:  %v\n obj_id:  %v\n url:  %v\n mime_type:  %v \n}\n", 
 	fileResp.Name, fileResp.Created_At, fileResp.ObjID, fileResp.Url, fileResp.MimeType)
 
@@ -116,4 +131,13 @@ func main() {
 		fmt.Printf("Ошибка: %v\n", err)
 		os.Exit(1)
 	}
+
+	if *archiveOut != "" {
+		manifest, err := client.DownloadArchive(*archiveURL, accessToken, *category, *archiveFormat, *archiveOut, nil, session.KEnc, session.KMac)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "DownloadArchive error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Архив категории «%s» распакован в %s, файлов: %d\n", *category, *archiveOut, len(manifest.Files))
+	}
 }
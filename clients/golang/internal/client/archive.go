@@ -0,0 +1,241 @@
+package client
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"example_client/internal/dto"
+	"example_client/internal/utils"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const archiveManifestName = "manifest.json"
+
+// DownloadArchive скачивает архив категории (или выбранных objectIDs) с /files/archive,
+// сверяет sha256 каждой записи с manifest.json и расшифровывает файлы в outDir/<category>/<оригинальное имя>.
+// Файлы с одинаковым именем в одной категории не перезаписывают друг друга: к имени добавляется " (1)", " (2)" и т.д.
+// Архив стримится во временный файл, поэтому целиком в ОЗУ не загружается.
+func DownloadArchive(archiveURL, accessToken, category, format, outDir string, objectIDs []dto.ObjectID, kEnc, kMac []byte) (*dto.ArchiveManifest, error) {
+	body, err := json.Marshal(dto.ArchiveReq{Format: format, Category: category, ObjectIDs: objectIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, archiveURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{Timeout: 30 * time.Minute}).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("archive download failed: status %d, body %q", resp.StatusCode, string(b))
+	}
+
+	tmp, err := os.CreateTemp("", "securecomm-archive-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	size, err := io.Copy(tmp, resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("save archive: %w", err)
+	}
+
+	if format == "tar" {
+		return extractTarArchive(tmp, outDir, kEnc, kMac)
+	}
+	return extractZipArchive(tmp, size, outDir, kEnc, kMac)
+}
+
+func extractZipArchive(f *os.File, size int64, outDir string, kEnc, kMac []byte) (*dto.ArchiveManifest, error) {
+	zr, err := zip.NewReader(f, size)
+	if err != nil {
+		return nil, fmt.Errorf("open zip: %w", err)
+	}
+
+	names := newArchiveNames()
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, zf := range zr.File {
+		files[zf.Name] = zf
+	}
+
+	mf, ok := files[archiveManifestName]
+	if !ok {
+		return nil, fmt.Errorf("archive has no %s", archiveManifestName)
+	}
+	manifest, err := readManifest(mf.Open)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range manifest.Files {
+		zf, ok := files[entry.Path]
+		if !ok {
+			return nil, fmt.Errorf("archive entry %s is missing", entry.Path)
+		}
+		rc, err := zf.Open()
+		if err != nil {
+			return nil, err
+		}
+		err = decryptArchiveEntry(rc, entry, outDir, names, kEnc, kMac)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return manifest, nil
+}
+
+// в tar manifest.json лежит последним, поэтому архив читается в два прохода
+func extractTarArchive(f *os.File, outDir string, kEnc, kMac []byte) (*dto.ArchiveManifest, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var manifest *dto.ArchiveManifest
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read tar: %w", err)
+		}
+		if hdr.Name == archiveManifestName {
+			manifest, err = readManifest(func() (io.ReadCloser, error) { return io.NopCloser(tr), nil })
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if manifest == nil {
+		return nil, fmt.Errorf("archive has no %s", archiveManifestName)
+	}
+
+	names := newArchiveNames()
+	entries := make(map[string]dto.ArchiveManifestEntry, len(manifest.Files))
+	for _, entry := range manifest.Files {
+		entries[entry.Path] = entry
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	tr = tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read tar: %w", err)
+		}
+		entry, ok := entries[hdr.Name]
+		if !ok {
+			continue
+		}
+		if err := decryptArchiveEntry(tr, entry, outDir, names, kEnc, kMac); err != nil {
+			return nil, err
+		}
+	}
+
+	return manifest, nil
+}
+
+func readManifest(open func() (io.ReadCloser, error)) (*dto.ArchiveManifest, error) {
+	rc, err := open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var manifest dto.ArchiveManifest
+	if err := json.NewDecoder(rc).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", archiveManifestName, err)
+	}
+	return &manifest, nil
+}
+
+// decryptArchiveEntry расшифровывает одну запись архива и параллельно считает sha256 шифртекста
+func decryptArchiveEntry(r io.Reader, entry dto.ArchiveManifestEntry, outDir string, names *archiveNames, kEnc, kMac []byte) error {
+	name := path.Base(entry.Path)
+	if entry.EncryptedMeta != "" {
+		meta, err := DecryptFileMeta(entry.EncryptedMeta, kEnc, kMac)
+		if err != nil {
+			return fmt.Errorf("%s: %w", entry.Path, err)
+		}
+		if meta.Name != "" {
+			name = filepath.Base(meta.Name)
+		}
+	} else if decoded, err := base64.StdEncoding.DecodeString(entry.Name); err == nil && len(decoded) > 0 {
+		name = filepath.Base(string(decoded))
+	}
+
+	outPath := names.reserve(filepath.Join(outDir, filepath.Base(entry.Category), name))
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	hasher := sha256.New()
+	if err := utils.DecryptStream(io.TeeReader(r, hasher), entry.Size, out, kEnc, kMac); err != nil {
+		os.Remove(outPath)
+		return fmt.Errorf("decrypt %s: %w", entry.Path, err)
+	}
+
+	if hex.EncodeToString(hasher.Sum(nil)) != entry.Sha256 {
+		os.Remove(outPath)
+		return fmt.Errorf("sha256 mismatch for %s", entry.Path)
+	}
+	return nil
+}
+
+// archiveNames выдаёт пути для файлов одного архива так, чтобы файлы с одинаковыми именами не затирали друг друга
+type archiveNames struct {
+	used map[string]struct{}
+}
+
+func newArchiveNames() *archiveNames {
+	return &archiveNames{used: make(map[string]struct{})}
+}
+
+// reserve возвращает outPath, а если он уже выдан в этом архиве — "имя (N).расширение" с первым свободным N
+func (n *archiveNames) reserve(outPath string) string {
+	candidate := outPath
+	ext := filepath.Ext(outPath)
+	base := strings.TrimSuffix(outPath, ext)
+	for i := 1; ; i++ {
+		if _, ok := n.used[candidate]; !ok {
+			n.used[candidate] = struct{}{}
+			return candidate
+		}
+		candidate = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+}
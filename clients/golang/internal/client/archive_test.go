@@ -0,0 +1,54 @@
+package client
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveNames_DuplicatesGetSuffix(t *testing.T) {
+	names := newArchiveNames()
+	dir := filepath.Join("out", "photo")
+
+	got := []string{
+		names.reserve(filepath.Join(dir, "cat.jpg")),
+		names.reserve(filepath.Join(dir, "cat.jpg")),
+		names.reserve(filepath.Join(dir, "cat.jpg")),
+		names.reserve(filepath.Join(dir, "notes")),
+		names.reserve(filepath.Join(dir, "notes")),
+	}
+	want := []string{
+		filepath.Join(dir, "cat.jpg"),
+		filepath.Join(dir, "cat (1).jpg"),
+		filepath.Join(dir, "cat (2).jpg"),
+		filepath.Join(dir, "notes"),
+		filepath.Join(dir, "notes (1)"),
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("reserve #%d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestArchiveNames_SuffixedNameAlreadyInArchive(t *testing.T) {
+	names := newArchiveNames()
+
+	first := names.reserve("cat (1).jpg")
+	second := names.reserve("cat.jpg")
+	third := names.reserve("cat.jpg")
+
+	if first != "cat (1).jpg" || second != "cat.jpg" || third != "cat (2).jpg" {
+		t.Errorf("got %q, %q, %q", first, second, third)
+	}
+}
+
+func TestArchiveNames_CategoriesAreIndependent(t *testing.T) {
+	names := newArchiveNames()
+
+	photo := names.reserve(filepath.Join("out", "photo", "a.txt"))
+	text := names.reserve(filepath.Join("out", "text", "a.txt"))
+
+	if photo != filepath.Join("out", "photo", "a.txt") || text != filepath.Join("out", "text", "a.txt") {
+		t.Errorf("got %q, %q", photo, text)
+	}
+}
@@ -0,0 +1,39 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"example_client/internal/crypto_utils"
+	"example_client/internal/dto"
+	"fmt"
+)
+
+// EncryptFileMeta сериализует meta в JSON и возвращает base64 blob-а для заголовка X-Encrypted-Meta.
+func EncryptFileMeta(meta dto.FileMeta, kEnc, kMac []byte) (string, error) {
+	plain, err := json.Marshal(meta)
+	if err != nil {
+		return "", err
+	}
+	blob, err := crypto_utils.BuildEncryptedBlob(plain, kEnc, kMac)
+	if err != nil {
+		return "", fmt.Errorf("encrypt meta: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(blob), nil
+}
+
+// DecryptFileMeta расшифровывает encrypted_meta из ответа сервера или manifest.json.
+func DecryptFileMeta(encryptedMeta string, kEnc, kMac []byte) (*dto.FileMeta, error) {
+	blob, err := base64.StdEncoding.DecodeString(encryptedMeta)
+	if err != nil {
+		return nil, fmt.Errorf("decode meta: %w", err)
+	}
+	plain, err := crypto_utils.OpenEncryptedBlob(blob, kEnc, kMac)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt meta: %w", err)
+	}
+	var meta dto.FileMeta
+	if err := json.Unmarshal(plain, &meta); err != nil {
+		return nil, fmt.Errorf("invalid meta: %w", err)
+	}
+	return &meta, nil
+}
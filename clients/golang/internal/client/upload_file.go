@@ -8,6 +8,7 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"example_client/internal/crypto_utils"
+	"example_client/internal/dto"
 	"fmt"
 	"io"
 	"mime"
@@ -27,6 +28,14 @@ func StreamingUploadEncryptedFile(filePath, cloudURL, accessToken, category stri
 	}
 	defer f.Close()
 
+	encMeta, err := EncryptFileMeta(dto.FileMeta{
+		Name:     filepath.Base(filePath),
+		MimeType: mime.TypeByExtension(filepath.Ext(filePath)),
+	}, kEnc, kMac)
+	if err != nil {
+		return err
+	}
+
 	// Настраиваем AES-CBC и HMAC
 	block, _ := aes.NewCipher(kEnc)
 	iv := make([]byte, aes.BlockSize)
@@ -79,9 +88,7 @@ func StreamingUploadEncryptedFile(filePath, cloudURL, accessToken, category stri
 	req, _ := http.NewRequest("POST", cloudURL, pr)
 	req.Header.Set("Authorization", "Bearer "+accessToken)
 	req.Header.Set("X-File-Category", category)
-	req.Header.Set("X-Orig-Filename", filepath.Base(filePath))
-	req.Header.Set("X-Orig-Mime", "audio/x-psf")
-	//mime.TypeByExtension(filepath.Ext(filePath))
+	req.Header.Set("X-Encrypted-Meta", encMeta)
 	req.Header.Set("Content-Type", "application/octet-stream")
 	// Content-Length мы не знаем заранее — пусть будет chunked
 
@@ -116,13 +123,20 @@ func NotStreamingUploadEncryptedFile(filePath, cloudURL, accessToken, category s
 		return nil, fmt.Errorf("encrypt blob: %w", err)
 	}
 
+	encMeta, err := EncryptFileMeta(dto.FileMeta{
+		Name:     filepath.Base(filePath),
+		MimeType: mime.TypeByExtension(filepath.Ext(filePath)),
+	}, kEnc, kMac)
+	if err != nil {
+		return nil, err
+	}
+
 	req, err := http.NewRequest("POST", cloudURL, bytes.NewReader(blob))
 	if err != nil {
 		return nil, err
 	}
 	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("X-Orig-Filename", filepath.Base(filePath))
-	req.Header.Set("X-Orig-Mime", mime.TypeByExtension(filepath.Ext(filePath)))
+	req.Header.Set("X-Encrypted-Meta", encMeta)
 	req.Header.Set("X-File-Category", category)
 	req.Header.Set("Content-Type", "application/octet-stream")
 
@@ -141,3 +141,42 @@ func BuildEncryptedBlob(plain, kEnc, kMac []byte) ([]byte, error) {
 	buf.Write(tag)
 	return buf.Bytes(), nil
 }
+
+// OpenEncryptedBlob проверяет HMAC и расшифровывает blob формата nonce (16B) || iv (16B) || ciphertext || hmac-tag,
+// собранный BuildEncryptedBlob. Подходит только для небольших данных (метаданные файла), т.к. работает в памяти.
+func OpenEncryptedBlob(blob, kEnc, kMac []byte) ([]byte, error) {
+	if len(blob) < 16+aes.BlockSize+aes.BlockSize+sha256.Size {
+		return nil, fmt.Errorf("blob too small: %d", len(blob))
+	}
+	iv := blob[16 : 16+aes.BlockSize]
+	ciphertext := blob[16+aes.BlockSize : len(blob)-sha256.Size]
+	tag := blob[len(blob)-sha256.Size:]
+	if len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("cipherLen not multiple of block: %d", len(ciphertext))
+	}
+
+	mac := hmac.New(sha256.New, kMac)
+	mac.Write(iv)
+	mac.Write(ciphertext)
+	if !hmac.Equal(mac.Sum(nil), tag) {
+		return nil, fmt.Errorf("HMAC mismatch")
+	}
+
+	block, err := aes.NewCipher(kEnc)
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plain, ciphertext)
+
+	pad := int(plain[len(plain)-1])
+	if pad < 1 || pad > aes.BlockSize {
+		return nil, fmt.Errorf("invalid padding: %d", pad)
+	}
+	for i := 0; i < pad; i++ {
+		if int(plain[len(plain)-1-i]) != pad {
+			return nil, fmt.Errorf("corrupt padding")
+		}
+	}
+	return plain[:len(plain)-pad], nil
+}
@@ -0,0 +1,29 @@
+package dto
+
+type ArchiveReq struct {
+	Format    string     `json:"format"`
+	Category  string     `json:"category"`
+	ObjectIDs []ObjectID `json:"object_ids"`
+}
+
+type ObjectID struct {
+	ObjID        string `json:"obj_id"`
+	FileCategory string `json:"file_category"`
+}
+
+type ArchiveManifest struct {
+	CreatedAt string                 `json:"created_at"`
+	Format    string                 `json:"format"`
+	Files     []ArchiveManifestEntry `json:"files"`
+}
+
+type ArchiveManifestEntry struct {
+	Path          string `json:"path"`
+	ObjID         string `json:"obj_id"`
+	Category      string `json:"category"`
+	Name          string `json:"name"` // base64, только для файлов, загруженных без X-Encrypted-Meta
+	MimeType      string `json:"mime_type"`
+	EncryptedMeta string `json:"encrypted_meta,omitempty"`
+	Size          int64  `json:"size"`
+	Sha256        string `json:"sha256"`
+}
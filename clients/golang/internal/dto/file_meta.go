@@ -0,0 +1,9 @@
+package dto
+
+// FileMeta — метаданные файла, которые клиент шифрует ключами сессии и отправляет в X-Encrypted-Meta.
+// Сервер хранит их непрозрачным blob-ом и не видит имя, MIME-тип и теги.
+type FileMeta struct {
+	Name     string   `json:"name"`
+	MimeType string   `json:"mime_type"`
+	Tags     []string `json:"tags,omitempty"`
+}
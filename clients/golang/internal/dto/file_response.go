@@ -1,9 +1,14 @@
 package dto
 
 type FileResponse struct {
-	Name       string `json:"name"`
-	Created_At string `json:"created_at"`
-	ObjID      string `json:"obj_id"`
-	Url        string `json:"url"`
-	MimeType   string `json:"mime_type"`
+	Name          string `json:"name"`
+	Created_At    string `json:"created_at"`
+	ObjID         string `json:"obj_id"`
+	Url           string `json:"url"`
+	MimeType      string `json:"mime_type"`
+	EncryptedMeta string `json:"encrypted_meta,omitempty"`
+	Size          int64  `json:"size"`
+	Category      string `json:"category"`
+	ExpiresAt     string `json:"expires_at,omitempty"`
+	ExpiresIn     int64  `json:"expires_in,omitempty"`
 }
@@ -34,6 +34,20 @@ func DownLoadFileByURL(presignedURL, outPath string, kEnc, kMac []byte) error {
 	if err != nil {
 		return err
 	}
+
+	// открываем файл
+	out, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	return DecryptStream(resp.Body, total, out, kEnc, kMac)
+}
+
+// DecryptStream читает из r blob формата nonce(16B) || iv(16B) || ciphertext || hmac-tag общей длиной total,
+// пишет расшифрованные данные в out и в конце сверяет HMAC(iv||ciphertext).
+func DecryptStream(r io.Reader, total int64, out io.Writer, kEnc, kMac []byte) error {
 	// минимальный: 16+16+16+32 = 80 байт (nonce+iv+1 блок+tag)
 	if total < int64(16+aes.BlockSize+aes.BlockSize+sha256.Size) {
 		return fmt.Errorf("blob too small: %d", total)
@@ -41,13 +55,13 @@ func DownLoadFileByURL(presignedURL, outPath string, kEnc, kMac []byte) error {
 
 	// 1) nonce
 	nonce := make([]byte, 16)
-	if _, err := io.ReadFull(resp.Body, nonce); err != nil {
+	if _, err := io.ReadFull(r, nonce); err != nil {
 		return err
 	}
 
 	// 2) iv
 	iv := make([]byte, aes.BlockSize)
-	if _, err := io.ReadFull(resp.Body, iv); err != nil {
+	if _, err := io.ReadFull(r, iv); err != nil {
 		return err
 	}
 	// 3) ciphertext length
@@ -67,16 +81,9 @@ func DownLoadFileByURL(presignedURL, outPath string, kEnc, kMac []byte) error {
 	}
 	cbc := cipher.NewCBCDecrypter(blockCipher, iv)
 
-	// открываем файл
-	out, err := os.Create(outPath)
-	if err != nil {
-		return err
-	}
-	defer out.Close()
-
 	// читаем первый блок ciphertext
 	buf := make([]byte, aes.BlockSize)
-	if _, err := io.ReadFull(resp.Body, buf); err != nil {
+	if _, err := io.ReadFull(r, buf); err != nil {
 		return err
 	}
 	macHasher.Write(buf)
@@ -85,7 +92,7 @@ func DownLoadFileByURL(presignedURL, outPath string, kEnc, kMac []byte) error {
 
 	// цикл по остальным блокам
 	for i := 1; i < blocks; i++ {
-		if _, err := io.ReadFull(resp.Body, buf); err != nil {
+		if _, err := io.ReadFull(r, buf); err != nil {
 			return err
 		}
 		macHasher.Write(buf)
@@ -117,7 +124,7 @@ func DownLoadFileByURL(presignedURL, outPath string, kEnc, kMac []byte) error {
 
 	// 4) tag
 	tag := make([]byte, sha256.Size)
-	if _, err := io.ReadFull(resp.Body, tag); err != nil {
+	if _, err := io.ReadFull(r, tag); err != nil {
 		return err
 	}
 	if !hmac.Equal(macHasher.Sum(nil), tag) {
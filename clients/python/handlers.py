@@ -8,7 +8,7 @@ from telegram import Update, ReplyKeyboardMarkup, KeyboardButton
 from telegram.ext import ContextTypes, ConversationHandler
 from telegram.error import TimedOut
 import asyncio
-from tests.client_http import encrypt_file, decrypt_file, perform_finalize, perform_handshake, derive_keys
+from tests.client_http import encrypt_file, decrypt_file, encrypt_file_meta, decrypt_file_meta, perform_finalize, perform_handshake, derive_keys
 from datetime import datetime
 import pytz
 import re
@@ -231,12 +231,11 @@ async def handle_file(update: Update, context: ContextTypes.DEFAULT_TYPE):
             raise Exception("Ошибка шифрования")
         file_category = get_file_category(mime_type)
         access_token = context.user_data["access_token"]
-        encoded_file_name = base64.b64encode(safe_file_name.encode('utf-8')).decode('ascii')
+        encrypted_meta = encrypt_file_meta(safe_file_name, mime_type, session["k_enc"], session["k_mac"])
         headers = {
             "Authorization": f"Bearer {access_token}",
             "Content-Type": "application/octet-stream",
-            "X-Orig-Filename": encoded_file_name,
-            "X-Orig-Mime": mime_type,
+            "X-Encrypted-Meta": encrypted_meta,
             "X-File-Category": file_category
         }
         response = requests.post(UPLOAD_FILES_URL, headers=headers, data=encrypted_data, timeout=30)
@@ -280,6 +279,26 @@ async def handle_file(update: Update, context: ContextTypes.DEFAULT_TYPE):
     except Exception as e:
         await update.message.reply_text(f"Ошибка: {e}. Попробуйте снова.")
 
+# Возвращает имя и MIME-тип файла из ответа сервера.
+# Новые файлы приходят с encrypted_meta, который расшифровывается ключами сессии;
+# у файлов, загруженных до шифрования метаданных, имя лежит в base64 в поле name.
+def resolve_file_meta(file, session, default_name):
+    encrypted_meta = file.get("encrypted_meta")
+    if encrypted_meta:
+        meta = decrypt_file_meta(encrypted_meta, session["k_enc"], session["k_mac"])
+        if meta:
+            return meta.get("name") or default_name, meta.get("mime_type") or "unknown"
+        logger.error("Не удалось расшифровать метаданные файла %s", file.get("obj_id"))
+        return default_name, "unknown"
+    encoded_name = file.get("name") or ""
+    mime_type = file.get("mime_type") or "unknown"
+    try:
+        if encoded_name and len(encoded_name) % 4 == 0 and re.match(r'^[A-Za-z0-9+/=]+$', encoded_name):
+            return base64.b64decode(encoded_name).decode('utf-8'), mime_type
+    except Exception as e:
+        logger.error(f"Ошибка декодирования имени файла: {encoded_name}, ошибка: {e}")
+    return encoded_name or default_name, mime_type
+
 # Начинает процесс получения файла
 async def get_file_start(update: Update, context: ContextTypes.DEFAULT_TYPE):
     session = get_session(update.effective_user.id)
@@ -310,13 +329,9 @@ async def get_file_id(update: Update, context: ContextTypes.DEFAULT_TYPE):
             if response.status_code == 200:
                 file_data = response.json()
                 download_url = file_data.get("url")
-                encoded_name = file_data.get("name", file_id)
-                try:
-                    file_name = base64.b64decode(encoded_name).decode('utf-8')
-                except Exception:
-                    file_name = file_id
+                file_name, file_mime = resolve_file_meta(file_data, session, file_id)
                 full_obj_id = file_data.get("obj_id", file_id)
-                file_category = get_file_category(file_data.get("mime_type", "unknown"))
+                file_category = get_file_category(file_mime)
                 if "file_urls" not in context.user_data:
                     context.user_data["file_urls"] = {}
                 context.user_data["file_urls"][full_obj_id] = {
@@ -487,6 +502,10 @@ async def handle_category_selection(update: Update, context: ContextTypes.DEFAUL
         '📁 Прочее': 'unknown'
     }
     file_category = category_map.get(selected_category)
+    session = get_session(update.effective_user.id)
+    if not session or "k_enc" not in session or "k_mac" not in session:
+        await update.message.reply_text("Войдите в аккаунт или сессия повреждена.")
+        return ConversationHandler.END
     try:
         access_token = context.user_data["access_token"]
         headers = {"Authorization": f"Bearer {access_token}"}
@@ -504,19 +523,10 @@ async def handle_category_selection(update: Update, context: ContextTypes.DEFAUL
             message = f"📂 *Файлы в категории {selected_category}:*\n"
             context.user_data["file_list"] = file_data
             for idx, file in enumerate(file_data, 1):
-                encoded_name = file.get("name", "Без имени")
-                try:
-                    if len(encoded_name) % 4 == 0 and re.match(r'^[A-Za-z0-9+/=]+$', encoded_name):
-                        name = base64.b64decode(encoded_name).decode('utf-8')
-                    else:
-                        name = encoded_name
-                except Exception as e:
-                    logger.error(f"Ошибка декодирования имени файла: {encoded_name}, ошибка: {e}")
-                    name = encoded_name if encoded_name != "Без имени" else "неизвестный_файл"
+                name, mime_type = resolve_file_meta(file, session, "неизвестный_файл")
                 obj_id = file.get("obj_id", "не указан")
                 download_url = file.get("url", None)
                 created_at_raw = file.get("created_at", "не указан")
-                mime_type = file.get("mime_type", "unknown")
                 clean_obj_id = obj_id.rstrip('.')
                 current_extension = obj_id.split('.')[-1].lower() if '.' in obj_id else ''
                 expected_extension = get_file_extension(mime_type).lstrip('.')
@@ -1,6 +1,7 @@
 import base64
 import hashlib
 import hmac
+import json
 import os
 import requests
 import time
@@ -146,10 +147,14 @@ def derive_keys(ks):
 
 # Шифрует файл с использованием AES-CBC и HMAC
 def encrypt_file(file_path, k_enc_b64, k_mac_b64):
-    k_enc = base64.b64decode(k_enc_b64)
-    k_mac = base64.b64decode(k_mac_b64)
     with open(file_path, "rb") as f:
         plaintext = f.read()
+    return encrypt_bytes(plaintext, k_enc_b64, k_mac_b64)
+
+# Шифрует произвольные байты в формат nonce || iv || ciphertext || hmac
+def encrypt_bytes(plaintext, k_enc_b64, k_mac_b64):
+    k_enc = base64.b64decode(k_enc_b64)
+    k_mac = base64.b64decode(k_mac_b64)
     padder = PKCS7(128).padder()
     padded = padder.update(plaintext) + padder.finalize()
     nonce = os.urandom(16)
@@ -190,6 +195,23 @@ def decrypt_file(encrypted_data, k_enc_b64, k_mac_b64):
         return None
 
 
+# Шифрует метаданные файла (имя и MIME) для заголовка X-Encrypted-Meta.
+# Сервер хранит их непрозрачным blob-ом, расшифровать их может только клиент.
+def encrypt_file_meta(name, mime_type, k_enc_b64, k_mac_b64):
+    plain = json.dumps({"name": name, "mime_type": mime_type}).encode("utf-8")
+    return base64.b64encode(encrypt_bytes(plain, k_enc_b64, k_mac_b64)).decode("ascii")
+
+# Расшифровывает encrypted_meta из ответа сервера, возвращает dict или None
+def decrypt_file_meta(encrypted_meta, k_enc_b64, k_mac_b64):
+    try:
+        plain = decrypt_file(base64.b64decode(encrypted_meta), k_enc_b64, k_mac_b64)
+        if plain is None:
+            return None
+        return json.loads(plain.decode("utf-8"))
+    except Exception:
+        return None
+
+
 # Потоковая загрузка зашифрованного файла
 def stream_upload_encrypted_file(file_path, cloud_url, access_token, category, k_enc, k_mac):
     """
@@ -273,8 +295,9 @@ def stream_upload_encrypted_file(file_path, cloud_url, access_token, category, k
     headers = {
         "Authorization": f"Bearer {access_token}",
         "X-File-Category": category,
-        "X-Orig-Filename": os.path.basename(file_path),
-        "X-Orig-Mime": "audio/x-psf",  # Как в Go примере
+        "X-Encrypted-Meta": encrypt_file_meta(
+            os.path.basename(file_path), "audio/x-psf",
+            base64.b64encode(k_enc).decode("ascii"), base64.b64encode(k_mac).decode("ascii")),
         "Content-Type": "application/octet-stream"
     }
 
@@ -1,19 +1,28 @@
 package main
 
 import (
+	"context"
+	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/1abobik1/AuthService/config"
+	"github.com/1abobik1/AuthService/internal/domain/models"
 	"github.com/1abobik1/AuthService/internal/external_api"
 	handlerToken "github.com/1abobik1/AuthService/internal/handler/http/token"
 	handlerUsers "github.com/1abobik1/AuthService/internal/handler/http/users"
+	"github.com/1abobik1/AuthService/internal/keyset"
+	"github.com/1abobik1/AuthService/internal/mailer"
 	"github.com/1abobik1/AuthService/internal/middleware"
+	"github.com/1abobik1/AuthService/internal/oidc"
 	serviceToken "github.com/1abobik1/AuthService/internal/service/token"
 	serviceUsers "github.com/1abobik1/AuthService/internal/service/users"
 	"github.com/1abobik1/AuthService/internal/storage/postgresql"
+	revocationStore "github.com/1abobik1/AuthService/internal/storage/redis"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
 
 	_ "github.com/1abobik1/AuthService/docs"
 	swaggerFiles "github.com/swaggo/files"
@@ -47,18 +56,68 @@ func main() {
 		panic("postgres connection error")
 	}
 
-	userService := serviceUsers.NewUserService(postgresStorage, *cfg)
+	// ключи подписи JWT: читаются один раз, открытые публикуются в /.well-known/jwks.json
+	keys, err := keyset.Load(cfg.JWT.PrivateKeyPath, cfg.JWT.ExtraPublicKeyPaths)
+	if err != nil {
+		panic(err)
+	}
+
+	// redis, общий с secure_comm_service: список отзыва access токенов
+	rClient := redis.NewClient(&redis.Options{
+		Addr: cfg.Redis.ServerAddr,
+	})
+	if err := rClient.Ping(context.Background()).Err(); err != nil {
+		panic("redis connection error")
+	}
+	revocations := revocationStore.NewRevocationStore(rClient)
+	// неудачные попытки входа по аккаунтам: блокировка общая для всех экземпляров
+	loginAttempts := revocationStore.NewLoginAttemptStore(rClient)
+
+	mail, err := mailer.New(cfg.Mailer.Driver, cfg.Mailer.SMTPAddr, cfg.Mailer.SMTPFrom, cfg.Mailer.SMTPUser, cfg.Mailer.SMTPPassword, cfg.Mailer.LogPath)
+	if err != nil {
+		panic(err)
+	}
 
 	// подключение клиента для внешних апи
 	httpClient := &http.Client{
 		Timeout: 3 * time.Second,
 	}
+
+	// OIDC провайдеры: discovery выполняется при первом входе, поэтому недоступный провайдер не мешает запуску
+	oidcProviders := make(map[string]serviceUsers.OIDCProviderI, len(cfg.OIDC.Providers))
+	for _, p := range cfg.OIDC.Providers {
+		oidcProviders[p.Name] = oidc.NewProvider(oidc.Config{
+			Name:         p.Name,
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  strings.TrimSuffix(cfg.OIDC.RedirectURL, "/") + "/" + p.Name,
+			Scopes:       p.Scopes,
+		}, httpClient)
+	}
+
+	userService := serviceUsers.NewUserService(postgresStorage, revocations, loginAttempts, keys, mail, oidcProviders, *cfg)
 	tgClient := external_api.NewTGClient(cfg.ExternalAPIs.TGClient, httpClient)
 	webClient := external_api.NewWEBClient(cfg.ExternalAPIs.WebClient, httpClient)
 
 	userHandler := handlerUsers.NewUserHandler(userService, tgClient, webClient)
 
-	tokenService := serviceToken.NewTokenService(postgresStorage, *cfg)
+	// фоновое удаление аккаунтов, у которых истёк льготный период
+	go func() {
+		ticker := time.NewTicker(cfg.AccountDeletion.WorkerInterval)
+		defer ticker.Stop()
+
+		for {
+			if n, err := userService.ProcessAccountDeletions(context.Background()); err != nil {
+				log.Printf("account deletion worker: %v", err)
+			} else if n > 0 {
+				log.Printf("account deletion worker: %d accounts deleted", n)
+			}
+			<-ticker.C
+		}
+	}()
+
+	tokenService := serviceToken.NewTokenService(postgresStorage, revocations, keys, *cfg)
 	tokenHandler := handlerToken.NewTokenHandler(tokenService)
 
 	r := gin.Default()
@@ -74,8 +133,6 @@ func main() {
 			"Origin",
 			"Authorization",
 			"Content-Type",
-			"X-Orig-Filename",
-			"X-Orig-Mime",
 			"X-File-Category",
 		},
 		ExposeHeaders:    []string{"Content-Length"},
@@ -87,7 +144,54 @@ func main() {
 	r.POST("/user/login", middleware.RegistrationAttemptLimiter() ,middleware.NewIPRateLimiter(cfg.LoginLimiter.RPC, cfg.LoginLimiter.Burst, cfg.LoginLimiter.Period), userHandler.Login)
 	r.POST("/user/logout", userHandler.Logout)
 
+	// общий лимит по IP на запросы, отправляющие письма
+	mailLimiter := middleware.NewIPRateLimiter(cfg.EmailVerify.LimiterRPC, cfg.EmailVerify.LimiterBurst, cfg.EmailVerify.LimiterPeriod)
+
+	r.POST("/user/email/confirm", userHandler.ConfirmEmail)
+	r.POST("/user/email/resend", mailLimiter, userHandler.ResendVerification)
+
+	r.POST("/user/password/forgot", mailLimiter, userHandler.ForgotPassword)
+	r.POST("/user/password/reset", userHandler.ResetPassword)
+	r.POST("/user/password/change", middleware.AccessTokenMiddleware(keys.Keyfunc, revocations), middleware.RegistrationAttemptLimiter(), userHandler.ChangePassword)
+
+	r.POST("/user/login/2fa", middleware.RegistrationAttemptLimiter(), middleware.NewIPRateLimiter(cfg.LoginLimiter.RPC, cfg.LoginLimiter.Burst, cfg.LoginLimiter.Period), userHandler.LoginTwoFactor)
+
+	oidcApi := r.Group("/user/oidc/:provider")
+	{
+		oidcApi.POST("/start", userHandler.StartOIDCLogin)
+		oidcApi.POST("/link", middleware.AccessTokenMiddleware(keys.Keyfunc, revocations), userHandler.StartOIDCLink)
+		oidcApi.POST("/callback", middleware.OptionalAccessTokenMiddleware(keys.Keyfunc, revocations), middleware.RegistrationAttemptLimiter(), middleware.NewIPRateLimiter(cfg.LoginLimiter.RPC, cfg.LoginLimiter.Burst, cfg.LoginLimiter.Period), userHandler.OIDCCallback)
+	}
+
+	twoFactorApi := r.Group("/user/2fa", middleware.AccessTokenMiddleware(keys.Keyfunc, revocations))
+	{
+		twoFactorApi.POST("/enroll", userHandler.EnrollTOTP)
+		twoFactorApi.POST("/confirm", middleware.RegistrationAttemptLimiter(), userHandler.ConfirmTOTP)
+		twoFactorApi.POST("/disable", middleware.RegistrationAttemptLimiter(), userHandler.DisableTOTP)
+	}
+
+	devicesApi := r.Group("/user/devices", middleware.AccessTokenMiddleware(keys.Keyfunc, revocations))
+	{
+		devicesApi.GET("", userHandler.ListDevices)
+		devicesApi.DELETE("", userHandler.RevokeAllDevices)
+		devicesApi.DELETE("/:id", userHandler.RevokeDevice)
+	}
+
+	accountApi := r.Group("/user/account")
+	{
+		accountApi.POST("/delete", middleware.AccessTokenMiddleware(keys.Keyfunc, revocations), middleware.RegistrationAttemptLimiter(), userHandler.RequestAccountDeletion)
+		accountApi.POST("/delete/cancel", middleware.AccessTokenMiddleware(keys.Keyfunc, revocations), userHandler.CancelAccountDeletion)
+		accountApi.GET("/deletion/:token", userHandler.AccountDeletionStatus)
+	}
+
+	adminApi := r.Group("/admin", middleware.AccessTokenMiddleware(keys.Keyfunc, revocations), middleware.RequireRole(models.RoleAdmin))
+	{
+		adminApi.POST("/users/:id/unlock", userHandler.UnlockAccount)
+	}
+
 	r.POST("/token/update", tokenHandler.TokenUpdate)
+	r.POST("/token/revoke", tokenHandler.TokenRevoke)
+	r.GET("/.well-known/jwks.json", tokenHandler.JWKS)
 
 	if err := r.Run(cfg.HTTPServ.ServerAddr); err != nil {
 		panic(err)
@@ -0,0 +1,34 @@
+// mock_oidc — локальный OIDC провайдер для разработки: сразу «входит» под пользователем из MOCK_OIDC_EMAIL
+// (или login_hint). Для auth_service: OIDC_PROVIDERS=mock, OIDC_MOCK_ISSUER=http://localhost:9000,
+// OIDC_MOCK_CLIENT_ID и OIDC_MOCK_CLIENT_SECRET — как MOCK_OIDC_CLIENT_ID и MOCK_OIDC_CLIENT_SECRET
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"github.com/1abobik1/AuthService/internal/oidc/oidctest"
+)
+
+func main() {
+	addr := getenv("MOCK_OIDC_ADDRESS", "localhost:9000")
+	issuer := getenv("MOCK_OIDC_ISSUER", "http://"+addr)
+
+	provider, handler, err := oidctest.NewHandler(issuer, getenv("MOCK_OIDC_CLIENT_ID", "passkeeper"), getenv("MOCK_OIDC_CLIENT_SECRET", "secret"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	email := getenv("MOCK_OIDC_EMAIL", "mock.user@example.com")
+	provider.AddUser(oidctest.User{Subject: "mock|" + email, Email: email, EmailVerified: true})
+
+	log.Printf("mock OIDC provider %s listening on %s", issuer, addr)
+	log.Fatal(http.ListenAndServe(addr, handler))
+}
+
+func getenv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
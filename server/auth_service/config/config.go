@@ -4,6 +4,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
@@ -13,8 +14,71 @@ import (
 type JWTConfig struct {
 	AccessTokenTTL  time.Duration `env:"ACCESS_TOKEN_TTL" env-required:"true"`
 	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL" env-required:"true"`
-	PublicKeyPath   string        `env:"PUBLIC_KEY_PATH" env-required:"true"`
-	PrivateKeyPath  string        `env:"PRIVATE_KEY_PATH" env-required:"true"`
+	PrivateKeyPath  string        `env:"PRIVATE_KEY_PATH" env-required:"true"` // текущий ключ подписи
+	// открытые ключи, которые публикуются в JWKS и принимаются, но не используются для подписи:
+	// прежний ключ после ротации (пока не истекут его токены) и следующий до неё
+	ExtraPublicKeyPaths []string `env:"JWT_EXTRA_PUBLIC_KEY_PATHS" env-separator:","`
+}
+
+// ServiceTokenConfig — короткоживущие JWT, которыми auth_service подписывает вызовы внутреннего API других сервисов
+type ServiceTokenConfig struct {
+	TTL           time.Duration `env:"SERVICE_TOKEN_TTL" env-default:"1m"`
+	QuotaAudience string        `env:"QUOTA_SERVICE_AUDIENCE" env-default:"secure_comm_service"`
+}
+
+type MailerConfig struct {
+	Driver       string `env:"MAILER" env-default:"log"`       // smtp или log
+	LogPath      string `env:"MAILER_LOG_PATH" env-default:""` // для log: файл с письмами; пустой — писать в лог
+	SMTPAddr     string `env:"SMTP_ADDR" env-default:""`       // host:port
+	SMTPFrom     string `env:"SMTP_FROM" env-default:"no-reply@securecomm.local"`
+	SMTPUser     string `env:"SMTP_USER" env-default:""`
+	SMTPPassword string `env:"SMTP_PASSWORD" env-default:""`
+}
+
+type EmailVerifyConfig struct {
+	URL            string        `env:"EMAIL_VERIFY_URL" env-default:"http://localhost:3000/verify-email"` // страница клиента, куда ведёт ссылка из письма (?token=...)
+	TTL            time.Duration `env:"EMAIL_VERIFY_TTL" env-default:"24h"`
+	ResendInterval time.Duration `env:"EMAIL_VERIFY_RESEND_INTERVAL" env-default:"1m"` // не чаще одного письма на пользователя
+	LimiterRPC     float64       `env:"EMAIL_RESEND_LIMITER_RPC" env-default:"3"`      // лимит повторной отправки по IP
+	LimiterBurst   int           `env:"EMAIL_RESEND_LIMITER_BURST" env-default:"1"`
+	LimiterPeriod  time.Duration `env:"EMAIL_RESEND_LIMITER_PERIOD" env-default:"10m"`
+}
+
+type PasswordResetConfig struct {
+	URL            string        `env:"PASSWORD_RESET_URL" env-default:"http://localhost:3000/reset-password"` // страница клиента, куда ведёт ссылка из письма (?token=...)
+	TTL            time.Duration `env:"PASSWORD_RESET_TTL" env-default:"1h"`
+	ResendInterval time.Duration `env:"PASSWORD_RESET_RESEND_INTERVAL" env-default:"1m"` // не чаще одного письма на пользователя
+}
+
+type TwoFactorConfig struct {
+	Issuer        string        `env:"TOTP_ISSUER" env-default:"SecureComm"` // название сервиса в приложении-аутентификаторе
+	ChallengeTTL  time.Duration `env:"TOTP_CHALLENGE_TTL" env-default:"5m"`  // сколько ждать код после ввода пароля
+	RecoveryCodes int           `env:"TOTP_RECOVERY_CODES" env-default:"10"` // сколько кодов восстановления выдавать
+}
+
+// OIDCConfig — вход через внешних OIDC провайдеров. Каждый провайдер из OIDC_PROVIDERS (например, google,gitlab)
+// настраивается переменными OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET
+// и необязательной OIDC_<NAME>_SCOPES (через пробел, по умолчанию "openid email profile")
+type OIDCConfig struct {
+	ProviderNames []string      `env:"OIDC_PROVIDERS" env-separator:","`
+	RedirectURL   string        `env:"OIDC_REDIRECT_URL" env-default:"http://localhost:3000/oidc/callback"` // страница клиента, к адресу добавляется /<provider>
+	StateTTL      time.Duration `env:"OIDC_STATE_TTL" env-default:"10m"`                                    // сколько ждать возврата пользователя от провайдера
+	Providers     []OIDCProviderConfig
+}
+
+type OIDCProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+// AccountDeletionConfig — удаление аккаунта: льготный период, за который запрос можно отменить, и фоновый обработчик
+type AccountDeletionConfig struct {
+	GracePeriod    time.Duration `env:"ACCOUNT_DELETION_GRACE_PERIOD" env-default:"0s"`    // 0 — удаление сразу
+	WorkerInterval time.Duration `env:"ACCOUNT_DELETION_WORKER_INTERVAL" env-default:"1m"` // как часто искать запросы, срок которых наступил
+	Lease          time.Duration `env:"ACCOUNT_DELETION_LEASE" env-default:"10m"`          // сколько один экземпляр держит запрос, пока удаляет
 }
 
 type HTTPServConfig struct {
@@ -27,10 +91,24 @@ type LoginLimiterConfig struct {
 	Period time.Duration `env:"LOGIN_LIMITER_PERIOD" env-required:"true"`
 }
 
+// LoginLockoutConfig — защита аккаунта от подбора пароля независимо от IP: после Threshold неудачных попыток
+// вход блокируется на BaseDuration, и каждая следующая неудача удваивает блокировку, но не больше MaxDuration
+type LoginLockoutConfig struct {
+	Threshold     int64         `env:"LOGIN_LOCKOUT_THRESHOLD" env-default:"5"`
+	BaseDuration  time.Duration `env:"LOGIN_LOCKOUT_BASE_DURATION" env-default:"1m"`
+	MaxDuration   time.Duration `env:"LOGIN_LOCKOUT_MAX_DURATION" env-default:"1h"`
+	FailureWindow time.Duration `env:"LOGIN_LOCKOUT_FAILURE_WINDOW" env-default:"24h"` // неудачи забываются, если их не было столько времени
+}
+
 type PostgresConfig struct {
 	StoragePath string `env:"STORAGE_PATH" env-required:"true"`
 }
 
+// RedisConfig — общий с secure_comm_service Redis, куда пишется список отзыва access токенов
+type RedisConfig struct {
+	ServerAddr string `env:"REDIS_SERVER_ADDRESS" env-default:"redis:6379"`
+}
+
 type ExternalAPIsConfig struct {
 	WebClient       string `env:"EXTERNAL_WEB_CLIENT" env-required:"true"`
 	TGClient        string `env:"EXTERNAL_TG_CLIENT" env-required:"true"`
@@ -38,11 +116,20 @@ type ExternalAPIsConfig struct {
 }
 
 type Config struct {
-	JWT          JWTConfig
-	HTTPServ     HTTPServConfig
-	LoginLimiter LoginLimiterConfig
-	Postgres     PostgresConfig
-	ExternalAPIs ExternalAPIsConfig
+	JWT             JWTConfig
+	ServiceToken    ServiceTokenConfig
+	HTTPServ        HTTPServConfig
+	LoginLimiter    LoginLimiterConfig
+	LoginLockout    LoginLockoutConfig
+	Postgres        PostgresConfig
+	Redis           RedisConfig
+	ExternalAPIs    ExternalAPIsConfig
+	Mailer          MailerConfig
+	EmailVerify     EmailVerifyConfig
+	PasswordReset   PasswordResetConfig
+	TwoFactor       TwoFactorConfig
+	OIDC            OIDCConfig
+	AccountDeletion AccountDeletionConfig
 }
 
 func MustLoad() *Config {
@@ -68,9 +155,40 @@ func MustLoad() *Config {
 		panic(fmt.Sprintf("Failed to load environment variables: %v", err))
 	}
 
+	providers, err := loadOIDCProviders(cfg.OIDC.ProviderNames)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to load OIDC providers: %v", err))
+	}
+	cfg.OIDC.Providers = providers
+
 	return &cfg
 }
 
+// loadOIDCProviders читает настройки провайдеров OIDC_<NAME>_*; issuer и client_id обязательны
+func loadOIDCProviders(names []string) ([]OIDCProviderConfig, error) {
+	var providers []OIDCProviderConfig
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+
+		p := OIDCProviderConfig{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:       strings.Fields(os.Getenv(prefix + "SCOPES")),
+		}
+		if p.Issuer == "" || p.ClientID == "" {
+			return nil, fmt.Errorf("%sISSUER and %sCLIENT_ID are required", prefix, prefix)
+		}
+		providers = append(providers, p)
+	}
+	return providers, nil
+}
+
 func getConfigPath() string {
 	if envPath := os.Getenv("CONFIG_PATH"); envPath != "" {
 		return envPath
@@ -8,14 +8,155 @@ CREATE TABLE IF NOT EXISTS auth_users (
     is_activated BOOLEAN DEFAULT FALSE
 );
 
+-- роль попадает в claim role access-токена; администратора назначают вручную:
+-- UPDATE auth_users SET role = 'admin' WHERE email = '...';
+ALTER TABLE auth_users ADD COLUMN IF NOT EXISTS role VARCHAR(16) NOT NULL DEFAULT 'user';
+ALTER TABLE auth_users DROP CONSTRAINT IF EXISTS auth_users_role_check;
+ALTER TABLE auth_users ADD CONSTRAINT auth_users_role_check CHECK (role IN ('user', 'admin'));
+
+-- сессии устройств: каждый вход (web-браузер, экземпляр бота) — отдельная сессия со своим семейством refresh токенов.
+-- id сессии совпадает с family_id её refresh токенов и передаётся в claim sid access токена
+CREATE TABLE IF NOT EXISTS device_sessions (
+    id TEXT PRIMARY KEY,
+    user_id INT NOT NULL,
+    platform token_platform_enum NOT NULL,
+    device_name VARCHAR(100) NOT NULL DEFAULT '',
+    user_agent VARCHAR(255) NOT NULL DEFAULT '',
+    ip VARCHAR(45) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ,
+    FOREIGN KEY (user_id) REFERENCES auth_users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_device_sessions_user_id ON device_sessions (user_id);
+
+-- refresh токены: хранится только sha256 токена. Каждый вход начинает семейство (family_id = id сессии устройства),
+-- при обновлении токен помечается rotated_at и заменяется следующим в том же семействе. Предъявление уже обменянного
+-- токена означает его кражу: всё семейство и сессия отзываются (revoked_at), событие пишется в security_events
 CREATE TABLE IF NOT EXISTS refresh_token (
     id SERIAL PRIMARY KEY,
-    token TEXT NOT NULL,
+    jti TEXT NOT NULL UNIQUE,
+    family_id TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
     user_id INT NOT NULL,
-    platform token_platform_enum NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    rotated_at TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
     FOREIGN KEY (user_id) REFERENCES auth_users(id) ON DELETE CASCADE,
-    CONSTRAINT unique_user_platform UNIQUE (user_id, platform)
+    FOREIGN KEY (family_id) REFERENCES device_sessions(id) ON DELETE CASCADE
 );
 
 CREATE INDEX idx_refresh_token_user_id ON refresh_token (user_id);
-CREATE INDEX idx_refresh_token_user_id_token ON refresh_token (user_id, token);
+CREATE INDEX idx_refresh_token_family_id ON refresh_token (family_id);
+
+-- события безопасности (повторное использование refresh токена и т.п.)
+CREATE TABLE IF NOT EXISTS security_events (
+    id SERIAL PRIMARY KEY,
+    user_id INT REFERENCES auth_users(id) ON DELETE CASCADE,
+    type VARCHAR(64) NOT NULL,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_security_events_user_id ON security_events (user_id, created_at);
+
+-- выполненные разовые обновления существующих баз (migrations/). В новой базе обновлять нечего,
+-- поэтому они сразу отмечаются выполненными и при случайном запуске ничего не меняют
+CREATE TABLE IF NOT EXISTS schema_upgrades (
+    name TEXT PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_upgrades (name) VALUES ('activate_existing_accounts') ON CONFLICT (name) DO NOTHING;
+
+-- подтверждение email: хранится только sha256 токена из письма, одна активная ссылка на пользователя
+CREATE TABLE IF NOT EXISTS email_verifications (
+    user_id INT PRIMARY KEY REFERENCES auth_users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- сброс пароля: как и для подтверждения email, хранится только sha256 токена, одна активная ссылка на пользователя
+CREATE TABLE IF NOT EXISTS password_resets (
+    user_id INT PRIMARY KEY REFERENCES auth_users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- двухфакторная аутентификация (TOTP): секрет появляется при подключении, totp_enabled — после подтверждения кодом.
+-- totp_last_step — период последнего принятого кода, чтобы один код нельзя было использовать дважды
+ALTER TABLE auth_users ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+ALTER TABLE auth_users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE auth_users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;
+
+-- одноразовые коды восстановления на случай потери устройства, хранится только sha256
+CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES auth_users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_totp_recovery_codes_user_id ON totp_recovery_codes (user_id);
+
+-- вход через внешних OIDC провайдеров: привязка учётной записи провайдера (provider, subject) к пользователю
+CREATE TABLE IF NOT EXISTS oidc_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES auth_users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(130),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_oidc_identities_user_id ON oidc_identities (user_id);
+
+-- начатые входы через OIDC до возврата пользователя от провайдера: хранятся только sha256 state
+-- и binding (секрета из cookie браузера, начавшего вход).
+-- link_user_id задан, если пользователь привязывает провайдера к своему аккаунту, а не входит
+CREATE TABLE IF NOT EXISTS oidc_auth_requests (
+    state_hash TEXT PRIMARY KEY,
+    binding_hash TEXT NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    nonce TEXT NOT NULL,
+    code_verifier TEXT NOT NULL,
+    platform token_platform_enum NOT NULL,
+    link_user_id INT REFERENCES auth_users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- ключи пользователей (используются сервисом токенов); таблица создавалась вне этого скрипта
+CREATE TABLE IF NOT EXISTS user_keys (
+    user_id INT PRIMARY KEY REFERENCES auth_users(id) ON DELETE CASCADE,
+    user_key TEXT NOT NULL
+);
+
+-- удаление аккаунта: запрос выполняется после scheduled_at (льготный период) по шагам, step — последний выполненный шаг,
+-- поэтому после сбоя удаление продолжается с места остановки. locked_until — аренда записи одним экземпляром сервиса
+-- или время следующей попытки после ошибки. Запись переживает пользователя: в receipt — итог удаления,
+-- email хранится только до отправки квитанции, квитанцию можно получить по токену (хранится sha256)
+CREATE TABLE IF NOT EXISTS account_deletions (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    email VARCHAR(130),
+    token_hash TEXT NOT NULL UNIQUE,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'completed', 'cancelled')),
+    step VARCHAR(32) NOT NULL DEFAULT '',
+    requested_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    scheduled_at TIMESTAMPTZ NOT NULL,
+    completed_at TIMESTAMPTZ,
+    locked_until TIMESTAMPTZ,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    receipt JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_account_deletions_pending_user ON account_deletions (user_id) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_account_deletions_due ON account_deletions (scheduled_at) WHERE status = 'pending';
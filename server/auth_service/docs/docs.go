@@ -24,9 +24,159 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "JWK Set (RFC 7517) с открытыми RSA ключами. Токены подписываются RS256, kid в заголовке токена указывает ключ.\nВо время ротации в наборе несколько ключей: текущий (первый), прежний и/или следующий.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "token"
+                ],
+                "summary": "Открытые ключи подписи JWT",
+                "responses": {
+                    "200": {
+                        "description": "Набор ключей",
+                        "schema": {
+                            "$ref": "#/definitions/keyset.JWKS"
+                        }
+                    }
+                }
+            }
+        },
+        "/admin/users/{id}/unlock": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Снимает блокировку входа после серии неудачных попыток и обнуляет счётчик неудач. Только для администратора (claim role = admin).\nСобытие пишется в security_events.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Снятие блокировки входа",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {access_token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "unlocked — была ли блокировка или неудачные попытки",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нет access‑токена или он недействителен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Нет прав администратора",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/token/revoke": {
+            "post": {
+                "description": "Добавляет access‑токен в список отзыва: secure_comm_service перестаёт его принимать, не дожидаясь ACCESS_TOKEN_TTL.\nНужен, если токен мог утечь. Остальные токены сессии продолжают действовать — для выхода используйте /user/logout или /user/devices.\nУже истёкший токен считается отозванным.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "token"
+                ],
+                "summary": "Отзыв access‑токена",
+                "parameters": [
+                    {
+                        "description": "access_token",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.RevokeTokenDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Токен отозван"
+                    },
+                    "400": {
+                        "description": "Не access‑токен или токен с чужой подписью",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
         "/token/update": {
             "post": {
-                "description": "Берёт refresh‑токен из Cookie и генерирует новые refresh- и access‑токены.\nКлиент должен вызывать этот endpoint при получении HTTP 401 (реализуйте interceptor на клиенте).",
+                "description": "Берёт refresh‑токен из Cookie и генерирует новые refresh- и access‑токены.\nRefresh‑токен одноразовый: после обновления старый токен недействителен, новый приходит в Cookie.\nПовторное использование уже обменянного токена отзывает все токены этого входа (нужно войти заново).\nКлиент должен вызывать этот endpoint при получении HTTP 401 (реализуйте interceptor на клиенте).",
                 "consumes": [
                     "application/json"
                 ],
@@ -57,17 +207,1249 @@ const docTemplate = `{
                         }
                     },
                     "401": {
-                        "description": "Unauthorized — отсутствует или невалидный refresh‑токен",
+                        "description": "Unauthorized — отсутствует, невалидный, отозванный или уже использованный refresh‑токен",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/2fa/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Включает 2FA, если код из приложения подходит к секрету из /user/2fa/enroll.\nВозвращает коды восстановления — они показываются только один раз.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "2fa"
+                ],
+                "summary": "Подтверждение 2FA",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {access_token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Код из приложения",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ConfirmTOTPDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "recovery_codes",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "array",
+                                "items": {
+                                    "type": "string"
+                                }
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный код или 2FA не подключалась",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нет access‑токена или он недействителен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Неверный код",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "2FA уже включена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/2fa/disable": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Выключает 2FA и удаляет коды восстановления. Требует пароль и код из приложения или код восстановления.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "2fa"
+                ],
+                "summary": "Отключение 2FA",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {access_token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Пароль и code или recovery_code",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.DisableTOTPDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "2FA выключена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или 2FA не включена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нет access‑токена или он недействителен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Неверный пароль, код или код восстановления",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много неудачных попыток",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/2fa/enroll": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Создаёт секрет TOTP и otpauth URI (для QR-кода). 2FA включится после подтверждения кодом через /user/2fa/confirm.\nПовторный вызов до подтверждения заменяет секрет.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "2fa"
+                ],
+                "summary": "Подключение 2FA",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {access_token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "secret и otpauth_uri",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нет access‑токена или он недействителен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "2FA уже включена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/account/delete": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Ставит аккаунт в очередь на удаление. После льготного периода (ACCOUNT_DELETION_GRACE_PERIOD) войти в аккаунт нельзя,\nа фоновая задача завершает все сессии, удаляет файлы, учёт квот и ключи в secure_comm_service и сам аккаунт.\nТребует пароль и, если включена 2FA, code или recovery_code. receipt_token (он же приходит письмом) нужен для проверки состояния и получения квитанции.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Удаление аккаунта",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {access_token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Пароль и code или recovery_code",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.AccountDeletionDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "deletion_id, scheduled_at, receipt_token",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нет access‑токена или он недействителен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Неверный пароль, код или код восстановления",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Удаление уже запрошено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много неудачных попыток",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/account/delete/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Отменяет запрошенное удаление, пока не истёк льготный период.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Отмена удаления аккаунта",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {access_token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Удаление отменено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нет access‑токена или он недействителен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Нет удаления, которое можно отменить",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/account/deletion/{token}": {
+            "get": {
+                "description": "Возвращает состояние удаления по receipt_token. После завершения receipt содержит квитанцию:\nвремя каждого шага и число удалённых сессий, файлов (и их объём) и записей в базах.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "account"
+                ],
+                "summary": "Состояние удаления аккаунта",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "receipt_token из /user/account/delete",
+                        "name": "token",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Состояние удаления",
+                        "schema": {
+                            "$ref": "#/definitions/dto.AccountDeletionStatusDTO"
+                        }
+                    },
+                    "404": {
+                        "description": "Удаление не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/devices": {
+            "get": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Возвращает действующие сессии устройств пользователя: имя устройства, user agent, IP, время входа и последнего обновления токенов.\nСессия, которой выдан access‑токен запроса, помечена current = true.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "devices"
+                ],
+                "summary": "Список устройств",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {access_token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сессии устройств",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.DeviceSessionDTO"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нет access‑токена или он недействителен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Завершает все сессии пользователя, включая текущую: refresh‑токены отзываются,\nа все access‑токены, выпущенные до этого момента, secure_comm_service больше не принимает.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "devices"
+                ],
+                "summary": "Выход на всех устройствах",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {access_token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Сессии завершены"
+                    },
+                    "401": {
+                        "description": "Нет access‑токена или он недействителен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/devices/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Завершает сессию устройства: её refresh‑токены отзываются, а access‑токены попадают в список отзыва и secure_comm_service их больше не принимает.\nМожно завершить и текущую сессию — это то же самое, что logout.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "devices"
+                ],
+                "summary": "Выход на устройстве",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {access_token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID сессии из /user/devices",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Сессия завершена"
+                    },
+                    "401": {
+                        "description": "Нет access‑токена или он недействителен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия не найдена или уже завершена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/email/confirm": {
+            "post": {
+                "description": "Подтверждает email по токену из письма, отправленного при регистрации. Токен одноразовый.\nПодтверждение попадёт в access‑токен (claim email_verified) при следующем входе или обновлении токена.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Подтверждение email",
+                "parameters": [
+                    {
+                        "description": "Токен из письма",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ConfirmEmailDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "email подтверждён",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error – некорректный запрос, токен недействителен или истёк",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/user/email/resend": {
+            "post": {
+                "description": "Отправляет новое письмо подтверждения, предыдущая ссылка перестаёт действовать.\nОтвет одинаковый для любых адресов, чтобы нельзя было проверить, зарегистрирован ли email.\nНе чаще одного письма на пользователя за EMAIL_VERIFY_RESEND_INTERVAL, дополнительно ограничено по IP.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Повторная отправка письма подтверждения",
+                "parameters": [
+                    {
+                        "description": "Email",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ResendVerificationDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Письмо отправлено, если адрес зарегистрирован и не подтверждён",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error – некорректный email",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком частые запросы",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
+                    }
+                }
+            }
+        },
+        "/user/login": {
+            "post": {
+                "description": "Логин по email и паролю.\nВ зависимости от поля ` + "`" + `platform` + "`" + ` в запросе возвращаются разные данные:\nДля platform=\"tg-bot\":\naccess_token\nrefresh_token\nk_enc(Base64)\nk_mac(Base64)\n\nДля platform=\"web\":\naccess_token\nks(JSON-объект с полями ` + "`" + `k_enc_iv` + "`" + `, ` + "`" + `k_enc_data` + "`" + `, ` + "`" + `k_mac_iv` + "`" + `, ` + "`" + `k_mac_data` + "`" + `)\n\nЕсли у пользователя включена 2FA, токены не выдаются: ответ {two_factor_required: true, challenge_token},\nвход завершается через /user/login/2fa с кодом из приложения.\n\nПосле LOGIN_LOCKOUT_THRESHOLD неудачных попыток подряд вход в аккаунт блокируется (с любого IP) на время,\nкоторое удваивается с каждой следующей неудачей; пользователю приходит письмо.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Аутентификация пользователя",
+                "parameters": [
+                    {
+                        "description": "Email, Password и Platform (web или tg-bot)",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.LogInDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Поля ответа зависят от платформы (см. описание выше)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad request или неверный формат platform",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "incorrect password or email",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "user not found",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много неудачных попыток с этого IP или вход в аккаунт временно заблокирован (retry_after — секунды)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/login/2fa": {
+            "post": {
+                "description": "Принимает challenge_token из /user/login и 6-значный код из приложения-аутентификатора либо одноразовый код восстановления.\nОтвет такой же, как у /user/login без 2FA. Для web нужен password — им расшифровываются ks.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Второй шаг входа с 2FA",
+                "parameters": [
+                    {
+                        "description": "challenge_token, code или recovery_code, password (для web)",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.LoginTwoFactorDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Поля ответа зависят от платформы (см. /user/login)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "challenge_token недействителен или истёк",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Неверный код, код восстановления или пароль",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "429": {
+                        "description": "Слишком много неудачных попыток с этого IP или вход в аккаунт временно заблокирован (retry_after — секунды)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/logout": {
+            "post": {
+                "description": "Отзывает refresh-токен. Для web берёт токен из cookie, для tg-bot — из JSON body. Для веба не надо передавать refresh_token в json body",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Выход (logout)",
+                "parameters": [
+                    {
+                        "description": "{",
+                        "name": "body",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlerUsers.LogoutRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "error — некорректный запрос или платформа не поддерживается",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized — отсутствует или невалидный токен",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/oidc/{provider}/callback": {
+            "post": {
+                "description": "Принимает code и state, с которыми провайдер вернул пользователя. state одноразовый и действует OIDC_STATE_TTL.\nНужна cookie oidc_binding, поставленная /start или /link в том же браузере, иначе 400.\nОтвет такой же, как у /user/login: токены (для web — ks, зашифрованные ks_key вместо пароля) или {two_factor_required: true, challenge_token}.\nДля web с 2FA в /user/login/2fa в поле password передаётся тот же ks_key.\nЕсли учётная запись провайдера ни к кому не привязана, создаётся новый аккаунт; если её email уже зарегистрирован — 409,\nнужно войти паролем и привязать провайдера через /user/oidc/{provider}/link. При привязке ответ {linked: true}.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Завершение входа через OIDC провайдера",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {access_token} — обязателен при привязке",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Имя провайдера из OIDC_PROVIDERS",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "code, state, ks_key (для web)",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.OIDCCallbackDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Поля ответа зависят от платформы (см. /user/login)",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос, state недействителен или истёк, нет cookie oidc_binding этого входа",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Провайдер не подтвердил вход; при привязке — нет access‑токена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Привязку начал другой пользователь",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Провайдер не настроен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Email уже зарегистрирован или учётная запись привязана к другому аккаунту",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/oidc/{provider}/link": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Как /user/oidc/{provider}/start, но после возврата от провайдера его учётная запись привязывается к аккаунту из access‑токена,\nи дальше через провайдера можно входить в этот аккаунт. Callback в этом случае отвечает {linked: true}\nи требует access‑токен того же пользователя и cookie oidc_binding из ответа этого запроса.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Привязка OIDC провайдера к аккаунту",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {access_token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Имя провайдера из OIDC_PROVIDERS",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "authorization_url",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нет access‑токена или он недействителен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Провайдер не настроен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/oidc/{provider}/start": {
+            "post": {
+                "description": "Возвращает authorization_url страницы входа провайдера (authorization code flow с PKCE), куда клиент перенаправляет пользователя.\nПосле входа провайдер вернёт пользователя на OIDC_REDIRECT_URL/{provider} с параметрами code и state, которые клиент передаёт в /user/oidc/{provider}/callback.\nОтвет ставит HttpOnly cookie oidc_binding: callback принимается только вместе с ней, то есть из того же браузера.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "oidc"
+                ],
+                "summary": "Начало входа через OIDC провайдера",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя провайдера из OIDC_PROVIDERS",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Platform (web или tg-bot)",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.OIDCStartDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "authorization_url",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Провайдер не настроен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/password/change": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Меняет пароль после проверки текущего. Все refresh‑токены пользователя, включая текущий, отзываются,\nего сессии в secure_comm_service сбрасываются — нужно войти заново. Для web refresh‑cookie удаляется.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Смена пароля",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {access_token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Текущий и новый пароль (от 6 символов)",
+                        "name": "body",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ChangePasswordDTO"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пароль изменён",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "error – новый пароль короче 6 символов",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нет access‑токена или он недействителен",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Неверный текущий пароль",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
+                    },
+                    "500": {
+                        "description": "Internal Server Error"
                     }
                 }
             }
         },
-        "/user/login": {
+        "/user/password/forgot": {
             "post": {
-                "description": "Логин по email и паролю.\nВ зависимости от поля ` + "`" + `platform` + "`" + ` в запросе возвращаются разные данные:\nДля platform=\"tg-bot\":\naccess_token\nrefresh_token\nk_enc(Base64)\nk_mac(Base64)\n\nДля platform=\"web\":\naccess_token\nks(JSON-объект с полями ` + "`" + `k_enc_iv` + "`" + `, ` + "`" + `k_enc_data` + "`" + `, ` + "`" + `k_mac_iv` + "`" + `, ` + "`" + `k_mac_data` + "`" + `)",
+                "description": "Отправляет на email одноразовую ссылку сброса пароля (срок жизни PASSWORD_RESET_TTL), предыдущая ссылка перестаёт действовать.\nОтвет одинаковый для любых адресов, чтобы нельзя было проверить, зарегистрирован ли email.",
                 "consumes": [
                     "application/json"
                 ],
@@ -77,28 +1459,21 @@ const docTemplate = `{
                 "tags": [
                     "users"
                 ],
-                "summary": "Аутентификация пользователя",
+                "summary": "Запрос сброса пароля",
                 "parameters": [
                     {
-                        "description": "Email, Password и Platform (web или tg-bot)",
+                        "description": "Email",
                         "name": "body",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.LogInDTO"
+                            "$ref": "#/definitions/dto.ForgotPasswordDTO"
                         }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Поля ответа зависят от платформы (см. описание выше)",
-                        "schema": {
-                            "type": "object",
-                            "additionalProperties": true
-                        }
-                    },
-                    "400": {
-                        "description": "Bad request или неверный формат platform",
+                    "202": {
+                        "description": "Письмо отправлено, если адрес зарегистрирован",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -106,8 +1481,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "403": {
-                        "description": "incorrect password or email",
+                    "400": {
+                        "description": "error – некорректный email",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -115,8 +1490,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "404": {
-                        "description": "user not found",
+                    "429": {
+                        "description": "Слишком частые запросы",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -125,17 +1500,14 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "string"
-                        }
+                        "description": "Internal Server Error"
                     }
                 }
             }
         },
-        "/user/logout": {
+        "/user/password/reset": {
             "post": {
-                "description": "Отзывает refresh-токен. Для web берёт токен из cookie, для tg-bot — из JSON body. Для веба не надо передавать refresh_token в json body",
+                "description": "Устанавливает новый пароль по одноразовому токену из письма. Все refresh‑токены пользователя отзываются,\nего сессии в secure_comm_service сбрасываются — нужно войти заново. Email при этом считается подтверждённым.",
                 "consumes": [
                     "application/json"
                 ],
@@ -145,26 +1517,30 @@ const docTemplate = `{
                 "tags": [
                     "users"
                 ],
-                "summary": "Выход (logout)",
+                "summary": "Сброс пароля по ссылке из письма",
                 "parameters": [
                     {
-                        "description": "{",
+                        "description": "Токен из письма и новый пароль (от 6 символов)",
                         "name": "body",
                         "in": "body",
+                        "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlerUsers.LogoutRequest"
+                            "$ref": "#/definitions/dto.ResetPasswordDTO"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "Пароль изменён",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "400": {
-                        "description": "error — некорректный запрос или платформа не поддерживается",
+                        "description": "error – некорректный пароль, токен недействителен или истёк",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -172,17 +1548,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized — отсутствует или невалидный токен",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
                     "500": {
-                        "description": "Internal Server Error",
-                        "schema": {
-                            "type": "string"
-                        }
+                        "description": "Internal Server Error"
                     }
                 }
             }
@@ -247,6 +1614,148 @@ const docTemplate = `{
         }
     },
     "definitions": {
+        "dto.AccountDeletionDTO": {
+            "type": "object",
+            "required": [
+                "password"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "recovery_code": {
+                    "type": "string",
+                    "maxLength": 32
+                }
+            }
+        },
+        "dto.AccountDeletionStatusDTO": {
+            "type": "object",
+            "properties": {
+                "completed_at": {
+                    "type": "string"
+                },
+                "deletion_id": {
+                    "type": "integer"
+                },
+                "receipt": {
+                    "type": "object"
+                },
+                "requested_at": {
+                    "type": "string"
+                },
+                "scheduled_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending, completed или cancelled",
+                    "type": "string"
+                },
+                "step": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ChangePasswordDTO": {
+            "type": "object",
+            "required": [
+                "current_password",
+                "new_password"
+            ],
+            "properties": {
+                "current_password": {
+                    "type": "string"
+                },
+                "new_password": {
+                    "type": "string",
+                    "minLength": 6
+                }
+            }
+        },
+        "dto.ConfirmEmailDTO": {
+            "type": "object",
+            "required": [
+                "token"
+            ],
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ConfirmTOTPDTO": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.DeviceSessionDTO": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "current": {
+                    "description": "сессия, которой выдан access токен запроса",
+                    "type": "boolean"
+                },
+                "device_name": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "ip": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "string"
+                },
+                "platform": {
+                    "type": "string"
+                },
+                "user_agent": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.DisableTOTPDTO": {
+            "type": "object",
+            "required": [
+                "password"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                },
+                "recovery_code": {
+                    "type": "string",
+                    "maxLength": 32
+                }
+            }
+        },
+        "dto.ForgotPasswordDTO": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
         "dto.LogInDTO": {
             "type": "object",
             "required": [
@@ -255,6 +1764,11 @@ const docTemplate = `{
                 "platform"
             ],
             "properties": {
+                "device_name": {
+                    "description": "необязательное имя устройства для списка сессий",
+                    "type": "string",
+                    "maxLength": 100
+                },
                 "email": {
                     "type": "string"
                 },
@@ -267,6 +1781,107 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.LoginTwoFactorDTO": {
+            "type": "object",
+            "required": [
+                "challenge_token"
+            ],
+            "properties": {
+                "challenge_token": {
+                    "type": "string"
+                },
+                "code": {
+                    "type": "string"
+                },
+                "device_name": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "password": {
+                    "description": "обязателен для web: им шифруются ks",
+                    "type": "string"
+                },
+                "recovery_code": {
+                    "type": "string",
+                    "maxLength": 32
+                }
+            }
+        },
+        "dto.OIDCCallbackDTO": {
+            "type": "object",
+            "required": [
+                "code",
+                "state"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "maxLength": 2048
+                },
+                "device_name": {
+                    "type": "string",
+                    "maxLength": 100
+                },
+                "ks_key": {
+                    "description": "обязателен для web: им вместо пароля шифруются ks",
+                    "type": "string",
+                    "maxLength": 128,
+                    "minLength": 16
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.OIDCStartDTO": {
+            "type": "object",
+            "required": [
+                "platform"
+            ],
+            "properties": {
+                "platform": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ResendVerificationDTO": {
+            "type": "object",
+            "required": [
+                "email"
+            ],
+            "properties": {
+                "email": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ResetPasswordDTO": {
+            "type": "object",
+            "required": [
+                "new_password",
+                "token"
+            ],
+            "properties": {
+                "new_password": {
+                    "type": "string",
+                    "minLength": 6
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.RevokeTokenDTO": {
+            "type": "object",
+            "required": [
+                "access_token"
+            ],
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                }
+            }
+        },
         "dto.SignUpDTO": {
             "type": "object",
             "required": [
@@ -275,6 +1890,11 @@ const docTemplate = `{
                 "platform"
             ],
             "properties": {
+                "device_name": {
+                    "description": "необязательное имя устройства для списка сессий",
+                    "type": "string",
+                    "maxLength": 100
+                },
                 "email": {
                     "type": "string"
                 },
@@ -305,6 +1925,40 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "keyset.JWK": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                }
+            }
+        },
+        "keyset.JWKS": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/keyset.JWK"
+                    }
+                }
+            }
         }
     },
     "securityDefinitions": {
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Состояния запроса на удаление аккаунта (account_deletions.status)
+const (
+	DeletionPending   = "pending"
+	DeletionCompleted = "completed"
+	DeletionCancelled = "cancelled"
+)
+
+// AccountDeletion — запрос на удаление аккаунта. Step — последний выполненный шаг удаления,
+// Receipt — JSON квитанции, которая дополняется на каждом шаге
+type AccountDeletion struct {
+	ID          int
+	UserID      int
+	Email       string
+	Status      string
+	Step        string
+	RequestedAt time.Time
+	ScheduledAt time.Time
+	CompletedAt *time.Time
+	Attempts    int
+	LastError   string
+	Receipt     []byte
+}
+
+// DeletionReceipt — квитанция об удалении аккаунта: когда выполнен каждый шаг и сколько удалено
+type DeletionReceipt struct {
+	DeletionID  int                  `json:"deletion_id"`
+	UserID      int                  `json:"user_id"`
+	RequestedAt time.Time            `json:"requested_at"`
+	CompletedAt *time.Time           `json:"completed_at,omitempty"`
+	Steps       map[string]time.Time `json:"steps"`
+	Sessions    int                  `json:"device_sessions"` // завершённые сессии устройств
+	Files       int                  `json:"files"`           // объекты MinIO
+	FileBytes   int64                `json:"file_bytes"`      // их размер
+	StorageRows int64                `json:"storage_db_rows"` // строки учёта в secure_comm_service
+	AuthRows    int64                `json:"auth_db_rows"`    // строки auth_service (без каскадных)
+	SessionKeys bool                 `json:"session_keys"`    // удалены ключи handshake в secure_comm_service
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// LoginAttempt — попытка входа, засчитанная до проверки пароля или кода 2FA
+type LoginAttempt struct {
+	Failures    int64     // номер попытки в серии неудач; 0, если вход заблокирован
+	LockedUntil time.Time // до какого времени заблокирован вход; нулевое, если попытка засчитана
+	// порог уже набран попытками, которые ещё проверяются (или одна попытка после окончания блокировки):
+	// пароль этой попытки не проверяется
+	OverLimit bool
+}
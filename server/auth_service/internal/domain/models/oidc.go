@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// OIDCAuthRequest — начатый вход через OIDC провайдера, ждущий возврата пользователя с code.
+// Вместо state и binding (секрета из cookie браузера, начавшего вход) хранятся их sha256.
+// LinkUserID != 0 — привязка провайдера к аккаунту LinkUserID
+type OIDCAuthRequest struct {
+	StateHash    string
+	BindingHash  string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	Platform     string
+	LinkUserID   int
+	ExpiresAt    time.Time
+}
+
+// OIDCIdentity — учётная запись пользователя у OIDC провайдера
+type OIDCIdentity struct {
+	Provider string
+	Subject  string
+	Email    string
+}
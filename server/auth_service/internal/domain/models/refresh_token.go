@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// RefreshToken — запись о выданном refresh токене. Сам токен не хранится, только его sha256 (TokenHash).
+// Токены одного входа образуют семейство FamilyID (= ID сессии устройства): при обновлении старый токен
+// заменяется новым в том же семействе
+type RefreshToken struct {
+	JTI       string
+	FamilyID  string
+	UserID    int
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+// DeviceInfo — сведения об устройстве, с которого выполнен вход
+type DeviceInfo struct {
+	Name      string
+	UserAgent string
+	IP        string
+}
+
+// DeviceSession — сессия устройства: один вход со своим семейством refresh токенов
+type DeviceSession struct {
+	ID       string
+	UserID   int
+	Platform string
+	DeviceInfo
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+}
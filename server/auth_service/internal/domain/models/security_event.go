@@ -0,0 +1,7 @@
+package models
+
+// Типы событий в security_events, связанные с блокировкой входа
+const (
+	SecurityEventLoginLockout = "login_lockout" // вход заблокирован после серии неудачных попыток
+	SecurityEventLoginUnlock  = "login_unlock"  // администратор снял блокировку
+)
@@ -1,8 +1,16 @@
 package models
 
+// Роли пользователя, передаются в claim role access-токена
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type UserModel struct {
 	ID          int
 	Email       string
 	Password    []byte
 	IsActivated bool
+	Role        string
+	TOTPEnabled bool
 }
@@ -1,13 +1,76 @@
 package dto
 
 type SignUpDTO struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
-	Platform string `json:"platform" validate:"required"`
+	Email      string `json:"email" validate:"required,email"`
+	Password   string `json:"password" validate:"required,min=6"`
+	Platform   string `json:"platform" validate:"required"`
+	DeviceName string `json:"device_name" validate:"max=100"` // необязательное имя устройства для списка сессий
 }
 
 type LogInDTO struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Email      string `json:"email" validate:"required,email"`
+	Password   string `json:"password" validate:"required,min=6"`
+	Platform   string `json:"platform" validate:"required"`
+	DeviceName string `json:"device_name" validate:"max=100"` // необязательное имя устройства для списка сессий
+}
+type ConfirmEmailDTO struct {
+	Token string `json:"token" validate:"required,hexadecimal,len=64"`
+}
+
+type ResendVerificationDTO struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ForgotPasswordDTO struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordDTO struct {
+	Token       string `json:"token" validate:"required,hexadecimal,len=64"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
+}
+
+type ChangePasswordDTO struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6"`
+}
+
+type LoginTwoFactorDTO struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode   string `json:"recovery_code" validate:"required_without=Code,omitempty,max=32"`
+	Password       string `json:"password"` // обязателен для web: им шифруются ks
+	DeviceName     string `json:"device_name" validate:"max=100"`
+}
+
+type ConfirmTOTPDTO struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+type DisableTOTPDTO struct {
+	Password     string `json:"password" validate:"required"`
+	Code         string `json:"code" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" validate:"required_without=Code,omitempty,max=32"`
+}
+
+type RevokeTokenDTO struct {
+	AccessToken string `json:"access_token" validate:"required"`
+}
+
+type OIDCStartDTO struct {
 	Platform string `json:"platform" validate:"required"`
-}
\ No newline at end of file
+}
+
+type OIDCCallbackDTO struct {
+	Code       string `json:"code" validate:"required,max=2048"`
+	State      string `json:"state" validate:"required,hexadecimal,len=64"`
+	KSKey      string `json:"ks_key" validate:"omitempty,min=16,max=128"` // обязателен для web: им вместо пароля шифруются ks
+	DeviceName string `json:"device_name" validate:"max=100"`
+}
+
+// AccountDeletionDTO — подтверждение удаления аккаунта: пароль и, если включена 2FA, code или recovery_code
+type AccountDeletionDTO struct {
+	Password     string `json:"password" validate:"required"`
+	Code         string `json:"code" validate:"omitempty,len=6,numeric"`
+	RecoveryCode string `json:"recovery_code" validate:"omitempty,max=32"`
+}
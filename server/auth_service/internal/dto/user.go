@@ -1,6 +1,11 @@
 package dto
 
-import "github.com/1abobik1/AuthService/internal/domain/models"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+)
 
 type UserDTO struct {
 	Email    string `json:"email" validate:"required,email"`
@@ -13,3 +18,26 @@ func (u *UserDTO) ToModel() *models.UserModel {
 		Password: []byte(u.Password),
 	}
 }
+
+// DeviceSessionDTO — сессия устройства в списке /user/devices
+type DeviceSessionDTO struct {
+	ID         string    `json:"id"`
+	Platform   string    `json:"platform"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Current    bool      `json:"current"` // сессия, которой выдан access токен запроса
+}
+
+// AccountDeletionStatusDTO — состояние удаления аккаунта; receipt заполняется по мере выполнения шагов
+type AccountDeletionStatusDTO struct {
+	ID          int             `json:"deletion_id"`
+	Status      string          `json:"status"` // pending, completed или cancelled
+	Step        string          `json:"step,omitempty"`
+	RequestedAt time.Time       `json:"requested_at"`
+	ScheduledAt time.Time       `json:"scheduled_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+	Receipt     json.RawMessage `json:"receipt,omitempty" swaggertype:"object"`
+}
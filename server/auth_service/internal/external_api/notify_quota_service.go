@@ -6,17 +6,21 @@ import (
 	"time"
 )
 
-// notifyQuotaService делает POST /users/{id}/plan/init и вставляет Bearer-токен
-func NotifyQuotaService(baseURL string, userID int, accessToken string) error {
+// ScopePlanInit — scope сервисного токена для инициализации плана нового пользователя
+const ScopePlanInit = "plans:init"
+
+// NotifyQuotaService делает POST /internal/users/{id}/plan/init с сервисным токеном (scope ScopePlanInit).
+// baseURL — адрес внутреннего API secure_comm_service
+func NotifyQuotaService(baseURL string, userID int, serviceToken string) error {
 	client := &http.Client{Timeout: 5 * time.Second}
-	url := fmt.Sprintf("%s/user/%d/plan/init", baseURL, userID)
+	url := fmt.Sprintf("%s/internal/users/%d/plan/init", baseURL, userID)
 
 	req, err := http.NewRequest(http.MethodPost, url, nil)
 	if err != nil {
 		return fmt.Errorf("new request: %w", err)
 	}
 	//  заголовок авторизации
-	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Authorization", "Bearer "+serviceToken)
 
 	resp, err := client.Do(req)
 	if err != nil {
@@ -0,0 +1,52 @@
+package external_api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ScopeUsersDelete — scope сервисного токена для удаления данных пользователя
+const ScopeUsersDelete = "users:delete"
+
+// PurgeResult — что secure_comm_service удалил при удалении аккаунта
+type PurgeResult struct {
+	Objects     int   `json:"objects"`
+	Bytes       int64 `json:"bytes"`
+	DBRows      int64 `json:"db_rows"`
+	SessionKeys bool  `json:"session_keys"`
+}
+
+// PurgeUserData делает DELETE /internal/users/{id} с сервисным токеном (scope ScopeUsersDelete): secure_comm_service
+// удаляет файлы, учёт и ключи пользователя. Вызов идемпотентен, после ошибки его нужно повторить;
+// вместе с ошибкой возвращается то, что этот вызов успел удалить
+func PurgeUserData(baseURL string, userID int, serviceToken string) (PurgeResult, error) {
+	// удаление файлов занимает время, пропорциональное их числу
+	client := &http.Client{Timeout: 5 * time.Minute}
+	url := fmt.Sprintf("%s/internal/users/%d", baseURL, userID)
+
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		return PurgeResult{}, fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+serviceToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return PurgeResult{}, fmt.Errorf("request to secure_comm_service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var res PurgeResult
+	if resp.StatusCode != http.StatusOK {
+		// при ошибке в теле то, что успели удалить до неё; тела может не быть, тогда удалено ничего не считается
+		_ = json.NewDecoder(resp.Body).Decode(&res)
+		return res, fmt.Errorf("secure_comm_service returned status %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return PurgeResult{}, fmt.Errorf("decode purge result: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,34 @@
+package external_api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ScopeSessionsRevoke — scope сервисного токена для сброса сессий пользователя
+const ScopeSessionsRevoke = "sessions:revoke"
+
+// RevokeUserSessions делает DELETE /internal/users/{id}/sessions с сервисным токеном (scope ScopeSessionsRevoke):
+// secure_comm_service удаляет сессионные ключи пользователя. baseURL — адрес внутреннего API secure_comm_service
+func RevokeUserSessions(baseURL string, userID int, serviceToken string) error {
+	client := &http.Client{Timeout: 5 * time.Second}
+	url := fmt.Sprintf("%s/internal/users/%d/sessions", baseURL, userID)
+
+	req, err := http.NewRequest(http.MethodDelete, url, nil)
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+serviceToken)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request to secure_comm_service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("secure_comm_service returned status %d", resp.StatusCode)
+	}
+	return nil
+}
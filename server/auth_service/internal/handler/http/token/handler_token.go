@@ -1,11 +1,15 @@
 package handlerToken
 
-import "github.com/golang-jwt/jwt/v4"
+import (
+	"context"
+
+	"github.com/1abobik1/AuthService/internal/keyset"
+)
 
 type TokenSeerviceI interface {
-	UpdateAccessToken(refreshToken string) (string, error)
-	UpdateRefreshToken(refreshToken string, userID int, userKey string) (string, error)
-	ValidateRefreshToken(refreshToken string) (bool, jwt.MapClaims, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (string, string, error)
+	RevokeAccessToken(ctx context.Context, accessToken string) error
+	JWKS() keyset.JWKS
 }
 
 type tokenHandler struct {
@@ -0,0 +1,24 @@
+package handlerToken
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// jwksCacheControl — сколько клиенты могут кешировать JWKS. Новый ключ публикуется заранее (JWT_EXTRA_PUBLIC_KEY_PATHS),
+// а на неизвестный kid проверяющие сервисы перечитывают набор сразу
+const jwksCacheControl = "public, max-age=300"
+
+// JWKS
+// @Summary      Открытые ключи подписи JWT
+// @Description  JWK Set (RFC 7517) с открытыми RSA ключами. Токены подписываются RS256, kid в заголовке токена указывает ключ.
+// @Description  Во время ротации в наборе несколько ключей: текущий (первый), прежний и/или следующий.
+// @Tags         token
+// @Produce      json
+// @Success      200  {object}  keyset.JWKS  "Набор ключей"
+// @Router       /.well-known/jwks.json [get]
+func (h *tokenHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", jwksCacheControl)
+	c.JSON(http.StatusOK, h.tokenService.JWKS())
+}
@@ -0,0 +1,51 @@
+package handlerToken
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/1abobik1/AuthService/internal/dto"
+	"github.com/1abobik1/AuthService/pkg/auth/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// TokenRevoke
+// @Summary      Отзыв access‑токена
+// @Description  Добавляет access‑токен в список отзыва: secure_comm_service перестаёт его принимать, не дожидаясь ACCESS_TOKEN_TTL.
+// @Description  Нужен, если токен мог утечь. Остальные токены сессии продолжают действовать — для выхода используйте /user/logout или /user/devices.
+// @Description  Уже истёкший токен считается отозванным.
+// @Tags         token
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.RevokeTokenDTO  true  "access_token"
+// @Success      204   "Токен отозван"
+// @Failure      400   {object}  map[string]string   "Не access‑токен или токен с чужой подписью"
+// @Failure      500   {string}  string              "Internal Server Error"
+// @Router       /token/revoke [post]
+func (h *tokenHandler) TokenRevoke(c *gin.Context) {
+	const op = "handler.http.token.TokenRevoke"
+
+	var req dto.RevokeTokenDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v, location %s", err, op)
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "access_token is required"})
+		return
+	}
+
+	if err := h.tokenService.RevokeAccessToken(c, req.AccessToken); err != nil {
+		if errors.Is(err, validation.ErrTokenInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid access token"})
+			return
+		}
+		log.Printf("Error revoking access token: %v, location %s", err, op)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,126 @@
+package handlerUsers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/1abobik1/AuthService/internal/dto"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// RequestAccountDeletion
+// @Summary      Удаление аккаунта
+// @Description  Ставит аккаунт в очередь на удаление. После льготного периода (ACCOUNT_DELETION_GRACE_PERIOD) войти в аккаунт нельзя,
+// @Description  а фоновая задача завершает все сессии, удаляет файлы, учёт квот и ключи в secure_comm_service и сам аккаунт.
+// @Description  Требует пароль и, если включена 2FA, code или recovery_code. receipt_token (он же приходит письмом) нужен для проверки состояния и получения квитанции.
+// @Tags         account
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer {access_token}"
+// @Param        body           body      dto.AccountDeletionDTO  true  "Пароль и code или recovery_code"
+// @Success      202            {object}  map[string]interface{}  "deletion_id, scheduled_at, receipt_token"
+// @Failure      400            {object}  map[string]string       "Некорректный запрос"
+// @Failure      401            {object}  map[string]string       "Нет access‑токена или он недействителен"
+// @Failure      403            {object}  map[string]string       "Неверный пароль, код или код восстановления"
+// @Failure      409            {object}  map[string]string       "Удаление уже запрошено"
+// @Failure      429            {object}  map[string]string       "Слишком много неудачных попыток"
+// @Failure      500            {string}  string                  "Internal Server Error"
+// @Security     bearerAuth
+// @Router       /user/account/delete [post]
+func (h *userHandler) RequestAccountDeletion(c *gin.Context) {
+	const op = "handler.http.users.RequestAccountDeletion"
+
+	var req dto.AccountDeletionDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v, location %s", err, op)
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password is required, code must be 6 digits"})
+		return
+	}
+
+	deletion, token, err := h.userService.RequestAccountDeletion(c, c.GetInt("user_id"), req.Password, req.Code, req.RecoveryCode)
+	if err != nil {
+		if errors.Is(err, storage.ErrDeletionRequested) {
+			c.JSON(http.StatusConflict, gin.H{"error": storage.ErrDeletionRequested.Error()})
+			return
+		}
+		c.Set("failed_registration", true)
+		writeTwoFactorError(c, err, op)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"deletion_id":   deletion.ID,
+		"scheduled_at":  deletion.ScheduledAt,
+		"receipt_token": token,
+	})
+}
+
+// CancelAccountDeletion
+// @Summary      Отмена удаления аккаунта
+// @Description  Отменяет запрошенное удаление, пока не истёк льготный период.
+// @Tags         account
+// @Produce      json
+// @Param        Authorization  header    string             true  "Bearer {access_token}"
+// @Success      200            {object}  map[string]string  "Удаление отменено"
+// @Failure      401            {object}  map[string]string  "Нет access‑токена или он недействителен"
+// @Failure      404            {object}  map[string]string  "Нет удаления, которое можно отменить"
+// @Failure      500            {string}  string             "Internal Server Error"
+// @Security     bearerAuth
+// @Router       /user/account/delete/cancel [post]
+func (h *userHandler) CancelAccountDeletion(c *gin.Context) {
+	const op = "handler.http.users.CancelAccountDeletion"
+
+	if err := h.userService.CancelAccountDeletion(c, c.GetInt("user_id")); err != nil {
+		if errors.Is(err, storage.ErrDeletionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "no account deletion to cancel"})
+			return
+		}
+		log.Printf("Error cancelling account deletion: %v, location %s", err, op)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "account deletion cancelled"})
+}
+
+// AccountDeletionStatus
+// @Summary      Состояние удаления аккаунта
+// @Description  Возвращает состояние удаления по receipt_token. После завершения receipt содержит квитанцию:
+// @Description  время каждого шага и число удалённых сессий, файлов (и их объём) и записей в базах.
+// @Tags         account
+// @Produce      json
+// @Param        token  path      string                        true  "receipt_token из /user/account/delete"
+// @Success      200    {object}  dto.AccountDeletionStatusDTO  "Состояние удаления"
+// @Failure      404    {object}  map[string]string             "Удаление не найдено"
+// @Failure      500    {string}  string                        "Internal Server Error"
+// @Router       /user/account/deletion/{token} [get]
+func (h *userHandler) AccountDeletionStatus(c *gin.Context) {
+	const op = "handler.http.users.AccountDeletionStatus"
+
+	deletion, err := h.userService.AccountDeletionStatus(c, c.Param("token"))
+	if err != nil {
+		if errors.Is(err, storage.ErrDeletionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": storage.ErrDeletionNotFound.Error()})
+			return
+		}
+		log.Printf("Error finding account deletion: %v, location %s", err, op)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.AccountDeletionStatusDTO{
+		ID:          deletion.ID,
+		Status:      deletion.Status,
+		Step:        deletion.Step,
+		RequestedAt: deletion.RequestedAt,
+		ScheduledAt: deletion.ScheduledAt,
+		CompletedAt: deletion.CompletedAt,
+		Receipt:     deletion.Receipt,
+	})
+}
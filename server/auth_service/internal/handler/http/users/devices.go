@@ -0,0 +1,105 @@
+package handlerUsers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/1abobik1/AuthService/internal/dto"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// ListDevices
+// @Summary      Список устройств
+// @Description  Возвращает действующие сессии устройств пользователя: имя устройства, user agent, IP, время входа и последнего обновления токенов.
+// @Description  Сессия, которой выдан access‑токен запроса, помечена current = true.
+// @Tags         devices
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer {access_token}"
+// @Success      200            {array}   dto.DeviceSessionDTO    "Сессии устройств"
+// @Failure      401            {object}  map[string]string       "Нет access‑токена или он недействителен"
+// @Failure      500            {string}  string                  "Internal Server Error"
+// @Security     bearerAuth
+// @Router       /user/devices [get]
+func (h *userHandler) ListDevices(c *gin.Context) {
+	const op = "handler.http.users.ListDevices"
+
+	sessions, err := h.userService.ListDevices(c, c.GetInt("user_id"))
+	if err != nil {
+		log.Printf("Error listing devices: %v, location %s", err, op)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	current := c.GetString("session_id")
+	out := make([]dto.DeviceSessionDTO, 0, len(sessions))
+	for _, s := range sessions {
+		out = append(out, dto.DeviceSessionDTO{
+			ID:         s.ID,
+			Platform:   s.Platform,
+			DeviceName: s.Name,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			Current:    s.ID == current,
+		})
+	}
+
+	c.JSON(http.StatusOK, out)
+}
+
+// RevokeDevice
+// @Summary      Выход на устройстве
+// @Description  Завершает сессию устройства: её refresh‑токены отзываются, а access‑токены попадают в список отзыва и secure_comm_service их больше не принимает.
+// @Description  Можно завершить и текущую сессию — это то же самое, что logout.
+// @Tags         devices
+// @Produce      json
+// @Param        Authorization  header    string             true  "Bearer {access_token}"
+// @Param        id             path      string             true  "ID сессии из /user/devices"
+// @Success      204            "Сессия завершена"
+// @Failure      401            {object}  map[string]string  "Нет access‑токена или он недействителен"
+// @Failure      404            {object}  map[string]string  "Сессия не найдена или уже завершена"
+// @Failure      500            {string}  string             "Internal Server Error"
+// @Security     bearerAuth
+// @Router       /user/devices/{id} [delete]
+func (h *userHandler) RevokeDevice(c *gin.Context) {
+	const op = "handler.http.users.RevokeDevice"
+
+	if err := h.userService.RevokeDevice(c, c.GetInt("user_id"), c.Param("id")); err != nil {
+		if errors.Is(err, storage.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "device session not found"})
+			return
+		}
+		log.Printf("Error revoking device: %v, location %s", err, op)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeAllDevices
+// @Summary      Выход на всех устройствах
+// @Description  Завершает все сессии пользователя, включая текущую: refresh‑токены отзываются,
+// @Description  а все access‑токены, выпущенные до этого момента, secure_comm_service больше не принимает.
+// @Tags         devices
+// @Produce      json
+// @Param        Authorization  header    string             true  "Bearer {access_token}"
+// @Success      204            "Сессии завершены"
+// @Failure      401            {object}  map[string]string  "Нет access‑токена или он недействителен"
+// @Failure      500            {string}  string             "Internal Server Error"
+// @Security     bearerAuth
+// @Router       /user/devices [delete]
+func (h *userHandler) RevokeAllDevices(c *gin.Context) {
+	const op = "handler.http.users.RevokeAllDevices"
+
+	if err := h.userService.RevokeAllDevices(c, c.GetInt("user_id")); err != nil {
+		log.Printf("Error revoking devices: %v, location %s", err, op)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
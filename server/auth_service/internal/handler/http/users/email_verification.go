@@ -0,0 +1,88 @@
+package handlerUsers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/1abobik1/AuthService/internal/dto"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// ConfirmEmail
+// @Summary      Подтверждение email
+// @Description  Подтверждает email по токену из письма, отправленного при регистрации. Токен одноразовый.
+// @Description  Подтверждение попадёт в access‑токен (claim email_verified) при следующем входе или обновлении токена.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.ConfirmEmailDTO  true  "Токен из письма"
+// @Success      200   {object}  map[string]string    "email подтверждён"
+// @Failure      400   {object}  map[string]string    "error – некорректный запрос, токен недействителен или истёк"
+// @Failure      500   {object}  nil                  "Internal Server Error"
+// @Router       /user/email/confirm [post]
+func (h *userHandler) ConfirmEmail(c *gin.Context) {
+	const op = "handler.http.users.ConfirmEmail"
+
+	var req dto.ConfirmEmailDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v, location %s", err, op)
+		return
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": storage.ErrVerificationNotFound.Error()})
+		return
+	}
+
+	if err := h.userService.ConfirmEmail(c, req.Token); err != nil {
+		if errors.Is(err, storage.ErrVerificationNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": storage.ErrVerificationNotFound.Error()})
+			return
+		}
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "email verified"})
+}
+
+// ResendVerification
+// @Summary      Повторная отправка письма подтверждения
+// @Description  Отправляет новое письмо подтверждения, предыдущая ссылка перестаёт действовать.
+// @Description  Ответ одинаковый для любых адресов, чтобы нельзя было проверить, зарегистрирован ли email.
+// @Description  Не чаще одного письма на пользователя за EMAIL_VERIFY_RESEND_INTERVAL, дополнительно ограничено по IP.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.ResendVerificationDTO  true  "Email"
+// @Success      202   {object}  map[string]string          "Письмо отправлено, если адрес зарегистрирован и не подтверждён"
+// @Failure      400   {object}  map[string]string          "error – некорректный email"
+// @Failure      429   {object}  map[string]string          "Слишком частые запросы"
+// @Failure      500   {object}  nil                        "Internal Server Error"
+// @Router       /user/email/resend [post]
+func (h *userHandler) ResendVerification(c *gin.Context) {
+	const op = "handler.http.users.ResendVerification"
+
+	var req dto.ResendVerificationDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v, location %s", err, op)
+		return
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the email format is incorrect"})
+		return
+	}
+
+	// о частых повторах тоже не сообщаем: иначе ответ выдал бы, что адрес зарегистрирован
+	if err := h.userService.ResendVerification(c, req.Email); err != nil && !errors.Is(err, storage.ErrVerificationThrottled) {
+		log.Printf("Error resending verification: %v, location %s", err, op)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"status": "if the address is registered and not verified, a new email has been sent"})
+}
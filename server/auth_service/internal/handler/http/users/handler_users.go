@@ -3,15 +3,37 @@ package handlerUsers
 import (
 	"context"
 
+	"github.com/1abobik1/AuthService/internal/domain/models"
 	"github.com/1abobik1/AuthService/internal/external_api"
+	serviceUsers "github.com/1abobik1/AuthService/internal/service/users"
+	"github.com/gin-gonic/gin"
 )
 
 const ErrValidation = `the email format is incorrect or the password must be at least 6 characters long. You may have incorrectly specified the "platform" (tg-bot, web).`
 
 type UserServiceI interface {
-	Register(ctx context.Context, email, password, platform string) (accessJWT string, refreshJWT string, er error)
-	Login(ctx context.Context, email, password, platform string) (accessJWT string, refreshJWT string, er error)
+	Register(ctx context.Context, email, password, platform string, device models.DeviceInfo) (accessJWT string, refreshJWT string, er error)
+	Login(ctx context.Context, email, password, platform string, device models.DeviceInfo) (accessJWT string, refreshJWT string, challengeJWT string, er error)
+	LoginTwoFactor(ctx context.Context, challengeJWT, code, recoveryCode, password string, device models.DeviceInfo) (accessJWT string, refreshJWT string, platform string, er error)
+	EnrollTOTP(ctx context.Context, userID int) (secret string, uri string, er error)
+	ConfirmTOTP(ctx context.Context, userID int, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, userID int, password, code, recoveryCode string) error
 	RevokeRefreshToken(ctx context.Context, refreshToken string) error
+	ListDevices(ctx context.Context, userID int) ([]models.DeviceSession, error)
+	RevokeDevice(ctx context.Context, userID int, sessionID string) error
+	RevokeAllDevices(ctx context.Context, userID int) error
+	ConfirmEmail(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, email string) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	ChangePassword(ctx context.Context, userID int, currentPassword, newPassword string) error
+	StartOIDCLogin(ctx context.Context, provider, platform string) (authURL string, binding string, er error)
+	StartOIDCLink(ctx context.Context, userID int, provider string) (authURL string, binding string, er error)
+	OIDCCallback(ctx context.Context, provider, code, state, binding string, userID int, ksKey string, device models.DeviceInfo) (serviceUsers.OIDCLoginResult, error)
+	RequestAccountDeletion(ctx context.Context, userID int, password, code, recoveryCode string) (models.AccountDeletion, string, error)
+	CancelAccountDeletion(ctx context.Context, userID int) error
+	AccountDeletionStatus(ctx context.Context, token string) (models.AccountDeletion, error)
+	UnlockAccount(ctx context.Context, adminID, userID int) (bool, error)
 }
 
 type TGClientKeysI interface {
@@ -35,3 +57,15 @@ func NewUserHandler(userService UserServiceI, tgClient TGClientKeysI, webClient
 		webClient:   webClient,
 	}
 }
+
+// максимальная длина user agent, сохраняемого в сессии устройства (столбец device_sessions.user_agent)
+const maxUserAgentLen = 255
+
+// deviceInfo собирает сведения об устройстве для новой сессии: имя из запроса, User-Agent и IP клиента
+func deviceInfo(c *gin.Context, name string) models.DeviceInfo {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLen {
+		userAgent = userAgent[:maxUserAgentLen]
+	}
+	return models.DeviceInfo{Name: name, UserAgent: userAgent, IP: c.ClientIP()}
+}
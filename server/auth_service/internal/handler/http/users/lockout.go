@@ -0,0 +1,68 @@
+package handlerUsers
+
+import (
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	serviceUsers "github.com/1abobik1/AuthService/internal/service/users"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/gin-gonic/gin"
+)
+
+// UnlockAccount
+// @Summary      Снятие блокировки входа
+// @Description  Снимает блокировку входа после серии неудачных попыток и обнуляет счётчик неудач. Только для администратора (claim role = admin).
+// @Description  Событие пишется в security_events.
+// @Tags         admin
+// @Produce      json
+// @Param        Authorization  header    string                  true  "Bearer {access_token}"
+// @Param        id             path      int                     true  "ID пользователя"
+// @Success      200            {object}  map[string]interface{}  "unlocked — была ли блокировка или неудачные попытки"
+// @Failure      400            {object}  map[string]string       "Некорректный ID"
+// @Failure      401            {object}  map[string]string       "Нет access‑токена или он недействителен"
+// @Failure      403            {object}  map[string]string       "Нет прав администратора"
+// @Failure      404            {object}  map[string]string       "Пользователь не найден"
+// @Failure      500            {string}  string                  "Internal Server Error"
+// @Security     bearerAuth
+// @Router       /admin/users/{id}/unlock [post]
+func (h *userHandler) UnlockAccount(c *gin.Context) {
+	const op = "handler.http.users.UnlockAccount"
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	unlocked, err := h.userService.UnlockAccount(c, c.GetInt("user_id"), userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			return
+		}
+		log.Printf("Error unlocking account: %v, location %s", err, op)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user_id": userID, "unlocked": unlocked})
+}
+
+// writeAccountLocked отвечает 429, если вход в аккаунт заблокирован, в том же формате, что и лимит попыток по IP
+func writeAccountLocked(c *gin.Context, err error) bool {
+	var locked *serviceUsers.AccountLockedError
+	if !errors.As(err, &locked) {
+		return false
+	}
+
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":        serviceUsers.ErrAccountLocked.Error(),
+		"retry_after":  int(math.Ceil(time.Until(locked.Until).Seconds())),
+		"locked_until": locked.Until.UTC(),
+	})
+	return true
+}
@@ -27,6 +27,12 @@ import (
 // @Description  Для platform="web":
 // @Description  access_token
 // @Description  ks(JSON-объект с полями `k_enc_iv`, `k_enc_data`, `k_mac_iv`, `k_mac_data`)
+// @Description
+// @Description  Если у пользователя включена 2FA, токены не выдаются: ответ {two_factor_required: true, challenge_token},
+// @Description  вход завершается через /user/login/2fa с кодом из приложения.
+// @Description
+// @Description  После LOGIN_LOCKOUT_THRESHOLD неудачных попыток подряд вход в аккаунт блокируется (с любого IP) на время,
+// @Description  которое удваивается с каждой следующей неудачей; пользователю приходит письмо.
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -35,6 +41,7 @@ import (
 // @Failure      400   {object}  map[string]string       "Bad request или неверный формат platform"
 // @Failure      403   {object}  map[string]string       "incorrect password or email"
 // @Failure      404   {object}  map[string]string       "user not found"
+// @Failure      429   {object}  map[string]interface{}  "Слишком много неудачных попыток с этого IP или вход в аккаунт временно заблокирован (retry_after — секунды)"
 // @Failure      500   {string}  string                  "Internal Server Error"
 // @Router       /user/login [post]
 func (h *userHandler) Login(c *gin.Context) {
@@ -64,8 +71,11 @@ func (h *userHandler) Login(c *gin.Context) {
 		return
 	}
 
-	accessToken, refreshToken, err := h.userService.Login(c, authDTO.Email, authDTO.Password, authDTO.Platform)
+	accessToken, refreshToken, challenge, err := h.userService.Login(c, authDTO.Email, authDTO.Password, authDTO.Platform, deviceInfo(c, authDTO.DeviceName))
 	if err != nil {
+		if writeAccountLocked(c, err) {
+			return
+		}
 		if errors.Is(err, serviceUsers.ErrInvalidCredentials) {
 			log.Printf("Error: %v", err)
 			c.Set("failed_registration", true)
@@ -87,7 +97,21 @@ func (h *userHandler) Login(c *gin.Context) {
 	ip := c.ClientIP()
 	middleware.FailedAttemptsCache.Delete("fail_" + ip)
 
-	if authDTO.Platform == "tg-bot" {
+	if challenge != "" {
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     challenge,
+		})
+		return
+	}
+
+	h.respondWithTokens(c, authDTO.Platform, authDTO.Password, accessToken, refreshToken)
+}
+
+// respondWithTokens отвечает на успешный вход: tg-боту — токены и ключи сессии,
+// web — access токен и ks, зашифрованные паролем пользователя (refresh в cookie)
+func (h *userHandler) respondWithTokens(c *gin.Context, platform, password, accessToken, refreshToken string) {
+	if platform == "tg-bot" {
 		// если клиент зашел с тг бота
 		kEncB64, kMacB64, err := h.tgClient.GetClientKS(c, accessToken)
 		if err != nil {
@@ -104,7 +128,7 @@ func (h *userHandler) Login(c *gin.Context) {
 		})
 	} else {
 		// если клиент зашел с web сайта, ks передается в зашифрованной ввиде паролем пользователя
-		ksB64, err := h.webClient.GetClientKS(c, authDTO.Password, accessToken)
+		ksB64, err := h.webClient.GetClientKS(c, password, accessToken)
 		if err != nil {
 			c.Set("failed_registration", true)
 			c.JSON(http.StatusBadRequest, gin.H{"error": "error in receiving session key and ecdsa"})
@@ -0,0 +1,173 @@
+package handlerUsers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/1abobik1/AuthService/internal/dto"
+	"github.com/1abobik1/AuthService/internal/middleware"
+	serviceUsers "github.com/1abobik1/AuthService/internal/service/users"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/1abobik1/AuthService/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// StartOIDCLogin
+// @Summary      Начало входа через OIDC провайдера
+// @Description  Возвращает authorization_url страницы входа провайдера (authorization code flow с PKCE), куда клиент перенаправляет пользователя.
+// @Description  После входа провайдер вернёт пользователя на OIDC_REDIRECT_URL/{provider} с параметрами code и state, которые клиент передаёт в /user/oidc/{provider}/callback.
+// @Description  Ответ ставит HttpOnly cookie oidc_binding: callback принимается только вместе с ней, то есть из того же браузера.
+// @Tags         oidc
+// @Accept       json
+// @Produce      json
+// @Param        provider  path      string             true  "Имя провайдера из OIDC_PROVIDERS"
+// @Param        body      body      dto.OIDCStartDTO   true  "Platform (web или tg-bot)"
+// @Success      200       {object}  map[string]string  "authorization_url"
+// @Failure      400       {object}  map[string]string  "Некорректный запрос"
+// @Failure      404       {object}  map[string]string  "Провайдер не настроен"
+// @Failure      500       {string}  string             "Internal Server Error"
+// @Router       /user/oidc/{provider}/start [post]
+func (h *userHandler) StartOIDCLogin(c *gin.Context) {
+	const op = "handler.http.users.StartOIDCLogin"
+
+	var req dto.OIDCStartDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v, location %s", err, op)
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "platform is required"})
+		return
+	}
+	if err := utils.ValidatePlatform(req.Platform); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the error checking platform. available platforms: web, tg-bot"})
+		return
+	}
+
+	authURL, binding, err := h.userService.StartOIDCLogin(c, c.Param("provider"), req.Platform)
+	if err != nil {
+		writeOIDCError(c, err, op)
+		return
+	}
+	utils.SetOIDCBindingCookie(c, binding)
+
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// StartOIDCLink
+// @Summary      Привязка OIDC провайдера к аккаунту
+// @Description  Как /user/oidc/{provider}/start, но после возврата от провайдера его учётная запись привязывается к аккаунту из access‑токена,
+// @Description  и дальше через провайдера можно входить в этот аккаунт. Callback в этом случае отвечает {linked: true}
+// @Description  и требует access‑токен того же пользователя и cookie oidc_binding из ответа этого запроса.
+// @Tags         oidc
+// @Produce      json
+// @Param        Authorization  header    string             true  "Bearer {access_token}"
+// @Param        provider       path      string             true  "Имя провайдера из OIDC_PROVIDERS"
+// @Success      200            {object}  map[string]string  "authorization_url"
+// @Failure      401            {object}  map[string]string  "Нет access‑токена или он недействителен"
+// @Failure      404            {object}  map[string]string  "Провайдер не настроен"
+// @Failure      500            {string}  string             "Internal Server Error"
+// @Security     bearerAuth
+// @Router       /user/oidc/{provider}/link [post]
+func (h *userHandler) StartOIDCLink(c *gin.Context) {
+	const op = "handler.http.users.StartOIDCLink"
+
+	authURL, binding, err := h.userService.StartOIDCLink(c, c.GetInt("user_id"), c.Param("provider"))
+	if err != nil {
+		writeOIDCError(c, err, op)
+		return
+	}
+	utils.SetOIDCBindingCookie(c, binding)
+
+	c.JSON(http.StatusOK, gin.H{"authorization_url": authURL})
+}
+
+// OIDCCallback
+// @Summary      Завершение входа через OIDC провайдера
+// @Description  Принимает code и state, с которыми провайдер вернул пользователя. state одноразовый и действует OIDC_STATE_TTL.
+// @Description  Нужна cookie oidc_binding, поставленная /start или /link в том же браузере, иначе 400.
+// @Description  Ответ такой же, как у /user/login: токены (для web — ks, зашифрованные ks_key вместо пароля) или {two_factor_required: true, challenge_token}.
+// @Description  Для web с 2FA в /user/login/2fa в поле password передаётся тот же ks_key.
+// @Description  Если учётная запись провайдера ни к кому не привязана, создаётся новый аккаунт; если её email уже зарегистрирован — 409,
+// @Description  нужно войти паролем и привязать провайдера через /user/oidc/{provider}/link. При привязке ответ {linked: true}.
+// @Tags         oidc
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                  false  "Bearer {access_token} — обязателен при привязке"
+// @Param        provider  path      string                  true  "Имя провайдера из OIDC_PROVIDERS"
+// @Param        body      body      dto.OIDCCallbackDTO     true  "code, state, ks_key (для web)"
+// @Success      200       {object}  map[string]interface{}  "Поля ответа зависят от платформы (см. /user/login)"
+// @Failure      400       {object}  map[string]string       "Некорректный запрос, state недействителен или истёк, нет cookie oidc_binding этого входа"
+// @Failure      401       {object}  map[string]string       "Провайдер не подтвердил вход; при привязке — нет access‑токена"
+// @Failure      403       {object}  map[string]string       "Привязку начал другой пользователь"
+// @Failure      404       {object}  map[string]string       "Провайдер не настроен"
+// @Failure      409       {object}  map[string]string       "Email уже зарегистрирован или учётная запись привязана к другому аккаунту"
+// @Failure      500       {string}  string                  "Internal Server Error"
+// @Router       /user/oidc/{provider}/callback [post]
+func (h *userHandler) OIDCCallback(c *gin.Context) {
+	const op = "handler.http.users.OIDCCallback"
+
+	var req dto.OIDCCallbackDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v, location %s", err, op)
+		c.Set("failed_registration", true)
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		c.Set("failed_registration", true)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code and state are required, ks_key must be 16-128 characters long"})
+		return
+	}
+
+	binding := utils.PopOIDCBindingCookie(c)
+	res, err := h.userService.OIDCCallback(c, c.Param("provider"), req.Code, req.State, binding, c.GetInt("user_id"), req.KSKey, deviceInfo(c, req.DeviceName))
+	if err != nil {
+		c.Set("failed_registration", true)
+		writeOIDCError(c, err, op)
+		return
+	}
+
+	middleware.FailedAttemptsCache.Delete("fail_" + c.ClientIP())
+
+	switch {
+	case res.Linked:
+		c.JSON(http.StatusOK, gin.H{"linked": true})
+	case res.ChallengeToken != "":
+		c.JSON(http.StatusOK, gin.H{
+			"two_factor_required": true,
+			"challenge_token":     res.ChallengeToken,
+		})
+	default:
+		h.respondWithTokens(c, res.Platform, req.KSKey, res.AccessToken, res.RefreshToken)
+	}
+}
+
+func writeOIDCError(c *gin.Context, err error, op string) {
+	switch {
+	case errors.Is(err, serviceUsers.ErrUnknownOIDCProvider):
+		c.JSON(http.StatusNotFound, gin.H{"error": serviceUsers.ErrUnknownOIDCProvider.Error()})
+	case errors.Is(err, storage.ErrOIDCStateNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": storage.ErrOIDCStateNotFound.Error()})
+	case errors.Is(err, serviceUsers.ErrKSKeyRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": serviceUsers.ErrKSKeyRequired.Error()})
+	case errors.Is(err, serviceUsers.ErrOIDCEmailMissing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": serviceUsers.ErrOIDCEmailMissing.Error()})
+	case errors.Is(err, serviceUsers.ErrOIDCLoginFailed):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": serviceUsers.ErrOIDCLoginFailed.Error()})
+	case errors.Is(err, serviceUsers.ErrOIDCAccountExists):
+		c.JSON(http.StatusConflict, gin.H{"error": serviceUsers.ErrOIDCAccountExists.Error()})
+	case errors.Is(err, serviceUsers.ErrOIDCBrowserMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": serviceUsers.ErrOIDCBrowserMismatch.Error()})
+	case errors.Is(err, serviceUsers.ErrOIDCLinkAuthRequired):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": serviceUsers.ErrOIDCLinkAuthRequired.Error()})
+	case errors.Is(err, serviceUsers.ErrOIDCLinkUserMismatch):
+		c.JSON(http.StatusForbidden, gin.H{"error": serviceUsers.ErrOIDCLinkUserMismatch.Error()})
+	case errors.Is(err, storage.ErrOIDCIdentityLinked):
+		c.JSON(http.StatusConflict, gin.H{"error": storage.ErrOIDCIdentityLinked.Error()})
+	default:
+		log.Printf("Error: %v, location %s", err, op)
+		c.Status(http.StatusInternalServerError)
+	}
+}
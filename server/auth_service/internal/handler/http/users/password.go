@@ -0,0 +1,134 @@
+package handlerUsers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/1abobik1/AuthService/internal/dto"
+	serviceUsers "github.com/1abobik1/AuthService/internal/service/users"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// ForgotPassword
+// @Summary      Запрос сброса пароля
+// @Description  Отправляет на email одноразовую ссылку сброса пароля (срок жизни PASSWORD_RESET_TTL), предыдущая ссылка перестаёт действовать.
+// @Description  Ответ одинаковый для любых адресов, чтобы нельзя было проверить, зарегистрирован ли email.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.ForgotPasswordDTO  true  "Email"
+// @Success      202   {object}  map[string]string      "Письмо отправлено, если адрес зарегистрирован"
+// @Failure      400   {object}  map[string]string      "error – некорректный email"
+// @Failure      429   {object}  map[string]string      "Слишком частые запросы"
+// @Failure      500   {object}  nil                    "Internal Server Error"
+// @Router       /user/password/forgot [post]
+func (h *userHandler) ForgotPassword(c *gin.Context) {
+	const op = "handler.http.users.ForgotPassword"
+
+	var req dto.ForgotPasswordDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v, location %s", err, op)
+		return
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the email format is incorrect"})
+		return
+	}
+
+	// о частых повторах не сообщаем: иначе ответ выдал бы, что адрес зарегистрирован
+	if err := h.userService.ForgotPassword(c, req.Email); err != nil && !errors.Is(err, storage.ErrResetThrottled) {
+		log.Printf("Error sending password reset: %v, location %s", err, op)
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"status": "if the address is registered, a password reset email has been sent"})
+}
+
+// ResetPassword
+// @Summary      Сброс пароля по ссылке из письма
+// @Description  Устанавливает новый пароль по одноразовому токену из письма. Все refresh‑токены пользователя отзываются,
+// @Description  его сессии в secure_comm_service сбрасываются — нужно войти заново. Email при этом считается подтверждённым.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.ResetPasswordDTO  true  "Токен из письма и новый пароль (от 6 символов)"
+// @Success      200   {object}  map[string]string     "Пароль изменён"
+// @Failure      400   {object}  map[string]string     "error – некорректный пароль, токен недействителен или истёк"
+// @Failure      500   {object}  nil                   "Internal Server Error"
+// @Router       /user/password/reset [post]
+func (h *userHandler) ResetPassword(c *gin.Context) {
+	const op = "handler.http.users.ResetPassword"
+
+	var req dto.ResetPasswordDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v, location %s", err, op)
+		return
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid token or the password must be at least 6 characters long"})
+		return
+	}
+
+	if err := h.userService.ResetPassword(c, req.Token, req.NewPassword); err != nil {
+		if errors.Is(err, storage.ErrResetNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": storage.ErrResetNotFound.Error()})
+			return
+		}
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "password changed, log in again"})
+}
+
+// ChangePassword
+// @Summary      Смена пароля
+// @Description  Меняет пароль после проверки текущего. Все refresh‑токены пользователя, включая текущий, отзываются,
+// @Description  его сессии в secure_comm_service сбрасываются — нужно войти заново. Для web refresh‑cookie удаляется.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string                 true  "Bearer {access_token}"
+// @Param        body           body      dto.ChangePasswordDTO  true  "Текущий и новый пароль (от 6 символов)"
+// @Success      200            {object}  map[string]string      "Пароль изменён"
+// @Failure      400            {object}  map[string]string      "error – новый пароль короче 6 символов"
+// @Failure      401            {object}  map[string]string      "Нет access‑токена или он недействителен"
+// @Failure      403            {object}  map[string]string      "Неверный текущий пароль"
+// @Failure      500            {object}  nil                    "Internal Server Error"
+// @Security     bearerAuth
+// @Router       /user/password/change [post]
+func (h *userHandler) ChangePassword(c *gin.Context) {
+	const op = "handler.http.users.ChangePassword"
+
+	var req dto.ChangePasswordDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v, location %s", err, op)
+		return
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the password must be at least 6 characters long"})
+		return
+	}
+
+	userID := c.GetInt("user_id")
+	if err := h.userService.ChangePassword(c, userID, req.CurrentPassword, req.NewPassword); err != nil {
+		if errors.Is(err, serviceUsers.ErrInvalidCredentials) {
+			c.Set("failed_registration", true)
+			c.JSON(http.StatusForbidden, gin.H{"error": "incorrect current password"})
+			return
+		}
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	// refresh токен отозван вместе с остальными — сбрасываем куку web-клиента
+	c.SetCookie("refresh_token", "", -1, "/", "", false, true)
+	c.JSON(http.StatusOK, gin.H{"status": "password changed, log in again"})
+}
@@ -48,7 +48,7 @@ func (h *userHandler) SignUp(c *gin.Context) {
 		return
 	}
 
-	accessToken, refreshToken, err := h.userService.Register(c, authDTO.Email, authDTO.Password, authDTO.Platform)
+	accessToken, refreshToken, err := h.userService.Register(c, authDTO.Email, authDTO.Password, authDTO.Platform, deviceInfo(c, authDTO.DeviceName))
 	if err != nil {
 		if errors.Is(err, storage.ErrUserExists) {
 			c.JSON(http.StatusConflict, gin.H{"error": "User with this email already exists"})
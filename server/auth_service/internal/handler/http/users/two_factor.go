@@ -0,0 +1,187 @@
+package handlerUsers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/1abobik1/AuthService/internal/dto"
+	"github.com/1abobik1/AuthService/internal/middleware"
+	serviceUsers "github.com/1abobik1/AuthService/internal/service/users"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// LoginTwoFactor
+// @Summary      Второй шаг входа с 2FA
+// @Description  Принимает challenge_token из /user/login и 6-значный код из приложения-аутентификатора либо одноразовый код восстановления.
+// @Description  Ответ такой же, как у /user/login без 2FA. Для web нужен password — им расшифровываются ks.
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        body  body      dto.LoginTwoFactorDTO   true  "challenge_token, code или recovery_code, password (для web)"
+// @Success      200   {object}  map[string]interface{}  "Поля ответа зависят от платформы (см. /user/login)"
+// @Failure      400   {object}  map[string]string       "Некорректный запрос"
+// @Failure      401   {object}  map[string]string       "challenge_token недействителен или истёк"
+// @Failure      403   {object}  map[string]string       "Неверный код, код восстановления или пароль"
+// @Failure      429   {object}  map[string]interface{}  "Слишком много неудачных попыток с этого IP или вход в аккаунт временно заблокирован (retry_after — секунды)"
+// @Failure      500   {string}  string                  "Internal Server Error"
+// @Router       /user/login/2fa [post]
+func (h *userHandler) LoginTwoFactor(c *gin.Context) {
+	const op = "handler.http.users.LoginTwoFactor"
+
+	var req dto.LoginTwoFactorDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v, location %s", err, op)
+		c.Set("failed_registration", true)
+		return
+	}
+
+	if err := validator.New().Struct(req); err != nil {
+		c.Set("failed_registration", true)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "challenge_token and a 6-digit code or a recovery_code are required"})
+		return
+	}
+
+	accessToken, refreshToken, platform, err := h.userService.LoginTwoFactor(c, req.ChallengeToken, req.Code, req.RecoveryCode, req.Password, deviceInfo(c, req.DeviceName))
+	if err != nil {
+		c.Set("failed_registration", true)
+		writeTwoFactorError(c, err, op)
+		return
+	}
+
+	middleware.FailedAttemptsCache.Delete("fail_" + c.ClientIP())
+	h.respondWithTokens(c, platform, req.Password, accessToken, refreshToken)
+}
+
+// EnrollTOTP
+// @Summary      Подключение 2FA
+// @Description  Создаёт секрет TOTP и otpauth URI (для QR-кода). 2FA включится после подтверждения кодом через /user/2fa/confirm.
+// @Description  Повторный вызов до подтверждения заменяет секрет.
+// @Tags         2fa
+// @Produce      json
+// @Param        Authorization  header    string             true  "Bearer {access_token}"
+// @Success      200            {object}  map[string]string  "secret и otpauth_uri"
+// @Failure      401            {object}  map[string]string  "Нет access‑токена или он недействителен"
+// @Failure      409            {object}  map[string]string  "2FA уже включена"
+// @Failure      500            {string}  string             "Internal Server Error"
+// @Security     bearerAuth
+// @Router       /user/2fa/enroll [post]
+func (h *userHandler) EnrollTOTP(c *gin.Context) {
+	const op = "handler.http.users.EnrollTOTP"
+
+	secret, uri, err := h.userService.EnrollTOTP(c, c.GetInt("user_id"))
+	if err != nil {
+		writeTwoFactorError(c, err, op)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"secret": secret, "otpauth_uri": uri})
+}
+
+// ConfirmTOTP
+// @Summary      Подтверждение 2FA
+// @Description  Включает 2FA, если код из приложения подходит к секрету из /user/2fa/enroll.
+// @Description  Возвращает коды восстановления — они показываются только один раз.
+// @Tags         2fa
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string               true  "Bearer {access_token}"
+// @Param        body           body      dto.ConfirmTOTPDTO   true  "Код из приложения"
+// @Success      200            {object}  map[string][]string  "recovery_codes"
+// @Failure      400            {object}  map[string]string    "Некорректный код или 2FA не подключалась"
+// @Failure      401            {object}  map[string]string    "Нет access‑токена или он недействителен"
+// @Failure      403            {object}  map[string]string    "Неверный код"
+// @Failure      409            {object}  map[string]string    "2FA уже включена"
+// @Failure      500            {string}  string               "Internal Server Error"
+// @Security     bearerAuth
+// @Router       /user/2fa/confirm [post]
+func (h *userHandler) ConfirmTOTP(c *gin.Context) {
+	const op = "handler.http.users.ConfirmTOTP"
+
+	var req dto.ConfirmTOTPDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v, location %s", err, op)
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "a 6-digit code is required"})
+		return
+	}
+
+	codes, err := h.userService.ConfirmTOTP(c, c.GetInt("user_id"), req.Code)
+	if err != nil {
+		c.Set("failed_registration", true)
+		writeTwoFactorError(c, err, op)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recovery_codes": codes})
+}
+
+// DisableTOTP
+// @Summary      Отключение 2FA
+// @Description  Выключает 2FA и удаляет коды восстановления. Требует пароль и код из приложения или код восстановления.
+// @Tags         2fa
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header    string              true  "Bearer {access_token}"
+// @Param        body           body      dto.DisableTOTPDTO  true  "Пароль и code или recovery_code"
+// @Success      200            {object}  map[string]string   "2FA выключена"
+// @Failure      400            {object}  map[string]string   "Некорректный запрос или 2FA не включена"
+// @Failure      401            {object}  map[string]string   "Нет access‑токена или он недействителен"
+// @Failure      403            {object}  map[string]string   "Неверный пароль, код или код восстановления"
+// @Failure      429            {object}  map[string]string   "Слишком много неудачных попыток"
+// @Failure      500            {string}  string              "Internal Server Error"
+// @Security     bearerAuth
+// @Router       /user/2fa/disable [post]
+func (h *userHandler) DisableTOTP(c *gin.Context) {
+	const op = "handler.http.users.DisableTOTP"
+
+	var req dto.DisableTOTPDTO
+	if err := c.BindJSON(&req); err != nil {
+		log.Printf("Error binding JSON: %v, location %s", err, op)
+		return
+	}
+	if err := validator.New().Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "password and a 6-digit code or a recovery_code are required"})
+		return
+	}
+
+	if err := h.userService.DisableTOTP(c, c.GetInt("user_id"), req.Password, req.Code, req.RecoveryCode); err != nil {
+		c.Set("failed_registration", true)
+		writeTwoFactorError(c, err, op)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "two-factor authentication disabled"})
+}
+
+func writeTwoFactorError(c *gin.Context, err error, op string) {
+	if writeAccountLocked(c, err) {
+		return
+	}
+
+	switch {
+	case errors.Is(err, serviceUsers.ErrInvalidChallenge):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": serviceUsers.ErrInvalidChallenge.Error()})
+	case errors.Is(err, serviceUsers.ErrInvalidCredentials):
+		c.JSON(http.StatusForbidden, gin.H{"error": "incorrect password"})
+	case errors.Is(err, serviceUsers.ErrPasswordRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": serviceUsers.ErrPasswordRequired.Error()})
+	case errors.Is(err, serviceUsers.ErrInvalidTOTPCode):
+		c.JSON(http.StatusForbidden, gin.H{"error": serviceUsers.ErrInvalidTOTPCode.Error()})
+	case errors.Is(err, storage.ErrTOTPCodeReused):
+		c.JSON(http.StatusForbidden, gin.H{"error": storage.ErrTOTPCodeReused.Error()})
+	case errors.Is(err, storage.ErrRecoveryCodeInvalid):
+		c.JSON(http.StatusForbidden, gin.H{"error": storage.ErrRecoveryCodeInvalid.Error()})
+	case errors.Is(err, storage.ErrTOTPAlreadyEnabled):
+		c.JSON(http.StatusConflict, gin.H{"error": storage.ErrTOTPAlreadyEnabled.Error()})
+	case errors.Is(err, storage.ErrTOTPNotEnrolled):
+		c.JSON(http.StatusBadRequest, gin.H{"error": storage.ErrTOTPNotEnrolled.Error()})
+	default:
+		log.Printf("Error: %v, location %s", err, op)
+		c.Status(http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,151 @@
+package keyset
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// JWK — открытый RSA ключ в формате RFC 7517
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKS — набор ключей для /.well-known/jwks.json
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type publicKey struct {
+	kid string
+	key *rsa.PublicKey
+}
+
+// KeySet — ключи подписи JWT. Токены подписываются текущим ключом, в заголовке kid — его отпечаток (RFC 7638).
+// Проверяются и публикуются в JWKS текущий ключ и дополнительные открытые ключи: старые, пока не истекут
+// подписанные ими токены, и следующие, чтобы проверяющие сервисы получили их заранее
+type KeySet struct {
+	signing    *rsa.PrivateKey
+	signingKID string
+	public     []publicKey
+}
+
+// Load читает ключи один раз при старте: privateKeyPath — текущий ключ подписи (PEM),
+// extraPublicKeyPaths — дополнительные открытые ключи (PEM) для ротации
+func Load(privateKeyPath string, extraPublicKeyPaths []string) (*KeySet, error) {
+	data, err := os.ReadFile(privateKeyPath)
+	if err != nil {
+		return nil, fmt.Errorf("read private key %s: %w", privateKeyPath, err)
+	}
+	signing, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+	if err != nil {
+		return nil, fmt.Errorf("parse private key %s: %w", privateKeyPath, err)
+	}
+
+	var extra []*rsa.PublicKey
+	for _, path := range extraPublicKeyPaths {
+		if path == "" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read public key %s: %w", path, err)
+		}
+		key, err := jwt.ParseRSAPublicKeyFromPEM(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse public key %s: %w", path, err)
+		}
+		extra = append(extra, key)
+	}
+
+	return New(signing, extra...), nil
+}
+
+// New собирает KeySet из уже загруженных ключей. Повторы открытых ключей пропускаются
+func New(signing *rsa.PrivateKey, extra ...*rsa.PublicKey) *KeySet {
+	ks := &KeySet{signing: signing, signingKID: Thumbprint(&signing.PublicKey)}
+	ks.public = append(ks.public, publicKey{kid: ks.signingKID, key: &signing.PublicKey})
+	for _, key := range extra {
+		kid := Thumbprint(key)
+		if _, ok := ks.find(kid); ok {
+			continue
+		}
+		ks.public = append(ks.public, publicKey{kid: kid, key: key})
+	}
+	return ks
+}
+
+// SigningKID — kid текущего ключа подписи
+func (ks *KeySet) SigningKID() string {
+	return ks.signingKID
+}
+
+// Sign подписывает claims текущим ключом (RS256) и ставит его kid в заголовок
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = ks.signingKID
+	return token.SignedString(ks.signing)
+}
+
+// Keyfunc выбирает открытый ключ по kid токена. Токены без kid выпущены до ротации ключей
+// и проверяются текущим ключом
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() != jwt.SigningMethodRS256.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return &ks.signing.PublicKey, nil
+	}
+	key, ok := ks.find(kid)
+	if !ok {
+		return nil, fmt.Errorf("kid %s: %w", kid, ErrUnknownKey)
+	}
+	return key, nil
+}
+
+// JWKS возвращает все открытые ключи набора, текущий — первым
+func (ks *KeySet) JWKS() JWKS {
+	out := JWKS{Keys: make([]JWK, 0, len(ks.public))}
+	for _, pk := range ks.public {
+		out.Keys = append(out.Keys, JWK{
+			Kty: "RSA",
+			Use: "sig",
+			Alg: jwt.SigningMethodRS256.Alg(),
+			Kid: pk.kid,
+			N:   base64.RawURLEncoding.EncodeToString(pk.key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pk.key.E)).Bytes()),
+		})
+	}
+	return out
+}
+
+// Thumbprint — отпечаток открытого RSA ключа по RFC 7638, используется как kid
+func Thumbprint(key *rsa.PublicKey) string {
+	n := base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	sum := sha256.Sum256([]byte(`{"e":"` + e + `","kty":"RSA","n":"` + n + `"}`))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func (ks *KeySet) find(kid string) (*rsa.PublicKey, bool) {
+	for _, pk := range ks.public {
+		if pk.kid == kid {
+			return pk.key, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,66 @@
+package keyset_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/1abobik1/AuthService/internal/keyset"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+func TestKeySet_SignSetsKID(t *testing.T) {
+	ks := keyset.New(newKey(t))
+
+	signed, err := ks.Sign(jwt.RegisteredClaims{Subject: "1"})
+	require.NoError(t, err)
+
+	token, err := jwt.Parse(signed, ks.Keyfunc)
+	require.NoError(t, err)
+	assert.Equal(t, ks.SigningKID(), token.Header["kid"])
+}
+
+func TestKeySet_RotationKeepsOldTokensValid(t *testing.T) {
+	oldKey, newKeyPair := newKey(t), newKey(t)
+	before := keyset.New(oldKey)
+	signed, err := before.Sign(jwt.RegisteredClaims{Subject: "1"})
+	require.NoError(t, err)
+
+	// после ротации старый ключ остаётся только для проверки
+	after := keyset.New(newKeyPair, &oldKey.PublicKey)
+	_, err = jwt.Parse(signed, after.Keyfunc)
+	assert.NoError(t, err)
+
+	// без старого ключа токен отклоняется
+	_, err = jwt.Parse(signed, keyset.New(newKeyPair).Keyfunc)
+	assert.ErrorIs(t, err, keyset.ErrUnknownKey)
+}
+
+func TestKeySet_LegacyTokenWithoutKID(t *testing.T) {
+	key := newKey(t)
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{Subject: "1"}).SignedString(key)
+	require.NoError(t, err)
+
+	_, err = jwt.Parse(signed, keyset.New(key).Keyfunc)
+	assert.NoError(t, err)
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	current, previous := newKey(t), newKey(t)
+	ks := keyset.New(current, &previous.PublicKey, &current.PublicKey)
+
+	jwks := ks.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, ks.SigningKID(), jwks.Keys[0].Kid)
+	assert.Equal(t, keyset.Thumbprint(&previous.PublicKey), jwks.Keys[1].Kid)
+	assert.Equal(t, "RS256", jwks.Keys[0].Alg)
+	assert.Equal(t, "AQAB", jwks.Keys[0].E)
+}
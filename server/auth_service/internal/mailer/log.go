@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogMailer не отправляет письма, а дописывает их в файл path (или в лог, если path пустой).
+// Позволяет пройти подтверждение email без почтового сервера.
+type LogMailer struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewLogMailer(path string) *LogMailer {
+	return &LogMailer{path: path}
+}
+
+func (m *LogMailer) Send(_ context.Context, to, subject, body string) error {
+	if m.path == "" {
+		log.Printf("mail to %s, subject %q:\n%s", to, subject, body)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("log mailer open %s: %w", m.path, err)
+	}
+	defer f.Close()
+
+	if _, err := fmt.Fprintf(f, "--- %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().UTC().Format(time.RFC3339), to, subject, body); err != nil {
+		return fmt.Errorf("log mailer write: %w", err)
+	}
+	return nil
+}
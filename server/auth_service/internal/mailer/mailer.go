@@ -0,0 +1,32 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	DriverSMTP = "smtp"
+	DriverLog  = "log"
+)
+
+// Mailer отправляет письма пользователям (подтверждение email и т.п.)
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// New создаёт Mailer по имени драйвера: smtp — реальная отправка, log — запись писем в файл или лог
+// (для локальной разработки без почтового сервера)
+func New(driver, smtpAddr, from, user, password, logPath string) (Mailer, error) {
+	switch driver {
+	case DriverSMTP:
+		if smtpAddr == "" {
+			return nil, fmt.Errorf("mailer %s: SMTP_ADDR is empty", driver)
+		}
+		return NewSMTPMailer(smtpAddr, from, user, password), nil
+	case DriverLog:
+		return NewLogMailer(logPath), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q, available: %s, %s", driver, DriverSMTP, DriverLog)
+	}
+}
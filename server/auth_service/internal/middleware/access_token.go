@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/1abobik1/AuthService/pkg/auth/validation"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// TokenRevocations — список отзыва access токенов (по jti, сессии устройства sid и времени отзыва всех токенов пользователя)
+type TokenRevocations interface {
+	IsRevoked(ctx context.Context, jti, sessionID string, userID int, issuedAt time.Time) (bool, error)
+}
+
+// AccessTokenMiddleware пропускает запросы с действующим access-токеном и кладёт user_id в контекст ("user_id"), роль — в "role",
+// а ID сессии устройства из claim sid — в "session_id" (у токенов, выпущенных до сессий устройств, его нет).
+// Refresh-токены (без claim role), сервисные токены (с claim scope) и отозванные токены не принимаются:
+// токен завершённой сессии устройства не может управлять остальными сессиями до истечения ACCESS_TOKEN_TTL
+func AccessTokenMiddleware(keyfunc jwt.Keyfunc, revocations TokenRevocations) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authenticate(c, keyfunc, revocations) {
+			c.Next()
+		}
+	}
+}
+
+// OptionalAccessTokenMiddleware — как AccessTokenMiddleware, но запрос без заголовка Authorization пропускается
+// без user_id в контексте. Для маршрутов, доступных и без входа, но по-разному работающих для вошедших
+func OptionalAccessTokenMiddleware(keyfunc jwt.Keyfunc, revocations TokenRevocations) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" || authenticate(c, keyfunc, revocations) {
+			c.Next()
+		}
+	}
+}
+
+// authenticate проверяет access-токен из заголовка Authorization и кладёт его claims в контекст.
+// При ошибке отвечает 401 (500, если список отзыва недоступен) и возвращает false
+func authenticate(c *gin.Context, keyfunc jwt.Keyfunc, revocations TokenRevocations) bool {
+	authHeader := c.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token format"})
+		return false
+	}
+
+	claims, err := validation.ValidateToken(strings.TrimPrefix(authHeader, "Bearer "), keyfunc)
+	if err != nil {
+		log.Printf("Error validating access token: %v", err)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return false
+	}
+
+	role, hasRole := claims["role"].(string)
+	_, hasScope := claims["scope"]
+	userID, ok := claims["user_id"].(float64)
+	if !hasRole || hasScope || !ok {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "access token required"})
+		return false
+	}
+
+	jti, _ := claims["jti"].(string)
+	sessionID, _ := claims["sid"].(string)
+	iat, _ := claims["iat"].(float64)
+	isRevoked, err := revocations.IsRevoked(c, jti, sessionID, int(userID), time.Unix(int64(iat), 0))
+	if err != nil {
+		log.Printf("Error checking token revocation: %v", err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not check token"})
+		return false
+	}
+	if isRevoked {
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+		return false
+	}
+
+	c.Set("user_id", int(userID))
+	c.Set("role", role)
+	if sessionID != "" {
+		c.Set("session_id", sessionID)
+	}
+	return true
+}
+
+// RequireRole пропускает только запросы, у access-токена которых claim role = role. Ставится после AccessTokenMiddleware
+func RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("role") != role {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient permissions"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/keyset"
+	"github.com/1abobik1/AuthService/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// revokedSessions отзывает токены по sid; err — недоступный список отзыва
+type revokedSessions struct {
+	sids map[string]bool
+	err  error
+}
+
+func (r *revokedSessions) IsRevoked(ctx context.Context, jti, sessionID string, userID int, issuedAt time.Time) (bool, error) {
+	return r.sids[sessionID], r.err
+}
+
+func newTestKeys(t *testing.T) *keyset.KeySet {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return keyset.New(key)
+}
+
+func serveWithToken(t *testing.T, keys *keyset.KeySet, revocations TokenRevocations, token string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/user/devices", AccessTokenMiddleware(keys.Keyfunc, revocations), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"session_id": c.GetString("session_id")})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/user/devices", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestAccessTokenMiddleware_Valid(t *testing.T) {
+	keys := newTestKeys(t)
+	token, err := utils.CreateAccessToken(7, "user", true, "sid-1", time.Minute, keys)
+	require.NoError(t, err)
+
+	w := serveWithToken(t, keys, &revokedSessions{}, token)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "sid-1")
+}
+
+// Токен завершённой сессии устройства не может управлять остальными сессиями
+func TestAccessTokenMiddleware_RevokedSession(t *testing.T) {
+	keys := newTestKeys(t)
+	token, err := utils.CreateAccessToken(7, "user", true, "sid-1", time.Minute, keys)
+	require.NoError(t, err)
+
+	w := serveWithToken(t, keys, &revokedSessions{sids: map[string]bool{"sid-1": true}}, token)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAccessTokenMiddleware_RevocationsUnavailable(t *testing.T) {
+	keys := newTestKeys(t)
+	token, err := utils.CreateAccessToken(7, "user", true, "sid-1", time.Minute, keys)
+	require.NoError(t, err)
+
+	w := serveWithToken(t, keys, &revokedSessions{err: errors.New("redis is down")}, token)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestAccessTokenMiddleware_RefreshTokenRejected(t *testing.T) {
+	keys := newTestKeys(t)
+	token, err := utils.CreateRefreshToken(7, "jti-1", "fam-1", time.Hour, keys)
+	require.NoError(t, err)
+
+	w := serveWithToken(t, keys, &revokedSessions{}, token)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
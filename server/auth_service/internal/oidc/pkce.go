@@ -0,0 +1,24 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+)
+
+// NewPKCE возвращает code_verifier (RFC 7636, 43 символа) и code_challenge = BASE64URL(SHA256(verifier))
+func NewPKCE() (verifier, challenge string, er error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", fmt.Errorf("generate code_verifier: %w", err)
+	}
+	verifier = base64.RawURLEncoding.EncodeToString(b)
+	return verifier, CodeChallengeS256(verifier), nil
+}
+
+// CodeChallengeS256 — code_challenge для метода S256
+func CodeChallengeS256(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
@@ -0,0 +1,100 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/1abobik1/AuthService/internal/oidc"
+	"github.com/1abobik1/AuthService/internal/oidc/oidctest"
+)
+
+const redirectURL = "http://client.local/oidc/callback"
+
+func setup(t *testing.T) (*oidctest.Provider, *oidc.Provider) {
+	t.Helper()
+	mock, srv, err := oidctest.NewServer("passkeeper", "secret")
+	require.NoError(t, err)
+	t.Cleanup(srv.Close)
+	mock.AddUser(oidctest.User{Subject: "sub-1", Email: "user@example.com", EmailVerified: true})
+
+	p := oidc.NewProvider(oidc.Config{
+		Name:         "mock",
+		Issuer:       mock.Issuer,
+		ClientID:     "passkeeper",
+		ClientSecret: "secret",
+		RedirectURL:  redirectURL,
+	}, srv.Client())
+	return mock, p
+}
+
+// authorize проходит /authorize мока и возвращает code из редиректа
+func authorize(t *testing.T, p *oidc.Provider, state, nonce, challenge string) string {
+	t.Helper()
+	authURL, err := p.AuthCodeURL(context.Background(), state, nonce, challenge)
+	require.NoError(t, err)
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authURL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	require.NoError(t, err)
+	require.Equal(t, state, loc.Query().Get("state"))
+	return loc.Query().Get("code")
+}
+
+func TestExchange(t *testing.T) {
+	_, p := setup(t)
+	verifier, challenge, err := oidc.NewPKCE()
+	require.NoError(t, err)
+
+	code := authorize(t, p, "state-1", "nonce-1", challenge)
+	claims, err := p.Exchange(context.Background(), code, verifier, "nonce-1")
+	require.NoError(t, err)
+	assert.Equal(t, oidc.Claims{Subject: "sub-1", Email: "user@example.com", EmailVerified: true}, claims)
+
+	// повторный обмен того же code отклоняется провайдером
+	_, err = p.Exchange(context.Background(), code, verifier, "nonce-1")
+	assert.True(t, errors.Is(err, oidc.ErrCodeExchange))
+}
+
+func TestExchangeWrongVerifier(t *testing.T) {
+	_, p := setup(t)
+	_, challenge, err := oidc.NewPKCE()
+	require.NoError(t, err)
+	otherVerifier, _, err := oidc.NewPKCE()
+	require.NoError(t, err)
+
+	code := authorize(t, p, "state", "nonce", challenge)
+	_, err = p.Exchange(context.Background(), code, otherVerifier, "nonce")
+	assert.True(t, errors.Is(err, oidc.ErrCodeExchange))
+}
+
+func TestExchangeNonceMismatch(t *testing.T) {
+	mock, p := setup(t)
+	mock.Nonce = "forged"
+	verifier, challenge, err := oidc.NewPKCE()
+	require.NoError(t, err)
+
+	code := authorize(t, p, "state", "nonce", challenge)
+	_, err = p.Exchange(context.Background(), code, verifier, "nonce")
+	assert.True(t, errors.Is(err, oidc.ErrInvalidIDToken))
+}
+
+func TestDiscoveryIssuerMismatch(t *testing.T) {
+	mock, srv, err := oidctest.NewServer("passkeeper", "secret")
+	require.NoError(t, err)
+	defer srv.Close()
+
+	p := oidc.NewProvider(oidc.Config{Issuer: mock.Issuer + "/", ClientID: "passkeeper"}, srv.Client())
+	_, err = p.AuthCodeURL(context.Background(), "s", "n", "c")
+	assert.True(t, errors.Is(err, oidc.ErrDiscovery))
+}
@@ -0,0 +1,8 @@
+package serviceToken
+
+import "github.com/1abobik1/AuthService/internal/keyset"
+
+// JWKS возвращает открытые ключи, которыми проверяются выпущенные auth_service токены
+func (s *tokenService) JWKS() keyset.JWKS {
+	return s.keys.JWKS()
+}
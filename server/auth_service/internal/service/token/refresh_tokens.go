@@ -0,0 +1,70 @@
+package serviceToken
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/1abobik1/AuthService/internal/utils"
+)
+
+// RefreshTokens обменивает refresh токен на новую пару access и refresh токенов. Refresh токен одноразовый:
+// при каждом обновлении выдаётся следующий токен того же семейства, а предъявленный помечается обменянным.
+// Повторное предъявление обменянного токена отзывает всё семейство и сессию устройства (storage.ErrTokenReused).
+func (s *tokenService) RefreshTokens(ctx context.Context, refreshToken string) (accessJWT string, refreshJWT string, er error) {
+	const op = "service.token.refresh.RefreshTokens"
+
+	userID, familyID, err := s.parseRefreshToken(refreshToken)
+	if err != nil {
+		return "", "", err
+	}
+
+	jti, err := utils.NewTokenID()
+	if err != nil {
+		return "", "", err
+	}
+
+	newRefreshToken, err := utils.CreateRefreshToken(userID, jti, familyID, s.cfg.JWT.RefreshTokenTTL, s.keys)
+	if err != nil {
+		log.Printf("Error creating refresh token: %v, location %s \n", err, op)
+		return "", "", fmt.Errorf("error creating refresh token: %w", err)
+	}
+
+	user, err := s.tokenStorage.RotateRefreshToken(ctx, utils.HashToken(refreshToken), models.RefreshToken{
+		JTI:       jti,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(newRefreshToken),
+		ExpiresAt: time.Now().Add(s.cfg.JWT.RefreshTokenTTL),
+	})
+	if err != nil {
+		if errors.Is(err, storage.ErrTokenReused) {
+			log.Printf("SECURITY: refresh token reuse detected for user %d, family %s revoked, location %s", userID, familyID, op)
+			s.revokeDeviceSession(ctx, familyID)
+		} else {
+			log.Printf("Error: %v, location %s", err, op)
+		}
+		return "", "", err
+	}
+
+	newAccessToken, err := utils.CreateAccessToken(user.ID, user.Role, user.IsActivated, familyID, s.cfg.JWT.AccessTokenTTL, s.keys)
+	if err != nil {
+		log.Printf("Error creating access token: %v, location %s \n", err, op)
+		return "", "", fmt.Errorf("error creating access token: %w", err)
+	}
+
+	return newAccessToken, newRefreshToken, nil
+}
+
+// revokeDeviceSession добавляет сессию устройства в список отзыва: её access токены больше не действуют.
+// Refresh токены к этому моменту уже отозваны, поэтому ошибка только логируется
+func (s *tokenService) revokeDeviceSession(ctx context.Context, sessionID string) {
+	const op = "service.token.revokeDeviceSession"
+
+	if err := s.revocations.RevokeSession(ctx, sessionID, s.cfg.JWT.AccessTokenTTL); err != nil {
+		log.Printf("warning: failed to revoke access tokens of device session %s: %v, location %s", sessionID, err, op)
+	}
+}
@@ -0,0 +1,49 @@
+package serviceToken
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/1abobik1/AuthService/pkg/auth/validation"
+)
+
+// RevokeAccessToken добавляет access токен в список отзыва до истечения его срока действия.
+// Истёкший токен уже недействителен — это не ошибка. Чужая подпись, refresh, сервисные токены
+// и токены без jti — validation.ErrTokenInvalid
+func (s *tokenService) RevokeAccessToken(ctx context.Context, accessToken string) error {
+	const op = "service.token.RevokeAccessToken"
+
+	claims, err := validation.ValidateToken(accessToken, s.keys.Keyfunc)
+	if errors.Is(err, validation.ErrTokenExpired) {
+		return nil
+	}
+	if err != nil {
+		log.Printf("Error: %v, location: %s", err, op)
+		return err
+	}
+
+	_, hasRole := claims["role"]
+	_, hasScope := claims["scope"]
+	jti, _ := claims["jti"].(string)
+	exp, _ := claims["exp"].(float64)
+	if !hasRole || hasScope || jti == "" || exp == 0 {
+		log.Printf("Error: not an access token with jti, location: %s", op)
+		return validation.ErrTokenInvalid
+	}
+
+	ttl := time.Until(time.Unix(int64(exp), 0))
+	if ttl <= 0 {
+		return nil
+	}
+	if err := s.revocations.RevokeToken(ctx, jti, ttl); err != nil {
+		log.Printf("Error revoking access token %s: %v, location: %s", jti, err, op)
+		return err
+	}
+
+	if userID, ok := claims["user_id"].(float64); ok {
+		log.Printf("access token %s of user %d revoked", jti, int(userID))
+	}
+	return nil
+}
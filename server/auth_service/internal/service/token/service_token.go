@@ -2,24 +2,36 @@ package serviceToken
 
 import (
 	"context"
+	"time"
 
 	"github.com/1abobik1/AuthService/config"
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/keyset"
 )
 
 type TokenStorageI interface {
-	CheckRefreshToken(refreshToken string) (int, error)
-	UpdateRefreshToken(oldRefreshToken, newRefreshToken string) error
+	RotateRefreshToken(ctx context.Context, oldHash string, next models.RefreshToken) (models.UserModel, error)
 	GetUserKey(ctx context.Context, userID int) (string, error)
 }
 
+// TokenRevocationI — список отзыва access токенов, который проверяет secure_comm_service
+type TokenRevocationI interface {
+	RevokeToken(ctx context.Context, jti string, ttl time.Duration) error
+	RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error
+}
+
 type tokenService struct {
 	tokenStorage TokenStorageI
+	revocations  TokenRevocationI
+	keys         *keyset.KeySet
 	cfg          config.Config
 }
 
-func NewTokenService(tokenStorage TokenStorageI, cfg config.Config) *tokenService {
+func NewTokenService(tokenStorage TokenStorageI, revocations TokenRevocationI, keys *keyset.KeySet, cfg config.Config) *tokenService {
 	return &tokenService{
 		tokenStorage: tokenStorage,
+		revocations:  revocations,
+		keys:         keys,
 		cfg:          cfg,
 	}
 }
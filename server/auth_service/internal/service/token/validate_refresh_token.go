@@ -1,26 +1,28 @@
 package serviceToken
 
 import (
-	"errors"
 	"log"
 
 	"github.com/1abobik1/AuthService/pkg/auth/validation"
-	"github.com/golang-jwt/jwt/v4"
 )
 
-// use only for refresh_token
-func (s *tokenService) ValidateRefreshToken(refreshToken string) (bool, jwt.MapClaims, error) {
-	const op = "service.token.validation.ValidateRefreshToken"
+// use only for refresh_token: проверяет подпись и срок действия, возвращает user_id и семейство токена
+func (s *tokenService) parseRefreshToken(refreshToken string) (int, string, error) {
+	const op = "service.token.validation.parseRefreshToken"
 
-	claims, err := validation.ValidateToken(refreshToken, s.cfg.JWT.PublicKeyPath)
+	claims, err := validation.ValidateToken(refreshToken, s.keys.Keyfunc)
 	if err != nil {
-		if errors.Is(err, validation.ErrTokenExpired) {
-			log.Printf("Warning: token expired, location: %s", op)
-			return true, claims, nil // Токен истёк, но валиден
-		}
 		log.Printf("Error: %v, location: %s", err, op)
-		return false, nil, err // Токен невалиден
+		return 0, "", err
 	}
 
-	return false, claims, nil // Токен валиден и не истёк
+	userID, ok := claims["user_id"].(float64)
+	familyID, _ := claims["fam"].(string)
+	// токены без семейства выпущены до ротации по семействам и в базе уже не найдутся
+	if !ok || familyID == "" {
+		log.Printf("Error: refresh token without user_id or fam claim, location: %s", op)
+		return 0, "", validation.ErrTokenInvalid
+	}
+
+	return int(userID), familyID, nil
 }
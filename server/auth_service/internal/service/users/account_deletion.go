@@ -0,0 +1,245 @@
+package serviceUsers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/external_api"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/1abobik1/AuthService/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Шаги удаления аккаунта в порядке выполнения. В account_deletions.step хранится последний выполненный,
+// поэтому после сбоя удаление продолжается со следующего; каждый шаг можно безопасно повторить
+const (
+	deletionStepSessions   = "sessions_revoked"   // сессии устройств завершены, access токены отозваны
+	deletionStepSecureComm = "secure_comm_purged" // файлы, учёт и ключи удалены в secure_comm_service
+	deletionStepAuth       = "auth_purged"        // пользователь удалён из auth_service
+)
+
+var deletionSteps = []string{deletionStepSessions, deletionStepSecureComm, deletionStepAuth}
+
+// максимальная пауза между попытками удаления после ошибок
+const maxDeletionRetryInterval = time.Hour
+
+// RequestAccountDeletion ставит аккаунт в очередь на удаление после льготного периода ACCOUNT_DELETION_GRACE_PERIOD.
+// Удаление подтверждается паролем и, если включена 2FA, кодом. Возвращает запрос и токен, по которому
+// можно узнать состояние удаления и получить квитанцию (он же уходит письмом)
+func (s *userService) RequestAccountDeletion(ctx context.Context, userID int, password, code, recoveryCode string) (models.AccountDeletion, string, error) {
+	const op = "service.users.RequestAccountDeletion"
+
+	user, err := s.userStorage.FindUserByID(ctx, userID)
+	if err != nil {
+		log.Printf("Error finding user %d: %v, location %s", userID, err, op)
+		return models.AccountDeletion{}, "", err
+	}
+	if err := bcrypt.CompareHashAndPassword(user.Password, []byte(password)); err != nil {
+		return models.AccountDeletion{}, "", ErrInvalidCredentials
+	}
+	if user.TOTPEnabled {
+		if err := s.verifySecondFactor(ctx, userID, code, recoveryCode); err != nil {
+			return models.AccountDeletion{}, "", err
+		}
+	}
+
+	token, err := newOneTimeToken()
+	if err != nil {
+		return models.AccountDeletion{}, "", err
+	}
+
+	deletion, err := s.userStorage.CreateAccountDeletion(ctx, userID, user.Email, hashOneTimeToken(token), time.Now().Add(s.cfg.AccountDeletion.GracePeriod))
+	if err != nil {
+		if !errors.Is(err, storage.ErrDeletionRequested) {
+			log.Printf("Error saving account deletion: %v, location %s", err, op)
+		}
+		return models.AccountDeletion{}, "", err
+	}
+	log.Printf("user %d requested account deletion %d, scheduled at %s", userID, deletion.ID, deletion.ScheduledAt.UTC().Format(time.RFC3339))
+
+	body := fmt.Sprintf("Аккаунт SecureComm будет удалён %s вместе со всеми файлами.\n\n", deletion.ScheduledAt.UTC().Format(time.RFC1123))
+	if s.cfg.AccountDeletion.GracePeriod > 0 {
+		body += "До этого момента удаление можно отменить в настройках аккаунта.\n\n"
+	}
+	body += "Код для проверки состояния удаления и получения квитанции: " + token
+	// письмо не критично: токен возвращается и в ответе
+	if err := s.mailer.Send(ctx, user.Email, "SecureComm: удаление аккаунта", body); err != nil {
+		log.Printf("warning: failed to send account deletion email to user %d: %v, location %s", userID, err, op)
+	}
+
+	return deletion, token, nil
+}
+
+// CancelAccountDeletion отменяет удаление, пока не истёк льготный период (storage.ErrDeletionNotFound — отменять нечего)
+func (s *userService) CancelAccountDeletion(ctx context.Context, userID int) error {
+	const op = "service.users.CancelAccountDeletion"
+
+	if err := s.userStorage.CancelAccountDeletion(ctx, userID); err != nil {
+		if !errors.Is(err, storage.ErrDeletionNotFound) {
+			log.Printf("Error cancelling account deletion of user %d: %v, location %s", userID, err, op)
+		}
+		return err
+	}
+
+	log.Printf("user %d cancelled account deletion", userID)
+	return nil
+}
+
+// AccountDeletionStatus возвращает запрос на удаление по токену из RequestAccountDeletion
+func (s *userService) AccountDeletionStatus(ctx context.Context, token string) (models.AccountDeletion, error) {
+	return s.userStorage.FindAccountDeletion(ctx, hashOneTimeToken(token))
+}
+
+// ProcessAccountDeletions выполняет все запросы на удаление, срок которых наступил. Запрос, шаг которого
+// завершился ошибкой, откладывается с растущей паузой и будет продолжен с того же шага. Возвращает число завершённых удалений
+func (s *userService) ProcessAccountDeletions(ctx context.Context) (int, error) {
+	const op = "service.users.ProcessAccountDeletions"
+
+	completed := 0
+	for ctx.Err() == nil {
+		deletion, err := s.userStorage.ClaimAccountDeletion(ctx, s.cfg.AccountDeletion.Lease)
+		if errors.Is(err, storage.ErrDeletionNotFound) {
+			return completed, nil
+		}
+		if err != nil {
+			log.Printf("Error claiming account deletion: %v, location %s", err, op)
+			return completed, err
+		}
+
+		if receipt, err := s.runAccountDeletion(ctx, deletion); err != nil {
+			retryAt := time.Now().Add(deletionRetryInterval(deletion.Attempts))
+			log.Printf("Error deleting account of user %d (deletion %d, attempt %d), retry at %s: %v, location %s",
+				deletion.UserID, deletion.ID, deletion.Attempts+1, retryAt.UTC().Format(time.RFC3339), err, op)
+			if err := s.userStorage.FailAccountDeletion(ctx, deletion.ID, err.Error(), retryAt, receipt); err != nil {
+				log.Printf("Error saving account deletion failure: %v, location %s", err, op)
+				return completed, err
+			}
+			continue
+		}
+		completed++
+	}
+	return completed, ctx.Err()
+}
+
+// runAccountDeletion выполняет оставшиеся шаги удаления и завершает его квитанцией.
+// При ошибке возвращает квитанцию с тем, что уже удалено (nil, если её не удалось собрать):
+// она сохраняется, и следующая попытка продолжает счёт
+func (s *userService) runAccountDeletion(ctx context.Context, deletion models.AccountDeletion) ([]byte, error) {
+	receipt := models.DeletionReceipt{}
+	if len(deletion.Receipt) > 0 {
+		if err := json.Unmarshal(deletion.Receipt, &receipt); err != nil {
+			return nil, fmt.Errorf("decode receipt: %w", err)
+		}
+	}
+	receipt.DeletionID = deletion.ID
+	receipt.UserID = deletion.UserID
+	receipt.RequestedAt = deletion.RequestedAt
+	if receipt.Steps == nil {
+		receipt.Steps = map[string]time.Time{}
+	}
+
+	done := deletion.Step == ""
+	for _, step := range deletionSteps {
+		if !done {
+			// шаги до сохранённого включительно уже выполнены
+			done = step == deletion.Step
+			continue
+		}
+
+		if err := s.runDeletionStep(ctx, step, deletion.UserID, &receipt); err != nil {
+			data, _ := json.Marshal(receipt)
+			return data, fmt.Errorf("step %s: %w", step, err)
+		}
+		receipt.Steps[step] = time.Now().UTC()
+
+		data, err := json.Marshal(receipt)
+		if err != nil {
+			return nil, fmt.Errorf("encode receipt: %w", err)
+		}
+		if err := s.userStorage.SaveAccountDeletionStep(ctx, deletion.ID, step, data); err != nil {
+			return data, err
+		}
+	}
+
+	completedAt := time.Now().UTC()
+	receipt.CompletedAt = &completedAt
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		return nil, fmt.Errorf("encode receipt: %w", err)
+	}
+
+	if deletion.Email != "" {
+		s.sendDeletionReceipt(ctx, deletion.Email, data)
+	}
+	if err := s.userStorage.CompleteAccountDeletion(ctx, deletion.ID, data); err != nil {
+		return data, err
+	}
+
+	log.Printf("account of user %d deleted (deletion %d)", deletion.UserID, deletion.ID)
+	return data, nil
+}
+
+func (s *userService) runDeletionStep(ctx context.Context, step string, userID int, receipt *models.DeletionReceipt) error {
+	switch step {
+	case deletionStepSessions:
+		sessionIDs, err := s.userStorage.RevokeAllDeviceSessions(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if err := s.revocations.RevokeUserTokensBefore(ctx, userID, time.Now(), s.cfg.JWT.AccessTokenTTL); err != nil {
+			return err
+		}
+		receipt.Sessions = len(sessionIDs)
+
+	case deletionStepSecureComm:
+		serviceToken, err := utils.CreateServiceToken(s.cfg.ServiceToken.QuotaAudience, []string{external_api.ScopeUsersDelete}, s.cfg.ServiceToken.TTL, s.keys)
+		if err != nil {
+			return fmt.Errorf("create service token: %w", err)
+		}
+		// повторный вызов удаляет только то, что осталось после прошлого, поэтому счётчики складываются
+		// по всем вызовам, в том числе завершившимся ошибкой после частичного удаления
+		res, err := external_api.PurgeUserData(s.cfg.ExternalAPIs.QuotaServiceURL, userID, serviceToken)
+		receipt.Files += res.Objects
+		receipt.FileBytes += res.Bytes
+		receipt.StorageRows += res.DBRows
+		receipt.SessionKeys = receipt.SessionKeys || res.SessionKeys
+		if err != nil {
+			return err
+		}
+
+	case deletionStepAuth:
+		rows, err := s.userStorage.DeleteUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		receipt.AuthRows = rows
+
+	default:
+		return fmt.Errorf("unknown deletion step %q", step)
+	}
+	return nil
+}
+
+// sendDeletionReceipt отправляет квитанцию на адрес удалённого аккаунта. Ошибка только логируется:
+// квитанция остаётся доступной по токену
+func (s *userService) sendDeletionReceipt(ctx context.Context, email string, receipt []byte) {
+	const op = "service.users.sendDeletionReceipt"
+
+	body := "Аккаунт SecureComm и все его данные удалены.\n\nКвитанция об удалении:\n" + string(receipt)
+	if err := s.mailer.Send(ctx, email, "SecureComm: аккаунт удалён", body); err != nil {
+		log.Printf("warning: failed to send deletion receipt: %v, location %s", err, op)
+	}
+}
+
+// deletionRetryInterval — пауза перед следующей попыткой: минута, удваивается с каждой ошибкой, не больше часа
+func deletionRetryInterval(attempts int) time.Duration {
+	if attempts > 6 {
+		return maxDeletionRetryInterval
+	}
+	return min(time.Minute<<attempts, maxDeletionRetryInterval)
+}
@@ -0,0 +1,78 @@
+package serviceUsers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/AuthService/config"
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/keyset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deletionStorage запоминает сохранённые шаги; остальные методы UsersStorageI тестам не нужны
+type deletionStorage struct {
+	UsersStorageI
+	completed []byte
+}
+
+func (s *deletionStorage) SaveAccountDeletionStep(ctx context.Context, id int, step string, receipt []byte) error {
+	return nil
+}
+
+func (s *deletionStorage) CompleteAccountDeletion(ctx context.Context, id int, receipt []byte) error {
+	s.completed = receipt
+	return nil
+}
+
+func (s *deletionStorage) DeleteUser(ctx context.Context, userID int) (int64, error) {
+	return 3, nil
+}
+
+// Первый вызов secure_comm_service удалил часть файлов и упал, второй удалил остальное:
+// в квитанции сумма по обоим вызовам
+func TestRunAccountDeletion_ReceiptAccumulatesAcrossRetries(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":"failed to purge files","objects":2,"bytes":20,"session_keys":true}`))
+			return
+		}
+		w.Write([]byte(`{"objects":1,"bytes":10,"db_rows":5}`))
+	}))
+	defer srv.Close()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	cfg := config.Config{}
+	cfg.ExternalAPIs.QuotaServiceURL = srv.URL
+	cfg.ServiceToken.TTL = time.Minute
+	store := &deletionStorage{}
+	s := NewUserService(store, nil, nil, keyset.New(key), nil, nil, cfg)
+
+	// сессии уже завершены
+	deletion := models.AccountDeletion{ID: 1, UserID: 7, Step: deletionStepSessions}
+	receipt, err := s.runAccountDeletion(context.Background(), deletion)
+	require.Error(t, err)
+
+	deletion.Receipt = receipt
+	_, err = s.runAccountDeletion(context.Background(), deletion)
+	require.NoError(t, err)
+
+	var got models.DeletionReceipt
+	require.NoError(t, json.Unmarshal(store.completed, &got))
+	assert.Equal(t, 3, got.Files)
+	assert.Equal(t, int64(30), got.FileBytes)
+	assert.Equal(t, int64(5), got.StorageRows)
+	assert.Equal(t, int64(3), got.AuthRows)
+	assert.True(t, got.SessionKeys)
+}
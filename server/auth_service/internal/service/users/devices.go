@@ -0,0 +1,76 @@
+package serviceUsers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/storage"
+)
+
+// ListDevices возвращает действующие сессии устройств пользователя
+func (s *userService) ListDevices(ctx context.Context, userID int) ([]models.DeviceSession, error) {
+	const op = "service.users.ListDevices"
+
+	sessions, err := s.userStorage.ListDeviceSessions(ctx, userID)
+	if err != nil {
+		log.Printf("Error listing device sessions of user %d: %v, location %s", userID, err, op)
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeDevice завершает сессию устройства sessionID: её refresh токены отзываются,
+// а access токены попадают в список отзыва
+func (s *userService) RevokeDevice(ctx context.Context, userID int, sessionID string) error {
+	const op = "service.users.RevokeDevice"
+
+	if err := s.userStorage.RevokeDeviceSession(ctx, userID, sessionID); err != nil {
+		if !errors.Is(err, storage.ErrSessionNotFound) {
+			log.Printf("Error revoking device session %s of user %d: %v, location %s", sessionID, userID, err, op)
+		}
+		return err
+	}
+
+	s.revokeDeviceSession(ctx, sessionID)
+	log.Printf("user %d revoked device session %s", userID, sessionID)
+	return nil
+}
+
+// RevokeAllDevices завершает все сессии устройств пользователя, в том числе текущую,
+// и отзывает все его access токены, выпущенные до этого момента
+func (s *userService) RevokeAllDevices(ctx context.Context, userID int) error {
+	const op = "service.users.RevokeAllDevices"
+
+	sessionIDs, err := s.userStorage.RevokeAllDeviceSessions(ctx, userID)
+	if err != nil {
+		log.Printf("Error revoking device sessions of user %d: %v, location %s", userID, err, op)
+		return err
+	}
+
+	s.revokeAccessTokens(ctx, userID)
+	log.Printf("user %d revoked all device sessions (%d)", userID, len(sessionIDs))
+	return nil
+}
+
+// revokeDeviceSession добавляет сессию в список отзыва. Refresh токены к этому моменту уже отозваны,
+// поэтому ошибка только логируется: access токены сессии в худшем случае доживут до ACCESS_TOKEN_TTL
+func (s *userService) revokeDeviceSession(ctx context.Context, sessionID string) {
+	const op = "service.users.revokeDeviceSession"
+
+	if err := s.revocations.RevokeSession(ctx, sessionID, s.cfg.JWT.AccessTokenTTL); err != nil {
+		log.Printf("warning: failed to revoke access tokens of device session %s: %v, location %s", sessionID, err, op)
+	}
+}
+
+// revokeAccessTokens отзывает все access токены пользователя, выпущенные до текущего момента.
+// Ошибка только логируется по той же причине, что и в revokeDeviceSession
+func (s *userService) revokeAccessTokens(ctx context.Context, userID int) {
+	const op = "service.users.revokeAccessTokens"
+
+	if err := s.revocations.RevokeUserTokensBefore(ctx, userID, time.Now(), s.cfg.JWT.AccessTokenTTL); err != nil {
+		log.Printf("warning: failed to revoke access tokens of user %d: %v, location %s", userID, err, op)
+	}
+}
@@ -0,0 +1,89 @@
+package serviceUsers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/storage"
+)
+
+// ConfirmEmail подтверждает email по токену из письма. Ссылка одноразовая.
+func (s *userService) ConfirmEmail(ctx context.Context, token string) error {
+	const op = "service.users.ConfirmEmail"
+
+	userID, err := s.userStorage.ConfirmEmail(ctx, hashOneTimeToken(token))
+	if err != nil {
+		if !errors.Is(err, storage.ErrVerificationNotFound) {
+			log.Printf("Error confirming email: %v, location %s", err, op)
+		}
+		return err
+	}
+
+	log.Printf("user %d confirmed email", userID)
+	return nil
+}
+
+// ResendVerification повторно отправляет письмо подтверждения. Для неизвестного или уже подтверждённого
+// адреса ничего не делает и не сообщает об этом, чтобы по ответу нельзя было проверить, зарегистрирован ли email.
+// Чаще EMAIL_VERIFY_RESEND_INTERVAL письма не отправляются (storage.ErrVerificationThrottled).
+func (s *userService) ResendVerification(ctx context.Context, email string) error {
+	const op = "service.users.ResendVerification"
+
+	user, err := s.userStorage.FindUser(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil
+		}
+		log.Printf("Error finding user: %v, location %s", err, op)
+		return err
+	}
+	if user.IsActivated {
+		return nil
+	}
+
+	return s.sendVerification(ctx, user.ID, user.Email, s.cfg.EmailVerify.ResendInterval)
+}
+
+// sendVerification создаёт новую ссылку подтверждения (старая перестаёт действовать) и отправляет её письмом
+func (s *userService) sendVerification(ctx context.Context, userID int, email string, resendInterval time.Duration) error {
+	token, err := newOneTimeToken()
+	if err != nil {
+		return err
+	}
+
+	if err := s.userStorage.SaveEmailVerification(ctx, userID, hashOneTimeToken(token), s.cfg.EmailVerify.TTL, resendInterval); err != nil {
+		return err
+	}
+
+	link := s.cfg.EmailVerify.URL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Подтвердите адрес электронной почты, перейдя по ссылке:\n%s\n\n"+
+		"Или отправьте код подтверждения: %s\n\nСсылка действует до %s.",
+		link, token, time.Now().Add(s.cfg.EmailVerify.TTL).UTC().Format(time.RFC1123))
+
+	if err := s.mailer.Send(ctx, email, "SecureComm: подтверждение email", body); err != nil {
+		return fmt.Errorf("send verification email: %w", err)
+	}
+	return nil
+}
+
+// newOneTimeToken — токен для ссылок из писем (подтверждение email, сброс пароля) и state входа через OIDC
+func newOneTimeToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate one-time token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// в базе хранится только хеш: утечка таблицы не даёт подтвердить чужой адрес или сбросить чужой пароль
+func hashOneTimeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,158 @@
+package serviceUsers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/storage"
+)
+
+var ErrAccountLocked = errors.New("account is temporarily locked due to too many failed login attempts")
+
+// AccountLockedError — вход в аккаунт заблокирован до Until. errors.Is(err, ErrAccountLocked) срабатывает и для неё
+type AccountLockedError struct {
+	Until time.Time
+}
+
+func (e *AccountLockedError) Error() string {
+	return fmt.Sprintf("%s until %s", ErrAccountLocked, e.Until.UTC().Format(time.RFC3339))
+}
+
+func (e *AccountLockedError) Unwrap() error {
+	return ErrAccountLocked
+}
+
+// reserveLoginAttempt засчитывает попытку входа пользователя до проверки пароля или кода и возвращает её номер
+// в серии неудач. Если вход заблокирован, возвращает *AccountLockedError. Если допустимые попытки уже заняты
+// параллельными запросами, эта попытка сразу считается неудачной и пароль не проверяется. После окончания блокировки
+// одна попытка снова проверяется: верный пароль входит, неверный блокирует вход на вдвое больший срок.
+// Если Redis недоступен, возвращает 0 и вход не блокируется: остаётся лимит по IP
+func (s *userService) reserveLoginAttempt(ctx context.Context, user models.UserModel, ip string) (int64, error) {
+	const op = "service.users.reserveLoginAttempt"
+
+	attempt, err := s.loginAttempts.ReserveAttempt(ctx, user.ID, s.cfg.LoginLockout.Threshold, s.cfg.LoginLockout.FailureWindow)
+	if err != nil {
+		log.Printf("warning: failed to reserve login attempt of user %d: %v, location %s", user.ID, err, op)
+		return 0, nil
+	}
+	if !attempt.LockedUntil.IsZero() {
+		return 0, &AccountLockedError{Until: attempt.LockedUntil}
+	}
+	if attempt.OverLimit {
+		return 0, s.lockLogin(ctx, user, ip, attempt.Failures)
+	}
+	return attempt.Failures, nil
+}
+
+// releaseLoginAttempt возвращает попытку, которая не оказалась неудачной, когда вход ещё не завершён
+func (s *userService) releaseLoginAttempt(ctx context.Context, userID int) {
+	const op = "service.users.releaseLoginAttempt"
+
+	if err := s.loginAttempts.ReleaseAttempt(ctx, userID); err != nil {
+		log.Printf("warning: failed to release login attempt of user %d: %v, location %s", userID, err, op)
+	}
+}
+
+// loginFailed учитывает неудачу попытки номер failures (см. reserveLoginAttempt). Если попыток набралось
+// LOGIN_LOCKOUT_THRESHOLD и больше, вход блокируется. Возвращает *AccountLockedError, если вход заблокирован, иначе err
+func (s *userService) loginFailed(ctx context.Context, user models.UserModel, ip string, failures int64, err error) error {
+	if failures < s.cfg.LoginLockout.Threshold {
+		return err
+	}
+	return s.lockLogin(ctx, user, ip, failures)
+}
+
+// lockLogin блокирует вход после failures неудач (каждая следующая неудача удваивает срок), пишет событие
+// в security_events, а при первой блокировке в серии отправляет пользователю письмо. Возвращает *AccountLockedError
+func (s *userService) lockLogin(ctx context.Context, user models.UserModel, ip string, failures int64) error {
+	const op = "service.users.lockLogin"
+
+	until := time.Now().Add(s.loginLockoutDuration(failures))
+	if err := s.loginAttempts.Lock(ctx, user.ID, until, failures); err != nil {
+		log.Printf("warning: failed to lock login of user %d: %v, location %s", user.ID, err, op)
+		return err
+	}
+	log.Printf("login of user %d locked until %s after %d failed attempts (last from %s)", user.ID, until.UTC().Format(time.RFC3339), failures, ip)
+
+	details := map[string]string{
+		"failures":     strconv.FormatInt(failures, 10),
+		"ip":           ip,
+		"locked_until": until.UTC().Format(time.RFC3339),
+	}
+	if err := s.userStorage.SaveSecurityEvent(ctx, user.ID, models.SecurityEventLoginLockout, details); err != nil {
+		log.Printf("Error saving security event: %v, location %s", err, op)
+	}
+
+	if failures == s.cfg.LoginLockout.Threshold {
+		body := fmt.Sprintf("Вход в аккаунт SecureComm заблокирован до %s: %d неудачных попыток входа подряд (последняя с IP %s).\n\n"+
+			"Если это были не вы, кто-то подбирает ваш пароль. Смените пароль через «Забыли пароль» (это также снимет блокировку) "+
+			"и включите двухфакторную аутентификацию.", until.UTC().Format(time.RFC1123), failures, ip)
+		if err := s.mailer.Send(ctx, user.Email, "SecureComm: вход в аккаунт заблокирован", body); err != nil {
+			log.Printf("warning: failed to send lockout email to user %d: %v, location %s", user.ID, err, op)
+		}
+	}
+
+	return &AccountLockedError{Until: until}
+}
+
+// loginLockoutDuration — срок блокировки после failures неудач: LOGIN_LOCKOUT_BASE_DURATION на пороге,
+// вдвое больше за каждую следующую неудачу, не больше LOGIN_LOCKOUT_MAX_DURATION
+func (s *userService) loginLockoutDuration(failures int64) time.Duration {
+	cfg := s.cfg.LoginLockout
+
+	shift := failures - cfg.Threshold
+	if shift < 0 {
+		shift = 0
+	}
+	if shift > 30 {
+		return cfg.MaxDuration
+	}
+	d := cfg.BaseDuration << shift
+	if d <= 0 || d > cfg.MaxDuration {
+		return cfg.MaxDuration
+	}
+	return d
+}
+
+// resetLoginFailures обнуляет счётчик неудач после успешного входа
+func (s *userService) resetLoginFailures(ctx context.Context, userID int) {
+	const op = "service.users.resetLoginFailures"
+
+	if _, err := s.loginAttempts.Reset(ctx, userID); err != nil {
+		log.Printf("warning: failed to reset login failures of user %d: %v, location %s", userID, err, op)
+	}
+}
+
+// UnlockAccount снимает блокировку входа и обнуляет счётчик неудач по запросу администратора adminID.
+// Возвращает false, если аккаунт не был заблокирован и неудач не было
+func (s *userService) UnlockAccount(ctx context.Context, adminID, userID int) (bool, error) {
+	const op = "service.users.UnlockAccount"
+
+	if _, err := s.userStorage.FindUserByID(ctx, userID); err != nil {
+		if !errors.Is(err, storage.ErrUserNotFound) {
+			log.Printf("Error finding user %d: %v, location %s", userID, err, op)
+		}
+		return false, err
+	}
+
+	unlocked, err := s.loginAttempts.Reset(ctx, userID)
+	if err != nil {
+		log.Printf("Error unlocking user %d: %v, location %s", userID, err, op)
+		return false, err
+	}
+	if !unlocked {
+		return false, nil
+	}
+
+	log.Printf("admin %d unlocked login of user %d", adminID, userID)
+	details := map[string]string{"admin_id": strconv.Itoa(adminID)}
+	if err := s.userStorage.SaveSecurityEvent(ctx, userID, models.SecurityEventLoginUnlock, details); err != nil {
+		log.Printf("Error saving security event: %v, location %s", err, op)
+	}
+	return true, nil
+}
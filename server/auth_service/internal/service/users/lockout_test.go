@@ -0,0 +1,221 @@
+package serviceUsers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/AuthService/config"
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/keyset"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// memoryLoginAttempts — LoginAttemptsI в памяти с теми же правилами, что и в Redis: до первой блокировки
+// допустимы threshold попыток, после каждой блокировки — ещё одна
+type memoryLoginAttempts struct {
+	failures    int64
+	lockBase    int64
+	lockedUntil time.Time
+	released    int
+}
+
+func (a *memoryLoginAttempts) ReserveAttempt(ctx context.Context, userID int, threshold int64, window time.Duration) (models.LoginAttempt, error) {
+	if time.Now().Before(a.lockedUntil) {
+		return models.LoginAttempt{LockedUntil: a.lockedUntil}, nil
+	}
+	a.failures++
+	limit := threshold
+	if a.lockBase > 0 && a.lockBase+1 > limit {
+		limit = a.lockBase + 1
+	}
+	return models.LoginAttempt{Failures: a.failures, OverLimit: a.failures > limit}, nil
+}
+
+func (a *memoryLoginAttempts) ReleaseAttempt(ctx context.Context, userID int) error {
+	if a.failures > 0 {
+		a.failures--
+	}
+	a.released++
+	return nil
+}
+
+func (a *memoryLoginAttempts) Lock(ctx context.Context, userID int, until time.Time, failures int64) error {
+	if until.After(a.lockedUntil) {
+		a.lockedUntil = until
+	}
+	if failures > a.lockBase {
+		a.lockBase = failures
+	}
+	return nil
+}
+
+func (a *memoryLoginAttempts) Reset(ctx context.Context, userID int) (bool, error) {
+	a.failures, a.lockBase, a.lockedUntil = 0, 0, time.Time{}
+	return true, nil
+}
+
+// lockoutStorage отдаёт одного пользователя и запоминает события безопасности
+type lockoutStorage struct {
+	UsersStorageI
+	user   models.UserModel
+	events []string
+}
+
+func (s *lockoutStorage) FindUser(ctx context.Context, email string) (models.UserModel, error) {
+	return s.user, nil
+}
+
+func (s *lockoutStorage) CreateDeviceSession(ctx context.Context, session models.DeviceSession, token models.RefreshToken) error {
+	return nil
+}
+
+func (s *lockoutStorage) SaveSecurityEvent(ctx context.Context, userID int, eventType string, details map[string]string) error {
+	s.events = append(s.events, eventType)
+	return nil
+}
+
+type countingMailer struct {
+	sent int
+}
+
+func (m *countingMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.sent++
+	return nil
+}
+
+func newLockoutTestService(t *testing.T, totp bool) (*userService, *memoryLoginAttempts, *lockoutStorage, *countingMailer) {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct"), bcrypt.MinCost)
+	require.NoError(t, err)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	store := &lockoutStorage{user: models.UserModel{ID: 7, Email: "user@example.com", Password: hash, TOTPEnabled: totp}}
+	attempts := &memoryLoginAttempts{}
+	mail := &countingMailer{}
+	cfg := config.Config{}
+	cfg.LoginLockout = config.LoginLockoutConfig{Threshold: 3, BaseDuration: time.Minute, MaxDuration: time.Hour, FailureWindow: time.Hour}
+	cfg.TwoFactor.ChallengeTTL = time.Minute
+	cfg.JWT.AccessTokenTTL = time.Minute
+	cfg.JWT.RefreshTokenTTL = time.Hour
+	s := NewUserService(store, nil, attempts, keyset.New(key), mail, nil, cfg)
+	return s, attempts, store, mail
+}
+
+func TestLoginLockoutDuration(t *testing.T) {
+	s := &userService{}
+	s.cfg.LoginLockout = config.LoginLockoutConfig{Threshold: 5, BaseDuration: time.Minute, MaxDuration: time.Hour}
+
+	tests := []struct {
+		failures int64
+		want     time.Duration
+	}{
+		{failures: 1, want: time.Minute}, // до порога срок не удваивается
+		{failures: 5, want: time.Minute},
+		{failures: 6, want: 2 * time.Minute},
+		{failures: 7, want: 4 * time.Minute},
+		{failures: 10, want: 32 * time.Minute},
+		{failures: 11, want: time.Hour}, // 64 минуты упираются в максимум
+		{failures: 40, want: time.Hour},
+		{failures: 5 + 64, want: time.Hour}, // сдвиг больше разрядности не переполняется
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, s.loginLockoutDuration(tt.failures), "failures = %d", tt.failures)
+	}
+}
+
+// Неудача на пороге блокирует вход, пишет событие и отправляет письмо; дальше даже верный пароль получает блокировку
+func TestLogin_LockedAtThreshold(t *testing.T) {
+	s, attempts, store, mail := newLockoutTestService(t, false)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, _, _, err := s.Login(ctx, "user@example.com", "wrong", "web", models.DeviceInfo{})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+	_, _, _, err := s.Login(ctx, "user@example.com", "wrong", "web", models.DeviceInfo{})
+	var locked *AccountLockedError
+	require.True(t, errors.As(err, &locked), "err = %v, want *AccountLockedError", err)
+	assert.WithinDuration(t, time.Now().Add(time.Minute), locked.Until, 5*time.Second)
+	assert.Equal(t, []string{models.SecurityEventLoginLockout}, store.events)
+	assert.Equal(t, 1, mail.sent)
+
+	_, _, _, err = s.Login(ctx, "user@example.com", "correct", "web", models.DeviceInfo{})
+	assert.True(t, errors.As(err, &locked), "err = %v, want *AccountLockedError", err)
+	assert.Equal(t, int64(3), attempts.failures)
+}
+
+// lockThenExpire набирает порог неудач и снимает блокировку так, будто её срок истёк
+func lockThenExpire(t *testing.T, s *userService, attempts *memoryLoginAttempts) {
+	t.Helper()
+	for i := 0; i < 3; i++ {
+		_, _, _, _ = s.Login(context.Background(), "user@example.com", "wrong", "web", models.DeviceInfo{})
+	}
+	require.False(t, attempts.lockedUntil.IsZero(), "the threshold must lock the login")
+	attempts.lockedUntil = time.Now().Add(-time.Second)
+}
+
+// Параллельные запросы уже заняли допустимые попытки: попытка сверх них блокирует вход, не проверяя пароль
+func TestLogin_AttemptBeyondThresholdRejected(t *testing.T) {
+	s, attempts, store, mail := newLockoutTestService(t, false)
+	attempts.failures = 3 // три попытки зарезервированы и ещё проверяются
+
+	_, _, _, err := s.Login(context.Background(), "user@example.com", "correct", "web", models.DeviceInfo{})
+	var locked *AccountLockedError
+	require.True(t, errors.As(err, &locked), "err = %v, want *AccountLockedError", err)
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), locked.Until, 5*time.Second)
+	assert.Equal(t, []string{models.SecurityEventLoginLockout}, store.events)
+	assert.Equal(t, 0, mail.sent, "the email is sent only once per series, at the threshold")
+}
+
+// Блокировка истекла: верный пароль входит, хотя счётчик неудач не меньше порога
+func TestLogin_LockExpiredCorrectPassword(t *testing.T) {
+	s, attempts, _, _ := newLockoutTestService(t, false)
+	lockThenExpire(t, s, attempts)
+
+	access, refresh, _, err := s.Login(context.Background(), "user@example.com", "correct", "web", models.DeviceInfo{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, access)
+	assert.NotEmpty(t, refresh)
+	assert.Equal(t, int64(0), attempts.failures, "a successful login resets the series")
+}
+
+// Блокировка истекла: неверный пароль проверяется и блокирует вход вдвое дольше
+func TestLogin_LockExpiredWrongPassword(t *testing.T) {
+	s, attempts, store, mail := newLockoutTestService(t, false)
+	lockThenExpire(t, s, attempts)
+
+	_, _, _, err := s.Login(context.Background(), "user@example.com", "wrong", "web", models.DeviceInfo{})
+	var locked *AccountLockedError
+	require.True(t, errors.As(err, &locked), "err = %v, want *AccountLockedError", err)
+	assert.WithinDuration(t, time.Now().Add(2*time.Minute), locked.Until, 5*time.Second)
+	assert.Len(t, store.events, 2)
+	assert.Equal(t, 1, mail.sent)
+
+	// следующая блокировка истекла, а параллельно проверяются уже две попытки: вторая пароль не проверяет
+	attempts.lockedUntil = time.Now().Add(-time.Second)
+	attempts.failures++ // попытка, которая ещё проверяется
+	_, _, _, err = s.Login(context.Background(), "user@example.com", "correct", "web", models.DeviceInfo{})
+	require.True(t, errors.As(err, &locked), "err = %v, want *AccountLockedError", err)
+}
+
+// Верный пароль при включённой 2FA возвращает попытку, но серию не обнуляет
+func TestLogin_TwoFactorReleasesAttempt(t *testing.T) {
+	s, attempts, _, _ := newLockoutTestService(t, true)
+	ctx := context.Background()
+
+	_, _, _, err := s.Login(ctx, "user@example.com", "wrong", "web", models.DeviceInfo{})
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	_, _, challenge, err := s.Login(ctx, "user@example.com", "correct", "web", models.DeviceInfo{})
+	require.NoError(t, err)
+	assert.NotEmpty(t, challenge)
+	assert.Equal(t, 1, attempts.released)
+	assert.Equal(t, int64(1), attempts.failures)
+}
@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 
+	"github.com/1abobik1/AuthService/internal/domain/models"
 	"github.com/1abobik1/AuthService/internal/storage"
 	"github.com/1abobik1/AuthService/internal/utils"
 	"golang.org/x/crypto/bcrypt"
@@ -15,40 +17,107 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 )
 
-func (s *userService) Login(ctx context.Context, email, password, platform string) (accessJWT string, refreshJWT string, er error) {
+// Login проверяет пароль и выдаёт токены. Если у пользователя включена 2FA, токены не выдаются:
+// возвращается challengeJWT, с которым нужно пройти второй шаг (LoginTwoFactor). device — сведения об устройстве для новой сессии.
+// Неверный пароль учитывается в блокировке аккаунта; пока она действует, возвращается *AccountLockedError
+func (s *userService) Login(ctx context.Context, email, password, platform string, device models.DeviceInfo) (accessJWT string, refreshJWT string, challengeJWT string, er error) {
 	const op = "service.users.Login"
 
 	userModel, err := s.userStorage.FindUser(ctx, email)
 	if err != nil {
 		if errors.Is(err, storage.ErrUserNotFound) {
 			log.Printf("Warning: %v, location %s", err, op)
-			return "", "", err
+			return "", "", "", err
 		}
 		log.Printf("Error failed to save user: %v, location %s", err, op)
-		return "", "", err
+		return "", "", "", err
+	}
+
+	failures, err := s.reserveLoginAttempt(ctx, userModel, device.IP)
+	if err != nil {
+		return "", "", "", err
 	}
 
 	if err := bcrypt.CompareHashAndPassword(userModel.Password, []byte(password)); err != nil {
 		log.Printf("Wrong password: %v, location %s", err, op)
-		return "", "", ErrInvalidCredentials
+		return "", "", "", s.loginFailed(ctx, userModel, device.IP, failures, ErrInvalidCredentials)
 	}
 
-	accessToken, err := utils.CreateAccessToken(userModel.ID, s.cfg.JWT.AccessTokenTTL, s.cfg.JWT.PrivateKeyPath)
+	if userModel.TOTPEnabled {
+		// пароль верный, но вход не завершён: серия неудач не сбрасывается до проверки кода
+		s.releaseLoginAttempt(ctx, userModel.ID)
+		challenge, err := utils.CreateChallengeToken(userModel.ID, platform, utils.AuthMethodPassword, s.cfg.TwoFactor.ChallengeTTL, s.keys)
+		if err != nil {
+			log.Printf("Error creating challenge token: %v, location %s \n", err, op)
+			return "", "", "", fmt.Errorf("error creating challenge token: %w", err)
+		}
+		return "", "", challenge, nil
+	}
+
+	s.resetLoginFailures(ctx, userModel.ID)
+	accessToken, refreshToken, err := s.issueTokens(ctx, userModel, platform, device)
+	if err != nil {
+		return "", "", "", err
+	}
+	return accessToken, refreshToken, "", nil
+}
+
+// issueTokens начинает сессию устройства и выдаёт access и refresh токены после успешного входа
+func (s *userService) issueTokens(ctx context.Context, userModel models.UserModel, platform string, device models.DeviceInfo) (accessJWT string, refreshJWT string, er error) {
+	const op = "service.users.issueTokens"
+
+	sessionID, refreshToken, err := s.newDeviceSession(ctx, userModel.ID, platform, device)
+	if err != nil {
+		return "", "", err
+	}
+
+	accessToken, err := utils.CreateAccessToken(userModel.ID, userModel.Role, userModel.IsActivated, sessionID, s.cfg.JWT.AccessTokenTTL, s.keys)
 	if err != nil {
 		log.Printf("Error creating access token: %v, location %s \n", err, op)
-		return  "", "", fmt.Errorf("error creating access token: %w", err)
+		return "", "", fmt.Errorf("error creating access token: %w", err)
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// newDeviceSession сохраняет сессию устройства и выдаёт первый refresh токен её семейства.
+// Возвращает ID сессии (он же семейство refresh токенов и claim sid access токена)
+func (s *userService) newDeviceSession(ctx context.Context, userID int, platform string, device models.DeviceInfo) (string, string, error) {
+	const op = "service.users.newDeviceSession"
+
+	jti, err := utils.NewTokenID()
+	if err != nil {
+		return "", "", err
+	}
+	sessionID, err := utils.NewTokenID()
+	if err != nil {
+		return "", "", err
 	}
 
-	refreshToken, err := utils.CreateRefreshToken(userModel.ID, s.cfg.JWT.RefreshTokenTTL, s.cfg.JWT.PrivateKeyPath)
+	refreshToken, err := utils.CreateRefreshToken(userID, jti, sessionID, s.cfg.JWT.RefreshTokenTTL, s.keys)
 	if err != nil {
 		log.Printf("Error creating refresh token: %v, location %s \n", err, op)
 		return "", "", fmt.Errorf("error creating refresh token: %w", err)
 	}
 
-	if err := s.userStorage.UpsertRefreshToken(ctx, refreshToken, userModel.ID, platform); err != nil {
-		log.Printf("Error upserting refresh token in db: %v", err)
-		return "", "", fmt.Errorf("error upserting refresh token in db: %w", err)
+	expiresAt := time.Now().Add(s.cfg.JWT.RefreshTokenTTL)
+	err = s.userStorage.CreateDeviceSession(ctx, models.DeviceSession{
+		ID:         sessionID,
+		UserID:     userID,
+		Platform:   platform,
+		DeviceInfo: device,
+		ExpiresAt:  expiresAt,
+	}, models.RefreshToken{
+		JTI:       jti,
+		FamilyID:  sessionID,
+		UserID:    userID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		log.Printf("Error saving device session in db: %v, location %s", err, op)
+		return "", "", fmt.Errorf("error saving device session in db: %w", err)
 	}
 
-	return accessToken, refreshToken, nil
+	return sessionID, refreshToken, nil
 }
@@ -1,7 +1,21 @@
 package serviceUsers
 
-import "context"
+import (
+	"context"
+	"log"
 
+	"github.com/1abobik1/AuthService/internal/utils"
+)
+
+// RevokeRefreshToken завершает сессию устройства: отзывает всё семейство токена, чтобы выход нельзя было обойти
+// ранее обменянными токенами, и добавляет сессию в список отзыва access токенов
 func (s *userService) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
-	return s.userStorage.DeleteRefreshToken(ctx, refreshToken)
+	userID, sessionID, err := s.userStorage.RevokeRefreshToken(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("user %d logged out of device session %s", userID, sessionID)
+	s.revokeDeviceSession(ctx, sessionID)
+	return nil
 }
@@ -0,0 +1,221 @@
+package serviceUsers
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/oidc"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/1abobik1/AuthService/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrUnknownOIDCProvider  = errors.New("unknown oidc provider")
+	ErrOIDCLoginFailed      = errors.New("oidc provider did not confirm the login")
+	ErrOIDCAccountExists    = errors.New("an account with this email already exists, log in and link the provider")
+	ErrOIDCEmailMissing     = errors.New("oidc provider did not return an email")
+	ErrKSKeyRequired        = errors.New("ks_key is required for web login")
+	ErrOIDCBrowserMismatch  = errors.New("the login was started in another browser, start it again")
+	ErrOIDCLinkAuthRequired = errors.New("log in to the account the provider is being linked to")
+	ErrOIDCLinkUserMismatch = errors.New("the provider is being linked to another account")
+)
+
+// OIDCLoginResult — итог возврата пользователя от OIDC провайдера: токены, challenge-токен 2FA
+// (если у пользователя включена 2FA) или Linked, если провайдер привязывался к аккаунту
+type OIDCLoginResult struct {
+	AccessToken    string
+	RefreshToken   string
+	ChallengeToken string
+	Platform       string
+	Linked         bool
+}
+
+// StartOIDCLogin начинает вход через провайдера и возвращает адрес его страницы входа
+// и binding — секрет, который клиент хранит в cookie и предъявляет вместе с state в OIDCCallback
+func (s *userService) StartOIDCLogin(ctx context.Context, provider, platform string) (string, string, error) {
+	return s.startOIDC(ctx, provider, platform, 0)
+}
+
+// StartOIDCLink начинает привязку провайдера к аккаунту userID и возвращает адрес его страницы входа и binding
+func (s *userService) StartOIDCLink(ctx context.Context, userID int, provider string) (string, string, error) {
+	// платформа при привязке не используется: токены не выдаются
+	return s.startOIDC(ctx, provider, "web", userID)
+}
+
+func (s *userService) startOIDC(ctx context.Context, providerName, platform string, linkUserID int) (string, string, error) {
+	const op = "service.users.startOIDC"
+
+	provider, ok := s.oidcProviders[providerName]
+	if !ok {
+		return "", "", ErrUnknownOIDCProvider
+	}
+
+	state, err := newOneTimeToken()
+	if err != nil {
+		return "", "", err
+	}
+	nonce, err := newOneTimeToken()
+	if err != nil {
+		return "", "", err
+	}
+	// binding привязывает state к браузеру, начавшему вход: state из чужой ссылки без cookie не сработает
+	binding, err := newOneTimeToken()
+	if err != nil {
+		return "", "", err
+	}
+	verifier, challenge, err := oidc.NewPKCE()
+	if err != nil {
+		return "", "", err
+	}
+
+	authURL, err := provider.AuthCodeURL(ctx, state, nonce, challenge)
+	if err != nil {
+		log.Printf("Error building %s authorization url: %v, location %s", providerName, err, op)
+		return "", "", err
+	}
+
+	err = s.userStorage.SaveOIDCAuthRequest(ctx, models.OIDCAuthRequest{
+		StateHash:    hashOneTimeToken(state),
+		BindingHash:  hashOneTimeToken(binding),
+		Provider:     providerName,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		Platform:     platform,
+		LinkUserID:   linkUserID,
+		ExpiresAt:    time.Now().Add(s.cfg.OIDC.StateTTL),
+	})
+	if err != nil {
+		log.Printf("Error saving oidc auth request: %v, location %s", err, op)
+		return "", "", err
+	}
+
+	return authURL, binding, nil
+}
+
+// OIDCCallback завершает вход по code и state, с которыми провайдер вернул пользователя. state одноразовый
+// и принимается только вместе с binding из StartOIDCLogin/StartOIDCLink, иначе ErrOIDCBrowserMismatch.
+// Привязку завершает только тот, кто её начал: userID из access токена должен совпасть с LinkUserID.
+// Учётная запись провайдера, ещё не привязанная ни к кому, регистрирует новый аккаунт; если её email уже занят,
+// возвращается ErrOIDCAccountExists — чужой аккаунт автоматически не привязывается.
+// Пароля при таком входе нет, поэтому web-клиент передаёт ksKey — случайный ключ, которым вместо пароля шифруются ks
+func (s *userService) OIDCCallback(ctx context.Context, providerName, code, state, binding string, userID int, ksKey string, device models.DeviceInfo) (OIDCLoginResult, error) {
+	const op = "service.users.OIDCCallback"
+
+	provider, ok := s.oidcProviders[providerName]
+	if !ok {
+		return OIDCLoginResult{}, ErrUnknownOIDCProvider
+	}
+
+	req, err := s.userStorage.ConsumeOIDCAuthRequest(ctx, providerName, hashOneTimeToken(state))
+	if err != nil {
+		if !errors.Is(err, storage.ErrOIDCStateNotFound) {
+			log.Printf("Error consuming oidc auth request: %v, location %s", err, op)
+		}
+		return OIDCLoginResult{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashOneTimeToken(binding)), []byte(req.BindingHash)) != 1 {
+		log.Printf("Warning: %s callback from another browser, location %s", providerName, op)
+		return OIDCLoginResult{}, ErrOIDCBrowserMismatch
+	}
+	if req.LinkUserID != 0 && userID == 0 {
+		return OIDCLoginResult{}, ErrOIDCLinkAuthRequired
+	}
+	if req.LinkUserID != 0 && userID != req.LinkUserID {
+		log.Printf("Warning: user %d tried to finish linking %s to user %d, location %s", userID, providerName, req.LinkUserID, op)
+		return OIDCLoginResult{}, ErrOIDCLinkUserMismatch
+	}
+	if req.LinkUserID == 0 && req.Platform == "web" && ksKey == "" {
+		return OIDCLoginResult{}, ErrKSKeyRequired
+	}
+
+	claims, err := provider.Exchange(ctx, code, req.CodeVerifier, req.Nonce)
+	if err != nil {
+		log.Printf("Warning: %s login failed: %v, location %s", providerName, err, op)
+		return OIDCLoginResult{}, fmt.Errorf("%w: %v", ErrOIDCLoginFailed, err)
+	}
+	identity := models.OIDCIdentity{Provider: providerName, Subject: claims.Subject, Email: claims.Email}
+
+	if req.LinkUserID != 0 {
+		if err := s.userStorage.LinkOIDCIdentity(ctx, req.LinkUserID, identity); err != nil {
+			if !errors.Is(err, storage.ErrOIDCIdentityLinked) {
+				log.Printf("Error linking %s identity to user %d: %v, location %s", providerName, req.LinkUserID, err, op)
+			}
+			return OIDCLoginResult{}, err
+		}
+		log.Printf("user %d linked %s identity", req.LinkUserID, providerName)
+		return OIDCLoginResult{Linked: true}, nil
+	}
+
+	user, err := s.userStorage.FindUserByOIDCIdentity(ctx, providerName, claims.Subject)
+	if errors.Is(err, storage.ErrUserNotFound) {
+		user, err = s.registerOIDCUser(ctx, identity, claims.EmailVerified)
+	}
+	if err != nil {
+		return OIDCLoginResult{}, err
+	}
+
+	if user.TOTPEnabled {
+		challenge, err := utils.CreateChallengeToken(user.ID, req.Platform, utils.AuthMethodOIDC, s.cfg.TwoFactor.ChallengeTTL, s.keys)
+		if err != nil {
+			log.Printf("Error creating challenge token: %v, location %s \n", err, op)
+			return OIDCLoginResult{}, fmt.Errorf("error creating challenge token: %w", err)
+		}
+		return OIDCLoginResult{ChallengeToken: challenge, Platform: req.Platform}, nil
+	}
+
+	accessToken, refreshToken, err := s.issueTokens(ctx, user, req.Platform, device)
+	if err != nil {
+		return OIDCLoginResult{}, err
+	}
+	return OIDCLoginResult{AccessToken: accessToken, RefreshToken: refreshToken, Platform: req.Platform}, nil
+}
+
+// registerOIDCUser регистрирует пользователя, впервые вошедшего через провайдера. Пароль случайный и никому
+// не известен: задать свой можно через сброс пароля. Email, не подтверждённый провайдером, подтверждается письмом
+func (s *userService) registerOIDCUser(ctx context.Context, identity models.OIDCIdentity, emailVerified bool) (models.UserModel, error) {
+	const op = "service.users.registerOIDCUser"
+
+	if identity.Email == "" {
+		return models.UserModel{}, ErrOIDCEmailMissing
+	}
+
+	password, err := newOneTimeToken()
+	if err != nil {
+		return models.UserModel{}, err
+	}
+	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("Error bcrypt.GenerateFromPassword: %v, location %s \n", err, op)
+		return models.UserModel{}, fmt.Errorf("error bcrypt.GenerateFromPassword: %w", err)
+	}
+
+	userID, err := s.userStorage.CreateOIDCUser(ctx, identity, passHash, emailVerified)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserExists) {
+			log.Printf("Warning: %s login with registered email, location %s", identity.Provider, op)
+			return models.UserModel{}, ErrOIDCAccountExists
+		}
+		log.Printf("Error failed to save user: %v, location %s", err, op)
+		return models.UserModel{}, err
+	}
+	log.Printf("user %d registered via %s", userID, identity.Provider)
+
+	if err := s.initFreePlan(userID); err != nil {
+		return models.UserModel{}, err
+	}
+
+	if !emailVerified {
+		// письмо не критично для входа: пользователь может запросить его повторно
+		if err := s.sendVerification(ctx, userID, identity.Email, 0); err != nil {
+			log.Printf("warning: failed to send verification email to user %d: %v, location %s", userID, err, op)
+		}
+	}
+
+	return models.UserModel{ID: userID, Email: identity.Email, IsActivated: emailVerified, Role: models.RoleUser}, nil
+}
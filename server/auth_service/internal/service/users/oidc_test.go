@@ -0,0 +1,116 @@
+package serviceUsers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/AuthService/config"
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/oidc"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/stretchr/testify/assert"
+)
+
+// oidcStorage хранит начатые входы в памяти; остальные методы UsersStorageI тестам не нужны
+type oidcStorage struct {
+	UsersStorageI
+	requests map[string]models.OIDCAuthRequest
+	linked   map[int]models.OIDCIdentity
+}
+
+func (s *oidcStorage) SaveOIDCAuthRequest(ctx context.Context, req models.OIDCAuthRequest) error {
+	s.requests[req.StateHash] = req
+	return nil
+}
+
+func (s *oidcStorage) ConsumeOIDCAuthRequest(ctx context.Context, provider, stateHash string) (models.OIDCAuthRequest, error) {
+	req, ok := s.requests[stateHash]
+	if !ok || req.Provider != provider {
+		return models.OIDCAuthRequest{}, storage.ErrOIDCStateNotFound
+	}
+	delete(s.requests, stateHash)
+	return req, nil
+}
+
+func (s *oidcStorage) LinkOIDCIdentity(ctx context.Context, userID int, identity models.OIDCIdentity) error {
+	s.linked[userID] = identity
+	return nil
+}
+
+// stateProvider запоминает state из AuthCodeURL, чтобы тест вернулся с ним в OIDCCallback
+type stateProvider struct {
+	state     string
+	exchanged int
+}
+
+func (p *stateProvider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	p.state = state
+	return "https://idp.example.com/authorize?state=" + state, nil
+}
+
+func (p *stateProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (oidc.Claims, error) {
+	p.exchanged++
+	return oidc.Claims{Subject: "idp|1", Email: "user@example.com", EmailVerified: true}, nil
+}
+
+func newOIDCTestService() (*userService, *oidcStorage, *stateProvider) {
+	store := &oidcStorage{requests: map[string]models.OIDCAuthRequest{}, linked: map[int]models.OIDCIdentity{}}
+	provider := &stateProvider{}
+	cfg := config.Config{}
+	cfg.OIDC.StateTTL = 10 * time.Minute
+	s := NewUserService(store, nil, nil, nil, nil, map[string]OIDCProviderI{"google": provider}, cfg)
+	return s, store, provider
+}
+
+// state из ссылки, открытой в другом браузере (без его cookie), не принимается, и code не обменивается
+func TestOIDCCallback_AnotherBrowser(t *testing.T) {
+	s, _, provider := newOIDCTestService()
+	ctx := context.Background()
+
+	_, binding, err := s.StartOIDCLogin(ctx, "google", "tg-bot")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, binding)
+
+	_, err = s.OIDCCallback(ctx, "google", "code", provider.state, "", 0, "", models.DeviceInfo{})
+	assert.ErrorIs(t, err, ErrOIDCBrowserMismatch)
+
+	_, err = s.OIDCCallback(ctx, "google", "code", provider.state, binding, 0, "", models.DeviceInfo{})
+	assert.ErrorIs(t, err, storage.ErrOIDCStateNotFound, "state must be consumed by the first attempt")
+	assert.Equal(t, 0, provider.exchanged)
+}
+
+// Привязку, начатую пользователем 7, не может завершить пользователь 8 или запрос без access токена
+func TestOIDCCallback_LinkByAnotherUser(t *testing.T) {
+	s, store, provider := newOIDCTestService()
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		userID int
+		err    error
+	}{
+		{userID: 8, err: ErrOIDCLinkUserMismatch},
+		{userID: 0, err: ErrOIDCLinkAuthRequired},
+	} {
+		_, binding, err := s.StartOIDCLink(ctx, 7, "google")
+		assert.NoError(t, err)
+
+		_, err = s.OIDCCallback(ctx, "google", "code", provider.state, binding, tc.userID, "", models.DeviceInfo{})
+		assert.ErrorIs(t, err, tc.err)
+	}
+	assert.Equal(t, 0, provider.exchanged)
+	assert.Empty(t, store.linked)
+}
+
+func TestOIDCCallback_LinkSameUserAndBrowser(t *testing.T) {
+	s, store, provider := newOIDCTestService()
+	ctx := context.Background()
+
+	_, binding, err := s.StartOIDCLink(ctx, 7, "google")
+	assert.NoError(t, err)
+
+	res, err := s.OIDCCallback(ctx, "google", "code", provider.state, binding, 7, "", models.DeviceInfo{})
+	assert.NoError(t, err)
+	assert.True(t, res.Linked)
+	assert.Equal(t, "idp|1", store.linked[7].Subject)
+}
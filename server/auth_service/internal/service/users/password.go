@@ -0,0 +1,125 @@
+package serviceUsers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/external_api"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/1abobik1/AuthService/internal/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ForgotPassword отправляет письмо со ссылкой сброса пароля. Для неизвестного адреса ничего не делает и не сообщает
+// об этом, чтобы по ответу нельзя было проверить, зарегистрирован ли email.
+// Чаще PASSWORD_RESET_RESEND_INTERVAL письма не отправляются (storage.ErrResetThrottled).
+func (s *userService) ForgotPassword(ctx context.Context, email string) error {
+	const op = "service.users.ForgotPassword"
+
+	user, err := s.userStorage.FindUser(ctx, email)
+	if err != nil {
+		if errors.Is(err, storage.ErrUserNotFound) {
+			return nil
+		}
+		log.Printf("Error finding user: %v, location %s", err, op)
+		return err
+	}
+
+	token, err := newOneTimeToken()
+	if err != nil {
+		return err
+	}
+	if err := s.userStorage.SavePasswordReset(ctx, user.ID, hashOneTimeToken(token), s.cfg.PasswordReset.TTL, s.cfg.PasswordReset.ResendInterval); err != nil {
+		return err
+	}
+
+	link := s.cfg.PasswordReset.URL + "?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Для смены пароля перейдите по ссылке:\n%s\n\n"+
+		"Или отправьте код: %s\n\nСсылка действует до %s. Если вы не запрашивали сброс пароля, просто проигнорируйте это письмо.",
+		link, token, time.Now().Add(s.cfg.PasswordReset.TTL).UTC().Format(time.RFC1123))
+
+	if err := s.mailer.Send(ctx, user.Email, "SecureComm: сброс пароля", body); err != nil {
+		return fmt.Errorf("send password reset email: %w", err)
+	}
+	return nil
+}
+
+// ResetPassword устанавливает новый пароль по токену из письма. Ссылка одноразовая.
+// Все refresh и access токены пользователя отзываются, его сессии в secure_comm_service сбрасываются.
+func (s *userService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	const op = "service.users.ResetPassword"
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("Error bcrypt.GenerateFromPassword: %v, location %s \n", err, op)
+		return fmt.Errorf("error bcrypt.GenerateFromPassword: %w", err)
+	}
+
+	userID, err := s.userStorage.ResetPassword(ctx, hashOneTimeToken(token), passHash)
+	if err != nil {
+		if !errors.Is(err, storage.ErrResetNotFound) {
+			log.Printf("Error resetting password: %v, location %s", err, op)
+		}
+		return err
+	}
+
+	log.Printf("user %d reset password", userID)
+	// сброс по ссылке из письма подтверждает владельца, поэтому снимает и блокировку входа
+	s.resetLoginFailures(ctx, userID)
+	s.revokeAccessTokens(ctx, userID)
+	s.revokeSessions(userID)
+	return nil
+}
+
+// ChangePassword меняет пароль после проверки текущего. Все refresh токены пользователя (в том числе текущий)
+// и access токены отзываются, его сессии в secure_comm_service сбрасываются.
+func (s *userService) ChangePassword(ctx context.Context, userID int, currentPassword, newPassword string) error {
+	const op = "service.users.ChangePassword"
+
+	user, err := s.userStorage.FindUserByID(ctx, userID)
+	if err != nil {
+		log.Printf("Error finding user %d: %v, location %s", userID, err, op)
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword(user.Password, []byte(currentPassword)); err != nil {
+		log.Printf("Wrong current password of user %d, location %s", userID, op)
+		return ErrInvalidCredentials
+	}
+
+	passHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		log.Printf("Error bcrypt.GenerateFromPassword: %v, location %s \n", err, op)
+		return fmt.Errorf("error bcrypt.GenerateFromPassword: %w", err)
+	}
+
+	if err := s.userStorage.ChangePassword(ctx, userID, passHash); err != nil {
+		log.Printf("Error changing password: %v, location %s", err, op)
+		return err
+	}
+
+	log.Printf("user %d changed password", userID)
+	s.revokeAccessTokens(ctx, userID)
+	s.revokeSessions(userID)
+	return nil
+}
+
+// revokeSessions сбрасывает сессии пользователя в secure_comm_service. Пароль к этому моменту уже сменён,
+// поэтому ошибка только логируется: сессии всё равно истекут по TTL
+func (s *userService) revokeSessions(userID int) {
+	const op = "service.users.revokeSessions"
+
+	serviceToken, err := utils.CreateServiceToken(s.cfg.ServiceToken.QuotaAudience, []string{external_api.ScopeSessionsRevoke}, s.cfg.ServiceToken.TTL, s.keys)
+	if err != nil {
+		log.Printf("warning: failed to create service token: %v, location %s", err, op)
+		return
+	}
+
+	if err := external_api.RevokeUserSessions(s.cfg.ExternalAPIs.QuotaServiceURL, userID, serviceToken); err != nil {
+		log.Printf("warning: failed to revoke sessions of user %d: %v, location %s", userID, err, op)
+	}
+}
@@ -2,28 +2,91 @@ package serviceUsers
 
 import (
 	"context"
+	"time"
 
 	"github.com/1abobik1/AuthService/config"
 	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/keyset"
+	"github.com/1abobik1/AuthService/internal/mailer"
+	"github.com/1abobik1/AuthService/internal/oidc"
 )
 
 type UsersStorageI interface {
 	SaveUser(ctx context.Context, email string, password []byte) (int, error)
-	UpsertRefreshToken(ctx context.Context, refreshToken string, userID int, platform string) error
+	CreateDeviceSession(ctx context.Context, session models.DeviceSession, token models.RefreshToken) error
+	ListDeviceSessions(ctx context.Context, userID int) ([]models.DeviceSession, error)
+	RevokeDeviceSession(ctx context.Context, userID int, sessionID string) error
+	RevokeAllDeviceSessions(ctx context.Context, userID int) ([]string, error)
 	FindUser(ctx context.Context, email string) (models.UserModel, error)
-	DeleteRefreshToken(сtx context.Context, refreshToken string) error
+	RevokeRefreshToken(ctx context.Context, tokenHash string) (int, string, error)
 	SaveUserKey(ctx context.Context, userID int, userKey string) error
 	GetUserKey(ctx context.Context, userID int) (string, error)
+	SaveEmailVerification(ctx context.Context, userID int, tokenHash string, ttl, resendInterval time.Duration) error
+	ConfirmEmail(ctx context.Context, tokenHash string) (int, error)
+	FindUserByID(ctx context.Context, userID int) (models.UserModel, error)
+	SavePasswordReset(ctx context.Context, userID int, tokenHash string, ttl, resendInterval time.Duration) error
+	ResetPassword(ctx context.Context, tokenHash string, passHash []byte) (int, error)
+	ChangePassword(ctx context.Context, userID int, passHash []byte) error
+	SetTOTPSecret(ctx context.Context, userID int, secret string) error
+	GetTOTPSecret(ctx context.Context, userID int) (string, bool, error)
+	UseTOTPStep(ctx context.Context, userID int, step int64) error
+	EnableTOTP(ctx context.Context, userID int, recoveryCodeHashes []string) error
+	UseRecoveryCode(ctx context.Context, userID int, codeHash string) error
+	DisableTOTP(ctx context.Context, userID int) error
+	SaveOIDCAuthRequest(ctx context.Context, req models.OIDCAuthRequest) error
+	ConsumeOIDCAuthRequest(ctx context.Context, provider, stateHash string) (models.OIDCAuthRequest, error)
+	FindUserByOIDCIdentity(ctx context.Context, provider, subject string) (models.UserModel, error)
+	LinkOIDCIdentity(ctx context.Context, userID int, identity models.OIDCIdentity) error
+	CreateOIDCUser(ctx context.Context, identity models.OIDCIdentity, passHash []byte, emailVerified bool) (int, error)
+	CreateAccountDeletion(ctx context.Context, userID int, email, tokenHash string, scheduledAt time.Time) (models.AccountDeletion, error)
+	CancelAccountDeletion(ctx context.Context, userID int) error
+	ClaimAccountDeletion(ctx context.Context, lease time.Duration) (models.AccountDeletion, error)
+	SaveAccountDeletionStep(ctx context.Context, id int, step string, receipt []byte) error
+	FailAccountDeletion(ctx context.Context, id int, lastError string, retryAt time.Time, receipt []byte) error
+	CompleteAccountDeletion(ctx context.Context, id int, receipt []byte) error
+	FindAccountDeletion(ctx context.Context, tokenHash string) (models.AccountDeletion, error)
+	DeleteUser(ctx context.Context, userID int) (int64, error)
+	SaveSecurityEvent(ctx context.Context, userID int, eventType string, details map[string]string) error
+}
+
+// LoginAttemptsI — неудачные попытки входа и блокировки аккаунтов, общие для всех экземпляров сервиса
+type LoginAttemptsI interface {
+	ReserveAttempt(ctx context.Context, userID int, threshold int64, window time.Duration) (models.LoginAttempt, error)
+	ReleaseAttempt(ctx context.Context, userID int) error
+	Lock(ctx context.Context, userID int, until time.Time, failures int64) error
+	Reset(ctx context.Context, userID int) (bool, error)
+}
+
+// TokenRevocationI — список отзыва access токенов, который проверяет secure_comm_service
+type TokenRevocationI interface {
+	RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error
+	RevokeUserTokensBefore(ctx context.Context, userID int, t time.Time, ttl time.Duration) error
+}
+
+// OIDCProviderI — внешний OIDC провайдер (authorization code flow с PKCE)
+type OIDCProviderI interface {
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (oidc.Claims, error)
 }
 
 type userService struct {
-	userStorage UsersStorageI
-	cfg         config.Config
+	userStorage   UsersStorageI
+	revocations   TokenRevocationI
+	loginAttempts LoginAttemptsI
+	keys          *keyset.KeySet
+	mailer        mailer.Mailer
+	oidcProviders map[string]OIDCProviderI
+	cfg           config.Config
 }
 
-func NewUserService(userStorage UsersStorageI, cfg config.Config) *userService {
+func NewUserService(userStorage UsersStorageI, revocations TokenRevocationI, loginAttempts LoginAttemptsI, keys *keyset.KeySet, mailer mailer.Mailer, oidcProviders map[string]OIDCProviderI, cfg config.Config) *userService {
 	return &userService{
-		userStorage: userStorage,
-		cfg:         cfg,
+		userStorage:   userStorage,
+		revocations:   revocations,
+		loginAttempts: loginAttempts,
+		keys:          keys,
+		mailer:        mailer,
+		oidcProviders: oidcProviders,
+		cfg:           cfg,
 	}
 }
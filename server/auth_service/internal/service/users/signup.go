@@ -6,13 +6,14 @@ import (
 	"fmt"
 	"log"
 
+	"github.com/1abobik1/AuthService/internal/domain/models"
 	"github.com/1abobik1/AuthService/internal/external_api"
 	"github.com/1abobik1/AuthService/internal/storage"
 	"github.com/1abobik1/AuthService/internal/utils"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func (s *userService) Register(ctx context.Context, email, password, platform string) (accessJWT string, refreshJWT string, er error) {
+func (s *userService) Register(ctx context.Context, email, password, platform string, device models.DeviceInfo) (accessJWT string, refreshJWT string, er error) {
 	const op = "service.users.Register"
 
 	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -32,27 +33,34 @@ func (s *userService) Register(ctx context.Context, email, password, platform st
 		return "", "", err
 	}
 
-	accessToken, err := utils.CreateAccessToken(userID, s.cfg.JWT.AccessTokenTTL, s.cfg.JWT.PrivateKeyPath)
+	accessToken, refreshToken, err := s.issueTokens(ctx, models.UserModel{ID: userID, Role: models.RoleUser}, platform, device)
 	if err != nil {
-		log.Printf("Error creating access token: %v \n", err)
-		return "", "", fmt.Errorf("error creating access token: %w", err)
+		return "", "", err
 	}
 
-	refreshToken, err := utils.CreateRefreshToken(userID, s.cfg.JWT.RefreshTokenTTL, s.cfg.JWT.PrivateKeyPath)
-	if err != nil {
-		log.Printf("Error creating refresh token: %v \n", err)
-		return "", "", fmt.Errorf("error creating refresh token: %w", err)
+	if err := s.initFreePlan(userID); err != nil {
+		return "", "", err
 	}
 
-	if err := s.userStorage.UpsertRefreshToken(ctx, refreshToken, userID, platform); err != nil {
-		log.Printf("Error upserting refresh token in db: %v", err)
-		return "", "", fmt.Errorf("error upserting refresh token in db: %w", err)
+	// письмо не критично для регистрации: пользователь может запросить его повторно
+	if err := s.sendVerification(ctx, userID, email, 0); err != nil {
+		log.Printf("warning: failed to send verification email to user %d: %v, location %s", userID, err, op)
 	}
 
-	if err := external_api.NotifyQuotaService(s.cfg.ExternalAPIs.QuotaServiceURL, userID, accessToken); err != nil {
-		log.Printf("warning: failed to init free plan for user %d: %v", userID, err)
-		return "", "", fmt.Errorf("failed to init free plan for user %d: %v", userID, err)
+	return accessToken, refreshToken, nil
+}
+
+// initFreePlan подключает новому пользователю бесплатный тариф в secure_comm_service
+func (s *userService) initFreePlan(userID int) error {
+	serviceToken, err := utils.CreateServiceToken(s.cfg.ServiceToken.QuotaAudience, []string{external_api.ScopePlanInit}, s.cfg.ServiceToken.TTL, s.keys)
+	if err != nil {
+		log.Printf("Error creating service token: %v \n", err)
+		return fmt.Errorf("error creating service token: %w", err)
 	}
 
-	return accessToken, refreshToken, nil
+	if err := external_api.NotifyQuotaService(s.cfg.ExternalAPIs.QuotaServiceURL, userID, serviceToken); err != nil {
+		log.Printf("warning: failed to init free plan for user %d: %v", userID, err)
+		return fmt.Errorf("failed to init free plan for user %d: %v", userID, err)
+	}
+	return nil
 }
@@ -0,0 +1,232 @@
+package serviceUsers
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/1abobik1/AuthService/internal/utils"
+	"github.com/1abobik1/AuthService/pkg/auth/validation"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidTOTPCode  = errors.New("invalid two-factor code")
+	ErrInvalidChallenge = errors.New("login challenge is invalid or expired, log in again")
+	ErrPasswordRequired = errors.New("password is required for the web platform")
+)
+
+// EnrollTOTP начинает подключение 2FA: создаёт секрет и otpauth URI для приложения-аутентификатора.
+// 2FA включится только после ConfirmTOTP с кодом из приложения.
+func (s *userService) EnrollTOTP(ctx context.Context, userID int) (secret string, uri string, er error) {
+	const op = "service.users.EnrollTOTP"
+
+	user, err := s.userStorage.FindUserByID(ctx, userID)
+	if err != nil {
+		log.Printf("Error finding user %d: %v, location %s", userID, err, op)
+		return "", "", err
+	}
+	if user.TOTPEnabled {
+		return "", "", storage.ErrTOTPAlreadyEnabled
+	}
+
+	secret, err = utils.GenerateTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.userStorage.SetTOTPSecret(ctx, userID, secret); err != nil {
+		return "", "", err
+	}
+
+	return secret, utils.TOTPURI(s.cfg.TwoFactor.Issuer, user.Email, secret), nil
+}
+
+// ConfirmTOTP включает 2FA, если код из приложения подходит к секрету из EnrollTOTP.
+// Возвращает коды восстановления — они показываются один раз, в базе хранятся только хеши.
+func (s *userService) ConfirmTOTP(ctx context.Context, userID int, code string) ([]string, error) {
+	const op = "service.users.ConfirmTOTP"
+
+	secret, enabled, err := s.userStorage.GetTOTPSecret(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, storage.ErrTOTPAlreadyEnabled
+	}
+
+	if err := s.checkTOTP(ctx, userID, secret, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes(s.cfg.TwoFactor.RecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.userStorage.EnableTOTP(ctx, userID, hashes); err != nil {
+		log.Printf("Error enabling 2FA for user %d: %v, location %s", userID, err, op)
+		return nil, err
+	}
+
+	log.Printf("user %d enabled 2FA", userID)
+	return codes, nil
+}
+
+// DisableTOTP выключает 2FA. Требует повторной аутентификации: пароль и код из приложения или код восстановления.
+func (s *userService) DisableTOTP(ctx context.Context, userID int, password, code, recoveryCode string) error {
+	const op = "service.users.DisableTOTP"
+
+	user, err := s.userStorage.FindUserByID(ctx, userID)
+	if err != nil {
+		log.Printf("Error finding user %d: %v, location %s", userID, err, op)
+		return err
+	}
+	if err := bcrypt.CompareHashAndPassword(user.Password, []byte(password)); err != nil {
+		log.Printf("Wrong password of user %d, location %s", userID, op)
+		return ErrInvalidCredentials
+	}
+	if !user.TOTPEnabled {
+		return storage.ErrTOTPNotEnrolled
+	}
+
+	if err := s.verifySecondFactor(ctx, userID, code, recoveryCode); err != nil {
+		return err
+	}
+
+	if err := s.userStorage.DisableTOTP(ctx, userID); err != nil {
+		log.Printf("Error disabling 2FA for user %d: %v, location %s", userID, err, op)
+		return err
+	}
+
+	log.Printf("user %d disabled 2FA", userID)
+	return nil
+}
+
+// LoginTwoFactor — второй шаг входа: по challenge-токену из Login и коду 2FA (или коду восстановления) выдаёт токены.
+// password нужен web-клиенту для получения ks; если передан, он проверяется ещё раз. После входа через OIDC
+// провайдера пароль не проверяется: web-клиент передаёт в нём свой ks_key (см. OIDCCallback).
+func (s *userService) LoginTwoFactor(ctx context.Context, challengeJWT, code, recoveryCode, password string, device models.DeviceInfo) (accessJWT string, refreshJWT string, platform string, er error) {
+	const op = "service.users.LoginTwoFactor"
+
+	userID, platform, method, err := s.parseChallenge(challengeJWT)
+	if err != nil {
+		log.Printf("Error parsing challenge token: %v, location %s", err, op)
+		return "", "", "", ErrInvalidChallenge
+	}
+
+	user, err := s.userStorage.FindUserByID(ctx, userID)
+	if err != nil {
+		log.Printf("Error finding user %d: %v, location %s", userID, err, op)
+		return "", "", "", err
+	}
+	if !user.TOTPEnabled {
+		// 2FA выключили, пока ждали код
+		return "", "", "", ErrInvalidChallenge
+	}
+	if platform == "web" && password == "" {
+		return "", "", "", ErrPasswordRequired
+	}
+	failures, err := s.reserveLoginAttempt(ctx, user, device.IP)
+	if err != nil {
+		return "", "", "", err
+	}
+	if password != "" && method != utils.AuthMethodOIDC {
+		if err := bcrypt.CompareHashAndPassword(user.Password, []byte(password)); err != nil {
+			return "", "", "", s.loginFailed(ctx, user, device.IP, failures, ErrInvalidCredentials)
+		}
+	}
+
+	if err := s.verifySecondFactor(ctx, userID, code, recoveryCode); err != nil {
+		// неверный код тоже попытка подбора: по одному challenge можно перебирать коды
+		if errors.Is(err, ErrInvalidTOTPCode) || errors.Is(err, storage.ErrTOTPCodeReused) || errors.Is(err, storage.ErrRecoveryCodeInvalid) {
+			return "", "", "", s.loginFailed(ctx, user, device.IP, failures, err)
+		}
+		s.releaseLoginAttempt(ctx, userID)
+		return "", "", "", err
+	}
+
+	s.resetLoginFailures(ctx, userID)
+
+	accessToken, refreshToken, err := s.issueTokens(ctx, user, platform, device)
+	if err != nil {
+		return "", "", "", err
+	}
+	return accessToken, refreshToken, platform, nil
+}
+
+// verifySecondFactor проверяет код из приложения или, если передан, код восстановления
+func (s *userService) verifySecondFactor(ctx context.Context, userID int, code, recoveryCode string) error {
+	if recoveryCode != "" {
+		err := s.userStorage.UseRecoveryCode(ctx, userID, hashOneTimeToken(normalizeRecoveryCode(recoveryCode)))
+		if err == nil {
+			log.Printf("user %d used a recovery code", userID)
+		}
+		return err
+	}
+
+	secret, _, err := s.userStorage.GetTOTPSecret(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return s.checkTOTP(ctx, userID, secret, code)
+}
+
+// checkTOTP проверяет код и запоминает его период, чтобы тот же код нельзя было использовать повторно
+func (s *userService) checkTOTP(ctx context.Context, userID int, secret, code string) error {
+	step, ok := utils.ValidateTOTP(secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return ErrInvalidTOTPCode
+	}
+	return s.userStorage.UseTOTPStep(ctx, userID, step)
+}
+
+func (s *userService) parseChallenge(challengeJWT string) (userID int, platform string, method string, er error) {
+	claims, err := validation.ValidateToken(challengeJWT, s.keys.Keyfunc)
+	if err != nil {
+		return 0, "", "", err
+	}
+	if scope, _ := claims["scope"].(string); scope != utils.ScopeLogin2FA {
+		return 0, "", "", fmt.Errorf("not a login challenge token")
+	}
+	id, ok := claims["user_id"].(float64)
+	platform, _ = claims["platform"].(string)
+	if !ok || platform == "" {
+		return 0, "", "", fmt.Errorf("malformed login challenge token")
+	}
+	// токены без amr выданы до появления входа через OIDC — после проверки пароля
+	method, _ = claims["amr"].(string)
+	if method == "" {
+		method = utils.AuthMethodPassword
+	}
+	return int(id), platform, method, nil
+}
+
+// алфавит кодов восстановления без похожих символов (0/o, 1/l)
+const recoveryAlphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+
+// newRecoveryCodes генерирует n кодов вида xxxxx-xxxxx и их хеши для базы
+func newRecoveryCodes(n int) (codes []string, hashes []string, er error) {
+	for i := 0; i < n; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("generate recovery code: %w", err)
+		}
+		for j := range b {
+			b[j] = recoveryAlphabet[int(b[j])%len(recoveryAlphabet)]
+		}
+		code := string(b[:5]) + "-" + string(b[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, hashOneTimeToken(normalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+// коды вводят вручную: регистр, пробелы и дефис не важны
+func normalizeRecoveryCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+}
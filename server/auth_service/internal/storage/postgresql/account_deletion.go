@@ -0,0 +1,207 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/storage"
+)
+
+// notBeingDeleted — условие на auth_users: у пользователя не наступил срок удаления аккаунта
+const notBeingDeleted = `NOT EXISTS (
+    SELECT 1 FROM account_deletions d
+    WHERE d.user_id = auth_users.id AND d.status = 'pending' AND d.scheduled_at <= NOW()
+)`
+
+const accountDeletionColumns = `id, user_id, COALESCE(email, ''), status, step, requested_at, scheduled_at, completed_at, attempts, last_error, receipt`
+
+func scanAccountDeletion(row interface{ Scan(...any) error }) (models.AccountDeletion, error) {
+	var d models.AccountDeletion
+	var completedAt sql.NullTime
+	err := row.Scan(&d.ID, &d.UserID, &d.Email, &d.Status, &d.Step, &d.RequestedAt, &d.ScheduledAt, &completedAt, &d.Attempts, &d.LastError, &d.Receipt)
+	if err != nil {
+		return models.AccountDeletion{}, err
+	}
+	if completedAt.Valid {
+		d.CompletedAt = &completedAt.Time
+	}
+	return d, nil
+}
+
+// CreateAccountDeletion сохраняет запрос на удаление аккаунта, которое начнётся в scheduledAt.
+// У пользователя может быть только один незавершённый запрос, повторный — storage.ErrDeletionRequested.
+func (p *PostgesStorage) CreateAccountDeletion(ctx context.Context, userID int, email, tokenHash string, scheduledAt time.Time) (models.AccountDeletion, error) {
+	const op = "storage.postgresql.CreateAccountDeletion"
+
+	query := `
+        INSERT INTO account_deletions (user_id, email, token_hash, scheduled_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (user_id) WHERE status = 'pending' DO NOTHING
+        RETURNING ` + accountDeletionColumns
+
+	d, err := scanAccountDeletion(p.db.QueryRowContext(ctx, query, userID, email, tokenHash, scheduledAt))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.AccountDeletion{}, fmt.Errorf("location %s: %w", op, storage.ErrDeletionRequested)
+	}
+	if err != nil {
+		return models.AccountDeletion{}, wrapPostgresErrors(err, op)
+	}
+	return d, nil
+}
+
+// CancelAccountDeletion отменяет запрос пользователя, пока удаление не началось.
+// Нечего отменять или удаление уже идёт — storage.ErrDeletionNotFound.
+func (p *PostgesStorage) CancelAccountDeletion(ctx context.Context, userID int) error {
+	const op = "storage.postgresql.CancelAccountDeletion"
+
+	query := `
+        UPDATE account_deletions
+        SET status = 'cancelled', email = NULL
+        WHERE user_id = $1
+          AND status = 'pending'
+          AND step = ''
+          AND (locked_until IS NULL OR locked_until < NOW())
+        RETURNING id
+    `
+
+	var id int
+	err := p.db.QueryRowContext(ctx, query, userID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("location %s: %w", op, storage.ErrDeletionNotFound)
+	}
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	return nil
+}
+
+// ClaimAccountDeletion берёт в работу один запрос, срок которого наступил, на время lease: пока аренда не истекла,
+// другие экземпляры сервиса его не возьмут. Нет таких запросов — storage.ErrDeletionNotFound.
+func (p *PostgesStorage) ClaimAccountDeletion(ctx context.Context, lease time.Duration) (models.AccountDeletion, error) {
+	const op = "storage.postgresql.ClaimAccountDeletion"
+
+	query := `
+        UPDATE account_deletions
+        SET locked_until = NOW() + $1 * INTERVAL '1 second'
+        WHERE id = (
+            SELECT id FROM account_deletions
+            WHERE status = 'pending'
+              AND scheduled_at <= NOW()
+              AND (locked_until IS NULL OR locked_until < NOW())
+            ORDER BY scheduled_at
+            LIMIT 1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING ` + accountDeletionColumns
+
+	d, err := scanAccountDeletion(p.db.QueryRowContext(ctx, query, int64(lease/time.Second)))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.AccountDeletion{}, fmt.Errorf("location %s: %w", op, storage.ErrDeletionNotFound)
+	}
+	if err != nil {
+		return models.AccountDeletion{}, wrapPostgresErrors(err, op)
+	}
+	return d, nil
+}
+
+// SaveAccountDeletionStep запоминает выполненный шаг удаления и квитанцию после него
+func (p *PostgesStorage) SaveAccountDeletionStep(ctx context.Context, id int, step string, receipt []byte) error {
+	const op = "storage.postgresql.SaveAccountDeletionStep"
+
+	_, err := p.db.ExecContext(ctx, `
+        UPDATE account_deletions
+        SET step = $2, receipt = $3, last_error = ''
+        WHERE id = $1
+    `, id, step, receipt)
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	return nil
+}
+
+// FailAccountDeletion записывает ошибку шага и квитанцию с тем, что успели удалить (nil — квитанция не меняется);
+// следующая попытка — не раньше retryAt
+func (p *PostgesStorage) FailAccountDeletion(ctx context.Context, id int, lastError string, retryAt time.Time, receipt []byte) error {
+	const op = "storage.postgresql.FailAccountDeletion"
+
+	var data any
+	if receipt != nil {
+		data = receipt
+	}
+	_, err := p.db.ExecContext(ctx, `
+        UPDATE account_deletions
+        SET attempts = attempts + 1, last_error = $2, locked_until = $3, receipt = COALESCE($4, receipt)
+        WHERE id = $1
+    `, id, lastError, retryAt, data)
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	return nil
+}
+
+// CompleteAccountDeletion завершает удаление: сохраняет итоговую квитанцию и забывает email
+func (p *PostgesStorage) CompleteAccountDeletion(ctx context.Context, id int, receipt []byte) error {
+	const op = "storage.postgresql.CompleteAccountDeletion"
+
+	_, err := p.db.ExecContext(ctx, `
+        UPDATE account_deletions
+        SET status = 'completed', completed_at = NOW(), email = NULL, locked_until = NULL, last_error = '', receipt = $2
+        WHERE id = $1
+    `, id, receipt)
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	return nil
+}
+
+// FindAccountDeletion ищет запрос на удаление по sha256 его токена
+func (p *PostgesStorage) FindAccountDeletion(ctx context.Context, tokenHash string) (models.AccountDeletion, error) {
+	const op = "storage.postgresql.FindAccountDeletion"
+
+	query := `SELECT ` + accountDeletionColumns + ` FROM account_deletions WHERE token_hash = $1`
+
+	d, err := scanAccountDeletion(p.db.QueryRowContext(ctx, query, tokenHash))
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.AccountDeletion{}, fmt.Errorf("location %s: %w", op, storage.ErrDeletionNotFound)
+	}
+	if err != nil {
+		return models.AccountDeletion{}, wrapPostgresErrors(err, op)
+	}
+	return d, nil
+}
+
+// DeleteUser удаляет пользователя со всеми строками auth_service: ключ, refresh токены и сессии устройств удаляются
+// явно, остальное (2FA, OIDC, письма, события) — каскадом. Идемпотентен. Возвращает число удалённых строк
+func (p *PostgesStorage) DeleteUser(ctx context.Context, userID int) (int64, error) {
+	const op = "storage.postgresql.DeleteUser"
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, wrapPostgresErrors(err, op)
+	}
+	defer tx.Rollback()
+
+	var deleted int64
+	for _, query := range []string{
+		`DELETE FROM user_keys WHERE user_id = $1`,
+		`DELETE FROM refresh_token WHERE user_id = $1`,
+		`DELETE FROM device_sessions WHERE user_id = $1`,
+		`DELETE FROM auth_users WHERE id = $1`,
+	} {
+		res, err := tx.ExecContext(ctx, query, userID)
+		if err != nil {
+			return 0, wrapPostgresErrors(err, op)
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, wrapPostgresErrors(err, op)
+	}
+	return deleted, nil
+}
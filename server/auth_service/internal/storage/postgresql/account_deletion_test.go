@@ -0,0 +1,152 @@
+package postgresql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/1abobik1/AuthService/internal/storage/postgresql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var accountDeletionCols = []string{"id", "user_id", "email", "status", "step", "requested_at", "scheduled_at", "completed_at", "attempts", "last_error", "receipt"}
+
+func TestCreateAccountDeletion_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	scheduledAt := now.Add(24 * time.Hour)
+	mock.ExpectQuery("INSERT INTO account_deletions").
+		WithArgs(5, "user@example.com", "hash_1", scheduledAt).
+		WillReturnRows(sqlmock.NewRows(accountDeletionCols).
+			AddRow(1, 5, "user@example.com", "pending", "", now, scheduledAt, nil, 0, "", []byte("{}")))
+
+	s := postgresql.NewPostgresForTesting(db)
+	d, err := s.CreateAccountDeletion(context.Background(), 5, "user@example.com", "hash_1", scheduledAt)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.AccountDeletion{
+		ID:          1,
+		UserID:      5,
+		Email:       "user@example.com",
+		Status:      models.DeletionPending,
+		RequestedAt: now,
+		ScheduledAt: scheduledAt,
+		Receipt:     []byte("{}"),
+	}, d)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateAccountDeletion_AlreadyRequested(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	scheduledAt := time.Now()
+	mock.ExpectQuery("INSERT INTO account_deletions").
+		WithArgs(5, "user@example.com", "hash_1", scheduledAt).
+		WillReturnRows(sqlmock.NewRows(accountDeletionCols))
+
+	s := postgresql.NewPostgresForTesting(db)
+	_, err = s.CreateAccountDeletion(context.Background(), 5, "user@example.com", "hash_1", scheduledAt)
+
+	assert.ErrorIs(t, err, storage.ErrDeletionRequested)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCancelAccountDeletion_AlreadyStarted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("UPDATE account_deletions").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	s := postgresql.NewPostgresForTesting(db)
+	err = s.CancelAccountDeletion(context.Background(), 5)
+
+	assert.ErrorIs(t, err, storage.ErrDeletionNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimAccountDeletion_NothingDue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("UPDATE account_deletions").
+		WithArgs(int64(300)).
+		WillReturnRows(sqlmock.NewRows(accountDeletionCols))
+
+	s := postgresql.NewPostgresForTesting(db)
+	_, err = s.ClaimAccountDeletion(context.Background(), 5*time.Minute)
+
+	assert.ErrorIs(t, err, storage.ErrDeletionNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteUser_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM user_keys").WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM refresh_token").WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("DELETE FROM device_sessions").WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM auth_users").WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	s := postgresql.NewPostgresForTesting(db)
+	deleted, err := s.DeleteUser(context.Background(), 5)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteUser_AlreadyDeleted(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM user_keys").WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM refresh_token").WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM device_sessions").WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM auth_users").WithArgs(5).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	s := postgresql.NewPostgresForTesting(db)
+	deleted, err := s.DeleteUser(context.Background(), 5)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), deleted)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Ошибка шага сохраняет квитанцию с уже удалённым, nil оставляет прежнюю
+func TestFailAccountDeletion_SavesReceipt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	retryAt := time.Now().Add(time.Minute)
+	mock.ExpectExec(`UPDATE account_deletions\s+SET attempts = attempts \+ 1, last_error = \$2, locked_until = \$3, receipt = COALESCE\(\$4, receipt\)`).
+		WithArgs(1, "boom", retryAt, []byte(`{"files":2}`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE account_deletions`).
+		WithArgs(1, "boom", retryAt, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s := postgresql.NewPostgresForTesting(db)
+	assert.NoError(t, s.FailAccountDeletion(context.Background(), 1, "boom", retryAt, []byte(`{"files":2}`)))
+	assert.NoError(t, s.FailAccountDeletion(context.Background(), 1, "boom", retryAt, nil))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,146 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/storage"
+)
+
+// CreateDeviceSession сохраняет сессию нового входа и первый refresh токен её семейства.
+// Другие сессии пользователя, в том числе на той же платформе, не затрагиваются; истёкшие удаляются.
+func (p *PostgesStorage) CreateDeviceSession(ctx context.Context, session models.DeviceSession, token models.RefreshToken) error {
+	const op = "storage.postgresql.CreateDeviceSession"
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM device_sessions WHERE user_id = $1 AND expires_at < NOW()`, session.UserID); err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO device_sessions (id, user_id, platform, device_name, user_agent, ip, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `, session.ID, session.UserID, session.Platform, session.Name, session.UserAgent, session.IP, session.ExpiresAt); err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+
+	if err := insertRefreshToken(ctx, tx, token); err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	return nil
+}
+
+// ListDeviceSessions возвращает действующие сессии пользователя, недавно использованные — первыми
+func (p *PostgesStorage) ListDeviceSessions(ctx context.Context, userID int) ([]models.DeviceSession, error) {
+	const op = "storage.postgresql.ListDeviceSessions"
+
+	rows, err := p.db.QueryContext(ctx, `
+        SELECT id, platform, device_name, user_agent, ip, created_at, last_used_at, expires_at
+        FROM device_sessions
+        WHERE user_id = $1
+          AND revoked_at IS NULL
+          AND expires_at > NOW()
+        ORDER BY last_used_at DESC
+    `, userID)
+	if err != nil {
+		return nil, wrapPostgresErrors(err, op)
+	}
+	defer rows.Close()
+
+	sessions := []models.DeviceSession{}
+	for rows.Next() {
+		s := models.DeviceSession{UserID: userID}
+		if err := rows.Scan(&s.ID, &s.Platform, &s.Name, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastUsedAt, &s.ExpiresAt); err != nil {
+			return nil, wrapPostgresErrors(err, op)
+		}
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapPostgresErrors(err, op)
+	}
+	return sessions, nil
+}
+
+// RevokeDeviceSession отзывает сессию sessionID пользователя userID со всеми её refresh токенами.
+// Чужая, неизвестная или уже отозванная сессия — storage.ErrSessionNotFound.
+func (p *PostgesStorage) RevokeDeviceSession(ctx context.Context, userID int, sessionID string) error {
+	const op = "storage.postgresql.RevokeDeviceSession"
+
+	var id string
+	err := p.db.QueryRowContext(ctx, `
+        WITH s AS (
+            UPDATE device_sessions
+            SET revoked_at = NOW()
+            WHERE id = $2
+              AND user_id = $1
+              AND revoked_at IS NULL
+            RETURNING id
+        ), t AS (
+            UPDATE refresh_token
+            SET revoked_at = NOW()
+            FROM s
+            WHERE refresh_token.family_id = s.id
+              AND refresh_token.revoked_at IS NULL
+        )
+        SELECT id FROM s
+    `, userID, sessionID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("location %s: %w", op, storage.ErrSessionNotFound)
+	}
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	return nil
+}
+
+// RevokeAllDeviceSessions отзывает все действующие сессии пользователя userID со всеми их refresh токенами
+// и возвращает ID отозванных сессий
+func (p *PostgesStorage) RevokeAllDeviceSessions(ctx context.Context, userID int) ([]string, error) {
+	const op = "storage.postgresql.RevokeAllDeviceSessions"
+
+	rows, err := p.db.QueryContext(ctx, `
+        WITH s AS (
+            UPDATE device_sessions
+            SET revoked_at = NOW()
+            WHERE user_id = $1
+              AND revoked_at IS NULL
+            RETURNING id
+        ), t AS (
+            UPDATE refresh_token
+            SET revoked_at = NOW()
+            FROM s
+            WHERE refresh_token.family_id = s.id
+              AND refresh_token.revoked_at IS NULL
+        )
+        SELECT id FROM s
+    `, userID)
+	if err != nil {
+		return nil, wrapPostgresErrors(err, op)
+	}
+	defer rows.Close()
+
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, wrapPostgresErrors(err, op)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, wrapPostgresErrors(err, op)
+	}
+	return ids, nil
+}
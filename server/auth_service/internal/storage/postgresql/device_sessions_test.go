@@ -0,0 +1,95 @@
+package postgresql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/1abobik1/AuthService/internal/storage/postgresql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateDeviceSession_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expires := time.Now().Add(time.Hour)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM device_sessions").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO device_sessions").
+		WithArgs("fam_1", 1, "web", "laptop", "Mozilla/5.0", "10.0.0.1", expires).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO refresh_token").
+		WithArgs("jti_1", "fam_1", "hash_1", 1, expires).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	s := postgresql.NewPostgresForTesting(db)
+	err = s.CreateDeviceSession(context.Background(), models.DeviceSession{
+		ID:         "fam_1",
+		UserID:     1,
+		Platform:   "web",
+		DeviceInfo: models.DeviceInfo{Name: "laptop", UserAgent: "Mozilla/5.0", IP: "10.0.0.1"},
+		ExpiresAt:  expires,
+	}, models.RefreshToken{JTI: "jti_1", FamilyID: "fam_1", UserID: 1, TokenHash: "hash_1", ExpiresAt: expires})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListDeviceSessions_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	now := time.Now()
+	cols := []string{"id", "platform", "device_name", "user_agent", "ip", "created_at", "last_used_at", "expires_at"}
+	mock.ExpectQuery("SELECT id, platform, device_name").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(cols).
+			AddRow("fam_2", "tg-bot", "", "Go-http-client/1.1", "10.0.0.2", now, now, now).
+			AddRow("fam_1", "web", "laptop", "Mozilla/5.0", "10.0.0.1", now, now, now))
+
+	s := postgresql.NewPostgresForTesting(db)
+	sessions, err := s.ListDeviceSessions(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	assert.Equal(t, "fam_2", sessions[0].ID)
+	assert.Equal(t, "laptop", sessions[1].Name)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeDeviceSession_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("UPDATE device_sessions").WithArgs(1, "fam_1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	s := postgresql.NewPostgresForTesting(db)
+	err = s.RevokeDeviceSession(context.Background(), 1, "fam_1")
+
+	assert.ErrorIs(t, err, storage.ErrSessionNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeAllDeviceSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("UPDATE device_sessions").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("fam_1").AddRow("fam_2"))
+
+	s := postgresql.NewPostgresForTesting(db)
+	ids, err := s.RevokeAllDeviceSessions(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"fam_1", "fam_2"}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,69 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/storage"
+)
+
+// SaveEmailVerification заменяет ссылку подтверждения пользователя новой. Если прошлое письмо ушло меньше
+// resendInterval назад, ссылка не меняется и возвращается storage.ErrVerificationThrottled.
+func (p *PostgesStorage) SaveEmailVerification(ctx context.Context, userID int, tokenHash string, ttl, resendInterval time.Duration) error {
+	const op = "storage.postgresql.SaveEmailVerification"
+
+	query := `
+        INSERT INTO email_verifications (user_id, token_hash, expires_at, sent_at)
+        VALUES ($1, $2, NOW() + $3 * INTERVAL '1 second', NOW())
+        ON CONFLICT (user_id) DO UPDATE
+        SET token_hash = EXCLUDED.token_hash,
+            expires_at = EXCLUDED.expires_at,
+            sent_at = EXCLUDED.sent_at
+        WHERE email_verifications.sent_at <= NOW() - $4 * INTERVAL '1 second'
+        RETURNING user_id
+    `
+
+	var id int
+	err := p.db.QueryRowContext(ctx, query, userID, tokenHash, int64(ttl/time.Second), int64(resendInterval/time.Second)).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("location %s: %w", op, storage.ErrVerificationThrottled)
+	}
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+
+	return nil
+}
+
+// ConfirmEmail погашает ссылку подтверждения и помечает email владельца подтверждённым. Возвращает ID пользователя.
+func (p *PostgesStorage) ConfirmEmail(ctx context.Context, tokenHash string) (int, error) {
+	const op = "storage.postgresql.ConfirmEmail"
+
+	query := `
+        WITH v AS (
+            DELETE FROM email_verifications
+            WHERE token_hash = $1
+              AND expires_at > NOW()
+            RETURNING user_id
+        )
+        UPDATE auth_users u
+        SET is_activated = TRUE
+        FROM v
+        WHERE u.id = v.user_id
+        RETURNING u.id
+    `
+
+	var userID int
+	err := p.db.QueryRowContext(ctx, query, tokenHash).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("location %s: %w", op, storage.ErrVerificationNotFound)
+	}
+	if err != nil {
+		return 0, wrapPostgresErrors(err, op)
+	}
+
+	return userID, nil
+}
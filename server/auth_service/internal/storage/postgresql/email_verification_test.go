@@ -0,0 +1,79 @@
+package postgresql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/1abobik1/AuthService/internal/storage/postgresql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveEmailVerification_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("INSERT INTO email_verifications").
+		WithArgs(1, "hash_1", int64(86400), int64(60)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(1))
+
+	s := postgresql.NewPostgresForTesting(db)
+	err = s.SaveEmailVerification(context.Background(), 1, "hash_1", 24*time.Hour, time.Minute)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveEmailVerification_Throttled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// письмо ушло недавно: ON CONFLICT ... WHERE не обновил строку
+	mock.ExpectQuery("INSERT INTO email_verifications").
+		WithArgs(1, "hash_1", int64(86400), int64(60)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+	s := postgresql.NewPostgresForTesting(db)
+	err = s.SaveEmailVerification(context.Background(), 1, "hash_1", 24*time.Hour, time.Minute)
+
+	assert.ErrorIs(t, err, storage.ErrVerificationThrottled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfirmEmail_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("DELETE FROM email_verifications").
+		WithArgs("hash_1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	s := postgresql.NewPostgresForTesting(db)
+	userID, err := s.ConfirmEmail(context.Background(), "hash_1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, userID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfirmEmail_InvalidOrExpired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("DELETE FROM email_verifications").
+		WithArgs("hash_1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	s := postgresql.NewPostgresForTesting(db)
+	userID, err := s.ConfirmEmail(context.Background(), "hash_1")
+
+	assert.ErrorIs(t, err, storage.ErrVerificationNotFound)
+	assert.Equal(t, 0, userID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,142 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/storage"
+)
+
+// SaveOIDCAuthRequest сохраняет начатый вход через OIDC провайдера, заодно удаляя просроченные
+func (p *PostgesStorage) SaveOIDCAuthRequest(ctx context.Context, req models.OIDCAuthRequest) error {
+	const op = "storage.postgresql.SaveOIDCAuthRequest"
+
+	if _, err := p.db.ExecContext(ctx, `DELETE FROM oidc_auth_requests WHERE expires_at < NOW()`); err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+
+	var linkUserID sql.NullInt64
+	if req.LinkUserID != 0 {
+		linkUserID = sql.NullInt64{Int64: int64(req.LinkUserID), Valid: true}
+	}
+
+	_, err := p.db.ExecContext(ctx, `
+        INSERT INTO oidc_auth_requests (state_hash, binding_hash, provider, nonce, code_verifier, platform, link_user_id, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    `, req.StateHash, req.BindingHash, req.Provider, req.Nonce, req.CodeVerifier, req.Platform, linkUserID, req.ExpiresAt)
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	return nil
+}
+
+// ConsumeOIDCAuthRequest погашает начатый вход по sha256 state: запись удаляется, поэтому state одноразовый.
+// Неизвестный, чужого провайдера или просроченный state — storage.ErrOIDCStateNotFound.
+func (p *PostgesStorage) ConsumeOIDCAuthRequest(ctx context.Context, provider, stateHash string) (models.OIDCAuthRequest, error) {
+	const op = "storage.postgresql.ConsumeOIDCAuthRequest"
+
+	query := `
+        DELETE FROM oidc_auth_requests
+        WHERE state_hash = $1
+          AND provider = $2
+          AND expires_at > NOW()
+        RETURNING binding_hash, nonce, code_verifier, platform, link_user_id, expires_at
+    `
+
+	req := models.OIDCAuthRequest{StateHash: stateHash, Provider: provider}
+	var linkUserID sql.NullInt64
+	err := p.db.QueryRowContext(ctx, query, stateHash, provider).Scan(&req.BindingHash, &req.Nonce, &req.CodeVerifier, &req.Platform, &linkUserID, &req.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.OIDCAuthRequest{}, fmt.Errorf("location %s: %w", op, storage.ErrOIDCStateNotFound)
+	}
+	if err != nil {
+		return models.OIDCAuthRequest{}, wrapPostgresErrors(err, op)
+	}
+	req.LinkUserID = int(linkUserID.Int64)
+
+	return req, nil
+}
+
+// FindUserByOIDCIdentity ищет пользователя, к которому привязана учётная запись провайдера.
+// Непривязанная учётная запись — storage.ErrUserNotFound.
+func (p *PostgesStorage) FindUserByOIDCIdentity(ctx context.Context, provider, subject string) (models.UserModel, error) {
+	const op = "storage.postgresql.FindUserByOIDCIdentity"
+
+	query := `
+        SELECT auth_users.id, auth_users.email, auth_users.password, auth_users.is_activated, auth_users.role, auth_users.totp_enabled
+        FROM oidc_identities i
+        JOIN auth_users ON auth_users.id = i.user_id
+        WHERE i.provider = $1 AND i.subject = $2
+          AND ` + notBeingDeleted
+
+	var userModel models.UserModel
+	err := p.db.QueryRowContext(ctx, query, provider, subject).Scan(&userModel.ID, &userModel.Email, &userModel.Password, &userModel.IsActivated, &userModel.Role, &userModel.TOTPEnabled)
+	if err != nil {
+		return models.UserModel{}, wrapPostgresErrors(err, op)
+	}
+
+	return userModel, nil
+}
+
+// LinkOIDCIdentity привязывает учётную запись провайдера к пользователю userID. Повторная привязка к тому же
+// пользователю обновляет email; привязанная к другому — storage.ErrOIDCIdentityLinked.
+func (p *PostgesStorage) LinkOIDCIdentity(ctx context.Context, userID int, identity models.OIDCIdentity) error {
+	const op = "storage.postgresql.LinkOIDCIdentity"
+
+	query := `
+        INSERT INTO oidc_identities (user_id, provider, subject, email)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (provider, subject) DO UPDATE
+        SET email = EXCLUDED.email
+        WHERE oidc_identities.user_id = EXCLUDED.user_id
+        RETURNING id
+    `
+
+	var id int
+	err := p.db.QueryRowContext(ctx, query, userID, identity.Provider, identity.Subject, identity.Email).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("location %s: %w", op, storage.ErrOIDCIdentityLinked)
+	}
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	return nil
+}
+
+// CreateOIDCUser в одной транзакции создаёт пользователя, вошедшего через провайдера, и привязывает к нему
+// учётную запись провайдера. passHash — хеш случайного пароля: свой пароль пользователь задаёт через сброс.
+// Email подтверждён, если это подтвердил провайдер. Занятый email — storage.ErrUserExists.
+func (p *PostgesStorage) CreateOIDCUser(ctx context.Context, identity models.OIDCIdentity, passHash []byte, emailVerified bool) (int, error) {
+	const op = "storage.postgresql.CreateOIDCUser"
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, wrapPostgresErrors(err, op)
+	}
+	defer tx.Rollback()
+
+	var userID int
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO auth_users (email, password, is_activated)
+        VALUES ($1, $2, $3)
+        RETURNING id
+    `, identity.Email, passHash, emailVerified).Scan(&userID)
+	if err != nil {
+		return 0, wrapPostgresErrors(err, op)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO oidc_identities (user_id, provider, subject, email)
+        VALUES ($1, $2, $3, $4)
+    `, userID, identity.Provider, identity.Subject, identity.Email); err != nil {
+		return 0, wrapPostgresErrors(err, op)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, wrapPostgresErrors(err, op)
+	}
+	return userID, nil
+}
@@ -0,0 +1,158 @@
+package postgresql_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/1abobik1/AuthService/internal/storage/postgresql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveOIDCAuthRequest_Link(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expiresAt := time.Now().Add(10 * time.Minute)
+	mock.ExpectExec("DELETE FROM oidc_auth_requests").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO oidc_auth_requests").
+		WithArgs("state_hash", "binding_hash", "google", "nonce", "verifier", "web", sql.NullInt64{Int64: 7, Valid: true}, expiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	s := postgresql.NewPostgresForTesting(db)
+	err = s.SaveOIDCAuthRequest(context.Background(), models.OIDCAuthRequest{
+		StateHash:    "state_hash",
+		BindingHash:  "binding_hash",
+		Provider:     "google",
+		Nonce:        "nonce",
+		CodeVerifier: "verifier",
+		Platform:     "web",
+		LinkUserID:   7,
+		ExpiresAt:    expiresAt,
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConsumeOIDCAuthRequest_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expiresAt := time.Now().Add(time.Minute)
+	mock.ExpectQuery("DELETE FROM oidc_auth_requests").
+		WithArgs("state_hash", "google").
+		WillReturnRows(sqlmock.NewRows([]string{"binding_hash", "nonce", "code_verifier", "platform", "link_user_id", "expires_at"}).
+			AddRow("binding_hash", "nonce", "verifier", "web", nil, expiresAt))
+
+	s := postgresql.NewPostgresForTesting(db)
+	req, err := s.ConsumeOIDCAuthRequest(context.Background(), "google", "state_hash")
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.OIDCAuthRequest{
+		StateHash:    "state_hash",
+		BindingHash:  "binding_hash",
+		Provider:     "google",
+		Nonce:        "nonce",
+		CodeVerifier: "verifier",
+		Platform:     "web",
+		ExpiresAt:    expiresAt,
+	}, req)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConsumeOIDCAuthRequest_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("DELETE FROM oidc_auth_requests").
+		WithArgs("state_hash", "google").
+		WillReturnRows(sqlmock.NewRows([]string{"binding_hash", "nonce", "code_verifier", "platform", "link_user_id", "expires_at"}))
+
+	s := postgresql.NewPostgresForTesting(db)
+	_, err = s.ConsumeOIDCAuthRequest(context.Background(), "google", "state_hash")
+
+	assert.ErrorIs(t, err, storage.ErrOIDCStateNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFindUserByOIDCIdentity_NotLinked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("FROM oidc_identities").
+		WithArgs("google", "sub-1").
+		WillReturnError(sql.ErrNoRows)
+
+	s := postgresql.NewPostgresForTesting(db)
+	_, err = s.FindUserByOIDCIdentity(context.Background(), "google", "sub-1")
+
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLinkOIDCIdentity_LinkedToAnotherUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("INSERT INTO oidc_identities").
+		WithArgs(1, "google", "sub-1", "user@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	s := postgresql.NewPostgresForTesting(db)
+	err = s.LinkOIDCIdentity(context.Background(), 1, models.OIDCIdentity{Provider: "google", Subject: "sub-1", Email: "user@example.com"})
+
+	assert.ErrorIs(t, err, storage.ErrOIDCIdentityLinked)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOIDCUser_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO auth_users").
+		WithArgs("user@example.com", []byte("hash"), true).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectExec("INSERT INTO oidc_identities").
+		WithArgs(4, "google", "sub-1", "user@example.com").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	s := postgresql.NewPostgresForTesting(db)
+	userID, err := s.CreateOIDCUser(context.Background(), models.OIDCIdentity{Provider: "google", Subject: "sub-1", Email: "user@example.com"}, []byte("hash"), true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, userID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateOIDCUser_EmailTaken(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("INSERT INTO auth_users").
+		WithArgs("user@example.com", []byte("hash"), false).
+		WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+
+	s := postgresql.NewPostgresForTesting(db)
+	_, err = s.CreateOIDCUser(context.Background(), models.OIDCIdentity{Provider: "google", Subject: "sub-1", Email: "user@example.com"}, []byte("hash"), false)
+
+	assert.ErrorIs(t, err, storage.ErrUserExists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
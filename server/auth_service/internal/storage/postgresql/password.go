@@ -0,0 +1,124 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/storage"
+)
+
+func (p *PostgesStorage) FindUserByID(ctx context.Context, userID int) (models.UserModel, error) {
+	const op = "storage.postgresql.FindUserByID"
+
+	var userModel models.UserModel
+	query := "SELECT id, email, password, is_activated, role, totp_enabled FROM auth_users WHERE id = $1"
+	err := p.db.QueryRowContext(ctx, query, userID).Scan(&userModel.ID, &userModel.Email, &userModel.Password, &userModel.IsActivated, &userModel.Role, &userModel.TOTPEnabled)
+	if err != nil {
+		return models.UserModel{}, wrapPostgresErrors(err, op)
+	}
+
+	return userModel, nil
+}
+
+// SavePasswordReset заменяет ссылку сброса пароля пользователя новой. Если прошлое письмо ушло меньше
+// resendInterval назад, ссылка не меняется и возвращается storage.ErrResetThrottled.
+func (p *PostgesStorage) SavePasswordReset(ctx context.Context, userID int, tokenHash string, ttl, resendInterval time.Duration) error {
+	const op = "storage.postgresql.SavePasswordReset"
+
+	query := `
+        INSERT INTO password_resets (user_id, token_hash, expires_at, sent_at)
+        VALUES ($1, $2, NOW() + $3 * INTERVAL '1 second', NOW())
+        ON CONFLICT (user_id) DO UPDATE
+        SET token_hash = EXCLUDED.token_hash,
+            expires_at = EXCLUDED.expires_at,
+            sent_at = EXCLUDED.sent_at
+        WHERE password_resets.sent_at <= NOW() - $4 * INTERVAL '1 second'
+        RETURNING user_id
+    `
+
+	var id int
+	err := p.db.QueryRowContext(ctx, query, userID, tokenHash, int64(ttl/time.Second), int64(resendInterval/time.Second)).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("location %s: %w", op, storage.ErrResetThrottled)
+	}
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+
+	return nil
+}
+
+// ResetPassword одним запросом погашает ссылку сброса, меняет пароль и удаляет все сессии устройств владельца (вместе с refresh токенами).
+// Ссылка пришла на email, поэтому адрес заодно считается подтверждённым. Возвращает ID пользователя.
+func (p *PostgesStorage) ResetPassword(ctx context.Context, tokenHash string, passHash []byte) (int, error) {
+	const op = "storage.postgresql.ResetPassword"
+
+	query := `
+        WITH r AS (
+            DELETE FROM password_resets
+            WHERE token_hash = $1
+              AND expires_at > NOW()
+            RETURNING user_id
+        ), u AS (
+            UPDATE auth_users
+            SET password = $2, is_activated = TRUE
+            FROM r
+            WHERE auth_users.id = r.user_id
+            RETURNING auth_users.id
+        ), t AS (
+            DELETE FROM device_sessions
+            USING u
+            WHERE device_sessions.user_id = u.id
+        )
+        SELECT id FROM u
+    `
+
+	var userID int
+	err := p.db.QueryRowContext(ctx, query, tokenHash, passHash).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("location %s: %w", op, storage.ErrResetNotFound)
+	}
+	if err != nil {
+		return 0, wrapPostgresErrors(err, op)
+	}
+
+	return userID, nil
+}
+
+// ChangePassword меняет пароль, удаляет все сессии устройств пользователя с их refresh токенами и неиспользованную ссылку сброса
+func (p *PostgesStorage) ChangePassword(ctx context.Context, userID int, passHash []byte) error {
+	const op = "storage.postgresql.ChangePassword"
+
+	query := `
+        WITH u AS (
+            UPDATE auth_users
+            SET password = $2
+            WHERE id = $1
+            RETURNING id
+        ), t AS (
+            DELETE FROM device_sessions
+            USING u
+            WHERE device_sessions.user_id = u.id
+        ), r AS (
+            DELETE FROM password_resets
+            USING u
+            WHERE password_resets.user_id = u.id
+        )
+        SELECT id FROM u
+    `
+
+	var id int
+	err := p.db.QueryRowContext(ctx, query, userID, passHash).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("location %s: %w", op, storage.ErrUserNotFound)
+	}
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package postgresql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/1abobik1/AuthService/internal/storage/postgresql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSavePasswordReset_Throttled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("INSERT INTO password_resets").
+		WithArgs(1, "hash_1", int64(3600), int64(60)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+	s := postgresql.NewPostgresForTesting(db)
+	err = s.SavePasswordReset(context.Background(), 1, "hash_1", time.Hour, time.Minute)
+
+	assert.ErrorIs(t, err, storage.ErrResetThrottled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResetPassword_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("DELETE FROM password_resets").
+		WithArgs("hash_1", []byte("new_hash")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+
+	s := postgresql.NewPostgresForTesting(db)
+	userID, err := s.ResetPassword(context.Background(), "hash_1", []byte("new_hash"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, userID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResetPassword_InvalidOrExpired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("DELETE FROM password_resets").
+		WithArgs("hash_1", []byte("new_hash")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	s := postgresql.NewPostgresForTesting(db)
+	userID, err := s.ResetPassword(context.Background(), "hash_1", []byte("new_hash"))
+
+	assert.ErrorIs(t, err, storage.ErrResetNotFound)
+	assert.Equal(t, 0, userID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestChangePassword_UserNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("UPDATE auth_users").
+		WithArgs(5, []byte("new_hash")).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	s := postgresql.NewPostgresForTesting(db)
+	err = s.ChangePassword(context.Background(), 5, []byte("new_hash"))
+
+	assert.ErrorIs(t, err, storage.ErrUserNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,149 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/storage"
+)
+
+// SecurityEventRefreshTokenReuse — тип события в security_events при повторном предъявлении обменянного refresh токена
+const SecurityEventRefreshTokenReuse = "refresh_token_reuse"
+
+// RotateRefreshToken обменивает токен с хешем oldHash на next из того же семейства, продлевает сессию устройства
+// и возвращает владельца с текущей ролью и статусом подтверждения email.
+// Неизвестный, отозванный или истёкший токен — storage.ErrTokenNotFound. Уже обменянный токен — признак кражи:
+// всё семейство и сессия отзываются, пишется событие в security_events и возвращается storage.ErrTokenReused.
+func (p *PostgesStorage) RotateRefreshToken(ctx context.Context, oldHash string, next models.RefreshToken) (models.UserModel, error) {
+	const op = "storage.postgresql.RotateRefreshToken"
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return models.UserModel{}, wrapPostgresErrors(err, op)
+	}
+	defer tx.Rollback()
+
+	var (
+		user            models.UserModel
+		familyID        string
+		platform        string
+		rotated, usable bool
+	)
+	err = tx.QueryRowContext(ctx, `
+        SELECT rt.user_id, rt.family_id, ds.platform, rt.rotated_at IS NOT NULL,
+               rt.revoked_at IS NULL AND ds.revoked_at IS NULL AND rt.expires_at > NOW(), u.role, u.is_activated
+        FROM refresh_token rt
+        JOIN device_sessions ds ON ds.id = rt.family_id
+        JOIN auth_users u ON u.id = rt.user_id
+        WHERE rt.token_hash = $1
+        FOR UPDATE OF rt
+    `, oldHash).Scan(&user.ID, &familyID, &platform, &rotated, &usable, &user.Role, &user.IsActivated)
+	if err != nil {
+		return models.UserModel{}, wrapPostgresErrors(err, op)
+	}
+
+	if !usable || familyID != next.FamilyID {
+		return models.UserModel{}, fmt.Errorf("location %s: %w", op, storage.ErrTokenNotFound)
+	}
+
+	if rotated {
+		if err := revokeFamily(ctx, tx, familyID); err != nil {
+			return models.UserModel{}, wrapPostgresErrors(err, op)
+		}
+		details, _ := json.Marshal(map[string]string{"family_id": familyID, "platform": platform})
+		if _, err := tx.ExecContext(ctx, `
+            INSERT INTO security_events (user_id, type, details)
+            VALUES ($1, $2, $3)
+        `, user.ID, SecurityEventRefreshTokenReuse, string(details)); err != nil {
+			return models.UserModel{}, wrapPostgresErrors(err, op)
+		}
+		if err := tx.Commit(); err != nil {
+			return models.UserModel{}, wrapPostgresErrors(err, op)
+		}
+		return models.UserModel{}, fmt.Errorf("location %s, family %s: %w", op, familyID, storage.ErrTokenReused)
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE refresh_token SET rotated_at = NOW() WHERE token_hash = $1`, oldHash); err != nil {
+		return models.UserModel{}, wrapPostgresErrors(err, op)
+	}
+
+	next.UserID = user.ID
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return models.UserModel{}, wrapPostgresErrors(err, op)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE device_sessions
+        SET last_used_at = NOW(), expires_at = $2
+        WHERE id = $1
+    `, familyID, next.ExpiresAt); err != nil {
+		return models.UserModel{}, wrapPostgresErrors(err, op)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return models.UserModel{}, wrapPostgresErrors(err, op)
+	}
+	return user, nil
+}
+
+// RevokeRefreshToken отзывает сессию устройства, к которой относится токен с хешем tokenHash, вместе со всем
+// семейством (logout). Возвращает владельца и ID сессии. Если сессия уже отозвана — storage.ErrTokenNotFound.
+func (p *PostgesStorage) RevokeRefreshToken(ctx context.Context, tokenHash string) (int, string, error) {
+	const op = "storage.postgresql.RevokeRefreshToken"
+
+	var (
+		userID    int
+		sessionID string
+	)
+	err := p.db.QueryRowContext(ctx, `
+        WITH s AS (
+            UPDATE device_sessions
+            SET revoked_at = NOW()
+            WHERE id = (SELECT family_id FROM refresh_token WHERE token_hash = $1)
+              AND revoked_at IS NULL
+            RETURNING id, user_id
+        ), t AS (
+            UPDATE refresh_token
+            SET revoked_at = NOW()
+            FROM s
+            WHERE refresh_token.family_id = s.id
+              AND refresh_token.revoked_at IS NULL
+        )
+        SELECT user_id, id FROM s
+    `, tokenHash).Scan(&userID, &sessionID)
+	if err != nil {
+		log.Printf("Error revoking refresh_token family: %v location: %s", err, op)
+		return 0, "", wrapPostgresErrors(err, op)
+	}
+
+	return userID, sessionID, nil
+}
+
+func insertRefreshToken(ctx context.Context, tx *sql.Tx, token models.RefreshToken) error {
+	_, err := tx.ExecContext(ctx, `
+        INSERT INTO refresh_token (jti, family_id, token_hash, user_id, expires_at)
+        VALUES ($1, $2, $3, $4, $5)
+    `, token.JTI, token.FamilyID, token.TokenHash, token.UserID, token.ExpiresAt)
+	return err
+}
+
+// revokeFamily отзывает сессию устройства familyID и все её refresh токены
+func revokeFamily(ctx context.Context, tx *sql.Tx, familyID string) error {
+	_, err := tx.ExecContext(ctx, `
+        WITH s AS (
+            UPDATE device_sessions
+            SET revoked_at = NOW()
+            WHERE id = $1
+              AND revoked_at IS NULL
+        )
+        UPDATE refresh_token
+        SET revoked_at = NOW()
+        WHERE family_id = $1
+          AND revoked_at IS NULL
+    `, familyID)
+	return err
+}
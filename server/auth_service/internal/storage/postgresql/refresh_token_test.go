@@ -0,0 +1,131 @@
+package postgresql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/1abobik1/AuthService/internal/storage/postgresql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var rotateCols = []string{"user_id", "family_id", "platform", "rotated", "usable", "role", "is_activated"}
+
+func nextRefreshToken(expires time.Time) models.RefreshToken {
+	return models.RefreshToken{JTI: "jti_2", FamilyID: "fam_1", TokenHash: "hash_2", ExpiresAt: expires}
+}
+
+func TestRotateRefreshToken_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	expires := time.Now().Add(time.Hour)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT rt.user_id, rt.family_id").WithArgs("hash_1").
+		WillReturnRows(sqlmock.NewRows(rotateCols).AddRow(1, "fam_1", "web", false, true, "user", true))
+	mock.ExpectExec("UPDATE refresh_token SET rotated_at").WithArgs("hash_1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO refresh_token").
+		WithArgs("jti_2", "fam_1", "hash_2", 1, expires).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec("UPDATE device_sessions").WithArgs("fam_1", expires).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	s := postgresql.NewPostgresForTesting(db)
+	user, err := s.RotateRefreshToken(context.Background(), "hash_1", nextRefreshToken(expires))
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.UserModel{ID: 1, Role: models.RoleUser, IsActivated: true}, user)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRotateRefreshToken_ReuseRevokesFamily(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT rt.user_id, rt.family_id").WithArgs("hash_1").
+		WillReturnRows(sqlmock.NewRows(rotateCols).AddRow(1, "fam_1", "web", true, true, "user", true))
+	mock.ExpectExec("UPDATE device_sessions").WithArgs("fam_1").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO security_events").
+		WithArgs(1, postgresql.SecurityEventRefreshTokenReuse, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	s := postgresql.NewPostgresForTesting(db)
+	user, err := s.RotateRefreshToken(context.Background(), "hash_1", nextRefreshToken(time.Now().Add(time.Hour)))
+
+	assert.ErrorIs(t, err, storage.ErrTokenReused)
+	assert.Equal(t, models.UserModel{}, user)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRotateRefreshToken_Revoked(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT rt.user_id, rt.family_id").WithArgs("hash_1").
+		WillReturnRows(sqlmock.NewRows(rotateCols).AddRow(1, "fam_1", "web", true, false, "user", true))
+	mock.ExpectRollback()
+
+	s := postgresql.NewPostgresForTesting(db)
+	_, err = s.RotateRefreshToken(context.Background(), "hash_1", nextRefreshToken(time.Now().Add(time.Hour)))
+
+	assert.ErrorIs(t, err, storage.ErrTokenNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRotateRefreshToken_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT rt.user_id, rt.family_id").WithArgs("hash_1").
+		WillReturnRows(sqlmock.NewRows(rotateCols))
+	mock.ExpectRollback()
+
+	s := postgresql.NewPostgresForTesting(db)
+	_, err = s.RotateRefreshToken(context.Background(), "hash_1", nextRefreshToken(time.Now().Add(time.Hour)))
+
+	assert.ErrorIs(t, err, storage.ErrTokenNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeRefreshToken_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("UPDATE device_sessions").WithArgs("hash_1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "id"}).AddRow(1, "fam_1"))
+
+	s := postgresql.NewPostgresForTesting(db)
+	userID, sessionID, err := s.RevokeRefreshToken(context.Background(), "hash_1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, userID)
+	assert.Equal(t, "fam_1", sessionID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRevokeRefreshToken_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("UPDATE device_sessions").WithArgs("hash_1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "id"}))
+
+	s := postgresql.NewPostgresForTesting(db)
+	_, _, err = s.RevokeRefreshToken(context.Background(), "hash_1")
+
+	assert.ErrorIs(t, err, storage.ErrTokenNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,28 @@
+package postgresql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// SaveSecurityEvent записывает событие безопасности пользователя (типы — models.SecurityEvent*); details сохраняются в JSONB
+func (p *PostgesStorage) SaveSecurityEvent(ctx context.Context, userID int, eventType string, details map[string]string) error {
+	const op = "storage.postgresql.SaveSecurityEvent"
+
+	if details == nil {
+		details = map[string]string{}
+	}
+	data, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("location %s: %w", op, err)
+	}
+
+	if _, err := p.db.ExecContext(ctx, `
+        INSERT INTO security_events (user_id, type, details)
+        VALUES ($1, $2, $3)
+    `, userID, eventType, string(data)); err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package postgresql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	"github.com/1abobik1/AuthService/internal/storage/postgresql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveSecurityEvent_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO security_events").
+		WithArgs(7, models.SecurityEventLoginLockout, `{"failures":"5","ip":"10.0.0.1"}`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	s := postgresql.NewPostgresForTesting(db)
+	err = s.SaveSecurityEvent(context.Background(), 7, models.SecurityEventLoginLockout, map[string]string{"ip": "10.0.0.1", "failures": "5"})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveSecurityEvent_NilDetails(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO security_events").
+		WithArgs(7, models.SecurityEventLoginUnlock, `{}`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	s := postgresql.NewPostgresForTesting(db)
+	err = s.SaveSecurityEvent(context.Background(), 7, models.SecurityEventLoginUnlock, nil)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSaveSecurityEvent_DBError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("INSERT INTO security_events").
+		WillReturnError(errors.New("connection lost"))
+
+	s := postgresql.NewPostgresForTesting(db)
+	err = s.SaveSecurityEvent(context.Background(), 7, models.SecurityEventLoginLockout, nil)
+
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,179 @@
+package postgresql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/1abobik1/AuthService/internal/storage"
+)
+
+// SetTOTPSecret сохраняет новый секрет TOTP, пока 2FA не подтверждена. Повторное подключение заменяет секрет.
+func (p *PostgesStorage) SetTOTPSecret(ctx context.Context, userID int, secret string) error {
+	const op = "storage.postgresql.SetTOTPSecret"
+
+	res, err := p.db.ExecContext(ctx, `
+        UPDATE auth_users
+        SET totp_secret = $2, totp_last_step = NULL
+        WHERE id = $1
+          AND NOT totp_enabled
+    `, userID, secret)
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	if n == 0 {
+		return fmt.Errorf("location %s: %w", op, storage.ErrTOTPAlreadyEnabled)
+	}
+	return nil
+}
+
+// GetTOTPSecret возвращает секрет TOTP пользователя и включена ли 2FA. Без секрета — storage.ErrTOTPNotEnrolled.
+func (p *PostgesStorage) GetTOTPSecret(ctx context.Context, userID int) (string, bool, error) {
+	const op = "storage.postgresql.GetTOTPSecret"
+
+	var secret sql.NullString
+	var enabled bool
+	err := p.db.QueryRowContext(ctx, `SELECT totp_secret, totp_enabled FROM auth_users WHERE id = $1`, userID).Scan(&secret, &enabled)
+	if err != nil {
+		return "", false, wrapPostgresErrors(err, op)
+	}
+	if !secret.Valid {
+		return "", false, fmt.Errorf("location %s: %w", op, storage.ErrTOTPNotEnrolled)
+	}
+	return secret.String, enabled, nil
+}
+
+// UseTOTPStep запоминает период принятого кода. Код того же или более раннего периода — storage.ErrTOTPCodeReused.
+func (p *PostgesStorage) UseTOTPStep(ctx context.Context, userID int, step int64) error {
+	const op = "storage.postgresql.UseTOTPStep"
+
+	res, err := p.db.ExecContext(ctx, `
+        UPDATE auth_users
+        SET totp_last_step = $2
+        WHERE id = $1
+          AND (totp_last_step IS NULL OR totp_last_step < $2)
+    `, userID, step)
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	if n == 0 {
+		return fmt.Errorf("location %s: %w", op, storage.ErrTOTPCodeReused)
+	}
+	return nil
+}
+
+// EnableTOTP включает 2FA и заменяет коды восстановления переданными хешами
+func (p *PostgesStorage) EnableTOTP(ctx context.Context, userID int, recoveryCodeHashes []string) error {
+	const op = "storage.postgresql.EnableTOTP"
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+        UPDATE auth_users
+        SET totp_enabled = TRUE
+        WHERE id = $1
+          AND totp_secret IS NOT NULL
+          AND NOT totp_enabled
+    `, userID)
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	if n == 0 {
+		return fmt.Errorf("location %s: %w", op, storage.ErrTOTPAlreadyEnabled)
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	return nil
+}
+
+// UseRecoveryCode погашает код восстановления. Неизвестный или использованный — storage.ErrRecoveryCodeInvalid.
+func (p *PostgesStorage) UseRecoveryCode(ctx context.Context, userID int, codeHash string) error {
+	const op = "storage.postgresql.UseRecoveryCode"
+
+	var id int
+	err := p.db.QueryRowContext(ctx, `
+        UPDATE totp_recovery_codes
+        SET used_at = NOW()
+        WHERE id = (
+            SELECT id FROM totp_recovery_codes
+            WHERE user_id = $1
+              AND code_hash = $2
+              AND used_at IS NULL
+            LIMIT 1
+            FOR UPDATE
+        )
+        RETURNING id
+    `, userID, codeHash).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("location %s: %w", op, storage.ErrRecoveryCodeInvalid)
+	}
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	return nil
+}
+
+// DisableTOTP выключает 2FA, удаляет секрет и коды восстановления
+func (p *PostgesStorage) DisableTOTP(ctx context.Context, userID int) error {
+	const op = "storage.postgresql.DisableTOTP"
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE auth_users
+        SET totp_enabled = FALSE, totp_secret = NULL, totp_last_step = NULL
+        WHERE id = $1
+    `, userID); err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, nil); err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return wrapPostgresErrors(err, op)
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM totp_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO totp_recovery_codes (user_id, code_hash) VALUES ($1, $2)`, userID, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
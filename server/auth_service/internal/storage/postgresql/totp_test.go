@@ -0,0 +1,94 @@
+package postgresql_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/1abobik1/AuthService/internal/storage"
+	"github.com/1abobik1/AuthService/internal/storage/postgresql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUseTOTPStep_Reused(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE auth_users").
+		WithArgs(1, int64(100)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	s := postgresql.NewPostgresForTesting(db)
+	err = s.UseTOTPStep(context.Background(), 1, 100)
+
+	assert.ErrorIs(t, err, storage.ErrTOTPCodeReused)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetTOTPSecret_NotEnrolled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT totp_secret, totp_enabled FROM auth_users WHERE id = \\$1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"totp_secret", "totp_enabled"}).AddRow(nil, false))
+
+	s := postgresql.NewPostgresForTesting(db)
+	_, _, err = s.GetTOTPSecret(context.Background(), 1)
+
+	assert.ErrorIs(t, err, storage.ErrTOTPNotEnrolled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnableTOTP_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE auth_users").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM totp_recovery_codes").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO totp_recovery_codes").WithArgs(1, "hash_1").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO totp_recovery_codes").WithArgs(1, "hash_2").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	s := postgresql.NewPostgresForTesting(db)
+	err = s.EnableTOTP(context.Background(), 1, []string{"hash_1", "hash_2"})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnableTOTP_AlreadyEnabled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE auth_users").WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	s := postgresql.NewPostgresForTesting(db)
+	err = s.EnableTOTP(context.Background(), 1, []string{"hash_1"})
+
+	assert.ErrorIs(t, err, storage.ErrTOTPAlreadyEnabled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUseRecoveryCode_Invalid(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("UPDATE totp_recovery_codes").
+		WithArgs(1, "hash_1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	s := postgresql.NewPostgresForTesting(db)
+	err = s.UseRecoveryCode(context.Background(), 1, "hash_1")
+
+	assert.ErrorIs(t, err, storage.ErrRecoveryCodeInvalid)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,138 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/domain/models"
+	goredis "github.com/go-redis/redis/v8"
+)
+
+const (
+	loginFailuresPrefix    = "login_failures:"     // число неудачных попыток входа пользователя подряд
+	loginLockedUntilPrefix = "login_locked_until:" // unix-время, до которого вход пользователя заблокирован
+	loginLockBasePrefix    = "login_lock_base:"    // число неудач, на котором вход был заблокирован в последний раз
+)
+
+// LoginAttemptStore — неудачные попытки входа по аккаунтам. Хранится в Redis, чтобы счётчик и блокировка
+// были общими для всех экземпляров auth_service
+type LoginAttemptStore struct {
+	cli *goredis.Client
+}
+
+func NewLoginAttemptStore(cli *goredis.Client) *LoginAttemptStore {
+	return &LoginAttemptStore{cli: cli}
+}
+
+// reserveAttemptScript атомарно проверяет блокировку и засчитывает попытку. Возвращает {0, until, 0}, если вход
+// заблокирован, иначе {номер попытки в серии, 0, 1 — если попытка сверх допустимых}. До первой блокировки
+// допустимы threshold попыток, после каждой блокировки — ещё одна
+var reserveAttemptScript = goredis.NewScript(`
+local locked = tonumber(redis.call('GET', KEYS[2]) or '0')
+if locked > tonumber(ARGV[2]) then
+    return {0, locked, 0}
+end
+local n = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+local limit = tonumber(ARGV[3])
+local base = tonumber(redis.call('GET', KEYS[3]) or '0')
+if base > 0 then
+    redis.call('PEXPIRE', KEYS[3], ARGV[1])
+    limit = math.max(limit, base + 1)
+end
+if n > limit then
+    return {n, 0, 1}
+end
+return {n, 0, 0}
+`)
+
+// releaseAttemptScript возвращает зарезервированную попытку, не опуская счётчик ниже нуля
+var releaseAttemptScript = goredis.NewScript(`
+local n = tonumber(redis.call('GET', KEYS[1]) or '0')
+if n > 0 then
+    redis.call('DECR', KEYS[1])
+end
+return 0
+`)
+
+// lockScript блокирует вход до ARGV[1], не сокращая уже действующую блокировку, и запоминает число неудач,
+// на котором вход заблокирован: после окончания блокировки следующая попытка проверяется заново
+var lockScript = goredis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+if tonumber(ARGV[1]) > current then
+    redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+end
+local base = tonumber(redis.call('GET', KEYS[2]) or '0')
+local ttl = redis.call('PTTL', KEYS[3])
+if tonumber(ARGV[3]) > base and ttl > 0 then
+    redis.call('SET', KEYS[2], ARGV[3], 'PX', ttl)
+end
+return 0
+`)
+
+// ReserveAttempt засчитывает попытку входа до проверки пароля или кода и возвращает её номер в серии неудач.
+// Проверка блокировки и увеличение счётчика атомарны, поэтому параллельные запросы получают разные номера.
+// Попытка сверх допустимых (threshold до первой блокировки, одна после окончания каждой блокировки) помечается
+// OverLimit: пароль в ней проверять нельзя, пока не проверены предыдущие. Если вход заблокирован, попытка
+// не засчитывается и возвращается время окончания блокировки. Серия забывается, если попыток не было window
+func (l *LoginAttemptStore) ReserveAttempt(ctx context.Context, userID int, threshold int64, window time.Duration) (models.LoginAttempt, error) {
+	const op = "storage.redis.ReserveAttempt"
+
+	id := strconv.Itoa(userID)
+	res, err := reserveAttemptScript.Run(ctx, l.cli,
+		[]string{loginFailuresPrefix + id, loginLockedUntilPrefix + id, loginLockBasePrefix + id},
+		window.Milliseconds(), time.Now().Unix(), threshold,
+	).Int64Slice()
+	if err != nil {
+		return models.LoginAttempt{}, fmt.Errorf("location %s, user %d: %w", op, userID, err)
+	}
+	if res[1] != 0 {
+		return models.LoginAttempt{LockedUntil: time.Unix(res[1], 0)}, nil
+	}
+	return models.LoginAttempt{Failures: res[0], OverLimit: res[2] == 1}, nil
+}
+
+// ReleaseAttempt возвращает попытку, зарезервированную ReserveAttempt, если она оказалась не неудачной,
+// а вход ещё не завершён (например, пароль верный и дальше нужен код 2FA)
+func (l *LoginAttemptStore) ReleaseAttempt(ctx context.Context, userID int) error {
+	const op = "storage.redis.ReleaseAttempt"
+
+	if err := releaseAttemptScript.Run(ctx, l.cli, []string{loginFailuresPrefix + strconv.Itoa(userID)}).Err(); err != nil {
+		return fmt.Errorf("location %s, user %d: %w", op, userID, err)
+	}
+	return nil
+}
+
+// Lock блокирует вход пользователя до until после failures неудач подряд. Более долгая блокировка,
+// выставленная параллельной попыткой, не сокращается
+func (l *LoginAttemptStore) Lock(ctx context.Context, userID int, until time.Time, failures int64) error {
+	const op = "storage.redis.Lock"
+
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	id := strconv.Itoa(userID)
+	err := lockScript.Run(ctx, l.cli,
+		[]string{loginLockedUntilPrefix + id, loginLockBasePrefix + id, loginFailuresPrefix + id},
+		until.Unix(), ttl.Milliseconds(), failures,
+	).Err()
+	if err != nil {
+		return fmt.Errorf("location %s, user %d: %w", op, userID, err)
+	}
+	return nil
+}
+
+// Reset снимает блокировку и обнуляет счётчик неудач. Возвращает false, если сбрасывать было нечего
+func (l *LoginAttemptStore) Reset(ctx context.Context, userID int) (bool, error) {
+	const op = "storage.redis.Reset"
+
+	id := strconv.Itoa(userID)
+	n, err := l.cli.Del(ctx, loginFailuresPrefix+id, loginLockedUntilPrefix+id, loginLockBasePrefix+id).Result()
+	if err != nil {
+		return false, fmt.Errorf("location %s, user %d: %w", op, userID, err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,186 @@
+package redis
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testThreshold = 3
+
+func newTestLoginAttemptStore(t *testing.T) (*LoginAttemptStore, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	cli := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { cli.Close() })
+	return NewLoginAttemptStore(cli), mr
+}
+
+func reserve(t *testing.T, l *LoginAttemptStore) (int64, bool) {
+	t.Helper()
+	attempt, err := l.ReserveAttempt(context.Background(), 7, testThreshold, time.Hour)
+	require.NoError(t, err)
+	require.True(t, attempt.LockedUntil.IsZero(), "login is locked until %s", attempt.LockedUntil)
+	return attempt.Failures, attempt.OverLimit
+}
+
+// expireLock снимает блокировку так, будто её срок истёк; счётчик неудач остаётся
+func expireLock(mr *miniredis.Miniredis) {
+	mr.Del(loginLockedUntilPrefix + "7")
+}
+
+func TestReserveAttempt_CountsAndExpires(t *testing.T) {
+	l, mr := newTestLoginAttemptStore(t)
+
+	for want := int64(1); want <= testThreshold; want++ {
+		n, overLimit := reserve(t, l)
+		assert.Equal(t, want, n)
+		assert.False(t, overLimit)
+	}
+	assert.Equal(t, time.Hour, mr.TTL(loginFailuresPrefix+"7"))
+
+	// серия забывается, если попыток не было window
+	mr.FastForward(time.Hour + time.Second)
+	n, _ := reserve(t, l)
+	assert.Equal(t, int64(1), n)
+}
+
+// Пока вход заблокирован, попытка не засчитывается и возвращается срок блокировки
+func TestReserveAttempt_Locked(t *testing.T) {
+	l, mr := newTestLoginAttemptStore(t)
+	ctx := context.Background()
+
+	reserve(t, l)
+	lockedUntil := time.Now().Add(time.Minute).Truncate(time.Second)
+	require.NoError(t, l.Lock(ctx, 7, lockedUntil, 1))
+
+	attempt, err := l.ReserveAttempt(ctx, 7, testThreshold, time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), attempt.Failures)
+	assert.True(t, attempt.LockedUntil.Equal(lockedUntil), "until = %s, want %s", attempt.LockedUntil, lockedUntil)
+
+	failures, err := mr.Get(loginFailuresPrefix + "7")
+	require.NoError(t, err)
+	assert.Equal(t, "1", failures)
+}
+
+// Параллельные попытки получают разные номера, и сверх порога помечаются OverLimit
+func TestReserveAttempt_Concurrent(t *testing.T) {
+	l, _ := newTestLoginAttemptStore(t)
+	ctx := context.Background()
+
+	const attempts = 20
+	var (
+		mu        sync.Mutex
+		got       []int64
+		overLimit int
+		wg        sync.WaitGroup
+	)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			attempt, err := l.ReserveAttempt(ctx, 7, testThreshold, time.Hour)
+			assert.NoError(t, err)
+			mu.Lock()
+			got = append(got, attempt.Failures)
+			if attempt.OverLimit {
+				overLimit++
+			}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+	for i, n := range got {
+		assert.Equal(t, int64(i+1), n)
+	}
+	assert.Equal(t, attempts-testThreshold, overLimit)
+}
+
+// После окончания блокировки одна попытка снова проверяется, а параллельная ей — нет
+func TestReserveAttempt_AfterLockExpired(t *testing.T) {
+	l, mr := newTestLoginAttemptStore(t)
+	ctx := context.Background()
+
+	for i := 0; i < testThreshold; i++ {
+		reserve(t, l)
+	}
+	require.NoError(t, l.Lock(ctx, 7, time.Now().Add(time.Minute), testThreshold))
+	expireLock(mr)
+
+	n, overLimit := reserve(t, l)
+	assert.Equal(t, int64(testThreshold+1), n)
+	assert.False(t, overLimit, "the first attempt after the lock is checked")
+	_, overLimit = reserve(t, l)
+	assert.True(t, overLimit, "a parallel attempt is not checked")
+
+	// неудачная попытка снова блокирует вход, после блокировки снова проверяется одна попытка
+	require.NoError(t, l.Lock(ctx, 7, time.Now().Add(2*time.Minute), testThreshold+2))
+	expireLock(mr)
+	n, overLimit = reserve(t, l)
+	assert.Equal(t, int64(testThreshold+3), n)
+	assert.False(t, overLimit)
+}
+
+// Более короткая блокировка от параллельной попытки не сокращает уже выставленную
+func TestLock_KeepsLongerLock(t *testing.T) {
+	l, _ := newTestLoginAttemptStore(t)
+	ctx := context.Background()
+
+	for i := 0; i < testThreshold+1; i++ {
+		reserve(t, l)
+	}
+	longer := time.Now().Add(2 * time.Minute).Truncate(time.Second)
+	require.NoError(t, l.Lock(ctx, 7, longer, testThreshold+1))
+	require.NoError(t, l.Lock(ctx, 7, time.Now().Add(time.Minute), testThreshold))
+
+	attempt, err := l.ReserveAttempt(ctx, 7, testThreshold, time.Hour)
+	require.NoError(t, err)
+	assert.True(t, attempt.LockedUntil.Equal(longer), "until = %s, want %s", attempt.LockedUntil, longer)
+}
+
+func TestReleaseAttempt_NotBelowZero(t *testing.T) {
+	l, mr := newTestLoginAttemptStore(t)
+	ctx := context.Background()
+
+	reserve(t, l)
+	require.NoError(t, l.ReleaseAttempt(ctx, 7))
+	require.NoError(t, l.ReleaseAttempt(ctx, 7))
+	failures, err := mr.Get(loginFailuresPrefix + "7")
+	require.NoError(t, err)
+	assert.Equal(t, "0", failures)
+
+	// у пользователя без попыток ключ не создаётся
+	require.NoError(t, l.ReleaseAttempt(ctx, 8))
+	assert.False(t, mr.Exists(loginFailuresPrefix+"8"))
+
+	n, _ := reserve(t, l)
+	assert.Equal(t, int64(1), n)
+}
+
+func TestResetLoginAttempts(t *testing.T) {
+	l, mr := newTestLoginAttemptStore(t)
+	ctx := context.Background()
+
+	reserve(t, l)
+	require.NoError(t, l.Lock(ctx, 7, time.Now().Add(time.Minute), 1))
+
+	_, err := l.Reset(ctx, 7)
+	require.NoError(t, err)
+	assert.False(t, mr.Exists(loginFailuresPrefix+"7"))
+	assert.False(t, mr.Exists(loginLockedUntilPrefix+"7"))
+	assert.False(t, mr.Exists(loginLockBasePrefix+"7"))
+
+	n, overLimit := reserve(t, l)
+	assert.Equal(t, int64(1), n)
+	assert.False(t, overLimit)
+}
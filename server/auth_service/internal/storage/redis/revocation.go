@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	goredis "github.com/go-redis/redis/v8"
+)
+
+// Ключи и канал общие с secure_comm_service (internal/revocation): менять только вместе
+const (
+	revokedJTIPrefix    = "revoked_jti:"    // отозванный access токен (claim jti)
+	revokedSIDPrefix    = "revoked_sid:"    // отозванная сессия устройства (claim sid)
+	revokedBeforePrefix = "revoked_before:" // unix-время: токены пользователя, выпущенные в эту секунду и раньше, недействительны
+	revocationChannel   = "token_revocations"
+)
+
+// RevocationStore — список отзыва access токенов в Redis. Каждая запись публикуется в revocationChannel,
+// чтобы secure_comm_service сразу обновил свой локальный фильтр. Записи живут ttl — дольше ACCESS_TOKEN_TTL
+// отозванные токены всё равно не действуют
+type RevocationStore struct {
+	cli *goredis.Client
+}
+
+func NewRevocationStore(cli *goredis.Client) *RevocationStore {
+	return &RevocationStore{cli: cli}
+}
+
+// RevokeToken отзывает один access токен по его jti
+func (r *RevocationStore) RevokeToken(ctx context.Context, jti string, ttl time.Duration) error {
+	return r.revoke(ctx, revokedJTIPrefix+jti, "1", ttl)
+}
+
+// RevokeSession отзывает все access токены сессии устройства
+func (r *RevocationStore) RevokeSession(ctx context.Context, sessionID string, ttl time.Duration) error {
+	return r.revoke(ctx, revokedSIDPrefix+sessionID, "1", ttl)
+}
+
+// RevokeUserTokensBefore отзывает все access токены пользователя, выпущенные раньше t.
+// iat хранится с точностью до секунды, поэтому отзываются и токены, выпущенные в ту же секунду, что и t
+func (r *RevocationStore) RevokeUserTokensBefore(ctx context.Context, userID int, t time.Time, ttl time.Duration) error {
+	return r.revoke(ctx, revokedBeforePrefix+strconv.Itoa(userID), strconv.FormatInt(t.Unix(), 10), ttl)
+}
+
+// IsRevoked проверяет access токен по списку отзыва: по jti, сессии устройства sessionID и времени отзыва
+// всех токенов пользователя. Пустые jti и sessionID не проверяются
+func (r *RevocationStore) IsRevoked(ctx context.Context, jti, sessionID string, userID int, issuedAt time.Time) (bool, error) {
+	const op = "storage.redis.IsRevoked"
+
+	keys := []string{revokedBeforePrefix + strconv.Itoa(userID)}
+	if jti != "" {
+		keys = append(keys, revokedJTIPrefix+jti)
+	}
+	if sessionID != "" {
+		keys = append(keys, revokedSIDPrefix+sessionID)
+	}
+	values, err := r.cli.MGet(ctx, keys...).Result()
+	if err != nil {
+		return false, fmt.Errorf("location %s, user %d: %w", op, userID, err)
+	}
+
+	if before, ok := values[0].(string); ok {
+		if unix, err := strconv.ParseInt(before, 10, 64); err == nil && issuedAt.Unix() <= unix {
+			return true, nil
+		}
+	}
+	for _, v := range values[1:] {
+		if v != nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *RevocationStore) revoke(ctx context.Context, key, value string, ttl time.Duration) error {
+	const op = "storage.redis.revoke"
+
+	_, err := r.cli.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		pipe.Set(ctx, key, value, ttl)
+		pipe.Publish(ctx, revocationChannel, key)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("location %s, key %s: %w", op, key, err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRevocationStore(t *testing.T) *RevocationStore {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	cli := goredis.NewClient(&goredis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { cli.Close() })
+	return NewRevocationStore(cli)
+}
+
+func TestIsRevoked(t *testing.T) {
+	r := newTestRevocationStore(t)
+	ctx := context.Background()
+	issuedAt := time.Now()
+
+	revoked, err := r.IsRevoked(ctx, "jti-1", "sid-1", 7, issuedAt)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, r.RevokeToken(ctx, "jti-1", time.Hour))
+	revoked, err = r.IsRevoked(ctx, "jti-1", "sid-1", 7, issuedAt)
+	require.NoError(t, err)
+	assert.True(t, revoked, "revoked by jti")
+
+	require.NoError(t, r.RevokeSession(ctx, "sid-2", time.Hour))
+	revoked, err = r.IsRevoked(ctx, "jti-2", "sid-2", 7, issuedAt)
+	require.NoError(t, err)
+	assert.True(t, revoked, "revoked by device session")
+
+	// токен без sid (выпущенный до сессий устройств) проверяется по jti и времени отзыва
+	revoked, err = r.IsRevoked(ctx, "jti-3", "", 7, issuedAt)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+// Время отзыва всех токенов пользователя покрывает и токены, выпущенные в ту же секунду
+func TestIsRevoked_UserWatermark(t *testing.T) {
+	r := newTestRevocationStore(t)
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, r.RevokeUserTokensBefore(ctx, 7, now, time.Hour))
+
+	revoked, err := r.IsRevoked(ctx, "jti-1", "sid-1", 7, now.Truncate(time.Second))
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = r.IsRevoked(ctx, "jti-2", "sid-1", 7, now.Add(time.Second))
+	require.NoError(t, err)
+	assert.False(t, revoked, "a token issued after the watermark second is valid")
+
+	revoked, err = r.IsRevoked(ctx, "jti-1", "sid-1", 8, now)
+	require.NoError(t, err)
+	assert.False(t, revoked, "the watermark applies only to its user")
+}
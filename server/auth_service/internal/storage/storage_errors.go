@@ -6,4 +6,24 @@ var (
 	ErrUserExists   = errors.New("user already exists")
 	ErrUserNotFound = errors.New("user not found")
 	ErrTokenNotFound = errors.New("refresh_token not found")
+	ErrTokenReused   = errors.New("refresh_token has already been used")
+
+	ErrSessionNotFound = errors.New("device session not found")
+
+	ErrVerificationNotFound  = errors.New("verification token is invalid or expired")
+	ErrVerificationThrottled = errors.New("verification email was sent recently")
+
+	ErrResetNotFound  = errors.New("password reset token is invalid or expired")
+	ErrResetThrottled = errors.New("password reset email was sent recently")
+
+	ErrTOTPAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled     = errors.New("two-factor authentication is not set up")
+	ErrTOTPCodeReused      = errors.New("two-factor code has already been used")
+	ErrRecoveryCodeInvalid = errors.New("recovery code is invalid or already used")
+
+	ErrOIDCStateNotFound  = errors.New("oidc login request is invalid or expired")
+	ErrOIDCIdentityLinked = errors.New("oidc identity is linked to another account")
+
+	ErrDeletionRequested = errors.New("account deletion has already been requested")
+	ErrDeletionNotFound  = errors.New("account deletion request not found")
 )
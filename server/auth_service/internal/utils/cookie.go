@@ -2,6 +2,7 @@ package utils
 
 import (
 	"fmt"
+	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
@@ -25,3 +26,29 @@ func SetRefreshTokenCookie(c *gin.Context, refreshToken string) {
 		true,  // доступен только через HTTP
 	)
 }
+
+// oidcBindingCookie — секрет, привязывающий начатый вход через OIDC к браузеру (см. serviceUsers.StartOIDCLogin)
+const oidcBindingCookie = "oidc_binding"
+
+// SetOIDCBindingCookie кладёт binding в cookie на время входа: без срока жизни (до закрытия браузера),
+// state всё равно действует только OIDC_STATE_TTL. SameSite=Lax: cookie не уходит с запросами, начатыми чужим сайтом
+func SetOIDCBindingCookie(c *gin.Context, binding string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(
+		oidcBindingCookie,
+		binding,
+		0,
+		"/user/oidc",
+		"",
+		false, // true для HTTPS
+		true,  // доступен только через HTTP
+	)
+}
+
+// PopOIDCBindingCookie возвращает binding из cookie ("" — cookie нет) и удаляет её: state одноразовый
+func PopOIDCBindingCookie(c *gin.Context) string {
+	binding, _ := c.Cookie(oidcBindingCookie)
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcBindingCookie, "", -1, "/user/oidc", "", false, true)
+	return binding
+}
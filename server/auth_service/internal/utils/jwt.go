@@ -1,40 +1,55 @@
 package utils
 
 import (
-	"crypto/rsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"os"
+	"strings"
 	"time"
 
+	"github.com/1abobik1/AuthService/internal/keyset"
 	"github.com/golang-jwt/jwt/v4"
 )
 
 // Структура данных для хранения пользовательских claims
 type customClaims struct {
-	UserID int `json:"user_id"`
+	UserID int    `json:"user_id"`
+	Role   string `json:"role,omitempty"` // только в access токене
+	// только в access токене; указатель, чтобы false не пропадал из токена
+	EmailVerified *bool `json:"email_verified,omitempty"`
+	// только в access токене: ID сессии устройства (совпадает с семейством refresh токенов)
+	SessionID string `json:"sid,omitempty"`
+	// только в refresh токене: семейство токенов одного входа
+	Family string `json:"fam,omitempty"`
 	jwt.RegisteredClaims
 }
 
-// Создание Access Token
-func CreateAccessToken(userID int, duration time.Duration, privateKeyPath string) (string, error) {
-	privateKey, err := getgPrivateKey(privateKeyPath)
+// Создание Access Token, role — роль пользователя (models.RoleUser, models.RoleAdmin),
+// emailVerified — подтверждён ли email (без этого часть действий в secure_comm_service запрещена),
+// sessionID — сессия устройства, по которой токен выдан (claim sid). Каждый токен получает свой jti,
+// по которому его можно отозвать до истечения срока
+func CreateAccessToken(userID int, role string, emailVerified bool, sessionID string, duration time.Duration, keys *keyset.KeySet) (string, error) {
+	jti, err := NewTokenID()
 	if err != nil {
-		return "", fmt.Errorf("error to get private key in file: %s", privateKeyPath)
+		return "", err
 	}
 
 	// Настраиваем claims для access токена
 	claims := customClaims{
-		UserID: userID,
+		UserID:        userID,
+		Role:          role,
+		EmailVerified: &emailVerified,
+		SessionID:     sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)), // Устанавливаем срок действия токена
 			IssuedAt:  jwt.NewNumericDate(time.Now()),               // Время выпуска токена
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-
-	// Подписываем токен секретным ключом
-	tokenString, err := token.SignedString(privateKey)
+	// Подписываем токен текущим ключом, kid ключа — в заголовке
+	tokenString, err := keys.Sign(claims)
 	if err != nil {
 		return "", err // Возвращаем ошибку, если подпись не удалась
 	}
@@ -42,26 +57,83 @@ func CreateAccessToken(userID int, duration time.Duration, privateKeyPath string
 	return tokenString, nil // Возвращаем готовый токен
 }
 
-// Создание Refresh Token
-func CreateRefreshToken(userID int, duration time.Duration, privateKeyPath string) (string, error) {
+// ServiceIssuer — sub сервисных токенов, выпущенных auth_service
+const ServiceIssuer = "auth_service"
 
-	privateKey, err := getgPrivateKey(privateKeyPath)
-	if err != nil {
-		return "", fmt.Errorf("error to get private key in file: %s", privateKeyPath)
+// claims сервисного токена: вместо user_id — aud (сервис-получатель) и scope (разрешённые операции через пробел)
+type serviceClaims struct {
+	Scope string `json:"scope"`
+	jwt.RegisteredClaims
+}
+
+// Создание сервисного токена для вызова внутреннего API сервиса audience.
+// Пользовательские middleware такие токены не принимают, внутренние — только с нужными aud и scope
+func CreateServiceToken(audience string, scopes []string, duration time.Duration, keys *keyset.KeySet) (string, error) {
+	now := time.Now()
+	claims := serviceClaims{
+		Scope: strings.Join(scopes, " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   ServiceIssuer,
+			Audience:  jwt.ClaimStrings{audience},
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	return keys.Sign(claims)
+}
+
+// ScopeLogin2FA — scope промежуточного токена входа при включённой 2FA. Такой токен годится только
+// для второго шага входа: secure_comm_service не принимает токены с claim scope
+const ScopeLogin2FA = "login:2fa"
+
+// Способ, которым пройден первый шаг входа (claim amr промежуточного токена)
+const (
+	AuthMethodPassword = "pwd"
+	AuthMethodOIDC     = "oidc"
+)
+
+type challengeClaims struct {
+	UserID   int    `json:"user_id"`
+	Platform string `json:"platform"`
+	Scope    string `json:"scope"`
+	Method   string `json:"amr"`
+	jwt.RegisteredClaims
+}
+
+// Создание промежуточного токена входа: первый шаг (пароль или OIDC провайдер, method) пройден, ожидается код 2FA
+func CreateChallengeToken(userID int, platform, method string, duration time.Duration, keys *keyset.KeySet) (string, error) {
+	now := time.Now()
+	claims := challengeClaims{
+		UserID:   userID,
+		Platform: platform,
+		Scope:    ScopeLogin2FA,
+		Method:   method,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(duration)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
 	}
+
+	return keys.Sign(claims)
+}
+
+// Создание Refresh Token. jti — уникальный идентификатор токена (claim jti), familyID — семейство токенов
+// одного входа (claim fam): при обновлении новый токен получает тот же familyID
+func CreateRefreshToken(userID int, jti, familyID string, duration time.Duration, keys *keyset.KeySet) (string, error) {
 	// Настраиваем claims для refresh токена
 	claims := customClaims{
 		UserID: userID,
+		Family: familyID,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(duration)), // Срок действия токена
 			IssuedAt:  jwt.NewNumericDate(time.Now()),               // Время выпуска токена
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-
-	// Подписываем токен секретным ключом
-	tokenString, err := token.SignedString(privateKey)
+	// Подписываем токен текущим ключом, kid ключа — в заголовке
+	tokenString, err := keys.Sign(claims)
 	if err != nil {
 		return "", err // Возвращаем ошибку, если подпись не удалась
 	}
@@ -69,16 +141,17 @@ func CreateRefreshToken(userID int, duration time.Duration, privateKeyPath strin
 	return tokenString, nil // Возвращаем готовый токен
 }
 
-func getgPrivateKey(file string) (*rsa.PrivateKey, error) {
-	// Читаем приватный ключ из файла
-	privateKeyData, err := os.ReadFile(file)
-	if err != nil {
-		return nil, err
-	}
-	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privateKeyData)
-	if err != nil {
-		return nil, err
+// NewTokenID возвращает случайный идентификатор для claim jti и семейств refresh токенов
+func NewTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate token id: %w", err)
 	}
+	return hex.EncodeToString(b), nil
+}
 
-	return privateKey, nil
+// HashToken — sha256 токена в hex. В базе refresh токены хранятся только в таком виде
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
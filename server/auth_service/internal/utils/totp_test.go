@@ -0,0 +1,39 @@
+package utils_test
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/AuthService/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// тестовые векторы RFC 6238 (приложение B, SHA1), последние 6 цифр
+func TestValidateTOTP_RFC6238(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for ts, code := range cases {
+		step, ok := utils.ValidateTOTP(secret, code, time.Unix(ts, 0))
+		assert.True(t, ok, "time %d", ts)
+		assert.Equal(t, ts/30, step)
+	}
+}
+
+func TestValidateTOTP_Skew(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	// код периода 59/30 = 1 принимается в соседнем периоде, но не через два
+	_, ok := utils.ValidateTOTP(secret, "287082", time.Unix(59+30, 0))
+	assert.True(t, ok)
+	_, ok = utils.ValidateTOTP(secret, "287082", time.Unix(59+60, 0))
+	assert.False(t, ok)
+	_, ok = utils.ValidateTOTP(secret, "28708", time.Unix(59, 0))
+	assert.False(t, ok)
+}
@@ -0,0 +1,29 @@
+-- Обновление базы, созданной до появления подтверждения email. docker-entrypoint-initdb.d выполняется только
+-- на пустом томе, поэтому на существующей базе скрипт запускается вручную один раз, до запуска новой версии auth_service:
+--   docker compose exec -T auth_db psql -U postgres -d auth-service < server/auth_service/migrations/activate_existing_accounts.sql
+-- Повторный запуск безопасен: аккаунты подтверждаются, только если обновление ещё не отмечено в schema_upgrades
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS schema_upgrades (
+    name TEXT PRIMARY KEY,
+    applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS email_verifications (
+    user_id INT PRIMARY KEY REFERENCES auth_users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- аккаунты, созданные до появления подтверждения email, считаются подтверждёнными
+WITH upgrade AS (
+    INSERT INTO schema_upgrades (name) VALUES ('activate_existing_accounts')
+    ON CONFLICT (name) DO NOTHING
+    RETURNING name
+)
+UPDATE auth_users SET is_activated = TRUE
+WHERE NOT is_activated AND EXISTS (SELECT 1 FROM upgrade);
+
+COMMIT;
@@ -1,10 +1,7 @@
 package validation
 
 import (
-	"crypto/rsa"
 	"errors"
-	"fmt"
-	"os"
 
 	"github.com/golang-jwt/jwt/v4"
 )
@@ -12,17 +9,14 @@ import (
 var ErrTokenExpired = errors.New("token is expired")
 var ErrTokenInvalid = errors.New("invalid token")
 
-func ValidateToken(tokenString, publicKeyPath string) (jwt.MapClaims, error) {
-	publicKey, err := getPublicKey(publicKeyPath)
-	if err != nil {
-		return nil, fmt.Errorf("error loading public key from file: %s", publicKeyPath)
-	}
-
+// ValidateToken проверяет подпись и срок действия токена. keyfunc выбирает открытый ключ по kid
+// из заголовка токена (см. keyset.KeySet.Keyfunc)
+func ValidateToken(tokenString string, keyfunc jwt.Keyfunc) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if token.Method.Alg() != jwt.SigningMethodRS256.Alg() {
 			return nil, ErrTokenInvalid
 		}
-		return publicKey, nil
+		return keyfunc(token)
 	})
 
 	if err != nil {
@@ -42,16 +36,3 @@ func ValidateToken(tokenString, publicKeyPath string) (jwt.MapClaims, error) {
 
 	return claims, nil
 }
-
-func getPublicKey(file string) (*rsa.PublicKey, error) {
-	publicKeyData, err := os.ReadFile(file)
-	if err != nil {
-		return nil, err
-	}
-	publicKey, err := jwt.ParseRSAPublicKeyFromPEM(publicKeyData)
-	if err != nil {
-		return nil, err
-	}
-
-	return publicKey, nil
-}
@@ -2,23 +2,35 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"time"
 
 	"github.com/1abobik1/SecureComm/config"
 	"github.com/1abobik1/SecureComm/internal/api"
+	"github.com/1abobik1/SecureComm/internal/billing"
 	"github.com/1abobik1/SecureComm/internal/checker"
+	"github.com/1abobik1/SecureComm/internal/handler/account_handler"
+	"github.com/1abobik1/SecureComm/internal/handler/admin_handler"
+	"github.com/1abobik1/SecureComm/internal/handler/billing_handler"
 	"github.com/1abobik1/SecureComm/internal/handler/cloud_handler"
 	"github.com/1abobik1/SecureComm/internal/handler/handshake_handler"
+	"github.com/1abobik1/SecureComm/internal/handler/org_handler"
 	"github.com/1abobik1/SecureComm/internal/handler/quota_handler"
+	"github.com/1abobik1/SecureComm/internal/jobs"
+	"github.com/1abobik1/SecureComm/internal/jwks"
+	"github.com/1abobik1/SecureComm/internal/notifier"
 	"github.com/1abobik1/SecureComm/internal/middleware"
 	"github.com/1abobik1/SecureComm/internal/repository/client_keystore"
 	"github.com/1abobik1/SecureComm/internal/repository/nonce_store"
 	"github.com/1abobik1/SecureComm/internal/repository/server_keystore"
 	"github.com/1abobik1/SecureComm/internal/repository/session_store"
+	"github.com/1abobik1/SecureComm/internal/revocation"
 	"github.com/1abobik1/SecureComm/internal/routes"
+	"github.com/1abobik1/SecureComm/internal/service/billing_service"
 	"github.com/1abobik1/SecureComm/internal/service/cloud_service"
 	"github.com/1abobik1/SecureComm/internal/service/handshake_service"
+	"github.com/1abobik1/SecureComm/internal/service/org_service"
 	"github.com/1abobik1/SecureComm/internal/service/quota_service"
 	"github.com/gin-contrib/cors"
 	"github.com/go-redis/redis/v8"
@@ -101,6 +113,22 @@ func main() {
 		cfg.Redis.SessionKeyTTL,
 	)
 
+	// открытые ключи auth_service для проверки JWT; если auth_service ещё не поднялся, ключи загрузятся при первом запросе
+	jwtKeys := jwks.NewVerifier(cfg.JWT.JWKSURL, cfg.JWT.JWKSCacheTTL, cfg.JWT.JWKSMinRefreshInterval)
+	if err := jwtKeys.Refresh(context.Background()); err != nil {
+		logrus.Warnf("jwks is not loaded yet: %v", err)
+	}
+
+	// сервисный слой handshake
+	hsService := handshake_service.NewService(hsNonceStore, sesNonceStore, serverKeys, clientKeys, sessionStore)
+
+	// список отзыва access токенов, который ведёт auth_service; при отзыве сессий удаляются и их сессионные ключи
+	revocations := revocation.NewChecker(rClient, cfg.Revocation.BloomCapacity, cfg.Revocation.CacheTTL, hsService)
+	if err := revocations.Start(context.Background()); err != nil {
+		log.Fatalf("revocation list init error: %v", err)
+	}
+	jobs.Every(context.Background(), "revocation filter rebuild", cfg.Revocation.RebuildInterval, revocations.Rebuild)
+
 	// Инициализация MinIO cloud_service слой
 	minioService := cloud_service.NewMinioClient(*cfg, rClient)
 	if err := minioService.InitMinio(cfg.Minio.Port, cfg.Minio.RootUser, cfg.Minio.RootPassword, cfg.Minio.UseSSL); err != nil {
@@ -113,14 +141,61 @@ func main() {
 		panic(err)
 	}
 
+	// уведомления пользователям: лог, вебхук и почта (если настроена)
+	userNotifier, emailNotifier := newNotifier(cfg.Notify)
+	if err := quotaService.EnableUsageAlerts(userNotifier, emailNotifier, cfg.Notify.UsageThresholds); err != nil {
+		log.Fatalf("usage alerts init error: %v", err)
+	}
+
+	// фоновая сверка MinIO, current_used и кеша redis
+	if cfg.Reconciler.Interval > 0 {
+		reconciler := jobs.NewReconciler(minioService, quotaService, jobs.ReconcilerOptions{DryRun: !cfg.Reconciler.Fix})
+		jobs.Every(context.Background(), "reconciler", cfg.Reconciler.Interval, reconciler.RunAndLog)
+	}
+
+	// удаление файлов с истёкшим сроком жизни
+	if cfg.Sweeper.Interval > 0 {
+		sweeper := jobs.NewExpirySweeper(minioService, quotaService)
+		jobs.Every(context.Background(), "expiry sweeper", cfg.Sweeper.Interval, sweeper.Run)
+	}
+
+	// организации с общим пулом хранилища
+	orgService, err := org_service.NewOrgService(cfg.Postges.StoragePath, quotaService)
+	if err != nil {
+		panic(err)
+	}
+	orgHandler := org_handler.NewOrgHandler(orgService)
+
 	// хендлерный слой quota
-	quotaHandler := quota_handler.NewQuotaHandler(quotaService)
+	quotaHandler := quota_handler.NewQuotaHandler(quotaService, orgService)
+	// админское API: тарифы, назначение планов и персональные лимиты
+	adminHandler := admin_handler.NewAdminHandler(quotaService)
+
+	// оплата тарифов
+	billingProvider, err := newBillingProvider(cfg.Billing)
+	if err != nil {
+		log.Fatalf("billing init error: %v", err)
+	}
+	billingService, err := billing_service.NewBillingService(cfg.Postges.StoragePath, billingProvider, quotaService, cfg.Billing.Currency)
+	if err != nil {
+		panic(err)
+	}
+	billingHandler := billing_handler.NewBillingHandler(billingService, orgService)
+
+	// окончание подписок: предупреждения, продление (через оплату), переход на free и grace-период.
+	// Подписки, до которых задача ещё не дошла (или она выключена), обрабатываются при обращении к квоте
+	planExpiry := jobs.NewPlanExpiryProcessor(quotaService, billingService, userNotifier, cfg.PlanExpiry.WarnBefore, cfg.PlanExpiry.Grace)
+	quotaService.SetLapseHandling(cfg.PlanExpiry.Grace, planExpiry.HandleTransition)
+	if cfg.PlanExpiry.Interval > 0 {
+		jobs.Every(context.Background(), "plan expiry", cfg.PlanExpiry.Interval, planExpiry.Run)
+	}
+
 	// хендлерный слой cloud_handler
-	minioHandler := cloud_handler.NewMinioHandler(minioService, quotaService)
-	// сервисный слой handshake
-	hsService := handshake_service.NewService(hsNonceStore, sesNonceStore, serverKeys, clientKeys, sessionStore)
+	minioHandler := cloud_handler.NewMinioHandler(minioService, quotaService, orgService)
 	// хендлерный слой handshake
 	hsHandler := handshake_handler.NewHandler(hsService)
+
+	accountHandler := account_handler.NewAccountHandler(minioService, quotaService, hsService)
 	// внешние клиенты
 	webClient := api.NewWEBClientKeysAPI(sessionStore)
 	tgClient := api.NewTGClientKeysAPI(sessionStore)
@@ -135,11 +210,12 @@ func main() {
 			"Origin",
 			"Authorization",
 			"Content-Type",
-			"X-Orig-Filename",
-			"X-Orig-Mime",
 			"X-File-Category",
+			"X-Encrypted-Meta",
+			"X-Expires-At",
+			"X-Expires-In",
 		},
-		ExposeHeaders:    []string{"Content-Length"},
+		ExposeHeaders:    []string{"Content-Length", "Content-Disposition"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -151,7 +227,21 @@ func main() {
 	hsAttemptLimiter := middleware.RegistrationAttemptLimiter()
 	sessionLimiter := middleware.NewIPRateLimiter(cfg.SesLimiter.RPC, cfg.SesLimiter.Burst, cfg.SesLimiter.Period) // middleware limiter для остальных апи
 	// регистрация всех маршрутов
-	routes.RegisterRoutes(r, cfg, quotaHandler, minioHandler, hsHandler, webClient, tgClient, hsLimiter, sessionLimiter, hsAttemptLimiter)
+	routes.RegisterRoutes(r, cfg, quotaHandler, minioHandler, billingHandler, orgHandler, adminHandler, quotaService, hsHandler, webClient, tgClient, hsLimiter, sessionLimiter, hsAttemptLimiter, jwtKeys.Keyfunc, revocations)
+
+	// внутреннее API: на отдельном адресе (не публикуется наружу) или под /internal основного
+	if cfg.Internal.ServerAddr != "" {
+		internal := gin.Default()
+		routes.RegisterInternalRoutes(internal, cfg, quotaHandler, hsHandler, accountHandler, jwtKeys.Keyfunc)
+		go func() {
+			logrus.Infof("Starting internal server on %s", cfg.Internal.ServerAddr)
+			if err := internal.Run(cfg.Internal.ServerAddr); err != nil {
+				panic(err)
+			}
+		}()
+	} else {
+		routes.RegisterInternalRoutes(r, cfg, quotaHandler, hsHandler, accountHandler, jwtKeys.Keyfunc)
+	}
 
 	logrus.Infof("Starting server on %s", cfg.HTTPServ.ServerAddr)
 	if err := r.Run(cfg.HTTPServ.ServerAddr); err != nil {
@@ -164,3 +254,37 @@ func main() {
 		panic(err)
 	}
 }
+
+// newNotifier собирает каналы уведомлений: лог всегда, вебхук и почту — если они настроены.
+// Почта возвращается и отдельно (nil без SMTP): через неё подтверждаются адреса для уведомлений
+func newNotifier(cfg config.NotifyConfig) (notifier.Notifier, notifier.Notifier) {
+	channels := []notifier.Notifier{
+		notifier.NewLogNotifier(),
+		notifier.NewWebhookNotifier(cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookTimeout, cfg.WebhookAllowPrivate),
+	}
+	var email notifier.Notifier
+	if cfg.SMTPAddr != "" {
+		email = notifier.NewEmailNotifier(cfg.SMTPAddr, cfg.SMTPFrom, cfg.SMTPUser, cfg.SMTPPassword)
+		channels = append(channels, email)
+	}
+	return notifier.NewMultiNotifier(channels...), email
+}
+
+// newBillingProvider создаёт платёжного провайдера по BILLING_PROVIDER
+func newBillingProvider(cfg config.BillingConfig) (billing.Provider, error) {
+	if cfg.WebhookSecret == "" {
+		return nil, fmt.Errorf("BILLING_WEBHOOK_SECRET is empty")
+	}
+
+	switch cfg.Provider {
+	case billing.FakeProviderName:
+		// fake подтверждает любую оплату без денег — в продакшене его нельзя включить случайно
+		if !cfg.AllowFake {
+			return nil, fmt.Errorf("the fake payment provider is for development only, set BILLING_ALLOW_FAKE=true to use it")
+		}
+		logrus.Warnf("billing: using the fake payment provider, payments are simulated via %s/billing/fake/pay", cfg.PublicURL)
+		return billing.NewFakeProvider(cfg.WebhookSecret, cfg.PublicURL, cfg.WebhookTolerance), nil
+	default:
+		return nil, fmt.Errorf("unknown billing provider %q", cfg.Provider)
+	}
+}
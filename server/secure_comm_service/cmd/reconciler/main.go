@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/1abobik1/SecureComm/config"
+	"github.com/1abobik1/SecureComm/internal/jobs"
+	"github.com/1abobik1/SecureComm/internal/service/cloud_service"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/go-redis/redis/v8"
+)
+
+// Разовый запуск реконсилера: сверяет объекты MinIO с user_plans.current_used и кешем redis
+// и печатает JSON-отчёт в stdout.
+//
+//	go run ./cmd/reconciler --config=.env                      # только отчёт
+//	go run ./cmd/reconciler --config=.env -dry-run=false        # исправить current_used и кеш
+//	go run ./cmd/reconciler --config=.env -dry-run=false -purge-orphans
+func main() {
+	dryRun := flag.Bool("dry-run", true, "только отчёт, без изменений")
+	purgeOrphans := flag.Bool("purge-orphans", false, "удалить объекты без владельца (работает только с -dry-run=false)")
+
+	// config.MustLoad сам вызывает flag.Parse
+	cfg := config.MustLoad()
+
+	rClient := redis.NewClient(&redis.Options{
+		Addr: cfg.Redis.ServerAddr,
+	})
+	if err := rClient.Ping(context.Background()).Err(); err != nil {
+		log.Fatalf("redis connection error: %v", err)
+	}
+
+	minioService := cloud_service.NewMinioClient(*cfg, rClient)
+	if err := minioService.InitMinio(cfg.Minio.Port, cfg.Minio.RootUser, cfg.Minio.RootPassword, cfg.Minio.UseSSL); err != nil {
+		log.Fatalf("minio init error: %v", err)
+	}
+
+	quotaService, err := quota_service.NewQuotaService(cfg.Postges.StoragePath)
+	if err != nil {
+		log.Fatalf("postgres init error: %v", err)
+	}
+
+	reconciler := jobs.NewReconciler(minioService, quotaService, jobs.ReconcilerOptions{
+		DryRun:       *dryRun,
+		PurgeOrphans: *purgeOrphans,
+	})
+
+	report, err := reconciler.Run(context.Background())
+	if err != nil {
+		log.Fatalf("reconcile error: %v", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -14,8 +14,11 @@ type PostgresConfig struct {
 	StoragePath string `env:"STORAGE_PATH" env-required:"true"`
 }
 
+// JWTConfig — открытые ключи для проверки JWT берутся из JWKS auth_service по kid токена
 type JWTConfig struct {
-	PublicKeyPath string `env:"JWT_PUBLIC_KEY_PATH" env-required:"true"`
+	JWKSURL                string        `env:"JWT_JWKS_URL" env-default:"http://auth_service:8081/.well-known/jwks.json"`
+	JWKSCacheTTL           time.Duration `env:"JWT_JWKS_CACHE_TTL" env-default:"1h"`
+	JWKSMinRefreshInterval time.Duration `env:"JWT_JWKS_MIN_REFRESH_INTERVAL" env-default:"30s"` // внеочередное обновление на неизвестный kid не чаще
 }
 
 type MinIoConfig struct {
@@ -47,6 +50,12 @@ type HTTPServConfig struct {
 	ServerAddr string `env:"HTTP_SERVER_ADDRESS" env-required:"true"`
 }
 
+// InternalConfig — внутреннее API для вызовов других сервисов (инициализация плана из auth_service)
+type InternalConfig struct {
+	ServerAddr string `env:"INTERNAL_HTTP_SERVER_ADDRESS" env-default:""`              // отдельный адрес внутреннего API; пустой — /internal на основном адресе
+	Audience   string `env:"SERVICE_TOKEN_AUDIENCE" env-default:"secure_comm_service"` // ожидаемый aud сервисных токенов
+}
+
 type HandShakeLimiter struct {
 	RPC    float64       `env:"HANDSHAKE_LIMITER_RPC" env-required:"true"`
 	Burst  int           `env:"HANDSHAKE_LIMITER_BURST" env-required:"true"`
@@ -59,6 +68,54 @@ type SessionLimiter struct {
 	Period time.Duration `env:"SESSION_LIMITER_PERIOD" env-required:"true"`
 }
 
+type ReconcilerConfig struct {
+	Interval time.Duration `env:"RECONCILER_INTERVAL" env-default:"0s"` // 0 — фоновый реконсилер выключен
+	Fix      bool          `env:"RECONCILER_FIX" env-default:"false"`   // false — только отчёт в логах
+}
+
+type SweeperConfig struct {
+	Interval time.Duration `env:"SWEEPER_INTERVAL" env-default:"1m"` // как часто удалять файлы с истёкшим сроком жизни
+}
+
+type PlanExpiryConfig struct {
+	Interval   time.Duration `env:"PLAN_EXPIRY_INTERVAL" env-default:"5m"`     // 0 — обработка окончания подписок выключена
+	WarnBefore time.Duration `env:"PLAN_EXPIRY_WARN_BEFORE" env-default:"72h"` // за сколько до окончания предупреждать
+	Grace      time.Duration `env:"PLAN_GRACE_PERIOD" env-default:"168h"`      // сколько можно быть выше лимита до read-only
+}
+
+type BillingConfig struct {
+	Provider         string        `env:"BILLING_PROVIDER" env-required:"true"`                   // платёжный провайдер, пока доступен только fake
+	AllowFake        bool          `env:"BILLING_ALLOW_FAKE" env-default:"false"`                 // разрешить fake (только для разработки)
+	WebhookSecret    string        `env:"BILLING_WEBHOOK_SECRET" env-required:"true"`             // секрет подписи вебхуков
+	WebhookTolerance time.Duration `env:"BILLING_WEBHOOK_TOLERANCE" env-default:"5m"`             // насколько старый вебхук ещё принимается
+	PublicURL        string        `env:"BILLING_PUBLIC_URL" env-default:"http://localhost:8080"` // адрес сервиса для checkout_url фейкового провайдера
+	Currency         string        `env:"BILLING_CURRENCY" env-default:"RUB"`
+}
+
+type DownloadConfig struct {
+	PublicURL  string `env:"DOWNLOAD_PUBLIC_URL" env-default:"http://localhost:8080"` // адрес сервиса в ссылках на скачивание
+	SigningKey string `env:"DOWNLOAD_SIGNING_KEY" env-required:"true"`                // ключ подписи ссылок, одинаковый на всех экземплярах
+}
+
+type NotifyConfig struct {
+	UsageThresholds     []int         `env:"USAGE_ALERT_THRESHOLDS" env-separator:"," env-default:"80,95,100"` // пороги заполнения хранилища по умолчанию, %
+	WebhookURL          string        `env:"NOTIFY_WEBHOOK_URL" env-default:""`                                // вебхук для пользователей, не указавших свой; пустой — не отправлять
+	WebhookSecret       string        `env:"NOTIFY_WEBHOOK_SECRET" env-default:""`                             // секрет подписи X-SecureComm-Signature; пустой — без подписи
+	WebhookTimeout      time.Duration `env:"NOTIFY_WEBHOOK_TIMEOUT" env-default:"10s"`
+	WebhookAllowPrivate bool          `env:"NOTIFY_WEBHOOK_ALLOW_PRIVATE" env-default:"false"` // разрешить вебхуки на внутренние адреса (локальная разработка)
+	SMTPAddr            string        `env:"SMTP_ADDR" env-default:""`                         // host:port; пустой — письма не отправляются
+	SMTPFrom            string        `env:"SMTP_FROM" env-default:"no-reply@securecomm.local"`
+	SMTPUser            string        `env:"SMTP_USER" env-default:""`
+	SMTPPassword        string        `env:"SMTP_PASSWORD" env-default:""`
+}
+
+// RevocationConfig — проверка access токенов по списку отзыва auth_service (в том же Redis)
+type RevocationConfig struct {
+	BloomCapacity   int           `env:"REVOCATION_BLOOM_CAPACITY" env-default:"100000"` // на сколько записей рассчитан фильтр Блума
+	RebuildInterval time.Duration `env:"REVOCATION_REBUILD_INTERVAL" env-default:"5m"`   // пересборка фильтра: убирает истёкшие записи
+	CacheTTL        time.Duration `env:"REVOCATION_CACHE_TTL" env-default:"30s"`         // сколько помнить ответы Redis
+}
+
 type Config struct {
 	Postges    PostgresConfig
 	JWT        JWTConfig
@@ -66,8 +123,16 @@ type Config struct {
 	Redis      RedisConfig
 	ServKeys   ServKeysConfig
 	HTTPServ   HTTPServConfig
+	Internal   InternalConfig
 	HSLimiter  HandShakeLimiter
 	SesLimiter SessionLimiter
+	Reconciler ReconcilerConfig
+	Sweeper    SweeperConfig
+	PlanExpiry PlanExpiryConfig
+	Billing    BillingConfig
+	Download   DownloadConfig
+	Notify     NotifyConfig
+	Revocation RevocationConfig
 }
 
 func MustLoad() *Config {
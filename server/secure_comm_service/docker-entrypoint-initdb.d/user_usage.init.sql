@@ -2,27 +2,257 @@
 CREATE TABLE IF NOT EXISTS plans (
     id SERIAL PRIMARY KEY,
     name TEXT NOT NULL UNIQUE,
-    storage_limit BIGINT NOT NULL,  -- 10 GiB = 10737418240
-    price_cents INT NOT NULL,       -- в копейках
-    period_days INT NOT NULL        -- длительность в днях
+    storage_limit BIGINT NOT NULL,            -- 10 GiB = 10737418240
+    price_cents INT NOT NULL,                 -- в копейках
+    period_days INT NOT NULL,                 -- длительность в днях
+    egress_limit BIGINT NOT NULL DEFAULT 0,   -- исходящий трафик в байтах за календарный месяц, 0 — без лимита
+    request_limit BIGINT NOT NULL DEFAULT 0,  -- запросов к файловому API за календарный месяц, 0 — без лимита
+    max_file_size BIGINT NOT NULL DEFAULT 0,  -- максимальный размер одного файла в байтах, 0 — без лимита
+    max_files BIGINT NOT NULL DEFAULT 0       -- максимальное число файлов, 0 — без лимита
 );
 
+-- для баз, созданных до появления лимитов трафика
+ALTER TABLE plans ADD COLUMN IF NOT EXISTS egress_limit BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE plans ADD COLUMN IF NOT EXISTS request_limit BIGINT NOT NULL DEFAULT 0;
+
+-- для баз, созданных до появления лимитов размера и числа файлов
+ALTER TABLE plans ADD COLUMN IF NOT EXISTS max_file_size BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE plans ADD COLUMN IF NOT EXISTS max_files BIGINT NOT NULL DEFAULT 0;
+
 -- тарифы
-INSERT INTO plans (name, storage_limit, price_cents, period_days)
+INSERT INTO plans (name, storage_limit, price_cents, period_days, egress_limit, request_limit,
+                   max_file_size, max_files)
 VALUES
-  ('free', 10737418240, 0, 0),           -- 10 * 1024^3
-  ('pro', 1099511627776, 25000, 30)      -- 1 * 1024^4
+  ('free', 10737418240, 0, 0, 21474836480, 100000,
+   5368709120, 10000),                                            -- 10 GiB хранения, 20 GiB трафика в месяц, файл до 5 GiB
+  ('pro', 1099511627776, 25000, 30, 2199023255552, 10000000,
+   53687091200, 0)                                                -- 1 TiB хранения, 2 TiB трафика в месяц, файл до 50 GiB
 ON CONFLICT (name) DO NOTHING;
 
+UPDATE plans SET egress_limit = 21474836480, request_limit = 100000
+WHERE name = 'free' AND egress_limit = 0 AND request_limit = 0;
+UPDATE plans SET egress_limit = 2199023255552, request_limit = 10000000
+WHERE name = 'pro' AND egress_limit = 0 AND request_limit = 0;
+
+-- раньше размер файла был ограничен константой 5 GiB для всех планов
+UPDATE plans SET max_file_size = 5368709120, max_files = 10000
+WHERE name = 'free' AND max_file_size = 0;
+UPDATE plans SET max_file_size = 53687091200
+WHERE name = 'pro' AND max_file_size = 0;
+
 CREATE TABLE IF NOT EXISTS user_plans (
     id SERIAL PRIMARY KEY,
     user_id INT NOT NULL,
     plan_id INT NOT NULL REFERENCES plans(id),
     started_at TIMESTAMP WITH TIME ZONE NOT NULL,
     expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
-    current_used BIGINT NOT NULL DEFAULT 0
+    current_used BIGINT NOT NULL DEFAULT 0,
+    status TEXT NOT NULL DEFAULT 'active',                -- active | replaced (закрыта сменой плана) | expired (обработана по окончании)
+    cancel_at_period_end BOOLEAN NOT NULL DEFAULT FALSE,  -- отменена, действует до expires_at
+    read_only BOOLEAN NOT NULL DEFAULT FALSE,             -- занято больше лимита после даунгрейда
+    auto_renew BOOLEAN NOT NULL DEFAULT FALSE,            -- продлить платный план по окончании периода
+    expiry_warned_at TIMESTAMP WITH TIME ZONE,            -- когда отправлено предупреждение об окончании
+    grace_until TIMESTAMP WITH TIME ZONE                  -- после этого момента аккаунт сверх лимита станет read-only
 );
 
+-- для баз, созданных до появления смены планов
+ALTER TABLE user_plans ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE user_plans ADD COLUMN IF NOT EXISTS cancel_at_period_end BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE user_plans ADD COLUMN IF NOT EXISTS read_only BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE user_plans ADD COLUMN IF NOT EXISTS auto_renew BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE user_plans ADD COLUMN IF NOT EXISTS expiry_warned_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE user_plans ADD COLUMN IF NOT EXISTS grace_until TIMESTAMP WITH TIME ZONE;
+
+-- история подписок: одна строка на каждый период/смену плана
+CREATE INDEX IF NOT EXISTS idx_user_plans_user_period ON user_plans (user_id, started_at, expires_at);
+
+-- журнал изменений занятого места: каждая запись current_used сопровождается строкой здесь,
+-- поэтому SUM(delta) по пользователю равна current_used, а разбивка по категориям и дням считается из журнала
+CREATE TABLE IF NOT EXISTS usage_ledger (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT NOT NULL,
+    obj_id TEXT NOT NULL DEFAULT '',
+    category TEXT NOT NULL DEFAULT '',  -- пусто — начальный остаток и корректировки реконсилера
+    delta BIGINT NOT NULL,              -- >0 занято, <0 освобождено
+    reason TEXT NOT NULL,               -- upload | delete | copy | move | expire | rollback | reconcile | opening_balance
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_usage_ledger_user_time ON usage_ledger (user_id, created_at);
+
+-- для баз, созданных до появления журнала: текущее занятое место становится начальным остатком
+INSERT INTO usage_ledger (user_id, delta, reason)
+SELECT up.user_id, up.current_used, 'opening_balance'
+FROM user_plans up
+WHERE up.status = 'active'
+  AND up.started_at <= NOW()
+  AND up.expires_at > NOW()
+  AND up.current_used > 0
+  AND up.user_id IS NOT NULL
+  AND NOT EXISTS (SELECT 1 FROM usage_ledger l WHERE l.user_id = up.user_id);
+
+-- трафик и число запросов за календарный месяц (UTC)
+CREATE TABLE IF NOT EXISTS traffic_usage (
+    user_id INT NOT NULL,
+    period_start DATE NOT NULL,              -- первое число месяца
+    egress_bytes BIGINT NOT NULL DEFAULT 0,
+    requests BIGINT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, period_start)
+);
+
+-- платёжные сессии: план активируется только после подтверждённой провайдером оплаты
+CREATE TABLE IF NOT EXISTS checkout_sessions (
+    id UUID PRIMARY KEY,
+    user_id INT NOT NULL,
+    plan_id INT NOT NULL REFERENCES plans(id),
+    provider TEXT NOT NULL,
+    provider_session_id TEXT NOT NULL,
+    amount_cents INT NOT NULL,
+    currency TEXT NOT NULL,
+    auto_renew BOOLEAN NOT NULL DEFAULT FALSE,
+    status TEXT NOT NULL DEFAULT 'pending',  -- pending | paid | failed | expired
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    UNIQUE (provider, provider_session_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_checkout_sessions_user ON checkout_sessions (user_id, created_at);
+
+-- журнал вебхуков: уникальность (provider, event_id) делает повторную доставку события безопасной
+CREATE TABLE IF NOT EXISTS billing_events (
+    id SERIAL PRIMARY KEY,
+    provider TEXT NOT NULL,
+    event_id TEXT NOT NULL,
+    type TEXT NOT NULL,
+    provider_session_id TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    received_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, event_id)
+);
+
+-- число файлов пользователя для лимита plans.max_files.
+-- Меняется вместе с usage_ledger; для файлов, загруженных до появления таблицы, значение выставит реконсилер.
+CREATE TABLE IF NOT EXISTS user_file_counts (
+    user_id INT PRIMARY KEY,
+    files BIGINT NOT NULL DEFAULT 0
+);
+
+-- настройки уведомлений о заполнении хранилища; пользователи без строки получают пороги по умолчанию из конфигурации
+CREATE TABLE IF NOT EXISTS usage_alert_settings (
+    user_id INT PRIMARY KEY,
+    thresholds INT[] NOT NULL,              -- проценты от storage_limit, например {80,95,100}
+    webhook_url TEXT NOT NULL DEFAULT '',
+    email TEXT NOT NULL DEFAULT '',
+    email_verified BOOLEAN NOT NULL DEFAULT FALSE, -- письма уходят только на адрес, подтверждённый кодом
+    email_token_hash TEXT NOT NULL DEFAULT '',      -- sha256 кода подтверждения email
+    email_token_expires_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- для баз, созданных до подтверждения адресов: сохранённые адреса считаются неподтверждёнными
+ALTER TABLE usage_alert_settings ADD COLUMN IF NOT EXISTS email_verified BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE usage_alert_settings ADD COLUMN IF NOT EXISTS email_token_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE usage_alert_settings ADD COLUMN IF NOT EXISTS email_token_expires_at TIMESTAMP WITH TIME ZONE;
+
+-- пересечённые пороги: строка появляется при пересечении и удаляется, когда занятое место опускается ниже порога,
+-- поэтому уведомление уходит ровно один раз на каждое пересечение
+CREATE TABLE IF NOT EXISTS usage_alert_state (
+    user_id INT NOT NULL,
+    threshold INT NOT NULL,
+    crossed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, threshold)
+);
+
+-- организации: общий пул хранилища для участников
+CREATE TABLE IF NOT EXISTS organizations (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL,
+    created_by INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS org_members (
+    org_id INT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id INT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('owner', 'admin', 'member')),
+    joined_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (org_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_org_members_user ON org_members (user_id);
+
+-- приглашения: участником пользователь становится, только приняв приглашение
+CREATE TABLE IF NOT EXISTS org_invites (
+    id SERIAL PRIMARY KEY,
+    org_id INT NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id INT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('admin', 'member')),
+    invited_by INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    UNIQUE (org_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_org_invites_user ON org_invites (user_id);
+
+-- подписка и current_used организации хранятся в user_plans: у строки задан ровно один владелец — user_id или org_id
+ALTER TABLE user_plans ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE user_plans ADD COLUMN IF NOT EXISTS org_id INT REFERENCES organizations(id);
+ALTER TABLE user_plans DROP CONSTRAINT IF EXISTS user_plans_owner_check;
+ALTER TABLE user_plans ADD CONSTRAINT user_plans_owner_check CHECK ((user_id IS NULL) <> (org_id IS NULL));
+CREATE INDEX IF NOT EXISTS idx_user_plans_org_period ON user_plans (org_id, started_at, expires_at) WHERE org_id IS NOT NULL;
+
+-- записи журнала в пространстве организации: org_id — чей пул, user_id — кто загрузил файл (NULL — корректировка реконсилера)
+ALTER TABLE usage_ledger ALTER COLUMN user_id DROP NOT NULL;
+ALTER TABLE usage_ledger ADD COLUMN IF NOT EXISTS org_id INT;
+CREATE INDEX IF NOT EXISTS idx_usage_ledger_org ON usage_ledger (org_id, user_id) WHERE org_id IS NOT NULL;
+
+-- число файлов организации для лимита plans.max_files
+CREATE TABLE IF NOT EXISTS org_file_counts (
+    org_id INT PRIMARY KEY,
+    files BIGINT NOT NULL DEFAULT 0
+);
+
+-- оплата плана организации: user_id — кто платит, org_id — чей план продлевается
+ALTER TABLE checkout_sessions ADD COLUMN IF NOT EXISTS org_id INT REFERENCES organizations(id);
+
+-- выведенный из продажи план: не показывается в /plans, на него нельзя перейти или оплатить,
+-- текущие подписчики остаются на нём до конца периода без автопродления
+ALTER TABLE plans ADD COLUMN IF NOT EXISTS retired_at TIMESTAMP WITH TIME ZONE;
+
+-- персональные лимиты, назначенные администратором поверх плана; NULL — действует значение плана
+CREATE TABLE IF NOT EXISTS user_limit_overrides (
+    user_id INT PRIMARY KEY,
+    storage_limit BIGINT,
+    max_file_size BIGINT,
+    max_files BIGINT,
+    egress_limit BIGINT,
+    request_limit BIGINT,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL, -- после этого момента снова действуют лимиты плана
+    reason TEXT NOT NULL DEFAULT '',
+    created_by INT NOT NULL,                      -- ID администратора
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expiry_processed_at TIMESTAMP WITH TIME ZONE  -- когда после истечения снова применены лимиты плана (ProcessExpiredOverrides)
+);
+
+ALTER TABLE user_limit_overrides ADD COLUMN IF NOT EXISTS expiry_processed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_user_limit_overrides_expiry ON user_limit_overrides (expires_at) WHERE expiry_processed_at IS NULL;
+
+-- журнал изменений, сделанных через админское API
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    admin_id INT NOT NULL,
+    action TEXT NOT NULL,         -- plan.create | plan.update | plan.retire | user.assign_plan | user.set_override | user.remove_override
+    target_type TEXT NOT NULL,    -- plan | user
+    target_id TEXT NOT NULL,      -- имя плана или ID пользователя
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_admin_audit_target ON admin_audit_log (target_type, target_id, created_at DESC);
+
 
 -- 10 * 1024 * 1024 * 1024 = 10737418240 это 10 гб
--- 1 * 1024 * 1024 * 1024 * 1024 = 1099511627776 это 1тб
\ No newline at end of file
+-- 1 * 1024 * 1024 * 1024 * 1024 = 1099511627776 это 1тб
@@ -15,21 +15,21 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/files/all": {
+        "/admin/audit": {
             "get": {
                 "security": [
                     {
-                        "bearerAuth": []
+                        "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает пре‐подписанные ссылки на скачивание всех файлов заданной категории (photo, unknown, video, text).",
+                "description": "Все изменения, сделанные через админское API, от новых к старым. Фильтры target_type (plan, user) и target_id (имя тарифа или ID пользователя) необязательны.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Files"
+                    "Admin"
                 ],
-                "summary": "Получение всех файлов категории",
+                "summary": "Журнал изменений",
                 "parameters": [
                     {
                         "type": "string",
@@ -40,24 +40,41 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Категория файлов (photo, unknown, video, text)",
-                        "name": "type",
-                        "in": "query",
-                        "required": true
+                        "description": "plan или user",
+                        "name": "target_type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Имя тарифа или ID пользователя",
+                        "name": "target_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Вернуть записи с id меньше этого",
+                        "name": "before_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы, по умолчанию 50, максимум 500",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Список ссылок на все файлы категории",
+                        "description": "Записи журнала",
                         "schema": {
                             "type": "array",
                             "items": {
-                                "$ref": "#/definitions/dto.FileResponse"
+                                "$ref": "#/definitions/domain.AdminAuditEntry"
                             }
                         }
                     },
                     "400": {
-                        "description": "Некорректная категория",
+                        "description": "Некорректные параметры",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -66,44 +83,41 @@ const docTemplate = `{
                         }
                     },
                     "403": {
-                        "description": "Доступ запрещён",
-                        "schema": {
-                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Файлы не найдены",
+                        "description": "Нет роли admin",
                         "schema": {
-                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/files/many": {
-            "delete": {
+        "/admin/plans": {
+            "get": {
                 "security": [
                     {
-                        "bearerAuth": []
+                        "BearerAuth": []
                     }
                 ],
-                "description": "Удаляет несколько объектов из MinIO, переданных в JSON-массиве, и снижает использование квоты.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает все тарифы, включая выведенные из продажи (retired_at). Только для администраторов.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Files"
+                    "Admin"
                 ],
-                "summary": "Удаление нескольких файлов",
+                "summary": "Каталог тарифов",
                 "parameters": [
                     {
                         "type": "string",
@@ -111,69 +125,55 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
-                    },
-                    {
-                        "description": "Массив идентификаторов объектов",
-                        "name": "objectIDs",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.ObjectIDs"
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Файлы успешно удалены",
+                        "description": "Тарифы",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Plan"
                             }
                         }
                     },
-                    "400": {
-                        "description": "Некорректный JSON в теле запроса",
-                        "schema": {
-                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
-                        }
-                    },
                     "403": {
-                        "description": "Доступ запрещён",
+                        "description": "Нет роли admin",
                         "schema": {
-                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
-                        }
-                    },
-                    "404": {
-                        "description": "Один из файлов не найден",
-                        "schema": {
-                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            }
-        },
-        "/files/one": {
-            "get": {
+            },
+            "post": {
                 "security": [
                     {
-                        "bearerAuth": []
+                        "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает пре‐подписанную ссылку на скачивание одного файла по ID и типу.",
+                "description": "Добавляет тариф в каталог. Лимиты в байтах, 0 — без ограничения (кроме storage_limit). Платный тариф должен иметь period_days \u003e 0.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Files"
+                    "Admin"
                 ],
-                "summary": "Получение одного файла",
+                "summary": "Создание тарифа",
                 "parameters": [
                     {
                         "type": "string",
@@ -183,67 +183,79 @@ const docTemplate = `{
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Идентификатор объекта",
-                        "name": "id",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Категория файла (photo, unknown, video, text)",
-                        "name": "type",
-                        "in": "query",
-                        "required": true
+                        "description": "Тариф",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreatePlanReq"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "Ссылка на скачивание файла",
+                    "201": {
+                        "description": "Созданный тариф",
                         "schema": {
-                            "$ref": "#/definitions/dto.FileResponse"
+                            "$ref": "#/definitions/domain.Plan"
                         }
                     },
                     "400": {
-                        "description": "Некорректный запрос",
+                        "description": "Некорректный тариф",
                         "schema": {
-                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "403": {
-                        "description": "Доступ запрещён",
+                        "description": "Нет роли admin",
                         "schema": {
-                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "404": {
-                        "description": "Файл не найден",
+                    "409": {
+                        "description": "Тариф с таким именем уже есть",
                         "schema": {
-                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/admin/plans/{name}": {
+            "patch": {
                 "security": [
                     {
-                        "bearerAuth": []
+                        "BearerAuth": []
                     }
                 ],
-                "description": "Удаляет один объект из MinIO и снижает использование квоты.",
+                "description": "Меняет переданные поля тарифа. Новые лимиты сразу действуют для всех подписчиков, новая цена и период — для следующих оплат.",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Files"
+                    "Admin"
                 ],
-                "summary": "Удаление одного файла",
+                "summary": "Изменение тарифа",
                 "parameters": [
                     {
                         "type": "string",
@@ -254,74 +266,82 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Идентификатор объекта",
-                        "name": "id",
-                        "in": "query",
+                        "description": "Имя тарифа",
+                        "name": "name",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Категория файла (photo, unknown, video, text)",
-                        "name": "type",
-                        "in": "query",
-                        "required": true
+                        "description": "Изменяемые поля",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdatePlanReq"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Файл успешно удалён",
+                        "description": "Тариф после изменения",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/domain.Plan"
                         }
                     },
                     "400": {
-                        "description": "Некорректный запрос",
+                        "description": "Некорректные значения",
                         "schema": {
-                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "403": {
-                        "description": "Доступ запрещён",
+                        "description": "Нет роли admin",
                         "schema": {
-                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
-                        "description": "Файл не найден",
+                        "description": "Тариф не найден",
                         "schema": {
-                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/files/one/encrypted": {
+        "/admin/plans/{name}/retire": {
             "post": {
                 "security": [
                     {
-                        "bearerAuth": []
+                        "BearerAuth": []
                     }
                 ],
-                "description": "Токен авторизации + зашифрованный поток в body + метаданные в заголовках",
-                "consumes": [
-                    "application/octet-stream"
-                ],
+                "description": "Тариф пропадает из /plans, на него нельзя перейти или оплатить. Текущие подписчики остаются на нём до конца периода\nбез автопродления и затем переходят на free. Тариф free вывести нельзя.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Files"
+                    "Admin"
                 ],
-                "summary": "Загрузка зашифрованного файла в MinIO(объектное хранилище) “на лету”",
+                "summary": "Вывод тарифа из продажи",
                 "parameters": [
                     {
                         "type": "string",
@@ -332,47 +352,21 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "Оригинальное имя файла (например photo.jpg)",
-                        "name": "X-Orig-Filename",
-                        "in": "header",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Оригинальный MIME-тип (например image/jpeg)",
-                        "name": "X-Orig-Mime",
-                        "in": "header",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Категория файла (photo, video, text, unknown)",
-                        "name": "X-File-Category",
-                        "in": "header",
+                        "description": "Имя тарифа",
+                        "name": "name",
+                        "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Зашифрованный бинарный поток (application/octet-stream)",
-                        "name": "file",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "array",
-                            "items": {
-                                "type": "integer"
-                            }
-                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешно: JSON с metadata + presigned URL",
+                        "description": "Выведенный тариф",
                         "schema": {
-                            "$ref": "#/definitions/dto.FileResponse"
+                            "$ref": "#/definitions/domain.Plan"
                         }
                     },
                     "400": {
-                        "description": "Некорректные заголовки или body",
+                        "description": "Тариф free",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -380,8 +374,26 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "Проблема с авторизацией",
+                    "403": {
+                        "description": "Нет роли admin",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Тариф не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Тариф уже выведен",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -401,140 +413,172 @@ const docTemplate = `{
                 }
             }
         },
-        "/handshake/finalize": {
-            "post": {
+        "/admin/users/{id}/history": {
+            "get": {
                 "security": [
                     {
-                        "bearerAuth": []
+                        "BearerAuth": []
                     }
                 ],
-                "description": "ЗАПРОС ОТ КЛИЕНТА:\nКлиент шлёт RSA-OAEP(encrypted payload), закодированный в Base64.\nи signature подписанный payload приватным ключем клиента\nоб отправляемых полях клиентом encrypted и signature3:\nРандомные 32 байта - это сессионная строка, назовем её ks, которая лежит в payload\npayload - это сумма байтов (ks || nonce3 || nonce2)\nsignature3 - это подписанный payload приватным ключем ECDSA клиента в base64\n\nОТВЕТ ОТ СЕРВЕРА:\nСервер возвращает подпись signature4 = SHA256(Ks || nonce3 || nonce2), подписанную приватным ECDSA-ключом сервера и закодированную в Base64.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Записи журнала админского API по пользователю от новых к старым: назначения тарифа и персональных лимитов.\nДля следующей страницы передайте before_id = id последней полученной записи.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "handshake"
+                    "Admin"
                 ],
-                "summary": "Завершает Handshake",
+                "summary": "История изменений пользователя",
                 "parameters": [
                     {
-                        "description": "Параметры завершения Handshake",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.HandshakeFinalizeReq"
-                        }
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Вернуть записи с id меньше этого",
+                        "name": "before_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы, по умолчанию 50, максимум 500",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешный ответ сервера",
+                        "description": "Записи журнала",
                         "schema": {
-                            "$ref": "#/definitions/dto.HandshakeFinalizeResp"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.AdminAuditEntry"
+                            }
                         }
                     },
                     "400": {
-                        "description": "Некорректный JSON или параметры",
-                        "schema": {
-                            "$ref": "#/definitions/dto.BadRequestErr"
-                        }
-                    },
-                    "401": {
-                        "description": "Unauthorized или подпись не верна",
+                        "description": "Некорректные параметры",
                         "schema": {
-                            "$ref": "#/definitions/dto.UnauthorizedErr"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "409": {
-                        "description": "Conflict — повторный запрос (replay-detected)",
+                    "403": {
+                        "description": "Нет роли admin",
                         "schema": {
-                            "$ref": "#/definitions/dto.ConflictErr"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/dto.InternalServerErr"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
             }
         },
-        "/handshake/init": {
-            "post": {
+        "/admin/users/{id}/limits": {
+            "get": {
                 "security": [
                     {
-                        "bearerAuth": []
+                        "BearerAuth": []
                     }
                 ],
-                "description": "ЗАПРОС ОТ КЛИЕНТА:\nКлиент отправляет свои публичные ключи и nonce1, всё это подписано приватным ECDSA-ключом.\nВсе бинарные данные (ключи, подписи, nonce) закодированы в Base64 (DER для ключей и подписи).\n\nrsa_pub_client - Base64(DER-закодированный RSA-публичный ключ клиента)\necdsa_pub_client - Base64(DER-закодированный ECDSA-публичный ключ клиента)\nnonce1 - Base64(8-байтовый случайный nonce)\nsignature1 - Base64(DER-закодированная подпись SHA256(clientRSA || clientECDSA || nonce1) приватным ECDSA-ключом клиента)\n\nОТВЕТ ОТ СЕРВЕРА:\nСервер отвечает своими публичными ключами и nonce2, всё это подписано приватным ECDSA-ключом сервера.\nВсе бинарные данные (ключи, подписи, nonce) закодированы в Base64 (DER для ключей и подписи).\n\nclient_id будет извлечён из JWT (Bearer-токена).\nrsa_pub_server - Base64(DER-закодированный RSA-публичный ключ сервера)\necdsa_pub_server - Base64(DER-закодированный ECDSA-публичный ключ сервера)\nnonce2 - Base64(8-байтовый случайный nonce)\nsignature2 - Base64(DER-подпись SHA256(rsaServer || ecdsaServer || nonce2 || nonce1 || clientID) приватным ECDSA-ключом сервера)",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Возвращает персональные лимиты, назначенные поверх плана, в том числе истёкшие (см. expires_at).",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "handshake"
+                    "Admin"
                 ],
-                "summary": "Инициализация Handshake",
+                "summary": "Персональные лимиты пользователя",
                 "parameters": [
                     {
-                        "description": "Параметры инициации Handshake",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/dto.HandshakeInitReq"
-                        }
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешный ответ сервера",
+                        "description": "Персональные лимиты",
                         "schema": {
-                            "$ref": "#/definitions/dto.HandshakeInitResp"
+                            "$ref": "#/definitions/domain.LimitOverride"
                         }
                     },
                     "400": {
-                        "description": "Некорректный JSON или параметры",
+                        "description": "Некорректный ID пользователя",
                         "schema": {
-                            "$ref": "#/definitions/dto.BadRequestErr"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "401": {
-                        "description": "Unauthorized или ошибка подписи",
+                    "403": {
+                        "description": "Нет роли admin",
                         "schema": {
-                            "$ref": "#/definitions/dto.UnauthorizedErr"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "409": {
-                        "description": "Conflict — повторный запрос (replay-detected)",
+                    "404": {
+                        "description": "Персональных лимитов нет",
                         "schema": {
-                            "$ref": "#/definitions/dto.ConflictErr"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/dto.InternalServerErr"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            }
-        },
-        "/session/test": {
-            "post": {
+            },
+            "put": {
                 "security": [
                     {
-                        "bearerAuth": []
+                        "BearerAuth": []
                     }
                 ],
-                "description": "Клиент шлёт серверу зашифрованный пакет, объединяющий метаданные и payload данные:\ntimestamp - время отправки клиента в формате Unix миллисекунд (8 байт)\nnonce - криптографически стойкое случайное значение (16 байт) для защиты от replay\nIV - инициализационный вектор AES-CBC (16 байт)\nciphertext - результат AES-256-CBC шифрования сессионного payload'а + PKCS#7\ntag - HMAC-SHA256 от (IV||ciphertext) для целостности данных\n\nпо итогу клиент отправляет:\nencrypted_message = timestamp(8 byte) || nonce(16 byte) || IV(16 byte) || ciphertext || tag(32 byte)\nclient_signature = Base64(DER‑закодированная подпись SHA256(timestamp || nonce || IV || ciphertext || tag) приватным ECDSA‑ключом клиента\n\nСервер выполняет следующие шаги:\nДекодирует Base64 и извлекает blob.\nИзвлекает timestamp и nonce из первых 24 байт и проверяет не использован ли nonce и если diff = (client_timestamp - server_timestamp) \u003e допустимому окну(30секунд). если это так, то запрос отклоняется с ошибкой\nПолучает K_enc и K_mac по clientID, иначе 401 Unauthorized;\nПроверяет HMAC-SHA256(iv||ciphertext), иначе 401 Unauthorized;\nРасшифровывает AES-256-CBC, снимает PKCS#7 padding, иначе 400 Bad Request;\nВозвращает JSON с plaintext - декодированный userData.",
+                "description": "Задаёт лимиты поверх плана до expires_at, заменяя предыдущие. Не переданные лимиты берутся из плана.\nПерсональные лимиты сохраняются при смене плана. Если новый лимит хранилища покрывает занятое место, read-only снимается, иначе начинается grace-период.",
                 "consumes": [
                     "application/json"
                 ],
@@ -542,72 +586,93 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "session"
+                    "Admin"
                 ],
-                "summary": "Тестовое расшифрование и проверка целостности сессионного сообщения",
+                "summary": "Назначение персональных лимитов",
                 "parameters": [
                     {
-                        "description": "Метаданные + зашифрованный payload в Base64",
-                        "name": "input",
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Лимиты, срок и причина",
+                        "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/dto.SessionMessageReq"
+                            "$ref": "#/definitions/dto.LimitOverrideReq"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Успешный ответ: plaintext",
+                        "description": "Персональные лимиты",
                         "schema": {
-                            "$ref": "#/definitions/dto.SessionMessageResp"
+                            "$ref": "#/definitions/domain.LimitOverride"
                         }
                     },
                     "400": {
-                        "description": "Неверный формат Base64, устаревший timestamp или padding",
+                        "description": "Нет ни одного лимита или срок в прошлом",
                         "schema": {
-                            "$ref": "#/definitions/dto.BadRequestErr"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "401": {
-                        "description": "Session not found или проверка HMAC не прошла",
+                    "403": {
+                        "description": "Нет роли admin",
                         "schema": {
-                            "$ref": "#/definitions/dto.UnauthorizedErr"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
-                    "409": {
-                        "description": "Повторное использование nonce (replay)",
+                    "404": {
+                        "description": "У пользователя нет активного плана",
                         "schema": {
-                            "$ref": "#/definitions/dto.ConflictErr"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера",
                         "schema": {
-                            "$ref": "#/definitions/dto.InternalServerErr"
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
                         }
                     }
                 }
-            }
-        },
-        "/user/{id}/plan/init": {
-            "post": {
+            },
+            "delete": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Создаёт для пользователя с переданным ID бесплатный план хранения объёмом до 10 GiB. Если план уже существует, операция игнорируется.",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Удаляет персональные лимиты: снова действуют лимиты плана. Если занято больше лимита плана, начинается grace-период.",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "Quota"
+                    "Admin"
                 ],
-                "summary": "Инициализация бесплатного плана(10гб) пользователя. Вызывается как внешнее апи, при регистрации(/user/signup)",
+                "summary": "Снятие персональных лимитов",
                 "parameters": [
                     {
                         "type": "string",
@@ -625,11 +690,8 @@ const docTemplate = `{
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created — план успешно инициализирован",
-                        "schema": {
-                            "type": "string"
-                        }
+                    "204": {
+                        "description": "Лимиты сняты"
                     },
                     "400": {
                         "description": "Некорректный ID пользователя",
@@ -640,8 +702,17 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "Ошибка авторизации или токен не предоставлен",
+                    "403": {
+                        "description": "Нет роли admin",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Персональных лимитов нет",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -661,14 +732,14 @@ const docTemplate = `{
                 }
             }
         },
-        "/user/{id}/usage": {
-            "get": {
+        "/admin/users/{id}/plan": {
+            "post": {
                 "security": [
                     {
                         "BearerAuth": []
                     }
                 ],
-                "description": "Возвращает, сколько гигабайт, мегабайт и килобайт хранится у пользователя, а также лимит (10 GiB для бесплатного плана) и имя плана.",
+                "description": "Переводит пользователя на тариф без оплаты и без проверки направления смены. Платный тариф действует period_days без автопродления.\nЕсли занято больше нового лимита, аккаунт становится read-only.",
                 "consumes": [
                     "application/json"
                 ],
@@ -676,9 +747,9 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Quota"
+                    "Admin"
                 ],
-                "summary": "Получение текущего использования диска пользователя",
+                "summary": "Назначение тарифа пользователю",
                 "parameters": [
                     {
                         "type": "string",
@@ -693,17 +764,26 @@ const docTemplate = `{
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Тариф и причина",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.AssignPlanReq"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Информация об использовании дискового пространства",
+                        "description": "Новая подписка пользователя",
                         "schema": {
-                            "$ref": "#/definitions/dto.UserUsage"
+                            "$ref": "#/definitions/domain.UserPlan"
                         }
                     },
                     "400": {
-                        "description": "Некорректный ID пользователя",
+                        "description": "Некорректный запрос",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -711,8 +791,8 @@ const docTemplate = `{
                             }
                         }
                     },
-                    "401": {
-                        "description": "Ошибка авторизации или токен не предоставлен",
+                    "403": {
+                        "description": "Нет роли admin",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -721,7 +801,16 @@ const docTemplate = `{
                         }
                     },
                     "404": {
-                        "description": "Пользователь не найден",
+                        "description": "Тариф или активный план пользователя не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Пользователь уже на этом тарифе",
                         "schema": {
                             "type": "object",
                             "additionalProperties": {
@@ -740,34 +829,4838 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "cloud_handler.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "details": {
-                    "description": "Дополнительные детали (при наличии)\nexample: \"field 'file' is required\""
-                },
-                "error": {
-                    "description": "Краткое описание ошибки\nexample: \"Invalid request\"",
-                    "type": "string"
-                },
-                "status": {
-                    "description": "Код HTTP-статуса\nexample: 400",
-                    "type": "integer"
-                }
-            }
         },
-        "dto.BadRequestErr": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "description": "example: invalid request data",
+        "/billing/fake/pay/{session_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Доступна только при BILLING_PROVIDER=fake и BILLING_ALLOW_FAKE=true (среда разработки). Формирует подписанный\nвебхук об оплате (или отказе при result=failed) и обрабатывает его так же, как вебхук настоящего провайдера.\nОплатить можно только свою платёжную сессию.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Billing"
+                ],
+                "summary": "Оплата через фейкового провайдера",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID сессии у провайдера из checkout_url",
+                        "name": "session_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "succeeded (по умолчанию) или failed",
+                        "name": "result",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Итоговый статус платёжной сессии",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нет user_id в токене",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия не найдена или принадлежит другому пользователю",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/billing/webhook/{provider}": {
+            "post": {
+                "description": "Проверяет подпись, записывает событие в журнал (повторная доставка игнорируется) и подключает оплаченный план.\nНе требует токена: подлинность подтверждается подписью провайдера.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Billing"
+                ],
+                "summary": "Вебхук платёжного провайдера",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя провайдера",
+                        "name": "provider",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Событие принято",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректная подпись или тело",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Неизвестный провайдер",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка, провайдер повторит доставку",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/files/all": {
+            "get": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Возвращает пре‐подписанные ссылки на скачивание всех файлов заданной категории (photo, unknown, video, text).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Files"
+                ],
+                "summary": "Получение всех файлов категории",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Категория файлов (photo, unknown, video, text)",
+                        "name": "type",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список ссылок на все файлы категории",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.FileResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректная категория",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещён",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Файлы не найдены",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/archive": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Стримит zip или tar с зашифрованными файлами (шифртекст как есть, без перешифрования) без буферизации на сервере.\nПоследним файлом архива идёт manifest.json с оригинальными именами (base64), MIME-типами, размерами и sha256 каждого файла.\nЕсли object_ids пуст, в архив попадают все файлы пользователя из category. В одном архиве не больше 1000 файлов.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/zip",
+                    "application/x-tar"
+                ],
+                "tags": [
+                    "Files"
+                ],
+                "summary": "Скачивание нескольких файлов одним архивом",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Формат архива и список файлов или категория",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ArchiveReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Архив с файлами и manifest.json",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или больше 1000 файлов",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещён или исчерпан месячный трафик",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LimitExceededErr"
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/download": {
+            "get": {
+                "description": "Ссылки из поля url ответов /files/* ведут сюда. Подпись и срок действия проверяются сервисом, токен не нужен.\nОтданные байты засчитываются в месячный трафик владельца файла; при исчерпании egress_limit плана возвращается 403.\nПоддерживаются Range-запросы, в трафик идут только фактически отправленные байты.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "Files"
+                ],
+                "summary": "Скачивание файла по ссылке",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Категория файла",
+                        "name": "bucket",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID объекта",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Срок действия ссылки, unix-время",
+                        "name": "expires",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Подпись ссылки",
+                        "name": "signature",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Шифртекст файла",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "206": {
+                        "description": "Часть шифртекста файла",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "403": {
+                        "description": "Недействительная или просроченная ссылка, исчерпан трафик",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Исчерпан месячный лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LimitExceededErr"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/many": {
+            "delete": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Удаляет несколько объектов из MinIO, переданных в JSON-массиве, и снижает использование квоты.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Files"
+                ],
+                "summary": "Удаление нескольких файлов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Массив идентификаторов объектов",
+                        "name": "objectIDs",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ObjectIDs"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Файлы успешно удалены",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный JSON в теле запроса",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещён",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Один из файлов не найден",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/one": {
+            "get": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Возвращает пре‐подписанную ссылку на скачивание одного файла по ID и типу.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Files"
+                ],
+                "summary": "Получение одного файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор объекта",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Категория файла (photo, unknown, video, text)",
+                        "name": "type",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ссылка на скачивание файла",
+                        "schema": {
+                            "$ref": "#/definitions/dto.FileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещён",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Удаляет один объект из MinIO и снижает использование квоты.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Files"
+                ],
+                "summary": "Удаление одного файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор объекта",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Категория файла (photo, unknown, video, text)",
+                        "name": "type",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Файл успешно удалён",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещён",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/one/copy": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Создаёт серверную копию файла (в той же или другой категории) под новым obj_id без повторной загрузки шифртекста.\nРазмер копии атомарно списывается с квоты до копирования; при ошибке копирования списание откатывается.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Files"
+                ],
+                "summary": "Копирование файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Исходный файл и целевая категория",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.FileTransferReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Копия файла",
+                        "schema": {
+                            "$ref": "#/definitions/dto.FileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещён, превышен лимит хранения/числа файлов или email не подтверждён",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LimitExceededErr"
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/one/encrypted": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Токен авторизации + зашифрованный поток в body + метаданные в заголовках.\nИмя файла, MIME-тип и теги передаются зашифрованным blob-ом в X-Encrypted-Meta и хранятся сервером как есть.",
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Files"
+                ],
+                "summary": "Загрузка зашифрованного файла в MinIO(объектное хранилище) “на лету”",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Зашифрованные метаданные файла в base64 (не длиннее 1536 символов)",
+                        "name": "X-Encrypted-Meta",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Категория файла (photo, video, text, unknown)",
+                        "name": "X-File-Category",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Когда удалить файл автоматически (RFC3339)",
+                        "name": "X-Expires-At",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Через сколько секунд удалить файл автоматически (если нет X-Expires-At)",
+                        "name": "X-Expires-In",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Зашифрованный бинарный поток (application/octet-stream)",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "integer"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешно: JSON с metadata + presigned URL",
+                        "schema": {
+                            "$ref": "#/definitions/dto.FileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные заголовки или body",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Проблема с авторизацией",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Превышен лимит хранения или числа файлов, аккаунт read-only либо email не подтверждён",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LimitExceededErr"
+                        }
+                    },
+                    "413": {
+                        "description": "Файл больше max_file_size плана",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LimitExceededErr"
+                        }
+                    },
+                    "429": {
+                        "description": "Исчерпан месячный лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LimitExceededErr"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/files/one/expiration": {
+            "patch": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Задаёт момент автоматического удаления файла через expires_at (RFC3339) или expires_in (секунды, не больше 365 дней).\nЕсли оба поля пустые, срок жизни снимается и файл становится бессрочным.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Files"
+                ],
+                "summary": "Изменение срока жизни файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Файл и новый срок жизни",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.FileExpirationReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Файл с обновлённым сроком жизни",
+                        "schema": {
+                            "$ref": "#/definitions/dto.FileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещён",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден или уже истёк",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/one/move": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Переносит файл в target_category с сохранением obj_id (CopyObject + удаление исходника). Квота не меняется.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Files"
+                ],
+                "summary": "Перенос файла в другую категорию",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Файл и целевая категория",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.FileTransferReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Файл в новой категории",
+                        "schema": {
+                            "$ref": "#/definitions/dto.FileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещён",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/files/one/rename": {
+            "patch": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Заменяет encrypted_meta файла (имя, MIME-тип, теги, зашифрованные клиентом) без повторной загрузки шифртекста.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Files"
+                ],
+                "summary": "Переименование файла",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Файл и новые зашифрованные метаданные",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.FileRenameReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Файл с новыми метаданными",
+                        "schema": {
+                            "$ref": "#/definitions/dto.FileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Доступ запрещён",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Файл не найден",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/handshake/finalize": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "ЗАПРОС ОТ КЛИЕНТА:\nКлиент шлёт RSA-OAEP(encrypted payload), закодированный в Base64.\nи signature подписанный payload приватным ключем клиента\nоб отправляемых полях клиентом encrypted и signature3:\nРандомные 32 байта - это сессионная строка, назовем её ks, которая лежит в payload\npayload - это сумма байтов (ks || nonce3 || nonce2)\nsignature3 - это подписанный payload приватным ключем ECDSA клиента в base64\n\nОТВЕТ ОТ СЕРВЕРА:\nСервер возвращает подпись signature4 = SHA256(Ks || nonce3 || nonce2), подписанную приватным ECDSA-ключом сервера и закодированную в Base64.\n\nKs привязывается к сессии устройства (claim sid токена) и удаляется, когда эту сессию завершают или отзывают.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "handshake"
+                ],
+                "summary": "Завершает Handshake",
+                "parameters": [
+                    {
+                        "description": "Параметры завершения Handshake",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.HandshakeFinalizeReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешный ответ сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.HandshakeFinalizeResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный JSON или параметры",
+                        "schema": {
+                            "$ref": "#/definitions/dto.BadRequestErr"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized или подпись не верна",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UnauthorizedErr"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict — повторный запрос (replay-detected)",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ConflictErr"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.InternalServerErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/handshake/init": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "ЗАПРОС ОТ КЛИЕНТА:\nКлиент отправляет свои публичные ключи и nonce1, всё это подписано приватным ECDSA-ключом.\nВсе бинарные данные (ключи, подписи, nonce) закодированы в Base64 (DER для ключей и подписи).\n\nrsa_pub_client - Base64(DER-закодированный RSA-публичный ключ клиента)\necdsa_pub_client - Base64(DER-закодированный ECDSA-публичный ключ клиента)\nnonce1 - Base64(8-байтовый случайный nonce)\nsignature1 - Base64(DER-закодированная подпись SHA256(clientRSA || clientECDSA || nonce1) приватным ECDSA-ключом клиента)\n\nОТВЕТ ОТ СЕРВЕРА:\nСервер отвечает своими публичными ключами и nonce2, всё это подписано приватным ECDSA-ключом сервера.\nВсе бинарные данные (ключи, подписи, nonce) закодированы в Base64 (DER для ключей и подписи).\n\nclient_id будет извлечён из JWT (Bearer-токена).\nrsa_pub_server - Base64(DER-закодированный RSA-публичный ключ сервера)\necdsa_pub_server - Base64(DER-закодированный ECDSA-публичный ключ сервера)\nnonce2 - Base64(8-байтовый случайный nonce)\nsignature2 - Base64(DER-подпись SHA256(rsaServer || ecdsaServer || nonce2 || nonce1 || clientID) приватным ECDSA-ключом сервера)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "handshake"
+                ],
+                "summary": "Инициализация Handshake",
+                "parameters": [
+                    {
+                        "description": "Параметры инициации Handshake",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.HandshakeInitReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешный ответ сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.HandshakeInitResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный JSON или параметры",
+                        "schema": {
+                            "$ref": "#/definitions/dto.BadRequestErr"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized или ошибка подписи",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UnauthorizedErr"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict — повторный запрос (replay-detected)",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ConflictErr"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.InternalServerErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/internal/users/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет ключи handshake (сессия и публичные ключи клиента в Redis), все объекты MinIO с префиксом пользователя во всех бакетах\nвместе с их кешем, затем подписки, журнал, трафик, счётчики, настройки уведомлений, персональные лимиты, приглашения и членство в организациях.\nОрганизации пользователя переходят к старшему admin (или участнику, вступившему раньше всех); организации без других участников удаляются вместе с файлами.\nИспользованные nonce к пользователю не привязаны и истекают сами. Файлы в пулах оставшихся организаций и оплаты сохраняются.\nИдемпотентен: после сбоя вызов повторяется и удаляет оставшееся. Принимает только сервисный токен с scope users:delete.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Internal"
+                ],
+                "summary": "Удаление данных пользователя. Внутреннее API, вызывается auth_service при удалении аккаунта",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {service token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Что удалено этим вызовом",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UserDataPurgeResult"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нет сервисного токена или он недействителен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "У токена нет scope users:delete",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера: в error причина, в остальных полях — что удалено до неё; вызов нужно повторить",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UserDataPurgeResult"
+                        }
+                    }
+                }
+            }
+        },
+        "/internal/users/{id}/plan/init": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создаёт для пользователя с переданным ID бесплатный план хранения объёмом до 10 GiB. Если план уже существует, операция игнорируется.\nПринимает только сервисный токен с aud = SERVICE_TOKEN_AUDIENCE и scope plans:init; пользовательские токены отклоняются.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Internal"
+                ],
+                "summary": "Инициализация бесплатного плана(10гб) пользователя. Внутреннее API, вызывается auth_service при регистрации(/user/signup)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {service token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created — план успешно инициализирован",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нет сервисного токена или он недействителен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "У токена нет scope plans:init",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/internal/users/{id}/sessions": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет сессионные ключи пользователя, клиенту придётся заново пройти handshake.\nПринимает только сервисный токен с aud = SERVICE_TOKEN_AUDIENCE и scope sessions:revoke.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Internal"
+                ],
+                "summary": "Сброс сессий пользователя. Внутреннее API, вызывается auth_service при смене или сбросе пароля",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {service token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "Сессии удалены"
+                    },
+                    "400": {
+                        "description": "Некорректный ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Нет сервисного токена или он недействителен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "У токена нет scope sessions:revoke",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает организации, в которых состоит пользователь, вместе с его ролью.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Мои организации",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Организации",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Org"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Проблема с авторизацией",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создаёт организацию с общим пулом хранилища на free-плане. Создатель становится её владельцем (owner).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Создание организации",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Название организации",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CreateOrgReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Созданная организация",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Org"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректное название",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Проблема с авторизацией",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Email не подтверждён",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs/invites": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Действующие приглашения в организации, адресованные пользователю.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Мои приглашения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Приглашения",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.OrgInvite"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Проблема с авторизацией",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs/invites/{invite_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Отклонение приглашения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID приглашения",
+                        "name": "invite_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Приглашение отклонено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Проблема с авторизацией",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Приглашение не найдено",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs/invites/{invite_id}/accept": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Пользователь становится участником организации с ролью из приглашения. Приглашение одноразовое.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Принятие приглашения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID приглашения",
+                        "name": "invite_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Организация",
+                        "schema": {
+                            "$ref": "#/definitions/domain.Org"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Проблема с авторизацией",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Приглашение не найдено или истекло",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Пользователь уже состоит в организации",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs/{org_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает организацию, роль пользователя в ней и список участников. Доступно только участникам.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Организация",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID организации",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Организация",
+                        "schema": {
+                            "$ref": "#/definitions/dto.OrgResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID организации",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Организация не найдена или пользователь в ней не состоит",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs/{org_id}/files/all": {
+            "get": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Возвращает пре‐подписанные ссылки на все файлы пула организации в категории. Доступно любому участнику.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Все файлы организации в категории",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID организации",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Категория файлов (photo, unknown, video, text)",
+                        "name": "type",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список файлов",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/dto.FileResponse"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректная категория",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Организация не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs/{org_id}/files/one": {
+            "get": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Возвращает пре‐подписанную ссылку на файл пула организации. Доступно любому участнику.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Файл организации",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID организации",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор объекта",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Категория файла (photo, unknown, video, text)",
+                        "name": "type",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ссылка на скачивание файла",
+                        "schema": {
+                            "$ref": "#/definitions/dto.FileResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Файл не принадлежит организации",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Организация или файл не найдены",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Удаляет файл из пула организации и освобождает место в пуле. Свой файл удаляет любой участник, чужой — owner и admin.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Удаление файла организации",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID организации",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Идентификатор объекта",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Категория файла (photo, unknown, video, text)",
+                        "name": "type",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Файл успешно удалён",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Чужой файл или файл другой организации",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Организация или файл не найдены",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/cloud_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs/{org_id}/files/one/encrypted": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Файл занимает место в пуле организации и засчитывается загрузившему участнику в разбивке по участникам.\nДоступно любому участнику. Ссылка на скачивание расходует месячный трафик загрузившего участника.",
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Загрузка зашифрованного файла в пространство организации",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID организации",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Зашифрованные метаданные файла в base64 (не длиннее 1536 символов)",
+                        "name": "X-Encrypted-Meta",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Категория файла (photo, video, text, unknown)",
+                        "name": "X-File-Category",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "Зашифрованный бинарный поток (application/octet-stream)",
+                        "name": "file",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "integer"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешно: JSON с metadata + presigned URL",
+                        "schema": {
+                            "$ref": "#/definitions/dto.FileResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные заголовки или body",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Превышен лимит хранения или числа файлов организации, пул read-only либо email не подтверждён",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LimitExceededErr"
+                        }
+                    },
+                    "404": {
+                        "description": "Организация не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "413": {
+                        "description": "Файл больше max_file_size плана организации",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LimitExceededErr"
+                        }
+                    },
+                    "429": {
+                        "description": "Исчерпан месячный лимит запросов",
+                        "schema": {
+                            "$ref": "#/definitions/dto.LimitExceededErr"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs/{org_id}/invites": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Действующие (не принятые и не истёкшие) приглашения. Доступно owner и admin.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Приглашения организации",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID организации",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Приглашения",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.OrgInvite"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID организации",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Организация не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Приглашает пользователя в организацию с ролью admin или member. Доступно owner и admin.\nУчастником пользователь становится, только приняв приглашение через POST /orgs/invites/{invite_id}/accept.\nПриглашение действует 7 дней, повторное приглашение заменяет роль и продлевает срок.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Приглашение участника",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID организации",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Пользователь и роль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.InviteOrgMemberReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Приглашение",
+                        "schema": {
+                            "$ref": "#/definitions/domain.OrgInvite"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или роль",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав или email не подтверждён",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Организация не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Пользователь уже состоит в организации",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs/{org_id}/invites/{invite_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет ещё не принятое приглашение. Доступно owner и admin.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Отзыв приглашения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID организации",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID приглашения",
+                        "name": "invite_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Приглашение отозвано",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Организация или приглашение не найдены",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs/{org_id}/members/{user_id}": {
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Участник может выйти сам. owner исключает admin и member, admin — только member. Владельца исключить нельзя.\nФайлы участника остаются в пуле организации.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Исключение участника",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID организации",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID участника",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Участник исключён",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Организация или участник не найдены",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Владельца исключить нельзя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Переводит участника между ролями admin и member. Доступно только owner, роль owner не меняется.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Смена роли участника",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID организации",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID участника",
+                        "name": "user_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новая роль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UpdateOrgMemberReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Роль изменена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или роль",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Организация или участник не найдены",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Роль владельца не меняется",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs/{org_id}/plan": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает действующий план пула организации. Доступно всем участникам.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Подписка организации",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID организации",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Текущая подписка",
+                        "schema": {
+                            "$ref": "#/definitions/domain.UserPlan"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID организации",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Организация не найдена или нет активного плана",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs/{org_id}/plan/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Платная подписка организации действует до конца оплаченного периода, после чего пул переходит на free. Доступно owner и admin.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Отмена подписки организации",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID организации",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Подписка с cancel_at_period_end=true",
+                        "schema": {
+                            "$ref": "#/definitions/domain.UserPlan"
+                        }
+                    },
+                    "400": {
+                        "description": "Бесплатный план нельзя отменить",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Организация не найдена или нет активного плана",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Подписка уже отменена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs/{org_id}/plan/checkout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "То же, что /user/{id}/plan/checkout, но оплаченный план подключается пулу организации. Доступно owner и admin.\nСтатус сессии проверяется через /user/{id}/plan/checkout/{session_id} пользователя, который начал оплату.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Billing"
+                ],
+                "summary": "Оплата тарифа организации",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID организации",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Имя тарифа",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CheckoutReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Платёжная сессия",
+                        "schema": {
+                            "$ref": "#/definitions/domain.CheckoutSession"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или бесплатный план",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав в организации",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Организация или план не найдены",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Занято больше лимита выбранного плана",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs/{org_id}/plan/downgrade": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "То же, что /user/{id}/plan/downgrade, но для пула организации. Доступно owner и admin.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Даунгрейд тарифа организации",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID организации",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Имя тарифа",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ChangePlanReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Новая подписка",
+                        "schema": {
+                            "$ref": "#/definitions/domain.UserPlan"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или план с большим лимитом",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Организация или план не найдены",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Занято больше нового лимита",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs/{org_id}/plan/subscribe": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "То же, что /user/{id}/plan/subscribe, но для пула организации. Доступно owner и admin.\nПлатный план подключается только после оплаты через /orgs/{org_id}/plan/checkout.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Подписка или апгрейд тарифа организации",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID организации",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Имя тарифа",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ChangePlanReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Новая подписка",
+                        "schema": {
+                            "$ref": "#/definitions/domain.UserPlan"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или план с меньшим лимитом",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Платный план требует оплаты",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Недостаточно прав",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Организация или план не найдены",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Организация уже на этом плане",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/orgs/{org_id}/usage": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает current_used, лимит и число файлов пула организации. owner и admin дополнительно видят вклад каждого участника\n(по журналу изменений; user_id=0 — корректировки реконсилера).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Orgs"
+                ],
+                "summary": "Использование пула организации",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID организации",
+                        "name": "org_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Использование пула",
+                        "schema": {
+                            "$ref": "#/definitions/domain.OrgUsage"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID организации",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Организация не найдена или нет активного плана",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/plans": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает тарифы, доступные для подписки, с лимитом хранилища (байты), ценой (копейки) и длительностью периода (0 — бессрочно).\nВыведенные из продажи тарифы не возвращаются.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Quota"
+                ],
+                "summary": "Список тарифов",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Список тарифов",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.Plan"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/session/test": {
+            "post": {
+                "security": [
+                    {
+                        "bearerAuth": []
+                    }
+                ],
+                "description": "Клиент шлёт серверу зашифрованный пакет, объединяющий метаданные и payload данные:\ntimestamp - время отправки клиента в формате Unix миллисекунд (8 байт)\nnonce - криптографически стойкое случайное значение (16 байт) для защиты от replay\nIV - инициализационный вектор AES-CBC (16 байт)\nciphertext - результат AES-256-CBC шифрования сессионного payload'а + PKCS#7\ntag - HMAC-SHA256 от (IV||ciphertext) для целостности данных\n\nпо итогу клиент отправляет:\nencrypted_message = timestamp(8 byte) || nonce(16 byte) || IV(16 byte) || ciphertext || tag(32 byte)\nclient_signature = Base64(DER‑закодированная подпись SHA256(timestamp || nonce || IV || ciphertext || tag) приватным ECDSA‑ключом клиента\n\nСервер выполняет следующие шаги:\nДекодирует Base64 и извлекает blob.\nИзвлекает timestamp и nonce из первых 24 байт и проверяет не использован ли nonce и если diff = (client_timestamp - server_timestamp) \u003e допустимому окну(30секунд). если это так, то запрос отклоняется с ошибкой\nПолучает K_enc и K_mac по clientID, иначе 401 Unauthorized;\nПроверяет HMAC-SHA256(iv||ciphertext), иначе 401 Unauthorized;\nРасшифровывает AES-256-CBC, снимает PKCS#7 padding, иначе 400 Bad Request;\nВозвращает JSON с plaintext - декодированный userData.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "session"
+                ],
+                "summary": "Тестовое расшифрование и проверка целостности сессионного сообщения",
+                "parameters": [
+                    {
+                        "description": "Метаданные + зашифрованный payload в Base64",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.SessionMessageReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Успешный ответ: plaintext",
+                        "schema": {
+                            "$ref": "#/definitions/dto.SessionMessageResp"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный формат Base64, устаревший timestamp или padding",
+                        "schema": {
+                            "$ref": "#/definitions/dto.BadRequestErr"
+                        }
+                    },
+                    "401": {
+                        "description": "Session not found или проверка HMAC не прошла",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UnauthorizedErr"
+                        }
+                    },
+                    "409": {
+                        "description": "Повторное использование nonce (replay)",
+                        "schema": {
+                            "$ref": "#/definitions/dto.ConflictErr"
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "$ref": "#/definitions/dto.InternalServerErr"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}/plan": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает действующий план, даты периода, флаги отмены и read-only, а также план, который начнёт действовать после отмены.\nlimit_override — персональные лимиты, назначенные администратором; они действуют вместо лимитов плана до expires_at.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Quota"
+                ],
+                "summary": "Текущая подписка",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Текущая подписка",
+                        "schema": {
+                            "$ref": "#/definitions/domain.UserPlan"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Чужой ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Нет активного плана",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}/plan/cancel": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Платная подписка продолжает действовать до конца оплаченного периода, после чего пользователь переходит на free.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Quota"
+                ],
+                "summary": "Отмена подписки",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Подписка с cancel_at_period_end=true",
+                        "schema": {
+                            "$ref": "#/definitions/domain.UserPlan"
+                        }
+                    },
+                    "400": {
+                        "description": "Бесплатный план нельзя отменить",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Чужой ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Нет активного плана",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Подписка уже отменена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}/plan/checkout": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Создаёт платёжную сессию у провайдера и возвращает checkout_url, на который нужно отправить пользователя.\nПлан подключается (или текущий продлевается на период) только после подписанного вебхука об успешной оплате.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Billing"
+                ],
+                "summary": "Оплата тарифа",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Имя тарифа",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.CheckoutReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Платёжная сессия",
+                        "schema": {
+                            "$ref": "#/definitions/domain.CheckoutSession"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или бесплатный план",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Чужой ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "План не найден или нет активного плана",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Занято больше лимита выбранного плана",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}/plan/checkout/{session_id}": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает платёжную сессию пользователя: pending, paid, failed или expired.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Billing"
+                ],
+                "summary": "Статус оплаты",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID платёжной сессии",
+                        "name": "session_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Платёжная сессия",
+                        "schema": {
+                            "$ref": "#/definitions/domain.CheckoutSession"
+                        }
+                    },
+                    "403": {
+                        "description": "Чужой ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Сессия не найдена",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}/plan/downgrade": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Сразу переводит пользователя на план с меньшим лимитом. Если занято больше нового лимита, возвращается 409,\nлибо (read_only_if_over_limit=true) аккаунт становится read-only: загрузка запрещена, пока лишние файлы не будут удалены.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Quota"
+                ],
+                "summary": "Даунгрейд тарифа",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Имя тарифа",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ChangePlanReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Новая подписка",
+                        "schema": {
+                            "$ref": "#/definitions/domain.UserPlan"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или план с большим лимитом",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Чужой ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "План не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Занято больше нового лимита",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}/plan/subscribe": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Сразу переводит пользователя на план с не меньшим лимитом. Текущий период закрывается, новый начинается сейчас, занятое место переносится.\nПовторная подписка на текущий план после отмены снимает отмену.\nПлатный план подключается только после оплаты: в этом случае возвращается 402, оплату нужно начать через /user/{id}/plan/checkout.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Quota"
+                ],
+                "summary": "Подписка или апгрейд тарифа",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Имя тарифа",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ChangePlanReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Новая подписка",
+                        "schema": {
+                            "$ref": "#/definitions/domain.UserPlan"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный запрос или план с меньшим лимитом",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "402": {
+                        "description": "Платный план требует оплаты",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Чужой ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "План не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "409": {
+                        "description": "Пользователь уже на этом плане",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}/usage": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Возвращает, сколько гигабайт, мегабайт и килобайт хранится у пользователя, а также лимит (10 GiB для бесплатного плана) и имя плана.\nДополнительно отдаются точные значения в байтах, сумма журнала изменений (ledger_used_bytes) и разбивка по категориям из журнала,\nа также исходящий трафик и число запросов за текущий месяц с лимитами плана (traffic).",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Quota"
+                ],
+                "summary": "Получение текущего использования диска пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Информация об использовании дискового пространства",
+                        "schema": {
+                            "$ref": "#/definitions/dto.UserUsage"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Ошибка авторизации или токен не предоставлен",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Чужой ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "Пользователь не найден",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}/usage/alerts": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Пороги в процентах от лимита хранилища, адреса вебхука и почты и уже пересечённые пороги.\nПока пользователь не менял настройки, действуют пороги по умолчанию (default = true).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Quota"
+                ],
+                "summary": "Настройки уведомлений о заполнении",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Настройки уведомлений",
+                        "schema": {
+                            "$ref": "#/definitions/domain.UsageAlertSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Чужой ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Уведомление отправляется один раз при пересечении порога вверх (проверяется при добавлении файлов).\nКогда занятое место опускается ниже порога, он снова срабатывает при следующем пересечении.\nВебхук получает JSON события с подписью в заголовке X-SecureComm-Signature.\nПисьма уходят только на подтверждённый адрес: на новый адрес отправляется код для /user/{id}/usage/alerts/email/confirm.\nПовторное сохранение неподтверждённого адреса отправляет новый код.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Quota"
+                ],
+                "summary": "Изменение уведомлений о заполнении",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Пороги и адреса",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.UsageAlertSettingsReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Сохранённые настройки",
+                        "schema": {
+                            "$ref": "#/definitions/domain.UsageAlertSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные пороги или адреса",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Чужой ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Удаляет пользовательские пороги и адреса, снова действуют пороги по умолчанию.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Quota"
+                ],
+                "summary": "Сброс уведомлений о заполнении",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Настройки по умолчанию",
+                        "schema": {
+                            "$ref": "#/definitions/domain.UsageAlertSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Чужой ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}/usage/alerts/email/confirm": {
+            "post": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Код приходит письмом после сохранения адреса в PUT /user/{id}/usage/alerts и действует 24 часа.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Quota"
+                ],
+                "summary": "Подтверждение адреса для уведомлений",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Код из письма",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/dto.ConfirmAlertEmailReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Настройки с подтверждённым адресом",
+                        "schema": {
+                            "$ref": "#/definitions/domain.UsageAlertSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Неверный или просроченный код",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Чужой ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}/usage/history": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Ряд по дням (UTC) из журнала изменений: сколько добавлено, сколько освобождено и сколько занято на конец дня.\nПо умолчанию — последние 30 дней, максимум 366. Можно ограничить одной категорией.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Quota"
+                ],
+                "summary": "История использования диска",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Первый день, YYYY-MM-DD",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Последний день включительно, YYYY-MM-DD",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Категория: photo, video, text, unknown",
+                        "name": "category",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Использование по дням",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.UsagePoint"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректный диапазон или категория",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Чужой ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}/usage/ledger": {
+            "get": {
+                "security": [
+                    {
+                        "BearerAuth": []
+                    }
+                ],
+                "description": "Записи журнала от новых к старым: файл, категория, изменение в байтах и причина (upload, delete, copy, move, expire, rollback, reconcile, opening_balance).\nДля следующей страницы передайте before_id = id последней полученной записи.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Quota"
+                ],
+                "summary": "Журнал использования диска",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer {token}",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Вернуть записи с id меньше этого",
+                        "name": "before_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы, по умолчанию 50, максимум 500",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Записи журнала",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/domain.UsageLedgerEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "403": {
+                        "description": "Чужой ID пользователя",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "cloud_handler.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "details": {
+                    "description": "Дополнительные детали (при наличии)\nexample: \"field 'file' is required\""
+                },
+                "error": {
+                    "description": "Краткое описание ошибки\nexample: \"Invalid request\"",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Код HTTP-статуса\nexample: 400",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.AdminAuditEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "description": "см. Audit*",
+                    "type": "string"
+                },
+                "admin_id": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "details": {
+                    "description": "Details — состояние до и после изменения",
+                    "type": "object"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "target_id": {
+                    "description": "имя плана или ID пользователя",
+                    "type": "string"
+                },
+                "target_type": {
+                    "description": "plan | user",
+                    "type": "string"
+                }
+            }
+        },
+        "domain.CategoryUsage": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "category": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.CheckoutSession": {
+            "type": "object",
+            "properties": {
+                "amount_cents": {
+                    "type": "integer"
+                },
+                "auto_renew": {
+                    "type": "boolean"
+                },
+                "checkout_url": {
+                    "description": "куда отправить пользователя для оплаты",
+                    "type": "string"
+                },
+                "completed_at": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "plan_name": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "pending | paid | failed | expired",
+                    "type": "string"
+                }
+            }
+        },
+        "domain.LimitOverride": {
+            "type": "object",
+            "properties": {
+                "created_by": {
+                    "description": "ID администратора",
+                    "type": "integer"
+                },
+                "egress_limit": {
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "max_file_size": {
+                    "type": "integer"
+                },
+                "max_files": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "request_limit": {
+                    "type": "integer"
+                },
+                "storage_limit": {
+                    "type": "integer"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Org": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "Role — роль пользователя, запросившего организацию",
+                    "type": "string"
+                }
+            }
+        },
+        "domain.OrgInvite": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "invited_by": {
+                    "type": "integer"
+                },
+                "org_id": {
+                    "type": "integer"
+                },
+                "org_name": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.OrgMember": {
+            "type": "object",
+            "properties": {
+                "joined_at": {
+                    "type": "string"
+                },
+                "role": {
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.OrgMemberUsage": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "type": "integer"
+                },
+                "files": {
+                    "type": "integer"
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.OrgUsage": {
+            "type": "object",
+            "properties": {
+                "current_used": {
+                    "type": "integer"
+                },
+                "files": {
+                    "type": "integer"
+                },
+                "members": {
+                    "description": "Members — кто сколько занял в пуле; только для owner и admin",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.OrgMemberUsage"
+                    }
+                },
+                "plan_name": {
+                    "type": "string"
+                },
+                "storage_limit": {
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.Plan": {
+            "type": "object",
+            "properties": {
+                "egress_limit": {
+                    "description": "исходящий трафик в байтах за месяц",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "max_file_size": {
+                    "description": "максимальный размер одного файла в байтах",
+                    "type": "integer"
+                },
+                "max_files": {
+                    "description": "максимальное число файлов",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "period_days": {
+                    "description": "0 — бессрочный",
+                    "type": "integer"
+                },
+                "price_cents": {
+                    "description": "цена за период в копейках",
+                    "type": "integer"
+                },
+                "request_limit": {
+                    "description": "запросов к файловому API за месяц",
+                    "type": "integer"
+                },
+                "retired_at": {
+                    "description": "RetiredAt — когда план выведен из продажи; на него нельзя перейти, текущие подписки не продлеваются",
+                    "type": "string"
+                },
+                "storage_limit": {
+                    "description": "лимит байт",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.TrafficUsage": {
+            "type": "object",
+            "properties": {
+                "egress_limit": {
+                    "description": "0 — без лимита",
+                    "type": "integer"
+                },
+                "egress_used": {
+                    "description": "байт отдано",
+                    "type": "integer"
+                },
+                "period_start": {
+                    "type": "string"
+                },
+                "request_limit": {
+                    "description": "0 — без лимита",
+                    "type": "integer"
+                },
+                "requests": {
+                    "description": "запросов к файловому API",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.UsageAlertSettings": {
+            "type": "object",
+            "properties": {
+                "crossed": {
+                    "description": "пороги, о пересечении которых уже отправлено уведомление",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "default": {
+                    "description": "пользователь не менял настройки, действуют пороги по умолчанию",
+                    "type": "boolean"
+                },
+                "email": {
+                    "description": "пустой — письма не отправляются",
+                    "type": "string"
+                },
+                "email_verified": {
+                    "description": "адрес подтверждён кодом из письма, до этого письма не отправляются",
+                    "type": "boolean"
+                },
+                "thresholds": {
+                    "description": "проценты от storage_limit по возрастанию",
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "webhook_url": {
+                    "description": "пустой — вебхук по умолчанию из конфигурации сервиса, если он задан",
+                    "type": "string"
+                }
+            }
+        },
+        "domain.UsageLedgerEntry": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delta": {
+                    "description": "\u003e0 — место занято, \u003c0 — освобождено",
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "obj_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                }
+            }
+        },
+        "domain.UsagePoint": {
+            "type": "object",
+            "properties": {
+                "added": {
+                    "type": "integer"
+                },
+                "day": {
+                    "type": "string"
+                },
+                "removed": {
+                    "type": "integer"
+                },
+                "total": {
+                    "description": "занято на конец дня",
+                    "type": "integer"
+                }
+            }
+        },
+        "domain.UserPlan": {
+            "type": "object",
+            "properties": {
+                "auto_renew": {
+                    "type": "boolean"
+                },
+                "cancel_at_period_end": {
+                    "type": "boolean"
+                },
+                "current_used": {
+                    "type": "integer"
+                },
+                "egress_limit": {
+                    "description": "исходящий трафик в байтах за месяц",
+                    "type": "integer"
+                },
+                "expires_at": {
+                    "description": "nil — бессрочно",
+                    "type": "string"
+                },
+                "grace_until": {
+                    "description": "GraceUntil — до какого момента можно удалить лишние файлы, прежде чем аккаунт станет read-only",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "limit_override": {
+                    "description": "LimitOverride — действующие персональные лимиты, назначенные администратором поверх плана",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.LimitOverride"
+                        }
+                    ]
+                },
+                "max_file_size": {
+                    "description": "максимальный размер одного файла в байтах",
+                    "type": "integer"
+                },
+                "max_files": {
+                    "description": "максимальное число файлов",
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "next_plan": {
+                    "description": "NextPlan — план, на который пользователь перейдёт после окончания текущего периода (после отмены)",
+                    "type": "string"
+                },
+                "period_days": {
+                    "description": "0 — бессрочный",
+                    "type": "integer"
+                },
+                "price_cents": {
+                    "description": "цена за период в копейках",
+                    "type": "integer"
+                },
+                "read_only": {
+                    "description": "занято больше лимита, загрузка запрещена",
+                    "type": "boolean"
+                },
+                "request_limit": {
+                    "description": "запросов к файловому API за месяц",
+                    "type": "integer"
+                },
+                "retired_at": {
+                    "description": "RetiredAt — когда план выведен из продажи; на него нельзя перейти, текущие подписки не продлеваются",
+                    "type": "string"
+                },
+                "started_at": {
+                    "type": "string"
+                },
+                "storage_limit": {
+                    "description": "лимит байт",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.ArchiveReq": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "type": "string"
+                },
+                "format": {
+                    "description": "zip или tar, по умолчанию zip",
+                    "type": "string"
+                },
+                "object_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "dto.AssignPlanReq": {
+            "type": "object",
+            "required": [
+                "plan_name",
+                "reason"
+            ],
+            "properties": {
+                "plan_name": {
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "причина попадает в журнал изменений",
+                    "type": "string",
+                    "maxLength": 500
+                }
+            }
+        },
+        "dto.BadRequestErr": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "description": "example: invalid request data",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.ChangePlanReq": {
+            "type": "object",
+            "required": [
+                "plan_name"
+            ],
+            "properties": {
+                "auto_renew": {
+                    "description": "продлевать платный план автоматически по окончании периода",
+                    "type": "boolean"
+                },
+                "plan_name": {
+                    "type": "string"
+                },
+                "read_only_if_over_limit": {
+                    "description": "только для downgrade: если занято больше нового лимита, перевести аккаунт в read-only вместо отказа",
+                    "type": "boolean"
+                }
+            }
+        },
+        "dto.CheckoutReq": {
+            "type": "object",
+            "required": [
+                "plan_name"
+            ],
+            "properties": {
+                "auto_renew": {
+                    "description": "продлевать платный план автоматически по окончании периода",
+                    "type": "boolean"
+                },
+                "plan_name": {
                     "type": "string"
                 }
             }
         },
+        "dto.ConfirmAlertEmailReq": {
+            "type": "object",
+            "required": [
+                "code"
+            ],
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "maxLength": 64
+                }
+            }
+        },
         "dto.ConflictErr": {
             "type": "object",
             "properties": {
@@ -777,12 +5670,119 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.CreateOrgReq": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "maxLength": 128
+                }
+            }
+        },
+        "dto.CreatePlanReq": {
+            "type": "object",
+            "required": [
+                "name",
+                "storage_limit"
+            ],
+            "properties": {
+                "egress_limit": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "max_file_size": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "max_files": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "name": {
+                    "type": "string",
+                    "maxLength": 64
+                },
+                "period_days": {
+                    "description": "0 — бессрочный, платному плану обязателен",
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "price_cents": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "request_limit": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "storage_limit": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.FileExpirationReq": {
+            "type": "object",
+            "required": [
+                "file_category",
+                "obj_id"
+            ],
+            "properties": {
+                "expires_at": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "type": "integer"
+                },
+                "file_category": {
+                    "type": "string"
+                },
+                "obj_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "dto.FileRenameReq": {
+            "type": "object",
+            "required": [
+                "encrypted_meta",
+                "file_category",
+                "obj_id"
+            ],
+            "properties": {
+                "encrypted_meta": {
+                    "type": "string"
+                },
+                "file_category": {
+                    "type": "string"
+                },
+                "obj_id": {
+                    "type": "string"
+                }
+            }
+        },
         "dto.FileResponse": {
             "type": "object",
             "properties": {
+                "category": {
+                    "type": "string"
+                },
                 "created_at": {
                     "type": "string"
                 },
+                "encrypted_meta": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "ExpiresAt — RFC3339, когда файл будет удалён автоматически; пусто — файл бессрочный",
+                    "type": "string"
+                },
+                "expires_in": {
+                    "description": "ExpiresIn — сколько секунд осталось до удаления",
+                    "type": "integer"
+                },
                 "mime_type": {
                     "type": "string"
                 },
@@ -792,11 +5792,33 @@ const docTemplate = `{
                 "obj_id": {
                     "type": "string"
                 },
+                "size": {
+                    "type": "integer"
+                },
                 "url": {
                     "type": "string"
                 }
             }
         },
+        "dto.FileTransferReq": {
+            "type": "object",
+            "required": [
+                "file_category",
+                "obj_id",
+                "target_category"
+            ],
+            "properties": {
+                "file_category": {
+                    "type": "string"
+                },
+                "obj_id": {
+                    "type": "string"
+                },
+                "target_category": {
+                    "type": "string"
+                }
+            }
+        },
         "dto.HandshakeFinalizeReq": {
             "description": "Клиент шлёт RSA-OAEP(encrypted payload), закодированный в Base64. Подробнее про поле encrypted... Рандомные 32 байта - это сессионная строка, назовем её ks, которая лежит в payload payload - это сумма байтов (ks || nonce3 || nonce2) signature3 - это подписанный payload приватным ключем клиента В конце encrypted это зашифрованные байты (payload || signature3(в DER формате)) encrypted - зашифрован RSA-OAEP публичным ключем сервера, отдается в формате Base64",
             "type": "object",
@@ -867,6 +5889,83 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.InviteOrgMemberReq": {
+            "type": "object",
+            "required": [
+                "role",
+                "user_id"
+            ],
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "admin",
+                        "member"
+                    ]
+                },
+                "user_id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.LimitExceededErr": {
+            "type": "object",
+            "properties": {
+                "actual": {
+                    "description": "значение, которое получилось бы после операции",
+                    "type": "integer"
+                },
+                "error": {
+                    "description": "example: quota exceeded",
+                    "type": "string"
+                },
+                "limit": {
+                    "description": "название лимита плана: storage_limit, max_file_size, max_files, egress_limit, request_limit\nexample: storage_limit",
+                    "type": "string"
+                },
+                "max": {
+                    "description": "значение лимита в плане",
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.LimitOverrideReq": {
+            "type": "object",
+            "required": [
+                "expires_at",
+                "reason"
+            ],
+            "properties": {
+                "egress_limit": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "expires_at": {
+                    "description": "RFC 3339, должен быть в будущем",
+                    "type": "string"
+                },
+                "max_file_size": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "max_files": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "reason": {
+                    "description": "причина попадает в журнал изменений",
+                    "type": "string",
+                    "maxLength": 500
+                },
+                "request_limit": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "storage_limit": {
+                    "type": "integer"
+                }
+            }
+        },
         "dto.ObjectIDs": {
             "type": "object",
             "properties": {
@@ -878,6 +5977,33 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.OrgResp": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "members": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.OrgMember"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                },
+                "role": {
+                    "description": "Role — роль пользователя, запросившего организацию",
+                    "type": "string"
+                }
+            }
+        },
         "dto.SessionMessageReq": {
             "type": "object",
             "properties": {
@@ -906,9 +6032,114 @@ const docTemplate = `{
                 }
             }
         },
+        "dto.UpdateOrgMemberReq": {
+            "type": "object",
+            "required": [
+                "role"
+            ],
+            "properties": {
+                "role": {
+                    "type": "string",
+                    "enum": [
+                        "admin",
+                        "member"
+                    ]
+                }
+            }
+        },
+        "dto.UpdatePlanReq": {
+            "type": "object",
+            "properties": {
+                "egress_limit": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "max_file_size": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "max_files": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "period_days": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "price_cents": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "request_limit": {
+                    "type": "integer",
+                    "minimum": 0
+                },
+                "storage_limit": {
+                    "type": "integer"
+                }
+            }
+        },
+        "dto.UsageAlertSettingsReq": {
+            "type": "object",
+            "required": [
+                "thresholds"
+            ],
+            "properties": {
+                "email": {
+                    "description": "адрес для писем, пустой — письма не отправляются. На новый адрес приходит код подтверждения",
+                    "type": "string"
+                },
+                "thresholds": {
+                    "description": "проценты от лимита хранилища, от 1 до 100; пустой список выключает уведомления",
+                    "type": "array",
+                    "maxItems": 10,
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "webhook_url": {
+                    "description": "адрес вебхука (http или https), пустой — вебхук по умолчанию из конфигурации сервиса",
+                    "type": "string"
+                }
+            }
+        },
+        "dto.UserDataPurgeResult": {
+            "type": "object",
+            "properties": {
+                "bytes": {
+                    "description": "их суммарный размер",
+                    "type": "integer"
+                },
+                "db_rows": {
+                    "description": "строки учёта: подписки, журнал, трафик, счётчики, уведомления, лимиты, приглашения, членство и организации без других участников",
+                    "type": "integer"
+                },
+                "error": {
+                    "description": "ошибка, прервавшая удаление: остальные поля — что удалено до неё, вызов нужно повторить",
+                    "type": "string"
+                },
+                "objects": {
+                    "description": "объекты MinIO во всех бакетах категорий",
+                    "type": "integer"
+                },
+                "session_keys": {
+                    "description": "удалены сессионные и публичные ключи handshake",
+                    "type": "boolean"
+                }
+            }
+        },
         "dto.UserUsage": {
             "type": "object",
             "properties": {
+                "by_category": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/domain.CategoryUsage"
+                    }
+                },
+                "current_used_bytes": {
+                    "type": "integer"
+                },
                 "current_used_gb": {
                     "type": "integer"
                 },
@@ -918,11 +6149,26 @@ const docTemplate = `{
                 "current_used_mb": {
                     "type": "integer"
                 },
+                "ledger_used_bytes": {
+                    "description": "LedgerUsedBytes — сумма журнала изменений, по которому можно проверить current_used",
+                    "type": "integer"
+                },
                 "plan_name": {
                     "type": "string"
                 },
+                "storage_limit_bytes": {
+                    "type": "integer"
+                },
                 "storage_limit_gb": {
                     "type": "integer"
+                },
+                "traffic": {
+                    "description": "Traffic — исходящий трафик и запросы за текущий месяц с лимитами плана",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/domain.TrafficUsage"
+                        }
+                    ]
                 }
             }
         }
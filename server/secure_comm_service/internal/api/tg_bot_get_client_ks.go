@@ -25,9 +25,10 @@ func (h *TGClientKeysAPI) GetClientKS(c *gin.Context) {
 	}
 	clientIDStr := strconv.Itoa(clientID)
 
-	kEnc, kMac, err := h.sessionI.GetSessionKeys(c, clientIDStr)
+	sessionID := utils.GetSessionID(c)
+	kEnc, kMac, err := h.sessionI.GetSessionKeys(c, sessionID)
 	if err != nil {
-		logrus.Errorf("%s: invalid session for clientID %s", op, clientIDStr)
+		logrus.Errorf("%s: invalid session %s for clientID %s", op, sessionID, clientIDStr)
 		return
 	}
 
@@ -2,7 +2,6 @@ package api
 
 import (
 	"net/http"
-	"strconv"
 
 	"github.com/1abobik1/SecureComm/internal/handler/utils"
 	"github.com/gin-gonic/gin"
@@ -26,8 +25,7 @@ func (h *WEBClientKeysAPI) GetClientKS(c *gin.Context) {
 		return
 	}
 
-	clientID, _ := utils.GetUserID(c)
-	kEnc, kMac, err := h.sessionI.GetSessionKeys(c, strconv.Itoa(clientID))
+	kEnc, kMac, err := h.sessionI.GetSessionKeys(c, utils.GetSessionID(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "no session"})
 		return
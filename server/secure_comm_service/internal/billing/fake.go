@@ -0,0 +1,141 @@
+package billing
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	FakeProviderName = "fake"
+
+	// FakeSignatureHeader — заголовок подписи в формате "t=<unix>,v1=<hex hmac-sha256(secret, t + "." + payload)>"
+	FakeSignatureHeader = "X-Fake-Signature"
+
+	fakeCheckoutTTL = 30 * time.Minute
+)
+
+// FakeProvider — встроенный провайдер без внешних вызовов. Вебхуки подписываются локально тем же секретом,
+// которым проверяются, поэтому весь сценарий оплаты можно прогнать офлайн.
+type FakeProvider struct {
+	secret    []byte
+	baseURL   string
+	tolerance time.Duration
+	now       func() time.Time
+}
+
+// NewFakeProvider создаёт фейкового провайдера. baseURL — адрес сервиса, на котором открыта страница "оплаты" /billing/fake/pay.
+func NewFakeProvider(secret, baseURL string, tolerance time.Duration) *FakeProvider {
+	return &FakeProvider{
+		secret:    []byte(secret),
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		tolerance: tolerance,
+		now:       time.Now,
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return FakeProviderName
+}
+
+func (p *FakeProvider) CreateCheckout(_ context.Context, req CheckoutRequest) (CheckoutSession, error) {
+	providerSessionID := "fake_cs_" + uuid.NewString()
+	return CheckoutSession{
+		ProviderSessionID: providerSessionID,
+		URL:               fmt.Sprintf("%s/billing/fake/pay/%s", p.baseURL, providerSessionID),
+		ExpiresAt:         p.now().Add(fakeCheckoutTTL),
+	}, nil
+}
+
+func (p *FakeProvider) ParseWebhook(payload []byte, header http.Header) (Event, error) {
+	sigHeader := header.Get(FakeSignatureHeader)
+	if sigHeader == "" {
+		return Event{}, ErrInvalidSignature
+	}
+
+	var ts int64
+	var sig string
+	for _, part := range strings.Split(sigHeader, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch k {
+		case "t":
+			parsed, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return Event{}, ErrInvalidSignature
+			}
+			ts = parsed
+		case "v1":
+			sig = v
+		}
+	}
+	if ts == 0 || sig == "" {
+		return Event{}, ErrInvalidSignature
+	}
+
+	expected, err := hex.DecodeString(sig)
+	if err != nil || !hmac.Equal(expected, p.sign(ts, payload)) {
+		return Event{}, ErrInvalidSignature
+	}
+
+	// защита от повторной отправки старого перехваченного вебхука
+	if p.tolerance > 0 {
+		age := p.now().Sub(time.Unix(ts, 0))
+		if age > p.tolerance || age < -p.tolerance {
+			return Event{}, ErrStaleWebhook
+		}
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return Event{}, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	if event.ID == "" || event.Type == "" || event.ProviderSessionID == "" {
+		return Event{}, ErrInvalidPayload
+	}
+	return event, nil
+}
+
+// BuildWebhook формирует подписанный вебхук так, как его прислал бы настоящий провайдер
+func (p *FakeProvider) BuildWebhook(eventType, providerSessionID string, amountCents int, currency string) ([]byte, http.Header, error) {
+	now := p.now()
+	payload, err := json.Marshal(Event{
+		ID:                "fake_evt_" + uuid.NewString(),
+		Type:              eventType,
+		ProviderSessionID: providerSessionID,
+		AmountCents:       amountCents,
+		Currency:          currency,
+		CreatedAt:         now.UTC(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header := http.Header{}
+	header.Set(FakeSignatureHeader, p.SignatureHeader(now.Unix(), payload))
+	return payload, header, nil
+}
+
+// SignatureHeader возвращает значение FakeSignatureHeader для payload
+func (p *FakeProvider) SignatureHeader(ts int64, payload []byte) string {
+	return fmt.Sprintf("t=%d,v1=%s", ts, hex.EncodeToString(p.sign(ts, payload)))
+}
+
+func (p *FakeProvider) sign(ts int64, payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(strconv.FormatInt(ts, 10)))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
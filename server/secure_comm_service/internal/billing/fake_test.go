@@ -0,0 +1,105 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestProvider(now time.Time) *FakeProvider {
+	p := NewFakeProvider("test-secret", "http://localhost:8080/", 5*time.Minute)
+	p.now = func() time.Time { return now }
+	return p
+}
+
+func TestFakeProvider_CreateCheckout(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	p := newTestProvider(now)
+
+	session, err := p.CreateCheckout(context.Background(), CheckoutRequest{SessionID: "s1", UserID: 1, PlanName: "pro", AmountCents: 25000, Currency: "RUB"})
+	if err != nil {
+		t.Fatalf("CreateCheckout: %v", err)
+	}
+	if !strings.HasPrefix(session.URL, "http://localhost:8080/billing/fake/pay/") || !strings.HasSuffix(session.URL, session.ProviderSessionID) {
+		t.Errorf("unexpected checkout url %q", session.URL)
+	}
+	if !session.ExpiresAt.Equal(now.Add(fakeCheckoutTTL)) {
+		t.Errorf("ExpiresAt = %v, want %v", session.ExpiresAt, now.Add(fakeCheckoutTTL))
+	}
+}
+
+func TestFakeProvider_WebhookRoundTrip(t *testing.T) {
+	p := newTestProvider(time.Unix(1_700_000_000, 0))
+
+	payload, header, err := p.BuildWebhook(EventPaymentSucceeded, "fake_cs_1", 25000, "RUB")
+	if err != nil {
+		t.Fatalf("BuildWebhook: %v", err)
+	}
+
+	event, err := p.ParseWebhook(payload, header)
+	if err != nil {
+		t.Fatalf("ParseWebhook: %v", err)
+	}
+	if event.Type != EventPaymentSucceeded || event.ProviderSessionID != "fake_cs_1" || event.AmountCents != 25000 || event.Currency != "RUB" {
+		t.Errorf("unexpected event %+v", event)
+	}
+	if event.ID == "" {
+		t.Error("event id is empty")
+	}
+}
+
+func TestFakeProvider_RejectsBadSignatures(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	p := newTestProvider(now)
+
+	payload, header, err := p.BuildWebhook(EventPaymentSucceeded, "fake_cs_1", 25000, "RUB")
+	if err != nil {
+		t.Fatalf("BuildWebhook: %v", err)
+	}
+
+	other := newTestProvider(now)
+	other.secret = []byte("other-secret")
+	_, otherHeader, _ := other.BuildWebhook(EventPaymentSucceeded, "fake_cs_1", 25000, "RUB")
+
+	tampered := []byte(strings.Replace(string(payload), "25000", "1", 1))
+
+	stale := http.Header{}
+	staleTs := now.Add(-10 * time.Minute).Unix()
+	stale.Set(FakeSignatureHeader, p.SignatureHeader(staleTs, payload))
+
+	cases := []struct {
+		name    string
+		payload []byte
+		header  http.Header
+		want    error
+	}{
+		{"missing header", payload, http.Header{}, ErrInvalidSignature},
+		{"garbage header", payload, http.Header{FakeSignatureHeader: {"nonsense"}}, ErrInvalidSignature},
+		{"tampered payload", tampered, header, ErrInvalidSignature},
+		{"wrong secret", payload, otherHeader, ErrInvalidSignature},
+		{"stale timestamp", payload, stale, ErrStaleWebhook},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := p.ParseWebhook(tc.payload, tc.header); !errors.Is(err, tc.want) {
+				t.Errorf("ParseWebhook error = %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestFakeProvider_RejectsIncompleteEvent(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	p := newTestProvider(now)
+
+	payload := []byte(`{"id":"evt_1","type":"payment.succeeded"}`)
+	header := http.Header{}
+	header.Set(FakeSignatureHeader, p.SignatureHeader(now.Unix(), payload))
+
+	if _, err := p.ParseWebhook(payload, header); !errors.Is(err, ErrInvalidPayload) {
+		t.Errorf("ParseWebhook error = %v, want %v", err, ErrInvalidPayload)
+	}
+}
@@ -0,0 +1,54 @@
+package billing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+const (
+	EventPaymentSucceeded = "payment.succeeded"
+	EventPaymentFailed    = "payment.failed"
+	EventCheckoutExpired  = "checkout.expired"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrStaleWebhook     = errors.New("webhook timestamp is outside the tolerance window")
+	ErrInvalidPayload   = errors.New("invalid webhook payload")
+)
+
+// CheckoutRequest — данные для создания платёжной сессии у провайдера
+type CheckoutRequest struct {
+	SessionID   string // наш ID сессии, провайдер возвращает его в вебхуке
+	UserID      int
+	PlanName    string
+	AmountCents int
+	Currency    string
+}
+
+// CheckoutSession — созданная у провайдера сессия оплаты
+type CheckoutSession struct {
+	ProviderSessionID string
+	URL               string // куда отправить пользователя для оплаты
+	ExpiresAt         time.Time
+}
+
+// Event — проверенное событие из вебхука провайдера
+type Event struct {
+	ID                string    `json:"id"` // уникален в рамках провайдера, по нему события дедуплицируются
+	Type              string    `json:"type"`
+	ProviderSessionID string    `json:"session_id"`
+	AmountCents       int       `json:"amount_cents"`
+	Currency          string    `json:"currency"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// Provider — платёжный провайдер. Реализация обязана проверять подпись вебхука
+// до того, как его содержимое будет использовано.
+type Provider interface {
+	Name() string
+	CreateCheckout(ctx context.Context, req CheckoutRequest) (CheckoutSession, error)
+	ParseWebhook(payload []byte, header http.Header) (Event, error)
+}
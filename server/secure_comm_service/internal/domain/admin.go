@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Действия, которые пишутся в журнал админского API
+const (
+	AuditPlanCreate         = "plan.create"
+	AuditPlanUpdate         = "plan.update"
+	AuditPlanRetire         = "plan.retire"
+	AuditUserAssignPlan     = "user.assign_plan"
+	AuditUserSetOverride    = "user.set_override"
+	AuditUserRemoveOverride = "user.remove_override"
+)
+
+// Типы объектов в журнале админского API
+const (
+	AuditTargetPlan = "plan"
+	AuditTargetUser = "user"
+)
+
+// PlanUpdate — изменение тарифа администратором; nil — поле не меняется
+type PlanUpdate struct {
+	StorageLimit *int64
+	PriceCents   *int
+	PeriodDays   *int
+	EgressLimit  *int64
+	RequestLimit *int64
+	MaxFileSize  *int64
+	MaxFiles     *int64
+}
+
+// LimitOverride — персональные лимиты пользователя поверх плана. nil — действует значение плана.
+// Сохраняются при смене плана и перестают действовать после ExpiresAt.
+type LimitOverride struct {
+	UserID       int       `json:"user_id"`
+	StorageLimit *int64    `json:"storage_limit,omitempty"`
+	MaxFileSize  *int64    `json:"max_file_size,omitempty"`
+	MaxFiles     *int64    `json:"max_files,omitempty"`
+	EgressLimit  *int64    `json:"egress_limit,omitempty"`
+	RequestLimit *int64    `json:"request_limit,omitempty"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	Reason       string    `json:"reason"`
+	CreatedBy    int       `json:"created_by"` // ID администратора
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// AdminAuditEntry — запись журнала изменений, сделанных через админское API
+type AdminAuditEntry struct {
+	ID         int64  `json:"id"`
+	AdminID    int    `json:"admin_id"`
+	Action     string `json:"action"`      // см. Audit*
+	TargetType string `json:"target_type"` // plan | user
+	TargetID   string `json:"target_id"`   // имя плана или ID пользователя
+	// Details — состояние до и после изменения
+	Details   json.RawMessage `json:"details" swaggertype:"object"`
+	CreatedAt time.Time       `json:"created_at"`
+}
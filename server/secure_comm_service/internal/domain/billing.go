@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// CheckoutSession — платёжная сессия пользователя
+type CheckoutSession struct {
+	ID          string     `json:"id"`
+	PlanName    string     `json:"plan_name"`
+	AmountCents int        `json:"amount_cents"`
+	Currency    string     `json:"currency"`
+	AutoRenew   bool       `json:"auto_renew"`
+	Status      string     `json:"status"`                 // pending | paid | failed | expired
+	CheckoutURL string     `json:"checkout_url,omitempty"` // куда отправить пользователя для оплаты
+	CreatedAt   time.Time  `json:"created_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
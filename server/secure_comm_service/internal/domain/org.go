@@ -0,0 +1,56 @@
+package domain
+
+import "time"
+
+// Роли участников организации
+const (
+	OrgRoleOwner  = "owner"  // создатель, управляет ролями; один на организацию
+	OrgRoleAdmin  = "admin"  // управляет участниками, планом и чужими файлами организации
+	OrgRoleMember = "member" // загружает файлы в общий пул и удаляет свои
+)
+
+// Org — организация с общим пулом хранилища
+type Org struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	CreatedBy int       `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+	// Role — роль пользователя, запросившего организацию
+	Role string `json:"role,omitempty"`
+}
+
+type OrgMember struct {
+	UserID   int       `json:"user_id"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// OrgInvite — приглашение в организацию. Участником пользователь становится, только приняв его.
+type OrgInvite struct {
+	ID        int       `json:"id"`
+	OrgID     int       `json:"org_id"`
+	OrgName   string    `json:"org_name,omitempty"`
+	UserID    int       `json:"user_id"`
+	Role      string    `json:"role"`
+	InvitedBy int       `json:"invited_by"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// OrgUsage — занятое место общего пула организации
+type OrgUsage struct {
+	CurrentUsed  int64  `json:"current_used"`
+	StorageLimit int64  `json:"storage_limit"`
+	PlanName     string `json:"plan_name"`
+	Files        int64  `json:"files"`
+	// Members — кто сколько занял в пуле; только для owner и admin
+	Members []OrgMemberUsage `json:"members,omitempty"`
+}
+
+// OrgMemberUsage — вклад одного участника в занятое место организации, посчитанный по usage_ledger.
+// UserID = 0 — корректировки реконсилера, которые нельзя приписать участнику.
+type OrgMemberUsage struct {
+	UserID int   `json:"user_id"`
+	Bytes  int64 `json:"bytes"`
+	Files  int64 `json:"files"`
+}
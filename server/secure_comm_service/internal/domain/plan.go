@@ -0,0 +1,58 @@
+package domain
+
+import "time"
+
+type Plan struct {
+	ID         int    `db:"id"          json:"id"`
+	Name       string `db:"name"        json:"name"`
+	PriceCents int    `db:"price_cents" json:"price_cents"` // цена за период в копейках
+	PeriodDays int    `db:"period_days" json:"period_days"` // 0 — бессрочный
+	// RetiredAt — когда план выведен из продажи; на него нельзя перейти, текущие подписки не продлеваются
+	RetiredAt *time.Time `db:"retired_at" json:"retired_at,omitempty"`
+	PlanLimits
+}
+
+// PlanLimits — типизированный набор лимитов плана. Для числовых лимитов, кроме storage_limit, 0 — без ограничения.
+type PlanLimits struct {
+	StorageLimit int64 `db:"storage_limit" json:"storage_limit"` // лимит байт
+	MaxFileSize  int64 `db:"max_file_size" json:"max_file_size"` // максимальный размер одного файла в байтах
+	MaxFiles     int64 `db:"max_files"     json:"max_files"`     // максимальное число файлов
+	EgressLimit  int64 `db:"egress_limit"  json:"egress_limit"`  // исходящий трафик в байтах за месяц
+	RequestLimit int64 `db:"request_limit" json:"request_limit"` // запросов к файловому API за месяц
+}
+
+// UserPlan — действующая подписка пользователя
+type UserPlan struct {
+	Plan
+	StartedAt         time.Time  `db:"started_at"           json:"started_at"`
+	ExpiresAt         *time.Time `db:"expires_at"           json:"expires_at"` // nil — бессрочно
+	CurrentUsed       int64      `db:"current_used"         json:"current_used"`
+	CancelAtPeriodEnd bool       `db:"cancel_at_period_end" json:"cancel_at_period_end"`
+	ReadOnly          bool       `db:"read_only"            json:"read_only"` // занято больше лимита, загрузка запрещена
+	AutoRenew         bool       `db:"auto_renew"           json:"auto_renew"`
+	// GraceUntil — до какого момента можно удалить лишние файлы, прежде чем аккаунт станет read-only
+	GraceUntil *time.Time `db:"grace_until" json:"grace_until,omitempty"`
+	// NextPlan — план, на который пользователь перейдёт после окончания текущего периода (после отмены)
+	NextPlan *string `json:"next_plan,omitempty"`
+	// LimitOverride — действующие персональные лимиты, назначенные администратором поверх плана
+	LimitOverride *LimitOverride `json:"limit_override,omitempty"`
+}
+
+// PlanTransition — что произошло с подпиской по окончании периода
+type PlanTransition struct {
+	UserID     int // 0 — подписка организации
+	OrgID      int // 0 — личная подписка
+	FromPlan   string
+	ToPlan     string
+	Action     string     // renewed | fallback_free | renewal_due | scheduled | override_expired
+	GraceUntil *time.Time // не nil, если после перехода занято больше лимита
+}
+
+// ExpiringPlan — подписка, о скором окончании которой нужно предупредить
+type ExpiringPlan struct {
+	UserID            int
+	PlanName          string
+	ExpiresAt         time.Time
+	AutoRenew         bool
+	CancelAtPeriodEnd bool
+}
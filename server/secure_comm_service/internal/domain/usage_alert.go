@@ -0,0 +1,11 @@
+package domain
+
+// UsageAlertSettings — пороги заполнения хранилища и адреса, на которые приходят уведомления
+type UsageAlertSettings struct {
+	Thresholds    []int  `json:"thresholds"`     // проценты от storage_limit по возрастанию
+	WebhookURL    string `json:"webhook_url"`    // пустой — вебхук по умолчанию из конфигурации сервиса, если он задан
+	Email         string `json:"email"`          // пустой — письма не отправляются
+	EmailVerified bool   `json:"email_verified"` // адрес подтверждён кодом из письма, до этого письма не отправляются
+	Default       bool   `json:"default"`        // пользователь не менял настройки, действуют пороги по умолчанию
+	Crossed       []int  `json:"crossed"`        // пороги, о пересечении которых уже отправлено уведомление
+}
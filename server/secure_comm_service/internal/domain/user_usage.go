@@ -1,7 +1,65 @@
 package domain
 
+import "time"
+
 type UserUsage struct {
 	CurrentUsed  int64  `db:"current_used" json:"current_used"`   // занято байт
 	StorageLimit int64  `db:"storage_limit" json:"storage_limit"` // лимит байт
 	PlanName     string `db:"plan_name"    json:"plan_name"`      // например "free" или "pro"
+	// LedgerUsed — сумма журнала usage_ledger; должна совпадать с CurrentUsed
+	LedgerUsed int64           `json:"ledger_used"`
+	ByCategory []CategoryUsage `json:"by_category"`
+	Traffic    TrafficUsage    `json:"traffic"`
+}
+
+// Причины записей в журнале usage_ledger
+const (
+	UsageReasonUpload         = "upload"
+	UsageReasonDelete         = "delete"
+	UsageReasonCopy           = "copy"
+	UsageReasonMove           = "move"
+	UsageReasonExpire         = "expire"
+	UsageReasonRollback       = "rollback"        // откат резерва, если операция не удалась
+	UsageReasonReconcile      = "reconcile"       // корректировка реконсилером по фактическому содержимому MinIO
+	UsageReasonOpeningBalance = "opening_balance" // остаток на момент появления журнала
+)
+
+// UsageRef — к какому файлу и операции относится изменение занятого места
+type UsageRef struct {
+	ObjID    string
+	Category string
+	Reason   string
+}
+
+// UsageLedgerEntry — одна запись журнала изменений занятого места
+type UsageLedgerEntry struct {
+	ID        int64     `json:"id"`
+	ObjID     string    `json:"obj_id,omitempty"`
+	Category  string    `json:"category,omitempty"`
+	Delta     int64     `json:"delta"` // >0 — место занято, <0 — освобождено
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CategoryUsage — занятое место в одной категории. Пустая категория — начальный остаток и корректировки реконсилера.
+type CategoryUsage struct {
+	Category string `json:"category"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// UsagePoint — изменение занятого места за один день
+type UsagePoint struct {
+	Day     time.Time `json:"day"`
+	Added   int64     `json:"added"`
+	Removed int64     `json:"removed"`
+	Total   int64     `json:"total"` // занято на конец дня
+}
+
+// TrafficUsage — исходящий трафик и запросы за текущий календарный месяц (UTC)
+type TrafficUsage struct {
+	PeriodStart  time.Time `json:"period_start"`
+	EgressUsed   int64     `json:"egress_used"`   // байт отдано
+	EgressLimit  int64     `json:"egress_limit"`  // 0 — без лимита
+	Requests     int64     `json:"requests"`      // запросов к файловому API
+	RequestLimit int64     `json:"request_limit"` // 0 — без лимита
 }
@@ -0,0 +1,11 @@
+package dto
+
+// UserDataPurgeResult — что удалено из secure_comm_service при удалении аккаунта
+// swagger:model UserDataPurgeResult
+type UserDataPurgeResult struct {
+	Objects     int    `json:"objects"`         // объекты MinIO во всех бакетах категорий
+	Bytes       int64  `json:"bytes"`           // их суммарный размер
+	DBRows      int64  `json:"db_rows"`         // строки учёта: подписки, журнал, трафик, счётчики, уведомления, лимиты, приглашения, членство и организации без других участников
+	SessionKeys bool   `json:"session_keys"`    // удалены сессионные и публичные ключи handshake
+	Error       string `json:"error,omitempty"` // ошибка, прервавшая удаление: остальные поля — что удалено до неё, вызов нужно повторить
+}
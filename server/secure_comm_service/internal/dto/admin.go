@@ -0,0 +1,49 @@
+package dto
+
+import "time"
+
+// CreatePlanReq — новый тариф. Лимиты в байтах, 0 — без ограничения (кроме storage_limit).
+// swagger:model CreatePlanReq
+type CreatePlanReq struct {
+	Name         string `json:"name" binding:"required,max=64"`
+	StorageLimit int64  `json:"storage_limit" binding:"required,gt=0"`
+	PriceCents   int    `json:"price_cents" binding:"min=0"`
+	PeriodDays   int    `json:"period_days" binding:"min=0"` // 0 — бессрочный, платному плану обязателен
+	EgressLimit  int64  `json:"egress_limit" binding:"min=0"`
+	RequestLimit int64  `json:"request_limit" binding:"min=0"`
+	MaxFileSize  int64  `json:"max_file_size" binding:"min=0"`
+	MaxFiles     int64  `json:"max_files" binding:"min=0"`
+}
+
+// UpdatePlanReq — изменение тарифа, отсутствующие поля не меняются
+// swagger:model UpdatePlanReq
+type UpdatePlanReq struct {
+	StorageLimit *int64 `json:"storage_limit" binding:"omitempty,gt=0"`
+	PriceCents   *int   `json:"price_cents" binding:"omitempty,min=0"`
+	PeriodDays   *int   `json:"period_days" binding:"omitempty,min=0"`
+	EgressLimit  *int64 `json:"egress_limit" binding:"omitempty,min=0"`
+	RequestLimit *int64 `json:"request_limit" binding:"omitempty,min=0"`
+	MaxFileSize  *int64 `json:"max_file_size" binding:"omitempty,min=0"`
+	MaxFiles     *int64 `json:"max_files" binding:"omitempty,min=0"`
+}
+
+// AssignPlanReq — ручное назначение тарифа пользователю
+// swagger:model AssignPlanReq
+type AssignPlanReq struct {
+	PlanName string `json:"plan_name" binding:"required"`
+	// причина попадает в журнал изменений
+	Reason string `json:"reason" binding:"required,max=500"`
+}
+
+// LimitOverrideReq — персональные лимиты пользователя поверх плана. Отсутствующие поля — лимит плана.
+// swagger:model LimitOverrideReq
+type LimitOverrideReq struct {
+	StorageLimit *int64    `json:"storage_limit" binding:"omitempty,gt=0"`
+	MaxFileSize  *int64    `json:"max_file_size" binding:"omitempty,min=0"`
+	MaxFiles     *int64    `json:"max_files" binding:"omitempty,min=0"`
+	EgressLimit  *int64    `json:"egress_limit" binding:"omitempty,min=0"`
+	RequestLimit *int64    `json:"request_limit" binding:"omitempty,min=0"`
+	ExpiresAt    time.Time `json:"expires_at" binding:"required"` // RFC 3339, должен быть в будущем
+	// причина попадает в журнал изменений
+	Reason string `json:"reason" binding:"required,max=500"`
+}
@@ -24,4 +24,18 @@ type ConflictErr struct {
 // swagger:model UnauthorizedErr
 type UnauthorizedErr struct {
 	Error string `json:"error"`
-}
\ No newline at end of file
+}
+
+// LimitExceededErr описывает ответ при превышении лимита тарифа (403, 413 или 429).
+// swagger:model LimitExceededErr
+type LimitExceededErr struct {
+	// example: quota exceeded
+	Error string `json:"error"`
+	// название лимита плана: storage_limit, max_file_size, max_files, egress_limit, request_limit
+	// example: storage_limit
+	Limit string `json:"limit"`
+	// значение лимита в плане
+	Max int64 `json:"max"`
+	// значение, которое получилось бы после операции
+	Actual int64 `json:"actual"`
+}
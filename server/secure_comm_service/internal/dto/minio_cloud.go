@@ -9,10 +9,78 @@ type ObjectID struct {
 	FileCategory string `json:"file_category"`
 }
 
+// FileTransferReq — запрос на копирование или перенос файла в другую категорию.
+// swagger:model FileTransferReq
+type FileTransferReq struct {
+	ObjID          string `json:"obj_id" binding:"required"`
+	FileCategory   string `json:"file_category" binding:"required"`
+	TargetCategory string `json:"target_category" binding:"required"`
+}
+
+// FileRenameReq — запрос на замену зашифрованных метаданных файла (новое имя, MIME-тип, теги).
+// swagger:model FileRenameReq
+type FileRenameReq struct {
+	ObjID         string `json:"obj_id" binding:"required"`
+	FileCategory  string `json:"file_category" binding:"required"`
+	EncryptedMeta string `json:"encrypted_meta" binding:"required"`
+}
+
+// FileResponse — описание файла для клиента.
+// Для файлов, загруженных с X-Encrypted-Meta, Name и MimeType пустые: имя, MIME-тип и теги
+// лежат в EncryptedMeta (base64 от blob, зашифрованного ключом файла) и расшифровываются только клиентом.
 type FileResponse struct {
-	Name       string `json:"name"`
-	Created_At string `json:"created_at"`
-	ObjID      string `json:"obj_id"`
-	Url        string `json:"url"`
-	MimeType   string `json:"mime_type"`
+	Name          string `json:"name"`
+	Created_At    string `json:"created_at"`
+	ObjID         string `json:"obj_id"`
+	Url           string `json:"url"`
+	MimeType      string `json:"mime_type"`
+	EncryptedMeta string `json:"encrypted_meta,omitempty"`
+	Size          int64  `json:"size"`
+	Category      string `json:"category"`
+	// ExpiresAt — RFC3339, когда файл будет удалён автоматически; пусто — файл бессрочный
+	ExpiresAt string `json:"expires_at,omitempty"`
+	// ExpiresIn — сколько секунд осталось до удаления
+	ExpiresIn int64 `json:"expires_in,omitempty"`
+}
+
+// FileExpirationReq — запрос на изменение срока жизни файла.
+// Передаётся expires_at (RFC3339) или expires_in (секунды); если оба пустые — срок жизни снимается.
+// swagger:model FileExpirationReq
+type FileExpirationReq struct {
+	ObjID        string `json:"obj_id" binding:"required"`
+	FileCategory string `json:"file_category" binding:"required"`
+	ExpiresAt    string `json:"expires_at"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// ArchiveReq — запрос на скачивание нескольких файлов одним архивом.
+// Если ObjectIDs пуст, в архив попадают все файлы пользователя из категории Category.
+// swagger:model ArchiveReq
+type ArchiveReq struct {
+	// zip или tar, по умолчанию zip
+	Format    string     `json:"format"`
+	Category  string     `json:"category"`
+	ObjectIDs []ObjectID `json:"object_ids"`
+}
+
+// ArchiveManifest — manifest.json, который дописывается последним файлом в архив.
+// swagger:model ArchiveManifest
+type ArchiveManifest struct {
+	CreatedAt string                 `json:"created_at"`
+	Format    string                 `json:"format"`
+	Files     []ArchiveManifestEntry `json:"files"`
+}
+
+// ArchiveManifestEntry описывает один зашифрованный файл внутри архива.
+// Name — base64 от оригинального имени (как и в dto.FileResponse), Sha256 — hex от содержимого записи (шифртекста).
+// Для файлов с зашифрованными метаданными Name и MimeType пустые, а заполнен EncryptedMeta.
+type ArchiveManifestEntry struct {
+	Path          string `json:"path"`
+	ObjID         string `json:"obj_id"`
+	Category      string `json:"category"`
+	Name          string `json:"name"`
+	MimeType      string `json:"mime_type"`
+	EncryptedMeta string `json:"encrypted_meta,omitempty"`
+	Size          int64  `json:"size"`
+	Sha256        string `json:"sha256"`
 }
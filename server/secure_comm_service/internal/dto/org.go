@@ -0,0 +1,29 @@
+package dto
+
+import "github.com/1abobik1/SecureComm/internal/domain"
+
+// CreateOrgReq — запрос на создание организации
+// swagger:model CreateOrgReq
+type CreateOrgReq struct {
+	Name string `json:"name" binding:"required,max=128"`
+}
+
+// InviteOrgMemberReq — приглашение пользователя в организацию
+// swagger:model InviteOrgMemberReq
+type InviteOrgMemberReq struct {
+	UserID int    `json:"user_id" binding:"required,gt=0"`
+	Role   string `json:"role"    binding:"required,oneof=admin member"`
+}
+
+// UpdateOrgMemberReq — запрос на смену роли участника
+// swagger:model UpdateOrgMemberReq
+type UpdateOrgMemberReq struct {
+	Role string `json:"role" binding:"required,oneof=admin member"`
+}
+
+// OrgResp — организация вместе с участниками
+// swagger:model OrgResp
+type OrgResp struct {
+	domain.Org
+	Members []domain.OrgMember `json:"members"`
+}
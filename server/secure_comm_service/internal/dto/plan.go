@@ -0,0 +1,19 @@
+package dto
+
+// ChangePlanReq — запрос на смену тарифа
+// swagger:model ChangePlanReq
+type ChangePlanReq struct {
+	PlanName string `json:"plan_name" binding:"required"`
+	// только для downgrade: если занято больше нового лимита, перевести аккаунт в read-only вместо отказа
+	ReadOnlyIfOverLimit bool `json:"read_only_if_over_limit"`
+	// продлевать платный план автоматически по окончании периода
+	AutoRenew bool `json:"auto_renew"`
+}
+
+// CheckoutReq — запрос на оплату тарифа
+// swagger:model CheckoutReq
+type CheckoutReq struct {
+	PlanName string `json:"plan_name" binding:"required"`
+	// продлевать платный план автоматически по окончании периода
+	AutoRenew bool `json:"auto_renew"`
+}
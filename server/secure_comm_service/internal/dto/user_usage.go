@@ -1,9 +1,36 @@
 package dto
 
+import "github.com/1abobik1/SecureComm/internal/domain"
+
 type UserUsage struct {
 	CurrentUsedGB  int    `json:"current_used_gb"`
 	CurrentUsedMB  int    `json:"current_used_mb"`
 	CurrentUsedKB  int    `json:"current_used_kb"`
 	StorageLimitGB int    `json:"storage_limit_gb"`
 	PlanName       string `json:"plan_name"`
+
+	CurrentUsedBytes  int64 `json:"current_used_bytes"`
+	StorageLimitBytes int64 `json:"storage_limit_bytes"`
+	// LedgerUsedBytes — сумма журнала изменений, по которому можно проверить current_used
+	LedgerUsedBytes int64                  `json:"ledger_used_bytes"`
+	ByCategory      []domain.CategoryUsage `json:"by_category"`
+	// Traffic — исходящий трафик и запросы за текущий месяц с лимитами плана
+	Traffic domain.TrafficUsage `json:"traffic"`
+}
+
+// UsageAlertSettingsReq — пороги уведомлений о заполнении хранилища
+// swagger:model UsageAlertSettingsReq
+type UsageAlertSettingsReq struct {
+	// проценты от лимита хранилища, от 1 до 100; пустой список выключает уведомления
+	Thresholds []int `json:"thresholds" binding:"required,max=10"`
+	// адрес вебхука (http или https), пустой — вебхук по умолчанию из конфигурации сервиса
+	WebhookURL string `json:"webhook_url"`
+	// адрес для писем, пустой — письма не отправляются. На новый адрес приходит код подтверждения
+	Email string `json:"email"`
+}
+
+// ConfirmAlertEmailReq — код из письма, подтверждающий адрес для уведомлений
+// swagger:model ConfirmAlertEmailReq
+type ConfirmAlertEmailReq struct {
+	Code string `json:"code" binding:"required,max=64"`
 }
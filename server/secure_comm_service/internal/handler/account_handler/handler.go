@@ -0,0 +1,24 @@
+package account_handler
+
+import (
+	"context"
+
+	"github.com/1abobik1/SecureComm/internal/service/cloud_service"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+)
+
+// UserKeysPurger удаляет ключи handshake пользователя (см. handshake_service)
+type UserKeysPurger interface {
+	PurgeUserKeys(ctx context.Context, userID string) error
+}
+
+// AccountHandler — внутреннее API удаления данных пользователя, вызывается auth_service при удалении аккаунта
+type AccountHandler struct {
+	minio        cloud_service.Client
+	quotaService *quota_service.QuotaService
+	keys         UserKeysPurger
+}
+
+func NewAccountHandler(minio cloud_service.Client, quotaService *quota_service.QuotaService, keys UserKeysPurger) *AccountHandler {
+	return &AccountHandler{minio: minio, quotaService: quotaService, keys: keys}
+}
@@ -0,0 +1,88 @@
+package account_handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// PurgeUserData удаляет все данные пользователя
+// @Summary      Удаление данных пользователя. Внутреннее API, вызывается auth_service при удалении аккаунта
+// @Description  Удаляет ключи handshake (сессия и публичные ключи клиента в Redis), все объекты MinIO с префиксом пользователя во всех бакетах
+// @Description  вместе с их кешем, затем подписки, журнал, трафик, счётчики, настройки уведомлений, персональные лимиты, приглашения и членство в организациях.
+// @Description  Организации пользователя переходят к старшему admin (или участнику, вступившему раньше всех); организации без других участников удаляются вместе с файлами.
+// @Description  Использованные nonce к пользователю не привязаны и истекают сами. Файлы в пулах оставшихся организаций и оплаты сохраняются.
+// @Description  Идемпотентен: после сбоя вызов повторяется и удаляет оставшееся. Принимает только сервисный токен с scope users:delete.
+// @Tags         Internal
+// @Produce      json
+// @Param        Authorization  header    string                   true  "Bearer {service token}"
+// @Param        id             path      int                      true  "ID пользователя"
+// @Success      200            {object}  dto.UserDataPurgeResult  "Что удалено этим вызовом"
+// @Failure      400            {object}  map[string]string        "Некорректный ID пользователя"
+// @Failure      401            {object}  map[string]string        "Нет сервисного токена или он недействителен"
+// @Failure      403            {object}  map[string]string        "У токена нет scope users:delete"
+// @Failure      500            {object}  dto.UserDataPurgeResult  "Внутренняя ошибка сервера: в error причина, в остальных полях — что удалено до неё; вызов нужно повторить"
+// @Security     BearerAuth
+// @Router       /internal/users/{id} [delete]
+func (h *AccountHandler) PurgeUserData(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var result dto.UserDataPurgeResult
+	// fail отвечает 500 вместе с тем, что удалено до ошибки: auth_service складывает это с итогом повторного вызова
+	fail := func(message string) {
+		result.Error = message
+		c.JSON(http.StatusInternalServerError, result)
+	}
+
+	// сначала ключи: с удалёнными ключами клиент уже не сможет ничего загрузить, пока удаляются файлы
+	if err := h.keys.PurgeUserKeys(c, strconv.Itoa(userID)); err != nil {
+		logrus.Errorf("purge keys of user %d: %v", userID, err)
+		fail("failed to purge session keys")
+		return
+	}
+	result.SessionKeys = true
+
+	files, err := h.minio.PurgeUserFiles(c, userID)
+	result.Objects, result.Bytes = files.Objects, files.Bytes
+	if err != nil {
+		logrus.Errorf("purge files of user %d (%d objects removed before the error): %v", userID, files.Objects, err)
+		fail("failed to purge files")
+		return
+	}
+
+	// организации, где пользователь был единственным участником, удаляются: сначала их файлы, затем учёт в DeleteUserData
+	orgIDs, err := h.quotaService.SoleOwnedOrgs(c, userID)
+	if err != nil {
+		logrus.Errorf("list organizations of user %d: %v", userID, err)
+		fail("failed to purge organizations")
+		return
+	}
+	for _, orgID := range orgIDs {
+		files, err := h.minio.PurgeOrgFiles(c, orgID)
+		result.Objects += files.Objects
+		result.Bytes += files.Bytes
+		if err != nil {
+			logrus.Errorf("purge files of org %d of user %d: %v", orgID, userID, err)
+			fail("failed to purge organization files")
+			return
+		}
+	}
+
+	rows, err := h.quotaService.DeleteUserData(c, userID)
+	if err != nil {
+		logrus.Errorf("purge quota data of user %d: %v", userID, err)
+		fail("failed to purge account data")
+		return
+	}
+	result.DBRows = rows
+
+	logrus.Infof("data of user %d purged by %s: %d objects, %d bytes, %d rows", userID, c.GetString("service"), result.Objects, result.Bytes, rows)
+	c.JSON(http.StatusOK, result)
+}
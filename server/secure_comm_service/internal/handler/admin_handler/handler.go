@@ -0,0 +1,13 @@
+package admin_handler
+
+import "github.com/1abobik1/SecureComm/internal/service/quota_service"
+
+// AdminHandler — админское API: каталог тарифов и управление планами пользователей.
+// Все маршруты закрыты middleware.AdminOnly.
+type AdminHandler struct {
+	quotaService *quota_service.QuotaService
+}
+
+func NewAdminHandler(quotaService *quota_service.QuotaService) *AdminHandler {
+	return &AdminHandler{quotaService: quotaService}
+}
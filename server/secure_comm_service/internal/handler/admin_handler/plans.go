@@ -0,0 +1,167 @@
+package admin_handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ListPlans возвращает весь каталог тарифов
+// @Summary      Каталог тарифов
+// @Description  Возвращает все тарифы, включая выведенные из продажи (retired_at). Только для администраторов.
+// @Tags         Admin
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Success      200            {array}   domain.Plan        "Тарифы"
+// @Failure      403            {object}  map[string]string  "Нет роли admin"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/plans [get]
+func (h *AdminHandler) ListPlans(c *gin.Context) {
+	plans, err := h.quotaService.ListAllPlans(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, plans)
+}
+
+// CreatePlan добавляет тариф
+// @Summary      Создание тарифа
+// @Description  Добавляет тариф в каталог. Лимиты в байтах, 0 — без ограничения (кроме storage_limit). Платный тариф должен иметь period_days > 0.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string             true  "Bearer {token}"
+// @Param        request        body    dto.CreatePlanReq  true  "Тариф"
+// @Success      201            {object}  domain.Plan        "Созданный тариф"
+// @Failure      400            {object}  map[string]string  "Некорректный тариф"
+// @Failure      403            {object}  map[string]string  "Нет роли admin"
+// @Failure      409            {object}  map[string]string  "Тариф с таким именем уже есть"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/plans [post]
+func (h *AdminHandler) CreatePlan(c *gin.Context) {
+	const op = "location internal.handler.admin_handler.CreatePlan"
+
+	var req dto.CreatePlanReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminID, _ := utils.GetUserID(c)
+	plan, err := h.quotaService.CreatePlan(c, adminID, domain.Plan{
+		Name:       req.Name,
+		PriceCents: req.PriceCents,
+		PeriodDays: req.PeriodDays,
+		PlanLimits: domain.PlanLimits{
+			StorageLimit: req.StorageLimit,
+			MaxFileSize:  req.MaxFileSize,
+			MaxFiles:     req.MaxFiles,
+			EgressLimit:  req.EgressLimit,
+			RequestLimit: req.RequestLimit,
+		},
+	})
+	if err != nil {
+		writeAdminError(c, err, op)
+		return
+	}
+	logrus.Infof("admin %d created plan %s", adminID, plan.Name)
+	c.JSON(http.StatusCreated, plan)
+}
+
+// UpdatePlan меняет тариф
+// @Summary      Изменение тарифа
+// @Description  Меняет переданные поля тарифа. Новые лимиты сразу действуют для всех подписчиков, новая цена и период — для следующих оплат.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string             true  "Bearer {token}"
+// @Param        name           path    string             true  "Имя тарифа"
+// @Param        request        body    dto.UpdatePlanReq  true  "Изменяемые поля"
+// @Success      200            {object}  domain.Plan        "Тариф после изменения"
+// @Failure      400            {object}  map[string]string  "Некорректные значения"
+// @Failure      403            {object}  map[string]string  "Нет роли admin"
+// @Failure      404            {object}  map[string]string  "Тариф не найден"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/plans/{name} [patch]
+func (h *AdminHandler) UpdatePlan(c *gin.Context) {
+	const op = "location internal.handler.admin_handler.UpdatePlan"
+
+	var req dto.UpdatePlanReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminID, _ := utils.GetUserID(c)
+	plan, err := h.quotaService.UpdatePlan(c, adminID, c.Param("name"), domain.PlanUpdate{
+		StorageLimit: req.StorageLimit,
+		PriceCents:   req.PriceCents,
+		PeriodDays:   req.PeriodDays,
+		EgressLimit:  req.EgressLimit,
+		RequestLimit: req.RequestLimit,
+		MaxFileSize:  req.MaxFileSize,
+		MaxFiles:     req.MaxFiles,
+	})
+	if err != nil {
+		writeAdminError(c, err, op)
+		return
+	}
+	logrus.Infof("admin %d updated plan %s", adminID, plan.Name)
+	c.JSON(http.StatusOK, plan)
+}
+
+// RetirePlan выводит тариф из продажи
+// @Summary      Вывод тарифа из продажи
+// @Description  Тариф пропадает из /plans, на него нельзя перейти или оплатить. Текущие подписчики остаются на нём до конца периода
+// @Description  без автопродления и затем переходят на free. Тариф free вывести нельзя.
+// @Tags         Admin
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        name           path    string  true  "Имя тарифа"
+// @Success      200            {object}  domain.Plan        "Выведенный тариф"
+// @Failure      400            {object}  map[string]string  "Тариф free"
+// @Failure      403            {object}  map[string]string  "Нет роли admin"
+// @Failure      404            {object}  map[string]string  "Тариф не найден"
+// @Failure      409            {object}  map[string]string  "Тариф уже выведен"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/plans/{name}/retire [post]
+func (h *AdminHandler) RetirePlan(c *gin.Context) {
+	const op = "location internal.handler.admin_handler.RetirePlan"
+
+	adminID, _ := utils.GetUserID(c)
+	plan, err := h.quotaService.RetirePlan(c, adminID, c.Param("name"))
+	if err != nil {
+		writeAdminError(c, err, op)
+		return
+	}
+	logrus.Infof("admin %d retired plan %s", adminID, plan.Name)
+	c.JSON(http.StatusOK, plan)
+}
+
+func writeAdminError(c *gin.Context, err error, op string) {
+	switch {
+	case errors.Is(err, quota_service.ErrPlanNotFound), errors.Is(err, quota_service.ErrNoActivePlan),
+		errors.Is(err, quota_service.ErrOverrideNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, quota_service.ErrInvalidPlan), errors.Is(err, quota_service.ErrFreePlanRetire),
+		errors.Is(err, quota_service.ErrInvalidOverride):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, quota_service.ErrPlanExists), errors.Is(err, quota_service.ErrPlanRetired),
+		errors.Is(err, quota_service.ErrAlreadyOnPlan):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logrus.Errorf("%s: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	}
+}
@@ -0,0 +1,130 @@
+package admin_handler
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/lib/pq"
+)
+
+func newTestHandler(t *testing.T) (*AdminHandler, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return NewAdminHandler(quota_service.NewQuotaServiceForTesting(db)), mock
+}
+
+// newTestContext — запрос администратора с ID 1
+func newTestContext(method, target, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, bytes.NewReader([]byte(body)))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("claims", jwt.MapClaims{"user_id": float64(1), "role": "admin"})
+	return c, w
+}
+
+func checkMock(t *testing.T, mock sqlmock.Sqlmock) {
+	t.Helper()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCreatePlan_InvalidBody(t *testing.T) {
+	h, mock := newTestHandler(t)
+	c, w := newTestContext(http.MethodPost, "/admin/plans", `{"name":"pro","storage_limit":0}`)
+
+	h.CreatePlan(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+	checkMock(t, mock)
+}
+
+func TestCreatePlan_PaidWithoutPeriod(t *testing.T) {
+	h, mock := newTestHandler(t)
+	c, w := newTestContext(http.MethodPost, "/admin/plans", `{"name":"pro","storage_limit":1024,"price_cents":100}`)
+
+	h.CreatePlan(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+	checkMock(t, mock)
+}
+
+func TestCreatePlan_Duplicate(t *testing.T) {
+	h, mock := newTestHandler(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO plans`).
+		WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+	c, w := newTestContext(http.MethodPost, "/admin/plans", `{"name":"pro","storage_limit":1024,"price_cents":100,"period_days":30}`)
+
+	h.CreatePlan(c)
+
+	if w.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409", w.Code)
+	}
+	checkMock(t, mock)
+}
+
+func TestUpdatePlan_NotFound(t *testing.T) {
+	h, mock := newTestHandler(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM plans p WHERE p.name = \$1 FOR UPDATE`).
+		WithArgs("gold").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	c, w := newTestContext(http.MethodPatch, "/admin/plans/gold", `{"storage_limit":2048}`)
+	c.Params = gin.Params{{Key: "name", Value: "gold"}}
+
+	h.UpdatePlan(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+	checkMock(t, mock)
+}
+
+func TestRetirePlan_Free(t *testing.T) {
+	h, mock := newTestHandler(t)
+	c, w := newTestContext(http.MethodPost, "/admin/plans/free/retire", "")
+	c.Params = gin.Params{{Key: "name", Value: "free"}}
+
+	h.RetirePlan(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+	checkMock(t, mock)
+}
+
+// Внутренние ошибки не раскрываются клиенту
+func TestWriteAdminError_InternalHidden(t *testing.T) {
+	c, w := newTestContext(http.MethodGet, "/admin/plans", "")
+
+	writeAdminError(c, errors.New("pq: connection refused"), "test")
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	if strings.Contains(w.Body.String(), "connection refused") {
+		t.Errorf("body %q leaks the error", w.Body.String())
+	}
+}
@@ -0,0 +1,254 @@
+package admin_handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	auditDefaultLimit = 50
+	auditMaxLimit     = 500
+)
+
+// AssignPlan вручную назначает тариф пользователю
+// @Summary      Назначение тарифа пользователю
+// @Description  Переводит пользователя на тариф без оплаты и без проверки направления смены. Платный тариф действует period_days без автопродления.
+// @Description  Если занято больше нового лимита, аккаунт становится read-only.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string             true  "Bearer {token}"
+// @Param        id             path    int                true  "ID пользователя"
+// @Param        request        body    dto.AssignPlanReq  true  "Тариф и причина"
+// @Success      200            {object}  domain.UserPlan    "Новая подписка пользователя"
+// @Failure      400            {object}  map[string]string  "Некорректный запрос"
+// @Failure      403            {object}  map[string]string  "Нет роли admin"
+// @Failure      404            {object}  map[string]string  "Тариф или активный план пользователя не найден"
+// @Failure      409            {object}  map[string]string  "Пользователь уже на этом тарифе"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/plan [post]
+func (h *AdminHandler) AssignPlan(c *gin.Context) {
+	const op = "location internal.handler.admin_handler.AssignPlan"
+
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	var req dto.AssignPlanReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminID, _ := utils.GetUserID(c)
+	plan, err := h.quotaService.AssignPlan(c, adminID, userID, req.PlanName, req.Reason)
+	if err != nil {
+		writeAdminError(c, err, op)
+		return
+	}
+	logrus.Infof("admin %d assigned plan %s to user %d", adminID, plan.Name, userID)
+	c.JSON(http.StatusOK, plan)
+}
+
+// GetLimitOverride возвращает персональные лимиты пользователя
+// @Summary      Персональные лимиты пользователя
+// @Description  Возвращает персональные лимиты, назначенные поверх плана, в том числе истёкшие (см. expires_at).
+// @Tags         Admin
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        id             path    int     true  "ID пользователя"
+// @Success      200            {object}  domain.LimitOverride  "Персональные лимиты"
+// @Failure      400            {object}  map[string]string     "Некорректный ID пользователя"
+// @Failure      403            {object}  map[string]string     "Нет роли admin"
+// @Failure      404            {object}  map[string]string     "Персональных лимитов нет"
+// @Failure      500            {object}  map[string]string     "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/limits [get]
+func (h *AdminHandler) GetLimitOverride(c *gin.Context) {
+	const op = "location internal.handler.admin_handler.GetLimitOverride"
+
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	o, err := h.quotaService.GetLimitOverride(c, userID)
+	if err != nil {
+		writeAdminError(c, err, op)
+		return
+	}
+	c.JSON(http.StatusOK, o)
+}
+
+// SetLimitOverride задаёт персональные лимиты пользователя
+// @Summary      Назначение персональных лимитов
+// @Description  Задаёт лимиты поверх плана до expires_at, заменяя предыдущие. Не переданные лимиты берутся из плана.
+// @Description  Персональные лимиты сохраняются при смене плана. Если новый лимит хранилища покрывает занятое место, read-only снимается, иначе начинается grace-период.
+// @Tags         Admin
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string                true  "Bearer {token}"
+// @Param        id             path    int                   true  "ID пользователя"
+// @Param        request        body    dto.LimitOverrideReq  true  "Лимиты, срок и причина"
+// @Success      200            {object}  domain.LimitOverride  "Персональные лимиты"
+// @Failure      400            {object}  map[string]string     "Нет ни одного лимита или срок в прошлом"
+// @Failure      403            {object}  map[string]string     "Нет роли admin"
+// @Failure      404            {object}  map[string]string     "У пользователя нет активного плана"
+// @Failure      500            {object}  map[string]string     "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/limits [put]
+func (h *AdminHandler) SetLimitOverride(c *gin.Context) {
+	const op = "location internal.handler.admin_handler.SetLimitOverride"
+
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	var req dto.LimitOverrideReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminID, _ := utils.GetUserID(c)
+	o, err := h.quotaService.SetLimitOverride(c, adminID, domain.LimitOverride{
+		UserID:       userID,
+		StorageLimit: req.StorageLimit,
+		MaxFileSize:  req.MaxFileSize,
+		MaxFiles:     req.MaxFiles,
+		EgressLimit:  req.EgressLimit,
+		RequestLimit: req.RequestLimit,
+		ExpiresAt:    req.ExpiresAt,
+		Reason:       req.Reason,
+	})
+	if err != nil {
+		writeAdminError(c, err, op)
+		return
+	}
+	logrus.Infof("admin %d set limit override for user %d until %s", adminID, userID, o.ExpiresAt)
+	c.JSON(http.StatusOK, o)
+}
+
+// RemoveLimitOverride снимает персональные лимиты пользователя
+// @Summary      Снятие персональных лимитов
+// @Description  Удаляет персональные лимиты: снова действуют лимиты плана. Если занято больше лимита плана, начинается grace-период.
+// @Tags         Admin
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        id             path    int     true  "ID пользователя"
+// @Success      204            "Лимиты сняты"
+// @Failure      400            {object}  map[string]string  "Некорректный ID пользователя"
+// @Failure      403            {object}  map[string]string  "Нет роли admin"
+// @Failure      404            {object}  map[string]string  "Персональных лимитов нет"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/limits [delete]
+func (h *AdminHandler) RemoveLimitOverride(c *gin.Context) {
+	const op = "location internal.handler.admin_handler.RemoveLimitOverride"
+
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	adminID, _ := utils.GetUserID(c)
+	if err := h.quotaService.RemoveLimitOverride(c, adminID, userID); err != nil {
+		writeAdminError(c, err, op)
+		return
+	}
+	logrus.Infof("admin %d removed limit override of user %d", adminID, userID)
+	c.Status(http.StatusNoContent)
+}
+
+// GetUserHistory возвращает историю изменений пользователя через админское API
+// @Summary      История изменений пользователя
+// @Description  Записи журнала админского API по пользователю от новых к старым: назначения тарифа и персональных лимитов.
+// @Description  Для следующей страницы передайте before_id = id последней полученной записи.
+// @Tags         Admin
+// @Produce      json
+// @Param        Authorization  header  string  true   "Bearer {token}"
+// @Param        id             path    int     true   "ID пользователя"
+// @Param        before_id      query   int     false  "Вернуть записи с id меньше этого"
+// @Param        limit          query   int     false  "Размер страницы, по умолчанию 50, максимум 500"
+// @Success      200            {array}   domain.AdminAuditEntry  "Записи журнала"
+// @Failure      400            {object}  map[string]string       "Некорректные параметры"
+// @Failure      403            {object}  map[string]string       "Нет роли admin"
+// @Failure      500            {object}  map[string]string       "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/users/{id}/history [get]
+func (h *AdminHandler) GetUserHistory(c *gin.Context) {
+	userID, ok := userIDParam(c)
+	if !ok {
+		return
+	}
+	h.listAudit(c, domain.AuditTargetUser, strconv.Itoa(userID))
+}
+
+// GetAudit возвращает журнал админского API
+// @Summary      Журнал изменений
+// @Description  Все изменения, сделанные через админское API, от новых к старым. Фильтры target_type (plan, user) и target_id (имя тарифа или ID пользователя) необязательны.
+// @Tags         Admin
+// @Produce      json
+// @Param        Authorization  header  string  true   "Bearer {token}"
+// @Param        target_type    query   string  false  "plan или user"
+// @Param        target_id      query   string  false  "Имя тарифа или ID пользователя"
+// @Param        before_id      query   int     false  "Вернуть записи с id меньше этого"
+// @Param        limit          query   int     false  "Размер страницы, по умолчанию 50, максимум 500"
+// @Success      200            {array}   domain.AdminAuditEntry  "Записи журнала"
+// @Failure      400            {object}  map[string]string       "Некорректные параметры"
+// @Failure      403            {object}  map[string]string       "Нет роли admin"
+// @Failure      500            {object}  map[string]string       "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /admin/audit [get]
+func (h *AdminHandler) GetAudit(c *gin.Context) {
+	targetType := c.Query("target_type")
+	if targetType != "" && targetType != domain.AuditTargetPlan && targetType != domain.AuditTargetUser {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "target_type must be plan or user"})
+		return
+	}
+	h.listAudit(c, targetType, c.Query("target_id"))
+}
+
+func (h *AdminHandler) listAudit(c *gin.Context, targetType, targetID string) {
+	var beforeID int64
+	if v := c.Query("before_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before_id must be a positive integer"})
+			return
+		}
+		beforeID = id
+	}
+
+	limit := auditDefaultLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > auditMaxLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.quotaService.ListAdminAudit(c, targetType, targetID, beforeID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+// userIDParam разбирает :id пользователя, при ошибке сам отвечает 400
+func userIDParam(c *gin.Context) (int, bool) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || userID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return 0, false
+	}
+	return userID, true
+}
@@ -0,0 +1,128 @@
+package admin_handler
+
+import (
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAssignPlan_InvalidUserID(t *testing.T) {
+	h, mock := newTestHandler(t)
+	c, w := newTestContext(http.MethodPost, "/admin/users/abc/plan", `{"plan_name":"pro","reason":"support"}`)
+	c.Params = gin.Params{{Key: "id", Value: "abc"}}
+
+	h.AssignPlan(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+	checkMock(t, mock)
+}
+
+// Причина обязательна: без неё в журнале не понять, зачем назначен план
+func TestAssignPlan_ReasonRequired(t *testing.T) {
+	h, mock := newTestHandler(t)
+	c, w := newTestContext(http.MethodPost, "/admin/users/7/plan", `{"plan_name":"pro"}`)
+	c.Params = gin.Params{{Key: "id", Value: "7"}}
+
+	h.AssignPlan(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+	checkMock(t, mock)
+}
+
+func TestGetLimitOverride_NotFound(t *testing.T) {
+	h, mock := newTestHandler(t)
+	mock.ExpectQuery(`FROM user_limit_overrides WHERE user_id = \$1`).
+		WithArgs(7).
+		WillReturnError(sql.ErrNoRows)
+	c, w := newTestContext(http.MethodGet, "/admin/users/7/limits", "")
+	c.Params = gin.Params{{Key: "id", Value: "7"}}
+
+	h.GetLimitOverride(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+	checkMock(t, mock)
+}
+
+func TestSetLimitOverride_ExpiredRejected(t *testing.T) {
+	h, mock := newTestHandler(t)
+	body := `{"storage_limit":1024,"expires_at":"` + time.Now().Add(-time.Hour).Format(time.RFC3339) + `","reason":"trial"}`
+	c, w := newTestContext(http.MethodPut, "/admin/users/7/limits", body)
+	c.Params = gin.Params{{Key: "id", Value: "7"}}
+
+	h.SetLimitOverride(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+	checkMock(t, mock)
+}
+
+func TestRemoveLimitOverride_NotFound(t *testing.T) {
+	h, mock := newTestHandler(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM user_limit_overrides`).
+		WithArgs(7).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	c, w := newTestContext(http.MethodDelete, "/admin/users/7/limits", "")
+	c.Params = gin.Params{{Key: "id", Value: "7"}}
+
+	h.RemoveLimitOverride(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+	checkMock(t, mock)
+}
+
+func TestGetUserHistory_FiltersByUser(t *testing.T) {
+	h, mock := newTestHandler(t)
+	mock.ExpectQuery(`FROM admin_audit_log`).
+		WithArgs(domain.AuditTargetUser, "7", int64(40), 10).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "admin_id", "action", "target_type", "target_id", "details", "created_at"}).
+			AddRow(39, 1, domain.AuditUserAssignPlan, domain.AuditTargetUser, "7", []byte(`{"to_plan":"pro"}`), time.Now()))
+	c, w := newTestContext(http.MethodGet, "/admin/users/7/history?before_id=40&limit=10", "")
+	c.Params = gin.Params{{Key: "id", Value: "7"}}
+
+	h.GetUserHistory(c)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	checkMock(t, mock)
+}
+
+func TestGetAudit_InvalidParams(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+	}{
+		{name: "target type", target: "/admin/audit?target_type=org"},
+		{name: "limit", target: "/admin/audit?limit=501"},
+		{name: "before id", target: "/admin/audit?before_id=-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, mock := newTestHandler(t)
+			c, w := newTestContext(http.MethodGet, tt.target, "")
+
+			h.GetAudit(c)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", w.Code)
+			}
+			checkMock(t, mock)
+		})
+	}
+}
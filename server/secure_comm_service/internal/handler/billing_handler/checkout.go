@@ -0,0 +1,231 @@
+package billing_handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/1abobik1/SecureComm/internal/billing"
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/1abobik1/SecureComm/internal/service/billing_service"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// maxWebhookBody — вебхуки провайдеров маленькие, больше этого не читаем
+const maxWebhookBody = 64 << 10
+
+// CreateCheckout начинает оплату тарифа
+// @Summary      Оплата тарифа
+// @Description  Создаёт платёжную сессию у провайдера и возвращает checkout_url, на который нужно отправить пользователя.
+// @Description  План подключается (или текущий продлевается на период) только после подписанного вебхука об успешной оплате.
+// @Tags         Billing
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string           true  "Bearer {token}"
+// @Param        id             path    int              true  "ID пользователя"
+// @Param        request        body    dto.CheckoutReq  true  "Имя тарифа"
+// @Success      201            {object}  domain.CheckoutSession  "Платёжная сессия"
+// @Failure      400            {object}  map[string]string       "Некорректный запрос или бесплатный план"
+// @Failure      403            {object}  map[string]string       "Чужой ID пользователя"
+// @Failure      404            {object}  map[string]string       "План не найден или нет активного плана"
+// @Failure      409            {object}  map[string]string       "Занято больше лимита выбранного плана"
+// @Failure      500            {object}  map[string]string       "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /user/{id}/plan/checkout [post]
+func (h *BillingHandler) CreateCheckout(c *gin.Context) {
+	const op = "location internal.handler.billing_handler.CreateCheckout"
+
+	userID, ok := utils.OwnUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.CheckoutReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.billingService.CreateCheckout(c, userID, req.PlanName, req.AutoRenew)
+	if err != nil {
+		writeCheckoutError(c, err, op)
+		return
+	}
+
+	logrus.Infof("user %d started checkout %s for plan %s", userID, session.ID, session.PlanName)
+	c.JSON(http.StatusCreated, session)
+}
+
+// CreateOrgCheckout начинает оплату тарифа организации
+// @Summary      Оплата тарифа организации
+// @Description  То же, что /user/{id}/plan/checkout, но оплаченный план подключается пулу организации. Доступно owner и admin.
+// @Description  Статус сессии проверяется через /user/{id}/plan/checkout/{session_id} пользователя, который начал оплату.
+// @Tags         Billing
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string           true  "Bearer {token}"
+// @Param        org_id         path    int              true  "ID организации"
+// @Param        request        body    dto.CheckoutReq  true  "Имя тарифа"
+// @Success      201            {object}  domain.CheckoutSession  "Платёжная сессия"
+// @Failure      400            {object}  map[string]string       "Некорректный запрос или бесплатный план"
+// @Failure      403            {object}  map[string]string       "Недостаточно прав в организации"
+// @Failure      404            {object}  map[string]string       "Организация или план не найдены"
+// @Failure      409            {object}  map[string]string       "Занято больше лимита выбранного плана"
+// @Failure      500            {object}  map[string]string       "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs/{org_id}/plan/checkout [post]
+func (h *BillingHandler) CreateOrgCheckout(c *gin.Context) {
+	const op = "location internal.handler.billing_handler.CreateOrgCheckout"
+
+	orgID, userID, ok := utils.OrgManager(c, h.orgService)
+	if !ok {
+		return
+	}
+
+	var req dto.CheckoutReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.billingService.CreateOrgCheckout(c, orgID, userID, req.PlanName, req.AutoRenew)
+	if err != nil {
+		writeCheckoutError(c, err, op)
+		return
+	}
+
+	logrus.Infof("user %d started checkout %s for plan %s of org %d", userID, session.ID, session.PlanName, orgID)
+	c.JSON(http.StatusCreated, session)
+}
+
+func writeCheckoutError(c *gin.Context, err error, op string) {
+	switch {
+	case errors.Is(err, quota_service.ErrPlanNotFound), errors.Is(err, quota_service.ErrNoActivePlan):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, billing_service.ErrFreePlanCheckout):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, quota_service.ErrDowngradeOverLimit):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logrus.Errorf("Error: %v, %s", err, op)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// GetCheckout возвращает статус платёжной сессии
+// @Summary      Статус оплаты
+// @Description  Возвращает платёжную сессию пользователя: pending, paid, failed или expired.
+// @Tags         Billing
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        id             path    int     true  "ID пользователя"
+// @Param        session_id     path    string  true  "ID платёжной сессии"
+// @Success      200            {object}  domain.CheckoutSession  "Платёжная сессия"
+// @Failure      403            {object}  map[string]string       "Чужой ID пользователя"
+// @Failure      404            {object}  map[string]string       "Сессия не найдена"
+// @Failure      500            {object}  map[string]string       "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /user/{id}/plan/checkout/{session_id} [get]
+func (h *BillingHandler) GetCheckout(c *gin.Context) {
+	userID, ok := utils.OwnUserID(c)
+	if !ok {
+		return
+	}
+
+	session, err := h.billingService.GetCheckout(c, userID, c.Param("session_id"))
+	if err != nil {
+		if errors.Is(err, billing_service.ErrCheckoutNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// Webhook принимает события платёжного провайдера
+// @Summary      Вебхук платёжного провайдера
+// @Description  Проверяет подпись, записывает событие в журнал (повторная доставка игнорируется) и подключает оплаченный план.
+// @Description  Не требует токена: подлинность подтверждается подписью провайдера.
+// @Tags         Billing
+// @Accept       json
+// @Produce      json
+// @Param        provider  path  string  true  "Имя провайдера"
+// @Success      200  {object}  map[string]interface{}  "Событие принято"
+// @Failure      400  {object}  map[string]string       "Некорректная подпись или тело"
+// @Failure      404  {object}  map[string]string       "Неизвестный провайдер"
+// @Failure      500  {object}  map[string]string       "Внутренняя ошибка, провайдер повторит доставку"
+// @Router       /billing/webhook/{provider} [post]
+func (h *BillingHandler) Webhook(c *gin.Context) {
+	const op = "location internal.handler.billing_handler.Webhook"
+
+	if c.Param("provider") != h.billingService.ProviderName() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown payment provider"})
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unable to read the request body"})
+		return
+	}
+
+	duplicate, err := h.billingService.HandleWebhook(c, payload, c.Request.Header)
+	if err != nil {
+		if errors.Is(err, billing.ErrInvalidSignature) || errors.Is(err, billing.ErrStaleWebhook) || errors.Is(err, billing.ErrInvalidPayload) {
+			logrus.Warnf("rejected webhook: %v, %s", err, op)
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		logrus.Errorf("Error: %v, %s", err, op)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "unable to process the event"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"received": true, "duplicate": duplicate})
+}
+
+// FakePay — страница оплаты встроенного фейкового провайдера
+// @Summary      Оплата через фейкового провайдера
+// @Description  Доступна только при BILLING_PROVIDER=fake и BILLING_ALLOW_FAKE=true (среда разработки). Формирует подписанный
+// @Description  вебхук об оплате (или отказе при result=failed) и обрабатывает его так же, как вебхук настоящего провайдера.
+// @Description  Оплатить можно только свою платёжную сессию.
+// @Tags         Billing
+// @Produce      json
+// @Param        Authorization  header  string  true   "Bearer {token}"
+// @Param        session_id     path    string  true   "ID сессии у провайдера из checkout_url"
+// @Param        result         query   string  false  "succeeded (по умолчанию) или failed"
+// @Success      200  {object}  map[string]string  "Итоговый статус платёжной сессии"
+// @Failure      401  {object}  map[string]string  "Нет user_id в токене"
+// @Failure      404  {object}  map[string]string  "Сессия не найдена или принадлежит другому пользователю"
+// @Failure      500  {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /billing/fake/pay/{session_id} [get]
+func (h *BillingHandler) FakePay(c *gin.Context) {
+	const op = "location internal.handler.billing_handler.FakePay"
+
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		logrus.Errorf("invalid token: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	status, err := h.billingService.SimulatePayment(c, userID, c.Param("session_id"), c.Query("result") != "failed")
+	if err != nil {
+		switch {
+		case errors.Is(err, billing_service.ErrCheckoutNotFound), errors.Is(err, billing_service.ErrNotSupportedByBilling):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			logrus.Errorf("Error: %v, %s", err, op)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": status})
+}
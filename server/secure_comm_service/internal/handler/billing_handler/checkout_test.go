@@ -0,0 +1,108 @@
+package billing_handler
+
+import (
+	"bytes"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/billing"
+	"github.com/1abobik1/SecureComm/internal/service/billing_service"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func newTestHandler(t *testing.T) (*BillingHandler, *billing.FakeProvider, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	provider := billing.NewFakeProvider("test-secret", "http://localhost:8080", 5*time.Minute)
+	service := billing_service.NewBillingServiceForTesting(db, provider, quota_service.NewQuotaServiceForTesting(db), "RUB")
+	return NewBillingHandler(service, nil), provider, mock
+}
+
+func newTestContext(method, target string, body []byte, userID int) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, target, bytes.NewReader(body))
+	if userID != 0 {
+		c.Set("claims", jwt.MapClaims{"user_id": float64(userID)})
+	}
+	return c, w
+}
+
+func TestWebhook_UnknownProvider(t *testing.T) {
+	h, _, mock := newTestHandler(t)
+	c, w := newTestContext(http.MethodPost, "/billing/webhook/stripe", []byte(`{}`), 0)
+	c.Params = gin.Params{{Key: "provider", Value: "stripe"}}
+
+	h.Webhook(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestWebhook_BadSignature(t *testing.T) {
+	h, provider, _ := newTestHandler(t)
+	payload, _, err := provider.BuildWebhook(billing.EventPaymentSucceeded, "fake_cs_1", 29900, "RUB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, w := newTestContext(http.MethodPost, "/billing/webhook/fake", payload, 0)
+	c.Params = gin.Params{{Key: "provider", Value: "fake"}}
+	c.Request.Header.Set(billing.FakeSignatureHeader, "t=1,v1=00")
+
+	h.Webhook(c)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
+
+func TestFakePay_RequiresToken(t *testing.T) {
+	h, _, mock := newTestHandler(t)
+	c, w := newTestContext(http.MethodGet, "/billing/fake/pay/fake_cs_1", nil, 0)
+	c.Params = gin.Params{{Key: "session_id", Value: "fake_cs_1"}}
+
+	h.FakePay(c)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("status = %d, want 401", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+// Сессию пользователя 7 не может оплатить пользователь 8: она для него не существует
+func TestFakePay_OtherUsersSession(t *testing.T) {
+	h, _, mock := newTestHandler(t)
+	mock.ExpectQuery(`SELECT amount_cents, currency FROM checkout_sessions`).
+		WithArgs("fake", "fake_cs_1", 8).
+		WillReturnError(sql.ErrNoRows)
+
+	c, w := newTestContext(http.MethodGet, "/billing/fake/pay/fake_cs_1", nil, 8)
+	c.Params = gin.Params{{Key: "session_id", Value: "fake_cs_1"}}
+
+	h.FakePay(c)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,15 @@
+package billing_handler
+
+import (
+	"github.com/1abobik1/SecureComm/internal/service/billing_service"
+	"github.com/1abobik1/SecureComm/internal/service/org_service"
+)
+
+type BillingHandler struct {
+	billingService *billing_service.BillingService
+	orgService     *org_service.OrgService
+}
+
+func NewBillingHandler(billingService *billing_service.BillingService, orgService *org_service.OrgService) *BillingHandler {
+	return &BillingHandler{billingService: billingService, orgService: orgService}
+}
@@ -0,0 +1,174 @@
+package cloud_handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/1abobik1/SecureComm/internal/service/cloud_service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// максимальный размер тела запроса /files/archive: MaxArchiveObjects идентификаторов с запасом
+const maxArchiveRequestBytes = 1 << 20
+
+// GetArchive отдаёт выбранные файлы одним архивом
+// @Summary      Скачивание нескольких файлов одним архивом
+// @Description  Стримит zip или tar с зашифрованными файлами (шифртекст как есть, без перешифрования) без буферизации на сервере.
+// @Description  Последним файлом архива идёт manifest.json с оригинальными именами (base64), MIME-типами, размерами и sha256 каждого файла.
+// @Description  Если object_ids пуст, в архив попадают все файлы пользователя из category. В одном архиве не больше 1000 файлов.
+// @Tags         Files
+// @Accept       json
+// @Produce      application/zip
+// @Produce      application/x-tar
+// @Param        Authorization header string true "Bearer {token}"
+// @Param        request body dto.ArchiveReq true "Формат архива и список файлов или категория"
+// @Success      200  {file}    file           "Архив с файлами и manifest.json"
+// @Failure      400  {object}  ErrorResponse  "Некорректный запрос или больше 1000 файлов"
+// @Failure      403  {object}  dto.LimitExceededErr  "Доступ запрещён или исчерпан месячный трафик"
+// @Failure      404  {object}  ErrorResponse  "Файл не найден"
+// @Failure      500  {object}  ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     bearerAuth
+// @Router       /files/archive [post]
+func (h *MinioHandler) GetArchive(c *gin.Context) {
+	const op = "location internal.handler.minio_handler.GetArchive"
+
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		logrus.Errorf("Errors: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the user's ID was not found in the token."})
+		return
+	}
+
+	var req dto.ArchiveReq
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxArchiveRequestBytes)
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logrus.Errorf("Error: %v,  %s", err, op)
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Status:  http.StatusBadRequest,
+			Error:   "Invalid request body",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if req.Format == "" {
+		req.Format = cloud_service.ArchiveFormatZip
+	}
+	if req.Format != cloud_service.ArchiveFormatZip && req.Format != cloud_service.ArchiveFormatTar {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: http.StatusBadRequest,
+			Error:  cloud_service.ErrInvalidArchiveFormat.Error(),
+		})
+		return
+	}
+	if len(req.ObjectIDs) > cloud_service.MaxArchiveObjects {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: http.StatusBadRequest,
+			Error:  cloud_service.ErrTooManyArchiveObjects.Error(),
+		})
+		return
+	}
+	if len(req.ObjectIDs) == 0 && req.Category != "photo" && req.Category != "unknown" && req.Category != "video" && req.Category != "text" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: http.StatusBadRequest,
+			Error:  "either object_ids or category {photo, unknown, video, text} must be passed",
+		})
+		return
+	}
+
+	entries, err := h.minioService.PrepareArchive(c.Request.Context(), req, userID)
+	if err != nil {
+		logrus.Errorf("Error: %v,  %s", err, op)
+
+		if errors.Is(err, cloud_service.ErrEmptyArchive) {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Status:  http.StatusNotFound,
+				Error:   "Files not found",
+				Details: err.Error(),
+			})
+			return
+		}
+
+		if errors.Is(err, cloud_service.ErrTooManyArchiveObjects) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Status: http.StatusBadRequest,
+				Error:  cloud_service.ErrTooManyArchiveObjects.Error(),
+			})
+			return
+		}
+
+		if errors.Is(err, cloud_service.ErrFileNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Status:  http.StatusNotFound,
+				Error:   "File not found",
+				Details: err.Error(),
+			})
+			return
+		}
+
+		if errors.Is(err, cloud_service.ErrForbiddenResource) {
+			c.JSON(http.StatusForbidden, ErrorResponse{
+				Status:  http.StatusForbidden,
+				Error:   "access to the requested resource is prohibited",
+				Details: err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status:  http.StatusInternalServerError,
+			Error:   "Unable to prepare the archive",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// архив отдаётся мимо подписанных ссылок, поэтому трафик резервируется здесь
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+	period, err := h.quotaService.TryAddEgress(c.Request.Context(), userID, total)
+	if err != nil {
+		logrus.Errorf("Error: %v,  %s", err, op)
+		if utils.WriteLimitExceeded(c, http.StatusForbidden, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status:  http.StatusInternalServerError,
+			Error:   "egress check failed",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	contentType := "application/zip"
+	if req.Format == cloud_service.ArchiveFormatTar {
+		contentType = "application/x-tar"
+	}
+	fileName := fmt.Sprintf("securecomm-%s.%s", time.Now().UTC().Format("20060102-150405"), req.Format)
+
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
+	c.Status(http.StatusOK)
+
+	// после начала стриминга статус уже отправлен, поэтому ошибку можно только залогировать
+	cw := &countingResponseWriter{ResponseWriter: c.Writer}
+	err = h.minioService.WriteArchive(c.Request.Context(), cw, req.Format, entries)
+
+	// при обрыве возвращаем неотданную часть резерва (заголовки архива в трафик не считаются)
+	if refundErr := h.quotaService.RefundEgress(context.WithoutCancel(c.Request.Context()), userID, period, total-cw.written); refundErr != nil {
+		logrus.Errorf("RefundEgress user %d: %v, %s", userID, refundErr, op)
+	}
+	if err != nil {
+		logrus.Errorf("Error: %v,  %s", err, op)
+		c.Abort()
+		return
+	}
+}
@@ -0,0 +1,113 @@
+package cloud_handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/1abobik1/SecureComm/internal/service/cloud_service"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// archiveClient подменяет только PrepareArchive; остальные методы cloud_service.Client в тестах не вызываются
+type archiveClient struct {
+	cloud_service.Client
+	prepared int
+	err      error
+}
+
+func (a *archiveClient) PrepareArchive(ctx context.Context, req dto.ArchiveReq, userID int) ([]cloud_service.ArchiveEntry, error) {
+	a.prepared++
+	return nil, a.err
+}
+
+func postArchive(t *testing.T, h *MinioHandler, body []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/files/archive", bytes.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Set("claims", jwt.MapClaims{"user_id": float64(7)})
+
+	h.GetArchive(c)
+	return w
+}
+
+func archiveBody(t *testing.T, n int) []byte {
+	t.Helper()
+	req := dto.ArchiveReq{Format: "zip"}
+	for i := 0; i < n; i++ {
+		req.ObjectIDs = append(req.ObjectIDs, dto.ObjectID{ObjID: "7/" + strconv.Itoa(i), FileCategory: "photo"})
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestGetArchive_TooManyObjects(t *testing.T) {
+	client := &archiveClient{}
+	h := &MinioHandler{minioService: client}
+
+	w := postArchive(t, h, archiveBody(t, cloud_service.MaxArchiveObjects+1))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if client.prepared != 0 {
+		t.Errorf("PrepareArchive called %d times, want 0", client.prepared)
+	}
+}
+
+func TestGetArchive_BodyTooLarge(t *testing.T) {
+	client := &archiveClient{}
+	h := &MinioHandler{minioService: client}
+
+	body := append([]byte(`{"format":"zip","category":"`), bytes.Repeat([]byte("a"), maxArchiveRequestBytes)...)
+	body = append(body, []byte(`"}`)...)
+	w := postArchive(t, h, body)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if client.prepared != 0 {
+		t.Errorf("PrepareArchive called %d times, want 0", client.prepared)
+	}
+}
+
+func TestGetArchive_CategoryTooLarge(t *testing.T) {
+	client := &archiveClient{err: cloud_service.ErrTooManyArchiveObjects}
+	h := &MinioHandler{minioService: client}
+
+	w := postArchive(t, h, []byte(`{"format":"zip","category":"photo"}`))
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if client.prepared != 1 {
+		t.Errorf("PrepareArchive called %d times, want 1", client.prepared)
+	}
+}
+
+func TestGetArchive_MaxObjectsAllowed(t *testing.T) {
+	client := &archiveClient{err: cloud_service.ErrFileNotFound}
+	h := &MinioHandler{minioService: client}
+
+	w := postArchive(t, h, archiveBody(t, cloud_service.MaxArchiveObjects))
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+	if client.prepared != 1 {
+		t.Errorf("PrepareArchive called %d times, want 1", client.prepared)
+	}
+}
@@ -0,0 +1,136 @@
+package cloud_handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// Download отдаёт файл по подписанной ссылке
+// @Summary      Скачивание файла по ссылке
+// @Description  Ссылки из поля url ответов /files/* ведут сюда. Подпись и срок действия проверяются сервисом, токен не нужен.
+// @Description  Отданные байты засчитываются в месячный трафик владельца файла; при исчерпании egress_limit плана возвращается 403.
+// @Description  Поддерживаются Range-запросы, в трафик идут только фактически отправленные байты.
+// @Tags         Files
+// @Produce      application/octet-stream
+// @Param        bucket     query  string  true  "Категория файла"
+// @Param        id         query  string  true  "ID объекта"
+// @Param        expires    query  int     true  "Срок действия ссылки, unix-время"
+// @Param        signature  query  string  true  "Подпись ссылки"
+// @Success      200  {file}    file           "Шифртекст файла"
+// @Success      206  {file}    file           "Часть шифртекста файла"
+// @Failure      403  {object}  ErrorResponse  "Недействительная или просроченная ссылка, исчерпан трафик"
+// @Failure      404  {object}  ErrorResponse  "Файл не найден"
+// @Failure      429  {object}  dto.LimitExceededErr  "Исчерпан месячный лимит запросов"
+// @Failure      500  {object}  ErrorResponse  "Внутренняя ошибка сервера"
+// @Router       /files/download [get]
+func (h *MinioHandler) Download(c *gin.Context) {
+	const op = "location internal.handler.minio_handler.Download"
+
+	bucket, objID := c.Query("bucket"), c.Query("id")
+	if err := h.minioService.VerifyDownloadLink(bucket, objID, c.Query("expires"), c.Query("signature")); err != nil {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Status: http.StatusForbidden,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	obj, info, err := h.minioService.OpenDownload(c.Request.Context(), bucket, objID)
+	if err != nil {
+		logrus.Errorf("Error: %v,  %s", err, op)
+		writeFileError(c, err, "Unable to download the object")
+		return
+	}
+	defer obj.Close()
+
+	if err := h.quotaService.CountRequest(c.Request.Context(), info.OwnerID); errors.Is(err, quota_service.ErrRequestLimitExceeded) {
+		utils.WriteLimitExceeded(c, http.StatusTooManyRequests, err)
+		return
+	}
+
+	// резервируем заранее столько, сколько будет отдано по Range, лишнее вернём после отдачи
+	reserved := egressReservation(c.Request, info.Size)
+	period, err := h.quotaService.TryAddEgress(c.Request.Context(), info.OwnerID, reserved)
+	if err != nil {
+		logrus.Errorf("Error: %v,  %s", err, op)
+		if utils.WriteLimitExceeded(c, http.StatusForbidden, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status:  http.StatusInternalServerError,
+			Error:   "egress check failed",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	cw := &countingResponseWriter{ResponseWriter: c.Writer}
+	c.Header("Content-Type", "application/octet-stream")
+	http.ServeContent(cw, c.Request, path.Base(objID), info.LastModified, obj)
+
+	// контекст запроса мог быть отменён обрывом соединения, а вернуть резерв нужно всё равно
+	if err := h.quotaService.RefundEgress(context.WithoutCancel(c.Request.Context()), info.OwnerID, period, reserved-cw.written); err != nil {
+		logrus.Errorf("RefundEgress user %d: %v, %s", info.OwnerID, err, op)
+	}
+}
+
+// egressReservation возвращает, сколько байт тела отдаст http.ServeContent на этот запрос.
+// Точно считается только один диапазон; несколько диапазонов, If-Range и некорректный Range резервируют весь файл.
+func egressReservation(r *http.Request, size int64) int64 {
+	header := r.Header.Get("Range")
+	if header == "" || r.Header.Get("If-Range") != "" {
+		return size
+	}
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if !ok || strings.Contains(spec, ",") {
+		return size
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return size
+	}
+	first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+
+	if first == "" {
+		// суффикс: последние n байт
+		n, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || n <= 0 {
+			return size
+		}
+		return min(n, size)
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return size
+	}
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return size
+		}
+		end = min(end, size-1)
+	}
+	return end - start + 1
+}
+
+// countingResponseWriter считает байты тела ответа, фактически отданные клиенту
+type countingResponseWriter struct {
+	http.ResponseWriter
+	written int64
+}
+
+func (w *countingResponseWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.written += int64(n)
+	return n, err
+}
@@ -0,0 +1,43 @@
+package cloud_handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEgressReservation(t *testing.T) {
+	const size = 1000
+	tests := []struct {
+		name    string
+		rng     string
+		ifRange string
+		want    int64
+	}{
+		{name: "no range", want: size},
+		{name: "closed range", rng: "bytes=0-99", want: 100},
+		{name: "open range", rng: "bytes=900-", want: 100},
+		{name: "suffix", rng: "bytes=-10", want: 10},
+		{name: "suffix longer than file", rng: "bytes=-5000", want: size},
+		{name: "end past file", rng: "bytes=990-5000", want: 10},
+		{name: "start past file", rng: "bytes=1000-", want: size},
+		{name: "multiple ranges", rng: "bytes=0-9,20-29", want: size},
+		{name: "if-range", rng: "bytes=0-9", ifRange: `"etag"`, want: size},
+		{name: "reversed", rng: "bytes=50-10", want: size},
+		{name: "other unit", rng: "items=0-9", want: size},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/files/download", nil)
+			if tt.rng != "" {
+				r.Header.Set("Range", tt.rng)
+			}
+			if tt.ifRange != "" {
+				r.Header.Set("If-Range", tt.ifRange)
+			}
+			if got := egressReservation(r, size); got != tt.want {
+				t.Errorf("egressReservation(%q) = %d, want %d", tt.rng, got, tt.want)
+			}
+		})
+	}
+}
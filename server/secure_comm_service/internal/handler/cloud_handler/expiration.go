@@ -0,0 +1,76 @@
+package cloud_handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/1abobik1/SecureComm/internal/service/cloud_service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// SetExpiration меняет срок жизни файла
+// @Summary      Изменение срока жизни файла
+// @Description  Задаёт момент автоматического удаления файла через expires_at (RFC3339) или expires_in (секунды, не больше 365 дней).
+// @Description  Если оба поля пустые, срок жизни снимается и файл становится бессрочным.
+// @Tags         Files
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {token}"
+// @Param        request body dto.FileExpirationReq true "Файл и новый срок жизни"
+// @Success      200  {object}  dto.FileResponse  "Файл с обновлённым сроком жизни"
+// @Failure      400  {object}  ErrorResponse  "Некорректный запрос"
+// @Failure      403  {object}  ErrorResponse  "Доступ запрещён"
+// @Failure      404  {object}  ErrorResponse  "Файл не найден или уже истёк"
+// @Failure      500  {object}  ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     bearerAuth
+// @Router       /files/one/expiration [patch]
+func (h *MinioHandler) SetExpiration(c *gin.Context) {
+	const op = "location internal.handler.minio_handler.SetExpiration"
+
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		logrus.Errorf("Errors: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the user's ID was not found in the token."})
+		return
+	}
+
+	var req dto.FileExpirationReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logrus.Errorf("Error: %v,  %s", err, op)
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Status:  http.StatusBadRequest,
+			Error:   "Invalid request body",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	expiresAt, err := cloud_service.ParseExpiration(req.ExpiresAt, req.ExpiresIn, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: http.StatusBadRequest,
+			Error:  err.Error(),
+		})
+		return
+	}
+
+	fileResp, err := h.minioService.SetExpiration(c.Request.Context(), dto.ObjectID{ObjID: req.ObjID, FileCategory: req.FileCategory}, userID, expiresAt)
+	if err != nil {
+		logrus.Errorf("Error: %v,  %s", err, op)
+		if errors.Is(err, cloud_service.ErrInvalidExpiration) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Status: http.StatusBadRequest,
+				Error:  err.Error(),
+			})
+			return
+		}
+		writeFileError(c, err, "Unable to update the expiration")
+		return
+	}
+
+	c.JSON(http.StatusOK, fileResp)
+}
@@ -2,17 +2,20 @@ package cloud_handler
 
 import (
 	"github.com/1abobik1/SecureComm/internal/service/cloud_service"
+	"github.com/1abobik1/SecureComm/internal/service/org_service"
 	"github.com/1abobik1/SecureComm/internal/service/quota_service"
 )
 
 type MinioHandler struct {
 	minioService cloud_service.Client
 	quotaService *quota_service.QuotaService
+	orgService   *org_service.OrgService
 }
 
-func NewMinioHandler(minioService cloud_service.Client, quotaService *quota_service.QuotaService) *MinioHandler {
+func NewMinioHandler(minioService cloud_service.Client, quotaService *quota_service.QuotaService, orgService *org_service.OrgService) *MinioHandler {
 	return &MinioHandler{
 		minioService: minioService,
 		quotaService: quotaService,
+		orgService:   orgService,
 	}
 }
@@ -4,10 +4,12 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/1abobik1/SecureComm/internal/count_reader"
+	"github.com/1abobik1/SecureComm/internal/domain"
 	"github.com/1abobik1/SecureComm/internal/dto"
 	"github.com/1abobik1/SecureComm/internal/handler/utils"
 	"github.com/1abobik1/SecureComm/internal/service/cloud_service"
@@ -21,25 +23,33 @@ import (
 // Клиент должен передать:
 //   - Authorization: Bearer <token>
 //   - Content-Type: application/octet-stream
-//   - X-Orig-Filename: <имя файла, напр. photo.jpg>
-//   - X-Orig-Mime: <исходный mime, напр. image/jpeg>
+//   - X-Encrypted-Meta: <base64 blob, зашифрованный ключами сессии JSON {name, mime_type, tags}>
 //   - X-File-Category: <photo|video|text|unknown>
+//   - X-Expires-At: <RFC3339> или X-Expires-In: <секунды> — необязательно, файл удалится автоматически
+//
+// Загрузка с именем и MIME-типом в открытом виде (X-Orig-Filename + X-Orig-Mime) больше не принимается.
+// Уже загруженные так файлы продолжают отдаваться с открытым File_name, см. buildFileResponse.
 //
 // Тело запроса (body) — это уже полностью зашифрованный поток (будь-то AES-CBC+HMAC по чанкам).
 //
 // @Summary      Загрузка зашифрованного файла в MinIO(объектное хранилище) “на лету”
-// @Description  Токен авторизации + зашифрованный поток в body + метаданные в заголовках
+// @Description  Токен авторизации + зашифрованный поток в body + метаданные в заголовках.
+// @Description  Имя файла, MIME-тип и теги передаются зашифрованным blob-ом в X-Encrypted-Meta и хранятся сервером как есть.
 // @Tags         Files
 // @Accept       application/octet-stream
 // @Produce      json
-// @Param        Authorization     header string true "Bearer {token}"
-// @Param        X-Orig-Filename   header string true "Оригинальное имя файла (например photo.jpg)"
-// @Param        X-Orig-Mime       header string true "Оригинальный MIME-тип (например image/jpeg)"
-// @Param        X-File-Category   header string true "Категория файла (photo, video, text, unknown)"
+// @Param        Authorization     header string true  "Bearer {token}"
+// @Param        X-Encrypted-Meta  header string true  "Зашифрованные метаданные файла в base64 (не длиннее 1536 символов)"
+// @Param        X-File-Category   header string true  "Категория файла (photo, video, text, unknown)"
+// @Param        X-Expires-At      header string false "Когда удалить файл автоматически (RFC3339)"
+// @Param        X-Expires-In      header int    false "Через сколько секунд удалить файл автоматически (если нет X-Expires-At)"
 // @Param 		file body []byte true "Зашифрованный бинарный поток (application/octet-stream)"
 // @Success      200  {object}  dto.FileResponse  "Успешно: JSON с metadata + presigned URL"
 // @Failure      400  {object}  map[string]string "Некорректные заголовки или body"
 // @Failure      401  {object}  map[string]string "Проблема с авторизацией"
+// @Failure      403  {object}  dto.LimitExceededErr "Превышен лимит хранения или числа файлов, аккаунт read-only либо email не подтверждён"
+// @Failure      413  {object}  dto.LimitExceededErr "Файл больше max_file_size плана"
+// @Failure      429  {object}  dto.LimitExceededErr "Исчерпан месячный лимит запросов"
 // @Failure      500  {object}  map[string]string "Внутренняя ошибка сервера"
 // @Security     bearerAuth
 // @Router       /files/one/encrypted [post]
@@ -53,35 +63,79 @@ func (h *MinioHandler) CreateOneEncrypted(c *gin.Context) {
 		return
 	}
 
-	origNameB64 := c.GetHeader("X-Orig-Filename")
-	logrus.Infof("X-Orig-Filename: %v", origNameB64)
-	origNameByte, err := utils.Decode(origNameB64)
+	category := strings.ToLower(c.GetHeader("X-File-Category"))
+	if category != "photo" && category != "video" && category != "text" && category != "unknown" {
+		logrus.Error("invalid X-File-Category(video or text or unknown or photo)")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid X-File-Category(video or text or unknown or photo)"})
+		return
+	}
+
+	createdAt := time.Now().UTC()
+
+	var expiresIn int64
+	if h := c.GetHeader("X-Expires-In"); h != "" {
+		expiresIn, err = strconv.ParseInt(h, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "X-Expires-In must be a number of seconds"})
+			return
+		}
+	}
+	expiresAt, err := cloud_service.ParseExpiration(c.GetHeader("X-Expires-At"), expiresIn, createdAt)
 	if err != nil {
 		logrus.Error(err)
-		c.Status(http.StatusInternalServerError)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	origName := string(origNameByte)
 
-	origMime := c.GetHeader("X-Orig-Mime")
-	category := strings.ToLower(c.GetHeader("X-File-Category"))
-	if origName == "" || origMime == "" || category == "" {
-		logrus.Error("missing X-Orig-Filename/X-Orig-Mime/X-File-Category")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "missing X-Orig-Filename/X-Orig-Mime/X-File-Category"})
+	fileResp := dto.FileResponse{
+		Created_At: createdAt.Format(time.RFC3339),
+		Category:   category,
+	}
+
+	encMeta := c.GetHeader("X-Encrypted-Meta")
+	if encMeta == "" {
+		logrus.Error("missing X-Encrypted-Meta")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing X-Encrypted-Meta"})
 		return
 	}
-	if category != "photo" && category != "video" && category != "text" && category != "unknown" {
-		logrus.Error("invalid X-File-Category(video or text or unknown or photo)")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid X-File-Category(video or text or unknown or photo)"})
+	if _, err := utils.Decode(encMeta); err != nil {
+		logrus.Errorf("invalid X-Encrypted-Meta: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Encrypted-Meta must be base64"})
 		return
 	}
 
-	objID := cloud_service.GenerateFileID(userID, cloud_service.GetFileExtension(origName))
-	metadata := cloud_service.GenerateUserMetaData(userID, origName, time.Now().UTC())
+	metadata, err := cloud_service.GenerateEncryptedUserMetaData(userID, encMeta, createdAt)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// расширение и MIME-тип не раскрываются ни в ключе объекта, ни в Content-Type
+	objID := cloud_service.GenerateFileID(userID, "")
 	opts := minio.PutObjectOptions{
-		ContentType:  origMime,
+		ContentType:  "application/octet-stream",
 		UserMetadata: metadata,
 	}
+	fileResp.EncryptedMeta = encMeta
+
+	if expiresAt != nil {
+		opts.UserMetadata = cloud_service.WithExpiry(opts.UserMetadata, *expiresAt)
+		fileResp.ExpiresAt = expiresAt.Format(time.RFC3339)
+		fileResp.ExpiresIn = int64(expiresAt.Sub(createdAt) / time.Second)
+	}
+
+	// read-only аккаунт (после даунгрейда) не может загружать файлы — проверяем до приёма тела
+	if err := h.quotaService.CheckQuota(c, userID, 0); errors.Is(err, quota_service.ErrReadOnly) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.quotaService.CheckFileCount(c, userID); err != nil {
+		if utils.WriteLimitExceeded(c, http.StatusForbidden, err) {
+			return
+		}
+		logrus.Errorf("%s CheckFileCount: %v", op, err)
+	}
 
 	// оборачивает c.Request.Body в countReader
 	cr := count_reader.NewCountReader(c.Request.Body)
@@ -90,15 +144,31 @@ func (h *MinioHandler) CreateOneEncrypted(c *gin.Context) {
 	_, err = h.minioService.PutEncryptedObject(c.Request.Context(), category, objID, cr, -1, opts)
 	if err != nil {
 		logrus.Error(err)
+		// MaxFileSizeMiddleware обрывает тело, если файл больше max_file_size плана
+		if utils.WriteLimitExceeded(c, http.StatusRequestEntityTooLarge, err) {
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "upload failed"})
 		return
 	}
 
-	// получение веса файла
+	// получение веса файла и атомарное списание квоты; при отказе загруженный объект удаляется
 	size := cr.N
-	if err := h.quotaService.CheckQuota(c, userID, size); err != nil {
-		if errors.Is(err, quota_service.ErrQuotaExceeded) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "quota exceeded"})
+	usageRef := domain.UsageRef{ObjID: objID, Category: category, Reason: domain.UsageReasonUpload}
+	if err := h.quotaService.TryAddUsage(c, userID, size, usageRef); err != nil {
+		logrus.Errorf("%s TryAddUsage: %v", op, err)
+		if rmErr := h.minioService.RemoveObject(c.Request.Context(), dto.ObjectID{ObjID: objID, FileCategory: category}); rmErr != nil {
+			logrus.Errorf("%s RemoveObject: %v", op, rmErr)
+		}
+		if utils.WriteLimitExceeded(c, http.StatusForbidden, err) {
+			return
+		}
+		if errors.Is(err, quota_service.ErrReadOnly) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, quota_service.ErrNoActivePlan) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "no active plan for user"})
 			return
 		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "quota check failed"})
@@ -113,26 +183,16 @@ func (h *MinioHandler) CreateOneEncrypted(c *gin.Context) {
 	}
 
 	// Собираем ответ и кешируем
-	fileResp := dto.FileResponse{
-		Name:       origName,
-		Created_At: time.Now().UTC().Format(time.RFC3339),
-		ObjID:      objID,
-		Url:        presignedURL.String(),
-		MimeType:   origMime,
-	}
+	fileResp.ObjID = objID
+	fileResp.Url = presignedURL.String()
+	fileResp.Size = size
 	if err := h.minioService.CacheFileResponse(c.Request.Context(), category, objID, fileResp); err != nil {
 		logrus.Errorf("CacheFileResponse error: %v", err)
 	}
-
-	if err := h.quotaService.AddUsage(c, userID, size); err != nil {
-		if errors.Is(err, quota_service.ErrNoActivePlan) {
-			logrus.Errorf("%s AddUsage: %v", op, err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "no active plan for user"})
-			return
+	if expiresAt != nil {
+		if err := h.minioService.ScheduleExpiry(c.Request.Context(), category, objID, *expiresAt); err != nil {
+			logrus.Errorf("ScheduleExpiry error: %v", err)
 		}
-		logrus.Errorf("%s AddUsage: %v", op, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
 	}
 
 	c.JSON(http.StatusOK, fileResp)
@@ -339,7 +399,8 @@ func (h *MinioHandler) DeleteOne(c *gin.Context) {
 		return
 	}
 
-	if err := h.quotaService.RemoveUsage(c, userID, size); err != nil {
+	usageRef := domain.UsageRef{ObjID: objectID.ObjID, Category: objectID.FileCategory, Reason: domain.UsageReasonDelete}
+	if err := h.quotaService.RemoveUsage(c, userID, size, usageRef); err != nil {
 		logrus.Infof("RemoveUsage error: %v", err)
 		c.Status(http.StatusInternalServerError)
 	}
@@ -388,7 +449,16 @@ func (h *MinioHandler) DeleteMany(c *gin.Context) {
 
 	logrus.Infof("ObjectIDsDto: %v \n", objectIDs)
 
-	sizes, errs := h.minioService.DeleteMany(c, objectIDs.ObjectIDs, userID)
+	deleted, errs := h.minioService.DeleteMany(c, objectIDs.ObjectIDs, userID)
+
+	// место освобождается по каждому удалённому файлу, даже если часть файлов удалить не удалось
+	for _, obj := range deleted {
+		usageRef := domain.UsageRef{ObjID: obj.ObjID, Category: obj.FileCategory, Reason: domain.UsageReasonDelete}
+		if err := h.quotaService.RemoveUsage(c.Request.Context(), userID, obj.Size, usageRef); err != nil {
+			logrus.Errorf("RemoveUsage %s: %v, %s", obj.ObjID, err, op)
+		}
+	}
+
 	for _, err := range errs {
 		if err != nil {
 			logrus.Errorf("Error: %v,  %s", err, op)
@@ -420,16 +490,6 @@ func (h *MinioHandler) DeleteMany(c *gin.Context) {
 		}
 	}
 
-	var totalRemoved int64
-	for _, sz := range sizes {
-		totalRemoved += sz
-	}
-
-	if err := h.quotaService.RemoveUsage(c.Request.Context(), userID, totalRemoved); err != nil {
-		logrus.Infof("RemoveUsage error: %v", err)
-		c.Status(http.StatusInternalServerError)
-	}
-
 	c.JSON(http.StatusOK, gin.H{
 		"status":  http.StatusOK,
 		"message": "Files deleted successfully",
@@ -0,0 +1,78 @@
+package cloud_handler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/1abobik1/SecureComm/internal/service/cloud_service"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/minio/minio-go/v7"
+)
+
+// uploadClient подменяет только PutEncryptedObject и запоминает число вызовов
+type uploadClient struct {
+	cloud_service.Client
+	puts int
+}
+
+func (u *uploadClient) PutEncryptedObject(ctx context.Context, bucket, objectID string, r io.Reader, size int64, opts minio.PutObjectOptions) (minio.UploadInfo, error) {
+	u.puts++
+	return minio.UploadInfo{}, nil
+}
+
+func postUpload(t *testing.T, h *MinioHandler, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/files/one/encrypted", bytes.NewReader([]byte("ciphertext")))
+	c.Request.Header.Set("Content-Type", "application/octet-stream")
+	for k, v := range headers {
+		c.Request.Header.Set(k, v)
+	}
+	c.Set("claims", jwt.MapClaims{"user_id": float64(7)})
+
+	h.CreateOneEncrypted(c)
+	return w
+}
+
+func TestCreateOneEncrypted_PlaintextMetaRejected(t *testing.T) {
+	client := &uploadClient{}
+	h := &MinioHandler{minioService: client}
+
+	w := postUpload(t, h, map[string]string{
+		"X-File-Category": "photo",
+		"X-Orig-Filename": "cGhvdG8uanBn",
+		"X-Orig-Mime":     "image/jpeg",
+	})
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if client.puts != 0 {
+		t.Errorf("PutEncryptedObject called %d times, want 0", client.puts)
+	}
+}
+
+func TestCreateOneEncrypted_InvalidEncryptedMeta(t *testing.T) {
+	client := &uploadClient{}
+	h := &MinioHandler{minioService: client}
+
+	w := postUpload(t, h, map[string]string{
+		"X-File-Category":  "photo",
+		"X-Encrypted-Meta": "not base64!",
+	})
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if client.puts != 0 {
+		t.Errorf("PutEncryptedObject called %d times, want 0", client.puts)
+	}
+}
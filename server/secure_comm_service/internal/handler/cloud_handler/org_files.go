@@ -0,0 +1,276 @@
+package cloud_handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/count_reader"
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/1abobik1/SecureComm/internal/service/cloud_service"
+	"github.com/1abobik1/SecureComm/internal/service/org_service"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/sirupsen/logrus"
+)
+
+// CreateOrgOneEncrypted загружает зашифрованный файл в общий пул организации.
+// Заголовки как у /files/one/encrypted, но X-Encrypted-Meta обязателен, а срок жизни файла не поддерживается.
+//
+// @Summary      Загрузка зашифрованного файла в пространство организации
+// @Description  Файл занимает место в пуле организации и засчитывается загрузившему участнику в разбивке по участникам.
+// @Description  Доступно любому участнику. Ссылка на скачивание расходует месячный трафик загрузившего участника.
+// @Tags         Orgs
+// @Accept       application/octet-stream
+// @Produce      json
+// @Param        Authorization     header string true  "Bearer {token}"
+// @Param        org_id            path   int    true  "ID организации"
+// @Param        X-Encrypted-Meta  header string true  "Зашифрованные метаданные файла в base64 (не длиннее 1536 символов)"
+// @Param        X-File-Category   header string true  "Категория файла (photo, video, text, unknown)"
+// @Param 		file body []byte true "Зашифрованный бинарный поток (application/octet-stream)"
+// @Success      200  {object}  dto.FileResponse  "Успешно: JSON с metadata + presigned URL"
+// @Failure      400  {object}  map[string]string "Некорректные заголовки или body"
+// @Failure      403  {object}  dto.LimitExceededErr "Превышен лимит хранения или числа файлов организации, пул read-only либо email не подтверждён"
+// @Failure      404  {object}  map[string]string "Организация не найдена"
+// @Failure      413  {object}  dto.LimitExceededErr "Файл больше max_file_size плана организации"
+// @Failure      429  {object}  dto.LimitExceededErr "Исчерпан месячный лимит запросов"
+// @Failure      500  {object}  map[string]string "Внутренняя ошибка сервера"
+// @Security     bearerAuth
+// @Router       /orgs/{org_id}/files/one/encrypted [post]
+func (h *MinioHandler) CreateOrgOneEncrypted(c *gin.Context) {
+	const op = "location internal.handler.minio_handler.CreateOrgOneEncrypted"
+
+	orgID, userID, _, ok := utils.OrgMember(c, h.orgService)
+	if !ok {
+		return
+	}
+
+	category := strings.ToLower(c.GetHeader("X-File-Category"))
+	if !cloud_service.IsValidCategory(category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid X-File-Category(video or text or unknown or photo)"})
+		return
+	}
+	if c.GetHeader("X-Expires-At") != "" || c.GetHeader("X-Expires-In") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "files of an organization do not support expiration"})
+		return
+	}
+
+	encMeta := c.GetHeader("X-Encrypted-Meta")
+	if encMeta == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "missing X-Encrypted-Meta"})
+		return
+	}
+	if _, err := utils.Decode(encMeta); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "X-Encrypted-Meta must be base64"})
+		return
+	}
+
+	createdAt := time.Now().UTC()
+	metadata, err := cloud_service.GenerateEncryptedOrgMetaData(orgID, userID, encMeta, createdAt)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	objID := cloud_service.GenerateOrgFileID(orgID)
+
+	// read-only пул и лимит числа файлов проверяем до приёма тела
+	if err := h.quotaService.CheckOrgQuota(c, orgID, 0); errors.Is(err, quota_service.ErrReadOnly) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.quotaService.CheckOrgFileCount(c, orgID); err != nil {
+		if utils.WriteLimitExceeded(c, http.StatusForbidden, err) {
+			return
+		}
+		logrus.Errorf("%s CheckOrgFileCount: %v", op, err)
+	}
+
+	cr := count_reader.NewCountReader(c.Request.Body)
+	defer cr.Close()
+
+	_, err = h.minioService.PutEncryptedObject(c.Request.Context(), category, objID, cr, -1, minio.PutObjectOptions{
+		ContentType:  "application/octet-stream",
+		UserMetadata: metadata,
+	})
+	if err != nil {
+		logrus.Error(err)
+		if utils.WriteLimitExceeded(c, http.StatusRequestEntityTooLarge, err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "upload failed"})
+		return
+	}
+
+	size := cr.N
+	usageRef := domain.UsageRef{ObjID: objID, Category: category, Reason: domain.UsageReasonUpload}
+	if err := h.quotaService.TryAddOrgUsage(c, orgID, userID, size, usageRef); err != nil {
+		logrus.Errorf("%s TryAddOrgUsage: %v", op, err)
+		if rmErr := h.minioService.RemoveObject(c.Request.Context(), dto.ObjectID{ObjID: objID, FileCategory: category}); rmErr != nil {
+			logrus.Errorf("%s RemoveObject: %v", op, rmErr)
+		}
+		if utils.WriteLimitExceeded(c, http.StatusForbidden, err) {
+			return
+		}
+		if errors.Is(err, quota_service.ErrReadOnly) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "quota check failed"})
+		return
+	}
+
+	presignedURL, err := h.minioService.PresignedGetURL(c.Request.Context(), category, objID)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not generate URL"})
+		return
+	}
+
+	fileResp := dto.FileResponse{
+		ObjID:         objID,
+		Url:           presignedURL.String(),
+		Size:          size,
+		Created_At:    createdAt.Format(time.RFC3339),
+		Category:      category,
+		EncryptedMeta: encMeta,
+	}
+	if err := h.minioService.CacheFileResponse(c.Request.Context(), category, objID, fileResp); err != nil {
+		logrus.Errorf("CacheFileResponse error: %v", err)
+	}
+
+	c.JSON(http.StatusOK, fileResp)
+}
+
+// GetAllOrg возвращает все файлы организации указанной категории
+// @Summary      Все файлы организации в категории
+// @Description  Возвращает пре‐подписанные ссылки на все файлы пула организации в категории. Доступно любому участнику.
+// @Tags         Orgs
+// @Produce      json
+// @Param        Authorization header string true "Bearer {token}"
+// @Param        org_id path int true "ID организации"
+// @Param        type query string true "Категория файлов (photo, unknown, video, text)"
+// @Success      200  {array}   dto.FileResponse  "Список файлов"
+// @Failure      400  {object}  map[string]string "Некорректная категория"
+// @Failure      404  {object}  map[string]string "Организация не найдена"
+// @Failure      500  {object}  ErrorResponse     "Внутренняя ошибка сервера"
+// @Security     bearerAuth
+// @Router       /orgs/{org_id}/files/all [get]
+func (h *MinioHandler) GetAllOrg(c *gin.Context) {
+	const op = "location internal.handler.minio_handler.GetAllOrg"
+
+	orgID, _, _, ok := utils.OrgMember(c, h.orgService)
+	if !ok {
+		return
+	}
+
+	t := c.Query("type")
+	if !cloud_service.IsValidCategory(t) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the passed type in the query parameter. It can only be one of these types {photo, unknown, video, text}"})
+		return
+	}
+
+	fileResp, errs := h.minioService.GetAllOrg(c, t, orgID)
+	if len(errs) > 0 {
+		logrus.Errorf("Error: %v,  %s", errs, op)
+		writeFileError(c, errs[0], "Enable to get many objects")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":    http.StatusOK,
+		"message":   "All Files received successfully",
+		"file_data": fileResp,
+	})
+}
+
+// GetOrgOne возвращает ссылку на один файл организации
+// @Summary      Файл организации
+// @Description  Возвращает пре‐подписанную ссылку на файл пула организации. Доступно любому участнику.
+// @Tags         Orgs
+// @Produce      json
+// @Param        Authorization header string true "Bearer {token}"
+// @Param        org_id path int true "ID организации"
+// @Param        id query string true "Идентификатор объекта"
+// @Param        type query string true "Категория файла (photo, unknown, video, text)"
+// @Success      200  {object}  dto.FileResponse  "Ссылка на скачивание файла"
+// @Failure      403  {object}  ErrorResponse     "Файл не принадлежит организации"
+// @Failure      404  {object}  ErrorResponse     "Организация или файл не найдены"
+// @Failure      500  {object}  ErrorResponse     "Внутренняя ошибка сервера"
+// @Security     bearerAuth
+// @Router       /orgs/{org_id}/files/one [get]
+func (h *MinioHandler) GetOrgOne(c *gin.Context) {
+	const op = "location internal.handler.minio_handler.GetOrgOne"
+
+	orgID, _, _, ok := utils.OrgMember(c, h.orgService)
+	if !ok {
+		return
+	}
+
+	objectID := dto.ObjectID{
+		ObjID:        c.Query("id"),
+		FileCategory: c.Query("type"),
+	}
+
+	fileResp, err := h.minioService.GetOrgOne(c, objectID, orgID)
+	if err != nil {
+		logrus.Errorf("Error: %v,  %s", err, op)
+		writeFileError(c, err, "Enable to get the object")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":    http.StatusOK,
+		"message":   "File received successfully",
+		"file_data": fileResp,
+	})
+}
+
+// DeleteOrgOne удаляет файл организации
+// @Summary      Удаление файла организации
+// @Description  Удаляет файл из пула организации и освобождает место в пуле. Свой файл удаляет любой участник, чужой — owner и admin.
+// @Tags         Orgs
+// @Produce      json
+// @Param        Authorization header string true "Bearer {token}"
+// @Param        org_id path int true "ID организации"
+// @Param        id query string true "Идентификатор объекта"
+// @Param        type query string true "Категория файла (photo, unknown, video, text)"
+// @Success      200  {object}  map[string]string  "Файл успешно удалён"
+// @Failure      403  {object}  ErrorResponse      "Чужой файл или файл другой организации"
+// @Failure      404  {object}  ErrorResponse      "Организация или файл не найдены"
+// @Failure      500  {object}  ErrorResponse      "Внутренняя ошибка сервера"
+// @Security     bearerAuth
+// @Router       /orgs/{org_id}/files/one [delete]
+func (h *MinioHandler) DeleteOrgOne(c *gin.Context) {
+	const op = "location internal.handler.minio_handler.DeleteOrgOne"
+
+	orgID, userID, role, ok := utils.OrgMember(c, h.orgService)
+	if !ok {
+		return
+	}
+
+	objectID := dto.ObjectID{
+		ObjID:        c.Query("id"),
+		FileCategory: c.Query("type"),
+	}
+
+	size, uploaderID, err := h.minioService.DeleteOrgOne(c, objectID, orgID, userID, org_service.CanManage(role))
+	if err != nil {
+		logrus.Infof("Error: %v,  %s", err, op)
+		writeFileError(c, err, "Cannot delete the object")
+		return
+	}
+
+	usageRef := domain.UsageRef{ObjID: objectID.ObjID, Category: objectID.FileCategory, Reason: domain.UsageReasonDelete}
+	if err := h.quotaService.RemoveOrgUsage(c, orgID, uploaderID, size, usageRef); err != nil {
+		logrus.Errorf("RemoveOrgUsage error: %v, %s", err, op)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  http.StatusOK,
+		"message": "File deleted successfully",
+	})
+}
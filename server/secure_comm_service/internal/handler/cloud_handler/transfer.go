@@ -0,0 +1,244 @@
+package cloud_handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/1abobik1/SecureComm/internal/service/cloud_service"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CopyOne копирует файл на стороне MinIO
+// @Summary      Копирование файла
+// @Description  Создаёт серверную копию файла (в той же или другой категории) под новым obj_id без повторной загрузки шифртекста.
+// @Description  Размер копии атомарно списывается с квоты до копирования; при ошибке копирования списание откатывается.
+// @Tags         Files
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {token}"
+// @Param        request body dto.FileTransferReq true "Исходный файл и целевая категория"
+// @Success      200  {object}  dto.FileResponse  "Копия файла"
+// @Failure      400  {object}  ErrorResponse  "Некорректный запрос"
+// @Failure      403  {object}  dto.LimitExceededErr  "Доступ запрещён, превышен лимит хранения/числа файлов или email не подтверждён"
+// @Failure      404  {object}  ErrorResponse  "Файл не найден"
+// @Failure      500  {object}  ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     bearerAuth
+// @Router       /files/one/copy [post]
+func (h *MinioHandler) CopyOne(c *gin.Context) {
+	const op = "location internal.handler.minio_handler.CopyOne"
+
+	userID, req, ok := bindTransferReq(c, op)
+	if !ok {
+		return
+	}
+	objectID := dto.ObjectID{ObjID: req.ObjID, FileCategory: req.FileCategory}
+
+	size, err := h.minioService.StatOne(c.Request.Context(), objectID, userID)
+	if err != nil {
+		logrus.Errorf("Error: %v,  %s", err, op)
+		writeFileError(c, err, "Unable to copy the object")
+		return
+	}
+
+	// резервируем место до копирования, чтобы параллельные копии не вышли за лимит
+	usageRef := domain.UsageRef{ObjID: req.ObjID, Category: req.TargetCategory, Reason: domain.UsageReasonCopy}
+	if err := h.quotaService.TryAddUsage(c.Request.Context(), userID, size, usageRef); err != nil {
+		logrus.Errorf("Error: %v,  %s", err, op)
+		if utils.WriteLimitExceeded(c, http.StatusForbidden, err) {
+			return
+		}
+		if errors.Is(err, quota_service.ErrReadOnly) {
+			c.JSON(http.StatusForbidden, ErrorResponse{
+				Status: http.StatusForbidden,
+				Error:  err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Status:  http.StatusInternalServerError,
+			Error:   "quota check failed",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	fileResp, err := h.minioService.CopyOne(c.Request.Context(), objectID, req.TargetCategory, userID)
+	if err != nil {
+		logrus.Errorf("Error: %v,  %s", err, op)
+		usageRef.Reason = domain.UsageReasonRollback
+		if rbErr := h.quotaService.RemoveUsage(c.Request.Context(), userID, size, usageRef); rbErr != nil {
+			logrus.Errorf("RemoveUsage rollback error: %v,  %s", rbErr, op)
+		}
+		writeFileError(c, err, "Unable to copy the object")
+		return
+	}
+
+	c.JSON(http.StatusOK, fileResp)
+}
+
+// MoveOne переносит файл в другую категорию
+// @Summary      Перенос файла в другую категорию
+// @Description  Переносит файл в target_category с сохранением obj_id (CopyObject + удаление исходника). Квота не меняется.
+// @Tags         Files
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {token}"
+// @Param        request body dto.FileTransferReq true "Файл и целевая категория"
+// @Success      200  {object}  dto.FileResponse  "Файл в новой категории"
+// @Failure      400  {object}  ErrorResponse  "Некорректный запрос"
+// @Failure      403  {object}  ErrorResponse  "Доступ запрещён"
+// @Failure      404  {object}  ErrorResponse  "Файл не найден"
+// @Failure      500  {object}  ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     bearerAuth
+// @Router       /files/one/move [post]
+func (h *MinioHandler) MoveOne(c *gin.Context) {
+	const op = "location internal.handler.minio_handler.MoveOne"
+
+	userID, req, ok := bindTransferReq(c, op)
+	if !ok {
+		return
+	}
+
+	fileResp, err := h.minioService.MoveOne(c.Request.Context(), dto.ObjectID{ObjID: req.ObjID, FileCategory: req.FileCategory}, req.TargetCategory, userID)
+	if err != nil {
+		logrus.Errorf("Error: %v,  %s", err, op)
+		if errors.Is(err, cloud_service.ErrSameCategory) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Status: http.StatusBadRequest,
+				Error:  err.Error(),
+			})
+			return
+		}
+		writeFileError(c, err, "Unable to move the object")
+		return
+	}
+
+	if err := h.quotaService.RecordMove(c.Request.Context(), userID, fileResp.Size, req.ObjID, req.FileCategory, req.TargetCategory); err != nil {
+		logrus.Errorf("RecordMove error: %v,  %s", err, op)
+	}
+
+	c.JSON(http.StatusOK, fileResp)
+}
+
+// RenameOne заменяет зашифрованные метаданные файла
+// @Summary      Переименование файла
+// @Description  Заменяет encrypted_meta файла (имя, MIME-тип, теги, зашифрованные клиентом) без повторной загрузки шифртекста.
+// @Tags         Files
+// @Accept       json
+// @Produce      json
+// @Param        Authorization header string true "Bearer {token}"
+// @Param        request body dto.FileRenameReq true "Файл и новые зашифрованные метаданные"
+// @Success      200  {object}  dto.FileResponse  "Файл с новыми метаданными"
+// @Failure      400  {object}  ErrorResponse  "Некорректный запрос"
+// @Failure      403  {object}  ErrorResponse  "Доступ запрещён"
+// @Failure      404  {object}  ErrorResponse  "Файл не найден"
+// @Failure      500  {object}  ErrorResponse  "Внутренняя ошибка сервера"
+// @Security     bearerAuth
+// @Router       /files/one/rename [patch]
+func (h *MinioHandler) RenameOne(c *gin.Context) {
+	const op = "location internal.handler.minio_handler.RenameOne"
+
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		logrus.Errorf("Errors: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the user's ID was not found in the token."})
+		return
+	}
+
+	var req dto.FileRenameReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logrus.Errorf("Error: %v,  %s", err, op)
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Status:  http.StatusBadRequest,
+			Error:   "Invalid request body",
+			Details: err.Error(),
+		})
+		return
+	}
+	if _, err := utils.Decode(req.EncryptedMeta); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: http.StatusBadRequest,
+			Error:  "encrypted_meta must be base64",
+		})
+		return
+	}
+
+	fileResp, err := h.minioService.RenameOne(c.Request.Context(), dto.ObjectID{ObjID: req.ObjID, FileCategory: req.FileCategory}, req.EncryptedMeta, userID)
+	if err != nil {
+		logrus.Errorf("Error: %v,  %s", err, op)
+		if errors.Is(err, cloud_service.ErrEncryptedMetaTooLarge) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Status: http.StatusBadRequest,
+				Error:  err.Error(),
+			})
+			return
+		}
+		writeFileError(c, err, "Unable to rename the object")
+		return
+	}
+
+	c.JSON(http.StatusOK, fileResp)
+}
+
+func bindTransferReq(c *gin.Context, op string) (int, dto.FileTransferReq, bool) {
+	var req dto.FileTransferReq
+
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		logrus.Errorf("Errors: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "the user's ID was not found in the token."})
+		return 0, req, false
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		logrus.Errorf("Error: %v,  %s", err, op)
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Status:  http.StatusBadRequest,
+			Error:   "Invalid request body",
+			Details: err.Error(),
+		})
+		return 0, req, false
+	}
+
+	if !cloud_service.IsValidCategory(req.FileCategory) || !cloud_service.IsValidCategory(req.TargetCategory) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Status: http.StatusBadRequest,
+			Error:  "file_category and target_category can only be one of these types {photo, unknown, video, text}",
+		})
+		return 0, req, false
+	}
+
+	return userID, req, true
+}
+
+// writeFileError отвечает клиенту по ошибкам cloud_service: 404, 403 или 500 с fallback-сообщением
+func writeFileError(c *gin.Context, err error, fallback string) {
+	if errors.Is(err, cloud_service.ErrFileNotFound) {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Status:  http.StatusNotFound,
+			Error:   "File not found",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if errors.Is(err, cloud_service.ErrForbiddenResource) {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Status:  http.StatusForbidden,
+			Error:   "access to the requested resource is prohibited",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Status:  http.StatusInternalServerError,
+		Error:   fallback,
+		Details: err.Error(),
+	})
+}
@@ -6,8 +6,9 @@ import "context"
 type Service interface {
 	Init(ctx context.Context, clientID string, clientRSA, clientECDSA []byte, nonce1 []byte, sig1 []byte) (serverRSA, serverECDSA, nonce2, signature2 []byte, er error)
 	ComputeFingerprint(ctx context.Context, rsaPub, ecdsaPub []byte) string
-	Finalize(ctx context.Context, clientID string, sig3, encrypted []byte) (signature4 []byte, er error)
-	DecryptWithSession(ctx context.Context, clientID string, signature, blob []byte) ([]byte, error)
+	Finalize(ctx context.Context, clientID, sessionID string, sig3, encrypted []byte) (signature4 []byte, er error)
+	DecryptWithSession(ctx context.Context, clientID, sessionID string, signature, blob []byte) ([]byte, error)
+	RevokeSessions(ctx context.Context, userID string) error
 }
 
 type HSHandler struct {
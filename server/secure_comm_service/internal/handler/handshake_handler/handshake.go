@@ -114,6 +114,8 @@ func (h *HSHandler) Init(c *gin.Context) {
 // @Description
 // @Description  ОТВЕТ ОТ СЕРВЕРА:
 // @Description  Сервер возвращает подпись signature4 = SHA256(Ks || nonce3 || nonce2), подписанную приватным ECDSA-ключом сервера и закодированную в Base64.
+// @Description
+// @Description  Ks привязывается к сессии устройства (claim sid токена) и удаляется, когда эту сессию завершают или отзывают.
 // @Tags         handshake
 // @Accept       json
 // @Produce      json
@@ -158,7 +160,15 @@ func (h *HSHandler) Finalize(c *gin.Context) {
 	}
 	clientIDStr := strconv.Itoa(clientID)
 
-	sig4, err := h.svc.Finalize(c, clientIDStr, sig3, encrypted)
+	sessionID := utils.GetSessionID(c)
+	if sessionID == "" {
+		logrus.Errorf("no sid in the token of client %s", clientIDStr)
+		c.Set("failed_handshake", true)
+		c.JSON(http.StatusUnauthorized, dto.UnauthorizedErr{Error: "the device session was not found in the token, log in again"})
+		return
+	}
+
+	sig4, err := h.svc.Finalize(c, clientIDStr, sessionID, sig3, encrypted)
 	if err != nil {
 		if errors.Is(err, handshake_service.ErrReplayDetected) {
 			logrus.Errorf("Service error: %s", err.Error())
@@ -0,0 +1,41 @@
+package handshake_handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RevokeUserSessions удаляет сессии пользователя
+// @Summary      Сброс сессий пользователя. Внутреннее API, вызывается auth_service при смене или сбросе пароля
+// @Description  Удаляет сессионные ключи пользователя, клиенту придётся заново пройти handshake.
+// @Description  Принимает только сервисный токен с aud = SERVICE_TOKEN_AUDIENCE и scope sessions:revoke.
+// @Tags         Internal
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {service token}"
+// @Param        id             path    int     true  "ID пользователя"
+// @Success      204            "Сессии удалены"
+// @Failure      400            {object}  map[string]string  "Некорректный ID пользователя"
+// @Failure      401            {object}  map[string]string  "Нет сервисного токена или он недействителен"
+// @Failure      403            {object}  map[string]string  "У токена нет scope sessions:revoke"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /internal/users/{id}/sessions [delete]
+func (h *HSHandler) RevokeUserSessions(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := h.svc.RevokeSessions(c, strconv.Itoa(userID)); err != nil {
+		logrus.Errorf("revoke sessions of user %d: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"})
+		return
+	}
+
+	logrus.Infof("sessions of user %d revoked by %s", userID, c.GetString("service"))
+	c.Status(http.StatusNoContent)
+}
@@ -68,7 +68,7 @@ func (h *HSHandler) SessionTester(c *gin.Context) {
 		return
 	}
 
-	plaintext, err := h.svc.DecryptWithSession(c, clientIDStr, sig, data)
+	plaintext, err := h.svc.DecryptWithSession(c, clientIDStr, utils.GetSessionID(c), sig, data)
 	if err != nil {
 		utils.WriteSessionError(c, err) // обработка различных ошибок
 		return
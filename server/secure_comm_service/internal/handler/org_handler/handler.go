@@ -0,0 +1,11 @@
+package org_handler
+
+import "github.com/1abobik1/SecureComm/internal/service/org_service"
+
+type OrgHandler struct {
+	orgService *org_service.OrgService
+}
+
+func NewOrgHandler(orgService *org_service.OrgService) *OrgHandler {
+	return &OrgHandler{orgService: orgService}
+}
@@ -0,0 +1,225 @@
+package org_handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// InviteMember приглашает пользователя в организацию
+// @Summary      Приглашение участника
+// @Description  Приглашает пользователя в организацию с ролью admin или member. Доступно owner и admin.
+// @Description  Участником пользователь становится, только приняв приглашение через POST /orgs/invites/{invite_id}/accept.
+// @Description  Приглашение действует 7 дней, повторное приглашение заменяет роль и продлевает срок.
+// @Tags         Orgs
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string                  true  "Bearer {token}"
+// @Param        org_id         path    int                     true  "ID организации"
+// @Param        request        body    dto.InviteOrgMemberReq  true  "Пользователь и роль"
+// @Success      201            {object}  domain.OrgInvite   "Приглашение"
+// @Failure      400            {object}  map[string]string  "Некорректный запрос или роль"
+// @Failure      403            {object}  map[string]string  "Недостаточно прав или email не подтверждён"
+// @Failure      404            {object}  map[string]string  "Организация не найдена"
+// @Failure      409            {object}  map[string]string  "Пользователь уже состоит в организации"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs/{org_id}/invites [post]
+func (h *OrgHandler) InviteMember(c *gin.Context) {
+	const op = "location internal.handler.org_handler.InviteMember"
+
+	orgID, actorID, _, ok := utils.OrgMember(c, h.orgService)
+	if !ok {
+		return
+	}
+
+	var req dto.InviteOrgMemberReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invite, err := h.orgService.InviteMember(c, orgID, actorID, req.UserID, req.Role)
+	if err != nil {
+		writeOrgError(c, err, op)
+		return
+	}
+	logrus.Infof("user %d invited user %d to org %d as %s", actorID, invite.UserID, orgID, invite.Role)
+	c.JSON(http.StatusCreated, invite)
+}
+
+// ListOrgInvites возвращает приглашения организации
+// @Summary      Приглашения организации
+// @Description  Действующие (не принятые и не истёкшие) приглашения. Доступно owner и admin.
+// @Tags         Orgs
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        org_id         path    int     true  "ID организации"
+// @Success      200            {array}   domain.OrgInvite   "Приглашения"
+// @Failure      400            {object}  map[string]string  "Некорректный ID организации"
+// @Failure      403            {object}  map[string]string  "Недостаточно прав"
+// @Failure      404            {object}  map[string]string  "Организация не найдена"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs/{org_id}/invites [get]
+func (h *OrgHandler) ListOrgInvites(c *gin.Context) {
+	const op = "location internal.handler.org_handler.ListOrgInvites"
+
+	orgID, actorID, _, ok := utils.OrgMember(c, h.orgService)
+	if !ok {
+		return
+	}
+
+	invites, err := h.orgService.ListOrgInvites(c, orgID, actorID)
+	if err != nil {
+		writeOrgError(c, err, op)
+		return
+	}
+	c.JSON(http.StatusOK, invites)
+}
+
+// RevokeInvite отзывает приглашение
+// @Summary      Отзыв приглашения
+// @Description  Удаляет ещё не принятое приглашение. Доступно owner и admin.
+// @Tags         Orgs
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        org_id         path    int     true  "ID организации"
+// @Param        invite_id      path    int     true  "ID приглашения"
+// @Success      200            {object}  map[string]string  "Приглашение отозвано"
+// @Failure      400            {object}  map[string]string  "Некорректный ID"
+// @Failure      403            {object}  map[string]string  "Недостаточно прав"
+// @Failure      404            {object}  map[string]string  "Организация или приглашение не найдены"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs/{org_id}/invites/{invite_id} [delete]
+func (h *OrgHandler) RevokeInvite(c *gin.Context) {
+	const op = "location internal.handler.org_handler.RevokeInvite"
+
+	orgID, actorID, _, ok := utils.OrgMember(c, h.orgService)
+	if !ok {
+		return
+	}
+	inviteID, ok := inviteIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.orgService.RevokeInvite(c, orgID, actorID, inviteID); err != nil {
+		writeOrgError(c, err, op)
+		return
+	}
+	logrus.Infof("user %d revoked invite %d of org %d", actorID, inviteID, orgID)
+	c.JSON(http.StatusOK, gin.H{"message": "invite revoked"})
+}
+
+// ListMyInvites возвращает приглашения пользователя
+// @Summary      Мои приглашения
+// @Description  Действующие приглашения в организации, адресованные пользователю.
+// @Tags         Orgs
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Success      200            {array}   domain.OrgInvite   "Приглашения"
+// @Failure      401            {object}  map[string]string  "Проблема с авторизацией"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs/invites [get]
+func (h *OrgHandler) ListMyInvites(c *gin.Context) {
+	const op = "location internal.handler.org_handler.ListMyInvites"
+
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	invites, err := h.orgService.ListUserInvites(c, userID)
+	if err != nil {
+		writeOrgError(c, err, op)
+		return
+	}
+	c.JSON(http.StatusOK, invites)
+}
+
+// AcceptInvite принимает приглашение
+// @Summary      Принятие приглашения
+// @Description  Пользователь становится участником организации с ролью из приглашения. Приглашение одноразовое.
+// @Tags         Orgs
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        invite_id      path    int     true  "ID приглашения"
+// @Success      200            {object}  domain.Org         "Организация"
+// @Failure      400            {object}  map[string]string  "Некорректный ID"
+// @Failure      401            {object}  map[string]string  "Проблема с авторизацией"
+// @Failure      404            {object}  map[string]string  "Приглашение не найдено или истекло"
+// @Failure      409            {object}  map[string]string  "Пользователь уже состоит в организации"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs/invites/{invite_id}/accept [post]
+func (h *OrgHandler) AcceptInvite(c *gin.Context) {
+	const op = "location internal.handler.org_handler.AcceptInvite"
+
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+	inviteID, ok := inviteIDParam(c)
+	if !ok {
+		return
+	}
+
+	org, err := h.orgService.AcceptInvite(c, inviteID, userID)
+	if err != nil {
+		writeOrgError(c, err, op)
+		return
+	}
+	logrus.Infof("user %d joined org %d as %s", userID, org.ID, org.Role)
+	c.JSON(http.StatusOK, org)
+}
+
+// DeclineInvite отклоняет приглашение
+// @Summary      Отклонение приглашения
+// @Tags         Orgs
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        invite_id      path    int     true  "ID приглашения"
+// @Success      200            {object}  map[string]string  "Приглашение отклонено"
+// @Failure      400            {object}  map[string]string  "Некорректный ID"
+// @Failure      401            {object}  map[string]string  "Проблема с авторизацией"
+// @Failure      404            {object}  map[string]string  "Приглашение не найдено"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs/invites/{invite_id} [delete]
+func (h *OrgHandler) DeclineInvite(c *gin.Context) {
+	const op = "location internal.handler.org_handler.DeclineInvite"
+
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+	inviteID, ok := inviteIDParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.orgService.DeclineInvite(c, inviteID, userID); err != nil {
+		writeOrgError(c, err, op)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "invite declined"})
+}
+
+func inviteIDParam(c *gin.Context) (int, bool) {
+	inviteID, err := strconv.Atoi(c.Param("invite_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid invite id"})
+		return 0, false
+	}
+	return inviteID, true
+}
@@ -0,0 +1,213 @@
+package org_handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/1abobik1/SecureComm/internal/service/org_service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// CreateOrg создаёт организацию
+// @Summary      Создание организации
+// @Description  Создаёт организацию с общим пулом хранилища на free-плане. Создатель становится её владельцем (owner).
+// @Tags         Orgs
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string            true  "Bearer {token}"
+// @Param        request        body    dto.CreateOrgReq  true  "Название организации"
+// @Success      201            {object}  domain.Org         "Созданная организация"
+// @Failure      400            {object}  map[string]string  "Некорректное название"
+// @Failure      401            {object}  map[string]string  "Проблема с авторизацией"
+// @Failure      403            {object}  map[string]string  "Email не подтверждён"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs [post]
+func (h *OrgHandler) CreateOrg(c *gin.Context) {
+	const op = "location internal.handler.org_handler.CreateOrg"
+
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	var req dto.CreateOrgReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	org, err := h.orgService.CreateOrg(c, userID, req.Name)
+	if err != nil {
+		writeOrgError(c, err, op)
+		return
+	}
+	logrus.Infof("user %d created org %d", userID, org.ID)
+	c.JSON(http.StatusCreated, org)
+}
+
+// ListOrgs возвращает организации пользователя
+// @Summary      Мои организации
+// @Description  Возвращает организации, в которых состоит пользователь, вместе с его ролью.
+// @Tags         Orgs
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Success      200            {array}   domain.Org         "Организации"
+// @Failure      401            {object}  map[string]string  "Проблема с авторизацией"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs [get]
+func (h *OrgHandler) ListOrgs(c *gin.Context) {
+	const op = "location internal.handler.org_handler.ListOrgs"
+
+	userID, err := utils.GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return
+	}
+
+	orgs, err := h.orgService.ListUserOrgs(c, userID)
+	if err != nil {
+		writeOrgError(c, err, op)
+		return
+	}
+	c.JSON(http.StatusOK, orgs)
+}
+
+// GetOrg возвращает организацию с участниками
+// @Summary      Организация
+// @Description  Возвращает организацию, роль пользователя в ней и список участников. Доступно только участникам.
+// @Tags         Orgs
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        org_id         path    int     true  "ID организации"
+// @Success      200            {object}  dto.OrgResp        "Организация"
+// @Failure      400            {object}  map[string]string  "Некорректный ID организации"
+// @Failure      404            {object}  map[string]string  "Организация не найдена или пользователь в ней не состоит"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs/{org_id} [get]
+func (h *OrgHandler) GetOrg(c *gin.Context) {
+	const op = "location internal.handler.org_handler.GetOrg"
+
+	orgID, userID, _, ok := utils.OrgMember(c, h.orgService)
+	if !ok {
+		return
+	}
+
+	org, err := h.orgService.GetOrg(c, orgID, userID)
+	if err != nil {
+		writeOrgError(c, err, op)
+		return
+	}
+	members, err := h.orgService.ListMembers(c, orgID)
+	if err != nil {
+		writeOrgError(c, err, op)
+		return
+	}
+	c.JSON(http.StatusOK, dto.OrgResp{Org: org, Members: members})
+}
+
+// UpdateMemberRole меняет роль участника
+// @Summary      Смена роли участника
+// @Description  Переводит участника между ролями admin и member. Доступно только owner, роль owner не меняется.
+// @Tags         Orgs
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string                  true  "Bearer {token}"
+// @Param        org_id         path    int                     true  "ID организации"
+// @Param        user_id        path    int                     true  "ID участника"
+// @Param        request        body    dto.UpdateOrgMemberReq  true  "Новая роль"
+// @Success      200            {object}  map[string]string  "Роль изменена"
+// @Failure      400            {object}  map[string]string  "Некорректный запрос или роль"
+// @Failure      403            {object}  map[string]string  "Недостаточно прав"
+// @Failure      404            {object}  map[string]string  "Организация или участник не найдены"
+// @Failure      409            {object}  map[string]string  "Роль владельца не меняется"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs/{org_id}/members/{user_id} [patch]
+func (h *OrgHandler) UpdateMemberRole(c *gin.Context) {
+	const op = "location internal.handler.org_handler.UpdateMemberRole"
+
+	orgID, actorID, _, ok := utils.OrgMember(c, h.orgService)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	var req dto.UpdateOrgMemberReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.orgService.UpdateMemberRole(c, orgID, actorID, userID, req.Role); err != nil {
+		writeOrgError(c, err, op)
+		return
+	}
+	logrus.Infof("user %d changed role of user %d in org %d to %s", actorID, userID, orgID, req.Role)
+	c.JSON(http.StatusOK, gin.H{"message": "role updated"})
+}
+
+// RemoveMember исключает участника или выходит из организации
+// @Summary      Исключение участника
+// @Description  Участник может выйти сам. owner исключает admin и member, admin — только member. Владельца исключить нельзя.
+// @Description  Файлы участника остаются в пуле организации.
+// @Tags         Orgs
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        org_id         path    int     true  "ID организации"
+// @Param        user_id        path    int     true  "ID участника"
+// @Success      200            {object}  map[string]string  "Участник исключён"
+// @Failure      400            {object}  map[string]string  "Некорректный ID"
+// @Failure      403            {object}  map[string]string  "Недостаточно прав"
+// @Failure      404            {object}  map[string]string  "Организация или участник не найдены"
+// @Failure      409            {object}  map[string]string  "Владельца исключить нельзя"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs/{org_id}/members/{user_id} [delete]
+func (h *OrgHandler) RemoveMember(c *gin.Context) {
+	const op = "location internal.handler.org_handler.RemoveMember"
+
+	orgID, actorID, _, ok := utils.OrgMember(c, h.orgService)
+	if !ok {
+		return
+	}
+	userID, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	if err := h.orgService.RemoveMember(c, orgID, actorID, userID); err != nil {
+		writeOrgError(c, err, op)
+		return
+	}
+	logrus.Infof("user %d removed user %d from org %d", actorID, userID, orgID)
+	c.JSON(http.StatusOK, gin.H{"message": "member removed"})
+}
+
+func writeOrgError(c *gin.Context, err error, op string) {
+	switch {
+	case errors.Is(err, org_service.ErrOrgNotFound), errors.Is(err, org_service.ErrNotOrgMember), errors.Is(err, org_service.ErrInviteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, org_service.ErrInvalidRole), errors.Is(err, org_service.ErrInvalidOrgName):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, org_service.ErrOrgForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, org_service.ErrMemberExists), errors.Is(err, org_service.ErrOwnerImmutable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logrus.Errorf("Error: %v, %s", err, op)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
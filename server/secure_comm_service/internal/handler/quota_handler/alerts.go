@@ -0,0 +1,160 @@
+package quota_handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GetUsageAlerts возвращает настройки уведомлений о заполнении хранилища
+// @Summary      Настройки уведомлений о заполнении
+// @Description  Пороги в процентах от лимита хранилища, адреса вебхука и почты и уже пересечённые пороги.
+// @Description  Пока пользователь не менял настройки, действуют пороги по умолчанию (default = true).
+// @Tags         Quota
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        id             path    int     true  "ID пользователя"
+// @Success      200            {object}  domain.UsageAlertSettings  "Настройки уведомлений"
+// @Failure      400            {object}  map[string]string          "Некорректный ID пользователя"
+// @Failure      403            {object}  map[string]string          "Чужой ID пользователя"
+// @Failure      500            {object}  map[string]string          "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /user/{id}/usage/alerts [get]
+func (h *QuotaHandler) GetUsageAlerts(c *gin.Context) {
+	userID, ok := utils.OwnUserID(c)
+	if !ok {
+		return
+	}
+
+	settings, err := h.quotaService.GetUsageAlertSettings(c, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// SetUsageAlerts сохраняет настройки уведомлений о заполнении хранилища
+// @Summary      Изменение уведомлений о заполнении
+// @Description  Уведомление отправляется один раз при пересечении порога вверх (проверяется при добавлении файлов).
+// @Description  Когда занятое место опускается ниже порога, он снова срабатывает при следующем пересечении.
+// @Description  Вебхук получает JSON события с подписью в заголовке X-SecureComm-Signature.
+// @Description  Письма уходят только на подтверждённый адрес: на новый адрес отправляется код для /user/{id}/usage/alerts/email/confirm.
+// @Description  Повторное сохранение неподтверждённого адреса отправляет новый код.
+// @Tags         Quota
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string                     true  "Bearer {token}"
+// @Param        id             path    int                        true  "ID пользователя"
+// @Param        request        body    dto.UsageAlertSettingsReq  true  "Пороги и адреса"
+// @Success      200            {object}  domain.UsageAlertSettings  "Сохранённые настройки"
+// @Failure      400            {object}  map[string]string          "Некорректные пороги или адреса"
+// @Failure      403            {object}  map[string]string          "Чужой ID пользователя"
+// @Failure      500            {object}  map[string]string          "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /user/{id}/usage/alerts [put]
+func (h *QuotaHandler) SetUsageAlerts(c *gin.Context) {
+	const op = "location internal.handler.quota_handler.SetUsageAlerts"
+
+	userID, ok := utils.OwnUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.UsageAlertSettingsReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "thresholds are required, at most 10"})
+		return
+	}
+
+	settings, err := h.quotaService.SetUsageAlertSettings(c, userID, domain.UsageAlertSettings{
+		Thresholds: req.Thresholds,
+		WebhookURL: req.WebhookURL,
+		Email:      req.Email,
+	})
+	if err != nil {
+		if errors.Is(err, quota_service.ErrInvalidAlertSettings) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		logrus.Errorf("%s: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// ConfirmAlertEmail подтверждает адрес для уведомлений кодом из письма
+// @Summary      Подтверждение адреса для уведомлений
+// @Description  Код приходит письмом после сохранения адреса в PUT /user/{id}/usage/alerts и действует 24 часа.
+// @Tags         Quota
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string                    true  "Bearer {token}"
+// @Param        id             path    int                       true  "ID пользователя"
+// @Param        request        body    dto.ConfirmAlertEmailReq  true  "Код из письма"
+// @Success      200            {object}  domain.UsageAlertSettings  "Настройки с подтверждённым адресом"
+// @Failure      400            {object}  map[string]string          "Неверный или просроченный код"
+// @Failure      403            {object}  map[string]string          "Чужой ID пользователя"
+// @Failure      500            {object}  map[string]string          "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /user/{id}/usage/alerts/email/confirm [post]
+func (h *QuotaHandler) ConfirmAlertEmail(c *gin.Context) {
+	const op = "location internal.handler.quota_handler.ConfirmAlertEmail"
+
+	userID, ok := utils.OwnUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.ConfirmAlertEmailReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "code is required"})
+		return
+	}
+
+	settings, err := h.quotaService.ConfirmAlertEmail(c, userID, req.Code)
+	if err != nil {
+		if errors.Is(err, quota_service.ErrAlertEmailToken) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		logrus.Errorf("%s: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
+
+// ResetUsageAlerts возвращает пороги по умолчанию
+// @Summary      Сброс уведомлений о заполнении
+// @Description  Удаляет пользовательские пороги и адреса, снова действуют пороги по умолчанию.
+// @Tags         Quota
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        id             path    int     true  "ID пользователя"
+// @Success      200            {object}  domain.UsageAlertSettings  "Настройки по умолчанию"
+// @Failure      400            {object}  map[string]string          "Некорректный ID пользователя"
+// @Failure      403            {object}  map[string]string          "Чужой ID пользователя"
+// @Failure      500            {object}  map[string]string          "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /user/{id}/usage/alerts [delete]
+func (h *QuotaHandler) ResetUsageAlerts(c *gin.Context) {
+	userID, ok := utils.OwnUserID(c)
+	if !ok {
+		return
+	}
+
+	settings, err := h.quotaService.ResetUsageAlertSettings(c, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, settings)
+}
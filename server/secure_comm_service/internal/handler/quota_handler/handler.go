@@ -1,11 +1,15 @@
 package quota_handler
 
-import "github.com/1abobik1/SecureComm/internal/service/quota_service"
+import (
+	"github.com/1abobik1/SecureComm/internal/service/org_service"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+)
 
 type QuotaHandler struct {
 	quotaService *quota_service.QuotaService
+	orgService   *org_service.OrgService
 }
 
-func NewQuotaHandler(quotaService *quota_service.QuotaService) *QuotaHandler {
-	return &QuotaHandler{quotaService: quotaService}
+func NewQuotaHandler(quotaService *quota_service.QuotaService, orgService *org_service.OrgService) *QuotaHandler {
+	return &QuotaHandler{quotaService: quotaService, orgService: orgService}
 }
@@ -0,0 +1,174 @@
+package quota_handler
+
+import (
+	"net/http"
+
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/1abobik1/SecureComm/internal/service/org_service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// GetOrgUsage возвращает занятое место общего пула организации
+// @Summary      Использование пула организации
+// @Description  Возвращает current_used, лимит и число файлов пула организации. owner и admin дополнительно видят вклад каждого участника
+// @Description  (по журналу изменений; user_id=0 — корректировки реконсилера).
+// @Tags         Orgs
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        org_id         path    int     true  "ID организации"
+// @Success      200            {object}  domain.OrgUsage    "Использование пула"
+// @Failure      400            {object}  map[string]string  "Некорректный ID организации"
+// @Failure      404            {object}  map[string]string  "Организация не найдена или нет активного плана"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs/{org_id}/usage [get]
+func (h *QuotaHandler) GetOrgUsage(c *gin.Context) {
+	orgID, _, role, ok := utils.OrgMember(c, h.orgService)
+	if !ok {
+		return
+	}
+
+	usage, err := h.quotaService.GetOrgUsage(c, orgID, org_service.CanManage(role))
+	if err != nil {
+		writePlanError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+// GetOrgPlan возвращает текущую подписку организации
+// @Summary      Подписка организации
+// @Description  Возвращает действующий план пула организации. Доступно всем участникам.
+// @Tags         Orgs
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        org_id         path    int     true  "ID организации"
+// @Success      200            {object}  domain.UserPlan    "Текущая подписка"
+// @Failure      400            {object}  map[string]string  "Некорректный ID организации"
+// @Failure      404            {object}  map[string]string  "Организация не найдена или нет активного плана"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs/{org_id}/plan [get]
+func (h *QuotaHandler) GetOrgPlan(c *gin.Context) {
+	orgID, _, _, ok := utils.OrgMember(c, h.orgService)
+	if !ok {
+		return
+	}
+
+	plan, err := h.quotaService.GetOrgPlan(c, orgID)
+	if err != nil {
+		writePlanError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// SubscribeOrg переводит организацию на тариф с не меньшим лимитом
+// @Summary      Подписка или апгрейд тарифа организации
+// @Description  То же, что /user/{id}/plan/subscribe, но для пула организации. Доступно owner и admin.
+// @Description  Платный план подключается только после оплаты через /orgs/{org_id}/plan/checkout.
+// @Tags         Orgs
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string             true  "Bearer {token}"
+// @Param        org_id         path    int                true  "ID организации"
+// @Param        request        body    dto.ChangePlanReq  true  "Имя тарифа"
+// @Success      200            {object}  domain.UserPlan    "Новая подписка"
+// @Failure      400            {object}  map[string]string  "Некорректный запрос или план с меньшим лимитом"
+// @Failure      402            {object}  map[string]string  "Платный план требует оплаты"
+// @Failure      403            {object}  map[string]string  "Недостаточно прав"
+// @Failure      404            {object}  map[string]string  "Организация или план не найдены"
+// @Failure      409            {object}  map[string]string  "Организация уже на этом плане"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs/{org_id}/plan/subscribe [post]
+func (h *QuotaHandler) SubscribeOrg(c *gin.Context) {
+	orgID, userID, ok := utils.OrgManager(c, h.orgService)
+	if !ok {
+		return
+	}
+
+	var req dto.ChangePlanReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := h.quotaService.SubscribeOrg(c, orgID, req.PlanName, req.AutoRenew)
+	if err != nil {
+		writePlanError(c, err)
+		return
+	}
+	logrus.Infof("user %d subscribed org %d to plan %s", userID, orgID, plan.Name)
+	c.JSON(http.StatusOK, plan)
+}
+
+// DowngradeOrg переводит организацию на тариф с меньшим лимитом
+// @Summary      Даунгрейд тарифа организации
+// @Description  То же, что /user/{id}/plan/downgrade, но для пула организации. Доступно owner и admin.
+// @Tags         Orgs
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string             true  "Bearer {token}"
+// @Param        org_id         path    int                true  "ID организации"
+// @Param        request        body    dto.ChangePlanReq  true  "Имя тарифа"
+// @Success      200            {object}  domain.UserPlan    "Новая подписка"
+// @Failure      400            {object}  map[string]string  "Некорректный запрос или план с большим лимитом"
+// @Failure      403            {object}  map[string]string  "Недостаточно прав"
+// @Failure      404            {object}  map[string]string  "Организация или план не найдены"
+// @Failure      409            {object}  map[string]string  "Занято больше нового лимита"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs/{org_id}/plan/downgrade [post]
+func (h *QuotaHandler) DowngradeOrg(c *gin.Context) {
+	orgID, userID, ok := utils.OrgManager(c, h.orgService)
+	if !ok {
+		return
+	}
+
+	var req dto.ChangePlanReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := h.quotaService.DowngradeOrg(c, orgID, req.PlanName, req.ReadOnlyIfOverLimit, req.AutoRenew)
+	if err != nil {
+		writePlanError(c, err)
+		return
+	}
+	logrus.Infof("user %d downgraded org %d to plan %s, read_only=%v", userID, orgID, plan.Name, plan.ReadOnly)
+	c.JSON(http.StatusOK, plan)
+}
+
+// CancelOrg отменяет платную подписку организации в конце периода
+// @Summary      Отмена подписки организации
+// @Description  Платная подписка организации действует до конца оплаченного периода, после чего пул переходит на free. Доступно owner и admin.
+// @Tags         Orgs
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        org_id         path    int     true  "ID организации"
+// @Success      200            {object}  domain.UserPlan    "Подписка с cancel_at_period_end=true"
+// @Failure      400            {object}  map[string]string  "Бесплатный план нельзя отменить"
+// @Failure      403            {object}  map[string]string  "Недостаточно прав"
+// @Failure      404            {object}  map[string]string  "Организация не найдена или нет активного плана"
+// @Failure      409            {object}  map[string]string  "Подписка уже отменена"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /orgs/{org_id}/plan/cancel [post]
+func (h *QuotaHandler) CancelOrg(c *gin.Context) {
+	orgID, userID, ok := utils.OrgManager(c, h.orgService)
+	if !ok {
+		return
+	}
+
+	plan, err := h.quotaService.CancelOrgAtPeriodEnd(c, orgID)
+	if err != nil {
+		writePlanError(c, err)
+		return
+	}
+	logrus.Infof("user %d canceled plan %s of org %d at period end", userID, plan.Name, orgID)
+	c.JSON(http.StatusOK, plan)
+}
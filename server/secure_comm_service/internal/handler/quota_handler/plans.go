@@ -0,0 +1,191 @@
+package quota_handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// ListPlans возвращает список тарифов
+// @Summary      Список тарифов
+// @Description  Возвращает тарифы, доступные для подписки, с лимитом хранилища (байты), ценой (копейки) и длительностью периода (0 — бессрочно).
+// @Description  Выведенные из продажи тарифы не возвращаются.
+// @Tags         Quota
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Success      200            {array}   domain.Plan        "Список тарифов"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /plans [get]
+func (h *QuotaHandler) ListPlans(c *gin.Context) {
+	plans, err := h.quotaService.ListPlans(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, plans)
+}
+
+// GetCurrentPlan возвращает текущую подписку пользователя
+// @Summary      Текущая подписка
+// @Description  Возвращает действующий план, даты периода, флаги отмены и read-only, а также план, который начнёт действовать после отмены.
+// @Description  limit_override — персональные лимиты, назначенные администратором; они действуют вместо лимитов плана до expires_at.
+// @Tags         Quota
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        id             path    int     true  "ID пользователя"
+// @Success      200            {object}  domain.UserPlan    "Текущая подписка"
+// @Failure      400            {object}  map[string]string  "Некорректный ID пользователя"
+// @Failure      403            {object}  map[string]string  "Чужой ID пользователя"
+// @Failure      404            {object}  map[string]string  "Нет активного плана"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /user/{id}/plan [get]
+func (h *QuotaHandler) GetCurrentPlan(c *gin.Context) {
+	userID, ok := utils.OwnUserID(c)
+	if !ok {
+		return
+	}
+
+	plan, err := h.quotaService.GetCurrentPlan(c, userID)
+	if err != nil {
+		writePlanError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// Subscribe подписывает пользователя на тариф
+// @Summary      Подписка или апгрейд тарифа
+// @Description  Сразу переводит пользователя на план с не меньшим лимитом. Текущий период закрывается, новый начинается сейчас, занятое место переносится.
+// @Description  Повторная подписка на текущий план после отмены снимает отмену.
+// @Description  Платный план подключается только после оплаты: в этом случае возвращается 402, оплату нужно начать через /user/{id}/plan/checkout.
+// @Tags         Quota
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string             true  "Bearer {token}"
+// @Param        id             path    int                true  "ID пользователя"
+// @Param        request        body    dto.ChangePlanReq  true  "Имя тарифа"
+// @Success      200            {object}  domain.UserPlan    "Новая подписка"
+// @Failure      400            {object}  map[string]string  "Некорректный запрос или план с меньшим лимитом"
+// @Failure      402            {object}  map[string]string  "Платный план требует оплаты"
+// @Failure      403            {object}  map[string]string  "Чужой ID пользователя"
+// @Failure      404            {object}  map[string]string  "План не найден"
+// @Failure      409            {object}  map[string]string  "Пользователь уже на этом плане"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /user/{id}/plan/subscribe [post]
+func (h *QuotaHandler) Subscribe(c *gin.Context) {
+	userID, ok := utils.OwnUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.ChangePlanReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := h.quotaService.Subscribe(c, userID, req.PlanName, req.AutoRenew)
+	if err != nil {
+		writePlanError(c, err)
+		return
+	}
+	logrus.Infof("user %d subscribed to plan %s", userID, plan.Name)
+	c.JSON(http.StatusOK, plan)
+}
+
+// Downgrade переводит пользователя на тариф с меньшим лимитом
+// @Summary      Даунгрейд тарифа
+// @Description  Сразу переводит пользователя на план с меньшим лимитом. Если занято больше нового лимита, возвращается 409,
+// @Description  либо (read_only_if_over_limit=true) аккаунт становится read-only: загрузка запрещена, пока лишние файлы не будут удалены.
+// @Tags         Quota
+// @Accept       json
+// @Produce      json
+// @Param        Authorization  header  string             true  "Bearer {token}"
+// @Param        id             path    int                true  "ID пользователя"
+// @Param        request        body    dto.ChangePlanReq  true  "Имя тарифа"
+// @Success      200            {object}  domain.UserPlan    "Новая подписка"
+// @Failure      400            {object}  map[string]string  "Некорректный запрос или план с большим лимитом"
+// @Failure      403            {object}  map[string]string  "Чужой ID пользователя"
+// @Failure      404            {object}  map[string]string  "План не найден"
+// @Failure      409            {object}  map[string]string  "Занято больше нового лимита"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /user/{id}/plan/downgrade [post]
+func (h *QuotaHandler) Downgrade(c *gin.Context) {
+	userID, ok := utils.OwnUserID(c)
+	if !ok {
+		return
+	}
+
+	var req dto.ChangePlanReq
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	plan, err := h.quotaService.Downgrade(c, userID, req.PlanName, req.ReadOnlyIfOverLimit, req.AutoRenew)
+	if err != nil {
+		writePlanError(c, err)
+		return
+	}
+	logrus.Infof("user %d downgraded to plan %s, read_only=%v", userID, plan.Name, plan.ReadOnly)
+	c.JSON(http.StatusOK, plan)
+}
+
+// Cancel отменяет платную подписку в конце периода
+// @Summary      Отмена подписки
+// @Description  Платная подписка продолжает действовать до конца оплаченного периода, после чего пользователь переходит на free.
+// @Tags         Quota
+// @Produce      json
+// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        id             path    int     true  "ID пользователя"
+// @Success      200            {object}  domain.UserPlan    "Подписка с cancel_at_period_end=true"
+// @Failure      400            {object}  map[string]string  "Бесплатный план нельзя отменить"
+// @Failure      403            {object}  map[string]string  "Чужой ID пользователя"
+// @Failure      404            {object}  map[string]string  "Нет активного плана"
+// @Failure      409            {object}  map[string]string  "Подписка уже отменена"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /user/{id}/plan/cancel [post]
+func (h *QuotaHandler) Cancel(c *gin.Context) {
+	userID, ok := utils.OwnUserID(c)
+	if !ok {
+		return
+	}
+
+	plan, err := h.quotaService.CancelAtPeriodEnd(c, userID)
+	if err != nil {
+		writePlanError(c, err)
+		return
+	}
+	logrus.Infof("user %d canceled plan %s at period end", userID, plan.Name)
+	c.JSON(http.StatusOK, plan)
+}
+
+func writePlanError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, quota_service.ErrPlanNotFound), errors.Is(err, quota_service.ErrNoActivePlan):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, quota_service.ErrNotUpgrade), errors.Is(err, quota_service.ErrNotDowngrade), errors.Is(err, quota_service.ErrFreePlanCancel):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, quota_service.ErrPaymentRequired):
+		checkout := "POST /user/" + c.Param("id") + "/plan/checkout"
+		if orgID := c.Param("org_id"); orgID != "" {
+			checkout = "POST /orgs/" + orgID + "/plan/checkout"
+		}
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error(), "checkout": checkout})
+	case errors.Is(err, quota_service.ErrAlreadyOnPlan), errors.Is(err, quota_service.ErrAlreadyCanceled), errors.Is(err, quota_service.ErrDowngradeOverLimit):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		logrus.Errorf("plan error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -6,9 +6,11 @@ import (
 	"strconv"
 
 	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
 	"github.com/1abobik1/SecureComm/internal/service/quota_service"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -18,19 +20,21 @@ const (
 )
 
 // InitUserPlan создаёт для userID бесплатный тариф 10 GiB
-// @Summary      Инициализация бесплатного плана(10гб) пользователя. Вызывается как внешнее апи, при регистрации(/user/signup)
+// @Summary      Инициализация бесплатного плана(10гб) пользователя. Внутреннее API, вызывается auth_service при регистрации(/user/signup)
 // @Description  Создаёт для пользователя с переданным ID бесплатный план хранения объёмом до 10 GiB. Если план уже существует, операция игнорируется.
-// @Tags         Quota
+// @Description  Принимает только сервисный токен с aud = SERVICE_TOKEN_AUDIENCE и scope plans:init; пользовательские токены отклоняются.
+// @Tags         Internal
 // @Accept       json
 // @Produce      json
-// @Param        Authorization  header  string  true  "Bearer {token}"
+// @Param        Authorization  header  string  true  "Bearer {service token}"
 // @Param        id             path    int     true  "ID пользователя"
 // @Success      201            {string}  string               "Created — план успешно инициализирован"
 // @Failure      400            {object}  map[string]string    "Некорректный ID пользователя"
-// @Failure      401            {object}  map[string]string    "Ошибка авторизации или токен не предоставлен"
+// @Failure      401            {object}  map[string]string    "Нет сервисного токена или он недействителен"
+// @Failure      403            {object}  map[string]string    "У токена нет scope plans:init"
 // @Failure      500            {object}  map[string]string    "Внутренняя ошибка сервера"
 // @Security     BearerAuth
-// @Router       /user/{id}/plan/init [post]
+// @Router       /internal/users/{id}/plan/init [post]
 func (h *QuotaHandler) InitUserPlan(c *gin.Context) {
 	idStr := c.Param("id")
 	userID, err := strconv.Atoi(idStr)
@@ -50,6 +54,8 @@ func (h *QuotaHandler) InitUserPlan(c *gin.Context) {
 // GetUserUsage выдает текущее кол-во используемой памяти по id пользователя
 // @Summary      Получение текущего использования диска пользователя
 // @Description  Возвращает, сколько гигабайт, мегабайт и килобайт хранится у пользователя, а также лимит (10 GiB для бесплатного плана) и имя плана.
+// @Description  Дополнительно отдаются точные значения в байтах, сумма журнала изменений (ledger_used_bytes) и разбивка по категориям из журнала,
+// @Description  а также исходящий трафик и число запросов за текущий месяц с лимитами плана (traffic).
 // @Tags         Quota
 // @Accept       json
 // @Produce      json
@@ -58,15 +64,14 @@ func (h *QuotaHandler) InitUserPlan(c *gin.Context) {
 // @Success      200            {object}  dto.UserUsage  "Информация об использовании дискового пространства"
 // @Failure      400            {object}  map[string]string    "Некорректный ID пользователя"
 // @Failure      401            {object}  map[string]string    "Ошибка авторизации или токен не предоставлен"
+// @Failure      403            {object}  map[string]string    "Чужой ID пользователя"
 // @Failure      404            {object}  map[string]string    "Пользователь не найден"
 // @Failure      500            {object}  map[string]string    "Внутренняя ошибка сервера"
 // @Security     BearerAuth
 // @Router       /user/{id}/usage [get]
 func (h *QuotaHandler) GetUserUsage(c *gin.Context) {
-	idStr := c.Param("id")
-	userID, err := strconv.Atoi(idStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+	userID, ok := utils.OwnUserID(c)
+	if !ok {
 		return
 	}
 
@@ -99,6 +104,16 @@ func (h *QuotaHandler) GetUserUsage(c *gin.Context) {
 		CurrentUsedKB:  int(kbCount),
 		StorageLimitGB: int(domainUserUsage.StorageLimit / bytesInGB),
 		PlanName:       domainUserUsage.PlanName,
+
+		CurrentUsedBytes:  used,
+		StorageLimitBytes: domainUserUsage.StorageLimit,
+		LedgerUsedBytes:   domainUserUsage.LedgerUsed,
+		ByCategory:        domainUserUsage.ByCategory,
+		Traffic:           domainUserUsage.Traffic,
+	}
+
+	if domainUserUsage.LedgerUsed != used {
+		logrus.Warnf("usage ledger of user %d sums to %d, current_used is %d", userID, domainUserUsage.LedgerUsed, used)
 	}
 
 	c.JSON(http.StatusOK, resp)
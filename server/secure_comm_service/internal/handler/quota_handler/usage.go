@@ -0,0 +1,131 @@
+package quota_handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/1abobik1/SecureComm/internal/service/cloud_service"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	usageHistoryDefaultDays = 30
+	usageLedgerDefaultLimit = 50
+	usageLedgerMaxLimit     = 500
+	usageDayLayout          = "2006-01-02"
+)
+
+// GetUsageHistory возвращает занятое место по дням
+// @Summary      История использования диска
+// @Description  Ряд по дням (UTC) из журнала изменений: сколько добавлено, сколько освобождено и сколько занято на конец дня.
+// @Description  По умолчанию — последние 30 дней, максимум 366. Можно ограничить одной категорией.
+// @Tags         Quota
+// @Produce      json
+// @Param        Authorization  header  string  true   "Bearer {token}"
+// @Param        id             path    int     true   "ID пользователя"
+// @Param        from           query   string  false  "Первый день, YYYY-MM-DD"
+// @Param        to             query   string  false  "Последний день включительно, YYYY-MM-DD"
+// @Param        category       query   string  false  "Категория: photo, video, text, unknown"
+// @Success      200            {array}   domain.UsagePoint  "Использование по дням"
+// @Failure      400            {object}  map[string]string  "Некорректный диапазон или категория"
+// @Failure      403            {object}  map[string]string  "Чужой ID пользователя"
+// @Failure      500            {object}  map[string]string  "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /user/{id}/usage/history [get]
+func (h *QuotaHandler) GetUsageHistory(c *gin.Context) {
+	userID, ok := utils.OwnUserID(c)
+	if !ok {
+		return
+	}
+
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(usageDayLayout, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be YYYY-MM-DD"})
+			return
+		}
+		to = t
+	}
+	from := to.AddDate(0, 0, -(usageHistoryDefaultDays - 1))
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(usageDayLayout, v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be YYYY-MM-DD"})
+			return
+		}
+		from = t
+	}
+
+	category := c.Query("category")
+	if category != "" && !cloud_service.IsValidCategory(category) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "category must be one of {photo, unknown, video, text}"})
+		return
+	}
+
+	// to включительно, а сервис принимает полуинтервал
+	points, err := h.quotaService.UsageHistory(c, userID, from, to.AddDate(0, 0, 1), category)
+	if err != nil {
+		if errors.Is(err, quota_service.ErrInvalidUsageRange) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must not be after to, range is limited to 366 days"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, points)
+}
+
+// GetUsageLedger возвращает журнал изменений занятого места
+// @Summary      Журнал использования диска
+// @Description  Записи журнала от новых к старым: файл, категория, изменение в байтах и причина (upload, delete, copy, move, expire, rollback, reconcile, opening_balance).
+// @Description  Для следующей страницы передайте before_id = id последней полученной записи.
+// @Tags         Quota
+// @Produce      json
+// @Param        Authorization  header  string  true   "Bearer {token}"
+// @Param        id             path    int     true   "ID пользователя"
+// @Param        before_id      query   int     false  "Вернуть записи с id меньше этого"
+// @Param        limit          query   int     false  "Размер страницы, по умолчанию 50, максимум 500"
+// @Success      200            {array}   domain.UsageLedgerEntry  "Записи журнала"
+// @Failure      400            {object}  map[string]string        "Некорректные параметры"
+// @Failure      403            {object}  map[string]string        "Чужой ID пользователя"
+// @Failure      500            {object}  map[string]string        "Внутренняя ошибка сервера"
+// @Security     BearerAuth
+// @Router       /user/{id}/usage/ledger [get]
+func (h *QuotaHandler) GetUsageLedger(c *gin.Context) {
+	userID, ok := utils.OwnUserID(c)
+	if !ok {
+		return
+	}
+
+	var beforeID int64
+	if v := c.Query("before_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "before_id must be a positive integer"})
+			return
+		}
+		beforeID = id
+	}
+
+	limit := usageLedgerDefaultLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > usageLedgerMaxLimit {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		limit = n
+	}
+
+	entries, err := h.quotaService.ListUsageLedger(c, userID, beforeID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
@@ -2,15 +2,19 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"encoding/base64"
 	"net/http"
+	"strconv"
 
 	"github.com/golang-jwt/jwt/v4"
 
 	"github.com/1abobik1/SecureComm/internal/dto"
 	"github.com/1abobik1/SecureComm/internal/service/handshake_service"
+	"github.com/1abobik1/SecureComm/internal/service/org_service"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/sirupsen/logrus"
@@ -31,6 +35,114 @@ func GetUserID(ctx context.Context) (int, error) {
 	return int(userIDFloat), nil
 }
 
+// GetSessionID извлекает ID сессии устройства из claim sid; у токенов без сессии — пустая строка
+func GetSessionID(ctx context.Context) string {
+	claims, ok := ctx.Value("claims").(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	sessionID, _ := claims["sid"].(string)
+	return sessionID
+}
+
+// RoleAdmin — значение claim role у администраторов (выдаёт auth_service)
+const RoleAdmin = "admin"
+
+// GetUserRole извлекает роль из claim role; у токенов без роли — пустая строка
+func GetUserRole(ctx context.Context) string {
+	claims, ok := ctx.Value("claims").(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	role, _ := claims["role"].(string)
+	return role
+}
+
+// EmailVerified — подтверждён ли email (claim email_verified). Токены без claim (выпущенные до подтверждения
+// почты, refresh-токены) считаются неподтверждёнными
+func EmailVerified(ctx context.Context) bool {
+	claims, ok := ctx.Value("claims").(jwt.MapClaims)
+	if !ok {
+		return false
+	}
+	verified, _ := claims["email_verified"].(bool)
+	return verified
+}
+
+// OwnUserID разбирает :id и проверяет, что он совпадает с user_id из токена.
+// При ошибке сам отвечает клиенту 400/403 и возвращает false.
+func OwnUserID(c *gin.Context) (int, bool) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return 0, false
+	}
+
+	tokenUserID, err := GetUserID(c)
+	if err != nil || tokenUserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "you can only manage your own account"})
+		return 0, false
+	}
+	return userID, true
+}
+
+// OrgMember разбирает :org_id и проверяет, что пользователь из токена состоит в организации.
+// При ошибке сам отвечает клиенту 400/401/404/500 и возвращает false. Не участнику организация не раскрывается (404).
+func OrgMember(c *gin.Context, orgs *org_service.OrgService) (orgID, userID int, role string, ok bool) {
+	orgID, err := strconv.Atoi(c.Param("org_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid organization id"})
+		return 0, 0, "", false
+	}
+
+	userID, err = GetUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+		return 0, 0, "", false
+	}
+
+	role, err = orgs.MemberRole(c, orgID, userID)
+	if err != nil {
+		if errors.Is(err, org_service.ErrNotOrgMember) {
+			c.JSON(http.StatusNotFound, gin.H{"error": org_service.ErrOrgNotFound.Error()})
+			return 0, 0, "", false
+		}
+		logrus.Errorf("MemberRole org %d user %d: %v", orgID, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "could not check organization membership"})
+		return 0, 0, "", false
+	}
+	return orgID, userID, role, true
+}
+
+// OrgManager — OrgMember, который дополнительно требует роль owner или admin (иначе 403)
+func OrgManager(c *gin.Context, orgs *org_service.OrgService) (orgID, userID int, ok bool) {
+	orgID, userID, role, ok := OrgMember(c, orgs)
+	if !ok {
+		return 0, 0, false
+	}
+	if !org_service.CanManage(role) {
+		c.JSON(http.StatusForbidden, gin.H{"error": org_service.ErrOrgForbidden.Error()})
+		return 0, 0, false
+	}
+	return orgID, userID, true
+}
+
+// WriteLimitExceeded отвечает status с названием превышенного лимита плана и прерывает обработчик.
+// Возвращает false и ничего не пишет, если err не quota_service.LimitExceededError.
+func WriteLimitExceeded(c *gin.Context, status int, err error) bool {
+	var le *quota_service.LimitExceededError
+	if !errors.As(err, &le) {
+		return false
+	}
+	c.AbortWithStatusJSON(status, dto.LimitExceededErr{
+		Error:  le.Err.Error(),
+		Limit:  le.Limit,
+		Max:    le.Max,
+		Actual: le.Actual,
+	})
+	return true
+}
+
 // Decode из base64 в байты
 func Decode(s string) ([]byte, error) {
 	b, err := base64.StdEncoding.DecodeString(s)
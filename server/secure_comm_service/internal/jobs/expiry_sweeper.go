@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/service/cloud_service"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/sirupsen/logrus"
+)
+
+// sweepBatch — сколько истёкших файлов удаляется за одну итерацию
+const sweepBatch = 100
+
+// ExpirySweeper удаляет файлы с истёкшим сроком жизни и освобождает квоту их владельцев.
+type ExpirySweeper struct {
+	storage cloud_service.Client
+	quota   *quota_service.QuotaService
+}
+
+func NewExpirySweeper(storage cloud_service.Client, quota *quota_service.QuotaService) *ExpirySweeper {
+	return &ExpirySweeper{storage: storage, quota: quota}
+}
+
+// Run удаляет истёкшие файлы пачками, пока очередь на текущий момент не опустеет
+func (s *ExpirySweeper) Run(ctx context.Context) error {
+	const op = "location internal.jobs.ExpirySweeper.Run"
+
+	for {
+		expired, err := s.storage.SweepExpired(ctx, time.Now(), sweepBatch)
+		if err != nil {
+			return err
+		}
+
+		for _, obj := range expired {
+			logrus.Infof("expired file deleted: %s/%s, user %d, %d bytes", obj.Bucket, obj.ObjID, obj.UserID, obj.Size)
+			usageRef := domain.UsageRef{ObjID: obj.ObjID, Category: obj.Bucket, Reason: domain.UsageReasonExpire}
+			if err := s.quota.RemoveUsage(ctx, obj.UserID, obj.Size, usageRef); err != nil {
+				// расхождение current_used поправит реконсилер
+				logrus.Errorf("RemoveUsage user %d: %v, %s", obj.UserID, err, op)
+			}
+		}
+
+		if len(expired) < sweepBatch {
+			return nil
+		}
+	}
+}
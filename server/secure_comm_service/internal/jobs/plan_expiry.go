@@ -0,0 +1,153 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/notifier"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/sirupsen/logrus"
+)
+
+// planExpiryBatch — сколько закончившихся подписок обрабатывается за одну итерацию
+const planExpiryBatch = 100
+
+// PlanExpiryProcessor предупреждает о скором окончании подписок, продлевает бесплатные или переводит на free закончившиеся,
+// снова применяет лимиты плана после истечения персональных лимитов и переводит в read-only аккаунты,
+// не уложившиеся в лимит за grace-период.
+type PlanExpiryProcessor struct {
+	quota      *quota_service.QuotaService
+	renewals   RenewalCheckouts
+	notifier   notifier.Notifier
+	warnBefore time.Duration
+	grace      time.Duration
+}
+
+// RenewalCheckouts создаёт платёжные сессии на продление платных планов, закончившихся без оплаты.
+// Реализуется billing_service.BillingService.
+type RenewalCheckouts interface {
+	CreateRenewalCheckout(ctx context.Context, t domain.PlanTransition) (session domain.CheckoutSession, payerID int, err error)
+}
+
+func NewPlanExpiryProcessor(quota *quota_service.QuotaService, renewals RenewalCheckouts, n notifier.Notifier, warnBefore, grace time.Duration) *PlanExpiryProcessor {
+	return &PlanExpiryProcessor{quota: quota, renewals: renewals, notifier: n, warnBefore: warnBefore, grace: grace}
+}
+
+func (p *PlanExpiryProcessor) Run(ctx context.Context) error {
+	const op = "location internal.jobs.PlanExpiryProcessor.Run"
+
+	if p.warnBefore > 0 {
+		expiring, err := p.quota.ClaimExpiryWarnings(ctx, p.warnBefore)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		for _, plan := range expiring {
+			msg := fmt.Sprintf("plan %s expires at %s", plan.PlanName, plan.ExpiresAt.UTC().Format(time.RFC3339))
+			if plan.AutoRenew && !plan.CancelAtPeriodEnd {
+				msg += ", pay for the next period to keep it, until then the account will be on the free plan"
+			} else {
+				msg += ", after that the account will be moved to the free plan"
+			}
+			p.notify(ctx, notifier.EventPlanExpiring, plan.UserID, msg)
+		}
+	}
+
+	for {
+		transitions, err := p.quota.ProcessLapsedPlans(ctx, p.grace, planExpiryBatch)
+		for _, t := range transitions {
+			p.HandleTransition(ctx, t)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if len(transitions) < planExpiryBatch {
+			break
+		}
+	}
+
+	// истёкшие персональные лимиты: снова действуют лимиты плана, превысившим начинается grace-период
+	for {
+		transitions, err := p.quota.ProcessExpiredOverrides(ctx, p.grace, planExpiryBatch)
+		for _, t := range transitions {
+			p.HandleTransition(ctx, t)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		if len(transitions) < planExpiryBatch {
+			break
+		}
+	}
+
+	readOnly, err := p.quota.EnforceGracePeriods(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	for _, userID := range readOnly {
+		p.notify(ctx, notifier.EventReadOnly, userID, "grace period is over, the account is read-only until usage drops below the plan limit")
+	}
+
+	return nil
+}
+
+// HandleTransition логирует переход подписки и уведомляет пользователя. Вызывается и для подписок,
+// обработанных прямо при обращении к квоте (см. QuotaService.SetLapseHandling).
+func (p *PlanExpiryProcessor) HandleTransition(ctx context.Context, t domain.PlanTransition) {
+	if t.Action == quota_service.PlanActionRenewalDue {
+		p.requestRenewal(ctx, t)
+	}
+	if t.OrgID != 0 {
+		// уведомления настроены по пользователям, переходы планов организаций только логируются
+		logrus.Infof("plan of org %d: %s -> %s (%s)", t.OrgID, t.FromPlan, t.ToPlan, t.Action)
+		return
+	}
+	logrus.Infof("plan of user %d: %s -> %s (%s)", t.UserID, t.FromPlan, t.ToPlan, t.Action)
+
+	switch t.Action {
+	case quota_service.PlanActionRenewed:
+		p.notify(ctx, notifier.EventPlanRenewed, t.UserID, fmt.Sprintf("plan %s has been renewed", t.ToPlan))
+	case quota_service.PlanActionFallbackFree:
+		p.notify(ctx, notifier.EventFallbackFree, t.UserID, fmt.Sprintf("plan %s has expired, the account is now on the free plan", t.FromPlan))
+	}
+
+	if t.GraceUntil != nil {
+		p.notify(ctx, notifier.EventGraceStarted, t.UserID, fmt.Sprintf(
+			"storage usage exceeds the %s plan limit, delete files before %s or the account will become read-only",
+			t.ToPlan, t.GraceUntil.UTC().Format(time.RFC3339)))
+	}
+}
+
+// requestRenewal создаёт платёжную сессию на продление и отправляет плательщику ссылку на оплату.
+// План вернётся только после вебхука об успешной оплате (ApplyPaidPeriod).
+func (p *PlanExpiryProcessor) requestRenewal(ctx context.Context, t domain.PlanTransition) {
+	const op = "location internal.jobs.PlanExpiryProcessor.requestRenewal"
+
+	session, payerID, err := p.renewals.CreateRenewalCheckout(ctx, t)
+	if err != nil {
+		logrus.Errorf("renewal checkout for plan %s (user %d, org %d): %v, %s", t.FromPlan, t.UserID, t.OrgID, err, op)
+		if t.UserID != 0 {
+			p.notify(ctx, notifier.EventRenewalDue, t.UserID, fmt.Sprintf(
+				"plan %s has expired, the account is on the free plan until the next period is paid", t.FromPlan))
+		}
+		return
+	}
+
+	msg := fmt.Sprintf("plan %s has expired, the account is on the free plan until the next period is paid: %s", t.FromPlan, session.CheckoutURL)
+	if t.OrgID != 0 {
+		msg = fmt.Sprintf("plan %s of organization %d has expired, the organization is on the free plan until the next period is paid: %s",
+			t.FromPlan, t.OrgID, session.CheckoutURL)
+	}
+	p.notify(ctx, notifier.EventRenewalDue, payerID, msg)
+}
+
+// notify не прерывает обработку: недоставленное уведомление не должно откатывать смену плана
+func (p *PlanExpiryProcessor) notify(ctx context.Context, eventType string, userID int, msg string) {
+	const op = "location internal.jobs.PlanExpiryProcessor.notify"
+
+	event := notifier.Event{Type: eventType, UserID: userID, Message: msg, At: time.Now()}
+	if err := p.notifier.Notify(ctx, event); err != nil {
+		logrus.Errorf("Notify user %d [%s]: %v, %s", userID, eventType, err, op)
+	}
+}
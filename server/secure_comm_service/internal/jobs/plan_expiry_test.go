@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/notifier"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+)
+
+type fakeRenewals struct {
+	payerID int
+	err     error
+	calls   []domain.PlanTransition
+}
+
+func (f *fakeRenewals) CreateRenewalCheckout(ctx context.Context, t domain.PlanTransition) (domain.CheckoutSession, int, error) {
+	f.calls = append(f.calls, t)
+	if f.err != nil {
+		return domain.CheckoutSession{}, 0, f.err
+	}
+	return domain.CheckoutSession{CheckoutURL: "http://localhost:8080/billing/fake/pay/fake_cs_1"}, f.payerID, nil
+}
+
+type recordingNotifier struct {
+	events []notifier.Event
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, event notifier.Event) error {
+	n.events = append(n.events, event)
+	return nil
+}
+
+// Закончившийся платный план продлевается только через оплату: создаётся сессия, плательщику уходит ссылка
+func TestHandleTransition_RenewalDueCreatesCheckout(t *testing.T) {
+	renewals := &fakeRenewals{payerID: 7}
+	n := &recordingNotifier{}
+	p := NewPlanExpiryProcessor(nil, renewals, n, 0, 0)
+
+	p.HandleTransition(context.Background(), domain.PlanTransition{UserID: 7, FromPlan: "pro", ToPlan: "free", Action: quota_service.PlanActionRenewalDue})
+
+	if len(renewals.calls) != 1 {
+		t.Fatalf("CreateRenewalCheckout called %d times, want 1", len(renewals.calls))
+	}
+	if len(n.events) != 1 || n.events[0].Type != notifier.EventRenewalDue || n.events[0].UserID != 7 {
+		t.Fatalf("events = %+v, want one renewal_due for user 7", n.events)
+	}
+	if !strings.Contains(n.events[0].Message, "/billing/fake/pay/fake_cs_1") {
+		t.Errorf("message %q has no checkout url", n.events[0].Message)
+	}
+}
+
+// За организацию платит owner: ссылка уходит ему
+func TestHandleTransition_OrgRenewalNotifiesOwner(t *testing.T) {
+	renewals := &fakeRenewals{payerID: 5}
+	n := &recordingNotifier{}
+	p := NewPlanExpiryProcessor(nil, renewals, n, 0, 0)
+
+	p.HandleTransition(context.Background(), domain.PlanTransition{OrgID: 3, FromPlan: "team", ToPlan: "free", Action: quota_service.PlanActionRenewalDue})
+
+	if len(n.events) != 1 || n.events[0].UserID != 5 {
+		t.Fatalf("events = %+v, want one for owner 5", n.events)
+	}
+}
+
+func TestHandleTransition_CheckoutErrorStillNotifies(t *testing.T) {
+	renewals := &fakeRenewals{err: errors.New("provider unavailable")}
+	n := &recordingNotifier{}
+	p := NewPlanExpiryProcessor(nil, renewals, n, 0, 0)
+
+	p.HandleTransition(context.Background(), domain.PlanTransition{UserID: 7, FromPlan: "pro", ToPlan: "free", Action: quota_service.PlanActionRenewalDue})
+
+	if len(n.events) != 1 || n.events[0].Type != notifier.EventRenewalDue {
+		t.Fatalf("events = %+v, want one renewal_due", n.events)
+	}
+}
+
+func TestHandleTransition_FallbackDoesNotCreateCheckout(t *testing.T) {
+	renewals := &fakeRenewals{}
+	n := &recordingNotifier{}
+	p := NewPlanExpiryProcessor(nil, renewals, n, 0, 0)
+
+	p.HandleTransition(context.Background(), domain.PlanTransition{UserID: 7, FromPlan: "pro", ToPlan: "free", Action: quota_service.PlanActionFallbackFree})
+
+	if len(renewals.calls) != 0 {
+		t.Errorf("CreateRenewalCheckout called %d times for a canceled plan", len(renewals.calls))
+	}
+	if len(n.events) != 1 || n.events[0].Type != notifier.EventFallbackFree {
+		t.Errorf("events = %+v, want one fallback_free", n.events)
+	}
+}
+
+// Истекли персональные лимиты, а занято больше лимита плана: пользователь узнаёт о grace-периоде
+func TestHandleTransition_OverrideExpiredWithGrace(t *testing.T) {
+	n := &recordingNotifier{}
+	p := NewPlanExpiryProcessor(nil, &fakeRenewals{}, n, 0, 0)
+	graceUntil := time.Now().Add(time.Hour)
+
+	p.HandleTransition(context.Background(), domain.PlanTransition{UserID: 7, FromPlan: "pro", ToPlan: "pro", Action: quota_service.PlanActionOverrideExpired, GraceUntil: &graceUntil})
+
+	if len(n.events) != 1 || n.events[0].Type != notifier.EventGraceStarted {
+		t.Fatalf("events = %+v, want one grace_started", n.events)
+	}
+}
@@ -0,0 +1,248 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/1abobik1/SecureComm/internal/service/cloud_service"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/sirupsen/logrus"
+)
+
+// uploadGracePeriod — пользователи с объектами моложе этого срока пропускаются:
+// объект уже в MinIO, а AddUsage ещё может не выполниться.
+const uploadGracePeriod = 10 * time.Minute
+
+// UsageDrift — расхождение current_used с фактическим объёмом файлов пользователя или организации (OrgID)
+type UsageDrift struct {
+	UserID      int   `json:"user_id,omitempty"`
+	OrgID       int   `json:"org_id,omitempty"`
+	CurrentUsed int64 `json:"current_used"`
+	ActualUsed  int64 `json:"actual_used"`
+	Files       int   `json:"files"`
+	Fixed       bool  `json:"fixed"`
+}
+
+// FileCountDrift — расхождение учтённого числа файлов (лимит max_files) с фактическим
+type FileCountDrift struct {
+	UserID       int   `json:"user_id,omitempty"`
+	OrgID        int   `json:"org_id,omitempty"`
+	CountedFiles int64 `json:"counted_files"`
+	ActualFiles  int64 `json:"actual_files"`
+	Fixed        bool  `json:"fixed"`
+}
+
+// ReconcileReport — результат одного прохода реконсилера
+type ReconcileReport struct {
+	StartedAt       time.Time                    `json:"started_at"`
+	DryRun          bool                         `json:"dry_run"`
+	UsageDrifts     []UsageDrift                 `json:"usage_drifts"`
+	FileCountDrifts []FileCountDrift             `json:"file_count_drifts"`
+	SkippedUsers    []int                        `json:"skipped_users"`
+	NoPlanUsers     []int                        `json:"no_plan_users"`
+	SkippedOrgs     []int                        `json:"skipped_orgs"`
+	NoPlanOrgs      []int                        `json:"no_plan_orgs"`
+	StaleCacheKeys  []string                     `json:"stale_cache_keys"`
+	Orphans         []cloud_service.OrphanObject `json:"orphans"`
+	PurgedOrphans   int                          `json:"purged_orphans"`
+}
+
+// ReconcilerOptions управляет тем, что реконсилер имеет право исправлять
+type ReconcilerOptions struct {
+	DryRun       bool // только отчёт, без изменений
+	PurgeOrphans bool // удалять объекты без владельца (только если не DryRun)
+}
+
+// Reconciler сверяет объекты MinIO, user_plans.current_used, число файлов пользователей и организаций и кеш Redis.
+type Reconciler struct {
+	storage cloud_service.Client
+	quota   *quota_service.QuotaService
+	opts    ReconcilerOptions
+}
+
+func NewReconciler(storage cloud_service.Client, quota *quota_service.QuotaService, opts ReconcilerOptions) *Reconciler {
+	return &Reconciler{storage: storage, quota: quota, opts: opts}
+}
+
+// Run выполняет один проход и возвращает отчёт.
+func (r *Reconciler) Run(ctx context.Context) (ReconcileReport, error) {
+	const op = "location internal.jobs.Reconciler.Run"
+
+	report := ReconcileReport{StartedAt: time.Now().UTC(), DryRun: r.opts.DryRun}
+
+	userPools := poolAccounting{
+		kind:         "user",
+		listUsage:    r.quota.ListActiveUsage,
+		listFiles:    r.quota.ListFileCounts,
+		setUsage:     r.quota.SetUsage,
+		setFileCount: r.quota.SetFileCount,
+	}
+	orgPools := poolAccounting{
+		kind:         "org",
+		listUsage:    r.quota.ListActiveOrgUsage,
+		listFiles:    r.quota.ListOrgFileCounts,
+		setUsage:     r.quota.SetOrgUsage,
+		setFileCount: r.quota.SetOrgFileCount,
+	}
+
+	// учёт читается до сканирования MinIO: исправления применяются только к владельцам,
+	// у которых с этого момента не было записей в журнале (загрузок и удалений во время сканирования)
+	watermark, err := r.quota.UsageWatermark(ctx)
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", op, err)
+	}
+	userSnap, err := userPools.snapshot(ctx, watermark)
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", op, err)
+	}
+	orgSnap, err := orgPools.snapshot(ctx, watermark)
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", op, err)
+	}
+
+	users, orgs, orphans, err := r.storage.ScanUsage(ctx)
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", op, err)
+	}
+	report.Orphans = orphans
+
+	pools := r.reconcilePools(ctx, users, userPools, userSnap)
+	report.UsageDrifts, report.FileCountDrifts = pools.usageDrifts, pools.fileCountDrifts
+	report.SkippedUsers, report.NoPlanUsers = pools.skipped, pools.noPlan
+
+	pools = r.reconcilePools(ctx, orgs, orgPools, orgSnap)
+	report.UsageDrifts = append(report.UsageDrifts, pools.usageDrifts...)
+	report.FileCountDrifts = append(report.FileCountDrifts, pools.fileCountDrifts...)
+	report.SkippedOrgs, report.NoPlanOrgs = pools.skipped, pools.noPlan
+
+	report.StaleCacheKeys, err = r.storage.PurgeStaleCache(ctx, r.opts.DryRun)
+	if err != nil {
+		return report, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if r.opts.PurgeOrphans && !r.opts.DryRun {
+		for _, orphan := range orphans {
+			if err := r.storage.RemoveObject(ctx, dto.ObjectID{ObjID: orphan.ObjID, FileCategory: orphan.Bucket}); err != nil {
+				logrus.Errorf("purge orphan %s/%s: %v, %s", orphan.Bucket, orphan.ObjID, err, op)
+				continue
+			}
+			report.PurgedOrphans++
+		}
+	}
+
+	return report, nil
+}
+
+// poolAccounting — учёт пулов одного вида (личных или организаций) в quota_service
+type poolAccounting struct {
+	kind         string // user | org
+	listUsage    func(ctx context.Context) (map[int]int64, error)
+	listFiles    func(ctx context.Context) (map[int]int64, error)
+	setUsage     func(ctx context.Context, id int, expected, actual, sinceLedgerID int64) (bool, error)
+	setFileCount func(ctx context.Context, id int, expected, actual, sinceLedgerID int64) (bool, error)
+}
+
+// poolSnapshot — учтённые current_used и число файлов на момент watermark (до сканирования MinIO)
+type poolSnapshot struct {
+	watermark  int64
+	usage      map[int]int64
+	fileCounts map[int]int64
+}
+
+func (a poolAccounting) snapshot(ctx context.Context, watermark int64) (poolSnapshot, error) {
+	usage, err := a.listUsage(ctx)
+	if err != nil {
+		return poolSnapshot{}, err
+	}
+	fileCounts, err := a.listFiles(ctx)
+	if err != nil {
+		return poolSnapshot{}, err
+	}
+	return poolSnapshot{watermark: watermark, usage: usage, fileCounts: fileCounts}, nil
+}
+
+// owner заполняет в расхождении ID пользователя или организации
+func (a poolAccounting) owner(id int) (userID, orgID int) {
+	if a.kind == "org" {
+		return 0, id
+	}
+	return id, 0
+}
+
+type poolReport struct {
+	usageDrifts     []UsageDrift
+	fileCountDrifts []FileCountDrift
+	skipped         []int
+	noPlan          []int
+}
+
+// reconcilePools сверяет фактический объём и число файлов stored с учтёнными в snap.
+// Исправление пишется, только если учёт не менялся после snap.watermark, иначе оно достанется следующему проходу.
+func (r *Reconciler) reconcilePools(ctx context.Context, stored map[int]cloud_service.StoredUsage, acc poolAccounting, snap poolSnapshot) poolReport {
+	const op = "location internal.jobs.Reconciler.reconcilePools"
+
+	var report poolReport
+	accounted, fileCounts := snap.usage, snap.fileCounts
+
+	for id := range stored {
+		if _, ok := accounted[id]; !ok {
+			report.noPlan = append(report.noPlan, id)
+		}
+	}
+
+	for id, currentUsed := range accounted {
+		u := stored[id]
+		if time.Since(u.LastModified) < uploadGracePeriod {
+			report.skipped = append(report.skipped, id)
+			continue
+		}
+		userID, orgID := acc.owner(id)
+		if counted := fileCounts[id]; counted != int64(u.Files) {
+			drift := FileCountDrift{UserID: userID, OrgID: orgID, CountedFiles: counted, ActualFiles: int64(u.Files)}
+			if !r.opts.DryRun {
+				fixed, err := acc.setFileCount(ctx, id, counted, int64(u.Files), snap.watermark)
+				if err != nil {
+					logrus.Errorf("SetFileCount %s %d: %v, %s", acc.kind, id, err, op)
+				}
+				drift.Fixed = fixed
+			}
+			report.fileCountDrifts = append(report.fileCountDrifts, drift)
+		}
+		if u.Bytes == currentUsed {
+			continue
+		}
+
+		drift := UsageDrift{UserID: userID, OrgID: orgID, CurrentUsed: currentUsed, ActualUsed: u.Bytes, Files: u.Files}
+		if !r.opts.DryRun {
+			fixed, err := acc.setUsage(ctx, id, currentUsed, u.Bytes, snap.watermark)
+			if err != nil {
+				logrus.Errorf("SetUsage %s %d: %v, %s", acc.kind, id, err, op)
+			}
+			drift.Fixed = fixed
+		}
+		report.usageDrifts = append(report.usageDrifts, drift)
+	}
+
+	return report
+}
+
+// RunAndLog — обёртка для планировщика: выполняет проход и пишет краткий итог в лог
+func (r *Reconciler) RunAndLog(ctx context.Context) error {
+	report, err := r.Run(ctx)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("reconciler: dry_run=%v usage_drifts=%d file_count_drifts=%d skipped=%d no_plan=%d skipped_orgs=%d no_plan_orgs=%d stale_cache=%d orphans=%d purged=%d",
+		report.DryRun, len(report.UsageDrifts), len(report.FileCountDrifts), len(report.SkippedUsers), len(report.NoPlanUsers),
+		len(report.SkippedOrgs), len(report.NoPlanOrgs), len(report.StaleCacheKeys), len(report.Orphans), report.PurgedOrphans)
+	for _, d := range report.UsageDrifts {
+		if d.OrgID != 0 {
+			logrus.Warnf("reconciler: org %d current_used=%d actual=%d fixed=%v", d.OrgID, d.CurrentUsed, d.ActualUsed, d.Fixed)
+			continue
+		}
+		logrus.Warnf("reconciler: user %d current_used=%d actual=%d fixed=%v", d.UserID, d.CurrentUsed, d.ActualUsed, d.Fixed)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/service/cloud_service"
+)
+
+// fakePools — учёт в памяти: set* применяется, только если значение совпадает с expected
+// и после sinceLedgerID не было изменений (changed[id] — ID последней записи журнала владельца)
+type fakePools struct {
+	usage   map[int]int64
+	files   map[int]int64
+	changed map[int]int64
+	calls   []int64 // sinceLedgerID каждого вызова set*
+}
+
+func (f *fakePools) accounting(kind string) poolAccounting {
+	return poolAccounting{
+		kind:      kind,
+		listUsage: func(ctx context.Context) (map[int]int64, error) { return copyMap(f.usage), nil },
+		listFiles: func(ctx context.Context) (map[int]int64, error) { return copyMap(f.files), nil },
+		setUsage: func(ctx context.Context, id int, expected, actual, since int64) (bool, error) {
+			f.calls = append(f.calls, since)
+			if f.usage[id] != expected || f.changed[id] > since {
+				return false, nil
+			}
+			f.usage[id] = actual
+			return true, nil
+		},
+		setFileCount: func(ctx context.Context, id int, expected, actual, since int64) (bool, error) {
+			f.calls = append(f.calls, since)
+			if f.files[id] != expected || f.changed[id] > since {
+				return false, nil
+			}
+			f.files[id] = actual
+			return true, nil
+		},
+	}
+}
+
+func copyMap(m map[int]int64) map[int]int64 {
+	out := make(map[int]int64, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
+func stored(bytes int64, files int) cloud_service.StoredUsage {
+	return cloud_service.StoredUsage{Bytes: bytes, Files: files, LastModified: time.Now().Add(-time.Hour)}
+}
+
+func TestReconcilePools_FixesDrift(t *testing.T) {
+	pools := &fakePools{
+		usage:   map[int]int64{7: 500},
+		files:   map[int]int64{7: 3},
+		changed: map[int]int64{},
+	}
+	acc := pools.accounting("user")
+	snap, err := acc.snapshot(context.Background(), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Reconciler{}
+	report := r.reconcilePools(context.Background(), map[int]cloud_service.StoredUsage{7: stored(300, 2)}, acc, snap)
+
+	if len(report.usageDrifts) != 1 || !report.usageDrifts[0].Fixed {
+		t.Fatalf("usage drifts = %+v, want one fixed", report.usageDrifts)
+	}
+	if len(report.fileCountDrifts) != 1 || !report.fileCountDrifts[0].Fixed {
+		t.Fatalf("file count drifts = %+v, want one fixed", report.fileCountDrifts)
+	}
+	if pools.usage[7] != 300 || pools.files[7] != 2 {
+		t.Errorf("usage=%d files=%d, want 300/2", pools.usage[7], pools.files[7])
+	}
+	for _, since := range pools.calls {
+		if since != 42 {
+			t.Errorf("sinceLedgerID = %d, want watermark 42", since)
+		}
+	}
+}
+
+// Файл загружен после того, как сканер прошёл его бакет: MinIO-скан его не видит,
+// а AddUsage уже записал журнал после watermark — current_used нельзя уменьшать.
+func TestReconcilePools_UploadDuringScanNotOverwritten(t *testing.T) {
+	pools := &fakePools{
+		usage:   map[int]int64{7: 500},
+		files:   map[int]int64{7: 3},
+		changed: map[int]int64{},
+	}
+	acc := pools.accounting("user")
+	snap, err := acc.snapshot(context.Background(), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// во время сканирования: +100 байт и ещё один файл
+	pools.usage[7] += 100
+	pools.files[7]++
+	pools.changed[7] = 43
+
+	r := &Reconciler{}
+	report := r.reconcilePools(context.Background(), map[int]cloud_service.StoredUsage{7: stored(500, 3)}, acc, snap)
+
+	if pools.usage[7] != 600 || pools.files[7] != 4 {
+		t.Errorf("usage=%d files=%d, want 600/4 untouched", pools.usage[7], pools.files[7])
+	}
+	for _, d := range report.usageDrifts {
+		if d.Fixed {
+			t.Errorf("usage drift %+v fixed, want skipped", d)
+		}
+	}
+	for _, d := range report.fileCountDrifts {
+		if d.Fixed {
+			t.Errorf("file count drift %+v fixed, want skipped", d)
+		}
+	}
+}
+
+// Удаление между листингом и сверкой возвращает current_used к снимку (+X, -X),
+// но журнал всё равно менялся — исправление не применяется.
+func TestReconcilePools_NetZeroChangeDuringScan(t *testing.T) {
+	pools := &fakePools{
+		usage:   map[int]int64{7: 500},
+		files:   map[int]int64{7: 3},
+		changed: map[int]int64{7: 50},
+	}
+	acc := pools.accounting("user")
+	snap, err := acc.snapshot(context.Background(), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Reconciler{}
+	report := r.reconcilePools(context.Background(), map[int]cloud_service.StoredUsage{7: stored(400, 3)}, acc, snap)
+
+	if pools.usage[7] != 500 {
+		t.Errorf("usage = %d, want 500 untouched", pools.usage[7])
+	}
+	if len(report.usageDrifts) != 1 || report.usageDrifts[0].Fixed {
+		t.Errorf("usage drifts = %+v, want one unfixed", report.usageDrifts)
+	}
+}
+
+func TestReconcilePools_SkipsRecentUploadsAndReportsNoPlan(t *testing.T) {
+	pools := &fakePools{
+		usage:   map[int]int64{7: 500},
+		files:   map[int]int64{7: 3},
+		changed: map[int]int64{},
+	}
+	acc := pools.accounting("user")
+	snap, err := acc.snapshot(context.Background(), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recent := cloud_service.StoredUsage{Bytes: 700, Files: 4, LastModified: time.Now()}
+	r := &Reconciler{}
+	report := r.reconcilePools(context.Background(), map[int]cloud_service.StoredUsage{7: recent, 9: stored(10, 1)}, acc, snap)
+
+	if len(report.skipped) != 1 || report.skipped[0] != 7 {
+		t.Errorf("skipped = %v, want [7]", report.skipped)
+	}
+	if len(report.noPlan) != 1 || report.noPlan[0] != 9 {
+		t.Errorf("noPlan = %v, want [9]", report.noPlan)
+	}
+	if pools.usage[7] != 500 {
+		t.Errorf("usage = %d, want 500 untouched", pools.usage[7])
+	}
+}
+
+func TestReconcilePools_OrgDriftReportsOrgID(t *testing.T) {
+	pools := &fakePools{
+		usage:   map[int]int64{3: 0},
+		files:   map[int]int64{3: 0},
+		changed: map[int]int64{},
+	}
+	acc := pools.accounting("org")
+	snap, err := acc.snapshot(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Reconciler{}
+	report := r.reconcilePools(context.Background(), map[int]cloud_service.StoredUsage{3: stored(100, 1)}, acc, snap)
+
+	if len(report.usageDrifts) != 1 {
+		t.Fatalf("usage drifts = %+v, want one", report.usageDrifts)
+	}
+	d := report.usageDrifts[0]
+	if d.OrgID != 3 || d.UserID != 0 || !d.Fixed {
+		t.Errorf("drift = %+v, want org 3 fixed", d)
+	}
+	if pools.usage[3] != 100 || pools.files[3] != 1 {
+		t.Errorf("usage=%d files=%d, want 100/1", pools.usage[3], pools.files[3])
+	}
+}
+
+func TestReconcilePools_DryRun(t *testing.T) {
+	pools := &fakePools{
+		usage:   map[int]int64{7: 500},
+		files:   map[int]int64{7: 3},
+		changed: map[int]int64{},
+	}
+	acc := pools.accounting("user")
+	snap, err := acc.snapshot(context.Background(), 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Reconciler{opts: ReconcilerOptions{DryRun: true}}
+	report := r.reconcilePools(context.Background(), map[int]cloud_service.StoredUsage{7: stored(300, 2)}, acc, snap)
+
+	if len(report.usageDrifts) != 1 || report.usageDrifts[0].Fixed {
+		t.Errorf("usage drifts = %+v, want one unfixed", report.usageDrifts)
+	}
+	if len(pools.calls) != 0 {
+		t.Errorf("set* called %d times in dry run", len(pools.calls))
+	}
+}
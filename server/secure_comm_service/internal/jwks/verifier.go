@@ -0,0 +1,188 @@
+package jwks
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrNoKID      = errors.New("token has no kid header")
+	ErrUnknownKID = errors.New("unknown kid")
+)
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type jwkSet struct {
+	Keys []jwk `json:"keys"`
+}
+
+// Verifier выбирает открытый ключ для проверки JWT по kid из JWKS auth_service.
+// Набор кешируется на cacheTTL; токен с неизвестным kid (auth_service сменил ключ) вызывает
+// внеочередное обновление, но не чаще minRefreshInterval, чтобы поддельные kid не нагружали auth_service.
+// Если auth_service недоступен, используются ранее полученные ключи
+type Verifier struct {
+	url                string
+	client             *http.Client
+	cacheTTL           time.Duration
+	minRefreshInterval time.Duration
+
+	refreshMu sync.Mutex // не больше одного запроса к auth_service одновременно
+
+	mu          sync.RWMutex
+	keys        map[string]*rsa.PublicKey
+	fetchedAt   time.Time
+	lastAttempt time.Time
+}
+
+func NewVerifier(url string, cacheTTL, minRefreshInterval time.Duration) *Verifier {
+	return &Verifier{
+		url:                url,
+		client:             &http.Client{Timeout: 5 * time.Second},
+		cacheTTL:           cacheTTL,
+		minRefreshInterval: minRefreshInterval,
+		keys:               map[string]*rsa.PublicKey{},
+	}
+}
+
+// Keyfunc для jwt.Parse: принимает только RS256 и токены с kid
+func (v *Verifier) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() != jwt.SigningMethodRS256.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, ErrNoKID
+	}
+
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	fresh := time.Since(v.fetchedAt) < v.cacheTTL
+	v.mu.RUnlock()
+	if ok && fresh {
+		return key, nil
+	}
+
+	return v.refreshFor(kid)
+}
+
+// Refresh загружает JWKS из auth_service
+func (v *Verifier) Refresh(ctx context.Context) error {
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+	return v.fetch(ctx)
+}
+
+// refreshFor обновляет набор ради kid, которого нет в кеше или кеш устарел
+func (v *Verifier) refreshFor(kid string) (*rsa.PublicKey, error) {
+	v.refreshMu.Lock()
+	defer v.refreshMu.Unlock()
+
+	// пока ждали блокировку, набор мог обновить другой запрос
+	v.mu.RLock()
+	key, ok := v.keys[kid]
+	fresh := time.Since(v.fetchedAt) < v.cacheTTL
+	throttled := time.Since(v.lastAttempt) < v.minRefreshInterval
+	v.mu.RUnlock()
+	if ok && (fresh || throttled) {
+		return key, nil
+	}
+	if throttled {
+		return nil, fmt.Errorf("kid %s: %w", kid, ErrUnknownKID)
+	}
+
+	if err := v.fetch(context.Background()); err != nil {
+		if ok {
+			logrus.Warnf("jwks refresh failed, using cached key %s: %v", kid, err)
+			return key, nil
+		}
+		return nil, err
+	}
+
+	v.mu.RLock()
+	key, ok = v.keys[kid]
+	v.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("kid %s: %w", kid, ErrUnknownKID)
+	}
+	return key, nil
+}
+
+// fetch вызывается под refreshMu
+func (v *Verifier) fetch(ctx context.Context) error {
+	v.mu.Lock()
+	v.lastAttempt = time.Now()
+	v.mu.Unlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.url, nil)
+	if err != nil {
+		return fmt.Errorf("new jwks request: %w", err)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("fetch jwks %s: %w", v.url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetch jwks %s: status %d", v.url, resp.StatusCode)
+	}
+
+	var set jwkSet
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return fmt.Errorf("decode jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || k.Kid == "" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := parseRSA(k)
+		if err != nil {
+			logrus.Warnf("skip jwk %s: %v", k.Kid, err)
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return errors.New("jwks has no usable RSA keys")
+	}
+
+	v.mu.Lock()
+	v.keys = keys
+	v.fetchedAt = time.Now()
+	v.mu.Unlock()
+	return nil
+}
+
+func parseRSA(k jwk) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("decode n: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("decode e: %w", err)
+	}
+	exp := new(big.Int).SetBytes(e)
+	if !exp.IsInt64() || exp.Int64() < 3 || exp.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid exponent")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+}
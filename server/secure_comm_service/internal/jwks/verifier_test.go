@@ -0,0 +1,137 @@
+package jwks
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type fakeAuth struct {
+	mu       sync.Mutex
+	keys     map[string]*rsa.PrivateKey
+	requests atomic.Int32
+}
+
+func (f *fakeAuth) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.requests.Add(1)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	set := jwkSet{}
+	for kid, key := range f.keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Use: "sig",
+			Kid: kid,
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	json.NewEncoder(w).Encode(set)
+}
+
+func (f *fakeAuth) addKey(t *testing.T, kid string) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	f.mu.Lock()
+	f.keys[kid] = key
+	f.mu.Unlock()
+	return key
+}
+
+func sign(t *testing.T, key *rsa.PrivateKey, kid string) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{Subject: "1"})
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return s
+}
+
+func TestVerifier_RefreshesOnUnknownKID(t *testing.T) {
+	auth := &fakeAuth{keys: map[string]*rsa.PrivateKey{}}
+	oldKey := auth.addKey(t, "old")
+	srv := httptest.NewServer(auth)
+	defer srv.Close()
+
+	v := NewVerifier(srv.URL, time.Hour, 0)
+	if _, err := jwt.Parse(sign(t, oldKey, "old"), v.Keyfunc); err != nil {
+		t.Fatalf("old key: %v", err)
+	}
+
+	// ротация в auth_service: новый kid должен подтянуться без ожидания cacheTTL
+	newKey := auth.addKey(t, "new")
+	if _, err := jwt.Parse(sign(t, newKey, "new"), v.Keyfunc); err != nil {
+		t.Fatalf("new key: %v", err)
+	}
+	if got := auth.requests.Load(); got != 2 {
+		t.Fatalf("jwks requests = %d, want 2", got)
+	}
+
+	// известный kid берётся из кеша
+	if _, err := jwt.Parse(sign(t, oldKey, "old"), v.Keyfunc); err != nil {
+		t.Fatalf("old key after rotation: %v", err)
+	}
+	if got := auth.requests.Load(); got != 2 {
+		t.Fatalf("jwks requests = %d, want 2", got)
+	}
+}
+
+func TestVerifier_UnknownKIDIsThrottled(t *testing.T) {
+	auth := &fakeAuth{keys: map[string]*rsa.PrivateKey{}}
+	auth.addKey(t, "current")
+	srv := httptest.NewServer(auth)
+	defer srv.Close()
+
+	v := NewVerifier(srv.URL, time.Hour, time.Minute)
+	forged, _ := rsa.GenerateKey(rand.Reader, 2048)
+	for i := 0; i < 3; i++ {
+		_, err := jwt.Parse(sign(t, forged, "forged"), v.Keyfunc)
+		if !errors.Is(err, ErrUnknownKID) {
+			t.Fatalf("attempt %d: err = %v, want ErrUnknownKID", i, err)
+		}
+	}
+	if got := auth.requests.Load(); got != 1 {
+		t.Fatalf("jwks requests = %d, want 1", got)
+	}
+}
+
+func TestVerifier_UsesCachedKeysWhenAuthIsDown(t *testing.T) {
+	auth := &fakeAuth{keys: map[string]*rsa.PrivateKey{}}
+	key := auth.addKey(t, "current")
+	srv := httptest.NewServer(auth)
+
+	v := NewVerifier(srv.URL, time.Nanosecond, 0)
+	if _, err := jwt.Parse(sign(t, key, "current"), v.Keyfunc); err != nil {
+		t.Fatalf("first parse: %v", err)
+	}
+	srv.Close()
+
+	if _, err := jwt.Parse(sign(t, key, "current"), v.Keyfunc); err != nil {
+		t.Fatalf("parse with auth_service down: %v", err)
+	}
+}
+
+func TestVerifier_RejectsTokenWithoutKID(t *testing.T) {
+	v := NewVerifier("http://127.0.0.1:0", time.Hour, time.Minute)
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	s, _ := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.RegisteredClaims{}).SignedString(key)
+
+	if _, err := jwt.Parse(s, v.Keyfunc); !errors.Is(err, ErrNoKID) {
+		t.Fatalf("err = %v, want ErrNoKID", err)
+	}
+}
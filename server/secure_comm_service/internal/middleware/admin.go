@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// AdminOnly пропускает только токены с claim role = admin. Ставится после JWTMiddleware.
+// Роль берётся из access-токена, поэтому снятие прав вступает в силу после его истечения.
+func AdminOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if utils.GetUserRole(c) != utils.RoleAdmin {
+			userID, _ := utils.GetUserID(c)
+			logrus.Warnf("user %d tried to access admin API %s %s", userID, c.Request.Method, c.FullPath())
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin role required"})
+			return
+		}
+		c.Next()
+	}
+}
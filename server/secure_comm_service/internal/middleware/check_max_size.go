@@ -5,14 +5,15 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
-var ErrFileTooLarge = errors.New("file too large")
-
-const MaxFileSize = 5 << 30 // 5 GiB
-
 type countingReader struct {
 	R     io.ReadCloser
 	read  int64
@@ -23,7 +24,12 @@ func (cr *countingReader) Read(p []byte) (int, error) {
 	n, err := cr.R.Read(p)
 	cr.read += int64(n)
 	if cr.read > cr.limit {
-		return n, ErrFileTooLarge
+		return n, &quota_service.LimitExceededError{
+			Limit:  quota_service.LimitMaxFileSize,
+			Max:    cr.limit,
+			Actual: cr.read,
+			Err:    quota_service.ErrFileTooLarge,
+		}
 	}
 	return n, err
 }
@@ -32,24 +38,67 @@ func (cr *countingReader) Close() error {
 	return cr.R.Close()
 }
 
-func MaxStreamMiddleware(limit int64) gin.HandlerFunc {
+// MaxFileSizeMiddleware ограничивает размер загружаемого файла лимитом max_file_size действующего плана.
+// Content-Length проверяется сразу (413), а тело без длины обрывается с quota_service.ErrFileTooLarge при чтении.
+// Ставится после JWTMiddleware.
+func MaxFileSizeMiddleware(quota *quota_service.QuotaService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Request.Body = &countingReader{
-			R:     c.Request.Body,
-			limit: limit,
+		userID, err := utils.GetUserID(c)
+		if err != nil {
+			c.Next()
+			return
 		}
-		c.Next()
+
+		limits, err := quota.GetLimits(c.Request.Context(), userID)
+		limitFileSize(c, limits, err, fmt.Sprintf("user %d", userID))
 	}
 }
 
-func MaxSizeMiddleware(limit int64) gin.HandlerFunc {
+// OrgMaxFileSizeMiddleware — MaxFileSizeMiddleware для загрузки в пул организации :org_id.
+// Членство в организации проверяет хендлер.
+func OrgMaxFileSizeMiddleware(quota *quota_service.QuotaService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if c.Request.ContentLength > limit {
-			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
-				"error": fmt.Sprintf("file is too large: max %d bytes allowed", limit),
-			})
+		orgID, err := strconv.Atoi(c.Param("org_id"))
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		limits, err := quota.GetOrgLimits(c.Request.Context(), orgID)
+		limitFileSize(c, limits, err, fmt.Sprintf("org %d", orgID))
+	}
+}
+
+// limitFileSize применяет max_file_size из limits к телу запроса. owner — для лога.
+func limitFileSize(c *gin.Context, limits domain.PlanLimits, err error, owner string) {
+	if err != nil {
+		if !errors.Is(err, quota_service.ErrNoActivePlan) {
+			logrus.Errorf("GetLimits %s: %v", owner, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not load plan limits"})
 			return
 		}
+		// без плана лимит не определён, ошибку вернёт сам хендлер
+		c.Next()
+		return
+	}
+	if limits.MaxFileSize == 0 {
 		c.Next()
+		return
+	}
+
+	if c.Request.ContentLength > limits.MaxFileSize {
+		utils.WriteLimitExceeded(c, http.StatusRequestEntityTooLarge, &quota_service.LimitExceededError{
+			Limit:  quota_service.LimitMaxFileSize,
+			Max:    limits.MaxFileSize,
+			Actual: c.Request.ContentLength,
+			Err:    quota_service.ErrFileTooLarge,
+		})
+		return
+	}
+
+	c.Request.Body = &countingReader{
+		R:     c.Request.Body,
+		limit: limits.MaxFileSize,
 	}
+	c.Next()
 }
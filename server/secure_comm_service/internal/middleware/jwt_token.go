@@ -1,12 +1,11 @@
 package middleware
 
 import (
-	"crypto/rsa"
+	"context"
 	"errors"
-	"fmt"
 	"net/http"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
@@ -16,7 +15,16 @@ import (
 var ErrTokenExpired = errors.New("token is expired")
 var ErrTokenInvalid = errors.New("invalid token")
 
-func JWTMiddleware(publicKeyPath string) gin.HandlerFunc {
+// TokenRevocations — список отзыва access токенов, который ведёт auth_service
+type TokenRevocations interface {
+	IsRevoked(ctx context.Context, jti, sessionID string, userID int, issuedAt time.Time) (bool, error)
+}
+
+// JWTMiddleware пропускает пользовательские access-токены и кладёт их claims в "claims".
+// Сервисные и refresh-токены не принимаются.
+// Отозванные токены (по jti, сессии устройства sid или отзыву всех токенов пользователя) отклоняются
+// до истечения срока действия
+func JWTMiddleware(keyfunc jwt.Keyfunc, revocations TokenRevocations) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if c.Request.Method == http.MethodOptions {
 			c.Next()
@@ -31,28 +39,59 @@ func JWTMiddleware(publicKeyPath string) gin.HandlerFunc {
 		}
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		claims, err := ValidateToken(tokenString, publicKeyPath)
+		claims, err := ValidateToken(tokenString, keyfunc)
 		if err != nil {
 			logrus.Errorf("Error ValidateToken: %v", err)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			return
 		}
+		// сервисные токены годятся только для внутреннего API
+		if isServiceToken(claims) {
+			logrus.Warn("Error service token used on public API")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return
+		}
+		// refresh-токен не попадает в список отзыва по jti и sid, поэтому вместо access-токена не принимается
+		if !isAccessToken(claims) {
+			logrus.Warn("Error non-access token used on public API")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "access token required"})
+			return
+		}
+		if revocations != nil {
+			jti, _ := claims["jti"].(string)
+			sessionID, _ := claims["sid"].(string)
+			userID, _ := claims["user_id"].(float64)
+			iat, _ := claims["iat"].(float64)
+			isRevoked, err := revocations.IsRevoked(c, jti, sessionID, int(userID), time.Unix(int64(iat), 0))
+			if err != nil {
+				logrus.Errorf("Error checking token revocation: %v", err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "could not check token"})
+				return
+			}
+			if isRevoked {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "token revoked"})
+				return
+			}
+		}
 		c.Set("claims", claims)
 		c.Next()
 	}
 }
 
-func ValidateToken(tokenString, publicKeyPath string) (jwt.MapClaims, error) {
-	publicKey, err := getPublicKey(publicKeyPath)
-	if err != nil {
-		return nil, fmt.Errorf("error loading public key from file: %s", publicKeyPath)
-	}
+// isAccessToken — access-токен auth_service несёт claim role и не несёт claim fam (семейство refresh-токенов)
+func isAccessToken(claims jwt.MapClaims) bool {
+	_, hasRole := claims["role"].(string)
+	_, hasFamily := claims["fam"]
+	return hasRole && !hasFamily
+}
 
+// ValidateToken проверяет подпись и срок действия токена. keyfunc выбирает открытый ключ по kid (см. jwks.Verifier)
+func ValidateToken(tokenString string, keyfunc jwt.Keyfunc) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if token.Method.Alg() != jwt.SigningMethodRS256.Alg() {
 			return nil, ErrTokenInvalid
 		}
-		return publicKey, nil
+		return keyfunc(token)
 	})
 
 	if err != nil {
@@ -72,18 +111,3 @@ func ValidateToken(tokenString, publicKeyPath string) (jwt.MapClaims, error) {
 
 	return claims, nil
 }
-
-func getPublicKey(file string) (*rsa.PublicKey, error) {
-	publicKeyData, err := os.ReadFile(file)
-	if err != nil {
-		logrus.Error("Error to parse jwt pub key")
-		return nil, err
-	}
-	publicKey, err := jwt.ParseRSAPublicKeyFromPEM(publicKeyData)
-	if err != nil {
-		logrus.Error("Error to parse ParseRSAPublicKeyFromPEM func")
-		return nil, err
-	}
-
-	return publicKey, nil
-}
\ No newline at end of file
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// noRevocations — список отзыва, в котором нет ни одного токена
+type noRevocations struct{}
+
+func (noRevocations) IsRevoked(ctx context.Context, jti, sessionID string, userID int, issuedAt time.Time) (bool, error) {
+	return false, nil
+}
+
+func serveJWT(t *testing.T, claims jwt.MapClaims) int {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyfunc := func(*jwt.Token) (interface{}, error) { return &key.PublicKey, nil }
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/files", JWTMiddleware(keyfunc, noRevocations{}), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodGet, "/files", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestJWTMiddleware_AccessToken(t *testing.T) {
+	now := time.Now()
+	code := serveJWT(t, jwt.MapClaims{
+		"user_id": 7, "role": "user", "email_verified": true, "sid": "sid-1",
+		"jti": "jti-1", "iat": now.Unix(), "exp": now.Add(time.Minute).Unix(),
+	})
+	if code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+}
+
+// refresh-токен нельзя отозвать по jti и sid, поэтому вместо access-токена он не принимается
+func TestJWTMiddleware_RefreshTokenRejected(t *testing.T) {
+	now := time.Now()
+	code := serveJWT(t, jwt.MapClaims{
+		"user_id": 7, "fam": "fam-1",
+		"jti": "jti-1", "iat": now.Unix(), "exp": now.Add(time.Hour).Unix(),
+	})
+	if code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", code)
+	}
+}
+
+func TestJWTMiddleware_ServiceTokenRejected(t *testing.T) {
+	now := time.Now()
+	code := serveJWT(t, jwt.MapClaims{
+		"sub": "auth_service", "aud": "secure_comm_service", "scope": "users:purge",
+		"iat": now.Unix(), "exp": now.Add(time.Minute).Unix(),
+	})
+	if code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", code)
+	}
+}
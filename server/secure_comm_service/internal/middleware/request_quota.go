@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// RequestQuotaMiddleware засчитывает запрос в месячный лимит запросов плана и отвечает 429, когда лимит исчерпан.
+// Ставится после JWTMiddleware. При ошибке БД запрос пропускается: недоступность счётчика не должна ронять файловое API.
+func RequestQuotaMiddleware(quota *quota_service.QuotaService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := utils.GetUserID(c)
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		err = quota.CountRequest(c.Request.Context(), userID)
+		switch {
+		case err == nil:
+		case errors.Is(err, quota_service.ErrRequestLimitExceeded):
+			utils.WriteLimitExceeded(c, http.StatusTooManyRequests, err)
+			return
+		case errors.Is(err, quota_service.ErrNoActivePlan):
+			// без плана лимит не определён, ошибку вернёт сам хендлер
+		default:
+			logrus.Errorf("CountRequest user %d: %v", userID, err)
+		}
+		c.Next()
+	}
+}
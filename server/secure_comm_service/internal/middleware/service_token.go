@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// scope сервисных токенов auth_service
+const (
+	ScopePlanInit       = "plans:init"      // инициализация плана нового пользователя
+	ScopeSessionsRevoke = "sessions:revoke" // сброс сессий пользователя после смены пароля
+	ScopeUsersDelete    = "users:delete"    // удаление данных пользователя при удалении аккаунта
+)
+
+// ServiceJWTMiddleware пропускает только сервисные токены: aud должен совпадать с audience, а scope — содержать scope.
+// Пользовательские access-токены (без aud и scope) здесь не принимаются. Имя вызвавшего сервиса (sub) кладётся в "service".
+func ServiceJWTMiddleware(keyfunc jwt.Keyfunc, audience, scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+			logrus.Error("Error auth header: invalid service token format")
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token format"})
+			return
+		}
+
+		claims, err := ValidateToken(strings.TrimPrefix(authHeader, "Bearer "), keyfunc)
+		if err != nil {
+			logrus.Errorf("Error ValidateToken: %v", err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return
+		}
+
+		service, _ := claims["sub"].(string)
+		if service == "" || !isServiceToken(claims) || !claims.VerifyAudience(audience, true) {
+			logrus.Warnf("non-service token rejected on internal API %s %s", c.Request.Method, c.FullPath())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "service token required"})
+			return
+		}
+
+		scopes, _ := claims["scope"].(string)
+		if !hasScope(scopes, scope) {
+			logrus.Warnf("service %s has no scope %s for %s %s", service, scope, c.Request.Method, c.FullPath())
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "insufficient scope"})
+			return
+		}
+
+		c.Set("service", service)
+		c.Next()
+	}
+}
+
+// isServiceToken — сервисные токены отличаются от пользовательских наличием claim scope
+func isServiceToken(claims jwt.MapClaims) bool {
+	_, ok := claims["scope"]
+	return ok
+}
+
+func hasScope(scopes, scope string) bool {
+	for _, s := range strings.Fields(scopes) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/1abobik1/SecureComm/internal/handler/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// VerifiedEmailOnly пропускает только токены с подтверждённым email (claim email_verified). Ставится после JWTMiddleware.
+// Подтверждение попадает в токен при следующем входе или обновлении access-токена в auth_service.
+func VerifiedEmailOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !utils.EmailVerified(c) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "email address is not verified: confirm it via the link from the email and refresh the access token",
+			})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// EmailNotifier отправляет событие письмом через SMTP на адрес из события; без адреса событие пропускается
+type EmailNotifier struct {
+	addr string // host:port SMTP-сервера
+	from string
+	auth smtp.Auth
+}
+
+// NewEmailNotifier создаёт канал почты. Если user пустой, письма отправляются без авторизации.
+func NewEmailNotifier(addr, from, user, password string) *EmailNotifier {
+	n := &EmailNotifier{addr: addr, from: from}
+	if user != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		n.auth = smtp.PlainAuth("", user, password, host)
+	}
+	return n
+}
+
+func (n *EmailNotifier) Notify(_ context.Context, event Event) error {
+	if event.Email == "" {
+		return nil
+	}
+	// адрес приходит из настроек пользователя: перевод строки позволил бы дописать свои заголовки
+	if strings.ContainsAny(event.Email, "\r\n") {
+		return fmt.Errorf("email send: invalid address")
+	}
+
+	msg := strings.Join([]string{
+		"From: " + n.from,
+		"To: " + event.Email,
+		"Subject: SecureComm: " + event.Type,
+		"Date: " + event.At.UTC().Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		event.Message,
+		"",
+	}, "\r\n")
+
+	if err := smtp.SendMail(n.addr, n.auth, n.from, []string{event.Email}, []byte(msg)); err != nil {
+		return fmt.Errorf("email send: %w", err)
+	}
+	return nil
+}
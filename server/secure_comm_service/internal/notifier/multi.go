@@ -0,0 +1,26 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// MultiNotifier рассылает событие во все каналы. Ошибка одного канала не мешает остальным.
+type MultiNotifier struct {
+	notifiers []Notifier
+}
+
+func NewMultiNotifier(notifiers ...Notifier) *MultiNotifier {
+	return &MultiNotifier{notifiers: notifiers}
+}
+
+func (m *MultiNotifier) Notify(ctx context.Context, event Event) error {
+	var errs []error
+	for _, n := range m.notifiers {
+		if err := n.Notify(ctx, event); err != nil {
+			errs = append(errs, fmt.Errorf("%T: %w", n, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,50 @@
+package notifier
+
+import (
+	"context"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	EventPlanExpiring = "plan_expiring" // подписка скоро закончится
+	EventPlanRenewed  = "plan_renewed"  // подписка продлена на новый период
+	EventFallbackFree = "fallback_free" // подписка закончилась, пользователь переведён на free
+	EventRenewalDue   = "renewal_due"   // платная подписка закончилась, на free до оплаты продления
+	EventGraceStarted = "grace_started" // занято больше лимита, начался grace-период
+	EventReadOnly     = "read_only"     // grace-период истёк, аккаунт стал read-only
+	EventUsageAlert   = "usage_alert"   // занятое место пересекло порог из настроек пользователя
+
+	EventAlertEmailConfirm = "alert_email_confirm" // код подтверждения адреса для уведомлений, только письмом
+)
+
+// Event — уведомление для пользователя. В таком виде оно уходит в тело вебхука.
+type Event struct {
+	Type    string         `json:"type"`
+	UserID  int            `json:"user_id"`
+	Message string         `json:"message"`
+	At      time.Time      `json:"at"`
+	Data    map[string]any `json:"data,omitempty"` // подробности события, например порог и занятое место
+
+	// адреса, указанные самим пользователем; пустые — каналы берут адрес из своей конфигурации или пропускают событие
+	WebhookURL string `json:"-"`
+	Email      string `json:"-"`
+}
+
+// Notifier доставляет уведомления пользователю (почта, вебхук, лог и т.д.)
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// LogNotifier пишет уведомления в лог; используется, пока нет реального канала доставки
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Notify(_ context.Context, event Event) error {
+	logrus.Infof("notify user %d [%s]: %s", event.UserID, event.Type, event.Message)
+	return nil
+}
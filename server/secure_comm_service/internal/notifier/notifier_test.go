@@ -0,0 +1,106 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier_DeliversSignedEvent(t *testing.T) {
+	var gotEvent Event
+	var gotSignature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotSignature = r.Header.Get(WebhookSignatureHeader)
+
+		ts := strings.TrimPrefix(strings.Split(gotSignature, ",")[0], "t=")
+		sec, _ := strconv.ParseInt(ts, 10, 64)
+		if want := SignWebhook([]byte("secret"), time.Unix(sec, 0), body); gotSignature != want {
+			t.Errorf("signature %q, want %q", gotSignature, want)
+		}
+		if err := json.Unmarshal(body, &gotEvent); err != nil {
+			t.Errorf("unmarshal: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	n := NewWebhookNotifier("", "secret", time.Second, true)
+	event := Event{Type: EventUsageAlert, UserID: 7, Message: "80%", At: time.Now().UTC(), Data: map[string]any{"threshold": 80}, WebhookURL: srv.URL}
+	if err := n.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify: %v", err)
+	}
+	if gotSignature == "" {
+		t.Fatal("webhook was not delivered")
+	}
+	if gotEvent.Type != EventUsageAlert || gotEvent.UserID != 7 || gotEvent.Data["threshold"] != float64(80) {
+		t.Errorf("unexpected event %+v", gotEvent)
+	}
+}
+
+func TestWebhookNotifier_SkipsWithoutAddress(t *testing.T) {
+	n := NewWebhookNotifier("", "", time.Second, false)
+	if err := n.Notify(context.Background(), Event{Type: EventUsageAlert}); err != nil {
+		t.Fatalf("Notify without address: %v", err)
+	}
+}
+
+func TestWebhookNotifier_DeniesPrivateAddress(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		t.Error("request to loopback must not be sent")
+	}))
+	defer srv.Close()
+
+	n := NewWebhookNotifier(srv.URL, "", time.Second, false)
+	err := n.Notify(context.Background(), Event{Type: EventUsageAlert})
+	if !errors.Is(err, ErrWebhookAddress) {
+		t.Fatalf("Notify to loopback: %v, want ErrWebhookAddress", err)
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	tests := []struct {
+		url string
+		ok  bool
+	}{
+		{"https://example.com/hook", true},
+		{"http://example.com:8080/hook?x=1", true},
+		{"ftp://example.com/hook", false},
+		{"/relative", false},
+		{"https://", false},
+	}
+	for _, tt := range tests {
+		if err := ValidateWebhookURL(tt.url); (err == nil) != tt.ok {
+			t.Errorf("ValidateWebhookURL(%q) = %v, want ok=%v", tt.url, err, tt.ok)
+		}
+	}
+}
+
+type recordingNotifier struct {
+	events []Event
+	err    error
+}
+
+func (r *recordingNotifier) Notify(_ context.Context, event Event) error {
+	r.events = append(r.events, event)
+	return r.err
+}
+
+func TestMultiNotifier_ContinuesAfterError(t *testing.T) {
+	failing := &recordingNotifier{err: errors.New("boom")}
+	ok := &recordingNotifier{}
+
+	err := NewMultiNotifier(failing, NewLogNotifier(), ok).Notify(context.Background(), Event{Type: EventUsageAlert, UserID: 1})
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("Notify error = %v, want boom", err)
+	}
+	if len(failing.events) != 1 || len(ok.events) != 1 {
+		t.Errorf("events delivered: failing=%d ok=%d, want 1 and 1", len(failing.events), len(ok.events))
+	}
+}
@@ -0,0 +1,123 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// WebhookSignatureHeader — заголовок с подписью тела вебхука: t=<unix>,v1=<hex(hmac_sha256(secret, "<t>.<body>"))>
+const WebhookSignatureHeader = "X-SecureComm-Signature"
+
+var ErrWebhookAddress = errors.New("webhook address is not allowed")
+
+// WebhookNotifier отправляет событие POST-запросом с JSON-телом.
+// Адрес берётся из события (указан пользователем), иначе — defaultURL; если нет обоих, событие пропускается.
+type WebhookNotifier struct {
+	defaultURL string
+	secret     []byte
+	client     *http.Client
+}
+
+// NewWebhookNotifier создаёт канал вебхуков. Пока allowPrivate выключен, запросы во внутреннюю сеть
+// (loopback, частные и link-local адреса) запрещены: адрес вебхука задаёт пользователь.
+func NewWebhookNotifier(defaultURL, secret string, timeout time.Duration, allowPrivate bool) *WebhookNotifier {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = denyPrivateAddress
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &WebhookNotifier{
+		defaultURL: defaultURL,
+		secret:     []byte(secret),
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: transport,
+			// редирект мог бы увести запрос на адрес, который не проверялся при сохранении настроек
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+func (n *WebhookNotifier) Notify(ctx context.Context, event Event) error {
+	target := event.WebhookURL
+	if target == "" {
+		target = n.defaultURL
+	}
+	if target == "" {
+		return nil
+	}
+	if err := ValidateWebhookURL(target); err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("webhook marshal: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(n.secret) > 0 {
+		req.Header.Set(WebhookSignatureHeader, SignWebhook(n.secret, time.Now(), body))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook send: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook send: unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// SignWebhook формирует значение WebhookSignatureHeader, по которому получатель проверяет подлинность вебхука
+func SignWebhook(secret []byte, at time.Time, body []byte) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidateWebhookURL проверяет, что адрес вебхука — абсолютный http(s) URL
+func ValidateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return fmt.Errorf("%w: must be an absolute http(s) URL", ErrWebhookAddress)
+	}
+	return nil
+}
+
+// denyPrivateAddress запрещает соединение с внутренними адресами уже после DNS-резолва
+func denyPrivateAddress(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w: %s", ErrWebhookAddress, host)
+	}
+	return nil
+}
@@ -78,3 +78,12 @@ func (r *redisClientPubKeyStore) GetClientECDSAPub(ctx context.Context, clientID
 	}
 	return pubECDSA, nil
 }
+
+// удаляет оба публичных ключа клиента, возвращает число удалённых ключей Redis
+func (r *redisClientPubKeyStore) DeleteClientKeys(ctx context.Context, clientID string) (int64, error) {
+	n, err := r.redis.Del(ctx, fmt.Sprintf("client:%s:rsa_pub", clientID), fmt.Sprintf("client:%s:ecdsa_pub", clientID)).Result()
+	if err != nil {
+		return 0, fmt.Errorf("redis delete client keys: %w", err)
+	}
+	return n, nil
+}
@@ -8,6 +8,9 @@ import (
 	"github.com/go-redis/redis/v8"
 )
 
+// Сессионные ключи хранятся по сессии устройства (claim sid access токена): у каждого устройства свой handshake,
+// и отзыв одной сессии не задевает остальные. Множество sess_sids:{userID} — сессии пользователя,
+// чтобы сбросить их все (смена пароля, выход на всех устройствах, удаление аккаунта)
 type redisSessionStore struct {
 	cli *redis.Client
 	ctx context.Context
@@ -22,16 +25,28 @@ func NewRedisSessionStore(rClient *redis.Client, ttl time.Duration) *redisSessio
 	}
 }
 
-func (r *redisSessionStore) SaveSessionKeys(ctx context.Context, clientID string, kEnc, kMac []byte) error {
+func sessionKey(sessionID string) string {
+	return fmt.Sprintf("sess:%s", sessionID)
+}
+
+func userSessionsKey(clientID string) string {
+	return fmt.Sprintf("sess_sids:%s", clientID)
+}
+
+func (r *redisSessionStore) SaveSessionKeys(ctx context.Context, clientID, sessionID string, kEnc, kMac []byte) error {
 	// составим единый blob: kEnc||kMac
 	blob := append(kEnc, kMac...)
-	key := fmt.Sprintf("sess:%s", clientID)
-	return r.cli.SetEX(ctx, key, blob, r.ttl).Err()
+	_, err := r.cli.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SetEX(ctx, sessionKey(sessionID), blob, r.ttl)
+		pipe.SAdd(ctx, userSessionsKey(clientID), sessionID)
+		pipe.Expire(ctx, userSessionsKey(clientID), r.ttl)
+		return nil
+	})
+	return err
 }
 
-func (r *redisSessionStore) GetSessionKeys(ctx context.Context, clientID string) (kEnc []byte, kMac []byte, er error) {
-	key := fmt.Sprintf("sess:%s", clientID)
-	blob, err := r.cli.Get(ctx, key).Bytes()
+func (r *redisSessionStore) GetSessionKeys(ctx context.Context, sessionID string) (kEnc []byte, kMac []byte, er error) {
+	blob, err := r.cli.Get(ctx, sessionKey(sessionID)).Bytes()
 	if err != nil {
 		return nil, nil, err
 	}
@@ -41,6 +56,22 @@ func (r *redisSessionStore) GetSessionKeys(ctx context.Context, clientID string)
 	return blob[:32], blob[32:], nil
 }
 
-func (r *redisSessionStore) DeleteSession(ctx context.Context, clientID string) error {
-	return r.cli.Del(ctx, fmt.Sprintf("sess:%s", clientID)).Err()
+// DeleteSession удаляет ключи одной сессии устройства. Запись в sess_sids пользователя остаётся
+// до DeleteUserSessions или истечения TTL: удалять уже нечего
+func (r *redisSessionStore) DeleteSession(ctx context.Context, sessionID string) error {
+	return r.cli.Del(ctx, sessionKey(sessionID)).Err()
+}
+
+// DeleteUserSessions удаляет ключи всех сессий пользователя
+func (r *redisSessionStore) DeleteUserSessions(ctx context.Context, clientID string) error {
+	sessionIDs, err := r.cli.SMembers(ctx, userSessionsKey(clientID)).Result()
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(sessionIDs)+1)
+	for _, sid := range sessionIDs {
+		keys = append(keys, sessionKey(sid))
+	}
+	keys = append(keys, userSessionsKey(clientID))
+	return r.cli.Del(ctx, keys...).Err()
 }
@@ -0,0 +1,79 @@
+package session_store
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func newTestStore(t *testing.T) *redisSessionStore {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	cli := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { cli.Close() })
+	return NewRedisSessionStore(cli, time.Hour)
+}
+
+func testKeys(b byte) ([]byte, []byte) {
+	return bytes.Repeat([]byte{b}, 32), bytes.Repeat([]byte{b + 1}, 32)
+}
+
+// Устройства одного пользователя не перезаписывают ключи друг друга, отзыв одного не задевает другое
+func TestSessionKeys_PerDevice(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	encA, macA := testKeys(1)
+	encB, macB := testKeys(10)
+	if err := s.SaveSessionKeys(ctx, "7", "sid-a", encA, macA); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveSessionKeys(ctx, "7", "sid-b", encB, macB); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteSession(ctx, "sid-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := s.GetSessionKeys(ctx, "sid-a"); err == nil {
+		t.Error("keys of the revoked session are still there")
+	}
+	kEnc, kMac, err := s.GetSessionKeys(ctx, "sid-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(kEnc, encB) || !bytes.Equal(kMac, macB) {
+		t.Error("keys of the other device changed")
+	}
+}
+
+func TestDeleteUserSessions_OnlyThatUser(t *testing.T) {
+	ctx := context.Background()
+	s := newTestStore(t)
+	enc, mac := testKeys(1)
+	for _, sid := range []string{"sid-a", "sid-b"} {
+		if err := s.SaveSessionKeys(ctx, "7", sid, enc, mac); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.SaveSessionKeys(ctx, "8", "sid-c", enc, mac); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteUserSessions(ctx, "7"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, sid := range []string{"sid-a", "sid-b"} {
+		if _, _, err := s.GetSessionKeys(ctx, sid); err == nil {
+			t.Errorf("session %s of user 7 survived", sid)
+		}
+	}
+	if _, _, err := s.GetSessionKeys(ctx, "sid-c"); err != nil {
+		t.Errorf("session of user 8 was deleted: %v", err)
+	}
+}
@@ -0,0 +1,57 @@
+package revocation
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// bloomFilter — фильтр Блума по ключам списка отзыва. Отрицательный ответ точный, поэтому для подавляющего
+// большинства запросов (токен не отозван) Redis не нужен. Удалять из фильтра нельзя: истёкшие записи
+// вычищаются пересборкой фильтра целиком
+type bloomFilter struct {
+	bits []uint64
+	m    uint64 // число бит
+	k    uint64 // число хеш-функций
+}
+
+// newBloomFilter подбирает размер под capacity элементов с долей ложных срабатываний fpRate
+func newBloomFilter(capacity int, fpRate float64) *bloomFilter {
+	if capacity < 1 {
+		capacity = 1
+	}
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Round(float64(m) / float64(capacity) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	words := (m + 63) / 64
+	return &bloomFilter{bits: make([]uint64, words), m: words * 64, k: k}
+}
+
+func (b *bloomFilter) add(key string) {
+	h1, h2 := bloomHashes(key)
+	for i := uint64(0); i < b.k; i++ {
+		pos := (h1 + i*h2) % b.m
+		b.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+// mayContain возвращает false, только если key точно не добавлялся
+func (b *bloomFilter) mayContain(key string) bool {
+	h1, h2 := bloomHashes(key)
+	for i := uint64(0); i < b.k; i++ {
+		pos := (h1 + i*h2) % b.m
+		if b.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomHashes — две независимые половины FNV-1a 64 для двойного хеширования (Kirsch–Mitzenmacher)
+func bloomHashes(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	sum := h.Sum64()
+	return sum & 0xffffffff, sum>>32 | 1
+}
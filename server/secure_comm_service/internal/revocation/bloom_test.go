@@ -0,0 +1,37 @@
+package revocation
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBloomFilter_NoFalseNegatives(t *testing.T) {
+	b := newBloomFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		b.add(fmt.Sprintf("revoked_jti:%d", i))
+	}
+	for i := 0; i < 1000; i++ {
+		if !b.mayContain(fmt.Sprintf("revoked_jti:%d", i)) {
+			t.Fatalf("key %d added but not found", i)
+		}
+	}
+}
+
+func TestBloomFilter_FalsePositiveRate(t *testing.T) {
+	b := newBloomFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		b.add(fmt.Sprintf("revoked_jti:%d", i))
+	}
+
+	falsePositives := 0
+	const probes = 10000
+	for i := 0; i < probes; i++ {
+		if b.mayContain(fmt.Sprintf("revoked_sid:%d", i)) {
+			falsePositives++
+		}
+	}
+	// при заполнении до capacity ожидается около 1%, с запасом на разброс
+	if rate := float64(falsePositives) / probes; rate > 0.03 {
+		t.Fatalf("false positive rate %.3f, want <= 0.03", rate)
+	}
+}
@@ -0,0 +1,247 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/patrickmn/go-cache"
+	"github.com/sirupsen/logrus"
+)
+
+// Ключи и канал общие с auth_service (internal/storage/redis): менять только вместе
+const (
+	revokedJTIPrefix    = "revoked_jti:"    // отозванный access токен (claim jti)
+	revokedSIDPrefix    = "revoked_sid:"    // отозванная сессия устройства (claim sid)
+	revokedBeforePrefix = "revoked_before:" // unix-время: токены пользователя, выпущенные в эту секунду и раньше, недействительны
+	revocationChannel   = "token_revocations"
+)
+
+// доля ложных срабатываний фильтра при заполнении до capacity
+const bloomFPRate = 0.01
+
+const (
+	pingInterval     = time.Minute     // сколько ждать сообщений, прежде чем проверить соединение ping-ом
+	resubscribeDelay = 5 * time.Second // пауза между повторными подписками, пока Redis недоступен
+)
+
+// Checker проверяет access токены по списку отзыва, который ведёт auth_service.
+// Быстрый путь — фильтр Блума в памяти: если ключа в нём нет, токен точно не отозван.
+// Положительный ответ фильтра подтверждается в Redis, результат кешируется локально.
+// Фильтр наполняется при старте и пересборкой (Rebuild), новые записи приходят через pub/sub
+type Checker struct {
+	cli      *redis.Client
+	capacity int
+	cache    *cache.Cache // ключ списка отзыва -> значение из Redis ("" — записи нет)
+	sessions SessionKeys  // nil — сессионные ключи при отзыве не удаляются
+
+	mu     sync.RWMutex
+	filter *bloomFilter
+	next   *bloomFilter // собирается в Rebuild; новые записи попадают и в него
+}
+
+// SessionKeys — сессионные ключи handshake. При отзыве сессии устройства или всех токенов пользователя
+// они удаляются, чтобы отозванное устройство не продолжило зашифрованный обмен. Реализуется handshake_service
+type SessionKeys interface {
+	RevokeDeviceSession(ctx context.Context, sessionID string) error
+	RevokeSessions(ctx context.Context, userID string) error
+}
+
+func NewChecker(cli *redis.Client, capacity int, cacheTTL time.Duration, sessions SessionKeys) *Checker {
+	return &Checker{
+		cli:      cli,
+		capacity: capacity,
+		cache:    cache.New(cacheTTL, 2*cacheTTL),
+		sessions: sessions,
+		filter:   newBloomFilter(capacity, bloomFPRate),
+	}
+}
+
+// Start подписывается на канал отзыва и загружает текущий список из Redis.
+// Подписка оформляется до загрузки, чтобы не потерять записи, сделанные в промежутке
+func (c *Checker) Start(ctx context.Context) error {
+	pubsub := c.cli.Subscribe(ctx, revocationChannel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("subscribe to %s: %w", revocationChannel, err)
+	}
+
+	go c.listen(ctx, pubsub)
+	return c.Rebuild(ctx)
+}
+
+// listen обрабатывает сообщения канала отзыва. После обрыва соединения go-redis подписывается заново
+// при следующем чтении и присылает подтверждение подписки: записи, опубликованные за время обрыва,
+// добираются пересборкой фильтра
+func (c *Checker) listen(ctx context.Context, pubsub *redis.PubSub) {
+	go func() {
+		<-ctx.Done()
+		pubsub.Close()
+	}()
+
+	failures := 0
+	for {
+		msg, err := pubsub.ReceiveTimeout(ctx, pingInterval)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				// в канале тихо: ping проверяет, что соединение живо, и при ошибке переподключает его
+				if err := pubsub.Ping(ctx); err != nil {
+					logrus.Warnf("revocation subscription ping: %v", err)
+				}
+				continue
+			}
+			logrus.Warnf("revocation subscription: %v, resubscribing", err)
+			// первая переподписка сразу, повторные — с паузой, чтобы не крутить цикл, пока Redis недоступен
+			failures++
+			if failures > 1 {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(resubscribeDelay):
+				}
+			}
+			continue
+		}
+		failures = 0
+
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if err := c.Rebuild(ctx); err != nil {
+				logrus.Errorf("rebuild revocation filter after resubscribe: %v", err)
+			}
+		case *redis.Message:
+			c.add(m.Payload)
+			c.dropSessionKeys(ctx, m.Payload)
+		}
+	}
+}
+
+// Rebuild собирает фильтр заново по ключам в Redis: истёкшие записи из него уходят,
+// пропущенные за время обрыва подписки — появляются
+func (c *Checker) Rebuild(ctx context.Context) error {
+	next := newBloomFilter(c.capacity, bloomFPRate)
+	c.mu.Lock()
+	c.next = next
+	c.mu.Unlock()
+
+	keys := 0
+	for _, prefix := range []string{revokedJTIPrefix, revokedSIDPrefix, revokedBeforePrefix} {
+		iter := c.cli.Scan(ctx, 0, prefix+"*", 1000).Iterator()
+		for iter.Next(ctx) {
+			c.mu.Lock()
+			next.add(iter.Val())
+			c.mu.Unlock()
+			keys++
+		}
+		if err := iter.Err(); err != nil {
+			c.mu.Lock()
+			c.next = nil
+			c.mu.Unlock()
+			return fmt.Errorf("scan %s*: %w", prefix, err)
+		}
+	}
+
+	c.mu.Lock()
+	c.filter, c.next = next, nil
+	c.mu.Unlock()
+	// в кеше могли остаться отрицательные ответы по ключам, записанным за время обрыва подписки
+	c.cache.Flush()
+
+	if keys > c.capacity {
+		logrus.Warnf("revocation list has %d entries, more than bloom capacity %d: more lookups will hit redis", keys, c.capacity)
+	}
+	return nil
+}
+
+// IsRevoked сообщает, отозван ли токен: сам по jti, его сессия устройства sid
+// или все токены пользователя userID, выпущенные не позже метки revoked_before. Пустые jti и sid не проверяются
+func (c *Checker) IsRevoked(ctx context.Context, jti, sessionID string, userID int, issuedAt time.Time) (bool, error) {
+	if jti != "" {
+		v, err := c.lookup(ctx, revokedJTIPrefix+jti)
+		if err != nil || v != "" {
+			return v != "", err
+		}
+	}
+	if sessionID != "" {
+		v, err := c.lookup(ctx, revokedSIDPrefix+sessionID)
+		if err != nil || v != "" {
+			return v != "", err
+		}
+	}
+
+	v, err := c.lookup(ctx, revokedBeforePrefix+strconv.Itoa(userID))
+	if err != nil || v == "" {
+		return false, err
+	}
+	before, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s%d value %q: %w", revokedBeforePrefix, userID, v, err)
+	}
+	// iat и метка хранятся с точностью до секунды: токены, выпущенные в ту же секунду, что и отзыв, тоже недействительны
+	return issuedAt.Unix() <= before, nil
+}
+
+// lookup возвращает значение ключа списка отзыва или "", если записи нет
+func (c *Checker) lookup(ctx context.Context, key string) (string, error) {
+	c.mu.RLock()
+	maybe := c.filter.mayContain(key)
+	c.mu.RUnlock()
+	if !maybe {
+		return "", nil
+	}
+
+	if v, ok := c.cache.Get(key); ok {
+		return v.(string), nil
+	}
+
+	v, err := c.cli.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		v = ""
+	} else if err != nil {
+		return "", fmt.Errorf("get %s: %w", key, err)
+	}
+	c.cache.SetDefault(key, v)
+	return v, nil
+}
+
+// add учитывает новую запись из канала отзыва
+func (c *Checker) add(key string) {
+	c.mu.Lock()
+	c.filter.add(key)
+	if c.next != nil {
+		c.next.add(key)
+	}
+	c.mu.Unlock()
+	c.cache.Delete(key)
+}
+
+// dropSessionKeys удаляет сессионные ключи по записи из канала отзыва. Пропущенное сообщение не оставляет дыры:
+// ключи ищутся по sid токена, а токены отозванной сессии отклоняет IsRevoked; ключи доживут только до своего TTL
+func (c *Checker) dropSessionKeys(ctx context.Context, key string) {
+	if c.sessions == nil {
+		return
+	}
+
+	var err error
+	switch {
+	case strings.HasPrefix(key, revokedSIDPrefix):
+		err = c.sessions.RevokeDeviceSession(ctx, strings.TrimPrefix(key, revokedSIDPrefix))
+	case strings.HasPrefix(key, revokedBeforePrefix):
+		err = c.sessions.RevokeSessions(ctx, strings.TrimPrefix(key, revokedBeforePrefix))
+	default:
+		return
+	}
+	if err != nil {
+		logrus.Errorf("drop session keys for %s: %v", key, err)
+	}
+}
@@ -0,0 +1,156 @@
+package revocation
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+type fakeSessionKeys struct {
+	mu      sync.Mutex
+	devices []string
+	users   []string
+}
+
+func (f *fakeSessionKeys) RevokeDeviceSession(ctx context.Context, sessionID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.devices = append(f.devices, sessionID)
+	return nil
+}
+
+func (f *fakeSessionKeys) RevokeSessions(ctx context.Context, userID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.users = append(f.users, userID)
+	return nil
+}
+
+func (f *fakeSessionKeys) calls() (int, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.devices), len(f.users)
+}
+
+func newTestChecker(t *testing.T, sessions SessionKeys) (*Checker, *miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	cli := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { cli.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	c := NewChecker(cli, 1000, time.Minute, sessions)
+	if err := c.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+	return c, mr, cli
+}
+
+// waitFor ждёт, пока подписка обработает опубликованное сообщение
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Отзыв сессии устройства удаляет её сессионные ключи, отзыв всех токенов — ключи всех устройств пользователя
+func TestChecker_RevocationDropsSessionKeys(t *testing.T) {
+	sessions := &fakeSessionKeys{}
+	_, _, cli := newTestChecker(t, sessions)
+	ctx := context.Background()
+
+	cli.Publish(ctx, revocationChannel, revokedSIDPrefix+"sid-a")
+	cli.Publish(ctx, revocationChannel, revokedBeforePrefix+"7")
+	cli.Publish(ctx, revocationChannel, revokedJTIPrefix+"jti-1")
+
+	waitFor(t, func() bool {
+		d, u := sessions.calls()
+		return d == 1 && u == 1
+	})
+	sessions.mu.Lock()
+	defer sessions.mu.Unlock()
+	if sessions.devices[0] != "sid-a" || sessions.users[0] != "7" {
+		t.Errorf("devices = %v, users = %v, want [sid-a] and [7]", sessions.devices, sessions.users)
+	}
+}
+
+func isRevoked(t *testing.T, c *Checker, jti, sessionID string, userID int, issuedAt time.Time) bool {
+	t.Helper()
+	revoked, err := c.IsRevoked(context.Background(), jti, sessionID, userID, issuedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return revoked
+}
+
+func TestIsRevoked_ByJTIAndSession(t *testing.T) {
+	c, mr, _ := newTestChecker(t, nil)
+	mr.Set(revokedJTIPrefix+"jti-1", "1")
+	mr.Set(revokedSIDPrefix+"sid-a", "1")
+	if err := c.Rebuild(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+
+	if !isRevoked(t, c, "jti-1", "sid-b", 7, now) {
+		t.Error("token with revoked jti is accepted")
+	}
+	if !isRevoked(t, c, "jti-2", "sid-a", 7, now) {
+		t.Error("token of revoked session is accepted")
+	}
+	if isRevoked(t, c, "jti-2", "sid-b", 7, now) {
+		t.Error("token that was not revoked is rejected")
+	}
+}
+
+// Метка revoked_before хранится в секундах: токен, выпущенный в ту же секунду, что и отзыв, тоже недействителен
+func TestIsRevoked_WatermarkCoversItsSecond(t *testing.T) {
+	c, mr, cli := newTestChecker(t, nil)
+	revokedAt := time.Unix(1700000000, 0)
+	mr.Set(revokedBeforePrefix+"7", strconv.FormatInt(revokedAt.Unix(), 10))
+	cli.Publish(context.Background(), revocationChannel, revokedBeforePrefix+"7")
+	waitFor(t, func() bool { return isRevoked(t, c, "", "", 7, revokedAt.Add(-time.Second)) })
+
+	if !isRevoked(t, c, "", "", 7, revokedAt) {
+		t.Error("token issued in the revocation second is accepted")
+	}
+	if isRevoked(t, c, "", "", 7, revokedAt.Add(time.Second)) {
+		t.Error("token issued after the revocation is rejected")
+	}
+	if isRevoked(t, c, "", "", 8, revokedAt) {
+		t.Error("token of another user is rejected")
+	}
+}
+
+// Записи, сделанные, пока подписка была оборвана, подхватываются пересборкой после переподписки
+func TestChecker_RebuildsAfterReconnect(t *testing.T) {
+	c, mr, _ := newTestChecker(t, nil)
+	// отрицательный ответ из Redis попадает в кеш; пересборка должна его сбросить
+	mr.Set(revokedSIDPrefix+"sid-a", "1")
+	if err := c.Rebuild(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	mr.Del(revokedSIDPrefix + "sid-a")
+	if isRevoked(t, c, "", "sid-a", 7, time.Now()) {
+		t.Fatal("session is revoked before the test")
+	}
+
+	mr.Close()
+	mr.Set(revokedSIDPrefix+"sid-a", "1")
+	if err := mr.Restart(); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(t, func() bool { return isRevoked(t, c, "", "sid-a", 7, time.Now()) })
+}
@@ -3,19 +3,40 @@ package routes
 import (
 	"github.com/1abobik1/SecureComm/config"
 	"github.com/1abobik1/SecureComm/internal/api"
+	"github.com/1abobik1/SecureComm/internal/billing"
+	"github.com/1abobik1/SecureComm/internal/handler/account_handler"
+	"github.com/1abobik1/SecureComm/internal/handler/admin_handler"
+	"github.com/1abobik1/SecureComm/internal/handler/billing_handler"
 	"github.com/1abobik1/SecureComm/internal/handler/cloud_handler"
 	"github.com/1abobik1/SecureComm/internal/handler/handshake_handler"
+	"github.com/1abobik1/SecureComm/internal/handler/org_handler"
 	"github.com/1abobik1/SecureComm/internal/handler/quota_handler"
 	"github.com/1abobik1/SecureComm/internal/middleware"
+	"github.com/1abobik1/SecureComm/internal/service/cloud_service"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
 )
 
-func RegisterRoutes(r *gin.Engine, cfg *config.Config, quotaHandler *quota_handler.QuotaHandler, minioHandler *cloud_handler.MinioHandler, hsHandler *handshake_handler.HSHandler,
-	webClient *api.WEBClientKeysAPI, tgClient *api.TGClientKeysAPI, hsLimiterMiddleware gin.HandlerFunc, sessionLimiterMiddleware gin.HandlerFunc, hsAttemptLimiter gin.HandlerFunc,
+func RegisterRoutes(r *gin.Engine, cfg *config.Config, quotaHandler *quota_handler.QuotaHandler, minioHandler *cloud_handler.MinioHandler, billingHandler *billing_handler.BillingHandler, orgHandler *org_handler.OrgHandler, adminHandler *admin_handler.AdminHandler, quotaService *quota_service.QuotaService, hsHandler *handshake_handler.HSHandler,
+	webClient *api.WEBClientKeysAPI, tgClient *api.TGClientKeysAPI, hsLimiterMiddleware gin.HandlerFunc, sessionLimiterMiddleware gin.HandlerFunc, hsAttemptLimiter gin.HandlerFunc, jwtKeys jwt.Keyfunc, revocations middleware.TokenRevocations,
 ) {
 
+	// вебхуки провайдера без JWT: подлинность проверяется подписью
+	billingApi := r.Group("/billing")
+	{
+		billingApi.POST("/webhook/:provider", billingHandler.Webhook)
+	}
+
+	// скачивание по подписанной ссылке без JWT: подпись проверяет хендлер, трафик считается владельцу файла
+	r.GET(cloud_service.DownloadPath, sessionLimiterMiddleware, minioHandler.Download)
+	r.HEAD(cloud_service.DownloadPath, sessionLimiterMiddleware, minioHandler.Download)
+
 	authGroup := r.Group("/")
-	authGroup.Use(middleware.JWTMiddleware(cfg.JWT.PublicKeyPath))
+	authGroup.Use(middleware.JWTMiddleware(jwtKeys, revocations))
+
+	// загрузка файлов и общий доступ (организации) — только с подтверждённым email
+	verifiedEmail := middleware.VerifiedEmailOnly()
 
 	{
 		hsGroup := authGroup.Group("/handshake")
@@ -24,6 +45,11 @@ func RegisterRoutes(r *gin.Engine, cfg *config.Config, quotaHandler *quota_handl
 			hsGroup.POST("/finalize", hsAttemptLimiter, hsLimiterMiddleware, hsHandler.Finalize)
 		}
 
+		// страница фейковой оплаты — только в среде разработки, и оплатить можно только свою сессию
+		if cfg.Billing.Provider == billing.FakeProviderName && cfg.Billing.AllowFake {
+			authGroup.GET("/billing/fake/pay/:session_id", sessionLimiterMiddleware, billingHandler.FakePay)
+		}
+
 		sGroup := authGroup.Group("/session")
 		{
 			sGroup.POST("/test", sessionLimiterMiddleware, hsHandler.SessionTester)
@@ -31,12 +57,18 @@ func RegisterRoutes(r *gin.Engine, cfg *config.Config, quotaHandler *quota_handl
 
 		// Файловое API
 		routesFileApi := authGroup.Group("/files")
+		routesFileApi.Use(middleware.RequestQuotaMiddleware(quotaService))
 		{
-			routesFileApi.POST("/one/encrypted", sessionLimiterMiddleware, middleware.MaxSizeMiddleware(middleware.MaxFileSize), middleware.MaxStreamMiddleware(middleware.MaxFileSize), minioHandler.CreateOneEncrypted)
-			routesFileApi.GET("/all", sessionLimiterMiddleware, middleware.MaxSizeMiddleware(middleware.MaxFileSize), middleware.MaxStreamMiddleware(middleware.MaxFileSize), minioHandler.GetAll)
-			routesFileApi.GET("/one", sessionLimiterMiddleware, middleware.MaxSizeMiddleware(middleware.MaxFileSize), middleware.MaxStreamMiddleware(middleware.MaxFileSize), minioHandler.GetOne)
-			routesFileApi.DELETE("/one", sessionLimiterMiddleware, middleware.MaxSizeMiddleware(middleware.MaxFileSize), middleware.MaxStreamMiddleware(middleware.MaxFileSize), minioHandler.DeleteOne)
-			routesFileApi.DELETE("/many", sessionLimiterMiddleware, middleware.MaxSizeMiddleware(middleware.MaxFileSize), middleware.MaxStreamMiddleware(middleware.MaxFileSize), minioHandler.DeleteMany)
+			routesFileApi.POST("/one/encrypted", sessionLimiterMiddleware, verifiedEmail, middleware.MaxFileSizeMiddleware(quotaService), minioHandler.CreateOneEncrypted)
+			routesFileApi.GET("/all", sessionLimiterMiddleware, minioHandler.GetAll)
+			routesFileApi.GET("/one", sessionLimiterMiddleware, minioHandler.GetOne)
+			routesFileApi.DELETE("/one", sessionLimiterMiddleware, minioHandler.DeleteOne)
+			routesFileApi.DELETE("/many", sessionLimiterMiddleware, minioHandler.DeleteMany)
+			routesFileApi.POST("/archive", sessionLimiterMiddleware, minioHandler.GetArchive)
+			routesFileApi.POST("/one/copy", sessionLimiterMiddleware, verifiedEmail, minioHandler.CopyOne)
+			routesFileApi.POST("/one/move", sessionLimiterMiddleware, minioHandler.MoveOne)
+			routesFileApi.PATCH("/one/rename", sessionLimiterMiddleware, minioHandler.RenameOne)
+			routesFileApi.PATCH("/one/expiration", sessionLimiterMiddleware, minioHandler.SetExpiration)
 		}
 
 		webClientApi := authGroup.Group("/web")
@@ -51,8 +83,80 @@ func RegisterRoutes(r *gin.Engine, cfg *config.Config, quotaHandler *quota_handl
 
 		quotaApi := authGroup.Group("/user")
 		{
-			quotaApi.POST("/:id/plan/init", quotaHandler.InitUserPlan)
 			quotaApi.GET("/:id/usage", sessionLimiterMiddleware, quotaHandler.GetUserUsage)
+			quotaApi.GET("/:id/usage/history", sessionLimiterMiddleware, quotaHandler.GetUsageHistory)
+			quotaApi.GET("/:id/usage/ledger", sessionLimiterMiddleware, quotaHandler.GetUsageLedger)
+			quotaApi.GET("/:id/usage/alerts", sessionLimiterMiddleware, quotaHandler.GetUsageAlerts)
+			quotaApi.PUT("/:id/usage/alerts", sessionLimiterMiddleware, quotaHandler.SetUsageAlerts)
+			quotaApi.DELETE("/:id/usage/alerts", sessionLimiterMiddleware, quotaHandler.ResetUsageAlerts)
+			quotaApi.POST("/:id/usage/alerts/email/confirm", sessionLimiterMiddleware, quotaHandler.ConfirmAlertEmail)
+			quotaApi.GET("/:id/plan", sessionLimiterMiddleware, quotaHandler.GetCurrentPlan)
+			quotaApi.POST("/:id/plan/subscribe", sessionLimiterMiddleware, quotaHandler.Subscribe)
+			quotaApi.POST("/:id/plan/downgrade", sessionLimiterMiddleware, quotaHandler.Downgrade)
+			quotaApi.POST("/:id/plan/cancel", sessionLimiterMiddleware, quotaHandler.Cancel)
+			quotaApi.POST("/:id/plan/checkout", sessionLimiterMiddleware, billingHandler.CreateCheckout)
+			quotaApi.GET("/:id/plan/checkout/:session_id", sessionLimiterMiddleware, billingHandler.GetCheckout)
 		}
+
+		// организации: участники, общий пул хранилища и его план
+		orgApi := authGroup.Group("/orgs")
+		{
+			orgApi.POST("", sessionLimiterMiddleware, verifiedEmail, orgHandler.CreateOrg)
+			orgApi.GET("", sessionLimiterMiddleware, orgHandler.ListOrgs)
+			orgApi.GET("/invites", sessionLimiterMiddleware, orgHandler.ListMyInvites)
+			orgApi.POST("/invites/:invite_id/accept", sessionLimiterMiddleware, orgHandler.AcceptInvite)
+			orgApi.DELETE("/invites/:invite_id", sessionLimiterMiddleware, orgHandler.DeclineInvite)
+			orgApi.GET("/:org_id", sessionLimiterMiddleware, orgHandler.GetOrg)
+			orgApi.POST("/:org_id/invites", sessionLimiterMiddleware, verifiedEmail, orgHandler.InviteMember)
+			orgApi.GET("/:org_id/invites", sessionLimiterMiddleware, orgHandler.ListOrgInvites)
+			orgApi.DELETE("/:org_id/invites/:invite_id", sessionLimiterMiddleware, orgHandler.RevokeInvite)
+			orgApi.PATCH("/:org_id/members/:user_id", sessionLimiterMiddleware, orgHandler.UpdateMemberRole)
+			orgApi.DELETE("/:org_id/members/:user_id", sessionLimiterMiddleware, orgHandler.RemoveMember)
+			orgApi.GET("/:org_id/usage", sessionLimiterMiddleware, quotaHandler.GetOrgUsage)
+			orgApi.GET("/:org_id/plan", sessionLimiterMiddleware, quotaHandler.GetOrgPlan)
+			orgApi.POST("/:org_id/plan/subscribe", sessionLimiterMiddleware, quotaHandler.SubscribeOrg)
+			orgApi.POST("/:org_id/plan/downgrade", sessionLimiterMiddleware, quotaHandler.DowngradeOrg)
+			orgApi.POST("/:org_id/plan/cancel", sessionLimiterMiddleware, quotaHandler.CancelOrg)
+			orgApi.POST("/:org_id/plan/checkout", sessionLimiterMiddleware, billingHandler.CreateOrgCheckout)
+
+			// файлы общего пула; запросы засчитываются в личный лимит участника
+			orgFileApi := orgApi.Group("/:org_id/files")
+			orgFileApi.Use(middleware.RequestQuotaMiddleware(quotaService))
+			{
+				orgFileApi.POST("/one/encrypted", sessionLimiterMiddleware, verifiedEmail, middleware.OrgMaxFileSizeMiddleware(quotaService), minioHandler.CreateOrgOneEncrypted)
+				orgFileApi.GET("/all", sessionLimiterMiddleware, minioHandler.GetAllOrg)
+				orgFileApi.GET("/one", sessionLimiterMiddleware, minioHandler.GetOrgOne)
+				orgFileApi.DELETE("/one", sessionLimiterMiddleware, minioHandler.DeleteOrgOne)
+			}
+		}
+
+		authGroup.GET("/plans", sessionLimiterMiddleware, quotaHandler.ListPlans)
+
+		// админское API: только для токенов с claim role = admin
+		adminApi := authGroup.Group("/admin")
+		adminApi.Use(middleware.AdminOnly())
+		{
+			adminApi.GET("/plans", sessionLimiterMiddleware, adminHandler.ListPlans)
+			adminApi.POST("/plans", sessionLimiterMiddleware, adminHandler.CreatePlan)
+			adminApi.PATCH("/plans/:name", sessionLimiterMiddleware, adminHandler.UpdatePlan)
+			adminApi.POST("/plans/:name/retire", sessionLimiterMiddleware, adminHandler.RetirePlan)
+			adminApi.POST("/users/:id/plan", sessionLimiterMiddleware, adminHandler.AssignPlan)
+			adminApi.GET("/users/:id/limits", sessionLimiterMiddleware, adminHandler.GetLimitOverride)
+			adminApi.PUT("/users/:id/limits", sessionLimiterMiddleware, adminHandler.SetLimitOverride)
+			adminApi.DELETE("/users/:id/limits", sessionLimiterMiddleware, adminHandler.RemoveLimitOverride)
+			adminApi.GET("/users/:id/history", sessionLimiterMiddleware, adminHandler.GetUserHistory)
+			adminApi.GET("/audit", sessionLimiterMiddleware, adminHandler.GetAudit)
+		}
+	}
+}
+
+// RegisterInternalRoutes регистрирует внутреннее API для других сервисов. Доступ — только по сервисным токенам
+// с нужным scope; пользовательские токены отклоняются
+func RegisterInternalRoutes(r *gin.Engine, cfg *config.Config, quotaHandler *quota_handler.QuotaHandler, hsHandler *handshake_handler.HSHandler, accountHandler *account_handler.AccountHandler, jwtKeys jwt.Keyfunc) {
+	internalApi := r.Group("/internal")
+	{
+		internalApi.POST("/users/:id/plan/init", middleware.ServiceJWTMiddleware(jwtKeys, cfg.Internal.Audience, middleware.ScopePlanInit), quotaHandler.InitUserPlan)
+		internalApi.DELETE("/users/:id/sessions", middleware.ServiceJWTMiddleware(jwtKeys, cfg.Internal.Audience, middleware.ScopeSessionsRevoke), hsHandler.RevokeUserSessions)
+		internalApi.DELETE("/users/:id", middleware.ServiceJWTMiddleware(jwtKeys, cfg.Internal.Audience, middleware.ScopeUsersDelete), accountHandler.PurgeUserData)
 	}
 }
@@ -0,0 +1,363 @@
+package billing_service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/1abobik1/SecureComm/internal/billing"
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/google/uuid"
+	_ "github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	CheckoutPending = "pending"
+	CheckoutPaid    = "paid"
+	CheckoutFailed  = "failed"
+	CheckoutExpired = "expired"
+)
+
+var (
+	ErrFreePlanCheckout      = errors.New("free plan does not require a payment")
+	ErrCheckoutNotFound      = errors.New("checkout session not found")
+	ErrNotSupportedByBilling = errors.New("operation is not supported by the configured payment provider")
+)
+
+type BillingService struct {
+	db       *sql.DB
+	provider billing.Provider
+	quota    *quota_service.QuotaService
+	currency string
+}
+
+func NewBillingService(storagePath string, provider billing.Provider, quota *quota_service.QuotaService, currency string) (*BillingService, error) {
+	db, err := sql.Open("postgres", storagePath)
+	if err != nil {
+		return nil, err
+	}
+	return &BillingService{db: db, provider: provider, quota: quota, currency: currency}, nil
+}
+
+func NewBillingServiceForTesting(db *sql.DB, provider billing.Provider, quota *quota_service.QuotaService, currency string) *BillingService {
+	return &BillingService{db: db, provider: provider, quota: quota, currency: currency}
+}
+
+func (s *BillingService) ProviderName() string {
+	return s.provider.Name()
+}
+
+// CreateCheckout создаёт платёжную сессию на план planName. План активируется (или продлевается)
+// только когда провайдер пришлёт подписанный вебхук об успешной оплате.
+func (s *BillingService) CreateCheckout(ctx context.Context, userID int, planName string, autoRenew bool) (domain.CheckoutSession, error) {
+	return s.createCheckout(ctx, userID, 0, planName, autoRenew)
+}
+
+// CreateOrgCheckout создаёт платёжную сессию на план организации. Платит userID (проверку роли делает вызывающий),
+// оплаченный период подключается пулу организации.
+func (s *BillingService) CreateOrgCheckout(ctx context.Context, orgID, userID int, planName string, autoRenew bool) (domain.CheckoutSession, error) {
+	return s.createCheckout(ctx, userID, orgID, planName, autoRenew)
+}
+
+// CreateRenewalCheckout создаёт платёжную сессию на продление платного плана, закончившегося без оплаты
+// (quota_service.PlanActionRenewalDue). Платит владелец подписки, за организацию — её owner.
+// Возвращает сессию и ID плательщика, которому нужно отправить ссылку на оплату.
+func (s *BillingService) CreateRenewalCheckout(ctx context.Context, t domain.PlanTransition) (domain.CheckoutSession, int, error) {
+	payerID := t.UserID
+	if t.OrgID != 0 {
+		err := s.db.QueryRowContext(ctx, `
+            SELECT user_id FROM org_members WHERE org_id = $1 AND role = 'owner'
+        `, t.OrgID).Scan(&payerID)
+		if err != nil {
+			return domain.CheckoutSession{}, 0, fmt.Errorf("get org owner: %w", err)
+		}
+	}
+
+	cs, err := s.createCheckout(ctx, payerID, t.OrgID, t.FromPlan, true)
+	if err != nil {
+		return domain.CheckoutSession{}, 0, err
+	}
+	return cs, payerID, nil
+}
+
+// createCheckout — orgID = 0 для личного плана
+func (s *BillingService) createCheckout(ctx context.Context, userID, orgID int, planName string, autoRenew bool) (domain.CheckoutSession, error) {
+	plans, err := s.quota.ListPlans(ctx)
+	if err != nil {
+		return domain.CheckoutSession{}, err
+	}
+	var target *domain.Plan
+	for i := range plans {
+		if plans[i].Name == planName {
+			target = &plans[i]
+			break
+		}
+	}
+	if target == nil {
+		return domain.CheckoutSession{}, quota_service.ErrPlanNotFound
+	}
+	if target.PriceCents == 0 {
+		return domain.CheckoutSession{}, ErrFreePlanCheckout
+	}
+
+	// не даём оплатить план, на который пользователь всё равно не сможет перейти
+	var cur domain.UserPlan
+	if orgID != 0 {
+		cur, err = s.quota.GetOrgPlan(ctx, orgID)
+	} else {
+		cur, err = s.quota.GetCurrentPlan(ctx, userID)
+	}
+	if err != nil {
+		return domain.CheckoutSession{}, err
+	}
+	if cur.ID != target.ID && cur.CurrentUsed > target.StorageLimit {
+		return domain.CheckoutSession{}, quota_service.ErrDowngradeOverLimit
+	}
+
+	id := uuid.NewString()
+	session, err := s.provider.CreateCheckout(ctx, billing.CheckoutRequest{
+		SessionID:   id,
+		UserID:      userID,
+		PlanName:    target.Name,
+		AmountCents: target.PriceCents,
+		Currency:    s.currency,
+	})
+	if err != nil {
+		return domain.CheckoutSession{}, fmt.Errorf("create checkout at %s: %w", s.provider.Name(), err)
+	}
+
+	cs := domain.CheckoutSession{
+		ID:          id,
+		PlanName:    target.Name,
+		AmountCents: target.PriceCents,
+		Currency:    s.currency,
+		AutoRenew:   autoRenew,
+		Status:      CheckoutPending,
+		CheckoutURL: session.URL,
+		ExpiresAt:   session.ExpiresAt,
+	}
+	err = s.db.QueryRowContext(ctx, `
+        INSERT INTO checkout_sessions (id, user_id, org_id, plan_id, provider, provider_session_id, amount_cents, currency, auto_renew, expires_at)
+        VALUES ($1, $2, NULLIF($3, 0), $4, $5, $6, $7, $8, $9, $10)
+        RETURNING created_at
+    `, id, userID, orgID, target.ID, s.provider.Name(), session.ProviderSessionID, cs.AmountCents, cs.Currency, autoRenew, session.ExpiresAt).Scan(&cs.CreatedAt)
+	if err != nil {
+		return domain.CheckoutSession{}, fmt.Errorf("insert checkout session: %w", err)
+	}
+	return cs, nil
+}
+
+// GetCheckout возвращает платёжную сессию пользователя
+func (s *BillingService) GetCheckout(ctx context.Context, userID int, id string) (domain.CheckoutSession, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return domain.CheckoutSession{}, ErrCheckoutNotFound
+	}
+
+	var cs domain.CheckoutSession
+	var completedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, `
+        SELECT cs.id, p.name, cs.amount_cents, cs.currency, cs.auto_renew, cs.status, cs.created_at, cs.expires_at, cs.completed_at
+        FROM checkout_sessions cs
+        JOIN plans p ON p.id = cs.plan_id
+        WHERE cs.id = $1
+          AND cs.user_id = $2
+    `, id, userID).Scan(&cs.ID, &cs.PlanName, &cs.AmountCents, &cs.Currency, &cs.AutoRenew, &cs.Status, &cs.CreatedAt, &cs.ExpiresAt, &completedAt)
+	if err == sql.ErrNoRows {
+		return domain.CheckoutSession{}, ErrCheckoutNotFound
+	}
+	if err != nil {
+		return domain.CheckoutSession{}, fmt.Errorf("get checkout session: %w", err)
+	}
+	if completedAt.Valid {
+		cs.CompletedAt = &completedAt.Time
+	}
+	return cs, nil
+}
+
+// HandleWebhook проверяет подпись вебхука и применяет событие. Событие записывается в billing_events
+// в одной транзакции со сменой плана, поэтому повторная доставка того же события ничего не меняет.
+// duplicate=true — событие уже было обработано раньше.
+func (s *BillingService) HandleWebhook(ctx context.Context, payload []byte, header http.Header) (duplicate bool, err error) {
+	const op = "location internal.service.billing_service.HandleWebhook"
+
+	event, err := s.provider.ParseWebhook(payload, header)
+	if err != nil {
+		return false, err
+	}
+	if event.Type == billing.EventPaymentSucceeded {
+		if err := s.resolveLapsedPlan(ctx, event.ProviderSessionID); err != nil {
+			return false, err
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("handle webhook: %w", err)
+	}
+	defer tx.Rollback()
+
+	var eventRowID int
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO billing_events (provider, event_id, type, provider_session_id, payload)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (provider, event_id) DO NOTHING
+        RETURNING id
+    `, s.provider.Name(), event.ID, event.Type, event.ProviderSessionID, string(payload)).Scan(&eventRowID)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("record billing event: %w", err)
+	}
+
+	var sessionID, status, currency string
+	var userID, planID, amountCents int
+	var orgID sql.NullInt64
+	var autoRenew, expired bool
+	err = tx.QueryRowContext(ctx, `
+        SELECT id, user_id, org_id, plan_id, amount_cents, currency, auto_renew, status, expires_at <= NOW()
+        FROM checkout_sessions
+        WHERE provider = $1
+          AND provider_session_id = $2
+        FOR UPDATE
+    `, s.provider.Name(), event.ProviderSessionID).Scan(&sessionID, &userID, &orgID, &planID, &amountCents, &currency, &autoRenew, &status, &expired)
+	if err == sql.ErrNoRows {
+		// событие по чужой или удалённой сессии: сохраняем в журнал, но ничего не применяем
+		logrus.Warnf("billing event %s for unknown session %s, %s", event.ID, event.ProviderSessionID, op)
+		return false, commit(tx)
+	}
+	if err != nil {
+		return false, fmt.Errorf("lock checkout session: %w", err)
+	}
+
+	if status != CheckoutPending {
+		logrus.Infof("billing event %s (%s) for checkout %s in status %s ignored", event.ID, event.Type, sessionID, status)
+		return false, commit(tx)
+	}
+
+	newStatus := ""
+	switch event.Type {
+	case billing.EventPaymentSucceeded:
+		if expired {
+			// цена и план зафиксированы в сессии на время её жизни, оплату просроченной сессии не применяем
+			logrus.Errorf("billing event %s: payment for expired checkout %s, %s", event.ID, sessionID, op)
+			newStatus = CheckoutExpired
+			break
+		}
+		if event.AmountCents != amountCents || event.Currency != currency {
+			logrus.Errorf("billing event %s: paid %d %s, expected %d %s for checkout %s, %s",
+				event.ID, event.AmountCents, event.Currency, amountCents, currency, sessionID, op)
+			newStatus = CheckoutFailed
+			break
+		}
+		if orgID.Valid {
+			err = s.quota.ApplyOrgPaidPeriod(ctx, tx, int(orgID.Int64), planID, autoRenew)
+		} else {
+			err = s.quota.ApplyPaidPeriod(ctx, tx, userID, planID, autoRenew)
+		}
+		if err != nil {
+			return false, fmt.Errorf("apply payment for checkout %s: %w", sessionID, err)
+		}
+		newStatus = CheckoutPaid
+	case billing.EventPaymentFailed:
+		newStatus = CheckoutFailed
+	case billing.EventCheckoutExpired:
+		newStatus = CheckoutExpired
+	default:
+		logrus.Infof("billing event %s of type %s ignored", event.ID, event.Type)
+		return false, commit(tx)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE checkout_sessions SET status = $2, completed_at = NOW() WHERE id = $1
+    `, sessionID, newStatus); err != nil {
+		return false, fmt.Errorf("update checkout session: %w", err)
+	}
+
+	if err := commit(tx); err != nil {
+		return false, err
+	}
+	if newStatus == CheckoutPaid {
+		if orgID.Valid {
+			s.quota.InvalidateOrgLimits(int(orgID.Int64))
+		} else {
+			s.quota.InvalidateLimits(userID)
+		}
+	}
+	logrus.Infof("checkout %s of user %d: %s", sessionID, userID, newStatus)
+	return false, nil
+}
+
+// resolveLapsedPlan обрабатывает закончившуюся подписку плательщика до транзакции вебхука:
+// ApplyPaidPeriod работает только с действующим планом, а оплата продления обычно приходит уже после окончания периода.
+func (s *BillingService) resolveLapsedPlan(ctx context.Context, providerSessionID string) error {
+	var userID int
+	var orgID sql.NullInt64
+	err := s.db.QueryRowContext(ctx, `
+        SELECT user_id, org_id FROM checkout_sessions WHERE provider = $1 AND provider_session_id = $2
+    `, s.provider.Name(), providerSessionID).Scan(&userID, &orgID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get checkout owner: %w", err)
+	}
+	if orgID.Valid {
+		return s.quota.ResolveLapsedOrgPlan(ctx, int(orgID.Int64))
+	}
+	return s.quota.ResolveLapsedPlan(ctx, userID)
+}
+
+// SimulatePayment имитирует оплату на странице фейкового провайдера: формирует подписанный вебхук
+// и прогоняет его через HandleWebhook, как если бы он пришёл по сети. Возвращает итоговый статус сессии.
+// Оплатить можно только свою сессию: userID — пользователь, который её создал.
+func (s *BillingService) SimulatePayment(ctx context.Context, userID int, providerSessionID string, succeed bool) (string, error) {
+	fake, ok := s.provider.(*billing.FakeProvider)
+	if !ok {
+		return "", ErrNotSupportedByBilling
+	}
+
+	var amountCents int
+	var currency string
+	err := s.db.QueryRowContext(ctx, `
+        SELECT amount_cents, currency FROM checkout_sessions WHERE provider = $1 AND provider_session_id = $2 AND user_id = $3
+    `, fake.Name(), providerSessionID, userID).Scan(&amountCents, &currency)
+	if err == sql.ErrNoRows {
+		return "", ErrCheckoutNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("simulate payment: %w", err)
+	}
+
+	eventType := billing.EventPaymentSucceeded
+	if !succeed {
+		eventType = billing.EventPaymentFailed
+	}
+	payload, header, err := fake.BuildWebhook(eventType, providerSessionID, amountCents, currency)
+	if err != nil {
+		return "", fmt.Errorf("simulate payment: %w", err)
+	}
+	if _, err := s.HandleWebhook(ctx, payload, header); err != nil {
+		return "", err
+	}
+
+	var status string
+	err = s.db.QueryRowContext(ctx, `
+        SELECT status FROM checkout_sessions WHERE provider = $1 AND provider_session_id = $2
+    `, fake.Name(), providerSessionID).Scan(&status)
+	if err != nil {
+		return "", fmt.Errorf("simulate payment: %w", err)
+	}
+	return status, nil
+}
+
+func commit(tx *sql.Tx) error {
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("handle webhook commit: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package billing_service_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/billing"
+	"github.com/1abobik1/SecureComm/internal/service/billing_service"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const (
+	sessionID         = "9b2f6a3e-0c1d-4f5e-8a7b-6c5d4e3f2a10"
+	providerSessionID = "fake_cs_1"
+)
+
+var sessionCols = []string{"id", "user_id", "org_id", "plan_id", "amount_cents", "currency", "auto_renew", "status", "expired"}
+
+func newBillingMock(t *testing.T) (*billing_service.BillingService, *billing.FakeProvider, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	provider := billing.NewFakeProvider("test-secret", "http://localhost:8080", 5*time.Minute)
+	quota := quota_service.NewQuotaServiceForTesting(db)
+	return billing_service.NewBillingServiceForTesting(db, provider, quota, "RUB"), provider, mock
+}
+
+func checkMock(t *testing.T, mock sqlmock.Sqlmock) {
+	t.Helper()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func buildWebhook(t *testing.T, p *billing.FakeProvider, eventType string, amountCents int) ([]byte, http.Header) {
+	t.Helper()
+	payload, header, err := p.BuildWebhook(eventType, providerSessionID, amountCents, "RUB")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return payload, header
+}
+
+// expectPayer — перед транзакцией вебхука обрабатывается закончившаяся подписка плательщика (её нет)
+func expectPayer(mock sqlmock.Sqlmock, userID int) {
+	mock.ExpectQuery(`SELECT user_id, org_id FROM checkout_sessions WHERE provider = \$1 AND provider_session_id = \$2`).
+		WithArgs("fake", providerSessionID).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "org_id"}).AddRow(userID, nil))
+	mock.ExpectQuery(`SELECT up.id FROM user_plans up\s+WHERE up.user_id = \$1`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+}
+
+func expectEventRecorded(mock sqlmock.Sqlmock, eventType string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO billing_events`).
+		WithArgs("fake", sqlmock.AnyArg(), eventType, providerSessionID, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+}
+
+func expectSession(mock sqlmock.Sqlmock, status string, expired bool) {
+	mock.ExpectQuery(`FROM checkout_sessions\s+WHERE provider = \$1\s+AND provider_session_id = \$2\s+FOR UPDATE`).
+		WithArgs("fake", providerSessionID).
+		WillReturnRows(sqlmock.NewRows(sessionCols).AddRow(sessionID, 7, nil, 2, 29900, "RUB", true, status, expired))
+}
+
+func expectSessionStatus(mock sqlmock.Sqlmock, status string) {
+	mock.ExpectExec(`UPDATE checkout_sessions SET status = \$2, completed_at = NOW\(\) WHERE id = \$1`).
+		WithArgs(sessionID, status).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestHandleWebhook_PaymentExtendsPlan(t *testing.T) {
+	s, provider, mock := newBillingMock(t)
+	payload, header := buildWebhook(t, provider, billing.EventPaymentSucceeded, 29900)
+
+	expectPayer(mock, 7)
+	expectEventRecorded(mock, billing.EventPaymentSucceeded)
+	expectSession(mock, billing_service.CheckoutPending, false)
+	mock.ExpectQuery(`SELECT up.id, up.plan_id, p.storage_limit, p.price_cents, up.current_used, up.cancel_at_period_end,.*FOR UPDATE OF up`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "plan_id", "storage_limit", "price_cents", "current_used", "cancel_at_period_end", "storage_override"}).
+			AddRow(10, 2, 10<<30, 29900, 100, false, nil))
+	mock.ExpectExec(`SET expires_at = up.expires_at \+ \(p.period_days \|\| ' days'\)::interval`).
+		WithArgs(10, true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM user_plans WHERE user_id = \$1 AND status = 'active' AND started_at > NOW\(\)`).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	expectSessionStatus(mock, billing_service.CheckoutPaid)
+	mock.ExpectCommit()
+
+	duplicate, err := s.HandleWebhook(context.Background(), payload, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if duplicate {
+		t.Error("duplicate = true, want false")
+	}
+	checkMock(t, mock)
+}
+
+// Оплата пришла после expires_at сессии: план не подключается, сессия помечается expired
+func TestHandleWebhook_ExpiredSessionRejected(t *testing.T) {
+	s, provider, mock := newBillingMock(t)
+	payload, header := buildWebhook(t, provider, billing.EventPaymentSucceeded, 29900)
+
+	expectPayer(mock, 7)
+	expectEventRecorded(mock, billing.EventPaymentSucceeded)
+	expectSession(mock, billing_service.CheckoutPending, true)
+	expectSessionStatus(mock, billing_service.CheckoutExpired)
+	mock.ExpectCommit()
+
+	if _, err := s.HandleWebhook(context.Background(), payload, header); err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}
+
+func TestHandleWebhook_AmountMismatchFails(t *testing.T) {
+	s, provider, mock := newBillingMock(t)
+	payload, header := buildWebhook(t, provider, billing.EventPaymentSucceeded, 100)
+
+	expectPayer(mock, 7)
+	expectEventRecorded(mock, billing.EventPaymentSucceeded)
+	expectSession(mock, billing_service.CheckoutPending, false)
+	expectSessionStatus(mock, billing_service.CheckoutFailed)
+	mock.ExpectCommit()
+
+	if _, err := s.HandleWebhook(context.Background(), payload, header); err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}
+
+func TestHandleWebhook_DuplicateEventIgnored(t *testing.T) {
+	s, provider, mock := newBillingMock(t)
+	payload, header := buildWebhook(t, provider, billing.EventPaymentFailed, 29900)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO billing_events`).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	duplicate, err := s.HandleWebhook(context.Background(), payload, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !duplicate {
+		t.Error("duplicate = false, want true")
+	}
+	checkMock(t, mock)
+}
+
+func TestHandleWebhook_InvalidSignature(t *testing.T) {
+	s, provider, mock := newBillingMock(t)
+	payload, header := buildWebhook(t, provider, billing.EventPaymentSucceeded, 29900)
+	header.Set(billing.FakeSignatureHeader, provider.SignatureHeader(time.Now().Unix(), []byte("другое тело")))
+
+	if _, err := s.HandleWebhook(context.Background(), payload, header); !errors.Is(err, billing.ErrInvalidSignature) {
+		t.Fatalf("err = %v, want ErrInvalidSignature", err)
+	}
+	checkMock(t, mock)
+}
+
+// Чужую сессию оплатить через страницу фейкового провайдера нельзя
+func TestSimulatePayment_OtherUsersSession(t *testing.T) {
+	s, _, mock := newBillingMock(t)
+	mock.ExpectQuery(`SELECT amount_cents, currency FROM checkout_sessions WHERE provider = \$1 AND provider_session_id = \$2 AND user_id = \$3`).
+		WithArgs("fake", providerSessionID, 8).
+		WillReturnError(sql.ErrNoRows)
+
+	if _, err := s.SimulatePayment(context.Background(), 8, providerSessionID, true); !errors.Is(err, billing_service.ErrCheckoutNotFound) {
+		t.Fatalf("err = %v, want ErrCheckoutNotFound", err)
+	}
+	checkMock(t, mock)
+}
@@ -0,0 +1,64 @@
+package cloud_service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/minio/minio-go/v7"
+)
+
+// PurgedFiles — итог удаления файлов пользователя
+type PurgedFiles struct {
+	Objects int
+	Bytes   int64
+}
+
+// PurgeUserFiles удаляет все объекты с префиксом "<userID>/" во всех бакетах категорий вместе с их кешем и очередью автоудаления.
+// Идемпотентен: повторный вызов после сбоя заново перечисляет оставшиеся объекты. Файлы, загруженные пользователем
+// в пул организации (префикс "org-<id>/"), принадлежат организации и не удаляются
+func (m *minioClient) PurgeUserFiles(ctx context.Context, userID int) (PurgedFiles, error) {
+	const op = "location internal.minio.PurgeUserFiles"
+
+	purged, err := m.purgePrefix(ctx, fmt.Sprintf("%d/", userID))
+	if err != nil {
+		return purged, fmt.Errorf("%s: %w", op, err)
+	}
+	return purged, nil
+}
+
+// PurgeOrgFiles удаляет все объекты организации (префикс "org-<orgID>/") во всех бакетах категорий.
+// Вызывается при удалении аккаунта, если пользователь был единственным участником организации. Идемпотентен
+func (m *minioClient) PurgeOrgFiles(ctx context.Context, orgID int) (PurgedFiles, error) {
+	const op = "location internal.minio.PurgeOrgFiles"
+
+	purged, err := m.purgePrefix(ctx, fmt.Sprintf("%s%d/", orgKeyPrefix, orgID))
+	if err != nil {
+		return purged, fmt.Errorf("%s: %w", op, err)
+	}
+	return purged, nil
+}
+
+// purgePrefix удаляет объекты с префиксом prefix во всех бакетах. При ошибке возвращает то, что успел удалить
+func (m *minioClient) purgePrefix(ctx context.Context, prefix string) (PurgedFiles, error) {
+	// отмена останавливает листинг MinIO, если выходим из цикла по ошибке
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var purged PurgedFiles
+	for _, bucket := range Buckets {
+		for object := range m.mc.ListObjects(ctx, bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+			if object.Err != nil {
+				return purged, fmt.Errorf("list %s: %w", bucket, object.Err)
+			}
+
+			if err := m.RemoveObject(ctx, dto.ObjectID{ObjID: object.Key, FileCategory: bucket}); err != nil {
+				return purged, err
+			}
+			purged.Objects++
+			purged.Bytes += object.Size
+		}
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,256 @@
+package cloud_service
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	ArchiveFormatZip = "zip"
+	ArchiveFormatTar = "tar"
+
+	archiveManifestName = "manifest.json"
+
+	// MaxArchiveObjects — сколько файлов можно выгрузить одним архивом (и явным списком, и всей категорией)
+	MaxArchiveObjects = 1000
+)
+
+var (
+	ErrInvalidArchiveFormat  = errors.New("invalid archive format, available: zip, tar")
+	ErrEmptyArchive          = errors.New("no files selected for the archive")
+	ErrTooManyArchiveObjects = fmt.Errorf("too many files for one archive, maximum %d", MaxArchiveObjects)
+)
+
+// ArchiveEntry — проверенный объект, который будет записан в архив.
+type ArchiveEntry struct {
+	Bucket        string
+	ObjID         string
+	Size          int64
+	Name          string
+	MimeType      string
+	EncryptedMeta string
+	CreatedAt     time.Time
+}
+
+// PrepareArchive проверяет права на все запрошенные объекты ещё до начала стриминга,
+// чтобы клиент получил корректный 403/404, а не оборванный архив.
+// Если req.ObjectIDs пуст — берутся все объекты пользователя из req.Category.
+func (m *minioClient) PrepareArchive(ctx context.Context, req dto.ArchiveReq, userID int) ([]ArchiveEntry, error) {
+	const op = "location internal.minio.PrepareArchive"
+
+	if len(req.ObjectIDs) > MaxArchiveObjects {
+		return nil, ErrTooManyArchiveObjects
+	}
+
+	// повторы в запросе убираются: иначе один файл попал бы в архив (и в трафик) несколько раз
+	objectIDs := make([]dto.ObjectID, 0, len(req.ObjectIDs))
+	seen := make(map[dto.ObjectID]struct{}, len(req.ObjectIDs))
+	for _, objectID := range req.ObjectIDs {
+		if _, ok := seen[objectID]; ok {
+			continue
+		}
+		seen[objectID] = struct{}{}
+		objectIDs = append(objectIDs, objectID)
+	}
+
+	if len(objectIDs) == 0 && req.Category != "" {
+		listCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		prefix := fmt.Sprintf("%d/", userID)
+		for object := range m.mc.ListObjects(listCtx, req.Category, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+			if object.Err != nil {
+				log.Printf("Error listing object: %v, %s", object.Err, op)
+				return nil, object.Err
+			}
+			if len(objectIDs) == MaxArchiveObjects {
+				return nil, ErrTooManyArchiveObjects
+			}
+			objectIDs = append(objectIDs, dto.ObjectID{ObjID: object.Key, FileCategory: req.Category})
+		}
+	}
+	if len(objectIDs) == 0 {
+		return nil, ErrEmptyArchive
+	}
+
+	entries := make([]ArchiveEntry, 0, len(objectIDs))
+	for _, objectID := range objectIDs {
+		objInfo, err := m.mc.StatObject(ctx, objectID.FileCategory, objectID.ObjID, minio.StatObjectOptions{})
+		if err != nil {
+			log.Printf("Error: %v, %s \n", err, op)
+			return nil, fmt.Errorf("error getting information about the object %s: %w", objectID.ObjID, ErrFileNotFound)
+		}
+
+		if err := checkObjectOwner(objInfo, userID); err != nil {
+			return nil, err
+		}
+
+		fileResp := buildFileResponse(objInfo, objectID.FileCategory, objectID.ObjID, "")
+		createdAt, err := time.Parse(time.RFC3339, fileResp.Created_At)
+		if err != nil {
+			createdAt = objInfo.LastModified
+		}
+
+		entries = append(entries, ArchiveEntry{
+			Bucket:        objectID.FileCategory,
+			ObjID:         objectID.ObjID,
+			Size:          objInfo.Size,
+			Name:          fileResp.Name,
+			MimeType:      fileResp.MimeType,
+			EncryptedMeta: fileResp.EncryptedMeta,
+			CreatedAt:     createdAt,
+		})
+	}
+
+	return entries, nil
+}
+
+// WriteArchive пишет в w zip или tar с шифртекстами entries без буферизации в памяти.
+// Хэши считаются на лету, поэтому manifest.json записывается последним файлом архива.
+func (m *minioClient) WriteArchive(ctx context.Context, w io.Writer, format string, entries []ArchiveEntry) error {
+	const op = "location internal.minio.WriteArchive"
+
+	var aw archiveWriter
+	switch format {
+	case ArchiveFormatZip:
+		aw = &zipArchiveWriter{zw: zip.NewWriter(w)}
+	case ArchiveFormatTar:
+		aw = &tarArchiveWriter{tw: tar.NewWriter(w)}
+	default:
+		return ErrInvalidArchiveFormat
+	}
+
+	manifest := dto.ArchiveManifest{
+		CreatedAt: time.Now().UTC().Format(time.RFC3339),
+		Format:    format,
+		Files:     make([]dto.ArchiveManifestEntry, 0, len(entries)),
+	}
+
+	for _, entry := range entries {
+		obj, err := m.mc.GetObject(ctx, entry.Bucket, entry.ObjID, minio.GetObjectOptions{})
+		if err != nil {
+			return fmt.Errorf("%s: get object %s: %w", op, entry.ObjID, err)
+		}
+
+		entryPath := path.Join(entry.Bucket, path.Base(entry.ObjID))
+		dst, err := aw.create(entryPath, entry.Size, entry.CreatedAt)
+		if err != nil {
+			obj.Close()
+			return fmt.Errorf("%s: create entry %s: %w", op, entryPath, err)
+		}
+
+		hasher := sha256.New()
+		n, err := io.CopyN(io.MultiWriter(dst, hasher), obj, entry.Size)
+		obj.Close()
+		if err != nil {
+			return fmt.Errorf("%s: copy object %s (%d of %d bytes): %w", op, entry.ObjID, n, entry.Size, err)
+		}
+
+		manifest.Files = append(manifest.Files, dto.ArchiveManifestEntry{
+			Path:          entryPath,
+			ObjID:         entry.ObjID,
+			Category:      entry.Bucket,
+			Name:          entry.Name,
+			MimeType:      entry.MimeType,
+			EncryptedMeta: entry.EncryptedMeta,
+			Size:          entry.Size,
+			Sha256:        hex.EncodeToString(hasher.Sum(nil)),
+		})
+	}
+
+	manifestJson, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%s: marshal manifest: %w", op, err)
+	}
+	dst, err := aw.create(archiveManifestName, int64(len(manifestJson)), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("%s: create manifest: %w", op, err)
+	}
+	if _, err := dst.Write(manifestJson); err != nil {
+		return fmt.Errorf("%s: write manifest: %w", op, err)
+	}
+
+	return aw.close()
+}
+
+// archiveWriter скрывает разницу между zip и tar при последовательной записи файлов.
+type archiveWriter interface {
+	create(name string, size int64, modified time.Time) (io.Writer, error)
+	close() error
+}
+
+type zipArchiveWriter struct {
+	zw *zip.Writer
+}
+
+func (z *zipArchiveWriter) create(name string, _ int64, modified time.Time) (io.Writer, error) {
+	// шифртекст не сжимается, поэтому пишем без компрессии
+	return z.zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: modified,
+	})
+}
+
+func (z *zipArchiveWriter) close() error {
+	return z.zw.Close()
+}
+
+type tarArchiveWriter struct {
+	tw *tar.Writer
+}
+
+func (t *tarArchiveWriter) create(name string, size int64, modified time.Time) (io.Writer, error) {
+	err := t.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    size,
+		ModTime: modified,
+		Format:  tar.FormatPAX,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t.tw, nil
+}
+
+func (t *tarArchiveWriter) close() error {
+	return t.tw.Close()
+}
+
+// checkObjectOwner сверяет владельца объекта из метаданных MinIO с userID.
+// Файлы организаций доступны только через org_files.go, даже их автору: иначе личные операции списали бы их на личный пул.
+func checkObjectOwner(objInfo minio.ObjectInfo, userID int) error {
+	if _, ok := objInfo.UserMetadata[fileMetaOrgID]; ok {
+		return fmt.Errorf("the object %s belongs to an organization: %w", objInfo.Key, ErrForbiddenResource)
+	}
+
+	userIdStr, ok := objInfo.UserMetadata[fileMetaOwnerID]
+	if !ok {
+		return fmt.Errorf("the user_id metadata was not found for the object %s: %w", objInfo.Key, ErrFileNotFound)
+	}
+
+	userIdInt, err := strconv.Atoi(userIdStr)
+	if err != nil {
+		return fmt.Errorf("error converting string number: %s to int", userIdStr)
+	}
+
+	if userIdInt != userID {
+		return fmt.Errorf("you don't have access rights to other people's files: %w", ErrForbiddenResource)
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package cloud_service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/go-redis/redis/v8"
+	"github.com/minio/minio-go/v7"
+)
+
+// fileExpiryKey — sorted set в redis: member "<bucket>:<objID>", score — unix-время удаления
+const fileExpiryKey = "file_expiry"
+
+// MaxFileLifetime — максимальный срок жизни, который можно задать файлу
+const MaxFileLifetime = 365 * 24 * time.Hour
+
+var ErrInvalidExpiration = errors.New("invalid expiration: must be in the future and not later than 365 days")
+
+// ExpiredObject — объект, удалённый sweeper-ом; по нему вызывающий код освобождает квоту
+type ExpiredObject struct {
+	Bucket string
+	ObjID  string
+	UserID int
+	Size   int64
+}
+
+// ParseExpiration переводит expires_at (RFC3339) или expires_in (секунды) в абсолютное время.
+// Если оба пустые — возвращает nil (файл бессрочный).
+func ParseExpiration(expiresAt string, expiresIn int64, now time.Time) (*time.Time, error) {
+	var t time.Time
+	switch {
+	case expiresAt != "":
+		parsed, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidExpiration, err)
+		}
+		t = parsed.UTC()
+	case expiresIn != 0:
+		t = now.Add(time.Duration(expiresIn) * time.Second).UTC()
+	default:
+		return nil, nil
+	}
+
+	if !t.After(now) || t.Sub(now) > MaxFileLifetime {
+		return nil, ErrInvalidExpiration
+	}
+	return &t, nil
+}
+
+// WithExpiry добавляет срок жизни в метаданные объекта перед загрузкой
+func WithExpiry(metadata map[string]string, expiresAt time.Time) map[string]string {
+	metadata[fileMetaExpiresAt] = expiresAt.UTC().Format(time.RFC3339)
+	return metadata
+}
+
+// ScheduleExpiry ставит объект в очередь sweeper-а
+func (m *minioClient) ScheduleExpiry(ctx context.Context, bucket, objID string, expiresAt time.Time) error {
+	return m.scheduleExpiry(ctx, bucket, objID, expiresAt.UTC().Format(time.RFC3339))
+}
+
+// SetExpiration задаёт (expiresAt != nil) или снимает срок жизни файла, переписывая метаданные объекта.
+func (m *minioClient) SetExpiration(ctx context.Context, objectID dto.ObjectID, userID int, expiresAt *time.Time) (dto.FileResponse, error) {
+	const op = "location internal.minio.SetExpiration"
+
+	objInfo, err := m.statOwned(ctx, objectID, userID)
+	if err != nil {
+		log.Printf("Error: %v, %s \n", err, op)
+		return dto.FileResponse{}, err
+	}
+	if isExpired(objInfo) {
+		return dto.FileResponse{}, fmt.Errorf("the object %s has expired: %w", objectID.ObjID, ErrFileNotFound)
+	}
+
+	expiry := ""
+	if expiresAt != nil {
+		expiry = expiresAt.UTC().Format(time.RFC3339)
+	}
+	metadata := replaceUserMetadata(objInfo.UserMetadata, map[string]string{fileMetaExpiresAt: expiry})
+
+	err = copyObject(ctx, m.mc, minio.CopyDestOptions{
+		Bucket:          objectID.FileCategory,
+		Object:          objectID.ObjID,
+		UserMetadata:    metadata,
+		ReplaceMetadata: true,
+		ContentType:     objInfo.ContentType,
+	}, minio.CopySrcOptions{
+		Bucket: objectID.FileCategory,
+		Object: objectID.ObjID,
+	}, objInfo)
+	if err != nil {
+		log.Printf("Error: %v, %s \n", err, op)
+		return dto.FileResponse{}, OperationError{ObjectID: objectID.ObjID, Err: fmt.Errorf("couldn't update expiration: %w", err)}
+	}
+
+	// refreshFileResponse обновит кеш и очередь file_expiry
+	return m.refreshFileResponse(ctx, objectID.FileCategory, objectID.ObjID)
+}
+
+// expiryObjects — часть *minio.Client, нужная sweeper-у; выделена для тестов
+type expiryObjects interface {
+	StatObject(ctx context.Context, bucketName, objectName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error)
+	RemoveObject(ctx context.Context, bucketName, objectName string, opts minio.RemoveObjectOptions) error
+}
+
+// SweepExpired удаляет до limit объектов, срок жизни которых истёк к now.
+// Метаданные объекта — источник истины: если срок успели продлить, объект остаётся и перепланируется.
+func (m *minioClient) SweepExpired(ctx context.Context, now time.Time, limit int64) ([]ExpiredObject, error) {
+	return sweepExpired(ctx, m.redisClient, m.mc, now, limit)
+}
+
+// sweepExpired — реализация SweepExpired. Каждый member сначала атомарно забирается из file_expiry (ZREM == 1):
+// при нескольких экземплярах сервиса объект удаляет и возвращает в ExpiredObject (а значит, освобождает квоту) только забравший.
+// Если удалить не получилось, member возвращается в очередь с прежним score и будет обработан на следующем проходе.
+func sweepExpired(ctx context.Context, rdb redis.Cmdable, objects expiryObjects, now time.Time, limit int64) ([]ExpiredObject, error) {
+	const op = "location internal.minio.SweepExpired"
+
+	due, err := rdb.ZRangeByScoreWithScores(ctx, fileExpiryKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(now.Unix(), 10),
+		Count: limit,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var expired []ExpiredObject
+	for _, z := range due {
+		member, _ := z.Member.(string)
+
+		claimed, err := rdb.ZRem(ctx, fileExpiryKey, member).Result()
+		if err != nil {
+			log.Printf("Error: %v, %s \n", err, op)
+			continue
+		}
+		if claimed == 0 {
+			// member уже забрал другой экземпляр sweeper-а или его перепланировали
+			continue
+		}
+		requeue := func() {
+			if err := rdb.ZAdd(ctx, fileExpiryKey, &redis.Z{Score: z.Score, Member: member}).Err(); err != nil {
+				log.Printf("Failed to requeue %s: %v, %s", member, err, op)
+			}
+		}
+
+		bucket, objID, ok := strings.Cut(member, ":")
+		if !ok {
+			continue
+		}
+
+		objInfo, err := objects.StatObject(ctx, bucket, objID, minio.StatObjectOptions{})
+		if err != nil {
+			if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+				continue
+			}
+			log.Printf("Error: %v, %s \n", err, op)
+			requeue()
+			continue
+		}
+
+		expiresAt, hasExpiry := objectExpiresAt(objInfo)
+		if !hasExpiry || expiresAt.After(now) {
+			if hasExpiry {
+				if err := scheduleExpiry(ctx, rdb, bucket, objID, expiresAt.Format(time.RFC3339)); err != nil {
+					log.Printf("Failed to schedule expiry: %v, %s", err, op)
+				}
+			}
+			continue
+		}
+
+		if err := objects.RemoveObject(ctx, bucket, objID, minio.RemoveObjectOptions{}); err != nil {
+			log.Printf("Error: %v, %s \n", err, op)
+			requeue()
+			continue
+		}
+		if err := rdb.Del(ctx, GetRedisKey(objID, bucket)).Err(); err != nil {
+			log.Printf("Warning deletion did not work, %s,  details: %v", op, err)
+		}
+
+		userID, _ := strconv.Atoi(objInfo.UserMetadata[fileMetaOwnerID])
+		expired = append(expired, ExpiredObject{Bucket: bucket, ObjID: objID, UserID: userID, Size: objInfo.Size})
+	}
+
+	return expired, nil
+}
+
+// scheduleExpiry добавляет объект в file_expiry или убирает его оттуда, если expiresAt пустой
+func (m *minioClient) scheduleExpiry(ctx context.Context, bucket, objID, expiresAt string) error {
+	return scheduleExpiry(ctx, m.redisClient, bucket, objID, expiresAt)
+}
+
+func scheduleExpiry(ctx context.Context, rdb redis.Cmdable, bucket, objID, expiresAt string) error {
+	member := expiryMember(bucket, objID)
+	if expiresAt == "" {
+		return rdb.ZRem(ctx, fileExpiryKey, member).Err()
+	}
+
+	t, err := time.Parse(time.RFC3339, expiresAt)
+	if err != nil {
+		return err
+	}
+	return rdb.ZAdd(ctx, fileExpiryKey, &redis.Z{Score: float64(t.Unix()), Member: member}).Err()
+}
+
+// cacheTTL не даёт кешу пережить сам файл
+func (m *minioClient) cacheTTL(fileResp dto.FileResponse) time.Duration {
+	ttl := m.cfg.Redis.MinioUrlTTL
+	if fileResp.ExpiresAt == "" {
+		return ttl
+	}
+	expiresAt, err := time.Parse(time.RFC3339, fileResp.ExpiresAt)
+	if err != nil {
+		return ttl
+	}
+	if left := time.Until(expiresAt); left < ttl {
+		return max(left, time.Second)
+	}
+	return ttl
+}
+
+func expiryMember(bucket, objID string) string {
+	return bucket + ":" + objID
+}
+
+func objectExpiresAt(objInfo minio.ObjectInfo) (time.Time, bool) {
+	expiresAtStr, ok := objInfo.UserMetadata[fileMetaExpiresAt]
+	if !ok {
+		return time.Time{}, false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, expiresAtStr)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return expiresAt, true
+}
+
+func isExpired(objInfo minio.ObjectInfo) bool {
+	expiresAt, ok := objectExpiresAt(objInfo)
+	return ok && !expiresAt.After(time.Now())
+}
+
+// withRemainingLifetime пересчитывает ExpiresIn (в кеше он устаревает) и возвращает false, если файл уже истёк
+func withRemainingLifetime(fileResp *dto.FileResponse) bool {
+	if fileResp.ExpiresAt == "" {
+		fileResp.ExpiresIn = 0
+		return true
+	}
+	expiresAt, err := time.Parse(time.RFC3339, fileResp.ExpiresAt)
+	if err != nil {
+		return true
+	}
+	left := time.Until(expiresAt)
+	if left <= 0 {
+		return false
+	}
+	fileResp.ExpiresIn = int64(left.Round(time.Second) / time.Second)
+	return true
+}
@@ -0,0 +1,196 @@
+package cloud_service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/minio/minio-go/v7"
+)
+
+// fakeExpiryObjects — объекты в памяти; removed считает удаления каждого ключа
+type fakeExpiryObjects struct {
+	mu        sync.Mutex
+	objects   map[string]minio.ObjectInfo // "<bucket>:<objID>" -> info
+	removed   map[string]int
+	removeErr error
+}
+
+func newFakeExpiryObjects() *fakeExpiryObjects {
+	return &fakeExpiryObjects{objects: map[string]minio.ObjectInfo{}, removed: map[string]int{}}
+}
+
+func (f *fakeExpiryObjects) put(bucket, objID string, userID int, size int64, expiresAt time.Time) {
+	f.objects[expiryMember(bucket, objID)] = minio.ObjectInfo{
+		Key:  objID,
+		Size: size,
+		UserMetadata: map[string]string{
+			fileMetaOwnerID:   strconv.Itoa(userID),
+			fileMetaExpiresAt: expiresAt.UTC().Format(time.RFC3339),
+		},
+	}
+}
+
+func (f *fakeExpiryObjects) StatObject(ctx context.Context, bucket, objID string, opts minio.StatObjectOptions) (minio.ObjectInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	info, ok := f.objects[expiryMember(bucket, objID)]
+	if !ok {
+		return minio.ObjectInfo{}, minio.ErrorResponse{Code: "NoSuchKey", StatusCode: http.StatusNotFound}
+	}
+	return info, nil
+}
+
+func (f *fakeExpiryObjects) RemoveObject(ctx context.Context, bucket, objID string, opts minio.RemoveObjectOptions) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.removeErr != nil {
+		return f.removeErr
+	}
+	key := expiryMember(bucket, objID)
+	f.removed[key]++
+	delete(f.objects, key)
+	return nil
+}
+
+func newExpiryRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return rdb
+}
+
+// Несколько экземпляров sweeper-а обрабатывают одну очередь: каждый объект удаляется и возвращается ровно один раз
+func TestSweepExpired_ConcurrentSweepersClaimOnce(t *testing.T) {
+	ctx := context.Background()
+	rdb := newExpiryRedis(t)
+	objects := newFakeExpiryObjects()
+
+	now := time.Now()
+	const files = 50
+	for i := 0; i < files; i++ {
+		objID := "7/" + strconv.Itoa(i)
+		objects.put("photo", objID, 7, 10, now.Add(-time.Minute))
+		if err := scheduleExpiry(ctx, rdb, "photo", objID, now.Add(-time.Minute).UTC().Format(time.RFC3339)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var mu sync.Mutex
+	var returned []ExpiredObject
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			expired, err := sweepExpired(ctx, rdb, objects, now, files)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			returned = append(returned, expired...)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if len(returned) != files {
+		t.Errorf("expired returned %d times, want %d (one refund per file)", len(returned), files)
+	}
+	for key, n := range objects.removed {
+		if n != 1 {
+			t.Errorf("%s removed %d times, want 1", key, n)
+		}
+	}
+	if left := rdb.ZCard(ctx, fileExpiryKey).Val(); left != 0 {
+		t.Errorf("file_expiry has %d members, want 0", left)
+	}
+}
+
+func TestSweepExpired_ExtendedIsRescheduled(t *testing.T) {
+	ctx := context.Background()
+	rdb := newExpiryRedis(t)
+	objects := newFakeExpiryObjects()
+
+	now := time.Now()
+	extended := now.Add(time.Hour)
+	objects.put("photo", "7/a", 7, 10, extended)
+	// в очереди остался старый срок
+	if err := scheduleExpiry(ctx, rdb, "photo", "7/a", now.Add(-time.Minute).UTC().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+
+	expired, err := sweepExpired(ctx, rdb, objects, now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 0 {
+		t.Errorf("expired = %v, want none", expired)
+	}
+	score, err := rdb.ZScore(ctx, fileExpiryKey, expiryMember("photo", "7/a")).Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(score) != extended.Unix() {
+		t.Errorf("score = %d, want %d", int64(score), extended.Unix())
+	}
+}
+
+func TestSweepExpired_RemoveFailureRequeued(t *testing.T) {
+	ctx := context.Background()
+	rdb := newExpiryRedis(t)
+	objects := newFakeExpiryObjects()
+	objects.removeErr = errors.New("minio unavailable")
+
+	now := time.Now()
+	due := now.Add(-time.Minute)
+	objects.put("photo", "7/a", 7, 10, due)
+	if err := scheduleExpiry(ctx, rdb, "photo", "7/a", due.UTC().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+
+	expired, err := sweepExpired(ctx, rdb, objects, now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 0 {
+		t.Errorf("expired = %v, want none", expired)
+	}
+	score, err := rdb.ZScore(ctx, fileExpiryKey, expiryMember("photo", "7/a")).Result()
+	if err != nil {
+		t.Fatalf("member not requeued: %v", err)
+	}
+	if int64(score) != due.Unix() {
+		t.Errorf("score = %d, want %d", int64(score), due.Unix())
+	}
+}
+
+func TestSweepExpired_MissingObjectDropped(t *testing.T) {
+	ctx := context.Background()
+	rdb := newExpiryRedis(t)
+	objects := newFakeExpiryObjects()
+
+	now := time.Now()
+	if err := scheduleExpiry(ctx, rdb, "photo", "7/gone", now.Add(-time.Minute).UTC().Format(time.RFC3339)); err != nil {
+		t.Fatal(err)
+	}
+
+	expired, err := sweepExpired(ctx, rdb, objects, now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expired) != 0 {
+		t.Errorf("expired = %v, want none", expired)
+	}
+	if left := rdb.ZCard(ctx, fileExpiryKey).Val(); left != 0 {
+		t.Errorf("file_expiry has %d members, want 0", left)
+	}
+}
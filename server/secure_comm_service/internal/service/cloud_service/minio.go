@@ -26,12 +26,23 @@ import (
 )
 
 const fileMetaOwnerID = "User_id"
+const fileMetaOrgID = "Org_id"
 const fileMetaFileName = "File_name"
 const fileMetaCreatedAt = "Created_At"
+const fileMetaEncrypted = "Enc_meta"
+const fileMetaExpiresAt = "Expires_at"
+
+// MaxEncryptedMetaLen ограничивает длину base64 зашифрованных метаданных:
+// MinIO/S3 допускают не больше 2 КБ пользовательских метаданных на объект.
+const MaxEncryptedMetaLen = 1536
+
+// Buckets — категории файлов, под каждую из которых в MinIO заведён отдельный бакет
+var Buckets = []string{"photo", "video", "text", "unknown"}
 
 var (
-	ErrForbiddenResource = errors.New("access to the requested resource is prohibited")
-	ErrFileNotFound      = errors.New("file not found")
+	ErrForbiddenResource     = errors.New("access to the requested resource is prohibited")
+	ErrFileNotFound          = errors.New("file not found")
+	ErrEncryptedMetaTooLarge = fmt.Errorf("encrypted metadata is too large: max %d base64 characters", MaxEncryptedMetaLen)
 )
 
 // Client интерфейс для взаимодействия с Minio
@@ -43,20 +54,40 @@ type Client interface {
 	GetMany(ctx context.Context, objectIDs []dto.ObjectID, userID int) ([]dto.FileResponse, []error)            // Метод для получения нескольких объектов из бакета Minio
 	GetAll(ctx context.Context, t string, userID int) ([]dto.FileResponse, []error)                             // Метод для получения всех объектов из конкретного бакета Minio для конкретного пользователя
 	DeleteOne(ctx context.Context, objectID dto.ObjectID, userID int) (int64, error)                            // Метод для удаления одного объекта из бакета Minio
-	DeleteMany(ctx context.Context, objectIDs []dto.ObjectID, userID int) ([]int64, []error)                    // Метод для удаления нескольких объектов из бакета Minio
+	DeleteMany(ctx context.Context, objectIDs []dto.ObjectID, userID int) ([]DeletedObject, []error)            // Метод для удаления нескольких объектов из бакета Minio
 	PutEncryptedObject(ctx context.Context, bucket, objectKey string, reader io.Reader, size int64, opts minio.PutObjectOptions) (minio.UploadInfo, error)
 	PresignedGetURL(ctx context.Context, bucket, objectKey string) (*url.URL, error)
 	CacheFileResponse(ctx context.Context, bucket, objectKey string, fileResp dto.FileResponse) error
+	PrepareArchive(ctx context.Context, req dto.ArchiveReq, userID int) ([]ArchiveEntry, error)                           // Метод для проверки прав и сбора объектов для архива
+	WriteArchive(ctx context.Context, w io.Writer, format string, entries []ArchiveEntry) error                           // Метод для потоковой записи архива с manifest.json
+	StatOne(ctx context.Context, objectID dto.ObjectID, userID int) (int64, error)                                        // Метод для проверки владельца и получения размера объекта
+	CopyOne(ctx context.Context, objectID dto.ObjectID, targetCategory string, userID int) (dto.FileResponse, error)      // Метод для серверного копирования объекта (в том числе в другую категорию)
+	MoveOne(ctx context.Context, objectID dto.ObjectID, targetCategory string, userID int) (dto.FileResponse, error)      // Метод для переноса объекта в другую категорию
+	RenameOne(ctx context.Context, objectID dto.ObjectID, encryptedMeta string, userID int) (dto.FileResponse, error)     // Метод для замены зашифрованных метаданных (имя, MIME, теги)
+	ScanUsage(ctx context.Context) (users, orgs map[int]StoredUsage, orphans []OrphanObject, err error)                   // Метод для подсчёта фактического объёма файлов по пользователям и организациям
+	PurgeStaleCache(ctx context.Context, dryRun bool) ([]string, error)                                                   // Метод для поиска и удаления кеша удалённых объектов
+	RemoveObject(ctx context.Context, objectID dto.ObjectID) error                                                        // Метод для удаления объекта без проверки владельца (служебные задачи)
+	ScheduleExpiry(ctx context.Context, bucket, objID string, expiresAt time.Time) error                                  // Метод для постановки файла в очередь на автоудаление
+	SetExpiration(ctx context.Context, objectID dto.ObjectID, userID int, expiresAt *time.Time) (dto.FileResponse, error) // Метод для изменения или снятия срока жизни файла
+	SweepExpired(ctx context.Context, now time.Time, limit int64) ([]ExpiredObject, error)                                // Метод для удаления файлов с истёкшим сроком жизни
+	VerifyDownloadLink(bucket, objID, expires, signature string) error                                                    // Метод для проверки подписи и срока ссылки на скачивание
+	OpenDownload(ctx context.Context, bucket, objID string) (*minio.Object, DownloadInfo, error)                          // Метод для открытия объекта на скачивание через сервис
+	GetAllOrg(ctx context.Context, t string, orgID int) ([]dto.FileResponse, []error)                                     // Метод для получения всех файлов организации из бакета
+	GetOrgOne(ctx context.Context, objectID dto.ObjectID, orgID int) (dto.FileResponse, error)                            // Метод для получения одного файла организации
+	DeleteOrgOne(ctx context.Context, objectID dto.ObjectID, orgID, userID int, manage bool) (int64, int, error)          // Метод для удаления файла организации (автор или admin)
+	PurgeUserFiles(ctx context.Context, userID int) (PurgedFiles, error)                                                  // Метод для удаления всех файлов пользователя (удаление аккаунта)
+	PurgeOrgFiles(ctx context.Context, orgID int) (PurgedFiles, error)                                                    // Метод для удаления всех файлов организации (удаление аккаунта её единственного участника)
 }
 
 type minioClient struct {
 	mc          *minio.Client
 	cfg         config.Config
 	redisClient *redis.Client
+	downloadKey []byte // ключ подписи ссылок на скачивание, см. download.go
 }
 
 func NewMinioClient(cfg config.Config, redisClient *redis.Client) Client {
-	return &minioClient{cfg: cfg, redisClient: redisClient}
+	return &minioClient{cfg: cfg, redisClient: redisClient, downloadKey: []byte(cfg.Download.SigningKey)}
 }
 
 func (m *minioClient) InitMinio(minioPort, minioRootUser, minioRootPassword string, minioUseSSL bool) error {
@@ -74,9 +105,7 @@ func (m *minioClient) InitMinio(minioPort, minioRootUser, minioRootPassword stri
 	// Установка подключения Minio
 	m.mc = client
 
-	buckets := []string{"photo", "video", "text", "unknown"}
-
-	for _, bucket := range buckets {
+	for _, bucket := range Buckets {
 		exists, err := m.mc.BucketExists(ctx, bucket)
 		if err != nil {
 			return err
@@ -102,18 +131,15 @@ func (m *minioClient) PutEncryptedObject(
 	return m.mc.PutObject(ctx, bucket, objectKey, reader, size, opts)
 }
 
-func (m *minioClient) PresignedGetURL(ctx context.Context, bucket, objectKey string) (*url.URL, error) {
-	return m.mc.PresignedGetObject(ctx, bucket, objectKey, m.cfg.Minio.UrlTTL, nil)
-}
-
 func (m *minioClient) CacheFileResponse(ctx context.Context, bucket, objectKey string, fileResp dto.FileResponse) error {
-	key := fmt.Sprintf("filemeta:%s:%s", bucket, objectKey)
+	// ключ тот же, что читают GetOne/GetAll, иначе кеш после загрузки никогда не используется
+	key := GetRedisKey(objectKey, bucket)
 	data, err := json.Marshal(fileResp)
 	if err != nil {
 		return err
 	}
 
-	return m.redisClient.Set(ctx, key, data, m.cfg.Redis.MinioUrlTTL).Err()
+	return m.redisClient.Set(ctx, key, data, m.cacheTTL(fileResp)).Err()
 }
 
 // CreateOne создает один объект в бакете Minio.
@@ -146,7 +172,7 @@ func (m *minioClient) CreateOne(ctx context.Context, file domain.FileContent, us
 	}
 
 	// Получение URL для загруженного объекта
-	url, err := m.mc.PresignedGetObject(ctx, fileCategory, objID, m.cfg.Minio.UrlTTL, nil)
+	url, err := m.PresignedGetURL(ctx, fileCategory, objID)
 	if err != nil {
 		return dto.FileResponse{}, fmt.Errorf("error when creating the URL for the object %s: %v", file.Name, err)
 	}
@@ -256,6 +282,9 @@ func (m *minioClient) GetOne(ctx context.Context, objectID dto.ObjectID, userID
 		if err := json.Unmarshal([]byte(fileRespJsonInRedis), &fileResp); err != nil {
 			return dto.FileResponse{}, fmt.Errorf("error unmarshaling FileResponse: %w", err)
 		}
+		if !withRemainingLifetime(&fileResp) {
+			return dto.FileResponse{}, fmt.Errorf("the object %s has expired: %w", objectID.ObjID, ErrFileNotFound)
+		}
 		return fileResp, nil
 	} else if err != redis.Nil {
 		return dto.FileResponse{}, err
@@ -268,37 +297,23 @@ func (m *minioClient) GetOne(ctx context.Context, objectID dto.ObjectID, userID
 		return dto.FileResponse{}, fmt.Errorf("error getting information about the object %s: %w", objectID.ObjID, ErrFileNotFound)
 	}
 
-	userIdStr, ok := objInfo.UserMetadata[fileMetaOwnerID]
-	if !ok {
+	if err := checkObjectOwner(objInfo, userID); err != nil {
 		log.Printf("Error: %v, %s \n", err, op)
-		return dto.FileResponse{}, fmt.Errorf("the user_id metadata was not found for the object %s: %w", objectID.ObjID, ErrFileNotFound)
-	}
-
-	userIdInt, err := strconv.Atoi(userIdStr)
-	if err != nil {
-		return dto.FileResponse{}, fmt.Errorf("error converting string number: %s to int", userIdStr)
+		return dto.FileResponse{}, err
 	}
 
-	if userIdInt != userID {
-		return dto.FileResponse{}, fmt.Errorf("you don't have access rights to other people's files: %w", ErrForbiddenResource)
+	// истёкший, но ещё не удалённый sweeper-ом файл уже недоступен
+	if isExpired(objInfo) {
+		return dto.FileResponse{}, fmt.Errorf("the object %s has expired: %w", objectID.ObjID, ErrFileNotFound)
 	}
 
 	// generate url in minio if not in redis
-	minioURL, err := m.mc.PresignedGetObject(ctx, objectID.FileCategory, objectID.ObjID, m.cfg.Minio.UrlTTL, nil)
+	minioURL, err := m.PresignedGetURL(ctx, objectID.FileCategory, objectID.ObjID)
 	if err != nil {
 		log.Printf("Error: %v, %s", err, op)
 		return dto.FileResponse{}, OperationError{ObjectID: objectID.ObjID, Err: fmt.Errorf("error when getting the URL for the object %s: %w", objectID.ObjID, ErrFileNotFound)}
 	}
-	createdAtStr, okDate := objInfo.UserMetadata[fileMetaCreatedAt]
-	if !okDate {
-		createdAtStr = objInfo.LastModified.Format(time.RFC3339)
-	}
-
-	fileResp.Created_At = createdAtStr
-	fileResp.Name = utils.Encode([]byte(objInfo.UserMetadata[fileMetaFileName]))
-	fileResp.ObjID = objectID.ObjID
-	fileResp.Url = minioURL.String()
-	fileResp.MimeType = objInfo.ContentType
+	fileResp = buildFileResponse(objInfo, objectID.FileCategory, objectID.ObjID, minioURL.String())
 
 	// преобразуем структуру в json для удобного хранения в redis
 	fileRespJson, errJson := json.Marshal(fileResp)
@@ -306,7 +321,7 @@ func (m *minioClient) GetOne(ctx context.Context, objectID dto.ObjectID, userID
 		return dto.FileResponse{}, fmt.Errorf("error marshaling FileResponse: %w", err)
 	}
 	// save in redis
-	err = m.redisClient.Set(ctx, cacheKey, fileRespJson, m.cfg.Redis.MinioUrlTTL).Err()
+	err = m.redisClient.Set(ctx, cacheKey, fileRespJson, m.cacheTTL(fileResp)).Err()
 	if err != nil {
 		log.Printf("Failed to save redis, file URL: %v, %s", err, op)
 	}
@@ -318,9 +333,12 @@ func (m *minioClient) GetOne(ctx context.Context, objectID dto.ObjectID, userID
 // для заданного пользователя. Он использует префикс "<userID>/" для фильтрации объектов.
 // Для каждого найденного объекта генерируется предварительно подписанный URL, который кешируется в Redis.
 func (m *minioClient) GetAll(ctx context.Context, t string, userID int) ([]dto.FileResponse, []error) {
-	const op = "location internal.minio.GetAll"
+	return m.listByPrefix(ctx, t, fmt.Sprintf("%d/", userID))
+}
 
-	prefix := fmt.Sprintf("%d/", userID)
+// listByPrefix возвращает файлы бакета t, ключи которых начинаются с prefix
+func (m *minioClient) listByPrefix(ctx context.Context, t, prefix string) ([]dto.FileResponse, []error) {
+	const op = "location internal.minio.listByPrefix"
 
 	// Список объектов из бакета t, удовлетворяющих префиксу
 	objectCh := m.mc.ListObjects(ctx, t, minio.ListObjectsOptions{
@@ -350,6 +368,9 @@ func (m *minioClient) GetAll(ctx context.Context, t string, userID int) ([]dto.F
 			if err := json.Unmarshal([]byte(fileRespJsonRedis), &fileResp); err != nil {
 				errs = append(errs, err)
 			}
+			if !withRemainingLifetime(&fileResp) {
+				continue
+			}
 			fileResponses = append(fileResponses, fileResp)
 		} else {
 
@@ -359,24 +380,18 @@ func (m *minioClient) GetAll(ctx context.Context, t string, userID int) ([]dto.F
 				log.Printf("Error: %v, %s \n", err, op)
 				errs = append(errs, err)
 			}
+			if isExpired(objInfo) {
+				continue
+			}
 
 			// Если в кеше не найдено, генерируем URL через MinIO
-			presignedURL, err := m.mc.PresignedGetObject(ctx, t, object.Key, m.cfg.Minio.UrlTTL, nil)
+			presignedURL, err := m.PresignedGetURL(ctx, t, object.Key)
 			if err != nil {
 				log.Printf("Error generating presigned URL for object %s: %v", object.Key, err)
 				errs = append(errs, err)
 				continue
 			}
-			createdAtStr, okDate := objInfo.UserMetadata[fileMetaCreatedAt]
-			if !okDate {
-				createdAtStr = objInfo.LastModified.Format(time.RFC3339)
-			}
-
-			fileResp.Created_At = createdAtStr
-			fileResp.Name = utils.Encode([]byte(objInfo.UserMetadata[fileMetaFileName]))
-			fileResp.ObjID = object.Key
-			fileResp.Url = presignedURL.String()
-			fileResp.MimeType = objInfo.ContentType
+			fileResp = buildFileResponse(objInfo, t, object.Key, presignedURL.String())
 
 			// преобразуем структуру в json
 			fileRespJson, err := json.Marshal(fileResp)
@@ -384,7 +399,7 @@ func (m *minioClient) GetAll(ctx context.Context, t string, userID int) ([]dto.F
 				errs = append(errs, err)
 			}
 			// Записываем json структуру dto.FileResponse в Redis с заданным TTL
-			err = m.redisClient.Set(ctx, cacheKey, fileRespJson, m.cfg.Redis.MinioUrlTTL).Err()
+			err = m.redisClient.Set(ctx, cacheKey, fileRespJson, m.cacheTTL(fileResp)).Err()
 			if err != nil {
 				log.Printf("Failed to cache URL for object %s: %v", object.Key, err)
 				errs = append(errs, err)
@@ -469,13 +484,6 @@ func (m *minioClient) GetMany(ctx context.Context, objectIDs []dto.ObjectID, use
 func (m *minioClient) DeleteOne(ctx context.Context, objectID dto.ObjectID, userID int) (int64, error) {
 	const op = "location internal.minio.DeleteOne"
 
-	cacheKey := GetRedisKey(objectID.ObjID, objectID.FileCategory)
-	//deleting data in redis
-	err := m.redisClient.Del(ctx, cacheKey).Err()
-	if err != nil {
-		log.Printf("Warning deletion did not work, %s,  details: %v", op, err)
-	}
-
 	objInfo, err := m.mc.StatObject(ctx, objectID.FileCategory, objectID.ObjID, minio.StatObjectOptions{})
 	if err != nil {
 		log.Printf("Error: %v, %s \n", err, op)
@@ -483,19 +491,9 @@ func (m *minioClient) DeleteOne(ctx context.Context, objectID dto.ObjectID, user
 	}
 	size := objInfo.Size
 
-	userIdStr, ok := objInfo.UserMetadata[fileMetaOwnerID]
-	if !ok {
+	if err := checkObjectOwner(objInfo, userID); err != nil {
 		log.Printf("Error: %v, %s \n", err, op)
-		return 0, fmt.Errorf("the user_id metadata was not found for the object %s: %w", objectID.ObjID, ErrFileNotFound)
-	}
-
-	userIdInt, err := strconv.Atoi(userIdStr)
-	if err != nil {
-		return 0, fmt.Errorf("error converting string number: %s to int", userIdStr)
-	}
-
-	if userIdInt != userID {
-		return 0, fmt.Errorf("you don't have access rights to other people's files: %w", ErrForbiddenResource)
+		return 0, err
 	}
 
 	// deleting data in minio if not in redis
@@ -504,12 +502,28 @@ func (m *minioClient) DeleteOne(ctx context.Context, objectID dto.ObjectID, user
 		log.Printf("error: %v, %s", err, op)
 		return 0, OperationError{ObjectID: objectID.ObjID, Err: fmt.Errorf("couldn't delete selected file: %w", ErrFileNotFound)}
 	}
+
+	// кеш чистим только после проверки владельца и удаления, иначе любой мог сбросить чужой кеш
+	cacheKey := GetRedisKey(objectID.ObjID, objectID.FileCategory)
+	if err := m.redisClient.Del(ctx, cacheKey).Err(); err != nil {
+		log.Printf("Warning deletion did not work, %s,  details: %v", op, err)
+	}
+	if err := m.redisClient.ZRem(ctx, fileExpiryKey, expiryMember(objectID.FileCategory, objectID.ObjID)).Err(); err != nil {
+		log.Printf("Warning deletion did not work, %s,  details: %v", op, err)
+	}
 	return size, nil
 }
 
-// DeleteMany удаляет сразу несколько объектов, возвращая размеры удалённых и ошибки
-func (m *minioClient) DeleteMany(ctx context.Context, objectIDs []dto.ObjectID, userID int) ([]int64, []error) {
+// DeletedObject — удалённый объект и его размер, чтобы вызывающий мог освободить квоту по каждому файлу
+type DeletedObject struct {
+	dto.ObjectID
+	Size int64
+}
+
+// DeleteMany удаляет сразу несколько объектов, возвращая удалённые объекты и ошибки
+func (m *minioClient) DeleteMany(ctx context.Context, objectIDs []dto.ObjectID, userID int) ([]DeletedObject, []error) {
 	type result struct {
+		obj  dto.ObjectID
 		size int64
 		err  error
 	}
@@ -521,7 +535,7 @@ func (m *minioClient) DeleteMany(ctx context.Context, objectIDs []dto.ObjectID,
 		go func(obj dto.ObjectID) {
 			defer wg.Done()
 			size, err := m.DeleteOne(ctx, obj, userID)
-			resCh <- result{size: size, err: err}
+			resCh <- result{obj: obj, size: size, err: err}
 		}(objectID)
 	}
 
@@ -532,17 +546,27 @@ func (m *minioClient) DeleteMany(ctx context.Context, objectIDs []dto.ObjectID,
 	}()
 
 	var (
-		sizes []int64
-		errs  []error
+		deleted []DeletedObject
+		errs    []error
 	)
 	for r := range resCh {
 		if r.err != nil {
 			errs = append(errs, r.err)
 		} else {
-			sizes = append(sizes, r.size)
+			deleted = append(deleted, DeletedObject{ObjectID: r.obj, Size: r.size})
 		}
 	}
-	return sizes, errs
+	return deleted, errs
+}
+
+// IsValidCategory проверяет, что category — одна из категорий Buckets
+func IsValidCategory(category string) bool {
+	for _, bucket := range Buckets {
+		if bucket == category {
+			return true
+		}
+	}
+	return false
 }
 
 func GetRedisKey(ObjID, fileType string) string {
@@ -554,11 +578,24 @@ func GetFileExtension(fileName string) string {
 	return strings.TrimPrefix(ext, ".")
 }
 
+// GenerateFileID генерирует ключ объекта вида "<userID>/<uuid>.<ext>".
+// Пустое расширение (файлы с зашифрованными метаданными) даёт ключ без точки, чтобы не раскрывать тип файла.
 func GenerateFileID(userID int, fileExt string) string {
 	fileExt = strings.TrimPrefix(fileExt, ".")
+	if fileExt == "" {
+		return fmt.Sprintf("%d/%s", userID, uuid.New().String())
+	}
 	return fmt.Sprintf("%d/%s.%s", userID, uuid.New().String(), fileExt)
 }
 
+// GenerateOrgFileID генерирует ключ объекта организации вида "org-<orgID>/<uuid>".
+// Префикс не число, поэтому такие ключи не пересекаются с личными "<userID>/".
+func GenerateOrgFileID(orgID int) string {
+	return fmt.Sprintf("%s%d/%s", orgKeyPrefix, orgID, uuid.New().String())
+}
+
+// GenerateUserMetaData — метаданные в открытом виде, используются только CreateOne.
+// HTTP-загрузка принимает лишь X-Encrypted-Meta.
 func GenerateUserMetaData(userID int, origName string, createdAt time.Time) map[string]string {
 	return map[string]string{
 		fileMetaOwnerID:   fmt.Sprintf("%d", userID),
@@ -567,6 +604,63 @@ func GenerateUserMetaData(userID int, origName string, createdAt time.Time) map[
 	}
 }
 
+// GenerateEncryptedUserMetaData — метаданные для файлов с X-Encrypted-Meta.
+// В открытом виде остаются только владелец и время создания, имя/MIME/теги хранятся непрозрачным blob-ом.
+func GenerateEncryptedUserMetaData(userID int, encryptedMetaB64 string, createdAt time.Time) (map[string]string, error) {
+	if len(encryptedMetaB64) > MaxEncryptedMetaLen {
+		return nil, ErrEncryptedMetaTooLarge
+	}
+	return map[string]string{
+		fileMetaOwnerID:   fmt.Sprintf("%d", userID),
+		fileMetaEncrypted: encryptedMetaB64,
+		fileMetaCreatedAt: createdAt.Format(time.RFC3339),
+	}, nil
+}
+
+// GenerateEncryptedOrgMetaData — метаданные файла организации: кроме автора (User_id) хранится Org_id.
+// Файлы организаций загружаются только с зашифрованными метаданными.
+func GenerateEncryptedOrgMetaData(orgID, userID int, encryptedMetaB64 string, createdAt time.Time) (map[string]string, error) {
+	metadata, err := GenerateEncryptedUserMetaData(userID, encryptedMetaB64, createdAt)
+	if err != nil {
+		return nil, err
+	}
+	metadata[fileMetaOrgID] = strconv.Itoa(orgID)
+	return metadata, nil
+}
+
+// buildFileResponse собирает dto.FileResponse из метаданных объекта MinIO.
+// Для файлов с зашифрованными метаданными имя и MIME-тип не заполняются.
+func buildFileResponse(objInfo minio.ObjectInfo, category, objID, url string) dto.FileResponse {
+	createdAtStr, okDate := objInfo.UserMetadata[fileMetaCreatedAt]
+	if !okDate {
+		createdAtStr = objInfo.LastModified.Format(time.RFC3339)
+	}
+
+	fileResp := dto.FileResponse{
+		Created_At: createdAtStr,
+		ObjID:      objID,
+		Url:        url,
+		Size:       objInfo.Size,
+		Category:   category,
+	}
+
+	if expiresAt, ok := objectExpiresAt(objInfo); ok {
+		fileResp.ExpiresAt = expiresAt.Format(time.RFC3339)
+		withRemainingLifetime(&fileResp)
+	}
+
+	if encMeta, ok := objInfo.UserMetadata[fileMetaEncrypted]; ok {
+		fileResp.EncryptedMeta = encMeta
+	} else {
+		// файлы, загруженные до перехода на X-Encrypted-Meta, хранят имя в открытом виде;
+		// перешифровать их сервер не может (ключей у него нет), поэтому отдаём как есть
+		fileResp.Name = utils.Encode([]byte(objInfo.UserMetadata[fileMetaFileName]))
+		fileResp.MimeType = objInfo.ContentType
+	}
+
+	return fileResp
+}
+
 func GetCategory(contentType string) string {
 	switch {
 	case strings.HasPrefix(contentType, "image/") || contentType == "photo":
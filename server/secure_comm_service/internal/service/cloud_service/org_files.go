@@ -0,0 +1,103 @@
+package cloud_service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/minio/minio-go/v7"
+)
+
+// orgKeyPrefix — начало ключей файлов организаций: "org-<orgID>/<uuid>"
+const orgKeyPrefix = "org-"
+
+// GetAllOrg возвращает все файлы организации из бакета t
+func (m *minioClient) GetAllOrg(ctx context.Context, t string, orgID int) ([]dto.FileResponse, []error) {
+	return m.listByPrefix(ctx, t, fmt.Sprintf("%s%d/", orgKeyPrefix, orgID))
+}
+
+// GetOrgOne возвращает файл организации со свежим presigned URL.
+// Принадлежность проверяется по метаданным до обращения к кешу.
+func (m *minioClient) GetOrgOne(ctx context.Context, objectID dto.ObjectID, orgID int) (dto.FileResponse, error) {
+	const op = "location internal.minio.GetOrgOne"
+
+	objInfo, err := m.statOrgOwned(ctx, objectID, orgID)
+	if err != nil {
+		log.Printf("Error: %v, %s \n", err, op)
+		return dto.FileResponse{}, err
+	}
+	if isExpired(objInfo) {
+		return dto.FileResponse{}, fmt.Errorf("the object %s has expired: %w", objectID.ObjID, ErrFileNotFound)
+	}
+
+	presignedURL, err := m.PresignedGetURL(ctx, objectID.FileCategory, objectID.ObjID)
+	if err != nil {
+		log.Printf("Error: %v, %s", err, op)
+		return dto.FileResponse{}, OperationError{ObjectID: objectID.ObjID, Err: fmt.Errorf("error when getting the URL for the object %s: %w", objectID.ObjID, ErrFileNotFound)}
+	}
+	return buildFileResponse(objInfo, objectID.FileCategory, objectID.ObjID, presignedURL.String()), nil
+}
+
+// DeleteOrgOne удаляет файл организации. Свой файл может удалить любой участник, чужой — только при manage (owner/admin).
+// Возвращает размер и автора файла: место освобождается в пуле организации, а вклад уменьшается у автора.
+func (m *minioClient) DeleteOrgOne(ctx context.Context, objectID dto.ObjectID, orgID, userID int, manage bool) (int64, int, error) {
+	const op = "location internal.minio.DeleteOrgOne"
+
+	objInfo, err := m.statOrgOwned(ctx, objectID, orgID)
+	if err != nil {
+		log.Printf("Error: %v, %s \n", err, op)
+		return 0, 0, err
+	}
+
+	// автор без User_id (битые метаданные) — удалить может только администратор
+	uploaderID, _ := strconv.Atoi(objInfo.UserMetadata[fileMetaOwnerID])
+	if uploaderID != userID && !manage {
+		return 0, 0, fmt.Errorf("only the author or an admin can delete the file of the organization: %w", ErrForbiddenResource)
+	}
+
+	if err := m.mc.RemoveObject(ctx, objectID.FileCategory, objectID.ObjID, minio.RemoveObjectOptions{}); err != nil {
+		log.Printf("error: %v, %s", err, op)
+		return 0, 0, OperationError{ObjectID: objectID.ObjID, Err: fmt.Errorf("couldn't delete selected file: %w", ErrFileNotFound)}
+	}
+
+	if err := m.redisClient.Del(ctx, GetRedisKey(objectID.ObjID, objectID.FileCategory)).Err(); err != nil {
+		log.Printf("Warning deletion did not work, %s,  details: %v", op, err)
+	}
+	return objInfo.Size, uploaderID, nil
+}
+
+func (m *minioClient) statOrgOwned(ctx context.Context, objectID dto.ObjectID, orgID int) (minio.ObjectInfo, error) {
+	objInfo, err := m.mc.StatObject(ctx, objectID.FileCategory, objectID.ObjID, minio.StatObjectOptions{})
+	if err != nil {
+		return minio.ObjectInfo{}, fmt.Errorf("error getting information about the object %s: %w", objectID.ObjID, ErrFileNotFound)
+	}
+	if err := checkOrgObject(objInfo, orgID); err != nil {
+		return minio.ObjectInfo{}, err
+	}
+	return objInfo, nil
+}
+
+// checkOrgObject сверяет организацию объекта из метаданных MinIO с orgID
+func checkOrgObject(objInfo minio.ObjectInfo, orgID int) error {
+	orgIdStr, ok := objInfo.UserMetadata[fileMetaOrgID]
+	if !ok {
+		return fmt.Errorf("the object %s does not belong to an organization: %w", objInfo.Key, ErrForbiddenResource)
+	}
+	if orgIdStr != strconv.Itoa(orgID) {
+		return fmt.Errorf("you don't have access rights to files of other organizations: %w", ErrForbiddenResource)
+	}
+	return nil
+}
+
+// parseOrgKeyPrefix извлекает orgID из префикса ключа "org-<orgID>"
+func parseOrgKeyPrefix(prefix string) (int, bool) {
+	rest, found := strings.CutPrefix(prefix, orgKeyPrefix)
+	if !found {
+		return 0, false
+	}
+	orgID, err := strconv.Atoi(rest)
+	return orgID, err == nil
+}
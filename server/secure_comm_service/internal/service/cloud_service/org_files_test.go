@@ -0,0 +1,53 @@
+package cloud_service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+)
+
+func TestParseOrgKeyPrefix(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   int
+		ok     bool
+	}{
+		{prefix: "org-3", want: 3, ok: true},
+		{prefix: "7", ok: false},
+		{prefix: "org-", ok: false},
+		{prefix: "org-x", ok: false},
+		{prefix: "organization-3", ok: false},
+	}
+	for _, tt := range tests {
+		got, ok := parseOrgKeyPrefix(tt.prefix)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseOrgKeyPrefix(%q) = %d, %v, want %d, %v", tt.prefix, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func orgObject(meta map[string]string) minio.ObjectInfo {
+	return minio.ObjectInfo{Key: "org-3/a", UserMetadata: meta}
+}
+
+func TestCheckOrgObject(t *testing.T) {
+	if err := checkOrgObject(orgObject(map[string]string{fileMetaOrgID: "3", fileMetaOwnerID: "7"}), 3); err != nil {
+		t.Errorf("own org: %v", err)
+	}
+	if err := checkOrgObject(orgObject(map[string]string{fileMetaOrgID: "4"}), 3); !errors.Is(err, ErrForbiddenResource) {
+		t.Errorf("other org: err = %v, want ErrForbiddenResource", err)
+	}
+	// личный файл участника нельзя получить через пространство организации
+	if err := checkOrgObject(orgObject(map[string]string{fileMetaOwnerID: "7"}), 3); !errors.Is(err, ErrForbiddenResource) {
+		t.Errorf("personal file: err = %v, want ErrForbiddenResource", err)
+	}
+}
+
+// Файл организации не отдаётся через личное API даже автору
+func TestCheckObjectOwner_OrgFileRejected(t *testing.T) {
+	err := checkObjectOwner(orgObject(map[string]string{fileMetaOrgID: "3", fileMetaOwnerID: "7"}), 7)
+	if !errors.Is(err, ErrForbiddenResource) {
+		t.Fatalf("err = %v, want ErrForbiddenResource", err)
+	}
+}
@@ -0,0 +1,147 @@
+package cloud_service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/minio/minio-go/v7"
+)
+
+// StoredUsage — фактическое занятое место пользователя по всем бакетам MinIO
+type StoredUsage struct {
+	Bytes int64
+	Files int
+	// LastModified — время последнего изменения среди объектов пользователя.
+	// Нужен реконсилеру, чтобы не трогать пользователей с незавершённой загрузкой.
+	LastModified time.Time
+}
+
+// OrphanObject — объект, который нельзя сопоставить с владельцем:
+// ключ не начинается с "<userID>/" или "org-<orgID>/", либо метаданные не совпадают с префиксом.
+type OrphanObject struct {
+	Bucket string `json:"bucket"`
+	ObjID  string `json:"obj_id"`
+	Size   int64  `json:"size"`
+	Reason string `json:"reason"`
+}
+
+// ScanUsage обходит все бакеты и считает фактический объём объектов по префиксам "<userID>/" (users)
+// и "org-<orgID>/" (orgs).
+func (m *minioClient) ScanUsage(ctx context.Context) (users, orgs map[int]StoredUsage, orphans []OrphanObject, err error) {
+	const op = "location internal.minio.ScanUsage"
+
+	users = make(map[int]StoredUsage)
+	orgs = make(map[int]StoredUsage)
+
+	for _, bucket := range Buckets {
+		for object := range m.mc.ListObjects(ctx, bucket, minio.ListObjectsOptions{Recursive: true}) {
+			if object.Err != nil {
+				log.Printf("Error listing object: %v, %s", object.Err, op)
+				return nil, nil, nil, fmt.Errorf("%s: list %s: %w", op, bucket, object.Err)
+			}
+
+			prefix, _, found := strings.Cut(object.Key, "/")
+			usage := users
+			ownerID, convErr := strconv.Atoi(prefix)
+			checkOwner := checkObjectOwner
+			if orgID, ok := parseOrgKeyPrefix(prefix); ok {
+				usage, ownerID, convErr, checkOwner = orgs, orgID, nil, checkOrgObject
+			}
+			if !found || convErr != nil {
+				orphans = append(orphans, OrphanObject{Bucket: bucket, ObjID: object.Key, Size: object.Size, Reason: "key has no user prefix"})
+				continue
+			}
+
+			objInfo, err := m.mc.StatObject(ctx, bucket, object.Key, minio.StatObjectOptions{})
+			if err != nil {
+				// объект удалили между листингом и Stat — просто пропускаем
+				continue
+			}
+			if err := checkOwner(objInfo, ownerID); err != nil {
+				orphans = append(orphans, OrphanObject{Bucket: bucket, ObjID: object.Key, Size: object.Size, Reason: err.Error()})
+				continue
+			}
+
+			// восстанавливаем очередь file_expiry, если redis её потерял
+			if expiresAt, ok := objectExpiresAt(objInfo); ok {
+				if err := m.scheduleExpiry(ctx, bucket, object.Key, expiresAt.Format(time.RFC3339)); err != nil {
+					log.Printf("Failed to schedule expiry: %v, %s", err, op)
+				}
+			}
+
+			u := usage[ownerID]
+			u.Bytes += objInfo.Size
+			u.Files++
+			if objInfo.LastModified.After(u.LastModified) {
+				u.LastModified = objInfo.LastModified
+			}
+			usage[ownerID] = u
+		}
+	}
+
+	return users, orgs, orphans, nil
+}
+
+// PurgeStaleCache находит ключи кеша ObjID:* (см. GetRedisKey), для которых объекта в MinIO больше нет.
+// При dryRun ключи только возвращаются, иначе удаляются.
+func (m *minioClient) PurgeStaleCache(ctx context.Context, dryRun bool) ([]string, error) {
+	const op = "location internal.minio.PurgeStaleCache"
+
+	var stale []string
+	iter := m.redisClient.Scan(ctx, 0, "ObjID:*", 500).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+
+		objID, bucket, ok := parseRedisKey(key)
+		if ok {
+			_, err := m.mc.StatObject(ctx, bucket, objID, minio.StatObjectOptions{})
+			if err == nil {
+				continue
+			}
+			if minio.ToErrorResponse(err).Code != "NoSuchKey" {
+				log.Printf("Error: %v, %s \n", err, op)
+				continue
+			}
+		}
+
+		stale = append(stale, key)
+		if dryRun {
+			continue
+		}
+		if err := m.redisClient.Del(ctx, key).Err(); err != nil {
+			log.Printf("Warning deletion did not work, %s,  details: %v", op, err)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return stale, fmt.Errorf("%s: scan: %w", op, err)
+	}
+
+	return stale, nil
+}
+
+// RemoveObject удаляет объект без проверки владельца и чистит его кеш. Только для служебных задач.
+func (m *minioClient) RemoveObject(ctx context.Context, objectID dto.ObjectID) error {
+	if err := m.mc.RemoveObject(ctx, objectID.FileCategory, objectID.ObjID, minio.RemoveObjectOptions{}); err != nil {
+		return OperationError{ObjectID: objectID.ObjID, Err: err}
+	}
+	m.redisClient.ZRem(ctx, fileExpiryKey, expiryMember(objectID.FileCategory, objectID.ObjID))
+	return m.redisClient.Del(ctx, GetRedisKey(objectID.ObjID, objectID.FileCategory)).Err()
+}
+
+// parseRedisKey разбирает ключ, созданный GetRedisKey
+func parseRedisKey(key string) (objID, fileType string, ok bool) {
+	rest, found := strings.CutPrefix(key, "ObjID:")
+	if !found {
+		return "", "", false
+	}
+	idx := strings.LastIndex(rest, "-file_type:")
+	if idx < 0 {
+		return "", "", false
+	}
+	return rest[:idx], rest[idx+len("-file_type:"):], true
+}
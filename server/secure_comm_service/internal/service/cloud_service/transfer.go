@@ -0,0 +1,253 @@
+package cloud_service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/dto"
+	"github.com/minio/minio-go/v7"
+)
+
+var ErrSameCategory = errors.New("the file is already in the target category")
+
+// maxCopyObjectSize — предел одиночного CopyObject в S3/MinIO (5 ГиБ).
+// Объекты крупнее копируются по частям через ComposeObject (UploadPartCopy).
+const maxCopyObjectSize int64 = 5 << 30
+
+// objectCopier — часть *minio.Client, нужная для серверного копирования; выделена для тестов
+type objectCopier interface {
+	CopyObject(ctx context.Context, dst minio.CopyDestOptions, src minio.CopySrcOptions) (minio.UploadInfo, error)
+	ComposeObject(ctx context.Context, dst minio.CopyDestOptions, srcs ...minio.CopySrcOptions) (minio.UploadInfo, error)
+}
+
+func needsMultipartCopy(size int64) bool {
+	return size > maxCopyObjectSize
+}
+
+// copyObject копирует объект на стороне MinIO: CopyObject до 5 ГиБ, multipart ComposeObject выше.
+// В multipart-ветке MinIO не переносит Content-Type и берёт метаданные только из dst,
+// поэтому они всегда передаются явно (из srcInfo, если вызывающий код их не заменяет).
+func copyObject(ctx context.Context, c objectCopier, dst minio.CopyDestOptions, src minio.CopySrcOptions, srcInfo minio.ObjectInfo) error {
+	if !needsMultipartCopy(srcInfo.Size) {
+		_, err := c.CopyObject(ctx, dst, src)
+		return err
+	}
+
+	metadata := dst.UserMetadata
+	if !dst.ReplaceMetadata {
+		metadata = srcInfo.UserMetadata
+	}
+	contentType := dst.ContentType
+	if contentType == "" {
+		contentType = srcInfo.ContentType
+	}
+	dst.UserMetadata = replaceUserMetadata(metadata, map[string]string{"Content-Type": contentType})
+	dst.ReplaceMetadata = true
+
+	_, err := c.ComposeObject(ctx, dst, src)
+	return err
+}
+
+// StatOne проверяет, что объект существует и принадлежит userID, и возвращает его размер.
+// Используется перед копированием, чтобы зарезервировать квоту до копирования.
+func (m *minioClient) StatOne(ctx context.Context, objectID dto.ObjectID, userID int) (int64, error) {
+	const op = "location internal.minio.StatOne"
+
+	objInfo, err := m.statOwned(ctx, objectID, userID)
+	if err != nil {
+		log.Printf("Error: %v, %s \n", err, op)
+		return 0, err
+	}
+	return objInfo.Size, nil
+}
+
+// CopyOne создаёт серверную копию объекта (шифртекст не проходит через сервис) в targetCategory под новым ID.
+// Квоту за копию должен списать вызывающий код.
+func (m *minioClient) CopyOne(ctx context.Context, objectID dto.ObjectID, targetCategory string, userID int) (dto.FileResponse, error) {
+	const op = "location internal.minio.CopyOne"
+
+	objInfo, err := m.statOwned(ctx, objectID, userID)
+	if err != nil {
+		log.Printf("Error: %v, %s \n", err, op)
+		return dto.FileResponse{}, err
+	}
+
+	newObjID := GenerateFileID(userID, path.Ext(objectID.ObjID))
+	metadata := replaceUserMetadata(objInfo.UserMetadata, map[string]string{
+		fileMetaCreatedAt: time.Now().UTC().Format(time.RFC3339),
+	})
+
+	err = copyObject(ctx, m.mc, minio.CopyDestOptions{
+		Bucket:          targetCategory,
+		Object:          newObjID,
+		UserMetadata:    metadata,
+		ReplaceMetadata: true,
+		ContentType:     objInfo.ContentType,
+	}, minio.CopySrcOptions{
+		Bucket: objectID.FileCategory,
+		Object: objectID.ObjID,
+	}, objInfo)
+	if err != nil {
+		log.Printf("Error: %v, %s \n", err, op)
+		return dto.FileResponse{}, OperationError{ObjectID: objectID.ObjID, Err: fmt.Errorf("couldn't copy selected file: %w", err)}
+	}
+
+	return m.refreshFileResponse(ctx, targetCategory, newObjID)
+}
+
+// MoveOne переносит объект в targetCategory с тем же ID: серверная копия + удаление исходника.
+// Если исходник удалить не удалось, копия удаляется, чтобы файл не оказался в двух категориях.
+func (m *minioClient) MoveOne(ctx context.Context, objectID dto.ObjectID, targetCategory string, userID int) (dto.FileResponse, error) {
+	const op = "location internal.minio.MoveOne"
+
+	if objectID.FileCategory == targetCategory {
+		return dto.FileResponse{}, ErrSameCategory
+	}
+
+	objInfo, err := m.statOwned(ctx, objectID, userID)
+	if err != nil {
+		log.Printf("Error: %v, %s \n", err, op)
+		return dto.FileResponse{}, err
+	}
+
+	err = copyObject(ctx, m.mc, minio.CopyDestOptions{
+		Bucket: targetCategory,
+		Object: objectID.ObjID,
+	}, minio.CopySrcOptions{
+		Bucket: objectID.FileCategory,
+		Object: objectID.ObjID,
+	}, objInfo)
+	if err != nil {
+		log.Printf("Error: %v, %s \n", err, op)
+		return dto.FileResponse{}, OperationError{ObjectID: objectID.ObjID, Err: fmt.Errorf("couldn't move selected file: %w", err)}
+	}
+
+	if err := m.mc.RemoveObject(ctx, objectID.FileCategory, objectID.ObjID, minio.RemoveObjectOptions{}); err != nil {
+		log.Printf("Error: %v, %s \n", err, op)
+		if rbErr := m.mc.RemoveObject(ctx, targetCategory, objectID.ObjID, minio.RemoveObjectOptions{}); rbErr != nil {
+			log.Printf("Rollback failed, the file %s is now in %s and %s: %v, %s", objectID.ObjID, objectID.FileCategory, targetCategory, rbErr, op)
+		}
+		return dto.FileResponse{}, OperationError{ObjectID: objectID.ObjID, Err: fmt.Errorf("couldn't move selected file: %w", err)}
+	}
+
+	if err := m.redisClient.Del(ctx, GetRedisKey(objectID.ObjID, objectID.FileCategory)).Err(); err != nil {
+		log.Printf("Warning deletion did not work, %s,  details: %v", op, err)
+	}
+	if err := m.redisClient.ZRem(ctx, fileExpiryKey, expiryMember(objectID.FileCategory, objectID.ObjID)).Err(); err != nil {
+		log.Printf("Warning deletion did not work, %s,  details: %v", op, err)
+	}
+
+	return m.refreshFileResponse(ctx, targetCategory, objectID.ObjID)
+}
+
+// RenameOne заменяет зашифрованные метаданные объекта (имя, MIME-тип, теги) копированием объекта в самого себя.
+// Открытые File_name устаревших файлов при этом удаляются.
+func (m *minioClient) RenameOne(ctx context.Context, objectID dto.ObjectID, encryptedMeta string, userID int) (dto.FileResponse, error) {
+	const op = "location internal.minio.RenameOne"
+
+	if len(encryptedMeta) > MaxEncryptedMetaLen {
+		return dto.FileResponse{}, ErrEncryptedMetaTooLarge
+	}
+
+	objInfo, err := m.statOwned(ctx, objectID, userID)
+	if err != nil {
+		log.Printf("Error: %v, %s \n", err, op)
+		return dto.FileResponse{}, err
+	}
+
+	metadata := replaceUserMetadata(objInfo.UserMetadata, map[string]string{
+		fileMetaEncrypted: encryptedMeta,
+		fileMetaFileName:  "",
+	})
+
+	err = copyObject(ctx, m.mc, minio.CopyDestOptions{
+		Bucket:          objectID.FileCategory,
+		Object:          objectID.ObjID,
+		UserMetadata:    metadata,
+		ReplaceMetadata: true,
+		ContentType:     "application/octet-stream",
+	}, minio.CopySrcOptions{
+		Bucket: objectID.FileCategory,
+		Object: objectID.ObjID,
+	}, objInfo)
+	if err != nil {
+		log.Printf("Error: %v, %s \n", err, op)
+		return dto.FileResponse{}, OperationError{ObjectID: objectID.ObjID, Err: fmt.Errorf("couldn't rename selected file: %w", err)}
+	}
+
+	return m.refreshFileResponse(ctx, objectID.FileCategory, objectID.ObjID)
+}
+
+func (m *minioClient) statOwned(ctx context.Context, objectID dto.ObjectID, userID int) (minio.ObjectInfo, error) {
+	objInfo, err := m.mc.StatObject(ctx, objectID.FileCategory, objectID.ObjID, minio.StatObjectOptions{})
+	if err != nil {
+		return minio.ObjectInfo{}, fmt.Errorf("error getting information about the object %s: %w", objectID.ObjID, ErrFileNotFound)
+	}
+	if err := checkObjectOwner(objInfo, userID); err != nil {
+		return minio.ObjectInfo{}, err
+	}
+	return objInfo, nil
+}
+
+// refreshFileResponse перечитывает метаданные объекта после изменения, выдаёт новый presigned URL и обновляет кеш
+func (m *minioClient) refreshFileResponse(ctx context.Context, bucket, objID string) (dto.FileResponse, error) {
+	const op = "location internal.minio.refreshFileResponse"
+
+	objInfo, err := m.mc.StatObject(ctx, bucket, objID, minio.StatObjectOptions{})
+	if err != nil {
+		log.Printf("Error: %v, %s \n", err, op)
+		return dto.FileResponse{}, fmt.Errorf("error getting information about the object %s: %w", objID, ErrFileNotFound)
+	}
+
+	presignedURL, err := m.PresignedGetURL(ctx, bucket, objID)
+	if err != nil {
+		log.Printf("Error: %v, %s", err, op)
+		return dto.FileResponse{}, OperationError{ObjectID: objID, Err: fmt.Errorf("error when getting the URL for the object %s: %w", objID, ErrFileNotFound)}
+	}
+	fileResp := buildFileResponse(objInfo, bucket, objID, presignedURL.String())
+
+	fileRespJson, err := json.Marshal(fileResp)
+	if err != nil {
+		return dto.FileResponse{}, fmt.Errorf("error marshaling FileResponse: %w", err)
+	}
+	if err := m.redisClient.Set(ctx, GetRedisKey(objID, bucket), fileRespJson, m.cacheTTL(fileResp)).Err(); err != nil {
+		log.Printf("Failed to save redis, file URL: %v, %s", err, op)
+	}
+
+	// копия/перенесённый файл наследует срок жизни исходного
+	if err := m.scheduleExpiry(ctx, bucket, objID, fileResp.ExpiresAt); err != nil {
+		log.Printf("Failed to schedule expiry: %v, %s", err, op)
+	}
+
+	return fileResp, nil
+}
+
+// replaceUserMetadata копирует метаданные объекта, заменяя ключи из overrides (пустое значение удаляет ключ).
+// MinIO возвращает ключи в канонической форме (Created_at вместо Created_At), поэтому сравнение без учёта регистра.
+func replaceUserMetadata(src map[string]string, overrides map[string]string) map[string]string {
+	metadata := make(map[string]string, len(src)+len(overrides))
+	for k, v := range src {
+		overridden := false
+		for ok := range overrides {
+			if strings.EqualFold(k, ok) {
+				overridden = true
+				break
+			}
+		}
+		if !overridden {
+			metadata[k] = v
+		}
+	}
+	for k, v := range overrides {
+		if v != "" {
+			metadata[k] = v
+		}
+	}
+	return metadata
+}
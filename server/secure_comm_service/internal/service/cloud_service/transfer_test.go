@@ -0,0 +1,122 @@
+package cloud_service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// fakeCopier запоминает, каким способом копировался объект
+type fakeCopier struct {
+	copies   int
+	composes int
+	dst      minio.CopyDestOptions
+}
+
+func (f *fakeCopier) CopyObject(ctx context.Context, dst minio.CopyDestOptions, src minio.CopySrcOptions) (minio.UploadInfo, error) {
+	f.copies++
+	f.dst = dst
+	return minio.UploadInfo{}, nil
+}
+
+func (f *fakeCopier) ComposeObject(ctx context.Context, dst minio.CopyDestOptions, srcs ...minio.CopySrcOptions) (minio.UploadInfo, error) {
+	f.composes++
+	f.dst = dst
+	return minio.UploadInfo{}, nil
+}
+
+func TestCopyObject_SizeBoundary(t *testing.T) {
+	cases := []struct {
+		name      string
+		size      int64
+		multipart bool
+	}{
+		{"empty", 0, false},
+		{"exactly 5 GiB", maxCopyObjectSize, false},
+		{"5 GiB + 1 byte", maxCopyObjectSize + 1, true},
+		{"20 GiB", 20 << 30, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			c := &fakeCopier{}
+			err := copyObject(context.Background(), c,
+				minio.CopyDestOptions{Bucket: "video", Object: "7/b"},
+				minio.CopySrcOptions{Bucket: "photo", Object: "7/a"},
+				minio.ObjectInfo{Size: tc.size})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			wantCopies, wantComposes := 1, 0
+			if tc.multipart {
+				wantCopies, wantComposes = 0, 1
+			}
+			if c.copies != wantCopies || c.composes != wantComposes {
+				t.Errorf("CopyObject=%d ComposeObject=%d, want %d/%d", c.copies, c.composes, wantCopies, wantComposes)
+			}
+		})
+	}
+}
+
+func TestCopyObject_MultipartKeepsMetadata(t *testing.T) {
+	c := &fakeCopier{}
+	srcInfo := minio.ObjectInfo{
+		Size:         maxCopyObjectSize + 1,
+		ContentType:  "video/mp4",
+		UserMetadata: map[string]string{"User_id": "7", "Created_at": "2026-01-01T00:00:00Z"},
+	}
+
+	// MoveOne не заменяет метаданные — в multipart-ветке они должны прийти из исходника
+	err := copyObject(context.Background(), c,
+		minio.CopyDestOptions{Bucket: "video", Object: "7/a"},
+		minio.CopySrcOptions{Bucket: "photo", Object: "7/a"},
+		srcInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !c.dst.ReplaceMetadata {
+		t.Error("ReplaceMetadata = false, want true")
+	}
+	if got := c.dst.UserMetadata["User_id"]; got != "7" {
+		t.Errorf("User_id = %q, want 7", got)
+	}
+	if got := c.dst.UserMetadata["Content-Type"]; got != "video/mp4" {
+		t.Errorf("Content-Type = %q, want video/mp4", got)
+	}
+}
+
+func TestCopyObject_MultipartReplacedMetadata(t *testing.T) {
+	c := &fakeCopier{}
+	srcInfo := minio.ObjectInfo{
+		Size:         maxCopyObjectSize + 1,
+		ContentType:  "video/mp4",
+		UserMetadata: map[string]string{"User_id": "7", "File_name": "movie.mp4"},
+	}
+
+	err := copyObject(context.Background(), c,
+		minio.CopyDestOptions{
+			Bucket:          "video",
+			Object:          "7/a",
+			UserMetadata:    map[string]string{"User_id": "7", "Enc_meta": "blob"},
+			ReplaceMetadata: true,
+			ContentType:     "application/octet-stream",
+		},
+		minio.CopySrcOptions{Bucket: "video", Object: "7/a"},
+		srcInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := c.dst.UserMetadata["File_name"]; ok {
+		t.Error("File_name copied from source, want replaced metadata only")
+	}
+	if got := c.dst.UserMetadata["Enc_meta"]; got != "blob" {
+		t.Errorf("Enc_meta = %q, want blob", got)
+	}
+	if got := c.dst.UserMetadata["Content-Type"]; got != "application/octet-stream" {
+		t.Errorf("Content-Type = %q, want application/octet-stream", got)
+	}
+}
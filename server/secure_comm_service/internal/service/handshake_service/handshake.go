@@ -109,7 +109,8 @@ func (s *service) ComputeFingerprint(ctx context.Context, rsaPub, ecdsaPub []byt
 
 // Finalize расшифровывает и проверяет подписанное RSA-OAEP сообщение,
 // извлекает key_session и nonce3, проверяет ECDSA-подпись, и возвращает nil в случае успеха.
-func (s *service) Finalize(ctx context.Context, clientID string, sig3, encrypted []byte) (signature4 []byte, er error) {
+// Сессионные ключи сохраняются для сессии устройства sessionID.
+func (s *service) Finalize(ctx context.Context, clientID, sessionID string, sig3, encrypted []byte) (signature4 []byte, er error) {
 	const op = "internal.service.handshake.Finalize"
 
 	rsaPrivS, _, ecdsaPrivS, _ := s.servKeysStore.GetServerKeys()
@@ -164,7 +165,7 @@ func (s *service) Finalize(ctx context.Context, clientID string, sig3, encrypted
 	kEnc := hkdfSha256(ks, []byte("enc"))
 	kMac := hkdfSha256(ks, []byte("mac"))
 
-	err = s.sessions.SaveSessionKeys(ctx, clientID, kEnc, kMac)
+	err = s.sessions.SaveSessionKeys(ctx, clientID, sessionID, kEnc, kMac)
 	if err != nil {
 		return nil, err
 	}
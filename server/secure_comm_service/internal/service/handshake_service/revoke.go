@@ -0,0 +1,35 @@
+package handshake_service
+
+import (
+	"context"
+	"fmt"
+)
+
+// RevokeSessions удаляет сессионные ключи всех устройств пользователя: следующие запросы с его сессиями получат 401,
+// клиентам придётся пройти handshake заново
+func (s *service) RevokeSessions(ctx context.Context, userID string) error {
+	if err := s.sessions.DeleteUserSessions(ctx, userID); err != nil {
+		return fmt.Errorf("revoke sessions of %s: %w", userID, err)
+	}
+	return nil
+}
+
+// RevokeDeviceSession удаляет сессионные ключи одной сессии устройства (отзыв устройства в auth_service)
+func (s *service) RevokeDeviceSession(ctx context.Context, sessionID string) error {
+	if err := s.sessions.DeleteSession(ctx, sessionID); err != nil {
+		return fmt.Errorf("revoke device session %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+// PurgeUserKeys удаляет сессионные ключи и публичные ключи клиента, сохранённые при handshake (удаление аккаунта).
+// Использованные nonce к пользователю не привязаны и истекают сами
+func (s *service) PurgeUserKeys(ctx context.Context, userID string) error {
+	if err := s.sessions.DeleteUserSessions(ctx, userID); err != nil {
+		return fmt.Errorf("purge sessions of %s: %w", userID, err)
+	}
+	if _, err := s.clientPubKeyStore.DeleteClientKeys(ctx, userID); err != nil {
+		return fmt.Errorf("purge client keys of %s: %w", userID, err)
+	}
+	return nil
+}
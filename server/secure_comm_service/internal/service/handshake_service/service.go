@@ -40,12 +40,15 @@ type ClientPubKeyStore interface {
 	SaveClientKeys(ctx context.Context, userID string, rsaPubDER, ecdsaPubDER []byte) error
 	GetClientRSAPub(ctx context.Context, userID string) ([]byte, error)
 	GetClientECDSAPub(ctx context.Context, userID string) (*ecdsa.PublicKey, error)
+	DeleteClientKeys(ctx context.Context, userID string) (int64, error)
 }
 
+// хранит сессионные ключи по сессии устройства (claim sid access токена)
 type SessionStore interface {
-	SaveSessionKeys(ctx context.Context, userID string, kEnc, kMac []byte) error
-	GetSessionKeys(ctx context.Context, userID string) (kEnc, kMac []byte, err error)
-	DeleteSession(ctx context.Context, userID string) error
+	SaveSessionKeys(ctx context.Context, userID, sessionID string, kEnc, kMac []byte) error
+	GetSessionKeys(ctx context.Context, sessionID string) (kEnc, kMac []byte, err error)
+	DeleteSession(ctx context.Context, sessionID string) error
+	DeleteUserSessions(ctx context.Context, userID string) error
 }
 
 type service struct {
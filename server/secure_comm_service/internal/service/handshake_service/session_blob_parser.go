@@ -16,7 +16,7 @@ import (
 
 // parseSessionBlob парсит пакет вида: [timestamp(8 byte) || nonce(16 byte) || IV(16 byte) || ciphertext || tag(32 byte)]
 // выдает чистые payload данные клиента
-func (s *service) parseSessionBlob(ctx context.Context, clientID string, signature, blob []byte) ([]byte, error) {
+func (s *service) parseSessionBlob(ctx context.Context, clientID, sessionID string, signature, blob []byte) ([]byte, error) {
 	const op = "internal.service.parseSessionBlob"
 
 	// парсим signature3 в r3, s3
@@ -51,9 +51,9 @@ func (s *service) parseSessionBlob(ctx context.Context, clientID string, signatu
 	tag := blob[tagStart:]
 
 	// получаем сессионный ключ
-	kEnc, kMac, err := s.sessions.GetSessionKeys(ctx, clientID)
+	kEnc, kMac, err := s.sessions.GetSessionKeys(ctx, sessionID)
 	if err != nil {
-		logrus.Errorf("%s: invalid session for clientID %s", op, clientID)
+		logrus.Errorf("%s: invalid session %s for clientID %s", op, sessionID, clientID)
 		return nil, ErrInvalidSession
 	}
 
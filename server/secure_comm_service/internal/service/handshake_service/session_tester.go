@@ -5,6 +5,6 @@ import (
 )
 
 // DecryptWithSession расшифровывает пакет:
-func (s *service) DecryptWithSession(ctx context.Context, clientID string, signature, blob []byte) ([]byte, error) {
-	return s.parseSessionBlob(ctx, clientID, signature, blob)
+func (s *service) DecryptWithSession(ctx context.Context, clientID, sessionID string, signature, blob []byte) ([]byte, error) {
+	return s.parseSessionBlob(ctx, clientID, sessionID, signature, blob)
 }
@@ -0,0 +1,156 @@
+package org_service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/lib/pq"
+)
+
+// OrgInviteTTL — сколько действует приглашение в организацию
+const OrgInviteTTL = 7 * 24 * time.Hour
+
+// InviteMember приглашает пользователя в организацию с ролью admin или member. Приглашать могут owner и admin.
+// Участником пользователь становится, только приняв приглашение (AcceptInvite): без согласия
+// его нельзя сделать участником чужой организации. Повторное приглашение заменяет роль и продлевает срок.
+func (s *OrgService) InviteMember(ctx context.Context, orgID, actorID, userID int, role string) (domain.OrgInvite, error) {
+	if role != domain.OrgRoleAdmin && role != domain.OrgRoleMember {
+		return domain.OrgInvite{}, ErrInvalidRole
+	}
+	if err := s.requireManager(ctx, orgID, actorID); err != nil {
+		return domain.OrgInvite{}, err
+	}
+
+	inv := domain.OrgInvite{OrgID: orgID, UserID: userID, Role: role, InvitedBy: actorID}
+	err := s.db.QueryRowContext(ctx, `
+        INSERT INTO org_invites (org_id, user_id, role, invited_by, expires_at)
+        SELECT $1, $2, $3, $4, NOW() + $5 * INTERVAL '1 second'
+        WHERE NOT EXISTS (SELECT 1 FROM org_members WHERE org_id = $1 AND user_id = $2)
+        ON CONFLICT (org_id, user_id) DO UPDATE
+        SET role = EXCLUDED.role,
+            invited_by = EXCLUDED.invited_by,
+            created_at = NOW(),
+            expires_at = EXCLUDED.expires_at
+        RETURNING id, created_at, expires_at
+    `, orgID, userID, role, actorID, int64(OrgInviteTTL/time.Second)).Scan(&inv.ID, &inv.CreatedAt, &inv.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return domain.OrgInvite{}, ErrMemberExists
+	}
+	if err != nil {
+		return domain.OrgInvite{}, fmt.Errorf("invite org member: %w", err)
+	}
+	return inv, nil
+}
+
+// ListOrgInvites возвращает действующие приглашения организации. Доступно owner и admin.
+func (s *OrgService) ListOrgInvites(ctx context.Context, orgID, actorID int) ([]domain.OrgInvite, error) {
+	if err := s.requireManager(ctx, orgID, actorID); err != nil {
+		return nil, err
+	}
+	return s.listInvites(ctx, `i.org_id = $1`, orgID)
+}
+
+// ListUserInvites возвращает действующие приглашения пользователя вместе с названиями организаций
+func (s *OrgService) ListUserInvites(ctx context.Context, userID int) ([]domain.OrgInvite, error) {
+	return s.listInvites(ctx, `i.user_id = $1`, userID)
+}
+
+func (s *OrgService) listInvites(ctx context.Context, cond string, id int) ([]domain.OrgInvite, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT i.id, i.org_id, o.name, i.user_id, i.role, i.invited_by, i.created_at, i.expires_at
+        FROM org_invites i
+        JOIN organizations o ON o.id = i.org_id
+        WHERE `+cond+`
+          AND i.expires_at > NOW()
+        ORDER BY i.id
+    `, id)
+	if err != nil {
+		return nil, fmt.Errorf("list org invites: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []domain.OrgInvite{}
+	for rows.Next() {
+		var inv domain.OrgInvite
+		if err := rows.Scan(&inv.ID, &inv.OrgID, &inv.OrgName, &inv.UserID, &inv.Role, &inv.InvitedBy, &inv.CreatedAt, &inv.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("list org invites scan: %w", err)
+		}
+		invites = append(invites, inv)
+	}
+	return invites, rows.Err()
+}
+
+// AcceptInvite принимает приглашение userID: приглашение погашается, пользователь становится участником
+func (s *OrgService) AcceptInvite(ctx context.Context, inviteID, userID int) (domain.Org, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Org{}, fmt.Errorf("accept org invite: %w", err)
+	}
+	defer tx.Rollback()
+
+	var orgID int
+	var role string
+	err = tx.QueryRowContext(ctx, `
+        DELETE FROM org_invites
+        WHERE id = $1
+          AND user_id = $2
+          AND expires_at > NOW()
+        RETURNING org_id, role
+    `, inviteID, userID).Scan(&orgID, &role)
+	if err == sql.ErrNoRows {
+		return domain.Org{}, ErrInviteNotFound
+	}
+	if err != nil {
+		return domain.Org{}, fmt.Errorf("accept org invite: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO org_members (org_id, user_id, role) VALUES ($1, $2, $3)
+    `, orgID, userID, role); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation {
+			return domain.Org{}, ErrMemberExists
+		}
+		return domain.Org{}, fmt.Errorf("accept org invite: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Org{}, fmt.Errorf("accept org invite: %w", err)
+	}
+	return s.GetOrg(ctx, orgID, userID)
+}
+
+// DeclineInvite отклоняет приглашение userID
+func (s *OrgService) DeclineInvite(ctx context.Context, inviteID, userID int) error {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM org_invites WHERE id = $1 AND user_id = $2`, inviteID, userID)
+	if err != nil {
+		return fmt.Errorf("decline org invite: %w", err)
+	}
+	return inviteDeleted(res)
+}
+
+// RevokeInvite отзывает приглашение организации. Доступно owner и admin.
+func (s *OrgService) RevokeInvite(ctx context.Context, orgID, actorID, inviteID int) error {
+	if err := s.requireManager(ctx, orgID, actorID); err != nil {
+		return err
+	}
+	res, err := s.db.ExecContext(ctx, `DELETE FROM org_invites WHERE id = $1 AND org_id = $2`, inviteID, orgID)
+	if err != nil {
+		return fmt.Errorf("revoke org invite: %w", err)
+	}
+	return inviteDeleted(res)
+}
+
+func inviteDeleted(res sql.Result) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("org invite rows: %w", err)
+	}
+	if rows == 0 {
+		return ErrInviteNotFound
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package org_service_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/service/org_service"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func newOrgMock(t *testing.T) (*org_service.OrgService, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return org_service.NewOrgServiceForTesting(db, quota_service.NewQuotaServiceForTesting(db)), mock
+}
+
+func checkMock(t *testing.T, mock sqlmock.Sqlmock) {
+	t.Helper()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func expectRole(mock sqlmock.Sqlmock, orgID, userID int, role string) {
+	mock.ExpectQuery(`SELECT role FROM org_members WHERE org_id = \$1 AND user_id = \$2`).
+		WithArgs(orgID, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(role))
+}
+
+func TestInviteMember_CreatesInvite(t *testing.T) {
+	s, mock := newOrgMock(t)
+	expectRole(mock, 3, 5, domain.OrgRoleAdmin)
+	now := time.Now()
+	mock.ExpectQuery(`INSERT INTO org_invites`).
+		WithArgs(3, 7, domain.OrgRoleMember, 5, int64(org_service.OrgInviteTTL/time.Second)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "expires_at"}).AddRow(11, now, now.Add(org_service.OrgInviteTTL)))
+
+	inv, err := s.InviteMember(context.Background(), 3, 5, 7, domain.OrgRoleMember)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv.ID != 11 || inv.OrgID != 3 || inv.UserID != 7 || inv.InvitedBy != 5 {
+		t.Errorf("invite = %+v", inv)
+	}
+	checkMock(t, mock)
+}
+
+// Рядовой участник приглашать не может
+func TestInviteMember_MemberForbidden(t *testing.T) {
+	s, mock := newOrgMock(t)
+	expectRole(mock, 3, 5, domain.OrgRoleMember)
+
+	if _, err := s.InviteMember(context.Background(), 3, 5, 7, domain.OrgRoleMember); !errors.Is(err, org_service.ErrOrgForbidden) {
+		t.Fatalf("err = %v, want ErrOrgForbidden", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestInviteMember_AlreadyMember(t *testing.T) {
+	s, mock := newOrgMock(t)
+	expectRole(mock, 3, 5, domain.OrgRoleOwner)
+	mock.ExpectQuery(`INSERT INTO org_invites`).
+		WillReturnError(sql.ErrNoRows)
+
+	if _, err := s.InviteMember(context.Background(), 3, 5, 7, domain.OrgRoleAdmin); !errors.Is(err, org_service.ErrMemberExists) {
+		t.Fatalf("err = %v, want ErrMemberExists", err)
+	}
+	checkMock(t, mock)
+}
+
+// Через приглашение нельзя выдать роль owner
+func TestInviteMember_OwnerRoleRejected(t *testing.T) {
+	s, mock := newOrgMock(t)
+
+	if _, err := s.InviteMember(context.Background(), 3, 5, 7, domain.OrgRoleOwner); !errors.Is(err, org_service.ErrInvalidRole) {
+		t.Fatalf("err = %v, want ErrInvalidRole", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestAcceptInvite_JoinsOrg(t *testing.T) {
+	s, mock := newOrgMock(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM org_invites\s+WHERE id = \$1\s+AND user_id = \$2\s+AND expires_at > NOW\(\)`).
+		WithArgs(11, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id", "role"}).AddRow(3, domain.OrgRoleMember))
+	mock.ExpectExec(`INSERT INTO org_members \(org_id, user_id, role\)`).
+		WithArgs(3, 7, domain.OrgRoleMember).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`FROM organizations o\s+JOIN org_members m`).
+		WithArgs(3, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_by", "created_at", "role"}).AddRow(3, "team", 5, time.Now(), domain.OrgRoleMember))
+
+	org, err := s.AcceptInvite(context.Background(), 11, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if org.ID != 3 || org.Role != domain.OrgRoleMember {
+		t.Errorf("org = %+v", org)
+	}
+	checkMock(t, mock)
+}
+
+// Чужое или просроченное приглашение принять нельзя
+func TestAcceptInvite_NotFound(t *testing.T) {
+	s, mock := newOrgMock(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM org_invites`).
+		WithArgs(11, 8).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	if _, err := s.AcceptInvite(context.Background(), 11, 8); !errors.Is(err, org_service.ErrInviteNotFound) {
+		t.Fatalf("err = %v, want ErrInviteNotFound", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestAcceptInvite_AlreadyMember(t *testing.T) {
+	s, mock := newOrgMock(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM org_invites`).
+		WithArgs(11, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id", "role"}).AddRow(3, domain.OrgRoleMember))
+	mock.ExpectExec(`INSERT INTO org_members`).
+		WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+
+	if _, err := s.AcceptInvite(context.Background(), 11, 7); !errors.Is(err, org_service.ErrMemberExists) {
+		t.Fatalf("err = %v, want ErrMemberExists", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestDeclineInvite_NotFound(t *testing.T) {
+	s, mock := newOrgMock(t)
+	mock.ExpectExec(`DELETE FROM org_invites WHERE id = \$1 AND user_id = \$2`).
+		WithArgs(11, 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := s.DeclineInvite(context.Background(), 11, 7); !errors.Is(err, org_service.ErrInviteNotFound) {
+		t.Fatalf("err = %v, want ErrInviteNotFound", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestRevokeInvite_ScopedToOrg(t *testing.T) {
+	s, mock := newOrgMock(t)
+	expectRole(mock, 3, 5, domain.OrgRoleOwner)
+	mock.ExpectExec(`DELETE FROM org_invites WHERE id = \$1 AND org_id = \$2`).
+		WithArgs(11, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := s.RevokeInvite(context.Background(), 3, 5, 11); err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}
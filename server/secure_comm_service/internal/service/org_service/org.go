@@ -0,0 +1,244 @@
+package org_service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+)
+
+var (
+	ErrOrgNotFound    = errors.New("organization not found")
+	ErrNotOrgMember   = errors.New("user is not a member of the organization")
+	ErrOrgForbidden   = errors.New("not enough rights in the organization")
+	ErrMemberExists   = errors.New("user is already a member of the organization")
+	ErrInvalidRole    = errors.New("invalid organization role")
+	ErrOwnerImmutable = errors.New("the owner of the organization cannot be removed or changed")
+	ErrInvalidOrgName = errors.New("invalid organization name")
+	ErrInviteNotFound = errors.New("invitation not found or expired")
+)
+
+// MaxOrgNameLen — максимальная длина названия организации
+const MaxOrgNameLen = 128
+
+// pgUniqueViolation — код ошибки PostgreSQL при нарушении PRIMARY KEY/UNIQUE
+const pgUniqueViolation = "23505"
+
+type OrgService struct {
+	db    *sql.DB
+	quota *quota_service.QuotaService
+}
+
+func NewOrgService(storagePath string, quota *quota_service.QuotaService) (*OrgService, error) {
+	db, err := sql.Open("postgres", storagePath)
+	if err != nil {
+		return nil, err
+	}
+	return &OrgService{db: db, quota: quota}, nil
+}
+
+func NewOrgServiceForTesting(db *sql.DB, quota *quota_service.QuotaService) *OrgService {
+	return &OrgService{db: db, quota: quota}
+}
+
+// CanManage — может ли роль управлять участниками, планом и чужими файлами организации
+func CanManage(role string) bool {
+	return role == domain.OrgRoleOwner || role == domain.OrgRoleAdmin
+}
+
+// CreateOrg создаёт организацию, делает userID её владельцем и подключает пулу free-план
+func (s *OrgService) CreateOrg(ctx context.Context, userID int, name string) (domain.Org, error) {
+	if name == "" || len(name) > MaxOrgNameLen {
+		return domain.Org{}, ErrInvalidOrgName
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Org{}, fmt.Errorf("create org: %w", err)
+	}
+	defer tx.Rollback()
+
+	org := domain.Org{Name: name, CreatedBy: userID, Role: domain.OrgRoleOwner}
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO organizations (name, created_by) VALUES ($1, $2) RETURNING id, created_at
+    `, name, userID).Scan(&org.ID, &org.CreatedAt)
+	if err != nil {
+		return domain.Org{}, fmt.Errorf("create org: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO org_members (org_id, user_id, role) VALUES ($1, $2, $3)
+    `, org.ID, userID, domain.OrgRoleOwner); err != nil {
+		return domain.Org{}, fmt.Errorf("add org owner: %w", err)
+	}
+
+	if err := s.quota.InitializeOrgPlan(ctx, tx, org.ID); err != nil {
+		return domain.Org{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain.Org{}, fmt.Errorf("create org: %w", err)
+	}
+	return org, nil
+}
+
+// ListUserOrgs возвращает организации, в которых состоит пользователь, с его ролью
+func (s *OrgService) ListUserOrgs(ctx context.Context, userID int) ([]domain.Org, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT o.id, o.name, o.created_by, o.created_at, m.role
+        FROM org_members m
+        JOIN organizations o ON o.id = m.org_id
+        WHERE m.user_id = $1
+        ORDER BY o.id
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list user orgs: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := []domain.Org{}
+	for rows.Next() {
+		var o domain.Org
+		if err := rows.Scan(&o.ID, &o.Name, &o.CreatedBy, &o.CreatedAt, &o.Role); err != nil {
+			return nil, fmt.Errorf("list user orgs scan: %w", err)
+		}
+		orgs = append(orgs, o)
+	}
+	return orgs, rows.Err()
+}
+
+// GetOrg возвращает организацию с ролью userID в ней. Не участнику — ErrOrgNotFound, чтобы не раскрывать чужие организации.
+func (s *OrgService) GetOrg(ctx context.Context, orgID, userID int) (domain.Org, error) {
+	var o domain.Org
+	err := s.db.QueryRowContext(ctx, `
+        SELECT o.id, o.name, o.created_by, o.created_at, m.role
+        FROM organizations o
+        JOIN org_members m ON m.org_id = o.id
+        WHERE o.id = $1
+          AND m.user_id = $2
+    `, orgID, userID).Scan(&o.ID, &o.Name, &o.CreatedBy, &o.CreatedAt, &o.Role)
+	if err == sql.ErrNoRows {
+		return domain.Org{}, ErrOrgNotFound
+	}
+	if err != nil {
+		return domain.Org{}, fmt.Errorf("get org: %w", err)
+	}
+	return o, nil
+}
+
+// MemberRole возвращает роль пользователя в организации или ErrNotOrgMember
+func (s *OrgService) MemberRole(ctx context.Context, orgID, userID int) (string, error) {
+	var role string
+	err := s.db.QueryRowContext(ctx, `
+        SELECT role FROM org_members WHERE org_id = $1 AND user_id = $2
+    `, orgID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", ErrNotOrgMember
+	}
+	if err != nil {
+		return "", fmt.Errorf("get member role: %w", err)
+	}
+	return role, nil
+}
+
+// ListMembers возвращает участников организации
+func (s *OrgService) ListMembers(ctx context.Context, orgID int) ([]domain.OrgMember, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT user_id, role, joined_at FROM org_members WHERE org_id = $1 ORDER BY joined_at, user_id
+    `, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("list org members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []domain.OrgMember{}
+	for rows.Next() {
+		var m domain.OrgMember
+		if err := rows.Scan(&m.UserID, &m.Role, &m.JoinedAt); err != nil {
+			return nil, fmt.Errorf("list org members scan: %w", err)
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// UpdateMemberRole меняет роль участника между admin и member. Менять роли может только owner.
+func (s *OrgService) UpdateMemberRole(ctx context.Context, orgID, actorID, userID int, role string) error {
+	if role != domain.OrgRoleAdmin && role != domain.OrgRoleMember {
+		return ErrInvalidRole
+	}
+	actorRole, err := s.MemberRole(ctx, orgID, actorID)
+	if err != nil {
+		return err
+	}
+	if actorRole != domain.OrgRoleOwner {
+		return ErrOrgForbidden
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+        UPDATE org_members SET role = $3 WHERE org_id = $1 AND user_id = $2 AND role <> $4
+    `, orgID, userID, role, domain.OrgRoleOwner)
+	if err != nil {
+		return fmt.Errorf("update member role: %w", err)
+	}
+	return s.checkMemberChanged(ctx, res, orgID, userID)
+}
+
+// RemoveMember исключает участника. Участник может выйти сам, owner и admin могут исключить member,
+// admin исключает другого admin только будучи owner. Owner исключить нельзя.
+// Файлы участника остаются в пуле организации.
+func (s *OrgService) RemoveMember(ctx context.Context, orgID, actorID, userID int) error {
+	if actorID != userID {
+		actorRole, err := s.MemberRole(ctx, orgID, actorID)
+		if err != nil {
+			return err
+		}
+		if !CanManage(actorRole) {
+			return ErrOrgForbidden
+		}
+		targetRole, err := s.MemberRole(ctx, orgID, userID)
+		if err != nil {
+			return err
+		}
+		if targetRole == domain.OrgRoleAdmin && actorRole != domain.OrgRoleOwner {
+			return ErrOrgForbidden
+		}
+	}
+
+	res, err := s.db.ExecContext(ctx, `
+        DELETE FROM org_members WHERE org_id = $1 AND user_id = $2 AND role <> $3
+    `, orgID, userID, domain.OrgRoleOwner)
+	if err != nil {
+		return fmt.Errorf("remove org member: %w", err)
+	}
+	return s.checkMemberChanged(ctx, res, orgID, userID)
+}
+
+func (s *OrgService) requireManager(ctx context.Context, orgID, userID int) error {
+	role, err := s.MemberRole(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if !CanManage(role) {
+		return ErrOrgForbidden
+	}
+	return nil
+}
+
+// checkMemberChanged различает «участника нет» и «это owner», если UPDATE/DELETE не затронул строк
+func (s *OrgService) checkMemberChanged(ctx context.Context, res sql.Result, orgID, userID int) error {
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("org member rows: %w", err)
+	}
+	if rows > 0 {
+		return nil
+	}
+	if _, err := s.MemberRole(ctx, orgID, userID); err != nil {
+		return err
+	}
+	return ErrOwnerImmutable
+}
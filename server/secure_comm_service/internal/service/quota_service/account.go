@@ -0,0 +1,180 @@
+package quota_service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// SoleOwnedOrgs возвращает организации, в которых пользователь — owner и единственный участник, и отзывает
+// приглашения в них: после этого в них никто не вступит, и их файлы можно удалять до DeleteUserData,
+// который удалит сами организации. Идемпотентен
+func (s *QuotaService) SoleOwnedOrgs(ctx context.Context, userID int) ([]int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("sole owned orgs: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+        SELECT m.org_id FROM org_members m
+        WHERE m.user_id = $1 AND m.role = 'owner'
+          AND NOT EXISTS (SELECT 1 FROM org_members o WHERE o.org_id = m.org_id AND o.user_id <> m.user_id)
+        ORDER BY m.org_id
+        FOR UPDATE OF m
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("sole owned orgs: %w", err)
+	}
+	defer rows.Close()
+
+	var orgIDs []int
+	for rows.Next() {
+		var orgID int
+		if err := rows.Scan(&orgID); err != nil {
+			return nil, fmt.Errorf("sole owned orgs: scan: %w", err)
+		}
+		orgIDs = append(orgIDs, orgID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("sole owned orgs: %w", err)
+	}
+
+	if len(orgIDs) > 0 {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM org_invites WHERE org_id = ANY($1)`, pq.Array(orgIDs)); err != nil {
+			return nil, fmt.Errorf("sole owned orgs: revoke invites: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("sole owned orgs: commit: %w", err)
+	}
+	return orgIDs, nil
+}
+
+// DeleteUserData удаляет учёт пользователя при удалении аккаунта: подписки, журнал, трафик, счётчики, уведомления,
+// персональные лимиты, приглашения и членство в организациях. Организации пользователя не остаются без owner:
+// им становится старший admin, а если admin нет — участник, вступивший раньше всех. Организации без других участников
+// удаляются вместе с учётом (их файлы удаляются заранее, см. SoleOwnedOrgs). Записи журнала в пулах оставшихся организаций
+// остаются без автора (user_id = NULL), а оплаты (checkout_sessions) сохраняются для бухгалтерии, незавершённые — закрываются.
+// Идемпотентен. Возвращает число удалённых строк
+func (s *QuotaService) DeleteUserData(ctx context.Context, userID int) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("delete user data: begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	deletedOrgs, deleted, err := s.handOverOrgs(ctx, tx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE usage_ledger SET user_id = NULL WHERE user_id = $1 AND org_id IS NOT NULL`, userID); err != nil {
+		return 0, fmt.Errorf("delete user data: anonymize org ledger: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE checkout_sessions SET status = 'expired' WHERE user_id = $1 AND status = 'pending'`, userID); err != nil {
+		return 0, fmt.Errorf("delete user data: close checkouts: %w", err)
+	}
+
+	for _, query := range []string{
+		`DELETE FROM user_plans WHERE user_id = $1`,
+		`DELETE FROM usage_ledger WHERE user_id = $1`,
+		`DELETE FROM traffic_usage WHERE user_id = $1`,
+		`DELETE FROM user_file_counts WHERE user_id = $1`,
+		`DELETE FROM usage_alert_settings WHERE user_id = $1`,
+		`DELETE FROM usage_alert_state WHERE user_id = $1`,
+		`DELETE FROM user_limit_overrides WHERE user_id = $1`,
+		`DELETE FROM org_invites WHERE user_id = $1`,
+		`DELETE FROM org_members WHERE user_id = $1`,
+	} {
+		res, err := tx.ExecContext(ctx, query, userID)
+		if err != nil {
+			return 0, fmt.Errorf("delete user data: %s: %w", query, err)
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("delete user data: commit: %w", err)
+	}
+
+	s.InvalidateLimits(userID)
+	for _, orgID := range deletedOrgs {
+		s.InvalidateOrgLimits(orgID)
+	}
+	return deleted, nil
+}
+
+// handOverOrgs передаёт организации, которыми владеет пользователь, следующему участнику, а организации
+// без других участников удаляет вместе с их подписками, журналом и счётчиками.
+// Возвращает удалённые организации и число удалённых строк
+func (s *QuotaService) handOverOrgs(ctx context.Context, tx *sql.Tx, userID int) ([]int, int64, error) {
+	rows, err := tx.QueryContext(ctx, `
+        SELECT org_id FROM org_members WHERE user_id = $1 AND role = 'owner' ORDER BY org_id FOR UPDATE
+    `, userID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("delete user data: owned orgs: %w", err)
+	}
+	var owned []int
+	for rows.Next() {
+		var orgID int
+		if err := rows.Scan(&orgID); err != nil {
+			rows.Close()
+			return nil, 0, fmt.Errorf("delete user data: owned orgs: scan: %w", err)
+		}
+		owned = append(owned, orgID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("delete user data: owned orgs: %w", err)
+	}
+
+	var deletedOrgs []int
+	var deleted int64
+	for _, orgID := range owned {
+		res, err := tx.ExecContext(ctx, `
+            UPDATE org_members SET role = 'owner'
+            WHERE (org_id, user_id) = (
+                SELECT org_id, user_id FROM org_members
+                WHERE org_id = $1 AND user_id <> $2
+                ORDER BY role = 'admin' DESC, joined_at, user_id
+                LIMIT 1
+            )
+        `, orgID, userID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("delete user data: hand over org %d: %w", orgID, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			continue
+		}
+
+		// других участников нет: оплаты остаются у плательщика без ссылки на организацию
+		_, err = tx.ExecContext(ctx, `
+            UPDATE checkout_sessions
+            SET org_id = NULL, status = CASE WHEN status = 'pending' THEN 'expired' ELSE status END
+            WHERE org_id = $1
+        `, orgID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("delete user data: detach checkouts of org %d: %w", orgID, err)
+		}
+		for _, query := range []string{
+			`DELETE FROM user_plans WHERE org_id = $1`,
+			`DELETE FROM usage_ledger WHERE org_id = $1`,
+			`DELETE FROM org_file_counts WHERE org_id = $1`,
+			`DELETE FROM organizations WHERE id = $1`,
+		} {
+			res, err := tx.ExecContext(ctx, query, orgID)
+			if err != nil {
+				return nil, 0, fmt.Errorf("delete user data: org %d: %s: %w", orgID, query, err)
+			}
+			n, _ := res.RowsAffected()
+			deleted += n
+		}
+		deletedOrgs = append(deletedOrgs, orgID)
+	}
+	return deletedOrgs, deleted, nil
+}
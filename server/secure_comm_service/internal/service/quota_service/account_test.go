@@ -0,0 +1,74 @@
+package quota_service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+// expectUserRowsDeleted — удаление строк самого пользователя, каждая таблица по одной строке
+func expectUserRowsDeleted(mock sqlmock.Sqlmock, userID int) {
+	mock.ExpectExec(`UPDATE usage_ledger SET user_id = NULL`).WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec(`UPDATE checkout_sessions SET status = 'expired' WHERE user_id = \$1`).WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 0))
+	for _, table := range []string{"user_plans", "usage_ledger", "traffic_usage", "user_file_counts", "usage_alert_settings", "usage_alert_state", "user_limit_overrides", "org_invites", "org_members"} {
+		mock.ExpectExec(`DELETE FROM ` + table + ` WHERE user_id = \$1`).WithArgs(userID).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+}
+
+// Организация с другими участниками переходит к ним, организация без участников удаляется с учётом,
+// приглашения пользователя удаляются
+func TestDeleteUserData_HandsOverOwnedOrgs(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT org_id FROM org_members WHERE user_id = \$1 AND role = 'owner'`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id"}).AddRow(3).AddRow(4))
+	// в организации 3 есть admin — он становится owner
+	mock.ExpectExec(`UPDATE org_members SET role = 'owner'.*ORDER BY role = 'admin' DESC, joined_at, user_id`).
+		WithArgs(3, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// в организации 4 никого больше нет
+	mock.ExpectExec(`UPDATE org_members SET role = 'owner'`).
+		WithArgs(4, 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE checkout_sessions\s+SET org_id = NULL`).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM user_plans WHERE org_id = \$1`).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM usage_ledger WHERE org_id = \$1`).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`DELETE FROM org_file_counts WHERE org_id = \$1`).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM organizations WHERE id = \$1`).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+	expectUserRowsDeleted(mock, 7)
+	mock.ExpectCommit()
+
+	deleted, err := s.DeleteUserData(context.Background(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 5+9 {
+		t.Errorf("deleted = %d, want 14", deleted)
+	}
+	checkMock(t, mock)
+}
+
+// Организации, где пользователь один, закрываются для приглашений до удаления их файлов
+func TestSoleOwnedOrgs_RevokesInvites(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT m.org_id FROM org_members m\s+WHERE m.user_id = \$1 AND m.role = 'owner'\s+AND NOT EXISTS`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"org_id"}).AddRow(4))
+	mock.ExpectExec(`DELETE FROM org_invites WHERE org_id = ANY\(\$1\)`).
+		WithArgs(pq.Array([]int{4})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	orgIDs, err := s.SoleOwnedOrgs(context.Background(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orgIDs) != 1 || orgIDs[0] != 4 {
+		t.Errorf("orgIDs = %v, want [4]", orgIDs)
+	}
+	checkMock(t, mock)
+}
@@ -0,0 +1,539 @@
+package quota_service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/lib/pq"
+)
+
+// Админское API: каталог тарифов, ручное назначение плана и персональные лимиты.
+// Каждое изменение пишется в admin_audit_log в той же транзакции. Проверку роли делает middleware.AdminOnly.
+
+var (
+	ErrPlanExists       = errors.New("plan with this name already exists")
+	ErrInvalidPlan      = errors.New("a paid plan must have a period, and the free plan must stay free and unlimited in time")
+	ErrFreePlanRetire   = errors.New("free plan cannot be retired")
+	ErrPlanRetired      = errors.New("plan is already retired")
+	ErrOverrideNotFound = errors.New("user has no limit override")
+	ErrInvalidOverride  = errors.New("override must set at least one limit, a positive storage limit and expire in the future")
+)
+
+// pgUniqueViolation — код ошибки PostgreSQL при нарушении PRIMARY KEY/UNIQUE
+const pgUniqueViolation = "23505"
+
+// freePlanName — план по умолчанию: его получают новые пользователи и те, чья подписка закончилась
+const freePlanName = "free"
+
+// ListAllPlans возвращает все тарифы, включая выведенные из продажи
+func (s *QuotaService) ListAllPlans(ctx context.Context) ([]domain.Plan, error) {
+	return s.listPlans(ctx, true)
+}
+
+// CreatePlan добавляет тариф в каталог
+func (s *QuotaService) CreatePlan(ctx context.Context, adminID int, p domain.Plan) (domain.Plan, error) {
+	if !validPlan(p) {
+		return domain.Plan{}, ErrInvalidPlan
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Plan{}, fmt.Errorf("create plan: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+        INSERT INTO plans (name, storage_limit, price_cents, period_days, egress_limit, request_limit,
+                           max_file_size, max_files)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id
+    `, p.Name, p.StorageLimit, p.PriceCents, p.PeriodDays, p.EgressLimit, p.RequestLimit,
+		p.MaxFileSize, p.MaxFiles).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pgUniqueViolation {
+			return domain.Plan{}, ErrPlanExists
+		}
+		return domain.Plan{}, fmt.Errorf("create plan: %w", err)
+	}
+
+	created, err := planByID(ctx, tx, id)
+	if err != nil {
+		return domain.Plan{}, err
+	}
+	if err := writeAudit(ctx, tx, adminID, domain.AuditPlanCreate, domain.AuditTargetPlan, created.Name, map[string]any{"after": created}); err != nil {
+		return domain.Plan{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Plan{}, fmt.Errorf("create plan: %w", err)
+	}
+	return created, nil
+}
+
+// UpdatePlan меняет лимиты и цену тарифа. Новые лимиты сразу действуют для всех подписчиков плана,
+// новая цена и длительность — для следующих оплат. Уже начатые периоды не пересчитываются.
+func (s *QuotaService) UpdatePlan(ctx context.Context, adminID int, name string, upd domain.PlanUpdate) (domain.Plan, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Plan{}, fmt.Errorf("update plan: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := planByNameAny(ctx, tx, name)
+	if err != nil {
+		return domain.Plan{}, err
+	}
+
+	after := before
+	setIf(&after.StorageLimit, upd.StorageLimit)
+	setIf(&after.PriceCents, upd.PriceCents)
+	setIf(&after.PeriodDays, upd.PeriodDays)
+	setIf(&after.EgressLimit, upd.EgressLimit)
+	setIf(&after.RequestLimit, upd.RequestLimit)
+	setIf(&after.MaxFileSize, upd.MaxFileSize)
+	setIf(&after.MaxFiles, upd.MaxFiles)
+	if !validPlan(after) {
+		return domain.Plan{}, ErrInvalidPlan
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE plans
+        SET storage_limit = $2, price_cents = $3, period_days = $4, egress_limit = $5, request_limit = $6,
+            max_file_size = $7, max_files = $8
+        WHERE id = $1
+    `, before.ID, after.StorageLimit, after.PriceCents, after.PeriodDays, after.EgressLimit, after.RequestLimit,
+		after.MaxFileSize, after.MaxFiles); err != nil {
+		return domain.Plan{}, fmt.Errorf("update plan: %w", err)
+	}
+	if err := writeAudit(ctx, tx, adminID, domain.AuditPlanUpdate, domain.AuditTargetPlan, name, map[string]any{"before": before, "after": after}); err != nil {
+		return domain.Plan{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Plan{}, fmt.Errorf("update plan: %w", err)
+	}
+	// лимиты плана закешированы у всех его подписчиков
+	s.limits.Flush()
+	return after, nil
+}
+
+// RetirePlan выводит тариф из продажи: он пропадает из /plans, на него нельзя перейти или оплатить.
+// Текущие подписчики остаются на нём до конца оплаченного периода, после чего переходят на free.
+func (s *QuotaService) RetirePlan(ctx context.Context, adminID int, name string) (domain.Plan, error) {
+	if name == freePlanName {
+		return domain.Plan{}, ErrFreePlanRetire
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.Plan{}, fmt.Errorf("retire plan: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := planByNameAny(ctx, tx, name)
+	if err != nil {
+		return domain.Plan{}, err
+	}
+	if before.RetiredAt != nil {
+		return domain.Plan{}, ErrPlanRetired
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE plans SET retired_at = NOW() WHERE id = $1`, before.ID); err != nil {
+		return domain.Plan{}, fmt.Errorf("retire plan: %w", err)
+	}
+	after, err := planByID(ctx, tx, before.ID)
+	if err != nil {
+		return domain.Plan{}, err
+	}
+	if err := writeAudit(ctx, tx, adminID, domain.AuditPlanRetire, domain.AuditTargetPlan, name, map[string]any{"retired_at": after.RetiredAt}); err != nil {
+		return domain.Plan{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.Plan{}, fmt.Errorf("retire plan: %w", err)
+	}
+	return after, nil
+}
+
+// AssignPlan вручную переводит пользователя на план без оплаты и проверки направления смены.
+// Платный план действует period_days и без автопродления, после чего пользователь переходит на free.
+// Если занято больше нового лимита, аккаунт становится read-only.
+func (s *QuotaService) AssignPlan(ctx context.Context, adminID, userID int, planName, reason string) (domain.UserPlan, error) {
+	owner := userOwner(userID)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.UserPlan{}, fmt.Errorf("assign plan: %w", err)
+	}
+	defer tx.Rollback()
+
+	cur, err := lockActivePlan(ctx, tx, owner)
+	if err != nil {
+		return domain.UserPlan{}, err
+	}
+	target, err := planByName(ctx, tx, planName)
+	if err != nil {
+		return domain.UserPlan{}, err
+	}
+	if target.ID == cur.planID {
+		return domain.UserPlan{}, ErrAlreadyOnPlan
+	}
+	from, err := planByID(ctx, tx, cur.planID)
+	if err != nil {
+		return domain.UserPlan{}, err
+	}
+
+	readOnly := cur.currentUsed > cur.storageLimitFor(target.StorageLimit)
+	if err := replacePlan(ctx, tx, owner, cur, target.ID, readOnly, false); err != nil {
+		return domain.UserPlan{}, err
+	}
+	if err := writeAudit(ctx, tx, adminID, domain.AuditUserAssignPlan, domain.AuditTargetUser, strconv.Itoa(userID), map[string]any{
+		"from_plan": from.Name,
+		"to_plan":   target.Name,
+		"read_only": readOnly,
+		"reason":    reason,
+	}); err != nil {
+		return domain.UserPlan{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.UserPlan{}, fmt.Errorf("assign plan: %w", err)
+	}
+	s.limits.Delete(owner.cacheKey())
+	return s.getCurrentPlan(ctx, owner)
+}
+
+// GetLimitOverride возвращает персональные лимиты пользователя, в том числе истёкшие
+func (s *QuotaService) GetLimitOverride(ctx context.Context, userID int) (domain.LimitOverride, error) {
+	o, err := scanLimitOverride(s.db.QueryRowContext(ctx, `
+        SELECT `+limitOverrideColumns+` FROM user_limit_overrides WHERE user_id = $1
+    `, userID))
+	if err == sql.ErrNoRows {
+		return domain.LimitOverride{}, ErrOverrideNotFound
+	}
+	if err != nil {
+		return domain.LimitOverride{}, fmt.Errorf("get limit override: %w", err)
+	}
+	return o, nil
+}
+
+// SetLimitOverride задаёт пользователю персональные лимиты до o.ExpiresAt, заменяя предыдущие.
+// Если с новым лимитом хранилища занятое место укладывается в лимит, read-only снимается, иначе начинается grace-период.
+func (s *QuotaService) SetLimitOverride(ctx context.Context, adminID int, o domain.LimitOverride) (domain.LimitOverride, error) {
+	if !o.ExpiresAt.After(time.Now()) || (o.StorageLimit != nil && *o.StorageLimit <= 0) ||
+		(o.StorageLimit == nil && o.MaxFileSize == nil && o.MaxFiles == nil && o.EgressLimit == nil && o.RequestLimit == nil) {
+		return domain.LimitOverride{}, ErrInvalidOverride
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.LimitOverride{}, fmt.Errorf("set limit override: %w", err)
+	}
+	defer tx.Rollback()
+
+	// пользователь без плана либо не существует, либо не прошёл регистрацию до конца
+	if _, err := lockActivePlan(ctx, tx, userOwner(o.UserID)); err != nil {
+		return domain.LimitOverride{}, err
+	}
+
+	before, err := scanLimitOverride(tx.QueryRowContext(ctx, `
+        SELECT `+limitOverrideColumns+` FROM user_limit_overrides WHERE user_id = $1 FOR UPDATE
+    `, o.UserID))
+	if err != nil && err != sql.ErrNoRows {
+		return domain.LimitOverride{}, fmt.Errorf("set limit override: %w", err)
+	}
+	hadBefore := err == nil
+
+	after, err := scanLimitOverride(tx.QueryRowContext(ctx, `
+        INSERT INTO user_limit_overrides (user_id, storage_limit, max_file_size, max_files, egress_limit, request_limit,
+                                          expires_at, reason, created_by, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW())
+        ON CONFLICT (user_id) DO UPDATE
+        SET storage_limit = EXCLUDED.storage_limit,
+            max_file_size = EXCLUDED.max_file_size,
+            max_files = EXCLUDED.max_files,
+            egress_limit = EXCLUDED.egress_limit,
+            request_limit = EXCLUDED.request_limit,
+            expires_at = EXCLUDED.expires_at,
+            reason = EXCLUDED.reason,
+            created_by = EXCLUDED.created_by,
+            updated_at = NOW(),
+            expiry_processed_at = NULL
+        RETURNING `+limitOverrideColumns,
+		o.UserID, o.StorageLimit, o.MaxFileSize, o.MaxFiles, o.EgressLimit, o.RequestLimit, o.ExpiresAt, o.Reason, adminID))
+	if err != nil {
+		return domain.LimitOverride{}, fmt.Errorf("set limit override: %w", err)
+	}
+
+	if _, err := applyStorageLimit(ctx, tx, []int{o.UserID}, s.lapse.grace); err != nil {
+		return domain.LimitOverride{}, err
+	}
+
+	details := map[string]any{"after": after}
+	if hadBefore {
+		details["before"] = before
+	}
+	if err := writeAudit(ctx, tx, adminID, domain.AuditUserSetOverride, domain.AuditTargetUser, strconv.Itoa(o.UserID), details); err != nil {
+		return domain.LimitOverride{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.LimitOverride{}, fmt.Errorf("set limit override: %w", err)
+	}
+	s.InvalidateLimits(o.UserID)
+	return after, nil
+}
+
+// RemoveLimitOverride снимает персональные лимиты: снова действуют лимиты плана.
+// Если занятое место в лимит плана не укладывается, начинается grace-период.
+func (s *QuotaService) RemoveLimitOverride(ctx context.Context, adminID, userID int) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("remove limit override: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := scanLimitOverride(tx.QueryRowContext(ctx, `
+        DELETE FROM user_limit_overrides WHERE user_id = $1 RETURNING `+limitOverrideColumns, userID))
+	if err == sql.ErrNoRows {
+		return ErrOverrideNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("remove limit override: %w", err)
+	}
+	if _, err := applyStorageLimit(ctx, tx, []int{userID}, s.lapse.grace); err != nil {
+		return err
+	}
+	if err := writeAudit(ctx, tx, adminID, domain.AuditUserRemoveOverride, domain.AuditTargetUser, strconv.Itoa(userID), map[string]any{"before": before}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("remove limit override: %w", err)
+	}
+	s.InvalidateLimits(userID)
+	return nil
+}
+
+// ProcessExpiredOverrides снова применяет лимиты плана к пользователям, у которых истекли персональные лимиты
+// (не больше limit за вызов). Истёкшие лимиты не удаляются — они остаются в GetLimitOverride, а помечаются обработанными,
+// поэтому при нескольких экземплярах сервиса каждый пользователь обрабатывается один раз.
+// Для каждого пользователя возвращается переход PlanActionOverrideExpired; GraceUntil не nil, если занято больше лимита плана.
+func (s *QuotaService) ProcessExpiredOverrides(ctx context.Context, grace time.Duration, limit int) ([]domain.PlanTransition, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("process expired overrides: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+        UPDATE user_limit_overrides
+        SET expiry_processed_at = NOW()
+        WHERE user_id IN (
+            SELECT user_id FROM user_limit_overrides
+            WHERE expires_at <= NOW()
+              AND expiry_processed_at IS NULL
+            ORDER BY expires_at
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING user_id
+    `, limit)
+	if err != nil {
+		return nil, fmt.Errorf("process expired overrides: %w", err)
+	}
+	var userIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("process expired overrides scan: %w", err)
+		}
+		userIDs = append(userIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("process expired overrides: %w", err)
+	}
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	transitions, err := applyStorageLimit(ctx, tx, userIDs, grace)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("process expired overrides: %w", err)
+	}
+	for _, id := range userIDs {
+		s.InvalidateLimits(id)
+	}
+	return transitions, nil
+}
+
+// applyStorageLimit пересчитывает read-only и grace-период пользователей после смены их лимита хранилища
+// (назначение, снятие или истечение персональных лимитов). Уложившимся в лимит read-only и grace снимаются,
+// у превысивших начинается grace-период (уже начатый не продлевается): в read-only их переводит EnforceGracePeriods.
+// Возвращает переходы по действующим подпискам.
+func applyStorageLimit(ctx context.Context, tx *sql.Tx, userIDs []int, grace time.Duration) ([]domain.PlanTransition, error) {
+	rows, err := tx.QueryContext(ctx, `
+        UPDATE user_plans up
+        SET read_only = up.read_only AND up.current_used > `+limitExpr(LimitStorage)+`,
+            grace_until = CASE WHEN up.current_used > `+limitExpr(LimitStorage)+`
+                               THEN COALESCE(up.grace_until, NOW() + $2 * INTERVAL '1 second')
+                          END
+        FROM plans p
+        WHERE p.id = up.plan_id
+          AND up.user_id = ANY($1)
+          AND `+livePlanCond+`
+        RETURNING up.user_id, p.name, up.grace_until, up.started_at <= NOW()
+    `, pq.Array(userIDs), int64(grace/time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("apply storage limit: %w", err)
+	}
+	defer rows.Close()
+
+	var transitions []domain.PlanTransition
+	for rows.Next() {
+		var t domain.PlanTransition
+		var graceUntil sql.NullTime
+		var started bool
+		if err := rows.Scan(&t.UserID, &t.ToPlan, &graceUntil, &started); err != nil {
+			return nil, fmt.Errorf("apply storage limit scan: %w", err)
+		}
+		if !started {
+			continue
+		}
+		t.FromPlan = t.ToPlan
+		t.Action = PlanActionOverrideExpired
+		if graceUntil.Valid {
+			t.GraceUntil = &graceUntil.Time
+		}
+		transitions = append(transitions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("apply storage limit: %w", err)
+	}
+
+	// пороги уведомлений считаются от лимита, который только что сменился
+	for _, id := range userIDs {
+		if err := rearmUsageAlerts(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+	return transitions, nil
+}
+
+// ListAdminAudit возвращает записи журнала админского API от новых к старым.
+// Пустые targetType и targetID — без фильтра; beforeID — курсор страницы, как в ListUsageLedger.
+func (s *QuotaService) ListAdminAudit(ctx context.Context, targetType, targetID string, beforeID int64, limit int) ([]domain.AdminAuditEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id, admin_id, action, target_type, target_id, details, created_at
+        FROM admin_audit_log
+        WHERE ($1 = '' OR target_type = $1)
+          AND ($2 = '' OR target_id = $2)
+          AND ($3 = 0 OR id < $3)
+        ORDER BY id DESC
+        LIMIT $4
+    `, targetType, targetID, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list admin audit: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []domain.AdminAuditEntry{}
+	for rows.Next() {
+		var e domain.AdminAuditEntry
+		var details []byte
+		if err := rows.Scan(&e.ID, &e.AdminID, &e.Action, &e.TargetType, &e.TargetID, &details, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("list admin audit scan: %w", err)
+		}
+		e.Details = details
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// activeLimitOverride возвращает действующие персональные лимиты пользователя или nil
+func (s *QuotaService) activeLimitOverride(ctx context.Context, userID int) (*domain.LimitOverride, error) {
+	o, err := scanLimitOverride(s.db.QueryRowContext(ctx, `
+        SELECT `+limitOverrideColumns+` FROM user_limit_overrides WHERE user_id = $1 AND expires_at > NOW()
+    `, userID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get limit override: %w", err)
+	}
+	return &o, nil
+}
+
+// limitOverrideColumns — колонки user_limit_overrides в порядке scanLimitOverride
+const limitOverrideColumns = `user_id, storage_limit, max_file_size, max_files, egress_limit, request_limit, expires_at, reason, created_by, updated_at`
+
+func scanLimitOverride(row *sql.Row) (domain.LimitOverride, error) {
+	var o domain.LimitOverride
+	err := row.Scan(&o.UserID, &o.StorageLimit, &o.MaxFileSize, &o.MaxFiles, &o.EgressLimit, &o.RequestLimit,
+		&o.ExpiresAt, &o.Reason, &o.CreatedBy, &o.UpdatedAt)
+	return o, err
+}
+
+// validPlan — платный план должен иметь период (иначе его нечем продлевать), а free — оставаться бесплатным и бессрочным
+func validPlan(p domain.Plan) bool {
+	if p.StorageLimit <= 0 {
+		return false
+	}
+	if p.Name == freePlanName {
+		return p.PriceCents == 0 && p.PeriodDays == 0
+	}
+	return p.PriceCents == 0 || p.PeriodDays > 0
+}
+
+func setIf[T any](dst *T, v *T) {
+	if v != nil {
+		*dst = *v
+	}
+}
+
+// planByID возвращает план по id, в том числе выведенный из продажи
+func planByID(ctx context.Context, tx *sql.Tx, id int) (domain.Plan, error) {
+	var p domain.Plan
+	err := tx.QueryRowContext(ctx, `SELECT `+planColumns+` FROM plans p WHERE p.id = $1`, id).Scan(planScanDest(&p)...)
+	if err == sql.ErrNoRows {
+		return domain.Plan{}, ErrPlanNotFound
+	}
+	if err != nil {
+		return domain.Plan{}, fmt.Errorf("get plan: %w", err)
+	}
+	return p, nil
+}
+
+// planByNameAny — planByName для админского API: находит и выведенные из продажи планы, строка блокируется
+func planByNameAny(ctx context.Context, tx *sql.Tx, name string) (domain.Plan, error) {
+	var p domain.Plan
+	err := tx.QueryRowContext(ctx, `SELECT `+planColumns+` FROM plans p WHERE p.name = $1 FOR UPDATE`, name).Scan(planScanDest(&p)...)
+	if err == sql.ErrNoRows {
+		return domain.Plan{}, ErrPlanNotFound
+	}
+	if err != nil {
+		return domain.Plan{}, fmt.Errorf("get plan: %w", err)
+	}
+	return p, nil
+}
+
+// writeAudit пишет запись в admin_audit_log в транзакции изменения
+func writeAudit(ctx context.Context, tx *sql.Tx, adminID int, action, targetType, targetID string, details any) error {
+	body, err := json.Marshal(details)
+	if err != nil {
+		return fmt.Errorf("marshal audit details: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO admin_audit_log (admin_id, action, target_type, target_id, details)
+        VALUES ($1, $2, $3, $4, $5)
+    `, adminID, action, targetType, targetID, body); err != nil {
+		return fmt.Errorf("write audit: %w", err)
+	}
+	return nil
+}
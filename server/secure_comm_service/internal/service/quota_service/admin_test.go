@@ -0,0 +1,320 @@
+package quota_service_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+var (
+	overrideCols       = []string{"user_id", "storage_limit", "max_file_size", "max_files", "egress_limit", "request_limit", "expires_at", "reason", "created_by", "updated_at"}
+	applyStorageCols   = []string{"user_id", "name", "grace_until", "started"}
+	applyStorageLimitQ = `UPDATE user_plans up\s+SET read_only = up.read_only AND up.current_used > .*grace_until = CASE.*WHERE p.id = up.plan_id\s+AND up.user_id = ANY\(\$1\)`
+)
+
+func expectAudit(mock sqlmock.Sqlmock, adminID int, action, targetType, targetID string) {
+	mock.ExpectExec(`INSERT INTO admin_audit_log`).
+		WithArgs(adminID, action, targetType, targetID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+}
+
+func expectPlanByID(mock sqlmock.Sqlmock, p testPlan, retiredAt any) {
+	mock.ExpectQuery(`FROM plans p WHERE p.id = \$1`).
+		WithArgs(p.id).
+		WillReturnRows(sqlmock.NewRows(planCols).AddRow(p.id, p.name, p.priceCents, p.periodDays, retiredAt, p.storage, 0, 0, 0, 0))
+}
+
+func expectPlanByNameAny(mock sqlmock.Sqlmock, p testPlan, retiredAt any) {
+	mock.ExpectQuery(`FROM plans p WHERE p.name = \$1 FOR UPDATE`).
+		WithArgs(p.name).
+		WillReturnRows(sqlmock.NewRows(planCols).AddRow(p.id, p.name, p.priceCents, p.periodDays, retiredAt, p.storage, 0, 0, 0, 0))
+}
+
+func expectAlertsRearmed(mock sqlmock.Sqlmock, userIDs ...int) {
+	for _, id := range userIDs {
+		mock.ExpectExec(`DELETE FROM usage_alert_state`).
+			WithArgs(id).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+}
+
+func storageLimit(v int64) *int64 { return &v }
+
+func TestCreatePlan_WritesAudit(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO plans`).
+		WithArgs(proPlan.name, proPlan.storage, proPlan.priceCents, proPlan.periodDays, int64(0), int64(0), int64(0), int64(0)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(proPlan.id))
+	expectPlanByID(mock, proPlan, nil)
+	expectAudit(mock, 1, domain.AuditPlanCreate, domain.AuditTargetPlan, proPlan.name)
+	mock.ExpectCommit()
+
+	p, err := s.CreatePlan(context.Background(), 1, domain.Plan{
+		Name: proPlan.name, PriceCents: proPlan.priceCents, PeriodDays: proPlan.periodDays,
+		PlanLimits: domain.PlanLimits{StorageLimit: proPlan.storage},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.ID != proPlan.id {
+		t.Errorf("plan = %+v", p)
+	}
+	checkMock(t, mock)
+}
+
+func TestCreatePlan_DuplicateName(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO plans`).
+		WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+
+	_, err := s.CreatePlan(context.Background(), 1, domain.Plan{Name: "pro", PriceCents: 100, PeriodDays: 30, PlanLimits: domain.PlanLimits{StorageLimit: gib}})
+	if !errors.Is(err, quota_service.ErrPlanExists) {
+		t.Fatalf("err = %v, want ErrPlanExists", err)
+	}
+	checkMock(t, mock)
+}
+
+// Платный план без периода нечем продлевать
+func TestCreatePlan_PaidWithoutPeriod(t *testing.T) {
+	s, mock := newQuotaMock(t)
+
+	_, err := s.CreatePlan(context.Background(), 1, domain.Plan{Name: "pro", PriceCents: 100, PlanLimits: domain.PlanLimits{StorageLimit: gib}})
+	if !errors.Is(err, quota_service.ErrInvalidPlan) {
+		t.Fatalf("err = %v, want ErrInvalidPlan", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestUpdatePlan_FreeMustStayFree(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	expectPlanByNameAny(mock, freePlan, nil)
+	mock.ExpectRollback()
+
+	price := 100
+	_, err := s.UpdatePlan(context.Background(), 1, "free", domain.PlanUpdate{PriceCents: &price})
+	if !errors.Is(err, quota_service.ErrInvalidPlan) {
+		t.Fatalf("err = %v, want ErrInvalidPlan", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestUpdatePlan_KeepsUnsetFields(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	expectPlanByNameAny(mock, proPlan, nil)
+	mock.ExpectExec(`UPDATE plans\s+SET storage_limit = \$2`).
+		WithArgs(proPlan.id, 20*gib, proPlan.priceCents, proPlan.periodDays, int64(0), int64(0), int64(0), int64(0)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectAudit(mock, 1, domain.AuditPlanUpdate, domain.AuditTargetPlan, proPlan.name)
+	mock.ExpectCommit()
+
+	p, err := s.UpdatePlan(context.Background(), 1, "pro", domain.PlanUpdate{StorageLimit: storageLimit(20 * gib)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.StorageLimit != 20*gib || p.PriceCents != proPlan.priceCents {
+		t.Errorf("plan = %+v", p)
+	}
+	checkMock(t, mock)
+}
+
+func TestRetirePlan_FreeRejected(t *testing.T) {
+	s, mock := newQuotaMock(t)
+
+	if _, err := s.RetirePlan(context.Background(), 1, "free"); !errors.Is(err, quota_service.ErrFreePlanRetire) {
+		t.Fatalf("err = %v, want ErrFreePlanRetire", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestRetirePlan_AlreadyRetired(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	expectPlanByNameAny(mock, proPlan, time.Now().Add(-time.Hour))
+	mock.ExpectRollback()
+
+	if _, err := s.RetirePlan(context.Background(), 1, "pro"); !errors.Is(err, quota_service.ErrPlanRetired) {
+		t.Fatalf("err = %v, want ErrPlanRetired", err)
+	}
+	checkMock(t, mock)
+}
+
+// Ручной даунгрейд без оплаты: занято больше лимита free — аккаунт сразу read-only
+func TestAssignPlan_OverLimitBecomesReadOnly(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	expectLock(mock, 7, proPlan, 5*gib, false, nil)
+	expectPlanByName(mock, freePlan)
+	expectPlanByID(mock, proPlan, nil)
+	expectReplacePlan(mock, 7, freePlan.id, 5*gib, true, false)
+	expectAudit(mock, 1, domain.AuditUserAssignPlan, domain.AuditTargetUser, "7")
+	mock.ExpectCommit()
+	expectCurrentPlan(mock, 7, freePlan, 5*gib, true)
+
+	up, err := s.AssignPlan(context.Background(), 1, 7, "free", "abuse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !up.ReadOnly {
+		t.Errorf("plan = %+v, want read-only", up)
+	}
+	checkMock(t, mock)
+}
+
+func TestAssignPlan_SamePlan(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	expectLock(mock, 7, proPlan, 0, false, nil)
+	expectPlanByName(mock, proPlan)
+	mock.ExpectRollback()
+
+	if _, err := s.AssignPlan(context.Background(), 1, 7, "pro", "support"); !errors.Is(err, quota_service.ErrAlreadyOnPlan) {
+		t.Fatalf("err = %v, want ErrAlreadyOnPlan", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestSetLimitOverride_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		o    domain.LimitOverride
+	}{
+		{name: "expired", o: domain.LimitOverride{UserID: 7, StorageLimit: storageLimit(gib), ExpiresAt: time.Now().Add(-time.Hour)}},
+		{name: "no limits", o: domain.LimitOverride{UserID: 7, ExpiresAt: time.Now().Add(time.Hour)}},
+		{name: "zero storage", o: domain.LimitOverride{UserID: 7, StorageLimit: storageLimit(0), ExpiresAt: time.Now().Add(time.Hour)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, mock := newQuotaMock(t)
+			if _, err := s.SetLimitOverride(context.Background(), 1, tt.o); !errors.Is(err, quota_service.ErrInvalidOverride) {
+				t.Fatalf("err = %v, want ErrInvalidOverride", err)
+			}
+			checkMock(t, mock)
+		})
+	}
+}
+
+// Лимит ниже занятого: read-only сразу не включается, начинается grace-период
+func TestSetLimitOverride_BelowUsageStartsGrace(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	s.SetLapseHandling(time.Hour, nil)
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	mock.ExpectBegin()
+	expectLock(mock, 7, proPlan, 5*gib, false, nil)
+	mock.ExpectQuery(`FROM user_limit_overrides WHERE user_id = \$1 FOR UPDATE`).
+		WithArgs(7).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`INSERT INTO user_limit_overrides`).
+		WithArgs(7, storageLimit(gib), nil, nil, nil, nil, expiresAt, "trial", 1).
+		WillReturnRows(sqlmock.NewRows(overrideCols).AddRow(7, gib, nil, nil, nil, nil, expiresAt, "trial", 1, time.Now()))
+	mock.ExpectQuery(applyStorageLimitQ).
+		WithArgs(pq.Array([]int{7}), int64(3600)).
+		WillReturnRows(sqlmock.NewRows(applyStorageCols).AddRow(7, "pro", time.Now().Add(time.Hour), true))
+	expectAlertsRearmed(mock, 7)
+	expectAudit(mock, 1, domain.AuditUserSetOverride, domain.AuditTargetUser, "7")
+	mock.ExpectCommit()
+
+	o, err := s.SetLimitOverride(context.Background(), 1, domain.LimitOverride{UserID: 7, StorageLimit: storageLimit(gib), ExpiresAt: expiresAt, Reason: "trial"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.StorageLimit == nil || *o.StorageLimit != gib {
+		t.Errorf("override = %+v", o)
+	}
+	checkMock(t, mock)
+}
+
+func TestRemoveLimitOverride_NotFound(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM user_limit_overrides WHERE user_id = \$1 RETURNING`).
+		WithArgs(7).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	if err := s.RemoveLimitOverride(context.Background(), 1, 7); !errors.Is(err, quota_service.ErrOverrideNotFound) {
+		t.Fatalf("err = %v, want ErrOverrideNotFound", err)
+	}
+	checkMock(t, mock)
+}
+
+// Снятый лимит больше не покрывает занятое: лимит плана применяется в той же транзакции
+func TestRemoveLimitOverride_ReappliesPlanLimit(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM user_limit_overrides WHERE user_id = \$1 RETURNING`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(overrideCols).AddRow(7, 20*gib, nil, nil, nil, nil, time.Now().Add(time.Hour), "trial", 1, time.Now()))
+	mock.ExpectQuery(applyStorageLimitQ).
+		WithArgs(pq.Array([]int{7}), int64(0)).
+		WillReturnRows(sqlmock.NewRows(applyStorageCols).AddRow(7, "pro", time.Now(), true))
+	expectAlertsRearmed(mock, 7)
+	expectAudit(mock, 1, domain.AuditUserRemoveOverride, domain.AuditTargetUser, "7")
+	mock.ExpectCommit()
+
+	if err := s.RemoveLimitOverride(context.Background(), 1, 7); err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}
+
+// Истёкшие лимиты: превысившему лимит плана начинается grace, запланированные строки в переходы не попадают
+func TestProcessExpiredOverrides_StartsGrace(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	graceUntil := time.Now().Add(time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE user_limit_overrides\s+SET expiry_processed_at = NOW\(\).*expiry_processed_at IS NULL.*FOR UPDATE SKIP LOCKED`).
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(7).AddRow(8))
+	mock.ExpectQuery(applyStorageLimitQ).
+		WithArgs(pq.Array([]int{7, 8}), int64(3600)).
+		WillReturnRows(sqlmock.NewRows(applyStorageCols).
+			AddRow(7, "pro", graceUntil, true).
+			AddRow(8, "pro", nil, true).
+			AddRow(8, "free", nil, false))
+	expectAlertsRearmed(mock, 7, 8)
+	mock.ExpectCommit()
+
+	transitions, err := s.ProcessExpiredOverrides(context.Background(), time.Hour, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 2 {
+		t.Fatalf("transitions = %+v, want two", transitions)
+	}
+	if tr := transitions[0]; tr.UserID != 7 || tr.Action != quota_service.PlanActionOverrideExpired || tr.GraceUntil == nil {
+		t.Errorf("transition = %+v, want override_expired with grace for user 7", tr)
+	}
+	if tr := transitions[1]; tr.UserID != 8 || tr.GraceUntil != nil {
+		t.Errorf("transition = %+v, want user 8 within the plan limit", tr)
+	}
+	checkMock(t, mock)
+}
+
+func TestProcessExpiredOverrides_NothingExpired(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE user_limit_overrides`).
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+	mock.ExpectRollback()
+
+	transitions, err := s.ProcessExpiredOverrides(context.Background(), time.Hour, 100)
+	if err != nil || len(transitions) != 0 {
+		t.Fatalf("transitions = %+v, err = %v", transitions, err)
+	}
+	checkMock(t, mock)
+}
@@ -0,0 +1,238 @@
+package quota_service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/patrickmn/go-cache"
+)
+
+// Названия лимитов плана — совпадают с колонками plans и попадают в ответ клиенту
+const (
+	LimitStorage     = "storage_limit"
+	LimitMaxFileSize = "max_file_size"
+	LimitMaxFiles    = "max_files"
+	LimitEgress      = "egress_limit"
+	LimitRequests    = "request_limit"
+)
+
+var (
+	ErrFileTooLarge = errors.New("file exceeds the max file size of the plan")
+	ErrTooManyFiles = errors.New("file count limit of the plan exceeded")
+)
+
+// limitsCacheTTL — сколько живут лимиты в кеше. Смена плана в этом процессе сбрасывает кеш сразу,
+// TTL ограничивает устаревание, если план сменил другой экземпляр сервиса.
+const limitsCacheTTL = time.Minute
+
+// planLimitColumns — колонки лимитов плана (алиас p) в порядке planLimitsScanDest
+const planLimitColumns = `p.storage_limit, p.max_file_size, p.max_files, p.egress_limit, p.request_limit`
+
+// overrideExpr — персональный лимит column действующей строки user_plans (алиас up), назначенный администратором, или NULL.
+// У строк организаций user_id = NULL, поэтому для них персональных лимитов не бывает.
+func overrideExpr(column string) string {
+	return `(SELECT lo.` + column + ` FROM user_limit_overrides lo WHERE lo.user_id = up.user_id AND lo.expires_at > NOW())`
+}
+
+// limitExpr — значение лимита column для действующей подписки (алиасы up и p): персональный лимит, если он задан, иначе лимит плана
+func limitExpr(column string) string {
+	return `COALESCE(` + overrideExpr(column) + `, p.` + column + `)`
+}
+
+// effectiveLimitColumns — то же, что planLimitColumns, но с учётом персональных лимитов (алиасы up и p)
+var effectiveLimitColumns = limitExpr(LimitStorage) + `, ` + limitExpr(LimitMaxFileSize) + `, ` + limitExpr(LimitMaxFiles) + `, ` +
+	limitExpr(LimitEgress) + `, ` + limitExpr(LimitRequests)
+
+func planLimitsScanDest(l *domain.PlanLimits) []any {
+	return []any{&l.StorageLimit, &l.MaxFileSize, &l.MaxFiles, &l.EgressLimit, &l.RequestLimit}
+}
+
+// LimitExceededError — операция упёрлась в конкретный лимит плана.
+// Err — одна из sentinel-ошибок (ErrQuotaExceeded, ErrFileTooLarge, ...), поэтому errors.Is продолжает работать.
+type LimitExceededError struct {
+	Limit  string // название лимита, см. Limit*
+	Max    int64  // значение лимита в плане
+	Actual int64  // значение, которое получилось бы после операции
+	Err    error
+}
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("%s: %s is %d, requested %d", e.Err, e.Limit, e.Max, e.Actual)
+}
+
+func (e *LimitExceededError) Unwrap() error {
+	return e.Err
+}
+
+func limitExceeded(limit string, max, actual int64, err error) error {
+	return &LimitExceededError{Limit: limit, Max: max, Actual: actual, Err: err}
+}
+
+// GetLimits возвращает лимиты действующего плана пользователя с учётом персональных лимитов.
+// Результат кешируется на limitsCacheTTL.
+func (s *QuotaService) GetLimits(ctx context.Context, userID int) (domain.PlanLimits, error) {
+	return s.getLimits(ctx, userOwner(userID))
+}
+
+func (s *QuotaService) getLimits(ctx context.Context, owner quotaOwner) (domain.PlanLimits, error) {
+	key := owner.cacheKey()
+	if v, ok := s.limits.Get(key); ok {
+		return v.(domain.PlanLimits), nil
+	}
+
+	var l domain.PlanLimits
+	err := s.withLapsedPlan(ctx, owner, func() error {
+		err := s.db.QueryRowContext(ctx, `
+            SELECT `+effectiveLimitColumns+`
+            FROM user_plans up
+            JOIN plans p ON p.id = up.plan_id
+            WHERE up.`+owner.column+` = $1
+              AND `+activePlanCond, owner.id).Scan(planLimitsScanDest(&l)...)
+		if err == sql.ErrNoRows {
+			return ErrNoActivePlan
+		}
+		if err != nil {
+			return fmt.Errorf("get limits: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.PlanLimits{}, err
+	}
+
+	s.limits.Set(key, l, cache.DefaultExpiration)
+	return l, nil
+}
+
+// InvalidateLimits сбрасывает закешированные лимиты после смены плана пользователя
+func (s *QuotaService) InvalidateLimits(userID int) {
+	s.limits.Delete(userOwner(userID).cacheKey())
+}
+
+// CheckFileSize проверяет размер одного файла по max_file_size плана (0 — без лимита)
+func (s *QuotaService) CheckFileSize(ctx context.Context, userID int, size int64) error {
+	return s.checkFileSize(ctx, userOwner(userID), size)
+}
+
+func (s *QuotaService) checkFileSize(ctx context.Context, owner quotaOwner, size int64) error {
+	l, err := s.getLimits(ctx, owner)
+	if err != nil {
+		return err
+	}
+	if l.MaxFileSize > 0 && size > l.MaxFileSize {
+		return limitExceeded(LimitMaxFileSize, l.MaxFileSize, size, ErrFileTooLarge)
+	}
+	return nil
+}
+
+// CheckFileCount проверяет, что пользователь может создать ещё один файл (max_files, 0 — без лимита).
+// Это предварительная проверка до приёма тела, окончательно лимит проверяет TryAddUsage.
+func (s *QuotaService) CheckFileCount(ctx context.Context, userID int) error {
+	return s.checkFileCount(ctx, userOwner(userID))
+}
+
+func (s *QuotaService) checkFileCount(ctx context.Context, owner quotaOwner) error {
+	l, err := s.getLimits(ctx, owner)
+	if err != nil {
+		return err
+	}
+	if l.MaxFiles == 0 {
+		return nil
+	}
+
+	var files int64
+	if err := s.db.QueryRowContext(ctx, `
+        SELECT COALESCE((SELECT files FROM `+owner.fileCountsTable()+` WHERE `+owner.column+` = $1), 0)
+    `, owner.id).Scan(&files); err != nil {
+		return fmt.Errorf("check file count: %w", err)
+	}
+	if files+1 > l.MaxFiles {
+		return limitExceeded(LimitMaxFiles, l.MaxFiles, files+1, ErrTooManyFiles)
+	}
+	return nil
+}
+
+// fileDelta — на сколько операция меняет число файлов владельца
+func fileDelta(reason string) int64 {
+	switch reason {
+	case domain.UsageReasonUpload, domain.UsageReasonCopy:
+		return 1
+	case domain.UsageReasonDelete, domain.UsageReasonExpire, domain.UsageReasonRollback:
+		return -1
+	}
+	return 0
+}
+
+// adjustFileCount меняет user_file_counts (org_file_counts) в транзакции, которая пишет usage_ledger
+func adjustFileCount(ctx context.Context, tx *sql.Tx, owner quotaOwner, reason string) error {
+	delta := fileDelta(reason)
+	if delta == 0 {
+		return nil
+	}
+	table := owner.fileCountsTable()
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO `+table+` (`+owner.column+`, files)
+        VALUES ($1, GREATEST($2, 0))
+        ON CONFLICT (`+owner.column+`) DO UPDATE
+        SET files = GREATEST(`+table+`.files + $2, 0)
+    `, owner.id, delta); err != nil {
+		return fmt.Errorf("adjust file count: %w", err)
+	}
+	return nil
+}
+
+// ListFileCounts возвращает учтённое число файлов всех пользователей (user_id -> files)
+func (s *QuotaService) ListFileCounts(ctx context.Context) (map[int]int64, error) {
+	return s.listFileCounts(ctx, userOwner(0))
+}
+
+// listFileCounts возвращает числа файлов всех владельцев того же вида, что kind
+func (s *QuotaService) listFileCounts(ctx context.Context, kind quotaOwner) (map[int]int64, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+kind.column+`, files FROM `+kind.fileCountsTable())
+	if err != nil {
+		return nil, fmt.Errorf("list file counts: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[int]int64)
+	for rows.Next() {
+		var ownerID int
+		var files int64
+		if err := rows.Scan(&ownerID, &files); err != nil {
+			return nil, fmt.Errorf("list file counts scan: %w", err)
+		}
+		counts[ownerID] = files
+	}
+	return counts, rows.Err()
+}
+
+// SetFileCount перезаписывает число файлов, только если оно всё ещё равно expected
+// и после sinceLedgerID в журнале не было записей пользователя (как SetUsage).
+// Возвращает false, если значение успело измениться.
+func (s *QuotaService) SetFileCount(ctx context.Context, userID int, expected, actual, sinceLedgerID int64) (bool, error) {
+	return s.setFileCount(ctx, userOwner(userID), expected, actual, sinceLedgerID)
+}
+
+func (s *QuotaService) setFileCount(ctx context.Context, owner quotaOwner, expected, actual, sinceLedgerID int64) (bool, error) {
+	table := owner.fileCountsTable()
+	res, err := s.db.ExecContext(ctx, `
+        INSERT INTO `+table+` (`+owner.column+`, files)
+        SELECT $1::int, $3::bigint
+        WHERE NOT EXISTS (SELECT 1 FROM usage_ledger l WHERE `+owner.ledgerFilter("$1")+` AND l.id > $4)
+        ON CONFLICT (`+owner.column+`) DO UPDATE
+        SET files = EXCLUDED.files
+        WHERE `+table+`.files = $2
+    `, owner.id, expected, actual, sinceLedgerID)
+	if err != nil {
+		return false, fmt.Errorf("set file count: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("set file count rows: %w", err)
+	}
+	return rows > 0, nil
+}
@@ -0,0 +1,70 @@
+package quota_service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var limitCols = []string{"storage_limit", "max_file_size", "max_files", "egress_limit", "request_limit"}
+
+func expectLimits(mock sqlmock.Sqlmock, userID int, l domain.PlanLimits) {
+	mock.ExpectQuery(`SELECT COALESCE\(\(SELECT lo.storage_limit FROM user_limit_overrides lo.*FROM user_plans up\s+JOIN plans p ON p.id = up.plan_id\s+WHERE up.user_id = \$1`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows(limitCols).AddRow(l.StorageLimit, l.MaxFileSize, l.MaxFiles, l.EgressLimit, l.RequestLimit))
+}
+
+// Лимиты читаются одним запросом и кешируются до InvalidateLimits
+func TestGetLimits_CachedUntilInvalidated(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	want := domain.PlanLimits{StorageLimit: gib, MaxFileSize: 100, MaxFiles: 10, EgressLimit: 2 * gib, RequestLimit: 1000}
+	expectLimits(mock, 7, want)
+
+	for i := 0; i < 2; i++ {
+		got, err := s.GetLimits(context.Background(), 7)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("limits = %+v, want %+v", got, want)
+		}
+	}
+	checkMock(t, mock)
+
+	s.InvalidateLimits(7)
+	expectLimits(mock, 7, want)
+	if _, err := s.GetLimits(context.Background(), 7); err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}
+
+func TestCheckFileSize_NamesTheLimit(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectLimits(mock, 7, domain.PlanLimits{StorageLimit: gib, MaxFileSize: 100})
+
+	err := s.CheckFileSize(context.Background(), 7, 101)
+	var limitErr *quota_service.LimitExceededError
+	if !errors.As(err, &limitErr) || !errors.Is(err, quota_service.ErrFileTooLarge) {
+		t.Fatalf("err = %v, want LimitExceededError wrapping ErrFileTooLarge", err)
+	}
+	if limitErr.Limit != quota_service.LimitMaxFileSize || limitErr.Max != 100 || limitErr.Actual != 101 {
+		t.Errorf("limit error = %+v", limitErr)
+	}
+	checkMock(t, mock)
+}
+
+// max_file_size = 0 — без лимита
+func TestCheckFileSize_Unlimited(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectLimits(mock, 7, domain.PlanLimits{StorageLimit: gib})
+
+	if err := s.CheckFileSize(context.Background(), 7, 50*gib); err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}
@@ -0,0 +1,163 @@
+package quota_service
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+)
+
+// Пул организации хранится в тех же user_plans (колонка org_id) и работает по тем же правилам, что личный:
+// лимиты плана, read-only, grace-период, оплата. Проверку роли участника делает вызывающий (org_service, хендлеры).
+
+// InitializeOrgPlan создаёт free-план только что созданной организации в транзакции org_service.CreateOrg
+func (s *QuotaService) InitializeOrgPlan(ctx context.Context, tx *sql.Tx, orgID int) error {
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO user_plans (org_id, plan_id, started_at, expires_at, current_used)
+        SELECT $1, p.id, NOW(), `+planExpiresAtExpr+`, 0
+        FROM plans p
+        WHERE p.name = 'free'
+    `, orgID); err != nil {
+		return fmt.Errorf("init org free plan: %w", err)
+	}
+	return nil
+}
+
+// CheckOrgQuota — CheckQuota для пула организации
+func (s *QuotaService) CheckOrgQuota(ctx context.Context, orgID int, newSize int64) error {
+	owner := orgOwner(orgID)
+	return s.withLapsedPlan(ctx, owner, func() error { return s.checkQuota(ctx, owner, newSize) })
+}
+
+// TryAddOrgUsage — TryAddUsage для пула организации. userID — участник, загрузивший файл: по нему считается вклад участника.
+func (s *QuotaService) TryAddOrgUsage(ctx context.Context, orgID, userID int, newSize int64, ref domain.UsageRef) error {
+	owner := orgOwner(orgID)
+	return s.withLapsedPlan(ctx, owner, func() error { return s.tryAddUsage(ctx, owner, userID, newSize, ref) })
+}
+
+// RemoveOrgUsage освобождает место в пуле организации. userID — участник, который загрузил файл,
+// чтобы его вклад в OrgMemberUsage уменьшился, даже если файл удалил администратор.
+func (s *QuotaService) RemoveOrgUsage(ctx context.Context, orgID, userID int, newSize int64, ref domain.UsageRef) error {
+	owner := orgOwner(orgID)
+	return s.withLapsedPlan(ctx, owner, func() error { return s.removeUsage(ctx, owner, userID, newSize, ref) })
+}
+
+func (s *QuotaService) GetOrgLimits(ctx context.Context, orgID int) (domain.PlanLimits, error) {
+	return s.getLimits(ctx, orgOwner(orgID))
+}
+
+func (s *QuotaService) InvalidateOrgLimits(orgID int) {
+	s.limits.Delete(orgOwner(orgID).cacheKey())
+}
+
+func (s *QuotaService) CheckOrgFileCount(ctx context.Context, orgID int) error {
+	return s.checkFileCount(ctx, orgOwner(orgID))
+}
+
+func (s *QuotaService) GetOrgPlan(ctx context.Context, orgID int) (domain.UserPlan, error) {
+	return s.getCurrentPlan(ctx, orgOwner(orgID))
+}
+
+func (s *QuotaService) SubscribeOrg(ctx context.Context, orgID int, planName string, autoRenew bool) (domain.UserPlan, error) {
+	return s.switchPlan(ctx, orgOwner(orgID), planName, false, false, autoRenew)
+}
+
+func (s *QuotaService) DowngradeOrg(ctx context.Context, orgID int, planName string, readOnlyIfOverLimit, autoRenew bool) (domain.UserPlan, error) {
+	return s.switchPlan(ctx, orgOwner(orgID), planName, true, readOnlyIfOverLimit, autoRenew)
+}
+
+func (s *QuotaService) CancelOrgAtPeriodEnd(ctx context.Context, orgID int) (domain.UserPlan, error) {
+	return s.cancelAtPeriodEnd(ctx, orgOwner(orgID))
+}
+
+// ApplyOrgPaidPeriod — ApplyPaidPeriod для плана организации
+func (s *QuotaService) ApplyOrgPaidPeriod(ctx context.Context, tx *sql.Tx, orgID, planID int, autoRenew bool) error {
+	return applyPaidPeriod(ctx, tx, orgOwner(orgID), planID, autoRenew)
+}
+
+// ListActiveOrgUsage возвращает current_used действующих планов всех организаций (org_id -> current_used)
+func (s *QuotaService) ListActiveOrgUsage(ctx context.Context) (map[int]int64, error) {
+	return s.listActiveUsage(ctx, "org_id")
+}
+
+// SetOrgUsage — SetUsage для пула организации
+func (s *QuotaService) SetOrgUsage(ctx context.Context, orgID int, expected, actual, sinceLedgerID int64) (bool, error) {
+	return s.setUsage(ctx, orgOwner(orgID), expected, actual, sinceLedgerID)
+}
+
+// ListOrgFileCounts возвращает учтённое число файлов всех организаций (org_id -> files)
+func (s *QuotaService) ListOrgFileCounts(ctx context.Context) (map[int]int64, error) {
+	return s.listFileCounts(ctx, orgOwner(0))
+}
+
+func (s *QuotaService) SetOrgFileCount(ctx context.Context, orgID int, expected, actual, sinceLedgerID int64) (bool, error) {
+	return s.setFileCount(ctx, orgOwner(orgID), expected, actual, sinceLedgerID)
+}
+
+// GetOrgUsage возвращает занятое место пула организации. withMembers — добавить вклад каждого участника.
+func (s *QuotaService) GetOrgUsage(ctx context.Context, orgID int, withMembers bool) (domain.OrgUsage, error) {
+	var usage domain.OrgUsage
+	err := s.withLapsedPlan(ctx, orgOwner(orgID), func() error {
+		return s.orgPoolUsage(ctx, orgID, &usage)
+	})
+	if err != nil {
+		return domain.OrgUsage{}, err
+	}
+
+	if withMembers {
+		usage.Members, err = s.OrgMemberUsage(ctx, orgID)
+		if err != nil {
+			return domain.OrgUsage{}, err
+		}
+	}
+	return usage, nil
+}
+
+func (s *QuotaService) orgPoolUsage(ctx context.Context, orgID int, usage *domain.OrgUsage) error {
+	err := s.db.QueryRowContext(ctx, `
+        SELECT up.current_used, p.storage_limit, p.name,
+               COALESCE((SELECT files FROM org_file_counts WHERE org_id = up.org_id), 0)
+        FROM user_plans up
+        JOIN plans p ON p.id = up.plan_id
+        WHERE up.org_id = $1
+          AND `+activePlanCond, orgID).Scan(&usage.CurrentUsed, &usage.StorageLimit, &usage.PlanName, &usage.Files)
+	if err == sql.ErrNoRows {
+		return ErrNoActivePlan
+	}
+	if err != nil {
+		return fmt.Errorf("get org usage: %w", err)
+	}
+	return nil
+}
+
+// OrgMemberUsage считает по журналу, сколько места и файлов в пуле организации приходится на каждого участника.
+// Участники, чей вклад обнулился, в ответ не попадают.
+func (s *QuotaService) OrgMemberUsage(ctx context.Context, orgID int) ([]domain.OrgMemberUsage, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT COALESCE(user_id, 0),
+               SUM(delta),
+               COUNT(*) FILTER (WHERE reason IN ($2, $3))
+                 - COUNT(*) FILTER (WHERE reason IN ($4, $5, $6))
+        FROM usage_ledger
+        WHERE org_id = $1
+        GROUP BY user_id
+        HAVING SUM(delta) <> 0
+        ORDER BY SUM(delta) DESC
+    `, orgID, domain.UsageReasonUpload, domain.UsageReasonCopy,
+		domain.UsageReasonDelete, domain.UsageReasonExpire, domain.UsageReasonRollback)
+	if err != nil {
+		return nil, fmt.Errorf("org member usage: %w", err)
+	}
+	defer rows.Close()
+
+	members := []domain.OrgMemberUsage{}
+	for rows.Next() {
+		var m domain.OrgMemberUsage
+		if err := rows.Scan(&m.UserID, &m.Bytes, &m.Files); err != nil {
+			return nil, fmt.Errorf("org member usage scan: %w", err)
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
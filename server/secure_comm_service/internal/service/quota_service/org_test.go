@@ -0,0 +1,104 @@
+package quota_service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var orgLockCols = []string{"current_used", "storage_limit", "read_only", "files", "max_files"}
+
+func expectOrgLock(mock sqlmock.Sqlmock, orgID int, used, limit int64) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`LEFT JOIN org_file_counts fc ON fc.org_id = up.org_id\s+WHERE up.org_id = \$1.*FOR UPDATE OF up`).
+		WithArgs(orgID).
+		WillReturnRows(sqlmock.NewRows(orgLockCols).AddRow(used, limit, false, 1, 0))
+}
+
+// Загрузка в пул: место списывается с организации, в журнал пишется и пул, и участник
+func TestTryAddOrgUsage_LedgerKeepsMember(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectOrgLock(mock, 3, 100, gib)
+	mock.ExpectExec(`UPDATE user_plans up\s+SET current_used = up.current_used \+ \$1\s+WHERE up.org_id = \$2`).
+		WithArgs(int64(50), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO usage_ledger`).
+		WithArgs(7, 3, "org-3/a", "photo", int64(50), domain.UsageReasonUpload).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerSync(mock, "org_id", 3, 0)
+	mock.ExpectExec(`INSERT INTO org_file_counts \(org_id, files\)`).
+		WithArgs(3, int64(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// пороги уведомлений личные: у пула они не проверяются
+	mock.ExpectCommit()
+
+	err := s.TryAddOrgUsage(context.Background(), 3, 7, 50, domain.UsageRef{ObjID: "org-3/a", Category: "photo", Reason: domain.UsageReasonUpload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}
+
+func TestTryAddOrgUsage_PoolLimit(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectOrgLock(mock, 3, gib-10, gib)
+	mock.ExpectRollback()
+
+	err := s.TryAddOrgUsage(context.Background(), 3, 7, 50, domain.UsageRef{ObjID: "org-3/a", Category: "photo", Reason: domain.UsageReasonUpload})
+	if !errors.Is(err, quota_service.ErrQuotaExceeded) {
+		t.Fatalf("err = %v, want ErrQuotaExceeded", err)
+	}
+	checkMock(t, mock)
+}
+
+// Администратор удаляет чужой файл: место освобождается в пуле, вклад уменьшается у автора
+func TestRemoveOrgUsage_ChargesUploader(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT up.current_used, up.started_at <= NOW\(\)\s+FROM user_plans up\s+WHERE up.org_id = \$1`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows(removeLockCols).AddRow(500, true))
+	mock.ExpectExec(`UPDATE user_plans up\s+SET current_used = GREATEST\(up.current_used - \$1, 0\).*AND up.org_id = \$2`).
+		WithArgs(int64(100), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO usage_ledger`).
+		WithArgs(9, 3, "org-3/a", "photo", int64(-100), domain.UsageReasonDelete).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerSync(mock, "org_id", 3, 0)
+	mock.ExpectExec(`INSERT INTO org_file_counts`).
+		WithArgs(3, int64(-1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := s.RemoveOrgUsage(context.Background(), 3, 9, 100, domain.UsageRef{ObjID: "org-3/a", Category: "photo", Reason: domain.UsageReasonDelete})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}
+
+func TestGetOrgUsage_WithMembers(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectQuery(`SELECT up.current_used, p.storage_limit, p.name,.*FROM org_file_counts`).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"current_used", "storage_limit", "name", "files"}).AddRow(300, gib, "team", 3))
+	mock.ExpectQuery(`FROM usage_ledger\s+WHERE org_id = \$1\s+GROUP BY user_id`).
+		WithArgs(3, domain.UsageReasonUpload, domain.UsageReasonCopy, domain.UsageReasonDelete, domain.UsageReasonExpire, domain.UsageReasonRollback).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "bytes", "files"}).AddRow(7, 200, 2).AddRow(0, 100, 1))
+
+	usage, err := s.GetOrgUsage(context.Background(), 3, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if usage.CurrentUsed != 300 || usage.PlanName != "team" || usage.Files != 3 {
+		t.Errorf("usage = %+v", usage)
+	}
+	if len(usage.Members) != 2 || usage.Members[0].UserID != 7 || usage.Members[1].UserID != 0 {
+		t.Errorf("members = %+v, want user 7 and reconciler corrections", usage.Members)
+	}
+	checkMock(t, mock)
+}
@@ -0,0 +1,58 @@
+package quota_service
+
+import "strconv"
+
+// quotaOwner — владелец строки user_plans: пользователь или организация.
+// Ровно одна из колонок user_id/org_id задана, поэтому запросы подставляют column в WHERE.
+type quotaOwner struct {
+	column string // user_id | org_id
+	id     int
+}
+
+func userOwner(userID int) quotaOwner {
+	return quotaOwner{column: "user_id", id: userID}
+}
+
+func orgOwner(orgID int) quotaOwner {
+	return quotaOwner{column: "org_id", id: orgID}
+}
+
+func (o quotaOwner) isOrg() bool {
+	return o.column == "org_id"
+}
+
+// cacheKey — ключ кеша лимитов; ID пользователя и организации могут совпадать
+func (o quotaOwner) cacheKey() string {
+	if o.isOrg() {
+		return "o:" + strconv.Itoa(o.id)
+	}
+	return "u:" + strconv.Itoa(o.id)
+}
+
+// fileCountsTable — таблица числа файлов владельца, ключевая колонка совпадает с column
+func (o quotaOwner) fileCountsTable() string {
+	if o.isOrg() {
+		return "org_file_counts"
+	}
+	return "user_file_counts"
+}
+
+// ledgerIDs — значения user_id и org_id записи usage_ledger.
+// В пространстве организации user_id — участник, выполнивший операцию (0 — неизвестен, например корректировка реконсилера).
+func (o quotaOwner) ledgerIDs(actorID int) (userID, orgID any) {
+	if !o.isOrg() {
+		return o.id, nil
+	}
+	if actorID == 0 {
+		return nil, o.id
+	}
+	return actorID, o.id
+}
+
+// ledgerFilter — условие на записи usage_ledger владельца (алиас таблицы l, ID владельца — параметр idParam)
+func (o quotaOwner) ledgerFilter(idParam string) string {
+	if o.isOrg() {
+		return "l.org_id = " + idParam
+	}
+	return "l.user_id = " + idParam + " AND l.org_id IS NULL"
+}
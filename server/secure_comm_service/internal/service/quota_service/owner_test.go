@@ -0,0 +1,49 @@
+package quota_service
+
+import "testing"
+
+// ID пользователя и организации могут совпадать: ключи кеша и таблицы не должны пересекаться
+func TestQuotaOwner_UserAndOrgDoNotCollide(t *testing.T) {
+	user, org := userOwner(3), orgOwner(3)
+	if user.cacheKey() == org.cacheKey() {
+		t.Errorf("cache keys collide: %q", user.cacheKey())
+	}
+	if user.fileCountsTable() != "user_file_counts" || org.fileCountsTable() != "org_file_counts" {
+		t.Errorf("file count tables = %q, %q", user.fileCountsTable(), org.fileCountsTable())
+	}
+	if user.isOrg() || !org.isOrg() {
+		t.Error("isOrg mismatch")
+	}
+}
+
+func TestQuotaOwner_LedgerIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		owner      quotaOwner
+		actorID    int
+		wantUserID any
+		wantOrgID  any
+	}{
+		{name: "user", owner: userOwner(7), actorID: 7, wantUserID: 7, wantOrgID: nil},
+		{name: "org member", owner: orgOwner(3), actorID: 7, wantUserID: 7, wantOrgID: 3},
+		{name: "org reconciler", owner: orgOwner(3), actorID: 0, wantUserID: nil, wantOrgID: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID, orgID := tt.owner.ledgerIDs(tt.actorID)
+			if userID != tt.wantUserID || orgID != tt.wantOrgID {
+				t.Errorf("ledgerIDs(%d) = %v, %v, want %v, %v", tt.actorID, userID, orgID, tt.wantUserID, tt.wantOrgID)
+			}
+		})
+	}
+}
+
+// Записи участника в пуле организации не входят в его личный журнал
+func TestQuotaOwner_LedgerFilter(t *testing.T) {
+	if got := userOwner(7).ledgerFilter("$1"); got != "l.user_id = $1 AND l.org_id IS NULL" {
+		t.Errorf("user filter = %q", got)
+	}
+	if got := orgOwner(3).ledgerFilter("$1"); got != "l.org_id = $1" {
+		t.Errorf("org filter = %q", got)
+	}
+}
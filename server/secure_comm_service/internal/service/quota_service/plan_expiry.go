@@ -0,0 +1,341 @@
+package quota_service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+)
+
+const (
+	PlanActionRenewed      = "renewed"
+	PlanActionFallbackFree = "fallback_free"
+	PlanActionScheduled    = "scheduled" // после отмены заранее создан следующий план
+	// PlanActionRenewalDue — платный план с auto_renew закончился: аккаунт на free, пока продление не оплачено
+	PlanActionRenewalDue = "renewal_due"
+	// PlanActionOverrideExpired — план не менялся, но истекли персональные лимиты: снова действуют лимиты плана
+	PlanActionOverrideExpired = "override_expired"
+)
+
+// lapseHandling — обработка подписок, закончившихся между запусками PlanExpiryProcessor, см. SetLapseHandling
+type lapseHandling struct {
+	grace  time.Duration
+	notify func(context.Context, domain.PlanTransition)
+}
+
+// SetLapseHandling задаёт grace-период и обработчик переходов для подписок, которые закончились, но ещё не обработаны
+// PlanExpiryProcessor (или он выключен). Такие подписки обрабатываются при первом обращении к квоте владельца.
+func (s *QuotaService) SetLapseHandling(grace time.Duration, notify func(context.Context, domain.PlanTransition)) {
+	s.lapse = lapseHandling{grace: grace, notify: notify}
+}
+
+// ClaimExpiryWarnings помечает личные платные подписки, заканчивающиеся в ближайшие within, как предупреждённые
+// и возвращает их. Помечает атомарно, поэтому предупреждение уходит один раз даже при нескольких экземплярах сервиса.
+func (s *QuotaService) ClaimExpiryWarnings(ctx context.Context, within time.Duration) ([]domain.ExpiringPlan, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        UPDATE user_plans up
+        SET expiry_warned_at = NOW()
+        FROM plans p
+        WHERE p.id = up.plan_id
+          AND p.price_cents > 0
+          AND up.user_id IS NOT NULL
+          AND up.expiry_warned_at IS NULL
+          AND up.expires_at <= NOW() + $1 * INTERVAL '1 second'
+          AND `+activePlanCond+`
+        RETURNING up.user_id, p.name, up.expires_at, up.auto_renew AND p.retired_at IS NULL, up.cancel_at_period_end
+    `, int64(within/time.Second))
+	if err != nil {
+		return nil, fmt.Errorf("claim expiry warnings: %w", err)
+	}
+	defer rows.Close()
+
+	var plans []domain.ExpiringPlan
+	for rows.Next() {
+		var p domain.ExpiringPlan
+		if err := rows.Scan(&p.UserID, &p.PlanName, &p.ExpiresAt, &p.AutoRenew, &p.CancelAtPeriodEnd); err != nil {
+			return nil, fmt.Errorf("claim expiry warnings scan: %w", err)
+		}
+		plans = append(plans, p)
+	}
+	return plans, rows.Err()
+}
+
+// ProcessLapsedPlans обрабатывает до limit подписок, период которых закончился:
+// продлевает бесплатные периодические планы (auto_renew), переводит на free с переносом current_used, либо просто закрывает,
+// если следующий план уже запланирован отменой. Платный план без оплаты не продлевается: аккаунт переходит на free
+// (PlanActionRenewalDue), а оплаченное продление возвращает его на план через ApplyPaidPeriod. Если после перехода занято больше лимита — выставляется grace_until.
+func (s *QuotaService) ProcessLapsedPlans(ctx context.Context, grace time.Duration, limit int) ([]domain.PlanTransition, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id FROM user_plans
+        WHERE status = 'active'
+          AND expires_at <= NOW()
+        ORDER BY expires_at
+        LIMIT $1
+    `, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list lapsed plans: %w", err)
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("list lapsed plans scan: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list lapsed plans: %w", err)
+	}
+
+	var transitions []domain.PlanTransition
+	for _, id := range ids {
+		t, ok, err := s.processLapsedPlan(ctx, id, grace, true)
+		if err != nil {
+			return transitions, err
+		}
+		if ok {
+			if t.OrgID != 0 {
+				s.limits.Delete(orgOwner(t.OrgID).cacheKey())
+			} else {
+				s.InvalidateLimits(t.UserID)
+			}
+			transitions = append(transitions, t)
+		}
+	}
+	return transitions, nil
+}
+
+// ResolveLapsedPlan обрабатывает закончившуюся, но ещё не обработанную подписку пользователя.
+// Нужен перед операциями в транзакции вызывающего (ApplyPaidPeriod), которые сами её обработать не могут.
+func (s *QuotaService) ResolveLapsedPlan(ctx context.Context, userID int) error {
+	_, err := s.resolveLapsed(ctx, userOwner(userID))
+	return err
+}
+
+// ResolveLapsedOrgPlan — ResolveLapsedPlan для подписки организации
+func (s *QuotaService) ResolveLapsedOrgPlan(ctx context.Context, orgID int) error {
+	_, err := s.resolveLapsed(ctx, orgOwner(orgID))
+	return err
+}
+
+// resolveLapsed обрабатывает закончившиеся подписки владельца так же, как ProcessLapsedPlans.
+// Возвращает true, если такие были: запрос, не нашедший действующего плана, можно повторить.
+func (s *QuotaService) resolveLapsed(ctx context.Context, owner quotaOwner) (bool, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT up.id FROM user_plans up
+        WHERE up.`+owner.column+` = $1
+          AND up.status = 'active'
+          AND up.expires_at <= NOW()
+        ORDER BY up.expires_at
+    `, owner.id)
+	if err != nil {
+		return false, fmt.Errorf("list lapsed plans: %w", err)
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("list lapsed plans scan: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("list lapsed plans: %w", err)
+	}
+
+	for _, id := range ids {
+		// ждём, а не пропускаем строку, которую прямо сейчас обрабатывает PlanExpiryProcessor:
+		// после его коммита повторный запрос найдёт новый план
+		t, ok, err := s.processLapsedPlan(ctx, id, s.lapse.grace, false)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			continue
+		}
+		s.limits.Delete(owner.cacheKey())
+		if s.lapse.notify != nil {
+			s.lapse.notify(ctx, t)
+		}
+	}
+	return len(ids) > 0, nil
+}
+
+// withLapsedPlan выполняет fn, а если действующего плана нет из-за закончившейся подписки —
+// обрабатывает её (продление или переход на free) и выполняет fn ещё раз.
+// Так квота не зависит от того, успел ли PlanExpiryProcessor обработать подписку и включён ли он вообще.
+func (s *QuotaService) withLapsedPlan(ctx context.Context, owner quotaOwner, fn func() error) error {
+	err := fn()
+	if !errors.Is(err, ErrNoActivePlan) {
+		return err
+	}
+	lapsed, resolveErr := s.resolveLapsed(ctx, owner)
+	if resolveErr != nil {
+		return resolveErr
+	}
+	if !lapsed {
+		return err
+	}
+	return fn()
+}
+
+// processLapsedPlan обрабатывает одну закончившуюся подписку. skipLocked — пропустить строку, заблокированную
+// другим экземпляром (пакетная обработка), иначе дождаться его коммита.
+func (s *QuotaService) processLapsedPlan(ctx context.Context, id int, grace time.Duration, skipLocked bool) (domain.PlanTransition, bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.PlanTransition{}, false, fmt.Errorf("process lapsed plan: %w", err)
+	}
+	defer tx.Rollback()
+
+	var t domain.PlanTransition
+	var userID, orgID sql.NullInt64
+	var planID, periodDays, priceCents int
+	var currentUsed int64
+	var autoRenew, canceled, retired bool
+	var expiresAt time.Time
+	lock := "FOR UPDATE OF up"
+	if skipLocked {
+		lock += " SKIP LOCKED"
+	}
+	err = tx.QueryRowContext(ctx, `
+        SELECT up.user_id, up.org_id, p.id, p.name, p.period_days, p.price_cents, up.current_used, up.auto_renew, up.cancel_at_period_end, up.expires_at,
+               p.retired_at IS NOT NULL
+        FROM user_plans up
+        JOIN plans p ON p.id = up.plan_id
+        WHERE up.id = $1
+          AND up.status = 'active'
+          AND up.expires_at <= NOW()
+        `+lock, id).Scan(&userID, &orgID, &planID, &t.FromPlan, &periodDays, &priceCents, &currentUsed, &autoRenew, &canceled, &expiresAt, &retired)
+	if err == sql.ErrNoRows {
+		// уже обработана другим экземпляром (или заблокирована им при skipLocked)
+		return domain.PlanTransition{}, false, nil
+	}
+	if err != nil {
+		return domain.PlanTransition{}, false, fmt.Errorf("process lapsed plan: %w", err)
+	}
+	owner := userOwner(int(userID.Int64))
+	if orgID.Valid {
+		owner = orgOwner(int(orgID.Int64))
+		t.OrgID = owner.id
+	} else {
+		t.UserID = owner.id
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE user_plans SET status = 'expired' WHERE id = $1`, id); err != nil {
+		return domain.PlanTransition{}, false, fmt.Errorf("expire plan: %w", err)
+	}
+
+	// следующий план уже есть (создан отменой) — current_used в нём вёлся параллельно
+	var nextID int
+	err = tx.QueryRowContext(ctx, `
+        SELECT up.id, p.name
+        FROM user_plans up
+        JOIN plans p ON p.id = up.plan_id
+        WHERE up.`+owner.column+` = $1
+          AND `+livePlanCond+`
+        ORDER BY up.started_at
+        LIMIT 1
+        FOR UPDATE OF up
+    `, owner.id).Scan(&nextID, &t.ToPlan)
+	switch {
+	case err == nil:
+		t.Action = PlanActionScheduled
+	case err != sql.ErrNoRows:
+		return domain.PlanTransition{}, false, fmt.Errorf("find next plan: %w", err)
+	case autoRenew && !canceled && periodDays > 0 && !retired && priceCents == 0:
+		// бесплатный периодический план продлевается сразу: новый период начинается ровно там, где закончился старый.
+		// Выведенный из продажи план не продлевается — подписчик переходит на free
+		err = tx.QueryRowContext(ctx, `
+            INSERT INTO user_plans (`+owner.column+`, plan_id, started_at, expires_at, current_used, auto_renew)
+            VALUES ($1, $2, $3, $3 + ($4 || ' days')::interval, $5, TRUE)
+            RETURNING id
+        `, owner.id, planID, expiresAt, periodDays, currentUsed).Scan(&nextID)
+		if err != nil {
+			return domain.PlanTransition{}, false, fmt.Errorf("renew plan: %w", err)
+		}
+		t.ToPlan = t.FromPlan
+		t.Action = PlanActionRenewed
+	default:
+		err = tx.QueryRowContext(ctx, `
+            INSERT INTO user_plans (`+owner.column+`, plan_id, started_at, expires_at, current_used)
+            SELECT $1, p.id, NOW(), 'infinity'::timestamptz, $2
+            FROM plans p
+            WHERE p.name = 'free'
+            RETURNING id
+        `, owner.id, currentUsed).Scan(&nextID)
+		if err != nil {
+			return domain.PlanTransition{}, false, fmt.Errorf("fallback to free: %w", err)
+		}
+		t.ToPlan = "free"
+		t.Action = PlanActionFallbackFree
+		if autoRenew && !canceled && periodDays > 0 && !retired {
+			// платное продление только через оплату: до вебхука об оплате аккаунт на free,
+			// оплаченный период переключит его обратно (ApplyPaidPeriod)
+			t.Action = PlanActionRenewalDue
+		}
+	}
+
+	// занято больше лимита нового плана — даём время удалить лишнее
+	var graceUntil sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+        UPDATE user_plans up
+        SET grace_until = COALESCE(up.grace_until, NOW() + $2 * INTERVAL '1 second')
+        FROM plans p
+        WHERE p.id = up.plan_id
+          AND up.id = $1
+          AND up.current_used > `+limitExpr(LimitStorage)+`
+        RETURNING up.grace_until
+    `, nextID, int64(grace/time.Second)).Scan(&graceUntil)
+	if err != nil && err != sql.ErrNoRows {
+		return domain.PlanTransition{}, false, fmt.Errorf("set grace period: %w", err)
+	}
+	if graceUntil.Valid {
+		t.GraceUntil = &graceUntil.Time
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain.PlanTransition{}, false, fmt.Errorf("process lapsed plan: %w", err)
+	}
+	return t, true, nil
+}
+
+// EnforceGracePeriods переводит в read-only аккаунты, у которых истёк grace-период, а занятое место всё ещё больше лимита.
+// Возвращает ID таких пользователей (организации тоже переводятся, но в список не попадают).
+// У тех, кто успел удалить лишнее, grace_until просто сбрасывается.
+func (s *QuotaService) EnforceGracePeriods(ctx context.Context) ([]int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        UPDATE user_plans up
+        SET read_only = up.current_used > `+limitExpr(LimitStorage)+`,
+            grace_until = NULL
+        FROM plans p
+        WHERE p.id = up.plan_id
+          AND up.grace_until <= NOW()
+          AND `+activePlanCond+`
+        RETURNING up.user_id, up.read_only
+    `)
+	if err != nil {
+		return nil, fmt.Errorf("enforce grace periods: %w", err)
+	}
+	defer rows.Close()
+
+	var readOnlyUsers []int
+	for rows.Next() {
+		var userID sql.NullInt64
+		var readOnly bool
+		if err := rows.Scan(&userID, &readOnly); err != nil {
+			return nil, fmt.Errorf("enforce grace periods scan: %w", err)
+		}
+		if readOnly && userID.Valid {
+			readOnlyUsers = append(readOnlyUsers, int(userID.Int64))
+		}
+	}
+	return readOnlyUsers, rows.Err()
+}
@@ -0,0 +1,195 @@
+package quota_service_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var lapsedCols = []string{"user_id", "org_id", "id", "name", "period_days", "price_cents", "current_used", "auto_renew", "cancel_at_period_end", "expires_at", "retired"}
+
+// expectLapsedRow — блокировка закончившейся строки user_plans id=10 и перевод её в expired
+func expectLapsedRow(mock sqlmock.Sqlmock, userID int, p testPlan, used int64, autoRenew, canceled bool, expiresAt time.Time) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM user_plans up\s+JOIN plans p ON p.id = up.plan_id\s+WHERE up.id = \$1.*FOR UPDATE OF up`).
+		WithArgs(10).
+		WillReturnRows(sqlmock.NewRows(lapsedCols).AddRow(userID, nil, p.id, p.name, p.periodDays, p.priceCents, used, autoRenew, canceled, expiresAt, false))
+	mock.ExpectExec(`UPDATE user_plans SET status = 'expired' WHERE id = \$1`).
+		WithArgs(10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func expectNoNextPlan(mock sqlmock.Sqlmock, userID int) {
+	mock.ExpectQuery(`SELECT up.id, p.name\s+FROM user_plans up`).
+		WithArgs(userID).
+		WillReturnError(sql.ErrNoRows)
+}
+
+// expectFallbackFree — вставка строки free с перенесённым current_used (id=11), занятое в лимит укладывается
+func expectFallbackFree(mock sqlmock.Sqlmock, userID int, used int64) {
+	mock.ExpectQuery(`INSERT INTO user_plans \(user_id, plan_id, started_at, expires_at, current_used\)\s+SELECT \$1, p.id, NOW\(\)`).
+		WithArgs(userID, used).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+	expectNoGrace(mock, 11)
+}
+
+func expectNoGrace(mock sqlmock.Sqlmock, planRowID int) {
+	mock.ExpectQuery(`SET grace_until = COALESCE`).
+		WithArgs(planRowID, sqlmock.AnyArg()).
+		WillReturnError(sql.ErrNoRows)
+}
+
+func expectLapsedIDs(mock sqlmock.Sqlmock, ids ...int) {
+	rows := sqlmock.NewRows([]string{"id"})
+	for _, id := range ids {
+		rows.AddRow(id)
+	}
+	mock.ExpectQuery(`SELECT id FROM user_plans\s+WHERE status = 'active'\s+AND expires_at <= NOW\(\)`).
+		WithArgs(100).
+		WillReturnRows(rows)
+}
+
+// Платный план с auto_renew без оплаты не продлевается: аккаунт на free, пока продление не оплачено
+func TestProcessLapsedPlans_PaidAutoRenewFallsBackUntilPaid(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectLapsedIDs(mock, 10)
+	expectLapsedRow(mock, 7, proPlan, 100, true, false, time.Now().Add(-time.Minute))
+	expectNoNextPlan(mock, 7)
+	expectFallbackFree(mock, 7, 100)
+	mock.ExpectCommit()
+
+	transitions, err := s.ProcessLapsedPlans(context.Background(), time.Hour, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 1 {
+		t.Fatalf("transitions = %+v, want one", transitions)
+	}
+	tr := transitions[0]
+	if tr.Action != quota_service.PlanActionRenewalDue || tr.FromPlan != "pro" || tr.ToPlan != "free" || tr.UserID != 7 {
+		t.Errorf("transition = %+v, want pro -> free (renewal_due) for user 7", tr)
+	}
+	checkMock(t, mock)
+}
+
+// Бесплатный периодический план продлевается сразу, новый период начинается с конца старого
+func TestProcessLapsedPlans_FreePeriodicPlanRenewed(t *testing.T) {
+	trial := testPlan{id: 3, name: "trial", priceCents: 0, periodDays: 14, storage: gib}
+	expiresAt := time.Now().Add(-time.Minute)
+
+	s, mock := newQuotaMock(t)
+	expectLapsedIDs(mock, 10)
+	expectLapsedRow(mock, 7, trial, 100, true, false, expiresAt)
+	expectNoNextPlan(mock, 7)
+	mock.ExpectQuery(`INSERT INTO user_plans \(user_id, plan_id, started_at, expires_at, current_used, auto_renew\)`).
+		WithArgs(7, trial.id, expiresAt, trial.periodDays, int64(100)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+	expectNoGrace(mock, 11)
+	mock.ExpectCommit()
+
+	transitions, err := s.ProcessLapsedPlans(context.Background(), time.Hour, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 1 || transitions[0].Action != quota_service.PlanActionRenewed || transitions[0].ToPlan != "trial" {
+		t.Fatalf("transitions = %+v, want trial renewed", transitions)
+	}
+	checkMock(t, mock)
+}
+
+// После отмены free уже создан заранее — закончившийся план просто закрывается
+func TestProcessLapsedPlans_CanceledSwitchesToScheduled(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectLapsedIDs(mock, 10)
+	expectLapsedRow(mock, 7, proPlan, 100, false, true, time.Now().Add(-time.Minute))
+	mock.ExpectQuery(`SELECT up.id, p.name\s+FROM user_plans up`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(11, "free"))
+	expectNoGrace(mock, 11)
+	mock.ExpectCommit()
+
+	transitions, err := s.ProcessLapsedPlans(context.Background(), time.Hour, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 1 || transitions[0].Action != quota_service.PlanActionScheduled || transitions[0].ToPlan != "free" {
+		t.Fatalf("transitions = %+v, want scheduled free", transitions)
+	}
+	checkMock(t, mock)
+}
+
+func TestProcessLapsedPlans_OverLimitStartsGrace(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectLapsedIDs(mock, 10)
+	expectLapsedRow(mock, 7, proPlan, 5*gib, false, false, time.Now().Add(-time.Minute))
+	expectNoNextPlan(mock, 7)
+	mock.ExpectQuery(`INSERT INTO user_plans \(user_id, plan_id, started_at, expires_at, current_used\)`).
+		WithArgs(7, 5*gib).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+	graceUntil := time.Now().Add(time.Hour)
+	mock.ExpectQuery(`SET grace_until = COALESCE`).
+		WithArgs(11, int64(3600)).
+		WillReturnRows(sqlmock.NewRows([]string{"grace_until"}).AddRow(graceUntil))
+	mock.ExpectCommit()
+
+	transitions, err := s.ProcessLapsedPlans(context.Background(), time.Hour, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(transitions) != 1 || transitions[0].Action != quota_service.PlanActionFallbackFree || transitions[0].GraceUntil == nil {
+		t.Fatalf("transitions = %+v, want fallback_free with grace", transitions)
+	}
+	checkMock(t, mock)
+}
+
+// Подписка закончилась, а PlanExpiryProcessor до неё ещё не дошёл: квота обрабатывает её сама и считает по free
+func TestCheckQuota_LapsedPlanResolvedInline(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	var notified []domain.PlanTransition
+	s.SetLapseHandling(time.Hour, func(ctx context.Context, tr domain.PlanTransition) {
+		notified = append(notified, tr)
+	})
+
+	mock.ExpectQuery(`SELECT up.current_used, .*up.read_only\s+FROM user_plans up`).
+		WithArgs(7).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`SELECT up.id FROM user_plans up\s+WHERE up.user_id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+	expectLapsedRow(mock, 7, proPlan, 100, true, false, time.Now().Add(-time.Minute))
+	expectNoNextPlan(mock, 7)
+	expectFallbackFree(mock, 7, 100)
+	mock.ExpectCommit()
+	mock.ExpectQuery(`SELECT up.current_used, .*up.read_only\s+FROM user_plans up`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"current_used", "storage_limit", "read_only"}).AddRow(100, gib, false))
+
+	if err := s.CheckQuota(context.Background(), 7, 2*gib); !errors.Is(err, quota_service.ErrQuotaExceeded) {
+		t.Fatalf("err = %v, want ErrQuotaExceeded against the free limit", err)
+	}
+	if len(notified) != 1 || notified[0].Action != quota_service.PlanActionRenewalDue {
+		t.Errorf("notified = %+v, want one renewal_due", notified)
+	}
+	checkMock(t, mock)
+}
+
+func TestCheckQuota_NoPlanAtAll(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectQuery(`SELECT up.current_used, .*up.read_only\s+FROM user_plans up`).
+		WithArgs(7).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`SELECT up.id FROM user_plans up\s+WHERE up.user_id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	if err := s.CheckQuota(context.Background(), 7, 1); !errors.Is(err, quota_service.ErrNoActivePlan) {
+		t.Fatalf("err = %v, want ErrNoActivePlan", err)
+	}
+	checkMock(t, mock)
+}
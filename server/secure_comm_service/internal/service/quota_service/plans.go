@@ -0,0 +1,398 @@
+package quota_service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+)
+
+var (
+	ErrPlanNotFound       = errors.New("plan not found")
+	ErrAlreadyOnPlan      = errors.New("user is already subscribed to this plan")
+	ErrNotUpgrade         = errors.New("the target plan has a smaller storage limit, use downgrade")
+	ErrNotDowngrade       = errors.New("the target plan does not have a smaller storage limit, use subscribe")
+	ErrDowngradeOverLimit = errors.New("storage used exceeds the limit of the target plan")
+	ErrFreePlanCancel     = errors.New("free plan cannot be canceled")
+	ErrAlreadyCanceled    = errors.New("subscription is already canceled at the end of the period")
+	ErrPaymentRequired    = errors.New("switching to a paid plan requires a confirmed payment")
+)
+
+// planColumns — колонки плана (алиас p) в порядке planScanDest
+const planColumns = `p.id, p.name, p.price_cents, p.period_days, p.retired_at, ` + planLimitColumns
+
+func planScanDest(p *domain.Plan) []any {
+	return append([]any{&p.ID, &p.Name, &p.PriceCents, &p.PeriodDays, &p.RetiredAt}, planLimitsScanDest(&p.PlanLimits)...)
+}
+
+// planExpiresAtExpr — expires_at новой подписки: сейчас + period_days, либо бесконечность для бессрочных планов
+const planExpiresAtExpr = `CASE
+              WHEN p.period_days > 0
+                THEN NOW() + (p.period_days || ' days')::interval
+              ELSE 'infinity'::timestamptz
+            END`
+
+// ListPlans возвращает тарифы, доступные для перехода и оплаты (без выведенных из продажи)
+func (s *QuotaService) ListPlans(ctx context.Context) ([]domain.Plan, error) {
+	return s.listPlans(ctx, false)
+}
+
+func (s *QuotaService) listPlans(ctx context.Context, withRetired bool) ([]domain.Plan, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT `+planColumns+`
+        FROM plans p
+        WHERE $1 OR p.retired_at IS NULL
+        ORDER BY p.storage_limit
+    `, withRetired)
+	if err != nil {
+		return nil, fmt.Errorf("list plans: %w", err)
+	}
+	defer rows.Close()
+
+	var plans []domain.Plan
+	for rows.Next() {
+		var p domain.Plan
+		if err := rows.Scan(planScanDest(&p)...); err != nil {
+			return nil, fmt.Errorf("list plans scan: %w", err)
+		}
+		plans = append(plans, p)
+	}
+	return plans, rows.Err()
+}
+
+// GetCurrentPlan возвращает действующую подписку и план, запланированный после её окончания
+func (s *QuotaService) GetCurrentPlan(ctx context.Context, userID int) (domain.UserPlan, error) {
+	return s.getCurrentPlan(ctx, userOwner(userID))
+}
+
+func (s *QuotaService) getCurrentPlan(ctx context.Context, owner quotaOwner) (domain.UserPlan, error) {
+	var up domain.UserPlan
+	var expiresAt, graceUntil sql.NullTime
+
+	// lib/pq не умеет сканировать 'infinity' в time.Time, поэтому бессрочный план отдаём как NULL
+	err := s.withLapsedPlan(ctx, owner, func() error {
+		err := s.db.QueryRowContext(ctx, `
+            SELECT `+planColumns+`,
+                   up.started_at,
+                   CASE WHEN up.expires_at = 'infinity' THEN NULL ELSE up.expires_at END,
+                   up.current_used, up.cancel_at_period_end, up.read_only, up.auto_renew, up.grace_until
+            FROM user_plans up
+            JOIN plans p ON p.id = up.plan_id
+            WHERE up.`+owner.column+` = $1
+              AND `+activePlanCond, owner.id).Scan(append(planScanDest(&up.Plan),
+			&up.StartedAt, &expiresAt, &up.CurrentUsed, &up.CancelAtPeriodEnd, &up.ReadOnly, &up.AutoRenew, &graceUntil,
+		)...)
+		if err == sql.ErrNoRows {
+			return ErrNoActivePlan
+		}
+		if err != nil {
+			return fmt.Errorf("get current plan: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.UserPlan{}, err
+	}
+	if expiresAt.Valid {
+		up.ExpiresAt = &expiresAt.Time
+	}
+	if graceUntil.Valid {
+		up.GraceUntil = &graceUntil.Time
+	}
+
+	var next string
+	err = s.db.QueryRowContext(ctx, `
+        SELECT p.name
+        FROM user_plans up
+        JOIN plans p ON p.id = up.plan_id
+        WHERE up.`+owner.column+` = $1
+          AND up.status = 'active'
+          AND up.started_at > NOW()
+        ORDER BY up.started_at
+        LIMIT 1
+    `, owner.id).Scan(&next)
+	if err != nil && err != sql.ErrNoRows {
+		return domain.UserPlan{}, fmt.Errorf("get next plan: %w", err)
+	}
+	if err == nil {
+		up.NextPlan = &next
+	}
+
+	if !owner.isOrg() {
+		if up.LimitOverride, err = s.activeLimitOverride(ctx, owner.id); err != nil {
+			return domain.UserPlan{}, err
+		}
+	}
+
+	return up, nil
+}
+
+// Subscribe переводит пользователя на план с не меньшим лимитом (апгрейд) сразу.
+// Повторная подписка на текущий план после отмены снимает отмену.
+// Переход на платный план возможен только через оплату (ErrPaymentRequired, см. ApplyPaidPeriod).
+// autoRenew — продлевать ли платный план автоматически по окончании периода (см. ProcessLapsedPlans).
+func (s *QuotaService) Subscribe(ctx context.Context, userID int, planName string, autoRenew bool) (domain.UserPlan, error) {
+	return s.switchPlan(ctx, userOwner(userID), planName, false, false, autoRenew)
+}
+
+// Downgrade сразу переводит пользователя на план с меньшим лимитом.
+// Если занято больше нового лимита: при readOnlyIfOverLimit аккаунт становится read-only, иначе ErrDowngradeOverLimit.
+func (s *QuotaService) Downgrade(ctx context.Context, userID int, planName string, readOnlyIfOverLimit, autoRenew bool) (domain.UserPlan, error) {
+	return s.switchPlan(ctx, userOwner(userID), planName, true, readOnlyIfOverLimit, autoRenew)
+}
+
+// CancelAtPeriodEnd отменяет платную подписку: она действует до expires_at,
+// после чего начинает действовать заранее созданная строка бесплатного плана.
+func (s *QuotaService) CancelAtPeriodEnd(ctx context.Context, userID int) (domain.UserPlan, error) {
+	return s.cancelAtPeriodEnd(ctx, userOwner(userID))
+}
+
+func (s *QuotaService) cancelAtPeriodEnd(ctx context.Context, owner quotaOwner) (domain.UserPlan, error) {
+	// закончившаяся подписка обрабатывается до транзакции: отменять уже нечего, действует то, что пришло ей на смену
+	if _, err := s.resolveLapsed(ctx, owner); err != nil {
+		return domain.UserPlan{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.UserPlan{}, fmt.Errorf("cancel plan: %w", err)
+	}
+	defer tx.Rollback()
+
+	cur, err := lockActivePlan(ctx, tx, owner)
+	if err != nil {
+		return domain.UserPlan{}, err
+	}
+	if cur.priceCents == 0 {
+		return domain.UserPlan{}, ErrFreePlanCancel
+	}
+	if cur.cancelAtPeriodEnd {
+		return domain.UserPlan{}, ErrAlreadyCanceled
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE user_plans SET cancel_at_period_end = TRUE, auto_renew = FALSE WHERE id = $1
+    `, cur.id); err != nil {
+		return domain.UserPlan{}, fmt.Errorf("cancel plan: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO user_plans (`+owner.column+`, plan_id, started_at, expires_at, current_used)
+        SELECT $1, p.id, up.expires_at, 'infinity'::timestamptz, up.current_used
+        FROM user_plans up, plans p
+        WHERE up.id = $2
+          AND p.name = 'free'
+    `, owner.id, cur.id); err != nil {
+		return domain.UserPlan{}, fmt.Errorf("schedule free plan: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain.UserPlan{}, fmt.Errorf("cancel plan: %w", err)
+	}
+	return s.getCurrentPlan(ctx, owner)
+}
+
+// activePlanRow — заблокированная строка действующей подписки
+type activePlanRow struct {
+	id                int
+	planID            int
+	storageLimit      int64
+	priceCents        int
+	currentUsed       int64
+	cancelAtPeriodEnd bool
+	storageOverride   sql.NullInt64 // персональный лимит хранилища, см. user_limit_overrides
+}
+
+// storageLimitFor — лимит хранилища после перехода на план с лимитом planLimit: персональный лимит сохраняется при смене плана
+func (r activePlanRow) storageLimitFor(planLimit int64) int64 {
+	if r.storageOverride.Valid {
+		return r.storageOverride.Int64
+	}
+	return planLimit
+}
+
+func lockActivePlan(ctx context.Context, tx *sql.Tx, owner quotaOwner) (activePlanRow, error) {
+	var cur activePlanRow
+	err := tx.QueryRowContext(ctx, `
+        SELECT up.id, up.plan_id, p.storage_limit, p.price_cents, up.current_used, up.cancel_at_period_end,
+               `+overrideExpr(LimitStorage)+`
+        FROM user_plans up
+        JOIN plans p ON p.id = up.plan_id
+        WHERE up.`+owner.column+` = $1
+          AND `+activePlanCond+`
+        FOR UPDATE OF up
+    `, owner.id).Scan(&cur.id, &cur.planID, &cur.storageLimit, &cur.priceCents, &cur.currentUsed, &cur.cancelAtPeriodEnd, &cur.storageOverride)
+	if err == sql.ErrNoRows {
+		return activePlanRow{}, ErrNoActivePlan
+	}
+	if err != nil {
+		return activePlanRow{}, fmt.Errorf("lock active plan: %w", err)
+	}
+	return cur, nil
+}
+
+// switchPlan закрывает текущую строку user_plans (expires_at = NOW(), status = 'replaced')
+// и создаёт новую с тем же current_used. Запланированные после отмены строки удаляются.
+func (s *QuotaService) switchPlan(ctx context.Context, owner quotaOwner, planName string, downgrade, readOnlyIfOverLimit, autoRenew bool) (domain.UserPlan, error) {
+	if _, err := s.resolveLapsed(ctx, owner); err != nil {
+		return domain.UserPlan{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.UserPlan{}, fmt.Errorf("switch plan: %w", err)
+	}
+	defer tx.Rollback()
+
+	cur, err := lockActivePlan(ctx, tx, owner)
+	if err != nil {
+		return domain.UserPlan{}, err
+	}
+
+	target, err := planByName(ctx, tx, planName)
+	if err != nil {
+		return domain.UserPlan{}, err
+	}
+
+	if target.ID == cur.planID {
+		if downgrade || !cur.cancelAtPeriodEnd {
+			return domain.UserPlan{}, ErrAlreadyOnPlan
+		}
+		// повторная подписка на отменённый план — просто снимаем отмену
+		if _, err := tx.ExecContext(ctx, `UPDATE user_plans SET cancel_at_period_end = FALSE, auto_renew = $2 WHERE id = $1`, cur.id, autoRenew); err != nil {
+			return domain.UserPlan{}, fmt.Errorf("resume plan: %w", err)
+		}
+		if err := deleteScheduledPlans(ctx, tx, owner); err != nil {
+			return domain.UserPlan{}, err
+		}
+		if err := tx.Commit(); err != nil {
+			return domain.UserPlan{}, fmt.Errorf("resume plan: %w", err)
+		}
+		return s.getCurrentPlan(ctx, owner)
+	}
+
+	isDowngrade := target.StorageLimit < cur.storageLimit
+	if downgrade && !isDowngrade {
+		return domain.UserPlan{}, ErrNotDowngrade
+	}
+	if !downgrade && isDowngrade {
+		return domain.UserPlan{}, ErrNotUpgrade
+	}
+	if target.PriceCents > 0 {
+		return domain.UserPlan{}, ErrPaymentRequired
+	}
+
+	readOnly := false
+	if cur.currentUsed > cur.storageLimitFor(target.StorageLimit) {
+		if !readOnlyIfOverLimit {
+			return domain.UserPlan{}, ErrDowngradeOverLimit
+		}
+		readOnly = true
+	}
+
+	if err := replacePlan(ctx, tx, owner, cur, target.ID, readOnly, autoRenew); err != nil {
+		return domain.UserPlan{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return domain.UserPlan{}, fmt.Errorf("switch plan: %w", err)
+	}
+	s.limits.Delete(owner.cacheKey())
+	return s.getCurrentPlan(ctx, owner)
+}
+
+// ApplyPaidPeriod применяет подтверждённую оплату внутри транзакции вызывающего (billing_service),
+// чтобы запись события оплаты и смена плана фиксировались атомарно.
+// Закончившуюся подписку вызывающий должен обработать заранее (ResolveLapsedPlan), иначе ErrNoActivePlan.
+// Оплата текущего плана продлевает его на period_days от текущего expires_at, оплата другого плана сразу переключает на него.
+func (s *QuotaService) ApplyPaidPeriod(ctx context.Context, tx *sql.Tx, userID, planID int, autoRenew bool) error {
+	return applyPaidPeriod(ctx, tx, userOwner(userID), planID, autoRenew)
+}
+
+func applyPaidPeriod(ctx context.Context, tx *sql.Tx, owner quotaOwner, planID int, autoRenew bool) error {
+	cur, err := lockActivePlan(ctx, tx, owner)
+	if err != nil {
+		return err
+	}
+
+	if cur.planID == planID {
+		if _, err := tx.ExecContext(ctx, `
+            UPDATE user_plans up
+            SET expires_at = up.expires_at + (p.period_days || ' days')::interval,
+                cancel_at_period_end = FALSE,
+                auto_renew = $2 AND p.period_days > 0,
+                expiry_warned_at = NULL
+            FROM plans p
+            WHERE p.id = up.plan_id
+              AND up.id = $1
+        `, cur.id, autoRenew); err != nil {
+			return fmt.Errorf("extend plan: %w", err)
+		}
+		return deleteScheduledPlans(ctx, tx, owner)
+	}
+
+	var storageLimit int64
+	err = tx.QueryRowContext(ctx, `SELECT storage_limit FROM plans WHERE id = $1`, planID).Scan(&storageLimit)
+	if err == sql.ErrNoRows {
+		return ErrPlanNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("apply paid period: %w", err)
+	}
+
+	// оплата уже прошла, поэтому превышение лимита не отменяет переход, а делает аккаунт read-only
+	return replacePlan(ctx, tx, owner, cur, planID, cur.currentUsed > cur.storageLimitFor(storageLimit), autoRenew)
+}
+
+// planByName возвращает план, на который можно перейти: выведенные из продажи не находятся
+func planByName(ctx context.Context, tx *sql.Tx, planName string) (domain.Plan, error) {
+	var p domain.Plan
+	err := tx.QueryRowContext(ctx, `
+        SELECT `+planColumns+` FROM plans p WHERE p.name = $1 AND p.retired_at IS NULL
+    `, planName).Scan(planScanDest(&p)...)
+	if err == sql.ErrNoRows {
+		return domain.Plan{}, ErrPlanNotFound
+	}
+	if err != nil {
+		return domain.Plan{}, fmt.Errorf("get plan: %w", err)
+	}
+	return p, nil
+}
+
+// replacePlan закрывает строку cur и вставляет новую строку плана planID с тем же current_used
+func replacePlan(ctx context.Context, tx *sql.Tx, owner quotaOwner, cur activePlanRow, planID int, readOnly, autoRenew bool) error {
+	if err := deleteScheduledPlans(ctx, tx, owner); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE user_plans SET expires_at = NOW(), status = 'replaced' WHERE id = $1
+    `, cur.id); err != nil {
+		return fmt.Errorf("close current plan: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO user_plans (`+owner.column+`, plan_id, started_at, expires_at, current_used, read_only, auto_renew)
+        SELECT $1, p.id, NOW(), `+planExpiresAtExpr+`, $3, $4, $5 AND p.period_days > 0
+        FROM plans p
+        WHERE p.id = $2
+    `, owner.id, planID, cur.currentUsed, readOnly, autoRenew); err != nil {
+		return fmt.Errorf("insert new plan: %w", err)
+	}
+	if owner.isOrg() {
+		return nil
+	}
+	// у большего плана процент заполнения ниже — пересечённые пороги снова должны сработать
+	return rearmUsageAlerts(ctx, tx, owner.id)
+}
+
+func deleteScheduledPlans(ctx context.Context, tx *sql.Tx, owner quotaOwner) error {
+	if _, err := tx.ExecContext(ctx, `
+        DELETE FROM user_plans WHERE `+owner.column+` = $1 AND status = 'active' AND started_at > NOW()
+    `, owner.id); err != nil {
+		return fmt.Errorf("delete scheduled plans: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,349 @@
+package quota_service_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+const gib = int64(1 << 30)
+
+var (
+	planCols       = []string{"id", "name", "price_cents", "period_days", "retired_at", "storage_limit", "max_file_size", "max_files", "egress_limit", "request_limit"}
+	lockCols       = []string{"id", "plan_id", "storage_limit", "price_cents", "current_used", "cancel_at_period_end", "storage_override"}
+	currentPlanCol = append(append([]string{}, planCols...), "started_at", "expires_at", "current_used", "cancel_at_period_end", "read_only", "auto_renew", "grace_until")
+)
+
+type testPlan struct {
+	id         int
+	name       string
+	priceCents int
+	periodDays int
+	storage    int64
+}
+
+var (
+	freePlan = testPlan{id: 1, name: "free", priceCents: 0, periodDays: 0, storage: gib}
+	proPlan  = testPlan{id: 2, name: "pro", priceCents: 29900, periodDays: 30, storage: 10 * gib}
+)
+
+// expectLock — lockActivePlan: строка user_plans id=10 на плане p
+func expectLock(mock sqlmock.Sqlmock, userID int, p testPlan, used int64, canceled bool, override any) {
+	mock.ExpectQuery(`SELECT up.id, up.plan_id, p.storage_limit, p.price_cents, up.current_used, up.cancel_at_period_end,.*FOR UPDATE OF up`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows(lockCols).AddRow(10, p.id, p.storage, p.priceCents, used, canceled, override))
+}
+
+// expectNoLapsed — resolveLapsed перед сменой плана: закончившихся подписок нет
+func expectNoLapsed(mock sqlmock.Sqlmock, userID int) {
+	mock.ExpectQuery(`SELECT up.id FROM user_plans up\s+WHERE up.user_id = \$1\s+AND up.status = 'active'\s+AND up.expires_at <= NOW\(\)`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+}
+
+func expectPlanByName(mock sqlmock.Sqlmock, p testPlan) {
+	mock.ExpectQuery(`FROM plans p WHERE p.name = \$1 AND p.retired_at IS NULL`).
+		WithArgs(p.name).
+		WillReturnRows(sqlmock.NewRows(planCols).AddRow(p.id, p.name, p.priceCents, p.periodDays, nil, p.storage, 0, 0, 0, 0))
+}
+
+// expectCurrentPlan — getCurrentPlan после коммита: действующая строка, запланированный план и персональные лимиты
+func expectCurrentPlan(mock sqlmock.Sqlmock, userID int, p testPlan, used int64, readOnly bool) {
+	mock.ExpectQuery(`SELECT p.id, p.name.*up.started_at.*FROM user_plans up`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows(currentPlanCol).AddRow(
+			p.id, p.name, p.priceCents, p.periodDays, nil, p.storage, 0, 0, 0, 0,
+			time.Now(), nil, used, false, readOnly, false, nil))
+	mock.ExpectQuery(`SELECT p.name\s+FROM user_plans up`).
+		WithArgs(userID).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`FROM user_limit_overrides WHERE user_id = \$1`).
+		WithArgs(userID).
+		WillReturnError(sql.ErrNoRows)
+}
+
+// expectReplacePlan — replacePlan для личного плана: старая строка закрывается, новая создаётся с тем же current_used
+func expectReplacePlan(mock sqlmock.Sqlmock, userID, planID int, used int64, readOnly, autoRenew bool) {
+	mock.ExpectExec(`DELETE FROM user_plans WHERE user_id = \$1 AND status = 'active' AND started_at > NOW\(\)`).
+		WithArgs(userID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE user_plans SET expires_at = NOW\(\), status = 'replaced' WHERE id = \$1`).
+		WithArgs(10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO user_plans \(user_id, plan_id, started_at, expires_at, current_used, read_only, auto_renew\)`).
+		WithArgs(userID, planID, used, readOnly, autoRenew).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM usage_alert_state`).
+		WithArgs(userID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestSubscribe_PaidPlanRequiresPayment(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectNoLapsed(mock, 7)
+	mock.ExpectBegin()
+	expectLock(mock, 7, freePlan, 100, false, nil)
+	expectPlanByName(mock, proPlan)
+	mock.ExpectRollback()
+
+	_, err := s.Subscribe(context.Background(), 7, "pro", true)
+	if !errors.Is(err, quota_service.ErrPaymentRequired) {
+		t.Fatalf("err = %v, want ErrPaymentRequired", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestSubscribe_SmallerPlanIsNotUpgrade(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectNoLapsed(mock, 7)
+	mock.ExpectBegin()
+	expectLock(mock, 7, proPlan, 100, false, nil)
+	expectPlanByName(mock, freePlan)
+	mock.ExpectRollback()
+
+	_, err := s.Subscribe(context.Background(), 7, "free", false)
+	if !errors.Is(err, quota_service.ErrNotUpgrade) {
+		t.Fatalf("err = %v, want ErrNotUpgrade", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestSubscribe_AlreadyOnPlan(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectNoLapsed(mock, 7)
+	mock.ExpectBegin()
+	expectLock(mock, 7, proPlan, 100, false, nil)
+	expectPlanByName(mock, proPlan)
+	mock.ExpectRollback()
+
+	_, err := s.Subscribe(context.Background(), 7, "pro", true)
+	if !errors.Is(err, quota_service.ErrAlreadyOnPlan) {
+		t.Fatalf("err = %v, want ErrAlreadyOnPlan", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestSubscribe_UnknownPlan(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectNoLapsed(mock, 7)
+	mock.ExpectBegin()
+	expectLock(mock, 7, freePlan, 100, false, nil)
+	mock.ExpectQuery(`FROM plans p WHERE p.name = \$1 AND p.retired_at IS NULL`).
+		WithArgs("legacy").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	_, err := s.Subscribe(context.Background(), 7, "legacy", false)
+	if !errors.Is(err, quota_service.ErrPlanNotFound) {
+		t.Fatalf("err = %v, want ErrPlanNotFound", err)
+	}
+	checkMock(t, mock)
+}
+
+// Повторная подписка на отменённый план снимает отмену без оплаты: период уже оплачен
+func TestSubscribe_ResumeCanceledPlan(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectNoLapsed(mock, 7)
+	mock.ExpectBegin()
+	expectLock(mock, 7, proPlan, 100, true, nil)
+	expectPlanByName(mock, proPlan)
+	mock.ExpectExec(`UPDATE user_plans SET cancel_at_period_end = FALSE, auto_renew = \$2 WHERE id = \$1`).
+		WithArgs(10, true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM user_plans WHERE user_id = \$1 AND status = 'active' AND started_at > NOW\(\)`).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectCurrentPlan(mock, 7, proPlan, 100, false)
+
+	up, err := s.Subscribe(context.Background(), 7, "pro", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if up.Name != "pro" || up.CancelAtPeriodEnd {
+		t.Errorf("plan = %s canceled=%v, want pro not canceled", up.Name, up.CancelAtPeriodEnd)
+	}
+	checkMock(t, mock)
+}
+
+func TestDowngrade_OverLimitRejected(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectNoLapsed(mock, 7)
+	mock.ExpectBegin()
+	expectLock(mock, 7, proPlan, 5*gib, false, nil)
+	expectPlanByName(mock, freePlan)
+	mock.ExpectRollback()
+
+	_, err := s.Downgrade(context.Background(), 7, "free", false, false)
+	if !errors.Is(err, quota_service.ErrDowngradeOverLimit) {
+		t.Fatalf("err = %v, want ErrDowngradeOverLimit", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestDowngrade_ReadOnlyIfOverLimit(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectNoLapsed(mock, 7)
+	mock.ExpectBegin()
+	expectLock(mock, 7, proPlan, 5*gib, false, nil)
+	expectPlanByName(mock, freePlan)
+	expectReplacePlan(mock, 7, freePlan.id, 5*gib, true, false)
+	mock.ExpectCommit()
+	expectCurrentPlan(mock, 7, freePlan, 5*gib, true)
+
+	up, err := s.Downgrade(context.Background(), 7, "free", true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !up.ReadOnly {
+		t.Error("read_only = false, want true")
+	}
+	checkMock(t, mock)
+}
+
+// Персональный лимит хранилища переживает смену плана — под ним даунгрейд не делает аккаунт read-only
+func TestDowngrade_StorageOverrideKept(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectNoLapsed(mock, 7)
+	mock.ExpectBegin()
+	expectLock(mock, 7, proPlan, 5*gib, false, 20*gib)
+	expectPlanByName(mock, freePlan)
+	expectReplacePlan(mock, 7, freePlan.id, 5*gib, false, false)
+	mock.ExpectCommit()
+	expectCurrentPlan(mock, 7, freePlan, 5*gib, false)
+
+	if _, err := s.Downgrade(context.Background(), 7, "free", false, false); err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}
+
+func TestDowngrade_LargerPlanIsNotDowngrade(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectNoLapsed(mock, 7)
+	mock.ExpectBegin()
+	expectLock(mock, 7, freePlan, 100, false, nil)
+	expectPlanByName(mock, proPlan)
+	mock.ExpectRollback()
+
+	_, err := s.Downgrade(context.Background(), 7, "pro", false, false)
+	if !errors.Is(err, quota_service.ErrNotDowngrade) {
+		t.Fatalf("err = %v, want ErrNotDowngrade", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestCancelAtPeriodEnd_FreePlan(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectNoLapsed(mock, 7)
+	mock.ExpectBegin()
+	expectLock(mock, 7, freePlan, 100, false, nil)
+	mock.ExpectRollback()
+
+	_, err := s.CancelAtPeriodEnd(context.Background(), 7)
+	if !errors.Is(err, quota_service.ErrFreePlanCancel) {
+		t.Fatalf("err = %v, want ErrFreePlanCancel", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestCancelAtPeriodEnd_SchedulesFree(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectNoLapsed(mock, 7)
+	mock.ExpectBegin()
+	expectLock(mock, 7, proPlan, 100, false, nil)
+	mock.ExpectExec(`UPDATE user_plans SET cancel_at_period_end = TRUE, auto_renew = FALSE WHERE id = \$1`).
+		WithArgs(10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO user_plans \(user_id, plan_id, started_at, expires_at, current_used\)\s+SELECT \$1, p.id, up.expires_at, 'infinity'::timestamptz, up.current_used`).
+		WithArgs(7, 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectCurrentPlan(mock, 7, proPlan, 100, false)
+
+	if _, err := s.CancelAtPeriodEnd(context.Background(), 7); err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}
+
+func TestCancelAtPeriodEnd_AlreadyCanceled(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	expectNoLapsed(mock, 7)
+	mock.ExpectBegin()
+	expectLock(mock, 7, proPlan, 100, true, nil)
+	mock.ExpectRollback()
+
+	_, err := s.CancelAtPeriodEnd(context.Background(), 7)
+	if !errors.Is(err, quota_service.ErrAlreadyCanceled) {
+		t.Fatalf("err = %v, want ErrAlreadyCanceled", err)
+	}
+	checkMock(t, mock)
+}
+
+func newPaidPeriodTx(t *testing.T) (*quota_service.QuotaService, *sql.Tx, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return quota_service.NewQuotaServiceForTesting(db), tx, mock
+}
+
+// Оплата текущего плана продлевает его от текущего expires_at
+func TestApplyPaidPeriod_ExtendsCurrentPlan(t *testing.T) {
+	s, tx, mock := newPaidPeriodTx(t)
+	expectLock(mock, 7, proPlan, 100, true, nil)
+	mock.ExpectExec(`UPDATE user_plans up\s+SET expires_at = up.expires_at \+ \(p.period_days \|\| ' days'\)::interval`).
+		WithArgs(10, true).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM user_plans WHERE user_id = \$1 AND status = 'active' AND started_at > NOW\(\)`).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := s.ApplyPaidPeriod(context.Background(), tx, 7, proPlan.id, true); err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}
+
+// Оплата уже прошла, поэтому превышение лимита нового плана не отменяет переход, а делает аккаунт read-only
+func TestApplyPaidPeriod_SwitchOverLimitIsReadOnly(t *testing.T) {
+	s, tx, mock := newPaidPeriodTx(t)
+	small := testPlan{id: 3, name: "lite", priceCents: 9900, periodDays: 30, storage: 2 * gib}
+	expectLock(mock, 7, proPlan, 5*gib, false, nil)
+	mock.ExpectQuery(`SELECT storage_limit FROM plans WHERE id = \$1`).
+		WithArgs(small.id).
+		WillReturnRows(sqlmock.NewRows([]string{"storage_limit"}).AddRow(small.storage))
+	expectReplacePlan(mock, 7, small.id, 5*gib, true, true)
+
+	if err := s.ApplyPaidPeriod(context.Background(), tx, 7, small.id, true); err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}
+
+func TestApplyPaidPeriod_UnknownPlan(t *testing.T) {
+	s, tx, mock := newPaidPeriodTx(t)
+	expectLock(mock, 7, freePlan, 100, false, nil)
+	mock.ExpectQuery(`SELECT storage_limit FROM plans WHERE id = \$1`).
+		WithArgs(99).
+		WillReturnError(sql.ErrNoRows)
+
+	err := s.ApplyPaidPeriod(context.Background(), tx, 7, 99, false)
+	if !errors.Is(err, quota_service.ErrPlanNotFound) {
+		t.Fatalf("err = %v, want ErrPlanNotFound", err)
+	}
+	checkMock(t, mock)
+}
@@ -8,19 +8,27 @@ import (
 
 	"github.com/1abobik1/SecureComm/internal/domain"
 	_ "github.com/lib/pq"
-)
-
-const (
-	FreeLimitBytes = int64(10 * 1024 * 1024 * 1024)       // 10 GiB
-	ProLimitBytes  = int64(1 * 1024 * 1024 * 1024 * 1024) // 1 TiB
+	"github.com/patrickmn/go-cache"
 )
 
 var ErrQuotaExceeded = errors.New("quota exceeded")
 var ErrNoActivePlan = errors.New("no active plan for user")
 var ErrUserNotFound = errors.New("there is no such user")
+var ErrReadOnly = errors.New("account is read-only: storage used exceeds the plan limit")
+
+// activePlanCond — строка user_plans, действующая прямо сейчас.
+// После отмены подписки в таблице уже лежит будущая строка (started_at > NOW()), поэтому одного expires_at > NOW() мало.
+const activePlanCond = `up.status = 'active' AND up.started_at <= NOW() AND up.expires_at > NOW()`
+
+// livePlanCond — действующая и запланированные строки. current_used ведётся во всех,
+// чтобы при переходе на запланированный план не потерялось занятое место.
+const livePlanCond = `up.status = 'active' AND up.expires_at > NOW()`
 
 type QuotaService struct {
-	db *sql.DB
+	db     *sql.DB
+	limits *cache.Cache // quotaOwner.cacheKey() -> domain.PlanLimits, см. GetLimits
+	alerts *usageAlerts // nil — уведомления о порогах выключены, см. EnableUsageAlerts
+	lapse  lapseHandling
 }
 
 func NewQuotaService(storagePath string) (*QuotaService, error) {
@@ -28,7 +36,11 @@ func NewQuotaService(storagePath string) (*QuotaService, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &QuotaService{db: db}, nil
+	return &QuotaService{db: db, limits: cache.New(limitsCacheTTL, 2*limitsCacheTTL)}, nil
+}
+
+func NewQuotaServiceForTesting(db *sql.DB) *QuotaService {
+	return &QuotaService{db: db, limits: cache.New(limitsCacheTTL, 2*limitsCacheTTL)}
 }
 
 func (s *QuotaService) InitializeFreePlan(ctx context.Context, userID int) error {
@@ -59,17 +71,23 @@ func (s *QuotaService) InitializeFreePlan(ctx context.Context, userID int) error
 
 // CheckQuota проверяет, что used + newSize не превысит storage_limit
 func (s *QuotaService) CheckQuota(ctx context.Context, userID int, newSize int64) error {
+	owner := userOwner(userID)
+	return s.withLapsedPlan(ctx, owner, func() error { return s.checkQuota(ctx, owner, newSize) })
+}
+
+func (s *QuotaService) checkQuota(ctx context.Context, owner quotaOwner, newSize int64) error {
 	var used, limit int64
+	var readOnly bool
 
 	// попробуем получить активную подписку
 	err := s.db.QueryRowContext(ctx, `
-        SELECT up.current_used, p.storage_limit
+        SELECT up.current_used, `+limitExpr(LimitStorage)+`, up.read_only
         FROM user_plans up
         JOIN plans p ON up.plan_id = p.id
-        WHERE up.user_id = $1
-          AND up.expires_at > NOW()
+        WHERE up.`+owner.column+` = $1
+          AND `+activePlanCond+`
         FOR SHARE
-    `, userID).Scan(&used, &limit)
+    `, owner.id).Scan(&used, &limit, &readOnly)
 
 	if err == sql.ErrNoRows {
 		// если записи нет — это баг, т.к. при регистрации создаём free-план
@@ -79,20 +97,33 @@ func (s *QuotaService) CheckQuota(ctx context.Context, userID int, newSize int64
 		return fmt.Errorf("quota check: %w", err)
 	}
 
+	if readOnly {
+		return ErrReadOnly
+	}
 	if used+newSize > limit {
-		return ErrQuotaExceeded
+		return limitExceeded(LimitStorage, limit, used+newSize, ErrQuotaExceeded)
 	}
 	return nil
 }
 
-// AddUsage прибавляет newSize к current_used в уже существующей активной записи
-func (s *QuotaService) AddUsage(ctx context.Context, userID int, newSize int64) error {
-	res, err := s.db.ExecContext(ctx, `
-        UPDATE user_plans
-        SET current_used = current_used + $1
-        WHERE user_id = $2
-          AND expires_at > NOW()
-    `, newSize, userID)
+// AddUsage прибавляет newSize к current_used в уже существующей активной записи и пишет изменение в журнал.
+// Если занятое место пересекло порог из настроек уведомлений, после коммита отправляется уведомление.
+func (s *QuotaService) AddUsage(ctx context.Context, userID int, newSize int64, ref domain.UsageRef) error {
+	return s.withLapsedPlan(ctx, userOwner(userID), func() error { return s.addUsage(ctx, userID, newSize, ref) })
+}
+
+func (s *QuotaService) addUsage(ctx context.Context, userID int, newSize int64, ref domain.UsageRef) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("add usage: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+        UPDATE user_plans up
+        SET current_used = up.current_used + $1
+        WHERE up.user_id = $2
+          AND `+livePlanCond, newSize, userID)
 	if err != nil {
 		return fmt.Errorf("add usage: %w", err)
 	}
@@ -104,54 +135,294 @@ func (s *QuotaService) AddUsage(ctx context.Context, userID int, newSize int64)
 	if rows == 0 {
 		return ErrNoActivePlan
 	}
+
+	if err := recordUsage(ctx, tx, userOwner(userID), userID, newSize, ref); err != nil {
+		return err
+	}
+	crossed, err := s.claimUsageAlerts(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.sendUsageAlerts(ctx, userID, crossed)
 	return nil
 }
 
-// RemoveUsage вычитает newSize из current_used в уже существующей активной записи
-func (s *QuotaService) RemoveUsage(ctx context.Context, userID int, newSize int64) error {
-	// Используем GREATEST, чтобы current_used не стал отрицательным
-	res, err := s.db.ExecContext(ctx, `
-        UPDATE user_plans
-        SET current_used = GREATEST(current_used - $1, 0)
-        WHERE user_id = $2
-          AND expires_at > NOW()
-    `, newSize, userID)
+// TryAddUsage атомарно прибавляет newSize к current_used, только если после этого не будет превышен storage_limit,
+// а для новых файлов (загрузка, копия) — ещё и max_files.
+// В отличие от пары CheckQuota + AddUsage, параллельные запросы не могут вместе выйти за лимит:
+// активная строка блокируется FOR UPDATE до конца транзакции.
+func (s *QuotaService) TryAddUsage(ctx context.Context, userID int, newSize int64, ref domain.UsageRef) error {
+	owner := userOwner(userID)
+	return s.withLapsedPlan(ctx, owner, func() error { return s.tryAddUsage(ctx, owner, userID, newSize, ref) })
+}
+
+// tryAddUsage — TryAddUsage для любого владельца пула. actorID — пользователь, выполнивший операцию.
+// Уведомления о порогах отправляются только для личных пулов.
+func (s *QuotaService) tryAddUsage(ctx context.Context, owner quotaOwner, actorID int, newSize int64, ref domain.UsageRef) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("try add usage: %w", err)
+	}
+	defer tx.Rollback()
+
+	var used, limit, files, maxFiles int64
+	var readOnly bool
+	err = tx.QueryRowContext(ctx, `
+        SELECT up.current_used, `+limitExpr(LimitStorage)+`, up.read_only,
+               COALESCE(fc.files, 0), `+limitExpr(LimitMaxFiles)+`
+        FROM user_plans up
+        JOIN plans p ON up.plan_id = p.id
+        LEFT JOIN `+owner.fileCountsTable()+` fc ON fc.`+owner.column+` = up.`+owner.column+`
+        WHERE up.`+owner.column+` = $1
+          AND `+activePlanCond+`
+        FOR UPDATE OF up
+    `, owner.id).Scan(&used, &limit, &readOnly, &files, &maxFiles)
+	if err == sql.ErrNoRows {
+		return ErrNoActivePlan
+	}
+	if err != nil {
+		return fmt.Errorf("try add usage: %w", err)
+	}
+
+	if readOnly {
+		return ErrReadOnly
+	}
+	if used+newSize > limit {
+		return limitExceeded(LimitStorage, limit, used+newSize, ErrQuotaExceeded)
+	}
+	if delta := fileDelta(ref.Reason); delta > 0 && maxFiles > 0 && files+delta > maxFiles {
+		return limitExceeded(LimitMaxFiles, maxFiles, files+delta, ErrTooManyFiles)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE user_plans up
+        SET current_used = up.current_used + $1
+        WHERE up.`+owner.column+` = $2
+          AND `+livePlanCond, newSize, owner.id); err != nil {
+		return fmt.Errorf("try add usage: %w", err)
+	}
+
+	if err := recordUsage(ctx, tx, owner, actorID, newSize, ref); err != nil {
+		return err
+	}
+	if owner.isOrg() {
+		return tx.Commit()
+	}
+	crossed, err := s.claimUsageAlerts(ctx, tx, owner.id)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	s.sendUsageAlerts(ctx, owner.id, crossed)
+	return nil
+}
+
+// RemoveUsage вычитает newSize из current_used в уже существующей активной записи и пишет изменение в журнал.
+// Если после удаления файлов место снова укладывается в лимит — режим read-only снимается.
+func (s *QuotaService) RemoveUsage(ctx context.Context, userID int, newSize int64, ref domain.UsageRef) error {
+	owner := userOwner(userID)
+	return s.withLapsedPlan(ctx, owner, func() error { return s.removeUsage(ctx, owner, userID, newSize, ref) })
+}
+
+func (s *QuotaService) removeUsage(ctx context.Context, owner quotaOwner, actorID int, newSize int64, ref domain.UsageRef) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("remove usage: %w", err)
 	}
-	rows, err := res.RowsAffected()
+	defer tx.Rollback()
+
+	// current_used не может стать отрицательным, поэтому в журнал пишем фактически вычтенное значение,
+	// иначе сумма журнала разойдётся с current_used.
+	// Блокируются те же строки, что меняет UPDATE ниже: действующая и запланированные (livePlanCond)
+	rows, err := tx.QueryContext(ctx, `
+        SELECT up.current_used, up.started_at <= NOW()
+        FROM user_plans up
+        WHERE up.`+owner.column+` = $1
+          AND `+livePlanCond+`
+        ORDER BY up.started_at
+        FOR UPDATE OF up
+    `, owner.id)
 	if err != nil {
-		return fmt.Errorf("remove usage rows: %w", err)
+		return fmt.Errorf("remove usage: %w", err)
 	}
-	if rows == 0 {
+	var used int64
+	var active bool
+	for rows.Next() {
+		var rowUsed int64
+		var started bool
+		if err := rows.Scan(&rowUsed, &started); err != nil {
+			rows.Close()
+			return fmt.Errorf("remove usage scan: %w", err)
+		}
+		if started && !active {
+			used, active = rowUsed, true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("remove usage: %w", err)
+	}
+	if !active {
 		return ErrNoActivePlan
 	}
-	return nil
+	removed := min(newSize, used)
+
+	if _, err := tx.ExecContext(ctx, `
+        UPDATE user_plans up
+        SET current_used = GREATEST(up.current_used - $1, 0),
+            read_only = up.read_only AND GREATEST(up.current_used - $1, 0) > `+limitExpr(LimitStorage)+`,
+            grace_until = CASE WHEN GREATEST(up.current_used - $1, 0) > `+limitExpr(LimitStorage)+` THEN up.grace_until END
+        FROM plans p
+        WHERE p.id = up.plan_id
+          AND up.`+owner.column+` = $2
+          AND `+livePlanCond, removed, owner.id); err != nil {
+		return fmt.Errorf("remove usage: %w", err)
+	}
+
+	if removed > 0 {
+		if err := recordUsage(ctx, tx, owner, actorID, -removed, ref); err != nil {
+			return err
+		}
+	} else if err := adjustFileCount(ctx, tx, owner, ref.Reason); err != nil {
+		// пустой файл не попадает в журнал, но из числа файлов его нужно вычесть
+		return err
+	}
+	if !owner.isOrg() {
+		if err := rearmUsageAlerts(ctx, tx, owner.id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func (s *QuotaService) GetUserUsage(ctx context.Context, userID int) (domain.UserUsage, error) {
-	const query = `
+	query := `
     SELECT
       up.current_used,
-      p.storage_limit,
+      ` + limitExpr(LimitStorage) + `,
       p.name AS plan_name
     FROM user_plans up
     JOIN plans p ON p.id = up.plan_id
     WHERE up.user_id = $1
-      AND (p.period_days = 0 OR up.expires_at > NOW())
-	`
+      AND ` + activePlanCond
 
 	var userUsage domain.UserUsage
 
-	err := s.db.QueryRowContext(ctx, query, userID).Scan(&userUsage.CurrentUsed, &userUsage.StorageLimit, &userUsage.PlanName)
+	err := s.withLapsedPlan(ctx, userOwner(userID), func() error {
+		err := s.db.QueryRowContext(ctx, query, userID).Scan(&userUsage.CurrentUsed, &userUsage.StorageLimit, &userUsage.PlanName)
+		if err == sql.ErrNoRows {
+			return ErrNoActivePlan
+		}
+		return err
+	})
 
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if err == ErrNoActivePlan {
 			return domain.UserUsage{}, ErrUserNotFound
 		}
 
 		return domain.UserUsage{}, err
 	}
 
+	userUsage.ByCategory, err = s.UsageByCategory(ctx, userID)
+	if err != nil {
+		return domain.UserUsage{}, err
+	}
+	for _, cu := range userUsage.ByCategory {
+		userUsage.LedgerUsed += cu.Bytes
+	}
+
+	userUsage.Traffic, err = s.GetTrafficUsage(ctx, userID)
+	if err != nil {
+		return domain.UserUsage{}, err
+	}
+
 	return userUsage, nil
 }
+
+// ListActiveUsage возвращает current_used всех активных личных подписок (user_id -> current_used)
+func (s *QuotaService) ListActiveUsage(ctx context.Context) (map[int]int64, error) {
+	return s.listActiveUsage(ctx, "user_id")
+}
+
+func (s *QuotaService) listActiveUsage(ctx context.Context, column string) (map[int]int64, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT up.`+column+`, up.current_used
+        FROM user_plans up
+        WHERE up.`+column+` IS NOT NULL
+          AND `+activePlanCond)
+	if err != nil {
+		return nil, fmt.Errorf("list usage: %w", err)
+	}
+	defer rows.Close()
+
+	usage := make(map[int]int64)
+	for rows.Next() {
+		var ownerID int
+		var used int64
+		if err := rows.Scan(&ownerID, &used); err != nil {
+			return nil, fmt.Errorf("list usage scan: %w", err)
+		}
+		usage[ownerID] = used
+	}
+	return usage, rows.Err()
+}
+
+// SetUsage перезаписывает current_used, только если он всё ещё равен expected
+// и после записи журнала sinceLedgerID у пользователя не было изменений (см. UsageWatermark).
+// Так реконсилер не затирает изменения, сделанные AddUsage/RemoveUsage во время сканирования MinIO.
+// Разница пишется в журнал как корректировка. Возвращает false, если значение успело измениться.
+func (s *QuotaService) SetUsage(ctx context.Context, userID int, expected, actual, sinceLedgerID int64) (bool, error) {
+	return s.setUsage(ctx, userOwner(userID), expected, actual, sinceLedgerID)
+}
+
+func (s *QuotaService) setUsage(ctx context.Context, owner quotaOwner, expected, actual, sinceLedgerID int64) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("set usage: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+        UPDATE user_plans up
+        SET current_used = $1
+        WHERE up.`+owner.column+` = $2
+          AND up.current_used = $3
+          AND `+livePlanCond+`
+          AND NOT EXISTS (SELECT 1 FROM usage_ledger l WHERE `+owner.ledgerFilter("$2")+` AND l.id > $4)`,
+		actual, owner.id, expected, sinceLedgerID)
+	if err != nil {
+		return false, fmt.Errorf("set usage: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("set usage rows: %w", err)
+	}
+	if rows == 0 {
+		return false, nil
+	}
+
+	// корректировка считается от суммы журнала, а не от expected: после неё журнал равен actual,
+	// и syncUsageWithLedger в recordUsage не откатит исправление
+	total, err := ledgerUsage(ctx, tx, owner)
+	if err != nil {
+		return false, err
+	}
+	if err := recordUsage(ctx, tx, owner, 0, actual-total, domain.UsageRef{Reason: domain.UsageReasonReconcile}); err != nil {
+		return false, err
+	}
+	if !owner.isOrg() {
+		if err := rearmUsageAlerts(ctx, tx, owner.id); err != nil {
+			return false, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("set usage: %w", err)
+	}
+	return true, nil
+}
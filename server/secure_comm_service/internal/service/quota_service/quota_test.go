@@ -0,0 +1,38 @@
+package quota_service_test
+
+import (
+	"testing"
+
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func newQuotaMock(t *testing.T) (*quota_service.QuotaService, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return quota_service.NewQuotaServiceForTesting(db), mock
+}
+
+func checkMock(t *testing.T, mock sqlmock.Sqlmock) {
+	t.Helper()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func expectLedgerSum(mock sqlmock.Sqlmock, ownerID int, total int64) {
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(l.delta\), 0\) FROM usage_ledger l WHERE`).
+		WithArgs(ownerID).
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(total))
+}
+
+// expectLedgerSync — syncUsageWithLedger после записи в журнал; fixed — сколько строк разошлись с журналом
+func expectLedgerSync(mock sqlmock.Sqlmock, column string, ownerID int, fixed int64) {
+	mock.ExpectExec(`UPDATE user_plans up\s+SET current_used = GREATEST\(l.total, 0\).*WHERE up.` + column + ` = \$1`).
+		WithArgs(ownerID).
+		WillReturnResult(sqlmock.NewResult(0, fixed))
+}
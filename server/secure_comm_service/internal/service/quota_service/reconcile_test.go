@@ -0,0 +1,107 @@
+package quota_service_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUsageWatermark(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectQuery(`SELECT COALESCE\(MAX\(id\), 0\) FROM usage_ledger`).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(int64(42)))
+
+	id, err := s.UsageWatermark(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != 42 {
+		t.Errorf("watermark = %d, want 42", id)
+	}
+	checkMock(t, mock)
+}
+
+func TestSetUsage_Applied(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE user_plans up\s+SET current_used = \$1.*NOT EXISTS \(SELECT 1 FROM usage_ledger l WHERE l.user_id = \$2 AND l.org_id IS NULL AND l.id > \$4\)`).
+		WithArgs(int64(300), 7, int64(500), int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerSum(mock, 7, 500)
+	mock.ExpectExec(`INSERT INTO usage_ledger`).
+		WithArgs(7, nil, "", "", int64(-200), "reconcile").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerSync(mock, "user_id", 7, 0)
+	mock.ExpectExec(`DELETE FROM usage_alert_state`).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	fixed, err := s.SetUsage(context.Background(), 7, 500, 300, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fixed {
+		t.Error("fixed = false, want true")
+	}
+	checkMock(t, mock)
+}
+
+// Загрузка или удаление во время сканирования MinIO пишут журнал после watermark — исправление не применяется
+func TestSetUsage_ChangedDuringScan(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE user_plans up`).
+		WithArgs(int64(300), 7, int64(500), int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	fixed, err := s.SetUsage(context.Background(), 7, 500, 300, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixed {
+		t.Error("fixed = true, want false")
+	}
+	checkMock(t, mock)
+}
+
+func TestSetOrgUsage_LedgerFilteredByOrg(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE user_plans up.*WHERE up.org_id = \$2.*NOT EXISTS \(SELECT 1 FROM usage_ledger l WHERE l.org_id = \$2 AND l.id > \$4\)`).
+		WithArgs(int64(100), 3, int64(0), int64(9)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerSum(mock, 3, 0)
+	mock.ExpectExec(`INSERT INTO usage_ledger`).
+		WithArgs(nil, 3, "", "", int64(100), "reconcile").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerSync(mock, "org_id", 3, 0)
+	mock.ExpectCommit()
+
+	fixed, err := s.SetOrgUsage(context.Background(), 3, 0, 100, 9)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fixed {
+		t.Error("fixed = false, want true")
+	}
+	checkMock(t, mock)
+}
+
+func TestSetFileCount_ChangedDuringScan(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectExec(`INSERT INTO user_file_counts \(user_id, files\)\s+SELECT \$1::int, \$3::bigint\s+WHERE NOT EXISTS \(SELECT 1 FROM usage_ledger l WHERE l.user_id = \$1 AND l.org_id IS NULL AND l.id > \$4\)`).
+		WithArgs(7, int64(4), int64(5), int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	fixed, err := s.SetFileCount(context.Background(), 7, 4, 5, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fixed {
+		t.Error("fixed = true, want false")
+	}
+	checkMock(t, mock)
+}
@@ -0,0 +1,130 @@
+package quota_service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+)
+
+var (
+	ErrEgressExceeded       = errors.New("monthly egress quota exceeded")
+	ErrRequestLimitExceeded = errors.New("monthly request limit exceeded")
+)
+
+// currentPeriodExpr — первое число текущего месяца по UTC, ключ строки traffic_usage
+const currentPeriodExpr = `date_trunc('month', NOW() AT TIME ZONE 'UTC')::date`
+
+// TryAddEgress резервирует size байт исходящего трафика до начала отдачи файла.
+// Резерв атомарный: параллельные скачивания не могут вместе выйти за egress_limit.
+// Если отдано меньше (обрыв соединения), разницу нужно вернуть через RefundEgress с возвращённым здесь периодом:
+// отдача может закончиться уже в следующем месяце, а вернуть резерв нужно туда, где он был взят.
+func (s *QuotaService) TryAddEgress(ctx context.Context, userID int, size int64) (time.Time, error) {
+	var period time.Time
+	err := s.withLapsedPlan(ctx, userOwner(userID), func() error {
+		var err error
+		period, err = s.addTraffic(ctx, userID, "egress_bytes", LimitEgress, size, ErrEgressExceeded)
+		return err
+	})
+	return period, err
+}
+
+// CountRequest засчитывает один запрос к файловому API, если месячный лимит запросов ещё не исчерпан
+func (s *QuotaService) CountRequest(ctx context.Context, userID int) error {
+	return s.withLapsedPlan(ctx, userOwner(userID), func() error {
+		_, err := s.addTraffic(ctx, userID, "requests", LimitRequests, 1, ErrRequestLimitExceeded)
+		return err
+	})
+}
+
+// RefundEgress возвращает неиспользованную часть резерва TryAddEgress в месяц period, за который резерв был взят
+func (s *QuotaService) RefundEgress(ctx context.Context, userID int, period time.Time, size int64) error {
+	if size <= 0 {
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, `
+        UPDATE traffic_usage
+        SET egress_bytes = GREATEST(egress_bytes - $2, 0)
+        WHERE user_id = $1
+          AND period_start = $3`, userID, size, period); err != nil {
+		return fmt.Errorf("refund egress: %w", err)
+	}
+	return nil
+}
+
+// GetTrafficUsage возвращает трафик и запросы за текущий месяц вместе с лимитами действующего плана
+func (s *QuotaService) GetTrafficUsage(ctx context.Context, userID int) (domain.TrafficUsage, error) {
+	var t domain.TrafficUsage
+	err := s.withLapsedPlan(ctx, userOwner(userID), func() error {
+		err := s.db.QueryRowContext(ctx, `
+            SELECT `+currentPeriodExpr+`,
+                   COALESCE(tu.egress_bytes, 0), `+limitExpr(LimitEgress)+`,
+                   COALESCE(tu.requests, 0), `+limitExpr(LimitRequests)+`
+            FROM user_plans up
+            JOIN plans p ON p.id = up.plan_id
+            LEFT JOIN traffic_usage tu ON tu.user_id = up.user_id AND tu.period_start = `+currentPeriodExpr+`
+            WHERE up.user_id = $1
+              AND `+activePlanCond, userID).Scan(&t.PeriodStart, &t.EgressUsed, &t.EgressLimit, &t.Requests, &t.RequestLimit)
+		if err == sql.ErrNoRows {
+			return ErrNoActivePlan
+		}
+		if err != nil {
+			return fmt.Errorf("get traffic usage: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.TrafficUsage{}, err
+	}
+	return t, nil
+}
+
+// addTraffic прибавляет amount к счётчику column за текущий месяц, если не будет превышен лимит limitColumn (0 — без лимита).
+// Строка месяца создаётся первым запросом, конкурентные запросы сериализуются блокировкой этой строки.
+// Возвращает начало месяца, в который засчитан amount.
+func (s *QuotaService) addTraffic(ctx context.Context, userID int, column, limitColumn string, amount int64, errExceeded error) (time.Time, error) {
+	var period time.Time
+	err := s.db.QueryRowContext(ctx, `
+        WITH lim AS (
+            SELECT `+limitExpr(limitColumn)+` AS value
+            FROM user_plans up
+            JOIN plans p ON p.id = up.plan_id
+            WHERE up.user_id = $1
+              AND `+activePlanCond+`
+        )
+        INSERT INTO traffic_usage (user_id, period_start, `+column+`)
+        SELECT $1, `+currentPeriodExpr+`, $2
+        FROM lim
+        WHERE lim.value = 0 OR $2 <= lim.value
+        ON CONFLICT (user_id, period_start) DO UPDATE
+        SET `+column+` = traffic_usage.`+column+` + EXCLUDED.`+column+`
+        WHERE (SELECT value FROM lim) = 0
+           OR traffic_usage.`+column+` + EXCLUDED.`+column+` <= (SELECT value FROM lim)
+        RETURNING period_start`, userID, amount).Scan(&period)
+	if err == nil {
+		return period, nil
+	}
+	if err != sql.ErrNoRows {
+		return time.Time{}, fmt.Errorf("add %s: %w", column, err)
+	}
+
+	// строка не вставлена и не обновлена: либо лимит, либо нет действующего плана
+	var limit, used int64
+	err = s.db.QueryRowContext(ctx, `
+        SELECT `+limitExpr(limitColumn)+`, COALESCE(tu.`+column+`, 0)
+        FROM user_plans up
+        JOIN plans p ON p.id = up.plan_id
+        LEFT JOIN traffic_usage tu ON tu.user_id = up.user_id AND tu.period_start = `+currentPeriodExpr+`
+        WHERE up.user_id = $1
+          AND `+activePlanCond, userID).Scan(&limit, &used)
+	if err == sql.ErrNoRows {
+		return time.Time{}, ErrNoActivePlan
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("add %s: %w", column, err)
+	}
+	return time.Time{}, limitExceeded(limitColumn, limit, used+amount, errExceeded)
+}
@@ -0,0 +1,58 @@
+package quota_service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// Резерв возвращает месяц, в который он засчитан, а возврат идёт в этот же месяц, а не в текущий
+func TestEgress_RefundGoesToReservedPeriod(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	period := time.Date(2026, time.September, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`INSERT INTO traffic_usage \(user_id, period_start, egress_bytes\).*RETURNING period_start`).
+		WithArgs(7, int64(100)).
+		WillReturnRows(sqlmock.NewRows([]string{"period_start"}).AddRow(period))
+	mock.ExpectExec(`UPDATE traffic_usage\s+SET egress_bytes = GREATEST\(egress_bytes - \$2, 0\)\s+WHERE user_id = \$1\s+AND period_start = \$3`).
+		WithArgs(7, int64(60), period).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	got, err := s.TryAddEgress(context.Background(), 7, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(period) {
+		t.Fatalf("period = %v, want %v", got, period)
+	}
+	if err := s.RefundEgress(context.Background(), 7, got, 60); err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}
+
+func TestEgress_LimitExceeded(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectQuery(`INSERT INTO traffic_usage`).
+		WithArgs(7, int64(100)).
+		WillReturnRows(sqlmock.NewRows([]string{"period_start"}))
+	mock.ExpectQuery(`SELECT .*COALESCE\(tu.egress_bytes, 0\)\s+FROM user_plans up`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"limit", "used"}).AddRow(1000, 950))
+
+	if _, err := s.TryAddEgress(context.Background(), 7, 100); !errors.Is(err, quota_service.ErrEgressExceeded) {
+		t.Fatalf("err = %v, want ErrEgressExceeded", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestRefundEgress_NothingToReturn(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	if err := s.RefundEgress(context.Background(), 7, time.Now(), 0); err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}
@@ -0,0 +1,390 @@
+package quota_service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"slices"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/notifier"
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// MaxUsageAlertThresholds — сколько порогов может задать пользователь
+const MaxUsageAlertThresholds = 10
+
+// usageAlertTimeout — сколько ждать доставки уведомлений о порогах
+const usageAlertTimeout = 30 * time.Second
+
+// alertEmailTokenTTL — сколько действует код подтверждения адреса для писем
+const alertEmailTokenTTL = 24 * time.Hour
+
+var (
+	ErrInvalidAlertSettings = errors.New("invalid usage alert settings")
+	ErrAlertEmailToken      = errors.New("invalid or expired email confirmation code")
+)
+
+// usageAlerts — каналы доставки и пороги для пользователей без своих настроек
+type usageAlerts struct {
+	notifier   notifier.Notifier
+	mailer     notifier.Notifier // только почта: коды подтверждения адресов; nil — почта не настроена
+	thresholds []int
+}
+
+// EnableUsageAlerts включает уведомления о заполнении хранилища. Вызывается при старте, до обработки запросов.
+// mailer — канал почты для кодов подтверждения адресов (nil, если SMTP не настроен: тогда адрес для писем задать нельзя).
+// Без вызова пороги не проверяются, но настройки пользователей сохраняются.
+func (s *QuotaService) EnableUsageAlerts(n, mailer notifier.Notifier, defaultThresholds []int) error {
+	thresholds, err := normalizeThresholds(defaultThresholds)
+	if err != nil {
+		return err
+	}
+	s.alerts = &usageAlerts{notifier: n, mailer: mailer, thresholds: thresholds}
+	return nil
+}
+
+// GetUsageAlertSettings возвращает настройки уведомлений пользователя и уже пересечённые пороги
+func (s *QuotaService) GetUsageAlertSettings(ctx context.Context, userID int) (domain.UsageAlertSettings, error) {
+	var settings domain.UsageAlertSettings
+	var thresholds []int64
+	err := s.db.QueryRowContext(ctx, `
+        SELECT thresholds, webhook_url, email, email_verified FROM usage_alert_settings WHERE user_id = $1
+    `, userID).Scan(pq.Array(&thresholds), &settings.WebhookURL, &settings.Email, &settings.EmailVerified)
+	switch {
+	case err == sql.ErrNoRows:
+		settings.Default = true
+		settings.Thresholds = s.defaultThresholds()
+	case err != nil:
+		return domain.UsageAlertSettings{}, fmt.Errorf("get usage alert settings: %w", err)
+	default:
+		settings.Thresholds = toInts(thresholds)
+	}
+
+	var crossed []int64
+	if err := s.db.QueryRowContext(ctx, `
+        SELECT COALESCE(array_agg(threshold ORDER BY threshold), '{}') FROM usage_alert_state WHERE user_id = $1
+    `, userID).Scan(pq.Array(&crossed)); err != nil {
+		return domain.UsageAlertSettings{}, fmt.Errorf("get usage alert state: %w", err)
+	}
+	settings.Crossed = toInts(crossed)
+	return settings, nil
+}
+
+// SetUsageAlertSettings сохраняет пороги и адреса уведомлений.
+// Отметки о пересечении удалённых порогов сбрасываются; новые пороги проверятся при следующем AddUsage.
+// Письма уходят только на подтверждённый адрес: на новый (или ещё не подтверждённый) адрес отправляется код,
+// который нужно передать в ConfirmAlertEmail. Иначе любой пользователь мог бы слать уведомления на чужую почту.
+func (s *QuotaService) SetUsageAlertSettings(ctx context.Context, userID int, settings domain.UsageAlertSettings) (domain.UsageAlertSettings, error) {
+	thresholds, err := normalizeThresholds(settings.Thresholds)
+	if err != nil {
+		return domain.UsageAlertSettings{}, err
+	}
+	if settings.WebhookURL != "" {
+		if err := notifier.ValidateWebhookURL(settings.WebhookURL); err != nil {
+			return domain.UsageAlertSettings{}, fmt.Errorf("%w: %v", ErrInvalidAlertSettings, err)
+		}
+	}
+	if settings.Email != "" {
+		if addr, err := mail.ParseAddress(settings.Email); err != nil || addr.Address != settings.Email {
+			return domain.UsageAlertSettings{}, fmt.Errorf("%w: invalid email", ErrInvalidAlertSettings)
+		}
+		if s.alerts == nil || s.alerts.mailer == nil {
+			return domain.UsageAlertSettings{}, fmt.Errorf("%w: email notifications are not configured", ErrInvalidAlertSettings)
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return domain.UsageAlertSettings{}, fmt.Errorf("set usage alert settings: %w", err)
+	}
+	defer tx.Rollback()
+
+	var prevEmail string
+	var prevVerified bool
+	err = tx.QueryRowContext(ctx, `
+        SELECT email, email_verified FROM usage_alert_settings WHERE user_id = $1 FOR UPDATE
+    `, userID).Scan(&prevEmail, &prevVerified)
+	if err != nil && err != sql.ErrNoRows {
+		return domain.UsageAlertSettings{}, fmt.Errorf("set usage alert settings: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO usage_alert_settings (user_id, thresholds, webhook_url, email, updated_at)
+        VALUES ($1, $2, $3, $4, NOW())
+        ON CONFLICT (user_id) DO UPDATE
+        SET thresholds = EXCLUDED.thresholds,
+            webhook_url = EXCLUDED.webhook_url,
+            email = EXCLUDED.email,
+            updated_at = NOW()
+    `, userID, pq.Array(toInt64s(thresholds)), settings.WebhookURL, settings.Email); err != nil {
+		return domain.UsageAlertSettings{}, fmt.Errorf("set usage alert settings: %w", err)
+	}
+
+	// подтверждённый адрес остаётся подтверждённым; новый или неподтверждённый получает новый код
+	var token string
+	if settings.Email != prevEmail || !prevVerified {
+		tokenHash := ""
+		if settings.Email != "" {
+			if token, err = newAlertEmailToken(); err != nil {
+				return domain.UsageAlertSettings{}, err
+			}
+			tokenHash = hashAlertEmailToken(token)
+		}
+		if _, err := tx.ExecContext(ctx, `
+            UPDATE usage_alert_settings
+            SET email_verified = FALSE,
+                email_token_hash = $2,
+                email_token_expires_at = NOW() + $3 * INTERVAL '1 second'
+            WHERE user_id = $1
+        `, userID, tokenHash, int64(alertEmailTokenTTL/time.Second)); err != nil {
+			return domain.UsageAlertSettings{}, fmt.Errorf("set usage alert settings: %w", err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+        DELETE FROM usage_alert_state WHERE user_id = $1 AND NOT (threshold = ANY($2))
+    `, userID, pq.Array(toInt64s(thresholds))); err != nil {
+		return domain.UsageAlertSettings{}, fmt.Errorf("set usage alert settings: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.UsageAlertSettings{}, fmt.Errorf("set usage alert settings: %w", err)
+	}
+
+	if token != "" {
+		if err := s.sendAlertEmailToken(ctx, userID, settings.Email, token); err != nil {
+			return domain.UsageAlertSettings{}, err
+		}
+	}
+	return s.GetUsageAlertSettings(ctx, userID)
+}
+
+// ConfirmAlertEmail подтверждает адрес для писем кодом из письма, отправленного SetUsageAlertSettings. Код одноразовый.
+func (s *QuotaService) ConfirmAlertEmail(ctx context.Context, userID int, token string) (domain.UsageAlertSettings, error) {
+	res, err := s.db.ExecContext(ctx, `
+        UPDATE usage_alert_settings
+        SET email_verified = TRUE,
+            email_token_hash = '',
+            email_token_expires_at = NULL
+        WHERE user_id = $1
+          AND email <> ''
+          AND email_token_hash = $2
+          AND email_token_expires_at > NOW()
+    `, userID, hashAlertEmailToken(token))
+	if err != nil {
+		return domain.UsageAlertSettings{}, fmt.Errorf("confirm alert email: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return domain.UsageAlertSettings{}, fmt.Errorf("confirm alert email: %w", err)
+	}
+	if n == 0 {
+		return domain.UsageAlertSettings{}, ErrAlertEmailToken
+	}
+	return s.GetUsageAlertSettings(ctx, userID)
+}
+
+// sendAlertEmailToken отправляет код подтверждения только письмом: вебхук и лог его получать не должны
+func (s *QuotaService) sendAlertEmailToken(ctx context.Context, userID int, email, token string) error {
+	err := s.alerts.mailer.Notify(ctx, notifier.Event{
+		Type:   notifier.EventAlertEmailConfirm,
+		UserID: userID,
+		Message: fmt.Sprintf("To receive storage usage alerts at this address, confirm it with the code %s "+
+			"(POST /user/%d/usage/alerts/email/confirm). The code is valid until %s.",
+			token, userID, time.Now().Add(alertEmailTokenTTL).UTC().Format(time.RFC1123)),
+		At:    time.Now().UTC(),
+		Email: email,
+	})
+	if err != nil {
+		return fmt.Errorf("send alert email confirmation: %w", err)
+	}
+	return nil
+}
+
+func newAlertEmailToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate alert email token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// в базе хранится только хеш кода
+func hashAlertEmailToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// ResetUsageAlertSettings удаляет настройки пользователя: снова действуют пороги по умолчанию
+func (s *QuotaService) ResetUsageAlertSettings(ctx context.Context, userID int) (domain.UsageAlertSettings, error) {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM usage_alert_settings WHERE user_id = $1`, userID); err != nil {
+		return domain.UsageAlertSettings{}, fmt.Errorf("reset usage alert settings: %w", err)
+	}
+	return s.GetUsageAlertSettings(ctx, userID)
+}
+
+// claimUsageAlerts в транзакции, увеличившей current_used, отмечает пересечённые пороги.
+// Возвращает только пороги, которые не были отмечены раньше, — по ним и уходят уведомления.
+func (s *QuotaService) claimUsageAlerts(ctx context.Context, tx *sql.Tx, userID int) ([]int, error) {
+	if s.alerts == nil {
+		return nil, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+        WITH cur AS (
+            SELECT up.current_used AS used, `+limitExpr(LimitStorage)+` AS lim
+            FROM user_plans up
+            JOIN plans p ON p.id = up.plan_id
+            WHERE up.user_id = $1
+              AND `+activePlanCond+`
+        ), th AS (
+            SELECT unnest(COALESCE(
+                (SELECT thresholds FROM usage_alert_settings WHERE user_id = $1),
+                $2::int[]
+            )) AS pct
+        )
+        INSERT INTO usage_alert_state (user_id, threshold)
+        SELECT $1, th.pct
+        FROM th, cur
+        WHERE cur.lim > 0
+          AND cur.used * 100 >= th.pct::bigint * cur.lim
+        ON CONFLICT (user_id, threshold) DO NOTHING
+        RETURNING threshold
+    `, userID, pq.Array(toInt64s(s.alerts.thresholds)))
+	if err != nil {
+		return nil, fmt.Errorf("claim usage alerts: %w", err)
+	}
+	defer rows.Close()
+
+	var crossed []int
+	for rows.Next() {
+		var pct int
+		if err := rows.Scan(&pct); err != nil {
+			return nil, fmt.Errorf("claim usage alerts scan: %w", err)
+		}
+		crossed = append(crossed, pct)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("claim usage alerts: %w", err)
+	}
+	slices.Sort(crossed)
+	return crossed, nil
+}
+
+// rearmUsageAlerts снимает отметки с порогов, ниже которых опустилось занятое место (удаление файлов, больший план),
+// чтобы следующее пересечение снова вызвало уведомление
+func rearmUsageAlerts(ctx context.Context, tx *sql.Tx, userID int) error {
+	if _, err := tx.ExecContext(ctx, `
+        DELETE FROM usage_alert_state s
+        USING user_plans up
+        JOIN plans p ON p.id = up.plan_id
+        WHERE s.user_id = $1
+          AND up.user_id = $1
+          AND `+activePlanCond+`
+          AND up.current_used * 100 < s.threshold::bigint * `+limitExpr(LimitStorage), userID); err != nil {
+		return fmt.Errorf("rearm usage alerts: %w", err)
+	}
+	return nil
+}
+
+// sendUsageAlerts доставляет уведомления о пересечённых порогах в фоне, чтобы медленный вебхук не задерживал загрузку.
+// Вызывается после коммита: отметка о пересечении уже сохранена, повторной отправки не будет.
+func (s *QuotaService) sendUsageAlerts(ctx context.Context, userID int, crossed []int) {
+	if s.alerts == nil || len(crossed) == 0 {
+		return
+	}
+
+	var used, limit int64
+	var planName, webhookURL, email string
+	err := s.db.QueryRowContext(ctx, `
+        SELECT up.current_used, `+limitExpr(LimitStorage)+`, p.name,
+               COALESCE(st.webhook_url, ''), CASE WHEN st.email_verified THEN st.email ELSE '' END
+        FROM user_plans up
+        JOIN plans p ON p.id = up.plan_id
+        LEFT JOIN usage_alert_settings st ON st.user_id = up.user_id
+        WHERE up.user_id = $1
+          AND `+activePlanCond, userID).Scan(&used, &limit, &planName, &webhookURL, &email)
+	if err != nil {
+		logrus.Errorf("usage alerts of user %d: %v", userID, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	events := make([]notifier.Event, 0, len(crossed))
+	for _, pct := range crossed {
+		events = append(events, notifier.Event{
+			Type:    notifier.EventUsageAlert,
+			UserID:  userID,
+			Message: fmt.Sprintf("storage usage has reached %d%% of the %s plan limit: %d of %d bytes used", pct, planName, used, limit),
+			At:      now,
+			Data: map[string]any{
+				"threshold":     pct,
+				"current_used":  used,
+				"storage_limit": limit,
+				"plan_name":     planName,
+			},
+			WebhookURL: webhookURL,
+			Email:      email,
+		})
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), usageAlertTimeout)
+		defer cancel()
+		for _, event := range events {
+			if err := s.alerts.notifier.Notify(ctx, event); err != nil {
+				logrus.Errorf("usage alert %d%% of user %d: %v", event.Data["threshold"], userID, err)
+			}
+		}
+	}()
+}
+
+func (s *QuotaService) defaultThresholds() []int {
+	if s.alerts == nil {
+		return []int{}
+	}
+	return slices.Clone(s.alerts.thresholds)
+}
+
+// normalizeThresholds проверяет пороги (1..100 процентов, не больше MaxUsageAlertThresholds),
+// сортирует их и убирает повторы
+func normalizeThresholds(thresholds []int) ([]int, error) {
+	if len(thresholds) > MaxUsageAlertThresholds {
+		return nil, fmt.Errorf("%w: at most %d thresholds", ErrInvalidAlertSettings, MaxUsageAlertThresholds)
+	}
+	out := slices.Clone(thresholds)
+	for _, pct := range out {
+		if pct < 1 || pct > 100 {
+			return nil, fmt.Errorf("%w: threshold %d is out of 1..100", ErrInvalidAlertSettings, pct)
+		}
+	}
+	slices.Sort(out)
+	out = slices.Compact(out)
+	if out == nil {
+		out = []int{}
+	}
+	return out, nil
+}
+
+func toInts(values []int64) []int {
+	out := make([]int, len(values))
+	for i, v := range values {
+		out[i] = int(v)
+	}
+	return out
+}
+
+func toInt64s(values []int) []int64 {
+	out := make([]int64, len(values))
+	for i, v := range values {
+		out[i] = int64(v)
+	}
+	return out
+}
@@ -0,0 +1,169 @@
+package quota_service_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/notifier"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+type recordingNotifier struct {
+	events []notifier.Event
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, event notifier.Event) error {
+	n.events = append(n.events, event)
+	return nil
+}
+
+// capturedArg запоминает значение аргумента запроса, чтобы сверить его после вызова
+type capturedArg struct {
+	value string
+}
+
+func (a *capturedArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	a.value = s
+	return ok
+}
+
+var alertCodeRe = regexp.MustCompile(`code ([0-9a-f]{32})`)
+
+func expectAlertSettingsUpsert(mock sqlmock.Sqlmock, userID int, prevEmail string, prevVerified bool) {
+	mock.ExpectBegin()
+	rows := sqlmock.NewRows([]string{"email", "email_verified"})
+	if prevEmail != "" {
+		rows.AddRow(prevEmail, prevVerified)
+	}
+	mock.ExpectQuery(`SELECT email, email_verified FROM usage_alert_settings WHERE user_id = \$1 FOR UPDATE`).
+		WithArgs(userID).
+		WillReturnRows(rows)
+	mock.ExpectExec(`INSERT INTO usage_alert_settings`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func expectAlertSettingsRead(mock sqlmock.Sqlmock, userID int, email string, verified bool) {
+	mock.ExpectQuery(`SELECT thresholds, webhook_url, email, email_verified FROM usage_alert_settings`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"thresholds", "webhook_url", "email", "email_verified"}).AddRow("{80}", "", email, verified))
+	mock.ExpectQuery(`FROM usage_alert_state WHERE user_id = \$1`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"crossed"}).AddRow("{}"))
+}
+
+// Новый адрес не подтверждён: код уходит только письмом, в базе остаётся его хеш
+func TestSetUsageAlertSettings_NewEmailNeedsConfirmation(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	all, mailer := &recordingNotifier{}, &recordingNotifier{}
+	if err := s.EnableUsageAlerts(all, mailer, []int{80}); err != nil {
+		t.Fatal(err)
+	}
+
+	tokenHash := &capturedArg{}
+	expectAlertSettingsUpsert(mock, 7, "", false)
+	mock.ExpectExec(`UPDATE usage_alert_settings\s+SET email_verified = FALSE`).
+		WithArgs(7, tokenHash, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM usage_alert_state`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	expectAlertSettingsRead(mock, 7, "me@example.com", false)
+
+	settings, err := s.SetUsageAlertSettings(context.Background(), 7, domain.UsageAlertSettings{Thresholds: []int{80}, Email: "me@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.EmailVerified {
+		t.Error("email_verified = true before confirmation")
+	}
+	checkMock(t, mock)
+
+	if len(all.events) != 0 {
+		t.Errorf("confirmation code went to the shared channels: %+v", all.events)
+	}
+	if len(mailer.events) != 1 || mailer.events[0].Email != "me@example.com" || mailer.events[0].Type != notifier.EventAlertEmailConfirm {
+		t.Fatalf("mail = %+v, want one confirmation to me@example.com", mailer.events)
+	}
+	m := alertCodeRe.FindStringSubmatch(mailer.events[0].Message)
+	if m == nil {
+		t.Fatalf("no code in %q", mailer.events[0].Message)
+	}
+	sum := sha256.Sum256([]byte(m[1]))
+	if tokenHash.value != hex.EncodeToString(sum[:]) {
+		t.Errorf("stored hash %q does not match the mailed code", tokenHash.value)
+	}
+}
+
+// Подтверждённый адрес при сохранении тех же настроек остаётся подтверждённым, письмо не отправляется
+func TestSetUsageAlertSettings_SameVerifiedEmailKept(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mailer := &recordingNotifier{}
+	if err := s.EnableUsageAlerts(&recordingNotifier{}, mailer, []int{80}); err != nil {
+		t.Fatal(err)
+	}
+
+	expectAlertSettingsUpsert(mock, 7, "me@example.com", true)
+	mock.ExpectExec(`DELETE FROM usage_alert_state`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	expectAlertSettingsRead(mock, 7, "me@example.com", true)
+
+	if _, err := s.SetUsageAlertSettings(context.Background(), 7, domain.UsageAlertSettings{Thresholds: []int{80}, Email: "me@example.com"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(mailer.events) != 0 {
+		t.Errorf("mail = %+v, want none", mailer.events)
+	}
+	checkMock(t, mock)
+}
+
+func TestSetUsageAlertSettings_EmailWithoutSMTP(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	if err := s.EnableUsageAlerts(&recordingNotifier{}, nil, []int{80}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := s.SetUsageAlertSettings(context.Background(), 7, domain.UsageAlertSettings{Thresholds: []int{80}, Email: "me@example.com"})
+	if !errors.Is(err, quota_service.ErrInvalidAlertSettings) {
+		t.Fatalf("err = %v, want ErrInvalidAlertSettings", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestConfirmAlertEmail_WrongCode(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectExec(`UPDATE usage_alert_settings\s+SET email_verified = TRUE.*email_token_expires_at > NOW\(\)`).
+		WithArgs(7, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err := s.ConfirmAlertEmail(context.Background(), 7, "0000"); !errors.Is(err, quota_service.ErrAlertEmailToken) {
+		t.Fatalf("err = %v, want ErrAlertEmailToken", err)
+	}
+	checkMock(t, mock)
+}
+
+func TestConfirmAlertEmail(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	sum := sha256.Sum256([]byte("code"))
+	mock.ExpectExec(`UPDATE usage_alert_settings\s+SET email_verified = TRUE`).
+		WithArgs(7, hex.EncodeToString(sum[:])).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectAlertSettingsRead(mock, 7, "me@example.com", true)
+
+	settings, err := s.ConfirmAlertEmail(context.Background(), 7, "code")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !settings.EmailVerified {
+		t.Error("email_verified = false after confirmation")
+	}
+	checkMock(t, mock)
+}
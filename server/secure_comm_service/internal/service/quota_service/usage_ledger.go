@@ -0,0 +1,198 @@
+package quota_service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/sirupsen/logrus"
+)
+
+// MaxUsageHistoryDays — максимальная длина ряда GET /user/:id/usage/history
+const MaxUsageHistoryDays = 366
+
+var ErrInvalidUsageRange = errors.New("invalid usage history range")
+
+// recordUsage пишет изменение занятого места в usage_ledger в транзакции, которая меняет current_used,
+// сверяет current_used с журналом и по причине изменения обновляет число файлов владельца. actorID — см. quotaOwner.ledgerIDs.
+func recordUsage(ctx context.Context, tx *sql.Tx, owner quotaOwner, actorID int, delta int64, ref domain.UsageRef) error {
+	userID, orgID := owner.ledgerIDs(actorID)
+	if _, err := tx.ExecContext(ctx, `
+        INSERT INTO usage_ledger (user_id, org_id, obj_id, category, delta, reason)
+        VALUES ($1, $2, $3, $4, $5, $6)
+    `, userID, orgID, ref.ObjID, ref.Category, delta, ref.Reason); err != nil {
+		return fmt.Errorf("record usage: %w", err)
+	}
+	if err := syncUsageWithLedger(ctx, tx, owner); err != nil {
+		return err
+	}
+	return adjustFileCount(ctx, tx, owner, ref.Reason)
+}
+
+// syncUsageWithLedger выставляет current_used живых строк владельца равным сумме его журнала.
+// Журнал — источник истины, current_used — его копия для быстрых проверок лимита: если они разошлись
+// (ручная правка, сбой до появления журнала), расхождение исправляется в той же транзакции, а не только логируется.
+func syncUsageWithLedger(ctx context.Context, tx *sql.Tx, owner quotaOwner) error {
+	res, err := tx.ExecContext(ctx, `
+        UPDATE user_plans up
+        SET current_used = GREATEST(l.total, 0)
+        FROM (SELECT COALESCE(SUM(l.delta), 0) AS total FROM usage_ledger l WHERE `+owner.ledgerFilter("$1")+`) l
+        WHERE up.`+owner.column+` = $1
+          AND up.current_used <> GREATEST(l.total, 0)
+          AND `+livePlanCond, owner.id)
+	if err != nil {
+		return fmt.Errorf("sync usage with ledger: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n > 0 {
+		logrus.Warnf("current_used of %s %d differed from the usage ledger and was reset to the ledger sum", owner.column, owner.id)
+	}
+	return nil
+}
+
+// ledgerUsage — сумма журнала владельца, т.е. занятое место по журналу
+func ledgerUsage(ctx context.Context, tx *sql.Tx, owner quotaOwner) (int64, error) {
+	var total int64
+	err := tx.QueryRowContext(ctx, `
+        SELECT COALESCE(SUM(l.delta), 0) FROM usage_ledger l WHERE `+owner.ledgerFilter("$1"), owner.id).Scan(&total)
+	if err != nil {
+		return 0, fmt.Errorf("ledger usage: %w", err)
+	}
+	return total, nil
+}
+
+// UsageWatermark возвращает ID последней записи usage_ledger.
+// Реконсилер берёт его до сканирования MinIO и исправляет только владельцев без записей после него.
+func (s *QuotaService) UsageWatermark(ctx context.Context) (int64, error) {
+	var id int64
+	if err := s.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM usage_ledger`).Scan(&id); err != nil {
+		return 0, fmt.Errorf("usage watermark: %w", err)
+	}
+	return id, nil
+}
+
+// RecordMove отражает в журнале перенос файла между категориями. current_used не меняется:
+// пишутся две записи (-size в старой категории и +size в новой), их сумма равна нулю.
+func (s *QuotaService) RecordMove(ctx context.Context, userID int, size int64, objID, fromCategory, toCategory string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("record move: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := recordUsage(ctx, tx, userOwner(userID), userID, -size, domain.UsageRef{ObjID: objID, Category: fromCategory, Reason: domain.UsageReasonMove}); err != nil {
+		return err
+	}
+	if err := recordUsage(ctx, tx, userOwner(userID), userID, size, domain.UsageRef{ObjID: objID, Category: toCategory, Reason: domain.UsageReasonMove}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UsageByCategory возвращает занятое место личного пула по категориям, посчитанное по журналу
+func (s *QuotaService) UsageByCategory(ctx context.Context, userID int) ([]domain.CategoryUsage, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT category, SUM(delta)
+        FROM usage_ledger
+        WHERE user_id = $1
+          AND org_id IS NULL
+        GROUP BY category
+        HAVING SUM(delta) <> 0
+        ORDER BY category
+    `, userID)
+	if err != nil {
+		return nil, fmt.Errorf("usage by category: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []domain.CategoryUsage{}
+	for rows.Next() {
+		var cu domain.CategoryUsage
+		if err := rows.Scan(&cu.Category, &cu.Bytes); err != nil {
+			return nil, fmt.Errorf("usage by category scan: %w", err)
+		}
+		categories = append(categories, cu)
+	}
+	return categories, rows.Err()
+}
+
+// UsageHistory возвращает изменение занятого места по дням (UTC) в [from, to).
+// Если category не пустая — только по этой категории. Дни без изменений тоже попадают в ряд.
+func (s *QuotaService) UsageHistory(ctx context.Context, userID int, from, to time.Time, category string) ([]domain.UsagePoint, error) {
+	from = from.UTC().Truncate(24 * time.Hour)
+	to = to.UTC().Truncate(24 * time.Hour)
+	if !to.After(from) || to.Sub(from) > MaxUsageHistoryDays*24*time.Hour {
+		return nil, ErrInvalidUsageRange
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+        WITH days AS (
+            SELECT generate_series($2::timestamptz, $3::timestamptz - INTERVAL '1 day', INTERVAL '1 day') AS day
+        ),
+        ledger AS (
+            SELECT delta, created_at
+            FROM usage_ledger
+            WHERE user_id = $1
+              AND org_id IS NULL
+              AND ($4::text = '' OR category = $4::text)
+        ),
+        opening AS (
+            SELECT COALESCE(SUM(delta), 0) AS total FROM ledger WHERE created_at < $2
+        ),
+        daily AS (
+            SELECT d.day,
+                   COALESCE(SUM(l.delta) FILTER (WHERE l.delta > 0), 0)  AS added,
+                   COALESCE(-SUM(l.delta) FILTER (WHERE l.delta < 0), 0) AS removed
+            FROM days d
+            LEFT JOIN ledger l ON l.created_at >= d.day AND l.created_at < d.day + INTERVAL '1 day'
+            GROUP BY d.day
+        )
+        SELECT day, added, removed,
+               (SELECT total FROM opening) + SUM(added - removed) OVER (ORDER BY day)
+        FROM daily
+        ORDER BY day
+    `, userID, from, to, category)
+	if err != nil {
+		return nil, fmt.Errorf("usage history: %w", err)
+	}
+	defer rows.Close()
+
+	points := []domain.UsagePoint{}
+	for rows.Next() {
+		var p domain.UsagePoint
+		if err := rows.Scan(&p.Day, &p.Added, &p.Removed, &p.Total); err != nil {
+			return nil, fmt.Errorf("usage history scan: %w", err)
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// ListUsageLedger возвращает записи журнала от новых к старым. beforeID > 0 — продолжение со следующей страницы.
+func (s *QuotaService) ListUsageLedger(ctx context.Context, userID int, beforeID int64, limit int) ([]domain.UsageLedgerEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+        SELECT id, obj_id, category, delta, reason, created_at
+        FROM usage_ledger
+        WHERE user_id = $1
+          AND org_id IS NULL
+          AND ($2 = 0 OR id < $2)
+        ORDER BY id DESC
+        LIMIT $3
+    `, userID, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list usage ledger: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []domain.UsageLedgerEntry{}
+	for rows.Next() {
+		var e domain.UsageLedgerEntry
+		if err := rows.Scan(&e.ID, &e.ObjID, &e.Category, &e.Delta, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("list usage ledger scan: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
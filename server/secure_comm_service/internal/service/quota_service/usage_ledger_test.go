@@ -0,0 +1,114 @@
+package quota_service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/1abobik1/SecureComm/internal/domain"
+	"github.com/1abobik1/SecureComm/internal/service/quota_service"
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var removeLockCols = []string{"current_used", "started"}
+
+// expectRemoveLock — removeUsage блокирует все живые строки владельца (действующую и запланированные)
+func expectRemoveLock(mock sqlmock.Sqlmock, userID int, rows *sqlmock.Rows) {
+	mock.ExpectQuery(`SELECT up.current_used, up.started_at <= NOW\(\)\s+FROM user_plans up\s+WHERE up.user_id = \$1\s+AND up.status = 'active' AND up.expires_at > NOW\(\)\s+ORDER BY up.started_at\s+FOR UPDATE OF up`).
+		WithArgs(userID).
+		WillReturnRows(rows)
+}
+
+func expectRemoveUpdate(mock sqlmock.Sqlmock, userID int, removed int64) {
+	mock.ExpectExec(`UPDATE user_plans up\s+SET current_used = GREATEST\(up.current_used - \$1, 0\).*AND up.status = 'active' AND up.expires_at > NOW\(\)`).
+		WithArgs(removed, userID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+}
+
+// Удаление больше занятого: в журнал пишется фактически вычтенное, и current_used сверяется с журналом в той же транзакции
+func TestRemoveUsage_ClampedAndSyncedWithLedger(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	// после отмены рядом с действующей лежит запланированная строка free — она тоже блокируется
+	expectRemoveLock(mock, 7, sqlmock.NewRows(removeLockCols).AddRow(500, true).AddRow(500, false))
+	expectRemoveUpdate(mock, 7, 500)
+	mock.ExpectExec(`INSERT INTO usage_ledger`).
+		WithArgs(7, nil, "7/a", "photo", int64(-500), domain.UsageReasonDelete).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerSync(mock, "user_id", 7, 0)
+	mock.ExpectExec(`INSERT INTO user_file_counts`).
+		WithArgs(7, int64(-1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM usage_alert_state`).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := s.RemoveUsage(context.Background(), 7, 800, domain.UsageRef{ObjID: "7/a", Category: "photo", Reason: domain.UsageReasonDelete})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}
+
+// current_used разошёлся с журналом — исправляется до коммита, а не только пишется в лог
+func TestRemoveUsage_DriftFixedInSameTx(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	expectRemoveLock(mock, 7, sqlmock.NewRows(removeLockCols).AddRow(500, true))
+	expectRemoveUpdate(mock, 7, 100)
+	mock.ExpectExec(`INSERT INTO usage_ledger`).
+		WithArgs(7, nil, "7/a", "photo", int64(-100), domain.UsageReasonDelete).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerSync(mock, "user_id", 7, 1)
+	mock.ExpectExec(`INSERT INTO user_file_counts`).
+		WithArgs(7, int64(-1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM usage_alert_state`).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := s.RemoveUsage(context.Background(), 7, 100, domain.UsageRef{ObjID: "7/a", Category: "photo", Reason: domain.UsageReasonDelete})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}
+
+// Живы только запланированные строки: действующего плана нет
+func TestRemoveUsage_OnlyScheduledRow(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	expectRemoveLock(mock, 7, sqlmock.NewRows(removeLockCols).AddRow(500, false))
+	mock.ExpectRollback()
+	mock.ExpectQuery(`SELECT up.id FROM user_plans up\s+WHERE up.user_id = \$1`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	err := s.RemoveUsage(context.Background(), 7, 100, domain.UsageRef{ObjID: "7/a", Category: "photo", Reason: domain.UsageReasonDelete})
+	if !errors.Is(err, quota_service.ErrNoActivePlan) {
+		t.Fatalf("err = %v, want ErrNoActivePlan", err)
+	}
+	checkMock(t, mock)
+}
+
+// Перенос между категориями: две записи журнала с нулевой суммой, после каждой current_used сверяется с журналом
+func TestRecordMove_SyncedWithLedger(t *testing.T) {
+	s, mock := newQuotaMock(t)
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO usage_ledger`).
+		WithArgs(7, nil, "7/a", "photo", int64(-10), domain.UsageReasonMove).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerSync(mock, "user_id", 7, 0)
+	mock.ExpectExec(`INSERT INTO usage_ledger`).
+		WithArgs(7, nil, "7/a", "video", int64(10), domain.UsageReasonMove).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectLedgerSync(mock, "user_id", 7, 0)
+	mock.ExpectCommit()
+
+	if err := s.RecordMove(context.Background(), 7, 10, "7/a", "photo", "video"); err != nil {
+		t.Fatal(err)
+	}
+	checkMock(t, mock)
+}